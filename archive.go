@@ -0,0 +1,711 @@
+package plist
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A UID is a reference to another object in the "$objects" array of an
+// NSKeyedArchiver archive. UIDs encode as CFKeyedArchiverUIDs.
+type UID uint32
+
+var uidType = reflect.TypeOf(UID(0))
+
+// An ArchivedObject is an object from a keyed archive whose class has no
+// native Go representation.
+//
+// Fields holds the archived keys of the object. References to other objects
+// are resolved, so a field refers to the same *ArchivedObject (or map, or
+// slice) as every other reference to that object in the archive. This means
+// the decoded graph may contain cycles.
+type ArchivedObject struct {
+	Class   string   // the value of $classname
+	Classes []string // the value of $classes, most derived class first
+	Fields  map[string]interface{}
+}
+
+// An ArchivedSet is the decoded form of an NSSet. Archive encodes it as an
+// NSSet rather than an NSArray.
+type ArchivedSet []interface{}
+
+// A Range is the decoded form of an NSValue holding an NSRange.
+type Range struct {
+	Location int64
+	Length   int64
+}
+
+// nsValueRangeType is the NS.special value NSValue uses for NSRange.
+const nsValueRangeType = 4
+
+// cocoaEpoch is the reference date of NSDate, 2001-01-01 00:00:00 UTC, in
+// seconds since the Unix epoch.
+const cocoaEpoch = 978307200
+
+var errCyclicArchive = errors.New("plist: keyed archive contains a cycle that cannot be represented")
+
+// An Unarchiver decodes the object graph of an NSKeyedArchiver archive.
+//
+// Objects that are referenced more than once are only decoded once, and every
+// reference is resolved to the same Go value. Cycles are supported for
+// ArchivedObjects, arrays and dictionaries.
+type Unarchiver struct {
+	objects []interface{}
+	top     map[string]interface{}
+	decoded map[UID]interface{}
+	pending map[UID]bool
+	allowed map[string]bool // nil if every class is allowed
+}
+
+// Unarchive decodes the root object of the NSKeyedArchiver archive data.
+//
+// NSArray, NSDictionary, NSString, NSData, NSDate and NSNumber objects decode
+// as []interface{}, map[string]interface{}, string, []byte, time.Time and the
+// numeric types respectively. In addition, the following Foundation classes
+// are bridged:
+//
+//	NSNull, as nil
+//	NSSet and NSMutableSet, as ArchivedSet
+//	NSURL, as *url.URL
+//	NSUUID, as [16]byte
+//	NSValue holding an NSRange, as Range
+//
+// NSAttributedString, NSColor, NSFont and NSFontDescriptor objects, and their
+// UIKit equivalents, decode as AttributedString, Color and Font. Objects of
+// any other class decode as an *ArchivedObject.
+func Unarchive(data []byte) (interface{}, error) {
+	u, err := NewUnarchiver(data)
+	if err != nil {
+		return nil, err
+	}
+	return u.Decode()
+}
+
+// NewUnarchiver parses data as an NSKeyedArchiver archive.
+func NewUnarchiver(data []byte) (*Unarchiver, error) {
+	var archive map[string]interface{}
+	if _, err := Unmarshal(data, &archive); err != nil {
+		return nil, err
+	}
+	if archiver, _ := archive["$archiver"].(string); archiver != "NSKeyedArchiver" {
+		return nil, errors.New("plist: not an NSKeyedArchiver archive")
+	}
+	objects, ok := archive["$objects"].([]interface{})
+	if !ok {
+		return nil, errors.New("plist: keyed archive has no $objects array")
+	}
+	top, ok := archive["$top"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("plist: keyed archive has no $top dictionary")
+	}
+	return &Unarchiver{
+		objects: objects,
+		top:     top,
+		decoded: make(map[UID]interface{}),
+		pending: make(map[UID]bool),
+	}, nil
+}
+
+// AllowClasses restricts decoding to objects of the named classes, in the
+// manner of NSSecureCoding. Once it has been called, decoding an object whose
+// class was not allowed by any call fails with a DisallowedClassError. This
+// should always be used when decoding archives from untrusted sources.
+//
+// The check applies to every object that carries a class, including the
+// bridged Foundation types, so for example both "NSArray" and
+// "NSMutableArray" must be allowed to accept either kind of array. Strings,
+// numbers and data stored directly in the archive are always allowed.
+//
+// Only the $classname of an object is checked. The superclasses listed in
+// $classes are supplied by the archive and are not trusted.
+func (u *Unarchiver) AllowClasses(classes ...string) {
+	if u.allowed == nil {
+		u.allowed = make(map[string]bool, len(classes))
+	}
+	for _, class := range classes {
+		u.allowed[class] = true
+	}
+}
+
+// Decode returns the root object of the archive, which is the one stored
+// under the name "root".
+func (u *Unarchiver) Decode() (interface{}, error) {
+	return u.DecodeRoot("root")
+}
+
+// Roots returns the names of the top-level objects of the archive, in sorted
+// order. Archives created with -encodeRootObject: have a single root named
+// "root", but archives may store any number of named roots.
+func (u *Unarchiver) Roots() []string {
+	names := make([]string, 0, len(u.top))
+	for name := range u.top {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DecodeRoot returns the top-level object of the archive with the given name.
+// Objects shared between roots decode to the same Go value.
+func (u *Unarchiver) DecodeRoot(name string) (interface{}, error) {
+	root, ok := u.top[name]
+	if !ok {
+		return nil, errors.New("plist: keyed archive has no root object named " + strconv.Quote(name))
+	}
+	return u.decodeValue(root)
+}
+
+// decodeValue resolves any UIDs in an archived value.
+func (u *Unarchiver) decodeValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case UID:
+		return u.decodeUID(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, elem := range v {
+			val, err := u.decodeValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = val
+		}
+		return result, nil
+	}
+	return v, nil
+}
+
+func (u *Unarchiver) decodeUID(uid UID) (interface{}, error) {
+	if uid == 0 {
+		// $objects[0] is always "$null"
+		return nil, nil
+	}
+	if obj, ok := u.decoded[uid]; ok {
+		return obj, nil
+	}
+	if u.pending[uid] {
+		return nil, errCyclicArchive
+	}
+	if int(uid) >= len(u.objects) {
+		return nil, errors.New("plist: keyed archive UID out of range")
+	}
+	u.pending[uid] = true
+	defer delete(u.pending, uid)
+	obj := u.objects[uid]
+	dict, ok := obj.(map[string]interface{})
+	if !ok {
+		// strings, numbers and data are stored directly
+		u.decoded[uid] = obj
+		return obj, nil
+	}
+	if _, ok := dict["$class"]; !ok {
+		u.decoded[uid] = dict
+		return dict, nil
+	}
+	return u.decodeObject(uid, dict)
+}
+
+// class returns the class names of an archived object, most derived first.
+func (u *Unarchiver) class(dict map[string]interface{}) ([]string, error) {
+	uid, ok := dict["$class"].(UID)
+	if !ok || int(uid) >= len(u.objects) {
+		return nil, errors.New("plist: keyed archive object has an invalid $class")
+	}
+	class, ok := u.objects[uid].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("plist: keyed archive object has an invalid $class")
+	}
+	name, _ := class["$classname"].(string)
+	if name == "" {
+		return nil, errors.New("plist: keyed archive class has no $classname")
+	}
+	classes := []string{name}
+	if names, ok := class["$classes"].([]interface{}); ok && len(names) > 0 {
+		classes = make([]string, 0, len(names))
+		for _, n := range names {
+			if s, ok := n.(string); ok {
+				classes = append(classes, s)
+			}
+		}
+	}
+	return classes, nil
+}
+
+func (u *Unarchiver) decodeObject(uid UID, dict map[string]interface{}) (interface{}, error) {
+	classes, err := u.class(dict)
+	if err != nil {
+		return nil, err
+	}
+	if u.allowed != nil && !u.allowed[classes[0]] {
+		return nil, &DisallowedClassError{classes[0]}
+	}
+	switch classes[0] {
+	case "NSArray", "NSMutableArray":
+		refs, _ := dict["NS.objects"].([]interface{})
+		result := make([]interface{}, len(refs))
+		// record the slice before decoding the elements so cycles resolve to it
+		u.decoded[uid] = result
+		for i, ref := range refs {
+			val, err := u.decodeValue(ref)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = val
+		}
+		return result, nil
+	case "NSDictionary", "NSMutableDictionary":
+		keys, _ := dict["NS.keys"].([]interface{})
+		refs, _ := dict["NS.objects"].([]interface{})
+		if len(keys) != len(refs) {
+			return nil, errors.New("plist: keyed archive dictionary has mismatched keys and objects")
+		}
+		result := make(map[string]interface{}, len(keys))
+		u.decoded[uid] = result
+		for i, keyRef := range keys {
+			key, err := u.decodeValue(keyRef)
+			if err != nil {
+				return nil, err
+			}
+			str, ok := key.(string)
+			if !ok {
+				return nil, errors.New("plist: keyed archive dictionary has a non-string key")
+			}
+			val, err := u.decodeValue(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			result[str] = val
+		}
+		return result, nil
+	case "NSString", "NSMutableString":
+		str, err := u.decodeValue(dict["NS.string"])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := str.(string); !ok {
+			return nil, errors.New("plist: keyed archive NSString has no NS.string")
+		}
+		u.decoded[uid] = str
+		return str, nil
+	case "NSData", "NSMutableData":
+		data, err := u.decodeValue(dict["NS.data"])
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := data.([]byte); !ok {
+			return nil, errors.New("plist: keyed archive NSData has no NS.data")
+		}
+		u.decoded[uid] = data
+		return data, nil
+	case "NSDate":
+		secs, ok := archivedFloat(dict["NS.time"])
+		if !ok {
+			return nil, errors.New("plist: keyed archive NSDate has no NS.time")
+		}
+		t := cocoaTime(secs)
+		u.decoded[uid] = t
+		return t, nil
+	case "NSNull":
+		u.decoded[uid] = nil
+		return nil, nil
+	case "NSSet", "NSMutableSet":
+		refs, _ := dict["NS.objects"].([]interface{})
+		result := make(ArchivedSet, len(refs))
+		u.decoded[uid] = result
+		for i, ref := range refs {
+			val, err := u.decodeValue(ref)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = val
+		}
+		return result, nil
+	case "NSURL":
+		result, err := u.decodeURL(dict)
+		if err != nil {
+			return nil, err
+		}
+		u.decoded[uid] = result
+		return result, nil
+	case "NSUUID":
+		data, _ := dict["NS.uuidbytes"].([]byte)
+		if len(data) != 16 {
+			return nil, errors.New("plist: keyed archive NSUUID has invalid NS.uuidbytes")
+		}
+		var result [16]byte
+		copy(result[:], data)
+		u.decoded[uid] = result
+		return result, nil
+	case "NSValue":
+		if special, _ := archivedFloat(dict["NS.special"]); special != nsValueRangeType {
+			// only NSRange is bridged
+			break
+		}
+		location, ok1 := archivedFloat(dict["NS.rangeval.location"])
+		length, ok2 := archivedFloat(dict["NS.rangeval.length"])
+		if !ok1 || !ok2 {
+			return nil, errors.New("plist: keyed archive NSValue has an invalid NSRange")
+		}
+		result := Range{int64(location), int64(length)}
+		u.decoded[uid] = result
+		return result, nil
+	}
+	if decoder, ok := classDecoders[classes[0]]; ok {
+		result, err := decoder(u, dict)
+		if err != nil {
+			return nil, err
+		}
+		u.decoded[uid] = result
+		return result, nil
+	}
+	obj := &ArchivedObject{
+		Class:   classes[0],
+		Classes: classes,
+		Fields:  make(map[string]interface{}, len(dict)-1),
+	}
+	u.decoded[uid] = obj
+	for key, value := range dict {
+		if key == "$class" {
+			continue
+		}
+		val, err := u.decodeValue(value)
+		if err != nil {
+			return nil, err
+		}
+		obj.Fields[key] = val
+	}
+	return obj, nil
+}
+
+// decodeURL resolves an archived NSURL against its base URL, if any.
+func (u *Unarchiver) decodeURL(dict map[string]interface{}) (*url.URL, error) {
+	relative, err := u.decodeValue(dict["NS.relative"])
+	if err != nil {
+		return nil, err
+	}
+	str, ok := relative.(string)
+	if !ok {
+		return nil, errors.New("plist: keyed archive NSURL has no NS.relative")
+	}
+	result, err := url.Parse(str)
+	if err != nil {
+		return nil, err
+	}
+	base, err := u.decodeValue(dict["NS.base"])
+	if err != nil {
+		return nil, err
+	}
+	if baseURL, ok := base.(*url.URL); ok {
+		result = baseURL.ResolveReference(result)
+	}
+	return result, nil
+}
+
+// archivedFloat returns the value of an archived number as a float64.
+func archivedFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// cocoaTime converts seconds since the NSDate reference date into a time.Time,
+// with the same millisecond rounding as CFDate conversion.
+func cocoaTime(secs float64) time.Time {
+	return unixTime(secs + cocoaEpoch)
+}
+
+func timeToCocoa(t time.Time) float64 {
+	ms := int64(time.Duration(t.UnixNano()) / time.Millisecond)
+	return float64(ms)/1000 - cocoaEpoch
+}
+
+// Archive returns an NSKeyedArchiver archive with v as its root object,
+// serialized in the given format.
+//
+// Archive accepts the values produced by Unarchive, including the bridged
+// Foundation types, which are archived as the class they were decoded from. A
+// url.URL is archived as an absolute NSURL. Maps, slices and
+// *ArchivedObjects that are referenced more than once are archived once and
+// shared by reference, so cyclic graphs of these values may be archived.
+// Identical strings are also archived only once.
+//
+// Numeric and boolean fields of an ArchivedObject are archived inline, as
+// NSKeyedArchiver does for -encodeInt:forKey: and friends. All other values
+// are archived as references.
+func Archive(v interface{}, format Format) ([]byte, error) {
+	return ArchiveRoots(map[string]interface{}{"root": v}, format)
+}
+
+// ArchiveRoots is like Archive, but stores each value of roots as a separate
+// top-level object under its key. Values shared between roots are archived
+// once.
+//
+// Roots, and the entries of maps and ArchivedObject fields, are archived in
+// the order of their keys, so the same values always produce the same
+// $objects and UIDs.
+func ArchiveRoots(roots map[string]interface{}, format Format) ([]byte, error) {
+	a := &archiver{
+		objects: []interface{}{"$null"},
+		uids:    make(map[archiveKey]UID),
+		strings: make(map[string]UID),
+		classes: make(map[string]UID),
+	}
+	names := make([]string, 0, len(roots))
+	for name := range roots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	top := make(map[string]interface{}, len(roots))
+	for _, name := range names {
+		uid, err := a.encode(reflect.ValueOf(roots[name]))
+		if err != nil {
+			return nil, err
+		}
+		top[name] = uid
+	}
+	archive := map[string]interface{}{
+		"$archiver": "NSKeyedArchiver",
+		"$version":  100000,
+		"$top":      top,
+		"$objects":  a.objects,
+	}
+	return Marshal(archive, format)
+}
+
+// archiveKey identifies a reference value by its identity.
+type archiveKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+type archiver struct {
+	objects []interface{}
+	uids    map[archiveKey]UID
+	strings map[string]UID
+	classes map[string]UID
+}
+
+var (
+	archivedObjectType = reflect.TypeOf((*ArchivedObject)(nil))
+	archivedSetType    = reflect.TypeOf(ArchivedSet(nil))
+	rangeType          = reflect.TypeOf(Range{})
+	urlType            = reflect.TypeOf(url.URL{})
+	uuidType           = reflect.TypeOf([16]byte{})
+)
+
+// reserve appends a placeholder to $objects and returns its UID, so that
+// references back to the object can be resolved before it's been encoded.
+func (a *archiver) reserve(key archiveKey) UID {
+	uid := UID(len(a.objects))
+	a.objects = append(a.objects, nil)
+	if key.typ != nil {
+		a.uids[key] = uid
+	}
+	return uid
+}
+
+func (a *archiver) encode(v reflect.Value) (UID, error) {
+	if !v.IsValid() {
+		return 0, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0, nil
+		}
+		if v.Type() == archivedObjectType {
+			return a.encodeObject(v.Interface().(*ArchivedObject))
+		}
+		return a.encode(v.Elem())
+	case reflect.String:
+		str := v.String()
+		if uid, ok := a.strings[str]; ok {
+			return uid, nil
+		}
+		uid := a.reserve(archiveKey{})
+		a.objects[uid] = str
+		a.strings[str] = uid
+		return uid, nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		uid := a.reserve(archiveKey{})
+		a.objects[uid] = v.Interface()
+		return uid, nil
+	case reflect.Struct:
+		var dict map[string]interface{}
+		switch v.Type() {
+		case timeType:
+			dict = map[string]interface{}{
+				"$class":  a.class("NSDate", "NSObject"),
+				"NS.time": timeToCocoa(v.Interface().(time.Time)),
+			}
+		case rangeType:
+			r := v.Interface().(Range)
+			dict = map[string]interface{}{
+				"$class":               a.class("NSValue", "NSObject"),
+				"NS.special":           nsValueRangeType,
+				"NS.rangeval.location": r.Location,
+				"NS.rangeval.length":   r.Length,
+			}
+		case urlType:
+			u := v.Interface().(url.URL)
+			relative, _ := a.encode(reflect.ValueOf(u.String()))
+			dict = map[string]interface{}{
+				"$class":      a.class("NSURL", "NSObject"),
+				"NS.base":     UID(0),
+				"NS.relative": relative,
+			}
+		default:
+			return 0, &UnsupportedTypeError{v.Type()}
+		}
+		uid := a.reserve(archiveKey{})
+		a.objects[uid] = dict
+		return uid, nil
+	case reflect.Slice, reflect.Array:
+		if v.Type() == uuidType {
+			uuid := v.Interface().([16]byte)
+			uid := a.reserve(archiveKey{})
+			a.objects[uid] = map[string]interface{}{
+				"$class":       a.class("NSUUID", "NSObject"),
+				"NS.uuidbytes": uuid[:],
+			}
+			return uid, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			uid := a.reserve(archiveKey{})
+			a.objects[uid] = v.Bytes()
+			return uid, nil
+		}
+		var key archiveKey
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return 0, nil
+			}
+			key = archiveKey{v.Type(), v.Pointer(), v.Len()}
+			if uid, ok := a.uids[key]; ok {
+				return uid, nil
+			}
+		}
+		uid := a.reserve(key)
+		refs := make([]interface{}, v.Len())
+		for i := range refs {
+			ref, err := a.encode(v.Index(i))
+			if err != nil {
+				return 0, err
+			}
+			refs[i] = ref
+		}
+		class := a.class("NSArray", "NSObject")
+		if v.Type() == archivedSetType {
+			class = a.class("NSSet", "NSObject")
+		}
+		a.objects[uid] = map[string]interface{}{
+			"$class":     class,
+			"NS.objects": refs,
+		}
+		return uid, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return 0, nil
+		}
+		key := archiveKey{v.Type(), v.Pointer(), 0}
+		if uid, ok := a.uids[key]; ok {
+			return uid, nil
+		}
+		uid := a.reserve(key)
+		mapKeys := v.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool { return mapKeys[i].String() < mapKeys[j].String() })
+		keys := make([]interface{}, len(mapKeys))
+		refs := make([]interface{}, len(mapKeys))
+		for i, mapKey := range mapKeys {
+			keyRef, err := a.encode(reflect.ValueOf(mapKey.String()))
+			if err != nil {
+				return 0, err
+			}
+			keys[i] = keyRef
+			ref, err := a.encode(v.MapIndex(mapKey))
+			if err != nil {
+				return 0, err
+			}
+			refs[i] = ref
+		}
+		a.objects[uid] = map[string]interface{}{
+			"$class":     a.class("NSDictionary", "NSObject"),
+			"NS.keys":    keys,
+			"NS.objects": refs,
+		}
+		return uid, nil
+	}
+	return 0, &UnsupportedTypeError{v.Type()}
+}
+
+func (a *archiver) encodeObject(obj *ArchivedObject) (UID, error) {
+	key := archiveKey{archivedObjectType, reflect.ValueOf(obj).Pointer(), 0}
+	if uid, ok := a.uids[key]; ok {
+		return uid, nil
+	}
+	if obj.Class == "" {
+		return 0, errors.New("plist: cannot archive ArchivedObject with no class")
+	}
+	uid := a.reserve(key)
+	classes := obj.Classes
+	if len(classes) == 0 || classes[0] != obj.Class {
+		classes = []string{obj.Class, "NSObject"}
+	}
+	dict := make(map[string]interface{}, len(obj.Fields)+1)
+	dict["$class"] = a.class(classes...)
+	names := make([]string, 0, len(obj.Fields))
+	for name := range obj.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := obj.Fields[name]
+		fv := reflect.ValueOf(field)
+		switch fv.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Float32, reflect.Float64:
+			dict[name] = field
+			continue
+		}
+		ref, err := a.encode(fv)
+		if err != nil {
+			return 0, err
+		}
+		dict[name] = ref
+	}
+	a.objects[uid] = dict
+	return uid, nil
+}
+
+// class returns the UID of the class dictionary for the given class hierarchy.
+func (a *archiver) class(classes ...string) UID {
+	if uid, ok := a.classes[classes[0]]; ok {
+		return uid
+	}
+	names := make([]interface{}, len(classes))
+	for i, name := range classes {
+		names[i] = name
+	}
+	uid := a.reserve(archiveKey{})
+	a.objects[uid] = map[string]interface{}{
+		"$classname": classes[0],
+		"$classes":   names,
+	}
+	a.classes[classes[0]] = uid
+	return uid
+}
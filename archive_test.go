@@ -0,0 +1,311 @@
+package plist
+
+import (
+	"bytes"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	date := time.Unix(1234567890, 0)
+	in := map[string]interface{}{
+		"string": "hello",
+		"number": int64(42),
+		"data":   []byte("bytes"),
+		"date":   date,
+		"array":  []interface{}{"a", "b", true},
+	}
+	data, err := Archive(in, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := out.(map[string]interface{})
+	if !ok {
+		t.Fatalf("got %T, want map[string]interface{}", out)
+	}
+	if got["string"] != "hello" || got["number"] != int64(42) {
+		t.Errorf("scalar mismatch: %#v", got)
+	}
+	if !reflect.DeepEqual(got["data"], []byte("bytes")) {
+		t.Errorf("data mismatch: %#v", got["data"])
+	}
+	if d, ok := got["date"].(time.Time); !ok || !d.Equal(date) {
+		t.Errorf("date mismatch: %#v", got["date"])
+	}
+	if !reflect.DeepEqual(got["array"], []interface{}{"a", "b", true}) {
+		t.Errorf("array mismatch: %#v", got["array"])
+	}
+}
+
+func TestArchiveSharedReferences(t *testing.T) {
+	shared := &ArchivedObject{Class: "Thing", Fields: map[string]interface{}{"n": int64(1)}}
+	data, err := Archive([]interface{}{shared, shared}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ary := out.([]interface{})
+	if len(ary) != 2 {
+		t.Fatalf("got %d elements, want 2", len(ary))
+	}
+	a, ok := ary[0].(*ArchivedObject)
+	if !ok {
+		t.Fatalf("got %T, want *ArchivedObject", ary[0])
+	}
+	if a != ary[1] {
+		t.Error("shared reference was decoded as two distinct objects")
+	}
+	if a.Class != "Thing" || a.Fields["n"] != int64(1) {
+		t.Errorf("got %#v", a)
+	}
+
+	// the shared object must only be archived once
+	var archive map[string]interface{}
+	if _, err := Unmarshal(data, &archive); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, obj := range archive["$objects"].([]interface{}) {
+		if dict, ok := obj.(map[string]interface{}); ok && dict["n"] != nil {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("shared object archived %d times, want 1", count)
+	}
+}
+
+func TestArchiveCycle(t *testing.T) {
+	node := &ArchivedObject{Class: "Node", Fields: map[string]interface{}{}}
+	children := []interface{}{node}
+	node.Fields["children"] = children
+	node.Fields["self"] = node
+
+	data, err := Archive(node, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := out.(*ArchivedObject)
+	if !ok {
+		t.Fatalf("got %T, want *ArchivedObject", out)
+	}
+	if got.Fields["self"] != got {
+		t.Error("self reference does not point to the decoded object")
+	}
+	kids, ok := got.Fields["children"].([]interface{})
+	if !ok || len(kids) != 1 || kids[0] != got {
+		t.Errorf("children do not point back to the decoded object: %#v", got.Fields["children"])
+	}
+}
+
+func TestUnarchiveNotArchive(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{"a": "b"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unarchive(data); err == nil {
+		t.Error("expected error for non-archive plist")
+	}
+}
+
+func TestArchiveFoundationBridging(t *testing.T) {
+	u, err := url.Parse("https://example.com/path?q=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuid := [16]byte{0xde, 0xad, 0xbe, 0xef, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	in := map[string]interface{}{
+		"set":   ArchivedSet{"a", "b"},
+		"url":   u,
+		"uuid":  uuid,
+		"range": Range{Location: 3, Length: 7},
+		"null":  nil,
+	}
+	data, err := Archive(in, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.(map[string]interface{})
+	if !reflect.DeepEqual(got["set"], ArchivedSet{"a", "b"}) {
+		t.Errorf("set: got %#v", got["set"])
+	}
+	if gotURL, ok := got["url"].(*url.URL); !ok || gotURL.String() != u.String() {
+		t.Errorf("url: got %#v", got["url"])
+	}
+	if got["uuid"] != uuid {
+		t.Errorf("uuid: got %#v", got["uuid"])
+	}
+	if got["range"] != (Range{3, 7}) {
+		t.Errorf("range: got %#v", got["range"])
+	}
+	if v, ok := got["null"]; !ok || v != nil {
+		t.Errorf("null: got %#v, %v", v, ok)
+	}
+}
+
+func TestUnarchiverAllowClasses(t *testing.T) {
+	obj := &ArchivedObject{Class: "Evil", Fields: map[string]interface{}{}}
+	data, err := Archive([]interface{}{"ok", obj}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := NewUnarchiver(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.AllowClasses("NSArray")
+	_, err = u.Decode()
+	if e, ok := err.(*DisallowedClassError); !ok || e.Class != "Evil" {
+		t.Errorf("got error %#v, want DisallowedClassError for Evil", err)
+	}
+
+	u, err = NewUnarchiver(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.AllowClasses("NSArray", "Evil")
+	if _, err := u.Decode(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUnarchiveAppKitTypes(t *testing.T) {
+	red := &ArchivedObject{Class: "NSColor", Fields: map[string]interface{}{
+		"NSColorSpace": 1,
+		"NSRGB":        []byte("1 0 0\x00"),
+	}}
+	font := &ArchivedObject{Class: "NSFont", Fields: map[string]interface{}{
+		"NSName":   "Helvetica",
+		"NSSize":   12.0,
+		"NSfFlags": 16,
+	}}
+	str := &ArchivedObject{Class: "NSAttributedString", Fields: map[string]interface{}{
+		"NSString": "héllo w\U0001F600rld",
+		"NSAttributes": []interface{}{
+			map[string]interface{}{"NSFont": font},
+			map[string]interface{}{"NSColor": red},
+		},
+		// runs of 6 UTF-16 units each, using attributes 0 and 1
+		"NSAttributeInfo": []byte{6, 0, 6, 1},
+	}}
+	data, err := Archive(str, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unarchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := out.(AttributedString)
+	if !ok {
+		t.Fatalf("got %T, want AttributedString", out)
+	}
+	if len(got.Runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(got.Runs))
+	}
+	if s := got.Substring(got.Runs[0].Range); s != "héllo " {
+		t.Errorf("first run: got %q", s)
+	}
+	if s := got.Substring(got.Runs[1].Range); s != "w\U0001F600rld" {
+		t.Errorf("second run: got %q", s)
+	}
+	wantFont := Font{Name: "Helvetica", Size: 12}
+	if f, ok := got.Runs[0].Attributes["NSFont"].(Font); !ok || !reflect.DeepEqual(f, wantFont) {
+		t.Errorf("font: got %#v", got.Runs[0].Attributes["NSFont"])
+	}
+	wantColor := Color{Space: CalibratedRGBColorSpace, Components: []float64{1, 0, 0, 1}}
+	if c, ok := got.Runs[1].Attributes["NSColor"].(Color); !ok || !reflect.DeepEqual(c, wantColor) {
+		t.Errorf("color: got %#v", got.Runs[1].Attributes["NSColor"])
+	}
+
+	// the runs must cover exactly the 12 UTF-16 units of the string
+	for _, info := range [][]byte{{6, 0, 8, 1}, {6, 0, 5, 1}, {13, 0}} {
+		str.Fields["NSAttributeInfo"] = info
+		data, err := Archive(str, BinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Unarchive(data); err == nil {
+			t.Errorf("NSAttributeInfo %v: expected an error", info)
+		}
+	}
+}
+
+func TestArchiveReproducible(t *testing.T) {
+	obj := &ArchivedObject{Class: "Thing", Fields: map[string]interface{}{}}
+	m := map[string]interface{}{}
+	roots := map[string]interface{}{}
+	for i := 0; i < 20; i++ {
+		name := strconv.Itoa(i)
+		obj.Fields["field"+name] = "value" + name
+		m["key"+name] = []interface{}{"element" + name}
+		roots["root"+name] = "string" + name
+	}
+	roots["object"] = obj
+	roots["map"] = m
+	first, err := ArchiveRoots(roots, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		data, err := ArchiveRoots(roots, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, first) {
+			t.Fatalf("archive #%d differs from the first", i+1)
+		}
+	}
+}
+
+func TestArchiveMultipleRoots(t *testing.T) {
+	shared := []interface{}{"shared"}
+	data, err := ArchiveRoots(map[string]interface{}{
+		"first":  shared,
+		"second": map[string]interface{}{"list": shared},
+	}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := NewUnarchiver(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roots := u.Roots(); !reflect.DeepEqual(roots, []string{"first", "second"}) {
+		t.Errorf("got roots %v", roots)
+	}
+	first, err := u.DecodeRoot("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := u.DecodeRoot("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := second.(map[string]interface{})["list"].([]interface{})
+	if &list[0] != &first.([]interface{})[0] {
+		t.Error("object shared between roots was decoded twice")
+	}
+	if _, err := u.Decode(); err == nil {
+		t.Error("expected error decoding missing root")
+	}
+}
@@ -0,0 +1,299 @@
+package plist
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// classDecoders maps archived class names to functions that decode them into
+// ready-made Go representations. These are consulted after the bridged
+// Foundation classes and before falling back to *ArchivedObject.
+var classDecoders map[string]func(u *Unarchiver, dict map[string]interface{}) (interface{}, error)
+
+func init() {
+	// this is populated in init() because the decoders refer back to it
+	classDecoders = map[string]func(u *Unarchiver, dict map[string]interface{}) (interface{}, error){
+		"NSAttributedString":        decodeAttributedString,
+		"NSMutableAttributedString": decodeAttributedString,
+		"NSColor":                   decodeColor,
+		"UIColor":                   decodeColor,
+		"NSFont":                    decodeFont,
+		"UIFont":                    decodeFont,
+		"NSFontDescriptor":          decodeFontDescriptor,
+		"UIFontDescriptor":          decodeFontDescriptor,
+	}
+}
+
+// An AttributedString is the decoded form of an NSAttributedString.
+type AttributedString struct {
+	String string
+	Runs   []AttributeRun
+}
+
+// An AttributeRun is a range of an AttributedString that shares the same
+// attributes. The range is measured in UTF-16 code units, like NSRange.
+type AttributeRun struct {
+	Range      Range
+	Attributes map[string]interface{}
+}
+
+// Substring returns the portion of s.String covered by r, which is measured in
+// UTF-16 code units. The range is clamped to the bounds of the string.
+func (s AttributedString) Substring(r Range) string {
+	units := utf16.Encode([]rune(s.String))
+	start, end := r.Location, r.Location+r.Length
+	if start < 0 {
+		start = 0
+	}
+	if end > int64(len(units)) {
+		end = int64(len(units))
+	}
+	if start >= end {
+		return ""
+	}
+	return string(utf16.Decode(units[start:end]))
+}
+
+func decodeAttributedString(u *Unarchiver, dict map[string]interface{}) (interface{}, error) {
+	str, err := u.decodeValue(dict["NSString"])
+	if err != nil {
+		return nil, err
+	}
+	result := AttributedString{}
+	result.String, _ = str.(string)
+	attrs, err := u.decodeValue(dict["NSAttributes"])
+	if err != nil {
+		return nil, err
+	}
+	length := int64(len(utf16.Encode([]rune(result.String))))
+	if length == 0 {
+		return result, nil
+	}
+	info, err := u.decodeValue(dict["NSAttributeInfo"])
+	if err != nil {
+		return nil, err
+	}
+	infoData, _ := info.([]byte)
+	if infoData == nil {
+		// a single set of attributes covers the whole string
+		m, _ := attrs.(map[string]interface{})
+		result.Runs = []AttributeRun{{Range{0, length}, m}}
+		return result, nil
+	}
+	// NSAttributeInfo is a sequence of varint pairs: the length of the run
+	// and the index of its attributes in the NSAttributes array.
+	attrList, _ := attrs.([]interface{})
+	var location int64
+	for len(infoData) > 0 {
+		runLength, n := binary.Uvarint(infoData)
+		if n <= 0 {
+			return nil, errors.New("plist: keyed archive NSAttributedString has invalid NSAttributeInfo")
+		}
+		infoData = infoData[n:]
+		index, n := binary.Uvarint(infoData)
+		if n <= 0 || index >= uint64(len(attrList)) {
+			return nil, errors.New("plist: keyed archive NSAttributedString has invalid NSAttributeInfo")
+		}
+		infoData = infoData[n:]
+		if runLength > uint64(length-location) {
+			return nil, errors.New("plist: keyed archive NSAttributedString has runs past the end of the string")
+		}
+		m, _ := attrList[index].(map[string]interface{})
+		result.Runs = append(result.Runs, AttributeRun{Range{location, int64(runLength)}, m})
+		location += int64(runLength)
+	}
+	if location != length {
+		return nil, errors.New("plist: keyed archive NSAttributedString runs don't cover the string")
+	}
+	return result, nil
+}
+
+// A ColorSpace identifies the color space of an archived NSColor. The values
+// match the NSColorSpace key of the archive.
+type ColorSpace int
+
+const (
+	CalibratedRGBColorSpace   ColorSpace = 1
+	DeviceRGBColorSpace       ColorSpace = 2
+	CalibratedWhiteColorSpace ColorSpace = 3
+	DeviceWhiteColorSpace     ColorSpace = 4
+	DeviceCMYKColorSpace      ColorSpace = 5
+	NamedColorSpace           ColorSpace = 6
+)
+
+func (c ColorSpace) String() string {
+	switch c {
+	case CalibratedRGBColorSpace:
+		return "CalibratedRGB"
+	case DeviceRGBColorSpace:
+		return "DeviceRGB"
+	case CalibratedWhiteColorSpace:
+		return "CalibratedWhite"
+	case DeviceWhiteColorSpace:
+		return "DeviceWhite"
+	case DeviceCMYKColorSpace:
+		return "DeviceCMYK"
+	case NamedColorSpace:
+		return "Named"
+	}
+	return "ColorSpace(" + strconv.Itoa(int(c)) + ")"
+}
+
+// A Color is the decoded form of an NSColor or UIColor.
+//
+// Components holds the color components in the order of the color space
+// (red, green, blue; white; or cyan, magenta, yellow, black) followed by
+// alpha. Named colors have no components of their own, but may carry the
+// color they were resolved to when archived in Fallback.
+type Color struct {
+	Space      ColorSpace
+	Components []float64
+	Catalog    string // catalog of a named color
+	Name       string // name of a named color
+	Fallback   *Color
+}
+
+func decodeColor(u *Unarchiver, dict map[string]interface{}) (interface{}, error) {
+	if _, ok := dict["NSColorSpace"]; !ok {
+		return decodeUIColor(dict)
+	}
+	space, _ := archivedFloat(dict["NSColorSpace"])
+	result := Color{Space: ColorSpace(space)}
+	var key string
+	var count int
+	switch result.Space {
+	case CalibratedRGBColorSpace, DeviceRGBColorSpace:
+		key, count = "NSRGB", 3
+	case CalibratedWhiteColorSpace, DeviceWhiteColorSpace:
+		key, count = "NSWhite", 1
+	case DeviceCMYKColorSpace:
+		key, count = "NSCMYK", 4
+	case NamedColorSpace:
+		for _, s := range []struct {
+			key string
+			dst *string
+		}{{"NSCatalogName", &result.Catalog}, {"NSColorName", &result.Name}} {
+			val, err := u.decodeValue(dict[s.key])
+			if err != nil {
+				return nil, err
+			}
+			*s.dst, _ = val.(string)
+		}
+		fallback, err := u.decodeValue(dict["NSColor"])
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := fallback.(Color); ok {
+			result.Fallback = &c
+		}
+		return result, nil
+	default:
+		// pattern images and custom color spaces aren't supported
+		return nil, errors.New("plist: keyed archive NSColor has unsupported color space " + result.Space.String())
+	}
+	val, err := u.decodeValue(dict[key])
+	if err != nil {
+		return nil, err
+	}
+	data, _ := val.([]byte)
+	components, err := parseColorComponents(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) == count {
+		components = append(components, 1)
+	}
+	if len(components) != count+1 {
+		return nil, errors.New("plist: keyed archive NSColor has the wrong number of components in " + key)
+	}
+	result.Components = components
+	return result, nil
+}
+
+// parseColorComponents parses the NUL-terminated, space-separated list of
+// numbers that NSColor archives its components as.
+func parseColorComponents(data []byte) ([]float64, error) {
+	str := string(data)
+	if i := strings.IndexByte(str, 0); i >= 0 {
+		str = str[:i]
+	}
+	fields := strings.Fields(str)
+	components := make([]float64, len(fields))
+	for i, field := range fields {
+		f, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		components[i] = f
+	}
+	return components, nil
+}
+
+func decodeUIColor(dict map[string]interface{}) (interface{}, error) {
+	alpha, ok := archivedFloat(dict["UIAlpha"])
+	if !ok {
+		alpha = 1
+	}
+	if white, ok := archivedFloat(dict["UIWhite"]); ok {
+		return Color{Space: DeviceWhiteColorSpace, Components: []float64{white, alpha}}, nil
+	}
+	red, ok1 := archivedFloat(dict["UIRed"])
+	green, ok2 := archivedFloat(dict["UIGreen"])
+	blue, ok3 := archivedFloat(dict["UIBlue"])
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("plist: keyed archive UIColor has no components")
+	}
+	return Color{Space: DeviceRGBColorSpace, Components: []float64{red, green, blue, alpha}}, nil
+}
+
+// A Font is the decoded form of an NSFont, UIFont, NSFontDescriptor or
+// UIFontDescriptor. Attributes holds the font descriptor attributes, if any
+// were archived.
+type Font struct {
+	Name       string
+	Size       float64
+	Attributes map[string]interface{}
+}
+
+func decodeFont(u *Unarchiver, dict map[string]interface{}) (interface{}, error) {
+	result := Font{}
+	nameKey, sizeKey := "NSName", "NSSize"
+	if _, ok := dict["UIFontName"]; ok {
+		nameKey, sizeKey = "UIFontName", "UIFontPointSize"
+	}
+	name, err := u.decodeValue(dict[nameKey])
+	if err != nil {
+		return nil, err
+	}
+	result.Name, _ = name.(string)
+	result.Size, _ = archivedFloat(dict[sizeKey])
+	if desc, ok := dict["UIFontDescriptor"]; ok {
+		val, err := u.decodeValue(desc)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := val.(Font); ok {
+			result.Attributes = f.Attributes
+		}
+	}
+	return result, nil
+}
+
+func decodeFontDescriptor(u *Unarchiver, dict map[string]interface{}) (interface{}, error) {
+	key := "NSFontDescriptorAttributes"
+	if _, ok := dict["UIFontDescriptorAttributes"]; ok {
+		key = "UIFontDescriptorAttributes"
+	}
+	val, err := u.decodeValue(dict[key])
+	if err != nil {
+		return nil, err
+	}
+	attrs, _ := val.(map[string]interface{})
+	result := Font{Attributes: attrs}
+	result.Name, _ = attrs["NSFontNameAttribute"].(string)
+	result.Size, _ = archivedFloat(attrs["NSFontSizeAttribute"])
+	return result, nil
+}
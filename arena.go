@@ -0,0 +1,219 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+const (
+	arenaChunkSize = 64 << 10 // bytes of string and data per chunk
+	arenaSlabSize  = 4096     // array elements per slab
+)
+
+// An Arena provides the memory for the strings, data, arrays and dictionaries
+// of property lists decoded into empty interfaces by a Decoder that uses it.
+// Instead of allocating each value separately, the Arena hands out pieces of
+// larger blocks, and keeps the maps it creates, so that decoding many small
+// property lists allocates little once the Arena has grown to size.
+//
+// Reset releases everything at once, making the memory available to later
+// decoding. Values decoded with an Arena, including any strings, slices or
+// maps taken from them, must not be used after Reset, since their memory is
+// then reused; copy whatever needs to outlive the Arena first.
+//
+// The zero value is an empty Arena ready to use. An Arena may be used by
+// several Decoders, but should not be Reset while any of them is decoding.
+type Arena struct {
+	mu     sync.Mutex
+	chunks [][]byte
+	chunk  int // index of the chunk in use
+	used   int // bytes of chunks[chunk] handed out
+	slabs  [][]interface{}
+	slab   int
+	filled int // elements of slabs[slab] handed out
+	maps   []map[string]interface{}
+	nmaps  int // maps handed out
+}
+
+// Reset releases all the values decoded with a.
+func (a *Arena) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := 0; i <= a.slab && i < len(a.slabs); i++ {
+		// drop the references to values that weren't allocated from a
+		clear(a.slabs[i])
+	}
+	for _, m := range a.maps[:a.nmaps] {
+		clear(m)
+	}
+	a.chunk, a.used = 0, 0
+	a.slab, a.filled = 0, 0
+	a.nmaps = 0
+}
+
+// bytes returns n bytes of memory. Large requests get their own allocation.
+func (a *Arena) bytes(n int) []byte {
+	if n > arenaChunkSize/4 {
+		return make([]byte, n)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chunk < len(a.chunks) && a.used+n > arenaChunkSize {
+		a.chunk++
+		a.used = 0
+	}
+	if a.chunk == len(a.chunks) {
+		a.chunks = append(a.chunks, make([]byte, arenaChunkSize))
+	}
+	b := a.chunks[a.chunk][a.used : a.used+n : a.used+n]
+	a.used += n
+	return b
+}
+
+// slice returns an []interface{} of length n.
+func (a *Arena) slice(n int) []interface{} {
+	if n > arenaSlabSize/4 {
+		return make([]interface{}, n)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.slab < len(a.slabs) && a.filled+n > arenaSlabSize {
+		a.slab++
+		a.filled = 0
+	}
+	if a.slab == len(a.slabs) {
+		a.slabs = append(a.slabs, make([]interface{}, arenaSlabSize))
+	}
+	s := a.slabs[a.slab][a.filled : a.filled+n : a.filled+n]
+	a.filled += n
+	return s
+}
+
+// newMap returns an empty map, reusing one released by Reset if possible.
+func (a *Arena) newMap(n int) map[string]interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.nmaps == len(a.maps) {
+		a.maps = append(a.maps, make(map[string]interface{}, n))
+	}
+	m := a.maps[a.nmaps]
+	a.nmaps++
+	return m
+}
+
+// string converts cfStr to a string whose bytes are in a.
+func (a *Arena) string(cfStr C.CFStringRef) string {
+	length := C.CFStringGetLength(cfStr)
+	if length == 0 {
+		return ""
+	}
+	cfRange := C.CFRange{0, length}
+	enc := C.CFStringEncoding(C.kCFStringEncodingUTF8)
+	var usedBufLen C.CFIndex
+	if C.CFStringGetBytes(cfStr, cfRange, enc, 0, C.false, nil, 0, &usedBufLen) == 0 || usedBufLen == 0 {
+		return ""
+	}
+	b := a.bytes(int(usedBufLen))
+	C.CFStringGetBytes(cfStr, cfRange, enc, 0, C.false, (*C.UInt8)(unsafe.Pointer(&b[0])), usedBufLen, nil)
+	return unsafe.String(&b[0], len(b))
+}
+
+// data copies the contents of cfData into a.
+func (a *Arena) data(cfData C.CFDataRef) []byte {
+	n := int(C.CFDataGetLength(cfData))
+	b := a.bytes(n)
+	if n > 0 {
+		C.CFDataGetBytes(cfData, C.CFRange{0, C.CFIndex(n)}, (*C.UInt8)(unsafe.Pointer(&b[0])))
+	}
+	return b
+}
+
+// useArena reports whether values decoded into an empty interface come from
+// the arena. Ordered dictionaries and nested property lists are decoded as
+// usual.
+func (state *unmarshalState) useArena() bool {
+	return state.arena != nil && !state.orderedDicts && !state.nestedPlists
+}
+
+// arenaValue converts cfObj to the value unmarshalValue would store in an
+// empty interface, allocating from the arena.
+func (state *unmarshalState) arenaValue(cfObj cfTypeRef) (interface{}, error) {
+	if err := state.checkContext(); err != nil {
+		return nil, err
+	}
+	a := state.arena
+	typeID := C.CFGetTypeID(C.CFTypeRef(cfObj))
+	switch typeID {
+	case cfStringTypeID:
+		return a.string(C.CFStringRef(cfObj)), nil
+	case cfDataTypeID:
+		return a.data(C.CFDataRef(cfObj)), nil
+	case cfArrayTypeID:
+		cfArray := C.CFArrayRef(cfObj)
+		count := int(C.CFArrayGetCount(cfArray))
+		if count == 0 {
+			// like unmarshalValue, which never makes the slice
+			return []interface{}(nil), nil
+		}
+		s := a.slice(count)
+		elems, base := state.getScratch(count)
+		defer func() { state.scratch = state.scratch[:base] }()
+		C.CFArrayGetValues(cfArray, C.CFRange{0, C.CFIndex(count)}, (*unsafe.Pointer)(&elems[0]))
+		for i, elem := range elems {
+			val, err := state.arenaValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = val
+		}
+		return s, nil
+	case cfDictionaryTypeID:
+		cfDict := C.CFDictionaryRef(cfObj)
+		count := int(C.CFDictionaryGetCount(cfDict))
+		m := a.newMap(count)
+		if count == 0 {
+			return m, nil
+		}
+		refs, base := state.getScratch(2 * count)
+		defer func() { state.scratch = state.scratch[:base] }()
+		cfKeys, cfVals := refs[:count], refs[count:]
+		C.CFDictionaryGetKeysAndValues(cfDict, (*unsafe.Pointer)(&cfKeys[0]), (*unsafe.Pointer)(&cfVals[0]))
+		for i, cfKey := range cfKeys {
+			var key string
+			if C.CFGetTypeID(C.CFTypeRef(cfKey)) == cfStringTypeID {
+				key = a.string(C.CFStringRef(cfKey))
+			} else {
+				var err error
+				if key, err = convertCFKeyToString(cfKey, state.stringifyKeys); err != nil {
+					return nil, err
+				}
+			}
+			if _, dup := m[key]; dup {
+				// only possible for stringified keys
+				return nil, &DuplicateKeyError{key}
+			}
+			val, err := state.arenaValue(cfVals[i])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case cfBooleanTypeID:
+		return convertCFBooleanToBool(C.CFBooleanRef(cfObj)), nil
+	case cfDateTypeID:
+		return state.convertCFDate(C.CFDateRef(cfObj)), nil
+	case cfNumberTypeID:
+		cfNumber := C.CFNumberRef(cfObj)
+		if C.CFNumberIsFloatType(cfNumber) != C.false {
+			return convertCFNumberToFloat64(cfNumber), nil
+		}
+		return convertCFNumberToInt64(cfNumber), nil
+	case cfKeyedArchiverUIDTypeID:
+		return convertCFKeyedArchiverUIDToUID(cfObj), nil
+	}
+	return nil, &UnknownCFTypeError{typeID}
+}
@@ -0,0 +1,62 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArena(t *testing.T) {
+	in := map[string]interface{}{
+		"string": "hello",
+		"long":   strings.Repeat("x", arenaChunkSize),
+		"data":   []byte("data"),
+		"array":  []interface{}{"a", int64(1), 2.5, true, []interface{}{}},
+		"dict":   map[string]interface{}{"date": time.Unix(1234567890, 0), "empty": map[string]interface{}{}},
+		"uid":    UID(3),
+	}
+	var a Arena
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(in, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want interface{}
+		if _, err := Unmarshal(data, &want); err != nil {
+			t.Fatal(err)
+		}
+		var firstMap uintptr
+		for i := 0; i < 2; i++ {
+			dec := NewDecoder(bytes.NewReader(data))
+			dec.UseArena(&a)
+			var got interface{}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%v: got %#v, want %#v", format, got, want)
+			}
+			ptr := reflect.ValueOf(got).Pointer()
+			if i == 0 {
+				firstMap = ptr
+			} else if ptr != firstMap {
+				t.Errorf("%v: the map wasn't reused after Reset", format)
+			}
+			a.Reset()
+		}
+
+		// map[string]interface{} values come from the arena too
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.UseArena(&a)
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, want) {
+			t.Errorf("%v: got %#v, want %#v", format, m, want)
+		}
+		a.Reset()
+	}
+}
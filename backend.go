@@ -0,0 +1,63 @@
+package plist
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+// A Backend selects the system API used to parse and serialize property list
+// data. Both backends produce the same CoreFoundation objects, so the choice
+// only affects parsing and serialization behavior and error reporting.
+type Backend int32
+
+const (
+	// CoreFoundationBackend uses CFPropertyListCreateWithData and
+	// CFPropertyListCreateData. It is the default.
+	CoreFoundationBackend Backend = iota
+	// FoundationBackend uses NSPropertyListSerialization. It is only
+	// available when built with the plist_foundation build tag, which
+	// compiles the package as Objective-C and links Foundation, and is then
+	// the default.
+	FoundationBackend
+)
+
+var backend int32 = int32(defaultBackend)
+
+var errNoFoundation = errors.New("plist: the Foundation backend requires the plist_foundation build tag")
+
+// SetBackend selects the backend used by all subsequent calls that parse or
+// serialize property list data. It is safe to call concurrently with those
+// calls, which each use the backend selected when they start. It returns an
+// error, and leaves the backend unchanged, if b isn't available in this
+// build.
+func SetBackend(b Backend) error {
+	switch b {
+	case CoreFoundationBackend:
+	case FoundationBackend:
+		if !foundationAvailable {
+			return errNoFoundation
+		}
+	default:
+		return errors.New("plist: unknown backend " + strconv.Itoa(int(b)))
+	}
+	atomic.StoreInt32(&backend, int32(b))
+	return nil
+}
+
+// CurrentBackend returns the backend selected by SetBackend, or the build's
+// default if SetBackend has not been called.
+func CurrentBackend() Backend {
+	return Backend(atomic.LoadInt32(&backend))
+}
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	switch b {
+	case CoreFoundationBackend:
+		return "CoreFoundation"
+	case FoundationBackend:
+		return "Foundation"
+	}
+	return "unknown backend"
+}
@@ -0,0 +1,25 @@
+//go:build !plist_foundation
+// +build !plist_foundation
+
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+const defaultBackend = CoreFoundationBackend
+
+// foundationAvailable is false because the Foundation backend, which is
+// compiled as Objective-C and links Foundation, is only built with the
+// plist_foundation build tag.
+const foundationAvailable = false
+
+// These are never called, since SetBackend doesn't select FoundationBackend
+// without the build tag.
+
+func foundationPropertyListCreateWithCFData(cfData C.CFDataRef) (cfObj cfTypeRef, format Format, err error) {
+	return nil, Format{}, errNoFoundation
+}
+
+func appendFoundationPropertyListData(dst []byte, plist cfTypeRef, format Format) ([]byte, error) {
+	return nil, errNoFoundation
+}
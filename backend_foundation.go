@@ -0,0 +1,9 @@
+//go:build plist_foundation
+// +build plist_foundation
+
+package plist
+
+const defaultBackend = FoundationBackend
+
+// foundationAvailable is true because foundation.go is built with this tag.
+const foundationAvailable = true
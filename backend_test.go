@@ -0,0 +1,62 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBackends(t *testing.T) {
+	defer SetBackend(CurrentBackend())
+	in := map[string]interface{}{
+		"string": "hello",
+		"number": int64(42),
+		"array":  []interface{}{"a", true},
+	}
+	for _, b := range []Backend{CoreFoundationBackend, FoundationBackend} {
+		if err := SetBackend(b); err != nil {
+			if b == FoundationBackend && !foundationAvailable {
+				continue
+			}
+			t.Fatalf("SetBackend(%v): %v", b, err)
+		}
+		for _, format := range []Format{XMLFormat, BinaryFormat} {
+			data, err := Marshal(in, format)
+			if err != nil {
+				t.Errorf("%v: %v", b, err)
+				continue
+			}
+			var out interface{}
+			gotFormat, err := Unmarshal(data, &out)
+			if err != nil {
+				t.Errorf("%v: %v", b, err)
+				continue
+			}
+			if gotFormat != format {
+				t.Errorf("%v: got format %v, want %v", b, gotFormat, format)
+			}
+			if !reflect.DeepEqual(out, in) {
+				t.Errorf("%v: got %#v, want %#v", b, out, in)
+			}
+		}
+		var out interface{}
+		if _, err := Unmarshal([]byte("<plist><dict>"), &out); err == nil {
+			t.Errorf("%v: expected error for malformed plist", b)
+		} else if _, ok := err.(*CFError); !ok {
+			t.Errorf("%v: got %T, want *CFError", b, err)
+		}
+	}
+}
+
+func TestSetBackendUnavailable(t *testing.T) {
+	defer SetBackend(CurrentBackend())
+	before := CurrentBackend()
+	if err := SetBackend(Backend(7)); err == nil {
+		t.Error("SetBackend accepted an unknown backend")
+	}
+	if err := SetBackend(FoundationBackend); (err == nil) != foundationAvailable {
+		t.Errorf("SetBackend(FoundationBackend) = %v with foundationAvailable = %v", err, foundationAvailable)
+	}
+	if !foundationAvailable && CurrentBackend() != before {
+		t.Errorf("a failed SetBackend changed the backend to %v", CurrentBackend())
+	}
+}
@@ -0,0 +1,191 @@
+// Package backup decodes the metadata property lists of iOS device backups
+// made by iTunes and Finder: Manifest.plist, Info.plist and Status.plist.
+//
+// The backed up files themselves are indexed by Manifest.db, a SQLite
+// database, which this package doesn't read.
+package backup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A Manifest is the decoded Manifest.plist of a backup.
+type Manifest struct {
+	Version              string
+	Date                 time.Time
+	IsEncrypted          bool
+	WasPasscodeSet       bool
+	SystemDomainsVersion string
+	BackupKeyBag         []byte
+	ManifestKey          []byte // the wrapped key of Manifest.db, in encrypted backups
+	Lockdown             Lockdown
+	Applications         map[string]ManifestApplication
+
+	// lockdown holds all of the Lockdown keys, for LockdownValue
+	lockdown map[string]interface{} `plist:"-"`
+}
+
+// Lockdown holds the device properties recorded by lockdownd at the time of
+// the backup.
+type Lockdown struct {
+	BuildVersion   string
+	DeviceName     string
+	ProductType    string
+	ProductVersion string
+	SerialNumber   string
+	UniqueDeviceID string
+}
+
+// A ManifestApplication describes an app whose data is in the backup.
+type ManifestApplication struct {
+	CFBundleIdentifier    string
+	CFBundleVersion       string
+	ContainerContentClass string
+	Path                  string
+}
+
+// LockdownValue returns the value of the given key of the Lockdown
+// dictionary, including keys Lockdown has no field for. Values stored as data
+// holding a keyed archive or a property list are decoded; a keyed archive is
+// decoded with plist.Unarchive.
+func (m *Manifest) LockdownValue(key string) (interface{}, error) {
+	v, ok := m.lockdown[key]
+	if !ok {
+		return nil, nil
+	}
+	data, ok := v.([]byte)
+	if !ok {
+		return v, nil
+	}
+	if bytes.Contains(data, []byte("NSKeyedArchiver")) {
+		if obj, err := plist.Unarchive(data); err == nil {
+			return obj, nil
+		}
+	}
+	var nested interface{}
+	if _, err := plist.Unmarshal(data, &nested); err == nil {
+		return nested, nil
+	}
+	return data, nil
+}
+
+// ParseManifest parses the contents of a Manifest.plist.
+func ParseManifest(data []byte) (*Manifest, error) {
+	m := new(Manifest)
+	if _, err := plist.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Lockdown map[string]interface{}
+	}
+	if _, err := plist.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	m.lockdown = raw.Lockdown
+	return m, nil
+}
+
+// An Info is the decoded Info.plist of a backup, written by iTunes or Finder
+// rather than the device.
+type Info struct {
+	BuildVersion          string                     `plist:"Build Version"`
+	DeviceName            string                     `plist:"Device Name"`
+	DisplayName           string                     `plist:"Display Name"`
+	GUID                  string                     `plist:"GUID"`
+	ICCID                 string                     `plist:"ICCID,omitempty"`
+	IMEI                  string                     `plist:"IMEI,omitempty"`
+	MEID                  string                     `plist:"MEID,omitempty"`
+	PhoneNumber           string                     `plist:"Phone Number,omitempty"`
+	LastBackupDate        time.Time                  `plist:"Last Backup Date"`
+	ProductName           string                     `plist:"Product Name"`
+	ProductType           string                     `plist:"Product Type"`
+	ProductVersion        string                     `plist:"Product Version"`
+	SerialNumber          string                     `plist:"Serial Number"`
+	TargetIdentifier      string                     `plist:"Target Identifier"`
+	TargetType            string                     `plist:"Target Type"`
+	UniqueIdentifier      string                     `plist:"Unique Identifier"`
+	ITunesVersion         string                     `plist:"iTunes Version"`
+	InstalledApplications []string                   `plist:"Installed Applications,omitempty"`
+	Applications          map[string]InfoApplication `plist:"Applications,omitempty"`
+}
+
+// An InfoApplication describes an installed app in a backup's Info.plist.
+type InfoApplication struct {
+	ApplicationSINF []byte `plist:",omitempty"`
+	PlaceholderIcon []byte `plist:",omitempty"`
+	// ITunesMetadata is the App Store metadata of the app, which is stored
+	// as an embedded property list.
+	ITunesMetadata map[string]interface{} `plist:"iTunesMetadata,omitempty"`
+}
+
+// ParseInfo parses the contents of a backup's Info.plist.
+func ParseInfo(data []byte) (*Info, error) {
+	info := new(Info)
+	dec := plist.NewDecoder(bytes.NewReader(data))
+	dec.DecodeNestedPlists()
+	if err := dec.Decode(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// A Status is the decoded Status.plist of a backup, which records whether the
+// backup completed.
+type Status struct {
+	BackupState   string // "new" or "empty"
+	SnapshotState string // "finished" once the backup is complete
+	IsFullBackup  bool
+	Date          time.Time
+	UUID          string
+	Version       string
+}
+
+// ParseStatus parses the contents of a backup's Status.plist.
+func ParseStatus(data []byte) (*Status, error) {
+	s := new(Status)
+	if _, err := plist.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// A Backup is the decoded metadata of a backup directory.
+type Backup struct {
+	Dir      string
+	Manifest *Manifest
+	Info     *Info
+	Status   *Status
+}
+
+// Open reads the metadata of the backup in dir, a directory named after the
+// device's UDID, usually in ~/Library/Application Support/MobileSync/Backup.
+func Open(dir string) (*Backup, error) {
+	b := &Backup{Dir: dir}
+	for _, f := range []struct {
+		name  string
+		parse func([]byte) error
+	}{
+		{"Manifest.plist", func(data []byte) (err error) { b.Manifest, err = ParseManifest(data); return }},
+		{"Info.plist", func(data []byte) (err error) { b.Info, err = ParseInfo(data); return }},
+		{"Status.plist", func(data []byte) (err error) { b.Status, err = ParseStatus(data); return }},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, f.name))
+		if err != nil {
+			return nil, err
+		}
+		if err := f.parse(data); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Complete reports whether the backup finished.
+func (b *Backup) Complete() bool {
+	return b.Status != nil && b.Status.SnapshotState == "finished"
+}
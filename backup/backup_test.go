@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	archived, err := plist.Archive(map[string]interface{}{"Enabled": true}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := plist.Marshal(map[string]interface{}{"itemName": "Example", "artistName": "Example Inc."}, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]interface{}{
+		"Manifest.plist": map[string]interface{}{
+			"Version":     "10.0",
+			"Date":        date,
+			"IsEncrypted": false,
+			"Lockdown": map[string]interface{}{
+				"DeviceName":              "iPhone",
+				"ProductVersion":          "17.4",
+				"UniqueDeviceID":          "00008030-001A2B3C4D5E6F70",
+				"com.apple.Accessibility": archived,
+			},
+			"Applications": map[string]interface{}{
+				"com.example.app": map[string]interface{}{"CFBundleIdentifier": "com.example.app", "Path": "/var/containers/Bundle/Application/X/Example.app"},
+			},
+		},
+		"Info.plist": map[string]interface{}{
+			"Device Name":      "iPhone",
+			"Last Backup Date": date,
+			"Product Type":     "iPhone12,1",
+			"Applications": map[string]interface{}{
+				"com.example.app": map[string]interface{}{"iTunesMetadata": metadata},
+			},
+		},
+		"Status.plist": map[string]interface{}{
+			"SnapshotState": "finished",
+			"IsFullBackup":  true,
+			"Date":          date,
+		},
+	} {
+		if err := plist.MarshalToFile(filepath.Join(dir, name), v, plist.BinaryFormat); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !b.Complete() {
+		t.Error("backup is not complete")
+	}
+	if b.Manifest.Lockdown.ProductVersion != "17.4" || !b.Manifest.Date.Equal(date) {
+		t.Errorf("Manifest: got %#v", b.Manifest)
+	}
+	if app := b.Manifest.Applications["com.example.app"]; app.CFBundleIdentifier != "com.example.app" {
+		t.Errorf("Manifest.Applications: got %#v", b.Manifest.Applications)
+	}
+	accessibility, err := b.Manifest.LockdownValue("com.apple.Accessibility")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(accessibility, map[string]interface{}{"Enabled": true}) {
+		t.Errorf("LockdownValue: got %#v", accessibility)
+	}
+	if b.Info.DeviceName != "iPhone" || b.Info.ProductType != "iPhone12,1" {
+		t.Errorf("Info: got %#v", b.Info)
+	}
+	if name := b.Info.Applications["com.example.app"].ITunesMetadata["itemName"]; name != "Example" {
+		t.Errorf("iTunesMetadata: got %#v", b.Info.Applications["com.example.app"])
+	}
+}
@@ -0,0 +1,224 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+// #include <stdlib.h>
+//
+// // goplist_createNumberArray creates a CFArray of count CFNumbers of the
+// // given type, reading each value from size bytes of values.
+// static CFArrayRef goplist_createNumberArray(CFNumberType type, const void *values, size_t size, CFIndex count) {
+// 	CFTypeRef *objs = malloc(sizeof(CFTypeRef) * count);
+// 	if (objs == NULL) {
+// 		return NULL;
+// 	}
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		objs[i] = CFNumberCreate(NULL, type, (const char *)values + i*size);
+// 	}
+// 	CFArrayRef array = CFArrayCreate(NULL, objs, count, &kCFTypeArrayCallBacks);
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		CFRelease(objs[i]);
+// 	}
+// 	free(objs);
+// 	return array;
+// }
+//
+// // goplist_createStringArray creates a CFArray of count CFStrings from the
+// // concatenated UTF-8 bytes of the strings and their lengths. It returns NULL
+// // if a string can't be created.
+// static CFArrayRef goplist_createStringArray(const UInt8 *bytes, const CFIndex *lengths, CFIndex count) {
+// 	CFTypeRef *objs = malloc(sizeof(CFTypeRef) * count);
+// 	if (objs == NULL) {
+// 		return NULL;
+// 	}
+// 	CFArrayRef array = NULL;
+// 	CFIndex i, offset = 0;
+// 	for (i = 0; i < count; i++) {
+// 		objs[i] = CFStringCreateWithBytes(NULL, bytes + offset, lengths[i], kCFStringEncodingUTF8, false);
+// 		if (objs[i] == NULL) {
+// 			goto done;
+// 		}
+// 		offset += lengths[i];
+// 	}
+// 	array = CFArrayCreate(NULL, objs, count, &kCFTypeArrayCallBacks);
+// done:
+// 	while (i-- > 0) {
+// 		CFRelease(objs[i]);
+// 	}
+// 	free(objs);
+// 	return array;
+// }
+//
+// // goplist_getNumberValues stores the values of the CFNumbers in array,
+// // converted to type, in values, each taking size bytes. It returns false if
+// // any element is not a CFNumber.
+// static Boolean goplist_getNumberValues(CFArrayRef array, CFNumberType type, void *values, size_t size) {
+// 	CFIndex count = CFArrayGetCount(array);
+// 	CFTypeID numberID = CFNumberGetTypeID();
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		CFTypeRef obj = CFArrayGetValueAtIndex(array, i);
+// 		if (CFGetTypeID(obj) != numberID) {
+// 			return false;
+// 		}
+// 		CFNumberGetValue((CFNumberRef)obj, type, (char *)values + i*size);
+// 	}
+// 	return true;
+// }
+//
+// // goplist_getStringLengths stores the UTF-8 length of each CFString in
+// // array in lengths and returns their sum. It returns -1 if any element is not
+// // a CFString or can't be fully converted to UTF-8.
+// static CFIndex goplist_getStringLengths(CFArrayRef array, CFIndex *lengths) {
+// 	CFIndex count = CFArrayGetCount(array), total = 0;
+// 	CFTypeID stringID = CFStringGetTypeID();
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		CFTypeRef obj = CFArrayGetValueAtIndex(array, i);
+// 		if (CFGetTypeID(obj) != stringID) {
+// 			return -1;
+// 		}
+// 		CFIndex length = CFStringGetLength((CFStringRef)obj);
+// 		if (CFStringGetBytes((CFStringRef)obj, CFRangeMake(0, length), kCFStringEncodingUTF8, 0, false, NULL, 0, &lengths[i]) != length) {
+// 			return -1;
+// 		}
+// 		total += lengths[i];
+// 	}
+// 	return total;
+// }
+//
+// // goplist_getStringBytes stores the concatenated UTF-8 bytes of the CFStrings
+// // in array in buf, using the lengths from goplist_getStringLengths.
+// static void goplist_getStringBytes(CFArrayRef array, UInt8 *buf, const CFIndex *lengths) {
+// 	CFIndex count = CFArrayGetCount(array), offset = 0;
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		CFStringRef str = CFArrayGetValueAtIndex(array, i);
+// 		CFStringGetBytes(str, CFRangeMake(0, CFStringGetLength(str)), kCFStringEncodingUTF8, 0, false, buf + offset, lengths[i], NULL);
+// 		offset += lengths[i];
+// 	}
+// }
+import "C"
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Slices of these types are converted to and from CFArrays in a single cgo
+// call, instead of a call per element.
+var (
+	int64SliceType   = reflect.TypeOf([]int64(nil))
+	float64SliceType = reflect.TypeOf([]float64(nil))
+	stringSliceType  = reflect.TypeOf([]string(nil))
+	// []int is only batched where int is 64 bits, so that it can be converted
+	// as SInt64 without checking for overflow.
+	intSliceType = reflect.TypeOf([]int(nil))
+)
+
+// batchInts is whether []int is batched.
+const batchInts = strconv.IntSize == 64
+
+// convertPrimitiveSliceToCFArray converts a []int64, []int (where batchInts
+// is set), []float64 or []string to a CFArray in a single cgo call. It returns
+// nil if v has any other type, is empty, or holds a value that the general
+// conversion has to handle, such as NaN or a string that isn't valid UTF-8.
+func convertPrimitiveSliceToCFArray(v reflect.Value) C.CFArrayRef {
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return nil
+	}
+	switch v.Type() {
+	case int64SliceType:
+		s := v.Interface().([]int64)
+		return cfCreated(C.goplist_createNumberArray(C.kCFNumberSInt64Type, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0])), C.CFIndex(len(s))))
+	case intSliceType:
+		if !batchInts {
+			return nil
+		}
+		s := v.Interface().([]int)
+		return cfCreated(C.goplist_createNumberArray(C.kCFNumberSInt64Type, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0])), C.CFIndex(len(s))))
+	case float64SliceType:
+		s := v.Interface().([]float64)
+		for _, f := range s {
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				return nil
+			}
+		}
+		return cfCreated(C.goplist_createNumberArray(C.kCFNumberDoubleType, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0])), C.CFIndex(len(s))))
+	case stringSliceType:
+		s := v.Interface().([]string)
+		total := 0
+		for _, str := range s {
+			if !utf8.ValidString(str) {
+				return nil
+			}
+			total += len(str)
+		}
+		buf := make([]byte, 0, total)
+		lengths := make([]C.CFIndex, len(s))
+		for i, str := range s {
+			buf = append(buf, str...)
+			lengths[i] = C.CFIndex(len(str))
+		}
+		var bytes *C.UInt8
+		if total > 0 {
+			bytes = (*C.UInt8)(unsafe.Pointer(&buf[0]))
+		}
+		return cfCreated(C.goplist_createStringArray(bytes, &lengths[0], C.CFIndex(len(s))))
+	}
+	return nil
+}
+
+// convertCFArrayToPrimitiveSlice converts cfArray to a value of type t, if t
+// is one of the slice types handled by convertPrimitiveSliceToCFArray, with
+// one cgo call (two for strings) besides getting the count. It returns false
+// if t is another type, if cfArray is empty, or if any element of cfArray
+// doesn't have the element type of t, leaving those cases to the general
+// conversion.
+func convertCFArrayToPrimitiveSlice(cfArray C.CFArrayRef, t reflect.Type) (interface{}, bool) {
+	if t != int64SliceType && t != float64SliceType && t != stringSliceType && (t != intSliceType || !batchInts) {
+		return nil, false
+	}
+	count := int(C.CFArrayGetCount(cfArray))
+	if count == 0 {
+		return nil, false
+	}
+	switch t {
+	case int64SliceType:
+		s := make([]int64, count)
+		if C.goplist_getNumberValues(cfArray, C.kCFNumberSInt64Type, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0]))) == 0 {
+			return nil, false
+		}
+		return s, true
+	case intSliceType:
+		s := make([]int, count)
+		if C.goplist_getNumberValues(cfArray, C.kCFNumberSInt64Type, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0]))) == 0 {
+			return nil, false
+		}
+		return s, true
+	case float64SliceType:
+		s := make([]float64, count)
+		if C.goplist_getNumberValues(cfArray, C.kCFNumberDoubleType, unsafe.Pointer(&s[0]), C.size_t(unsafe.Sizeof(s[0]))) == 0 {
+			return nil, false
+		}
+		return s, true
+	}
+	lengths := make([]C.CFIndex, count)
+	total := int(C.goplist_getStringLengths(cfArray, &lengths[0]))
+	if total < 0 {
+		return nil, false
+	}
+	s := make([]string, count)
+	if total == 0 {
+		return s, true
+	}
+	buf := make([]byte, total)
+	C.goplist_getStringBytes(cfArray, (*C.UInt8)(unsafe.Pointer(&buf[0])), &lengths[0])
+	// the strings share buf, which is never modified after this, as in
+	// strings.Builder
+	all := *(*string)(unsafe.Pointer(&buf))
+	offset := 0
+	for i, length := range lengths {
+		s[i] = all[offset : offset+int(length)]
+		offset += int(length)
+	}
+	return s, true
+}
@@ -0,0 +1,105 @@
+package plist
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPrimitiveSliceRoundTrip(t *testing.T) {
+	ints := make([]int64, 1000)
+	plainInts := make([]int, 1000)
+	floats := make([]float64, 1000)
+	strs := make([]string, 1000)
+	for i := range ints {
+		ints[i] = int64(i*i) - 500
+		plainInts[i] = i*i - 500
+		floats[i] = float64(i) / 3
+		strs[i] = "é" + strconv.Itoa(i)
+	}
+	strs[10] = ""
+	ints[0] = math.MinInt64
+	ints[1] = math.MaxInt64
+	for _, v := range []interface{}{ints, plainInts, floats, strs} {
+		for _, format := range []Format{XMLFormat, BinaryFormat} {
+			data, err := Marshal(v, format)
+			if err != nil {
+				t.Fatalf("%T %v: %v", v, format, err)
+			}
+			got := reflect.New(reflect.TypeOf(v))
+			if _, err := Unmarshal(data, got.Interface()); err != nil {
+				t.Fatalf("%T %v: %v", v, format, err)
+			}
+			if !reflect.DeepEqual(got.Elem().Interface(), v) {
+				t.Errorf("%T %v: round trip changed the value", v, format)
+			}
+			// the general conversion must agree with the batched one
+			var generic interface{}
+			if _, err := Unmarshal(data, &generic); err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(generic, v) {
+				t.Errorf("%T %v: decoding into an interface{} got a different value", v, format)
+			}
+		}
+	}
+}
+
+func TestPrimitiveSliceBatched(t *testing.T) {
+	for _, v := range []interface{}{[]int64{1}, []int{1}, []float64{1}, []string{"a"}} {
+		cfAry := convertPrimitiveSliceToCFArray(reflect.ValueOf(v))
+		if cfAry == nil {
+			if _, isInt := v.([]int); !isInt || batchInts {
+				t.Errorf("%T was not batched", v)
+			}
+			continue
+		}
+		cfRelease(cfTypeRef(cfAry))
+	}
+}
+
+func TestPrimitiveSliceFallback(t *testing.T) {
+	// values the batched conversion leaves to the general one
+	if _, err := Marshal([]float64{1, math.NaN()}, XMLFormat); err == nil {
+		t.Error("NaN: expected an error")
+	} else if _, ok := err.(*UnsupportedValueError); !ok {
+		t.Errorf("NaN: got %T, want UnsupportedValueError", err)
+	}
+	data, err := Marshal([]string{"ok", "bad\xff"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	if _, err := Unmarshal(data, &strs); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ok", "bad\ufffd"}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("invalid UTF-8: got %q, want %q", strs, want)
+	}
+
+	data, err = Marshal([]interface{}{int64(1), "two", 3.5}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ints []int64
+	_, err = Unmarshal(data, &ints)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Errorf("mixed array: got error %v, want UnmarshalTypeError", err)
+	}
+	if want := []int64{1, 0, 3}; !reflect.DeepEqual(ints, want) {
+		t.Errorf("mixed array: got %v, want %v", ints, want)
+	}
+
+	ints = []int64{1}
+	data, err = Marshal([]int64{}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unmarshal(data, &ints); err != nil {
+		t.Fatal(err)
+	}
+	if len(ints) != 1 {
+		t.Errorf("empty array: got %v, want the slice left alone", ints)
+	}
+}
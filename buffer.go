@@ -0,0 +1,48 @@
+package plist
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer is the capacity above which a buffer is dropped instead of
+// being returned to bufferPool, so one huge property list doesn't stay
+// allocated for the life of the process.
+const maxPooledBuffer = 4 << 20
+
+// bufferPool holds the buffers that property lists are encoded into when the
+// encoding is only needed until it has been written somewhere.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+// putBuffer returns buf to the pool, keeping the storage of data, which must
+// have been appended to (*buf)[:0].
+func putBuffer(buf *[]byte, data []byte) {
+	if cap(data) > maxPooledBuffer {
+		return
+	}
+	*buf = data[:0]
+	bufferPool.Put(buf)
+}
+
+// MarshalTo writes the property list encoding of v to w, in the given format.
+// The encoding is made in a buffer that is reused by later calls, so unlike
+// Marshal it doesn't allocate a new slice for every value. Nothing is written
+// if v can't be marshaled.
+func MarshalTo(w io.Writer, v interface{}, format Format) error {
+	buf := getBuffer()
+	data, err := MarshalAppend((*buf)[:0], v, format)
+	if err == nil {
+		_, err = w.Write(data)
+	}
+	putBuffer(buf, data)
+	return err
+}
@@ -0,0 +1,66 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMarshalAppend(t *testing.T) {
+	v := map[string]interface{}{"a": "one", "b": []int64{1, 2}}
+	d := &Dict{}
+	d.Set("z", 1)
+	d.Set("a", 2)
+	for _, v := range []interface{}{v, d} {
+		for _, format := range []Format{XMLFormat, BinaryFormat} {
+			want, err := Marshal(v, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 0, len(want)+16)
+			buf = append(buf, "prefix"...)
+			got, err := MarshalAppend(buf, v, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "prefix"+string(want) {
+				t.Errorf("%T %v: got %q, want the prefix followed by %q", v, format, got, want)
+			}
+			if &got[0] != &buf[0] {
+				t.Errorf("%T %v: buffer was reallocated despite its capacity", v, format)
+			}
+		}
+	}
+
+	dst := []byte("keep")
+	got, err := MarshalAppend(dst, math.NaN(), XMLFormat)
+	if err == nil {
+		t.Error("NaN: expected an error")
+	}
+	if string(got) != "keep" {
+		t.Errorf("NaN: got %q, want dst unchanged", got)
+	}
+}
+
+func TestMarshalTo(t *testing.T) {
+	v := map[string]interface{}{"a": "one"}
+	want, err := Marshal(v, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		var b bytes.Buffer
+		if err := MarshalTo(&b, v, BinaryFormat); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b.Bytes(), want) {
+			t.Errorf("call %d: got %q, want %q", i, b.Bytes(), want)
+		}
+	}
+	var b bytes.Buffer
+	if err := MarshalTo(&b, math.Inf(1), BinaryFormat); err == nil {
+		t.Error("Inf: expected an error")
+	} else if b.Len() != 0 {
+		t.Errorf("Inf: wrote %q", b.Bytes())
+	}
+}
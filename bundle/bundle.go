@@ -0,0 +1,251 @@
+// Package bundle locates and decodes the Info.plist of application, framework
+// and plug-in bundles.
+//
+// Bundles are opened with CFBundle, so the same rules that the system uses to
+// find a bundle's Info.plist and localized resources apply here. Values are
+// converted with the same rules as plist.Unmarshal.
+//
+// The Info.plist of an app can also be read straight out of an .ipa or other
+// zip archive with ReadAppInfo, and that of a command-line tool out of its
+// executable with ReadEmbeddedInfo.
+package bundle
+
+// #cgo LDFLAGS: -framework CoreFoundation
+// #include <CoreFoundation/CoreFoundation.h>
+//
+// static CFStringRef bundleCreateString(const char *bytes, CFIndex len) {
+//     return CFStringCreateWithBytes(NULL, (const UInt8 *)bytes, len, kCFStringEncodingUTF8, false);
+// }
+//
+// static CFBundleRef bundleCreate(const char *path, CFIndex len) {
+//     CFURLRef url = CFURLCreateFromFileSystemRepresentation(NULL, (const UInt8 *)path, len, true);
+//     if (url == NULL) return NULL;
+//     CFBundleRef bundle = CFBundleCreate(NULL, url);
+//     CFRelease(url);
+//     return bundle;
+// }
+//
+// static CFPropertyListRef bundleCreatePropertyList(const void *bytes, CFIndex len) {
+//     CFDataRef data = CFDataCreate(NULL, bytes, len);
+//     CFPropertyListRef plist = CFPropertyListCreateWithData(NULL, data, kCFPropertyListImmutable, NULL, NULL);
+//     CFRelease(data);
+//     return plist;
+// }
+//
+// static void bundleSetValue(const void *key, const void *value, void *context) {
+//     CFDictionarySetValue((CFMutableDictionaryRef)context, key, value);
+// }
+//
+// // bundleCreateOverlay returns a copy of base with the entries of overlay
+// // replacing its own.
+// static CFDictionaryRef bundleCreateOverlay(CFDictionaryRef base, CFDictionaryRef overlay) {
+//     CFMutableDictionaryRef dict = CFDictionaryCreateMutableCopy(NULL, 0, base);
+//     if (overlay != NULL && CFGetTypeID(overlay) == CFDictionaryGetTypeID()) {
+//         CFDictionaryApplyFunction(overlay, bundleSetValue, dict);
+//     }
+//     return dict;
+// }
+//
+// static CFStringRef bundleCopyPath(CFURLRef url) {
+//     return CFURLCopyFileSystemPath(url, kCFURLPOSIXPathStyle);
+// }
+import "C"
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+	"unsafe"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A Bundle is an open bundle. It must be closed with Close when it is no
+// longer needed.
+type Bundle struct {
+	Path string
+	ref  C.CFBundleRef
+}
+
+// Open opens the bundle at path, which is the path of the bundle directory
+// itself, e.g. "/Applications/Safari.app". It returns an error if the
+// directory has no Info.plist.
+func Open(path string) (*Bundle, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(abs); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, errors.New("bundle: " + path + " is not a directory")
+	}
+	b := []byte(abs)
+	ref := C.bundleCreate((*C.char)(unsafe.Pointer(&b[0])), C.CFIndex(len(b)))
+	if ref == nil {
+		return nil, errors.New("bundle: could not open bundle " + path)
+	}
+	info := C.CFBundleGetInfoDictionary(ref)
+	if info == nil || C.CFDictionaryGetCount(info) == 0 {
+		C.CFRelease(C.CFTypeRef(ref))
+		return nil, errors.New("bundle: no Info.plist found in " + path)
+	}
+	return &Bundle{Path: abs, ref: ref}, nil
+}
+
+// Close releases the bundle.
+func (b *Bundle) Close() error {
+	if b.ref != nil {
+		C.CFRelease(C.CFTypeRef(b.ref))
+		b.ref = nil
+	}
+	return nil
+}
+
+// cfString returns a new CFString. Invalid UTF-8 sequences are replaced by
+// U+FFFD, as plist.Marshal does, since CFStringCreateWithBytes fails on them.
+// The caller must release it.
+func cfString(s string) C.CFStringRef {
+	if !utf8.ValidString(s) {
+		s = string([]rune(s))
+	}
+	b := []byte(s)
+	var ptr *C.char
+	if len(b) > 0 {
+		ptr = (*C.char)(unsafe.Pointer(&b[0]))
+	}
+	return C.bundleCreateString(ptr, C.CFIndex(len(b)))
+}
+
+// unmarshalCF stores the property list cfObj into v using
+// plist.UnmarshalCFType.
+func unmarshalCF(cfObj C.CFPropertyListRef, v interface{}) error {
+	return plist.UnmarshalCFType(unsafe.Pointer(cfObj), v)
+}
+
+// Info stores the contents of the bundle's Info.plist in the value pointed to
+// by v, without applying any localization.
+func (b *Bundle) Info(v interface{}) error {
+	return unmarshalCF(C.CFPropertyListRef(C.CFBundleGetInfoDictionary(b.ref)), v)
+}
+
+// LocalizedInfo is like Info, but the keys of Info.plist are overlaid with the
+// values from the InfoPlist.strings file of the localization that best
+// matches the user's preferred languages.
+func (b *Bundle) LocalizedInfo(v interface{}) error {
+	local := C.CFBundleGetLocalInfoDictionary(b.ref)
+	dict := C.bundleCreateOverlay(C.CFBundleGetInfoDictionary(b.ref), local)
+	defer C.CFRelease(C.CFTypeRef(dict))
+	return unmarshalCF(C.CFPropertyListRef(dict), v)
+}
+
+// LocalizedInfoFor is like LocalizedInfo, but uses the InfoPlist.strings file
+// of the given localization, e.g. "fr" or "pt-BR". If the bundle has no such
+// file, the result is the same as Info.
+func (b *Bundle) LocalizedInfoFor(localization string, v interface{}) error {
+	var local C.CFPropertyListRef
+	path, err := b.resourcePath("InfoPlist", "strings", localization)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 {
+			local = C.bundleCreatePropertyList(unsafe.Pointer(&data[0]), C.CFIndex(len(data)))
+			if local == nil {
+				return errors.New("bundle: could not parse " + path)
+			}
+			defer C.CFRelease(C.CFTypeRef(local))
+		}
+	}
+	dict := C.bundleCreateOverlay(C.CFBundleGetInfoDictionary(b.ref), C.CFDictionaryRef(local))
+	defer C.CFRelease(C.CFTypeRef(dict))
+	return unmarshalCF(C.CFPropertyListRef(dict), v)
+}
+
+// resourcePath returns the path of the named resource for the given
+// localization, or "" if it doesn't exist.
+func (b *Bundle) resourcePath(name, ext, localization string) (string, error) {
+	cfName, cfExt := cfString(name), cfString(ext)
+	defer C.CFRelease(C.CFTypeRef(cfName))
+	defer C.CFRelease(C.CFTypeRef(cfExt))
+	var cfLoc C.CFStringRef
+	if localization != "" {
+		cfLoc = cfString(localization)
+		defer C.CFRelease(C.CFTypeRef(cfLoc))
+	}
+	url := C.CFBundleCopyResourceURLForLocalization(b.ref, cfName, cfExt, nil, cfLoc)
+	if url == nil {
+		return "", nil
+	}
+	defer C.CFRelease(C.CFTypeRef(url))
+	cfPath := C.bundleCopyPath(url)
+	if cfPath == nil {
+		return "", errors.New("bundle: could not get the path of resource " + name + "." + ext)
+	}
+	defer C.CFRelease(C.CFTypeRef(cfPath))
+	var path string
+	if err := unmarshalCF(C.CFPropertyListRef(cfPath), &path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Localizations returns the localizations that the bundle contains resources
+// for.
+func (b *Bundle) Localizations() ([]string, error) {
+	cfArray := C.CFBundleCopyBundleLocalizations(b.ref)
+	if cfArray == nil {
+		return nil, nil
+	}
+	defer C.CFRelease(C.CFTypeRef(cfArray))
+	var locs []string
+	err := unmarshalCF(C.CFPropertyListRef(cfArray), &locs)
+	return locs, err
+}
+
+// ReadInfo opens the bundle at path and stores the contents of its
+// Info.plist in the value pointed to by v.
+func ReadInfo(path string, v interface{}) error {
+	b, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return b.Info(v)
+}
+
+// ReadLocalizedInfo opens the bundle at path and stores the contents of its
+// Info.plist, localized as described by LocalizedInfo, in the value pointed to
+// by v.
+func ReadLocalizedInfo(path string, v interface{}) error {
+	b, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	return b.LocalizedInfo(v)
+}
+
+// InfoPlistPath returns the path of the Info.plist file of the bundle at
+// path. It understands the layouts of macOS application bundles
+// (Contents/Info.plist), versioned frameworks (Resources/Info.plist) and iOS
+// style shallow bundles (Info.plist).
+func InfoPlistPath(path string) (string, error) {
+	for _, rel := range []string{
+		"Contents/Info.plist",
+		"Resources/Info.plist",
+		"Versions/Current/Resources/Info.plist",
+		"Info.plist",
+	} {
+		p := filepath.Join(path, rel)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() {
+			return p, nil
+		}
+	}
+	return "", errors.New("bundle: no Info.plist found in " + path)
+}
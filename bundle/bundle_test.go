@@ -0,0 +1,102 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+type testInfo struct {
+	CFBundleIdentifier        string
+	CFBundleName              string
+	CFBundleDevelopmentRegion string
+}
+
+// makeBundle creates an application bundle with English and French
+// localizations of CFBundleName.
+func makeBundle(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "Test.app")
+	contents := filepath.Join(path, "Contents")
+	info := testInfo{
+		CFBundleIdentifier:        "com.github.kballard.go-osx-plist.bundle-test",
+		CFBundleName:              "Test",
+		CFBundleDevelopmentRegion: "en",
+	}
+	for _, lproj := range []string{"en.lproj", "fr.lproj"} {
+		if err := os.MkdirAll(filepath.Join(contents, "Resources", lproj), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := plist.MarshalToFile(filepath.Join(contents, "Info.plist"), info, plist.XMLFormat); err != nil {
+		t.Fatal(err)
+	}
+	strings := filepath.Join(contents, "Resources", "fr.lproj", "InfoPlist.strings")
+	if err := os.WriteFile(strings, []byte(`"CFBundleName" = "Essai";`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBundle(t *testing.T) {
+	path := makeBundle(t)
+	b, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	var info testInfo
+	if err := b.Info(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.CFBundleName != "Test" || info.CFBundleIdentifier == "" {
+		t.Errorf("Info: got %#v", info)
+	}
+
+	var fr testInfo
+	if err := b.LocalizedInfoFor("fr", &fr); err != nil {
+		t.Fatal(err)
+	}
+	if fr.CFBundleName != "Essai" || fr.CFBundleIdentifier != info.CFBundleIdentifier {
+		t.Errorf("LocalizedInfoFor(fr): got %#v", fr)
+	}
+
+	var en testInfo
+	if err := b.LocalizedInfoFor("en", &en); err != nil {
+		t.Fatal(err)
+	}
+	if en != info {
+		t.Errorf("LocalizedInfoFor(en): got %#v, want %#v", en, info)
+	}
+
+	var bad testInfo
+	if err := b.LocalizedInfoFor("\xff", &bad); err != nil {
+		t.Fatal(err)
+	}
+	if bad != info {
+		t.Errorf("LocalizedInfoFor(\"\\xff\"): got %#v, want %#v", bad, info)
+	}
+
+	locs, err := b.Localizations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(locs)
+	if !reflect.DeepEqual(locs, []string{"en", "fr"}) {
+		t.Errorf("Localizations: got %v", locs)
+	}
+
+	if p, err := InfoPlistPath(path); err != nil || p != filepath.Join(path, "Contents", "Info.plist") {
+		t.Errorf("InfoPlistPath: got %q, %v", p, err)
+	}
+}
+
+func TestOpenNotBundle(t *testing.T) {
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("expected error opening a directory without an Info.plist")
+	}
+}
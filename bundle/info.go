@@ -0,0 +1,142 @@
+package bundle
+
+// An Info models the commonly used keys of an Info.plist. It can be passed to
+// Bundle.Info and friends, or used with plist.Marshal to generate an
+// Info.plist. Keys it doesn't cover can be read by also decoding the
+// Info.plist into a map[string]interface{}.
+//
+// All fields are omitted when empty, so marshaling an Info only produces the
+// keys that were set.
+type Info struct {
+	// Core Foundation keys
+	CFBundleIdentifier              string         `plist:",omitempty"`
+	CFBundleName                    string         `plist:",omitempty"`
+	CFBundleDisplayName             string         `plist:",omitempty"`
+	CFBundleExecutable              string         `plist:",omitempty"`
+	CFBundlePackageType             string         `plist:",omitempty"`
+	CFBundleSignature               string         `plist:",omitempty"`
+	CFBundleShortVersionString      string         `plist:",omitempty"`
+	CFBundleVersion                 string         `plist:",omitempty"`
+	CFBundleInfoDictionaryVersion   string         `plist:",omitempty"`
+	CFBundleDevelopmentRegion       string         `plist:",omitempty"`
+	CFBundleLocalizations           []string       `plist:",omitempty"`
+	CFBundleAllowMixedLocalizations bool           `plist:",omitempty"`
+	CFBundleIconFile                string         `plist:",omitempty"`
+	CFBundleIconName                string         `plist:",omitempty"`
+	CFBundleSupportedPlatforms      []string       `plist:",omitempty"`
+	CFBundleURLTypes                []URLType      `plist:",omitempty"`
+	CFBundleDocumentTypes           []DocumentType `plist:",omitempty"`
+
+	// Launch Services keys
+	LSMinimumSystemVersion      string   `plist:",omitempty"`
+	LSApplicationCategoryType   string   `plist:",omitempty"`
+	LSApplicationQueriesSchemes []string `plist:",omitempty"`
+	LSUIElement                 bool     `plist:",omitempty"`
+	LSBackgroundOnly            bool     `plist:",omitempty"`
+	LSRequiresIPhoneOS          bool     `plist:",omitempty"`
+
+	// UIKit keys
+	MinimumOSVersion                 string   `plist:",omitempty"`
+	UIDeviceFamily                   []int    `plist:",omitempty"`
+	UISupportedInterfaceOrientations []string `plist:",omitempty"`
+	UILaunchStoryboardName           string   `plist:",omitempty"`
+	UIMainStoryboardFile             string   `plist:",omitempty"`
+	UIBackgroundModes                []string `plist:",omitempty"`
+	// UIRequiredDeviceCapabilities is either an array of capability names or
+	// a dictionary from capability names to booleans.
+	UIRequiredDeviceCapabilities interface{} `plist:",omitempty"`
+
+	// Cocoa keys
+	NSPrincipalClass         string                `plist:",omitempty"`
+	NSMainNibFile            string                `plist:",omitempty"`
+	NSMainStoryboardFile     string                `plist:",omitempty"`
+	NSHumanReadableCopyright string                `plist:",omitempty"`
+	NSHighResolutionCapable  bool                  `plist:",omitempty"`
+	NSAppTransportSecurity   *AppTransportSecurity `plist:",omitempty"`
+
+	// Uniform Type Identifiers
+	UTExportedTypeDeclarations []TypeDeclaration `plist:",omitempty"`
+	UTImportedTypeDeclarations []TypeDeclaration `plist:",omitempty"`
+
+	// Usage descriptions, shown to the user when the app requests access to
+	// protected resources
+	NSAppleEventsUsageDescription                string `plist:",omitempty"`
+	NSBluetoothAlwaysUsageDescription            string `plist:",omitempty"`
+	NSBluetoothPeripheralUsageDescription        string `plist:",omitempty"`
+	NSCalendarsUsageDescription                  string `plist:",omitempty"`
+	NSCameraUsageDescription                     string `plist:",omitempty"`
+	NSContactsUsageDescription                   string `plist:",omitempty"`
+	NSFaceIDUsageDescription                     string `plist:",omitempty"`
+	NSHealthShareUsageDescription                string `plist:",omitempty"`
+	NSHealthUpdateUsageDescription               string `plist:",omitempty"`
+	NSHomeKitUsageDescription                    string `plist:",omitempty"`
+	NSLocalNetworkUsageDescription               string `plist:",omitempty"`
+	NSLocationAlwaysAndWhenInUseUsageDescription string `plist:",omitempty"`
+	NSLocationWhenInUseUsageDescription          string `plist:",omitempty"`
+	NSMicrophoneUsageDescription                 string `plist:",omitempty"`
+	NSMotionUsageDescription                     string `plist:",omitempty"`
+	NSPhotoLibraryAddUsageDescription            string `plist:",omitempty"`
+	NSPhotoLibraryUsageDescription               string `plist:",omitempty"`
+	NSRemindersUsageDescription                  string `plist:",omitempty"`
+	NSSpeechRecognitionUsageDescription          string `plist:",omitempty"`
+	NSSystemAdministrationUsageDescription       string `plist:",omitempty"`
+	NSUserTrackingUsageDescription               string `plist:",omitempty"`
+}
+
+// A URLType is an entry of CFBundleURLTypes, declaring URL schemes that the
+// app handles.
+type URLType struct {
+	CFBundleURLName     string   `plist:",omitempty"`
+	CFBundleURLSchemes  []string `plist:",omitempty"`
+	CFBundleTypeRole    string   `plist:",omitempty"` // "Editor", "Viewer", "Shell" or "None"
+	CFBundleURLIconFile string   `plist:",omitempty"`
+}
+
+// A DocumentType is an entry of CFBundleDocumentTypes, declaring document
+// types that the app can open.
+type DocumentType struct {
+	CFBundleTypeName       string   `plist:",omitempty"`
+	CFBundleTypeRole       string   `plist:",omitempty"`
+	CFBundleTypeIconFile   string   `plist:",omitempty"`
+	CFBundleTypeIconFiles  []string `plist:",omitempty"`
+	CFBundleTypeExtensions []string `plist:",omitempty"` // deprecated in favor of LSItemContentTypes
+	LSItemContentTypes     []string `plist:",omitempty"`
+	LSHandlerRank          string   `plist:",omitempty"` // "Owner", "Default", "Alternate" or "None"
+	LSTypeIsPackage        bool     `plist:",omitempty"`
+	NSDocumentClass        string   `plist:",omitempty"`
+}
+
+// A TypeDeclaration is an entry of UTExportedTypeDeclarations or
+// UTImportedTypeDeclarations, declaring a uniform type identifier.
+type TypeDeclaration struct {
+	UTTypeIdentifier   string   `plist:",omitempty"`
+	UTTypeDescription  string   `plist:",omitempty"`
+	UTTypeConformsTo   []string `plist:",omitempty"`
+	UTTypeIconFile     string   `plist:",omitempty"`
+	UTTypeIconName     string   `plist:",omitempty"`
+	UTTypeReferenceURL string   `plist:",omitempty"`
+	// UTTypeTagSpecification maps tag classes such as
+	// "public.filename-extension" and "public.mime-type" to a tag or an array
+	// of tags.
+	UTTypeTagSpecification map[string]interface{} `plist:",omitempty"`
+}
+
+// An AppTransportSecurity is the NSAppTransportSecurity dictionary, which
+// configures App Transport Security.
+type AppTransportSecurity struct {
+	NSAllowsArbitraryLoads             bool                       `plist:",omitempty"`
+	NSAllowsArbitraryLoadsForMedia     bool                       `plist:",omitempty"`
+	NSAllowsArbitraryLoadsInWebContent bool                       `plist:",omitempty"`
+	NSAllowsLocalNetworking            bool                       `plist:",omitempty"`
+	NSExceptionDomains                 map[string]ExceptionDomain `plist:",omitempty"`
+}
+
+// An ExceptionDomain relaxes App Transport Security for a single domain.
+type ExceptionDomain struct {
+	NSIncludesSubdomains               bool   `plist:",omitempty"`
+	NSExceptionAllowsInsecureHTTPLoads bool   `plist:",omitempty"`
+	NSExceptionMinimumTLSVersion       string `plist:",omitempty"` // e.g. "TLSv1.2"
+	// NSExceptionRequiresForwardSecrecy defaults to true when nil.
+	NSExceptionRequiresForwardSecrecy *bool `plist:",omitempty"`
+	NSRequiresCertificateTransparency bool  `plist:",omitempty"`
+}
@@ -0,0 +1,47 @@
+package bundle
+
+import (
+	"reflect"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestInfoRoundTrip(t *testing.T) {
+	no := false
+	in := Info{
+		CFBundleIdentifier: "com.example.app",
+		CFBundleURLTypes:   []URLType{{CFBundleURLName: "example", CFBundleURLSchemes: []string{"example"}}},
+		NSAppTransportSecurity: &AppTransportSecurity{
+			NSExceptionDomains: map[string]ExceptionDomain{
+				"example.com": {NSIncludesSubdomains: true, NSExceptionRequiresForwardSecrecy: &no},
+			},
+		},
+		UTExportedTypeDeclarations: []TypeDeclaration{{
+			UTTypeIdentifier:       "com.example.doc",
+			UTTypeConformsTo:       []string{"public.data"},
+			UTTypeTagSpecification: map[string]interface{}{"public.filename-extension": []interface{}{"exdoc"}},
+		}},
+		NSCameraUsageDescription: "Scan documents",
+	}
+	data, err := plist.Marshal(in, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys map[string]interface{}
+	if _, err := plist.Unmarshal(data, &keys); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 5 {
+		t.Errorf("empty fields were not omitted: %v", keys)
+	}
+
+	var out Info
+	if _, err := plist.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
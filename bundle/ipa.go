@@ -0,0 +1,93 @@
+package bundle
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// ReadAppInfo stores the Info.plist of the app at path in the value pointed to
+// by v. The path may be an app bundle directory, or a zip archive such as an
+// .ipa holding the app in its Payload directory. Info.plists of nested
+// bundles, like frameworks and extensions, are ignored.
+func ReadAppInfo(path string, v interface{}) error {
+	data, err := AppInfoPlist(path)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(data, v)
+	return err
+}
+
+// AppInfoPlist returns the contents of the Info.plist of the app at path, as
+// described by ReadAppInfo.
+func AppInfoPlist(path string) ([]byte, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		infoPath, err := InfoPlistPath(path)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(infoPath)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ArchiveInfoPlist(f, fi.Size())
+}
+
+// ReadArchiveInfo is like ReadAppInfo for a zip archive that is already open.
+func ReadArchiveInfo(r io.ReaderAt, size int64, v interface{}) error {
+	data, err := ArchiveInfoPlist(r, size)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(data, v)
+	return err
+}
+
+// ArchiveInfoPlist returns the contents of the app's Info.plist in a zip
+// archive.
+func ArchiveInfoPlist(r io.ReaderAt, size int64) ([]byte, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	var found *zip.File
+	for _, f := range zr.File {
+		if isAppInfoPlist(f.Name) && (found == nil || len(f.Name) < len(found.Name)) {
+			found = f
+		}
+	}
+	if found == nil {
+		return nil, errors.New("bundle: no app Info.plist found in archive")
+	}
+	rc, err := found.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// isAppInfoPlist reports whether name is the Info.plist of a top-level app in
+// an archive: "X.app/Info.plist" or "X.app/Contents/Info.plist", optionally
+// inside a Payload directory.
+func isAppInfoPlist(name string) bool {
+	name = strings.TrimPrefix(filepath.ToSlash(name), "Payload/")
+	app, rest, ok := strings.Cut(name, "/")
+	if !ok || !strings.HasSuffix(app, ".app") {
+		return false
+	}
+	return rest == "Info.plist" || rest == "Contents/Info.plist"
+}
@@ -0,0 +1,54 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestReadArchiveInfo(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, id := range map[string]string{
+		"Payload/Test.app/Frameworks/Lib.framework/Info.plist": "com.example.lib",
+		"Payload/Test.app/PlugIns/Ext.appex/Info.plist":        "com.example.app.ext",
+		"Payload/Test.app/Info.plist":                          "com.example.app",
+	} {
+		data, err := plist.Marshal(Info{CFBundleIdentifier: id}, plist.BinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var info Info
+	if err := ReadArchiveInfo(bytes.NewReader(buf.Bytes()), int64(buf.Len()), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.CFBundleIdentifier != "com.example.app" {
+		t.Errorf("got %q, want com.example.app", info.CFBundleIdentifier)
+	}
+}
+
+func TestIsAppInfoPlist(t *testing.T) {
+	for name, want := range map[string]bool{
+		"Payload/Test.app/Info.plist":                 true,
+		"Test.app/Contents/Info.plist":                true,
+		"Payload/Test.app/Watch/W.app/Info.plist":     false,
+		"Payload/Test.app/PlugIns/E.appex/Info.plist": false,
+		"Payload/Info.plist":                          false,
+	} {
+		if got := isAppInfoPlist(name); got != want {
+			t.Errorf("isAppInfoPlist(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
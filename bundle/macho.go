@@ -0,0 +1,70 @@
+package bundle
+
+import (
+	"debug/macho"
+	"errors"
+	"io"
+	"os"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// ReadEmbeddedInfo stores the Info.plist embedded in the __TEXT,__info_plist
+// section of the Mach-O executable at path in the value pointed to by v.
+// Command-line tools that aren't in a bundle carry their Info.plist this way.
+func ReadEmbeddedInfo(path string, v interface{}) error {
+	data, err := EmbeddedInfoPlist(path)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(data, v)
+	return err
+}
+
+// EmbeddedInfoPlist returns the contents of the __TEXT,__info_plist section
+// of the Mach-O executable at path. For a universal (fat) binary, the section
+// is taken from the first architecture that has one.
+func EmbeddedInfoPlist(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return embeddedInfoPlist(f, path)
+}
+
+func embeddedInfoPlist(r io.ReaderAt, path string) ([]byte, error) {
+	fat, err := macho.NewFatFile(r)
+	if err == nil {
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			if data, err := sectionData(arch.File); data != nil || err != nil {
+				return data, err
+			}
+		}
+		return nil, errors.New("bundle: no embedded Info.plist in " + path)
+	} else if err != macho.ErrNotFat {
+		return nil, err
+	}
+	file, err := macho.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := sectionData(file)
+	if data == nil && err == nil {
+		return nil, errors.New("bundle: no embedded Info.plist in " + path)
+	}
+	return data, err
+}
+
+// sectionData returns the contents of the __TEXT,__info_plist section, or nil
+// if the file doesn't have one.
+func sectionData(f *macho.File) ([]byte, error) {
+	for _, sect := range f.Sections {
+		if sect.Seg == "__TEXT" && sect.Name == "__info_plist" {
+			return sect.Data()
+		}
+	}
+	return nil, nil
+}
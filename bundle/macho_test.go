@@ -0,0 +1,81 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// machO returns a minimal 64-bit Mach-O executable whose only section is
+// __TEXT,__info_plist containing data.
+func machO(data []byte) []byte {
+	const headerSize, segmentSize, sectionSize = 32, 72, 80
+	offset := uint32(headerSize + segmentSize + sectionSize)
+	name := func(s string) (b [16]byte) {
+		copy(b[:], s)
+		return
+	}
+	var buf bytes.Buffer
+	w := func(v interface{}) { binary.Write(&buf, binary.LittleEndian, v) }
+	// mach_header_64
+	w([]uint32{0xfeedfacf, 0x01000007, 3, 2, 1, segmentSize + sectionSize, 0, 0})
+	// segment_command_64
+	w([]uint32{0x19, segmentSize + sectionSize})
+	w(name("__TEXT"))
+	w([]uint64{0, uint64(offset) + uint64(len(data)), 0, uint64(offset) + uint64(len(data))})
+	w([]uint32{5, 5, 1, 0})
+	// section_64
+	w(name("__info_plist"))
+	w(name("__TEXT"))
+	w([]uint64{uint64(offset), uint64(len(data))})
+	w([]uint32{offset, 0, 0, 0, 0, 0, 0, 0})
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// fat wraps a thin Mach-O file in a universal binary.
+func fat(thin []byte) []byte {
+	const offset = 4096
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []uint32{0xcafebabe, 1, 0x01000007, 3, offset, uint32(len(thin)), 12})
+	buf.Write(make([]byte, offset-buf.Len()))
+	buf.Write(thin)
+	return buf.Bytes()
+}
+
+func TestReadEmbeddedInfo(t *testing.T) {
+	data, err := plist.Marshal(map[string]string{"CFBundleIdentifier": "com.example.tool"}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for name, contents := range map[string][]byte{
+		"thin": machO(data),
+		"fat":  fat(machO(data)),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, contents, 0755); err != nil {
+			t.Fatal(err)
+		}
+		var info map[string]string
+		if err := ReadEmbeddedInfo(path, &info); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if info["CFBundleIdentifier"] != "com.example.tool" {
+			t.Errorf("%s: got %#v", name, info)
+		}
+	}
+
+	path := filepath.Join(dir, "notmacho")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EmbeddedInfoPlist(path); err == nil {
+		t.Error("expected error for a file that isn't Mach-O")
+	}
+}
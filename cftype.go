@@ -0,0 +1,216 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// ToCFType converts v to a CoreFoundation property list object, using the same
+// rules as Marshal, and returns it as a CFTypeRef. This allows values to be
+// handed to other cgo bindings that accept CoreFoundation types.
+//
+// The returned reference is owned by the caller, following the CoreFoundation
+// Create Rule, and must be balanced by a call to CFRelease (or ReleaseCFType).
+// Bindings that represent CoreFoundation types as integers can convert the
+// result with uintptr(ref). MarshalCF returns the same object wrapped in a
+// CFObject, which releases it automatically.
+func ToCFType(v interface{}) (ref unsafe.Pointer, err error) {
+	defer recoverPanic(&err)
+	cfObj, err := (&marshalState{}).marshalValue(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return unsafe.Pointer(cfObj), nil
+}
+
+// FromCFType converts the CoreFoundation property list object ref to a Go
+// value. The result uses the same types that Unmarshal stores in an
+// interface{} value. Ownership of ref is not affected; the caller is still
+// responsible for releasing it if needed.
+//
+// Dictionary keys that aren't strings cause an UnsupportedKeyTypeError. Use
+// CFTypeOptions to convert them to strings instead.
+func FromCFType(ref unsafe.Pointer) (interface{}, error) {
+	return (*CFTypeOptions)(nil).FromCFType(ref)
+}
+
+// UnmarshalCFType stores the Go value of the CoreFoundation property list
+// object ref in the value pointed to by v, following the same rules as
+// Unmarshal. This decodes objects obtained from other CoreFoundation APIs
+// without serializing them first. Ownership of ref is not affected.
+func UnmarshalCFType(ref unsafe.Pointer, v interface{}) error {
+	return (*CFTypeOptions)(nil).UnmarshalCFType(ref, v)
+}
+
+// CFTypeOptions holds options for converting CoreFoundation objects to Go
+// values. A nil *CFTypeOptions is the same as the zero CFTypeOptions, which
+// FromCFType and UnmarshalCFType use. To convert the object of a CFObject with
+// options, pass its Ref and keep the CFObject alive until the call returns.
+type CFTypeOptions struct {
+	// StringifyKeys converts dictionary keys that aren't strings, which
+	// dictionaries returned by other APIs (IOKit, Security) may have, to
+	// strings: numbers as decimal, data as lowercase hex, and booleans as
+	// "true" or "false". Other key types still cause an
+	// UnsupportedKeyTypeError, and two keys of a dictionary that convert to
+	// the same string cause a DuplicateKeyError.
+	StringifyKeys bool
+}
+
+// FromCFType converts ref to a Go value as the FromCFType function does,
+// following the options in opts.
+func (opts *CFTypeOptions) FromCFType(ref unsafe.Pointer) (interface{}, error) {
+	if ref == nil {
+		return nil, errors.New("plist: FromCFType called with NULL reference")
+	}
+	return convertCFTypeToInterfaceKeys(cfTypeRef(ref), opts.stringifyKeys())
+}
+
+// UnmarshalCFType stores the Go value of ref in the value pointed to by v as
+// the UnmarshalCFType function does, following the options in opts.
+func (opts *CFTypeOptions) UnmarshalCFType(ref unsafe.Pointer, v interface{}) (err error) {
+	if ref == nil {
+		return errors.New("plist: UnmarshalCFType called with NULL reference")
+	}
+	defer recoverPanic(&err)
+	state := &unmarshalState{decodeOptions: decodeOptions{stringifyKeys: opts.stringifyKeys()}}
+	return state.unmarshalRoot(cfTypeRef(ref), v)
+}
+
+func (opts *CFTypeOptions) stringifyKeys() bool {
+	return opts != nil && opts.StringifyKeys
+}
+
+// ReleaseCFType releases a reference returned by ToCFType. It does nothing if
+// ref is nil.
+func ReleaseCFType(ref unsafe.Pointer) {
+	cfRelease(cfTypeRef(ref))
+}
+
+// A CFObject holds a reference to a CoreFoundation object. The reference is
+// released when Release is called, or when the CFObject becomes unreachable if
+// Release is never called, so objects can't leak when an error path skips
+// cleanup.
+//
+// Copies of a CFObject share the same reference. The zero CFObject holds no
+// reference. A CFObject is not safe to Release concurrently with other uses.
+type CFObject struct {
+	obj *cfObject
+}
+
+type cfObject struct {
+	ref cfTypeRef
+}
+
+func (obj *cfObject) release() {
+	cfRelease(obj.ref)
+	obj.ref = nil
+}
+
+// NewCFObject returns a CFObject that takes ownership of ref, which must be a
+// reference the caller owns (e.g. one obtained from a Create or Copy function,
+// or from ToCFType). If ref is nil, the zero CFObject is returned.
+func NewCFObject(ref unsafe.Pointer) CFObject {
+	if ref == nil {
+		return CFObject{}
+	}
+	obj := &cfObject{cfTypeRef(ref)}
+	runtime.SetFinalizer(obj, (*cfObject).release)
+	return CFObject{obj}
+}
+
+// RetainCFObject returns a CFObject holding a new reference to ref, leaving
+// ownership of ref unaffected. Use this for references obtained from a Get
+// function.
+func RetainCFObject(ref unsafe.Pointer) CFObject {
+	if ref == nil {
+		return CFObject{}
+	}
+	return NewCFObject(unsafe.Pointer(cfRetain(cfTypeRef(ref))))
+}
+
+// MarshalCF converts v to a CoreFoundation property list object as ToCFType
+// does, and returns it as a CFObject.
+func MarshalCF(v interface{}) (CFObject, error) {
+	ref, err := ToCFType(v)
+	if err != nil {
+		return CFObject{}, err
+	}
+	return NewCFObject(ref), nil
+}
+
+// IsNil reports whether o holds no reference, either because it is the zero
+// CFObject or because it has been released.
+func (o CFObject) IsNil() bool {
+	return o.obj == nil || o.obj.ref == nil
+}
+
+// Ref returns the underlying CFTypeRef without transferring ownership. The
+// reference is only valid while o is reachable and not released; callers that
+// pass it to C must keep o alive (e.g. with runtime.KeepAlive) until the call
+// returns.
+func (o CFObject) Ref() unsafe.Pointer {
+	if o.obj == nil {
+		return nil
+	}
+	return unsafe.Pointer(o.obj.ref)
+}
+
+// Retain returns a new reference to the underlying object, which the caller
+// owns and must release. It returns nil if o holds no reference.
+func (o CFObject) Retain() unsafe.Pointer {
+	if o.IsNil() {
+		return nil
+	}
+	ref := unsafe.Pointer(cfRetain(o.obj.ref))
+	runtime.KeepAlive(o.obj)
+	return ref
+}
+
+// Release releases the reference held by o. It is safe to call Release more
+// than once, and on the zero CFObject.
+func (o CFObject) Release() {
+	if o.obj == nil {
+		return
+	}
+	o.obj.release()
+	runtime.SetFinalizer(o.obj, nil)
+}
+
+// Unmarshal stores the Go value of the object in the value pointed to by v, as
+// UnmarshalCFType does.
+func (o CFObject) Unmarshal(v interface{}) error {
+	if o.IsNil() {
+		return errors.New("plist: CFObject holds no reference")
+	}
+	err := UnmarshalCFType(unsafe.Pointer(o.obj.ref), v)
+	runtime.KeepAlive(o.obj)
+	return err
+}
+
+// DeepCopy returns an immutable copy of the object, made with
+// CFPropertyListCreateDeepCopy, so it no longer shares any mutable containers
+// with the original. It returns the zero CFObject if o holds no reference or
+// isn't a property list object.
+func (o CFObject) DeepCopy() CFObject {
+	if o.IsNil() {
+		return CFObject{}
+	}
+	ref := C.CFPropertyListCreateDeepCopy(nil, C.CFPropertyListRef(o.obj.ref), C.kCFPropertyListImmutable)
+	cfTrack(cfTypeRef(ref))
+	runtime.KeepAlive(o.obj)
+	return NewCFObject(unsafe.Pointer(ref))
+}
+
+// Interface converts the object to a Go value as FromCFType does.
+func (o CFObject) Interface() (interface{}, error) {
+	if o.IsNil() {
+		return nil, errors.New("plist: CFObject holds no reference")
+	}
+	v, err := convertCFTypeToInterface(o.obj.ref)
+	runtime.KeepAlive(o.obj)
+	return v, err
+}
@@ -0,0 +1,175 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCFTypeRoundTrip(t *testing.T) {
+	in := map[string]interface{}{
+		"string": "hello",
+		"number": int64(42),
+		"array":  []interface{}{"a", true},
+	}
+	ref, err := ToCFType(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ReleaseCFType(ref)
+	out, err := FromCFType(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestFromCFTypeNil(t *testing.T) {
+	if _, err := FromCFType(nil); err == nil {
+		t.Error("expected error for NULL reference")
+	}
+}
+
+func TestCFObject(t *testing.T) {
+	obj, err := MarshalCF([]interface{}{"a", int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.IsNil() {
+		t.Fatal("MarshalCF returned a nil CFObject")
+	}
+	out, err := obj.Interface()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, []interface{}{"a", int64(1)}) {
+		t.Errorf("got %#v", out)
+	}
+
+	retained := RetainCFObject(obj.Ref())
+	obj.Release()
+	obj.Release()
+	if !obj.IsNil() {
+		t.Error("CFObject is not nil after Release")
+	}
+	if _, err := obj.Interface(); err == nil {
+		t.Error("expected error from released CFObject")
+	}
+	if out, err := retained.Interface(); err != nil || !reflect.DeepEqual(out, []interface{}{"a", int64(1)}) {
+		t.Errorf("retained object: got %#v, %v", out, err)
+	}
+	retained.Release()
+
+	var zero CFObject
+	if !zero.IsNil() || zero.Ref() != nil || zero.Retain() != nil {
+		t.Error("zero CFObject is not nil")
+	}
+	zero.Release()
+}
+
+// cfWrapper holds a CoreFoundation object directly, as a type wrapping an
+// object from another framework would.
+type cfWrapper struct {
+	obj CFObject
+}
+
+func (w *cfWrapper) MarshalPlistCF() (CFObject, error) {
+	return w.obj, nil
+}
+
+func (w *cfWrapper) UnmarshalPlistCF(obj CFObject) error {
+	w.obj = obj
+	return nil
+}
+
+func TestCFMarshaler(t *testing.T) {
+	obj, err := MarshalCF(map[string]interface{}{"key": "value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Release()
+	in := struct {
+		Wrapped *cfWrapper
+		Name    string
+	}{&cfWrapper{obj}, "test"}
+	data, err := Marshal(in, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Wrapped *cfWrapper
+		Name    string
+	}
+	if _, err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Wrapped == nil || out.Wrapped.obj.IsNil() {
+		t.Fatal("UnmarshalPlistCF was not called")
+	}
+	defer out.Wrapped.obj.Release()
+	got, err := out.Wrapped.obj.Interface()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"key": "value"}) {
+		t.Errorf("got %#v", got)
+	}
+	if out.Name != "test" {
+		t.Errorf("got name %q", out.Name)
+	}
+}
+
+func TestUnmarshalCFType(t *testing.T) {
+	type item struct {
+		Name  string `plist:"name"`
+		Count int    `plist:"count"`
+	}
+	ref, err := ToCFType(map[string]interface{}{"name": "widget", "count": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ReleaseCFType(ref)
+	var got item
+	if err := UnmarshalCFType(ref, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (item{"widget", 3}) {
+		t.Errorf("got %#v", got)
+	}
+
+	obj := RetainCFObject(ref)
+	defer obj.Release()
+	got = item{}
+	if err := obj.Unmarshal(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (item{"widget", 3}) {
+		t.Errorf("CFObject.Unmarshal: got %#v", got)
+	}
+
+	if err := UnmarshalCFType(ref, got); err == nil {
+		t.Error("expected error for non-pointer value")
+	}
+}
+
+func TestCFObjectDeepCopy(t *testing.T) {
+	obj, err := MarshalCF(map[string]interface{}{"a": []interface{}{"b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Release()
+	cp := obj.DeepCopy()
+	defer cp.Release()
+	if cp.IsNil() || cp.Ref() == obj.Ref() {
+		t.Fatal("DeepCopy did not create a new object")
+	}
+	got, err := cp.Interface()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"a": []interface{}{"b"}}) {
+		t.Errorf("got %#v", got)
+	}
+}
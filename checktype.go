@@ -0,0 +1,102 @@
+package plist
+
+import "reflect"
+
+// A TypeCheckError is returned by CheckType for a type that holds a type
+// Marshal can't encode. Path is where the unsupported type appears, as the
+// name of the checked type followed by the Go field names that lead to it,
+// with "[]" for the elements of slices, arrays, maps and iterators, e.g.
+// "main.Config.Servers[].Port".
+type TypeCheckError struct {
+	Type reflect.Type
+	Path string
+}
+
+func (e *TypeCheckError) Error() string {
+	return "plist: unsupported type " + e.Type.String() + " at " + e.Path
+}
+
+// Unwrap returns the UnsupportedTypeError that Marshal would return.
+func (e *TypeCheckError) Unwrap() error {
+	return &UnsupportedTypeError{e.Type}
+}
+
+// CheckType reports whether values of type t can be encoded by Marshal,
+// following the same rules, so that types can be checked up front, such as
+// when they are registered, rather than the first time a value is encoded.
+// It returns a TypeCheckError for the first unsupported type it finds:
+// uint64 and 64-bit uint and uintptr, complex numbers, channels, functions
+// other than iterators, unsafe pointers, and maps whose keys aren't strings,
+// outside of fields with the "set" option.
+//
+// Types with Marshal methods, and interface types, are accepted as they are,
+// since what they encode as depends on their values. So are fields with the
+// "stringer" option whose type has a String method.
+func CheckType(t reflect.Type) error {
+	if t == nil {
+		return &UnsupportedTypeError{t}
+	}
+	return checkType(t, t.String(), make(map[reflect.Type]bool))
+}
+
+// checkType checks t, which is at path. Struct types already in seen are
+// being checked, or have been, and are skipped.
+func checkType(t reflect.Type, path string, seen map[reflect.Type]bool) error {
+	if hasMarshaler(t, true) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Float32, reflect.Float64,
+		reflect.String, reflect.Interface:
+		return nil
+	case reflect.Uint, reflect.Uintptr:
+		if t.Bits() < 64 {
+			return nil
+		}
+	case reflect.Ptr:
+		return checkType(t.Elem(), path, seen)
+	case reflect.Slice, reflect.Array:
+		if t == byteSliceType || isUUIDType(t) || t == ipType {
+			return nil
+		}
+		return checkType(t.Elem(), path+"[]", seen)
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return checkType(t.Elem(), path+"[]", seen)
+		}
+	case reflect.Struct:
+		switch t {
+		case timeType, urlType, addrType, dictType, syncMapType:
+			return nil
+		}
+		if seen[t] {
+			return nil
+		}
+		seen[t] = true
+		for _, ef := range encodeFields(t) {
+			f := t.Field(ef.i)
+			if err := checkField(&ef, f.Type, path+"."+f.Name, seen); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Func:
+		if arity := seqArity(t); arity != 0 {
+			return checkType(t.In(0).In(arity-1), path+"[]", seen)
+		}
+	}
+	return &TypeCheckError{t, path}
+}
+
+// checkField checks t, the type of the field ef, which is at path, taking the
+// options of the field into account.
+func checkField(ef *encodeField, t reflect.Type, path string, seen map[reflect.Type]bool) error {
+	if ef.stringer && (t.Implements(stringerType) || reflect.PointerTo(t).Implements(stringerType)) {
+		return nil
+	}
+	if ef.set && isSetType(t) {
+		return checkType(t.Key(), path+"[]", seen)
+	}
+	return checkType(t, path, seen)
+}
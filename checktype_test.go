@@ -0,0 +1,61 @@
+package plist
+
+import (
+	"errors"
+	"iter"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type checkServer struct {
+	Host  string
+	Ports map[uint16]bool `plist:",set"`
+	Alive time.Duration   `plist:",stringer"`
+}
+
+type checkConfig struct {
+	Name    string
+	Servers []checkServer
+	Extra   map[string]interface{}
+	Tags    iter.Seq2[string, int]
+	Next    *checkConfig
+	skipped chan int
+}
+
+type checkBad struct {
+	Config checkConfig
+	Files  map[string]struct{ Size complex64 }
+}
+
+func TestCheckType(t *testing.T) {
+	if err := CheckType(reflect.TypeOf(checkConfig{})); err != nil {
+		t.Errorf("CheckType(checkConfig): %v", err)
+	}
+	tests := []struct {
+		v    interface{}
+		typ  reflect.Type
+		path string
+	}{
+		{uint64(0), reflect.TypeOf(uint64(0)), "uint64"},
+		{map[int]string{}, reflect.TypeOf(map[int]string{}), "map[int]string"},
+		{[]chan int{}, reflect.TypeOf(make(chan int)), "[]chan int[]"},
+		{struct{ F func() }{}, reflect.TypeOf(func() {}), "struct { F func() }.F"},
+		{checkBad{}, reflect.TypeOf(complex64(0)), "plist.checkBad.Files[].Size"},
+	}
+	for _, test := range tests {
+		err := CheckType(reflect.TypeOf(test.v))
+		var typeErr *TypeCheckError
+		if !errors.As(err, &typeErr) {
+			t.Errorf("CheckType(%T) returned %v, want a TypeCheckError", test.v, err)
+			continue
+		}
+		if typeErr.Type != test.typ || typeErr.Path != test.path {
+			t.Errorf("CheckType(%T) = %v at %q, want %v at %q", test.v, typeErr.Type, typeErr.Path, test.typ, test.path)
+		}
+		var unsupported *UnsupportedTypeError
+		if !errors.As(err, &unsupported) || unsupported.Type != test.typ {
+			t.Errorf("CheckType(%T) error %v doesn't wrap an UnsupportedTypeError", test.v, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+var convertCmd = &command{
+	name:    "convert",
+	args:    "[file]",
+	summary: "convert a property list to another format",
+	run:     runConvert,
+}
+
+// runConvert implements "plist convert -to format [-o output] [file]". The
+// input, standard input by default, may be in any supported format.
+func runConvert(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	to := fs.String("to", formatXML, "output `format`: xml1, binary1, json or der")
+	out := fs.String("o", "-", "write the result to `file` instead of standard output")
+	if !c.parse(fs, args, 0, 1) {
+		return exitUsage
+	}
+	if err := checkFormat(*to); err != nil {
+		return fail(env, c, err)
+	}
+	in := "-"
+	if fs.NArg() > 0 {
+		in = fs.Arg(0)
+	}
+	v, _, err := readPlist(env, in)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	data, err := encodePlist(v, *to)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if err := writeFile(env, *out, data); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var diffCmd = &command{
+	name:    "diff",
+	args:    "file1 file2",
+	summary: "compare two property lists",
+	run:     runDiff,
+}
+
+// exitTrouble is diff's exit status for errors. As with diff(1), status 1
+// means the files differ.
+const exitTrouble = 2
+
+// runDiff implements "plist diff [-format text|json] [-ignore-case]
+// [-epsilon e] [-unordered keypath]... file1 file2". The files are compared
+// by value, so differences in format or key order are ignored.
+//
+// Text output has a line per change: "+ path: value" for additions,
+// "- path: value" for removals and "~ path: old -> new" for replacements, with
+// values in JSON. JSON output is an array of objects with "op", "path" (an
+// array of key path components), and "old" and "new" values where present.
+func runDiff(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	format := fs.String("format", "text", "output `format`: text or json")
+	var opts plist.EqualOptions
+	fs.BoolVar(&opts.IgnoreKeyCase, "ignore-case", false, "match dictionary keys case-insensitively")
+	fs.Float64Var(&opts.FloatEpsilon, "epsilon", 0, "largest difference between numbers that are considered equal")
+	fs.Var((*stringList)(&opts.UnorderedArrays), "unordered", "compare the array at `keypath` ignoring order (may be repeated; * matches any key)")
+	if !c.parse(fs, args, 2, 2) {
+		return exitUsage
+	}
+	if *format != "text" && *format != "json" {
+		fail(env, c, errors.New("unknown output format "+*format+" (want text or json)"))
+		return exitTrouble
+	}
+	a, _, err := readPlist(env, fs.Arg(0))
+	if err != nil {
+		fail(env, c, err)
+		return exitTrouble
+	}
+	b, _, err := readPlist(env, fs.Arg(1))
+	if err != nil {
+		fail(env, c, err)
+		return exitTrouble
+	}
+	patch := plist.DiffWithOptions(a, b, &opts)
+	var out []byte
+	if *format == "json" {
+		out, err = patchJSON(patch)
+	} else {
+		out, err = patchText(patch)
+	}
+	if err != nil {
+		fail(env, c, err)
+		return exitTrouble
+	}
+	if err := writeFile(env, "-", out); err != nil {
+		fail(env, c, err)
+		return exitTrouble
+	}
+	if len(patch) > 0 {
+		return 1
+	}
+	return exitOK
+}
+
+func patchText(patch plist.Patch) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, change := range patch {
+		path := change.Path.String()
+		if path == "" {
+			path = ":"
+		}
+		switch change.Op {
+		case plist.Add:
+			v, err := inlineJSON(change.New)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "+ %s: %s\n", path, v)
+		case plist.Remove:
+			v, err := inlineJSON(change.Old)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "- %s: %s\n", path, v)
+		case plist.Replace:
+			old, err := inlineJSON(change.Old)
+			if err != nil {
+				return nil, err
+			}
+			v, err := inlineJSON(change.New)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "~ %s: %s -> %s\n", path, old, v)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func patchJSON(patch plist.Patch) ([]byte, error) {
+	changes := []interface{}{}
+	for _, change := range patch {
+		d := &plist.Dict{}
+		d.Set("op", change.Op.String())
+		path := []interface{}{}
+		for _, comp := range change.Path {
+			path = append(path, comp)
+		}
+		d.Set("path", path)
+		if change.Old != nil {
+			d.Set("old", change.Old)
+		}
+		if change.New != nil {
+			d.Set("new", change.New)
+		}
+		changes = append(changes, d)
+	}
+	return encodePlist(changes, formatJSON)
+}
+
+// inlineJSON formats v as single-line JSON.
+func inlineJSON(v interface{}) (string, error) {
+	data, err := plist.Marshal(v, plist.XMLFormat)
+	if err != nil {
+		return "", err
+	}
+	data, err = plist.ToJSON(data, nil)
+	return string(data), err
+}
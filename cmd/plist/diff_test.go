@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.plist")
+	b := filepath.Join(dir, "b.json")
+	if err := os.WriteFile(a, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the same property list in another format and key order
+	if err := os.WriteFile(b, []byte(`{"Items": ["a", "b"], "Count": 3, "Name": "example"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, stdout, stderr := runTest(t, "", "diff", a, b); code != exitOK || stdout != "" {
+		t.Errorf("equal files: got %d, %q (%s)", code, stdout, stderr)
+	}
+
+	if err := os.WriteFile(b, []byte(`{"Items": ["b", "a", "c"], "Count": 4, "New": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := runTest(t, "", "diff", a, b)
+	want := `- Name: "example"
+~ Count: 3 -> 4
+~ Items:0: "a" -> "b"
+~ Items:1: "b" -> "a"
++ Items:2: "c"
++ New: true
+`
+	if code != 1 || stdout != want {
+		t.Errorf("text: got %d, %q (%s), want:\n%s", code, stdout, stderr, want)
+	}
+
+	code, stdout, stderr = runTest(t, "", "diff", "-format", "json", "-unordered", "Items", a, b)
+	wantJSON := `[
+  {
+    "op": "remove",
+    "path": [
+      "Name"
+    ],
+    "old": "example"
+  },
+  {
+    "op": "replace",
+    "path": [
+      "Count"
+    ],
+    "old": 3,
+    "new": 4
+  },
+  {
+    "op": "replace",
+    "path": [
+      "Items"
+    ],
+    "old": [
+      "a",
+      "b"
+    ],
+    "new": [
+      "b",
+      "a",
+      "c"
+    ]
+  },
+  {
+    "op": "add",
+    "path": [
+      "New"
+    ],
+    "new": true
+  }
+]
+`
+	if code != 1 || stdout != wantJSON {
+		t.Errorf("json: got %d, %q (%s), want:\n%s", code, stdout, stderr, wantJSON)
+	}
+
+	if code, _, _ := runTest(t, "", "diff", a, filepath.Join(dir, "missing")); code != exitTrouble {
+		t.Errorf("missing file: got exit %d", code)
+	}
+}
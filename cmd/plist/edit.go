@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var (
+	setCmd = &command{
+		name:    "set",
+		args:    "keypath value file",
+		summary: "set the value at a key path",
+		run:     runSet,
+	}
+	addCmd = &command{
+		name:    "add",
+		args:    "keypath [value] file",
+		summary: "add a new entry at a key path",
+		run:     runAdd,
+	}
+	deleteCmd = &command{
+		name:    "delete",
+		args:    "keypath file",
+		summary: "delete the entry at a key path",
+		run:     runDelete,
+	}
+)
+
+const typeUsage = "value `type`: string, integer, real, bool, date (RFC 3339), data (hex or base64), dict or array"
+
+// runSet implements "plist set [-type type] keypath value file" and "plist
+// set keypath -type value... file". Without -type, the value is parsed as the
+// type of the entry it replaces, or as a string for a new entry.
+//
+// The second form takes the value as "defaults write" does, as parsed by
+// plist.ParseValueArgs: "plist set Count -int 3 file" or "plist set Tags
+// -array a b file".
+func runSet(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	typ := fs.String("type", "", typeUsage)
+	if !c.parse(fs, args, 3, -1) {
+		return exitUsage
+	}
+	path, valueArgs, file := fs.Arg(0), fs.Args()[1:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+	typedArgs := len(valueArgs) > 1 || isValueFlag(valueArgs[0])
+	if typedArgs && *typ != "" {
+		fs.Usage()
+		return exitUsage
+	}
+	return editFile(c, env, file, func(v interface{}) (interface{}, error) {
+		var value interface{}
+		var err error
+		if typedArgs {
+			value, err = plist.ParseValueArgs(valueArgs)
+		} else {
+			t := *typ
+			if t == "" {
+				t = "string"
+				if old, err := plist.GetPath(v, path); err == nil {
+					t = typeName(old)
+				}
+			}
+			value, err = plist.ParseValue(t, valueArgs[0])
+		}
+		if err != nil {
+			return nil, err
+		}
+		return plist.SetPath(v, path, value)
+	})
+}
+
+// isValueFlag reports whether arg is a type flag of plist.ParseValueArgs that
+// takes no argument, such as "-array", so that it stands for a value by
+// itself.
+func isValueFlag(arg string) bool {
+	switch strings.ToLower(arg) {
+	case "-array", "-dict", "-dictionary":
+		return true
+	}
+	return false
+}
+
+// runAdd implements "plist add -type type keypath [value] file". The value is
+// omitted for the dict and array types, which add an empty container. An
+// array index inserts before that element; it fails if a dictionary entry
+// already exists.
+func runAdd(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	typ := fs.String("type", "string", typeUsage)
+	if !c.parse(fs, args, 2, 3) {
+		return exitUsage
+	}
+	path, text, file := fs.Arg(0), "", fs.Arg(fs.NArg()-1)
+	isContainer := *typ == "dict" || *typ == "array"
+	if fs.NArg() == 3 {
+		if isContainer {
+			fs.Usage()
+			return exitUsage
+		}
+		text = fs.Arg(1)
+	} else if !isContainer {
+		fs.Usage()
+		return exitUsage
+	}
+	return editFile(c, env, file, func(v interface{}) (interface{}, error) {
+		value, err := plist.ParseValue(*typ, text)
+		if err != nil {
+			return nil, err
+		}
+		return plist.AddPath(v, path, value)
+	})
+}
+
+// runDelete implements "plist delete keypath file".
+func runDelete(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	if !c.parse(fs, args, 2, 2) {
+		return exitUsage
+	}
+	path, file := fs.Arg(0), fs.Arg(1)
+	return editFile(c, env, file, func(v interface{}) (interface{}, error) {
+		return plist.DeletePath(v, path)
+	})
+}
+
+// editFile applies edit to the property list in file and atomically writes
+// the result back in the file's original format. OpenStep property lists,
+// which can't be written, are rejected before they're edited.
+func editFile(c *command, env *env, file string, edit func(interface{}) (interface{}, error)) int {
+	if file == "-" {
+		return fail(env, c, errors.New("cannot edit standard input in place"))
+	}
+	v, format, err := readPlist(env, file)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if format == formatOpenStep {
+		return fail(env, c, errors.New(file+": cannot edit OpenStep property lists in place; convert it to xml1 or binary1 first"))
+	}
+	v, err = edit(v)
+	if err != nil {
+		return failPath(env, c, err)
+	}
+	data, err := encodePlist(v, format)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if err := writeFile(env, file, data); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
+
+// typeName returns the type name of v, as plist.ParseValue accepts it.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "date"
+	case []byte:
+		return "data"
+	case []interface{}:
+		return "array"
+	case *plist.Dict, map[string]interface{}:
+		return "dict"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Float32, reflect.Float64:
+		return "real"
+	}
+	return "integer"
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.plist")
+	if err := os.WriteFile(path, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"set", "Count", "5", path}, exitOK},
+		{[]string{"set", "Count", "five", path}, exitError},
+		{[]string{"set", "-type", "bool", "Enabled", "yes", path}, exitOK},
+		{[]string{"add", "-type", "dict", "Sub", path}, exitOK},
+		{[]string{"add", "-type", "date", "Sub:When", "2020-01-02T03:04:05Z", path}, exitOK},
+		{[]string{"add", "Items:0", "first", path}, exitOK},
+		{[]string{"add", "Name", "again", path}, exitError},
+		{[]string{"add", "-type", "array", "List", "x", path}, exitUsage},
+		{[]string{"delete", "Items:2", path}, exitOK},
+		{[]string{"delete", "Missing", path}, exitNotFound},
+		{[]string{"set", "Name:x", "y", path}, exitNotFound},
+		{[]string{"delete", "Name", "-"}, exitError},
+	}
+	for _, step := range steps {
+		if code, _, stderr := runTest(t, "", step.args...); code != step.code {
+			t.Errorf("%q: got exit %d, want %d: %s", step.args, code, step.code, stderr)
+		}
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>example</string>
+	<key>Count</key>
+	<integer>5</integer>
+	<key>Items</key>
+	<array>
+		<string>first</string>
+		<string>a</string>
+	</array>
+	<key>Enabled</key>
+	<true/>
+	<key>Sub</key>
+	<dict>
+		<key>When</key>
+		<date>2020-01-02T03:04:05Z</date>
+	</dict>
+</dict>
+</plist>
+`
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestEditKeepsFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.plist")
+	if code, _, stderr := runTest(t, testXML, "convert", "-to", "binary1", "-o", path); code != exitOK {
+		t.Fatal(stderr)
+	}
+	if code, _, stderr := runTest(t, "", "set", "Name", "changed", path); code != exitOK {
+		t.Fatal(stderr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("bplist00")) {
+		t.Errorf("got %q, want a binary property list", data)
+	}
+	if code, stdout, _ := runTest(t, "", "get", "Name", path); code != exitOK || stdout != "changed\n" {
+		t.Errorf("got %d, %q", code, stdout)
+	}
+}
+
+func TestEditOpenStep(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.plist")
+	const openStep = "{Name = example; }"
+	if err := os.WriteFile(path, []byte(openStep), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr := runTest(t, "", "set", "Name", "changed", path)
+	if code != exitError || !strings.Contains(stderr, "cannot edit OpenStep") {
+		t.Errorf("got %d, %q", code, stderr)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != openStep {
+		t.Errorf("file changed to %q, %v", data, err)
+	}
+}
+
+func TestSetValueArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.plist")
+	if err := os.WriteFile(path, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"set", "Count", "-int", "7", path}, exitOK},
+		{[]string{"set", "Flag", "-bool", "YES", path}, exitOK},
+		{[]string{"set", "Items", "-array", "x", "-int", "2", path}, exitOK},
+		{[]string{"set", "Empty", "-dict", path}, exitOK},
+		{[]string{"set", "Count", "-int", "x", path}, exitError},
+		{[]string{"set", "-type", "string", "Name", "-int", "1", path}, exitUsage},
+	}
+	for _, step := range steps {
+		if code, _, stderr := runTest(t, "", step.args...); code != step.code {
+			t.Errorf("%q: got exit %d, want %d: %s", step.args, code, step.code, stderr)
+		}
+	}
+	code, stdout, stderr := runTest(t, "", "convert", "-to", "json", path)
+	if code != exitOK {
+		t.Fatal(stderr)
+	}
+	want := "{\n  \"Name\": \"example\",\n  \"Count\": 7,\n  \"Items\": [\n    \"x\",\n    2\n  ],\n  \"Flag\": true,\n  \"Empty\": {}\n}\n"
+	if stdout != want {
+		t.Errorf("got %q, want %q", stdout, want)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var getCmd = &command{
+	name:    "get",
+	args:    "keypath [file]",
+	summary: "print the value at a key path",
+	run:     runGet,
+}
+
+// runGet implements "plist get [-format raw|json|plist] keypath [file]". Key
+// paths are colon-separated, as in PlistBuddy, and "" or ":" is the root.
+// If the key path doesn't exist, it exits with status 3.
+func runGet(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	format := fs.String("format", "raw", "output `format`: raw, json or plist")
+	if !c.parse(fs, args, 1, 2) {
+		return exitUsage
+	}
+	switch *format {
+	case "raw", "json", "plist":
+	default:
+		return fail(env, c, errors.New("unknown output format "+*format+" (want raw, json or plist)"))
+	}
+	in := "-"
+	if fs.NArg() > 1 {
+		in = fs.Arg(1)
+	}
+	v, _, err := readPlist(env, in)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	v, err = plist.GetPath(v, fs.Arg(0))
+	if err != nil {
+		return failPath(env, c, err)
+	}
+	var out []byte
+	switch *format {
+	case "raw":
+		out, err = formatRaw(v)
+	case "json":
+		out, err = encodePlist(v, formatJSON)
+	case "plist":
+		out, err = xmlFragment(v)
+	}
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if err := writeFile(env, "-", out); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
+
+// failPath prints err and returns exitNotFound if it is because a key path
+// doesn't exist, so scripts can tell a missing entry from other failures.
+func failPath(env *env, c *command, err error) int {
+	code := fail(env, c, err)
+	if errors.Is(err, plist.ErrPathNotFound) || errors.Is(err, plist.ErrNotContainer) || errors.Is(err, plist.ErrBadIndex) {
+		return exitNotFound
+	}
+	return code
+}
+
+// formatRaw formats v for use in shell scripts. Scalars are printed on a
+// line of their own: strings unquoted, dates in RFC 3339 format and data in
+// base64. Dictionaries and arrays are printed as JSON.
+func formatRaw(v interface{}) ([]byte, error) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case time.Time:
+		s = v.UTC().Format(time.RFC3339)
+	case []byte:
+		s = base64.StdEncoding.EncodeToString(v)
+	case plist.UID:
+		s = strconv.FormatUint(uint64(v), 10)
+	default:
+		// numbers decode as whichever type CoreFoundation stored them as
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(rv.Int(), 10)
+		case reflect.Float32, reflect.Float64:
+			s = strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits())
+		default:
+			return encodePlist(v, formatJSON)
+		}
+	}
+	return append([]byte(s), '\n'), nil
+}
+
+// xmlFragment returns the XML property list element for v, without the
+// surrounding document.
+func xmlFragment(v interface{}) ([]byte, error) {
+	data, err := plist.Marshal(v, plist.XMLFormat)
+	if err != nil {
+		return nil, err
+	}
+	const start, end = "<plist version=\"1.0\">\n", "</plist>\n"
+	if i := bytes.Index(data, []byte(start)); i >= 0 && bytes.HasSuffix(data, []byte(end)) {
+		data = data[i+len(start) : len(data)-len(end)]
+	}
+	return data, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	plist "github.com/kballard/go-osx-plist"
+	"github.com/kballard/go-osx-plist/bundle"
+	"github.com/kballard/go-osx-plist/launchd"
+	"github.com/kballard/go-osx-plist/schema"
+)
+
+var lintCmd = &command{
+	name:    "lint",
+	args:    "file...",
+	summary: "check property lists for problems",
+	run:     runLint,
+}
+
+// A lintFinding is a problem found by lint.
+type lintFinding struct {
+	path     string // key path, empty for the file as a whole
+	severity string // "error" or "warning"
+	message  string
+}
+
+// runLint implements "plist lint [-domain none|launchd|info] [-schema file]
+// [-strict] file...". Every file is checked for well-formedness, then against
+// the rules of the domain and the schema, if given. The schema file holds a
+// schema.Schema encoded as a property list.
+//
+// Each finding is printed as "file: keypath: severity: message". The exit
+// status is 1 if any file has errors, or warnings with -strict, so lint can
+// gate CI jobs.
+func runLint(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	domain := fs.String("domain", "none", "also check the rules of `domain`: none, launchd or info (Info.plist)")
+	schemaPath := fs.String("schema", "", "also check against the schema in `file`")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	if !c.parse(fs, args, 1, -1) {
+		return exitUsage
+	}
+	var schemas []*schema.Schema
+	switch *domain {
+	case "none", "launchd":
+	case "info":
+		s, err := infoSchema()
+		if err != nil {
+			return fail(env, c, err)
+		}
+		schemas = append(schemas, s)
+	default:
+		return fail(env, c, errors.New("unknown domain "+*domain+" (want none, launchd or info)"))
+	}
+	if *schemaPath != "" {
+		s, err := readSchema(env, *schemaPath)
+		if err != nil {
+			return fail(env, c, err)
+		}
+		schemas = append(schemas, s)
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		findings := lintFile(env, path, *domain == "launchd", schemas)
+		name := path
+		if name == "-" {
+			name = "<stdin>"
+		}
+		for _, f := range findings {
+			if f.path == "" {
+				fmt.Fprintf(env.stdout, "%s: %s: %s\n", name, f.severity, f.message)
+			} else {
+				fmt.Fprintf(env.stdout, "%s: %s: %s: %s\n", name, f.path, f.severity, f.message)
+			}
+			if f.severity == "error" || *strict {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return exitError
+	}
+	return exitOK
+}
+
+func lintFile(env *env, path string, launchdRules bool, schemas []*schema.Schema) []lintFinding {
+	data, err := readFile(env, path)
+	if err != nil {
+		return []lintFinding{{"", "error", err.Error()}}
+	}
+	v, _, err := decodePlist(data)
+	if err != nil {
+		return []lintFinding{{"", "error", err.Error()}}
+	}
+	var findings []lintFinding
+	if launchdRules {
+		results, err := launchd.Validate(v)
+		if err != nil {
+			return []lintFinding{{"", "error", err.Error()}}
+		}
+		for _, f := range results {
+			findings = append(findings, lintFinding{f.Key, f.Severity.String(), f.Message})
+		}
+	}
+	for _, s := range schemas {
+		for _, violation := range schema.Validate(v, s) {
+			findings = append(findings, lintFinding{violation.Path.String(), "error", violation.Message})
+		}
+	}
+	return findings
+}
+
+// readSchema reads a schema.Schema from the property list in path.
+func readSchema(env *env, path string) (*schema.Schema, error) {
+	v, _, err := readPlist(env, path)
+	if err != nil {
+		return nil, err
+	}
+	// decodePlist accepts more formats than Unmarshal, so re-encode the value
+	data, err := plist.Marshal(v, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	s := new(schema.Schema)
+	if _, err := plist.Unmarshal(data, s); err != nil {
+		return nil, errors.New(path + ": " + err.Error())
+	}
+	return s, nil
+}
+
+// infoSchema returns the schema for Info.plist files: the types of the keys
+// modeled by bundle.Info, plus the keys every bundle needs.
+func infoSchema() (*schema.Schema, error) {
+	s, err := schema.Generate(bundle.Info{})
+	if err != nil {
+		return nil, err
+	}
+	s.Required = append(s.Required, "CFBundleIdentifier")
+	s.Properties["CFBundleIdentifier"].Pattern = `^[A-Za-z0-9.-]+$`
+	s.Properties["CFBundlePackageType"].Pattern = `^.{4}$`
+	s.Properties["CFBundleSignature"].Pattern = `^.{4}$`
+	return s, nil
+}
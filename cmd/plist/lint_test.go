@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJob = `{"Label": "com.example.job", "ProgramArguments": ["/usr/bin/true"], "Bogus": 1}`
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good := write("good.plist", testXML)
+	bad := write("bad.plist", "<plist><dict><key>x</key></dict>")
+	job := write("job.plist", testJob)
+	unlabeled := write("unlabeled.plist", `{"ProgramArguments": ["/usr/bin/true"]}`)
+	info := write("Info.plist", `{"CFBundleIdentifier": "com.example app", "LSUIElement": "yes"}`)
+	schemaFile := write("schema.plist", `{"type": "dictionary", "required": ["Name", "Version"], "properties": {"Count": {"type": "integer", "max": 2}}}`)
+
+	tests := []struct {
+		args []string
+		code int
+		out  []string
+	}{
+		{[]string{"lint", good}, exitOK, nil},
+		{[]string{"lint", good, bad}, exitError, []string{bad + ": error: "}},
+		{[]string{"lint", "-domain", "launchd", job}, exitOK, []string{job + ": Bogus: warning: unknown key"}},
+		{[]string{"lint", "-domain", "launchd", "-strict", job}, exitError, []string{job + ": Bogus: warning: unknown key"}},
+		{[]string{"lint", "-domain", "launchd", unlabeled}, exitError, []string{unlabeled + ": Label: error: is required"}},
+		{[]string{"lint", "-domain", "info", info}, exitError, []string{info + ": CFBundleIdentifier: error: ", info + ": LSUIElement: error: "}},
+		{[]string{"lint", "-schema", schemaFile, good}, exitError, []string{good + ": error: ", good + ": Count: error: "}},
+		{[]string{"lint", "-domain", "bogus", good}, exitError, nil},
+		{[]string{"lint"}, exitUsage, nil},
+	}
+	for _, tc := range tests {
+		code, stdout, stderr := runTest(t, "", tc.args...)
+		if code != tc.code {
+			t.Errorf("%q: got exit %d, want %d: %s", tc.args, code, tc.code, stderr)
+		}
+		lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+		if stdout == "" {
+			lines = nil
+		}
+		if len(lines) != len(tc.out) {
+			t.Errorf("%q: got output %q, want %d lines", tc.args, stdout, len(tc.out))
+			continue
+		}
+		for i, want := range tc.out {
+			if !strings.HasPrefix(lines[i], want) {
+				t.Errorf("%q: line %d: got %q, want prefix %q", tc.args, i, lines[i], want)
+			}
+		}
+	}
+
+	if code, stdout, _ := runTest(t, "not a plist <", "lint", "-"); code != exitError || !strings.HasPrefix(stdout, "<stdin>: error: ") {
+		t.Errorf("standard input: got %d, %q", code, stdout)
+	}
+}
@@ -0,0 +1,264 @@
+// Command plist inspects, converts and edits property lists.
+//
+// Usage:
+//
+//	plist <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	add       add a new entry at a key path
+//	convert   convert a property list to another format
+//	delete    delete the entry at a key path
+//	diff      compare two property lists
+//	get       print the value at a key path
+//	lint      check property lists for problems
+//	merge     layer property lists over a base
+//	print     print a property list as a tree
+//	set       set the value at a key path
+//	unarchive print the objects of NSKeyedArchiver archives
+//	watch     print changes to a property list as they happen
+//
+// Run "plist <command> -h" for the flags of a command. Files named "-" are
+// read from standard input or written to standard output.
+//
+// Property lists are read in any format CoreFoundation understands, as JSON,
+// or as DER-encoded entitlements. Output formats are named as plutil names
+// them: xml1, binary1 and json, plus der. OpenStep property lists can be read
+// but not written, as CoreFoundation can't write them.
+//
+// Key paths are colon-separated, as in PlistBuddy: "Items:3:Name" is the Name
+// entry of the fourth element of the Items array.
+//
+// The exit status is 0 on success, 1 if the command fails, 2 if it is used
+// incorrectly and 3 if a key path doesn't exist. As with diff(1), diff exits
+// with status 1 if the property lists differ and 2 if it fails.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+	"github.com/kballard/go-osx-plist/entitlements"
+)
+
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+)
+
+// A command is a subcommand of plist.
+type command struct {
+	name    string
+	args    string // argument synopsis for usage messages
+	summary string
+	run     func(c *command, env *env, args []string) int
+}
+
+var commands = []*command{
+	convertCmd,
+	getCmd,
+	setCmd,
+	addCmd,
+	deleteCmd,
+	diffCmd,
+	mergeCmd,
+	lintCmd,
+	unarchiveCmd,
+	watchCmd,
+	printCmd,
+}
+
+// An env holds the standard streams, so commands can be run in tests.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{os.Stdin, os.Stdout, os.Stderr}))
+}
+
+func run(args []string, env *env) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		usage(env.stderr)
+		return exitUsage
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(c, env, args[1:])
+		}
+	}
+	fmt.Fprintf(env.stderr, "plist: unknown command %q\n", args[0])
+	usage(env.stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: plist <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	sorted := append([]*command(nil), commands...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+// flags returns a FlagSet for c that reports errors to env.
+func (c *command) flags(env *env) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: plist %s [flags] %s\n", c.name, c.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags in args and checks the number of remaining
+// arguments. It returns false after printing a message if they are invalid.
+func (c *command) parse(fs *flag.FlagSet, args []string, min, max int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if n := fs.NArg(); n < min || (max >= 0 && n > max) {
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+// fail prints err and returns exitError.
+func fail(env *env, c *command, err error) int {
+	fmt.Fprintf(env.stderr, "plist %s: %v\n", c.name, err)
+	return exitError
+}
+
+// The names of the supported formats. formatOpenStep is only read.
+const (
+	formatXML      = "xml1"
+	formatBinary   = "binary1"
+	formatOpenStep = "openstep"
+	formatJSON     = "json"
+	formatDER      = "der"
+)
+
+// formatNames are the formats that can be written.
+var formatNames = []string{formatXML, formatBinary, formatJSON, formatDER}
+
+func checkFormat(name string) error {
+	for _, f := range formatNames {
+		if f == name {
+			return nil
+		}
+	}
+	return errors.New("unknown format " + name + " (want one of " + strings.Join(formatNames, ", ") + ")")
+}
+
+func readFile(env *env, path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(env.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readPlist reads and decodes the property list in path, returning its value,
+// with dictionaries as *plist.Dict values, and the name of its format.
+func readPlist(env *env, path string) (interface{}, string, error) {
+	data, err := readFile(env, path)
+	if err != nil {
+		return nil, "", err
+	}
+	v, format, err := decodePlist(data)
+	if err != nil && path != "-" {
+		err = errors.New(path + ": " + err.Error())
+	}
+	return v, format, err
+}
+
+func decodePlist(data []byte) (interface{}, string, error) {
+	format := formatOpenStep
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(data) > 0 && data[0] == 0x70:
+		// constructed [APPLICATION 16], which starts DER entitlements
+		var v interface{}
+		if err := entitlements.UnmarshalDER(data, &v); err != nil {
+			return nil, "", err
+		}
+		return v, formatDER, nil
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed):
+		var err error
+		if data, err = plist.FromJSON(trimmed, plist.XMLFormat); err != nil {
+			return nil, "", err
+		}
+		format = formatJSON
+	case bytes.HasPrefix(data, []byte("bplist")):
+		format = formatBinary
+	case bytes.HasPrefix(trimmed, []byte("<")) || bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		format = formatXML
+	}
+	dec := plist.NewDecoder(bytes.NewReader(data))
+	dec.UseOrderedDicts()
+	dec.StringifyKeys()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, "", err
+	}
+	return v, format, nil
+}
+
+// encodePlist encodes v in the named format.
+func encodePlist(v interface{}, format string) ([]byte, error) {
+	switch format {
+	case formatXML:
+		return plist.Marshal(v, plist.XMLFormat)
+	case formatBinary:
+		return plist.Marshal(v, plist.BinaryFormat)
+	case formatOpenStep:
+		return nil, errors.New("cannot write OpenStep property lists")
+	case formatJSON:
+		// go through XML, which keeps the order of dictionary keys
+		data, err := plist.Marshal(v, plist.XMLFormat)
+		if err != nil {
+			return nil, err
+		}
+		data, err = plist.ToJSON(data, &plist.JSONOptions{Indent: "  "})
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case formatDER:
+		return entitlements.MarshalDER(v)
+	}
+	return nil, checkFormat(format)
+}
+
+func writeFile(env *env, path string, data []byte) error {
+	if path == "-" {
+		_, err := env.stdout.Write(data)
+		return err
+	}
+	return plist.WriteFile(path, data, false)
+}
+
+// A stringList is a flag that may be repeated to build a list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
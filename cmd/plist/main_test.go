@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>example</string>
+	<key>Count</key>
+	<integer>3</integer>
+	<key>Items</key>
+	<array>
+		<string>a</string>
+		<string>b</string>
+	</array>
+</dict>
+</plist>
+`
+
+// runTest runs the plist command with args and input on standard input, and
+// returns its exit status and output.
+func runTest(t *testing.T, input string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{strings.NewReader(input), &stdout, &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, stderr := runTest(t, ""); code != exitUsage || !strings.Contains(stderr, "convert") {
+		t.Errorf("no arguments: got %d, %q", code, stderr)
+	}
+	if code, _, stderr := runTest(t, "", "bogus"); code != exitUsage || !strings.Contains(stderr, `unknown command "bogus"`) {
+		t.Errorf("unknown command: got %d, %q", code, stderr)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	code, stdout, stderr := runTest(t, testXML, "convert", "-to", "json")
+	if code != exitOK {
+		t.Fatalf("convert to json: exit %d: %s", code, stderr)
+	}
+	wantJSON := "{\n  \"Name\": \"example\",\n  \"Count\": 3,\n  \"Items\": [\n    \"a\",\n    \"b\"\n  ]\n}\n"
+	if stdout != wantJSON {
+		t.Errorf("convert to json: got %q, want %q", stdout, wantJSON)
+	}
+
+	code, stdout, stderr = runTest(t, wantJSON, "convert")
+	if code != exitOK {
+		t.Fatalf("convert from json: exit %d: %s", code, stderr)
+	}
+	if stdout != testXML {
+		t.Errorf("convert from json: got:\n%s\nwant:\n%s", stdout, testXML)
+	}
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.plist")
+	out := filepath.Join(dir, "out.plist")
+	if err := os.WriteFile(in, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runTest(t, "", "convert", "-to", "binary1", "-o", out, in); code != exitOK {
+		t.Fatalf("convert to binary1: exit %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("bplist00")) {
+		t.Errorf("convert to binary1: got %q", data)
+	}
+	if code, stdout, _ := runTest(t, "", "convert", out); code != exitOK || stdout != testXML {
+		t.Errorf("convert from binary1: got %d, %q", code, stdout)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	if code, _, _ := runTest(t, testXML, "convert", "-to", "yaml"); code != exitError {
+		t.Errorf("unknown format: got exit %d", code)
+	}
+	if code, _, stderr := runTest(t, testXML, "convert", "-to", "openstep"); code != exitError || !strings.Contains(stderr, "unknown format openstep") {
+		t.Errorf("openstep output: got %d, %q", code, stderr)
+	}
+	if code, _, _ := runTest(t, "", "convert", "a", "b"); code != exitUsage {
+		t.Errorf("too many arguments: got exit %d", code)
+	}
+	if code, _, _ := runTest(t, "not a plist <", "convert"); code != exitError {
+		t.Errorf("invalid input: got exit %d", code)
+	}
+	if code, _, _ := runTest(t, "", "convert", filepath.Join(t.TempDir(), "missing")); code != exitError {
+		t.Errorf("missing file: got exit %d", code)
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		out  string
+	}{
+		{[]string{"get", "Name"}, exitOK, "example\n"},
+		{[]string{"get", ":Count"}, exitOK, "3\n"},
+		{[]string{"get", "Items:1"}, exitOK, "b\n"},
+		{[]string{"get", "Items"}, exitOK, "[\n  \"a\",\n  \"b\"\n]\n"},
+		{[]string{"get", "-format", "json", "Name"}, exitOK, "\"example\"\n"},
+		{[]string{"get", "-format", "plist", "Items"}, exitOK, "<array>\n\t<string>a</string>\n\t<string>b</string>\n</array>\n"},
+		{[]string{"get", "Missing"}, exitNotFound, ""},
+		{[]string{"get", "Items:5"}, exitNotFound, ""},
+		{[]string{"get", "Name:x"}, exitNotFound, ""},
+		{[]string{"get", "-format", "yaml", "Name"}, exitError, ""},
+		{[]string{"get"}, exitUsage, ""},
+	}
+	for _, tc := range tests {
+		code, stdout, stderr := runTest(t, testXML, tc.args...)
+		if code != tc.code || stdout != tc.out {
+			t.Errorf("%q: got %d, %q (%s), want %d, %q", tc.args, code, stdout, stderr, tc.code, tc.out)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var mergeCmd = &command{
+	name:    "merge",
+	args:    "base overlay...",
+	summary: "layer property lists over a base",
+	run:     runMerge,
+}
+
+var arrayStrategies = map[string]plist.ArrayStrategy{
+	"replace": plist.ReplaceArrays,
+	"append":  plist.AppendArrays,
+	"union":   plist.UnionArrays,
+}
+
+// runMerge implements "plist merge [-arrays strategy] [-array
+// keypath=strategy]... [-to format] [-o output] base overlay...". Each
+// overlay is merged over the result of the previous ones, as plist.Merge
+// does. The result is written in the format of base unless -to is given.
+func runMerge(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	arrays := fs.String("arrays", "replace", "array `strategy`: replace, append or union")
+	var paths stringList
+	fs.Var(&paths, "array", "use a different strategy for the arrays at a key path, as `keypath=strategy` (may be repeated; * matches any key)")
+	to := fs.String("to", "", "output `format` (default the format of base)")
+	out := fs.String("o", "-", "write the result to `file` instead of standard output")
+	if !c.parse(fs, args, 2, -1) {
+		return exitUsage
+	}
+	var opts plist.MergeOptions
+	var ok bool
+	if opts.Arrays, ok = arrayStrategies[*arrays]; !ok {
+		return fail(env, c, errors.New("unknown array strategy "+*arrays+" (want replace, append or union)"))
+	}
+	for _, p := range paths {
+		i := strings.LastIndex(p, "=")
+		if i < 0 {
+			return fail(env, c, errors.New("invalid -array "+p+" (want keypath=strategy)"))
+		}
+		strategy, ok := arrayStrategies[p[i+1:]]
+		if !ok {
+			return fail(env, c, errors.New("unknown array strategy "+p[i+1:]+" (want replace, append or union)"))
+		}
+		if opts.ArrayPaths == nil {
+			opts.ArrayPaths = make(map[string]plist.ArrayStrategy)
+		}
+		opts.ArrayPaths[p[:i]] = strategy
+	}
+	if *to != "" {
+		if err := checkFormat(*to); err != nil {
+			return fail(env, c, err)
+		}
+	}
+
+	v, format, err := readPlist(env, fs.Arg(0))
+	if err != nil {
+		return fail(env, c, err)
+	}
+	for _, path := range fs.Args()[1:] {
+		overlay, _, err := readPlist(env, path)
+		if err != nil {
+			return fail(env, c, err)
+		}
+		v = plist.Merge(v, overlay, &opts)
+	}
+	if *to != "" {
+		format = *to
+	}
+	data, err := encodePlist(v, format)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if err := writeFile(env, *out, data); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.plist")
+	overlay := filepath.Join(dir, "overlay.json")
+	if err := os.WriteFile(base, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(overlay, []byte(`{"Count": 4, "Items": ["b", "c"], "Extra": {"On": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		flags []string
+		want  string
+	}{
+		{nil, `{"Name":"example","Count":4,"Items":["b","c"],"Extra":{"On":true}}`},
+		{[]string{"-arrays", "append"}, `{"Name":"example","Count":4,"Items":["a","b","b","c"],"Extra":{"On":true}}`},
+		{[]string{"-arrays", "append", "-array", "Items=union"}, `{"Name":"example","Count":4,"Items":["a","b","c"],"Extra":{"On":true}}`},
+	}
+	for _, tc := range tests {
+		args := append(append([]string{"merge", "-to", "json"}, tc.flags...), base, overlay)
+		code, stdout, stderr := runTest(t, "", args...)
+		if code != exitOK {
+			t.Errorf("%q: exit %d: %s", args, code, stderr)
+			continue
+		}
+		if got, _ := inlineJSON(mustDecode(t, stdout)); got != tc.want {
+			t.Errorf("%q: got %s, want %s", args, got, tc.want)
+		}
+	}
+
+	// the output defaults to the format of base
+	code, stdout, stderr := runTest(t, "", "merge", base, base)
+	if code != exitOK || stdout != testXML {
+		t.Errorf("merge with itself: got %d, %q (%s)", code, stdout, stderr)
+	}
+
+	for _, flags := range [][]string{{"-arrays", "zip"}, {"-array", "Items"}, {"-array", "Items=zip"}, {"-to", "yaml"}} {
+		args := append(append([]string{"merge"}, flags...), base, overlay)
+		if code, _, _ := runTest(t, "", args...); code != exitError {
+			t.Errorf("%q: got exit %d", args, code)
+		}
+	}
+}
+
+func mustDecode(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, _, err := decodePlist([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
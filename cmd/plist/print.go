@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var printCmd = &command{
+	name:    "print",
+	args:    "[file]",
+	summary: "print a property list as a tree",
+	run:     runPrint,
+}
+
+// runPrint implements "plist print [-types] [-color when] [-depth n] [-data n]
+// [file]". The output has the layout of plutil -p: each dictionary entry is
+// printed as "key => value", and each array element as "index => value".
+// With -types, values are prefixed with their type, and containers with
+// their number of entries.
+func runPrint(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	types := fs.Bool("types", false, "annotate values with their types")
+	color := fs.String("color", "auto", "colorize the output: `when` is auto, always or never")
+	depth := fs.Int("depth", 0, "print only `n` levels of containers (0 for all)")
+	data := fs.Int("data", maxDataDump, "print at most `n` bytes of data values (0 for all)")
+	if !c.parse(fs, args, 0, 1) {
+		return exitUsage
+	}
+	d := newDumper()
+	d.types = *types
+	d.maxDepth = *depth
+	d.dataLimit = *data
+	switch *color {
+	case "always":
+		d.color = true
+	case "never":
+	case "auto":
+		d.color = isTerminal(env.stdout) && os.Getenv("NO_COLOR") == ""
+	default:
+		return fail(env, c, errors.New("invalid -color "+*color+" (want auto, always or never)"))
+	}
+	if *depth < 0 || *data < 0 {
+		return fail(env, c, errors.New("-depth and -data must not be negative"))
+	}
+	path := "-"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	v, _, err := readPlist(env, path)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	d.value(v, 0)
+	d.buf.WriteByte('\n')
+	if err := writeFile(env, "-", d.buf.Bytes()); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w interface{}) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ANSI escape sequences used by colorized output.
+const (
+	colorKey    = "\x1b[34m" // blue
+	colorString = "\x1b[32m" // green
+	colorNumber = "\x1b[36m" // cyan
+	colorOther  = "\x1b[33m" // yellow, for booleans, dates and other scalars
+	colorData   = "\x1b[35m" // magenta
+	colorType   = "\x1b[2m"  // faint
+	colorReset  = "\x1b[0m"
+)
+
+// A dumpRef identifies a map, slice or pointer being printed.
+type dumpRef struct {
+	kind reflect.Kind
+	ptr  uintptr
+}
+
+// A dumper prints property lists and object graphs in the style of plutil
+// -p. Graphs may be cyclic, so references back to an object that is being
+// printed are printed as "<cycle>".
+type dumper struct {
+	buf    bytes.Buffer
+	active map[dumpRef]bool
+
+	types     bool // prefix values with their types
+	color     bool // colorize the output with ANSI escapes
+	maxDepth  int  // levels of containers to print, 0 for all
+	dataLimit int  // bytes of data values to print, 0 for all
+}
+
+// maxDataDump is the default number of bytes of a data value that are
+// printed.
+const maxDataDump = 32
+
+func newDumper() *dumper {
+	return &dumper{active: make(map[dumpRef]bool), dataLimit: maxDataDump}
+}
+
+func (d *dumper) value(v interface{}, depth int) {
+	switch v := v.(type) {
+	case nil:
+		d.paint(colorOther, "nil")
+		return
+	case nestedArchive:
+		d.paint(colorType, "archive")
+		d.buf.WriteByte(' ')
+		d.value(v.value, depth)
+		return
+	case string:
+		d.annotate("string")
+		d.paint(colorString, strconv.Quote(v))
+		return
+	case []byte:
+		d.annotate("data")
+		d.paint(colorData, formatDataDump(v, d.dataLimit))
+		return
+	case time.Time:
+		d.annotate("date")
+		d.paint(colorOther, v.UTC().Format(time.RFC3339Nano))
+		return
+	case plist.UID:
+		d.paint(colorOther, "UID("+strconv.FormatUint(uint64(v), 10)+")")
+		return
+	case *url.URL:
+		d.annotate("url")
+		d.paint(colorString, strconv.Quote(v.String()))
+		return
+	case [16]byte:
+		d.annotate("uuid")
+		d.paint(colorOther, fmt.Sprintf("%X-%X-%X-%X-%X", v[0:4], v[4:6], v[6:8], v[8:10], v[10:]))
+		return
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		if rv.IsNil() {
+			d.paint(colorOther, "nil")
+			return
+		}
+		ref := dumpRef{rv.Kind(), rv.Pointer()}
+		if d.active[ref] {
+			if obj, ok := v.(*plist.ArchivedObject); ok {
+				d.buf.WriteString("<cycle " + obj.Class + ">")
+			} else {
+				d.buf.WriteString("<cycle>")
+			}
+			return
+		}
+		d.active[ref] = true
+		defer delete(d.active, ref)
+	}
+
+	switch v := v.(type) {
+	case *plist.Dict:
+		keys := v.Keys()
+		d.annotate("dict (" + strconv.Itoa(len(keys)) + ")")
+		d.block("{", "}", len(keys), depth, func(i int) {
+			elem, _ := v.Get(keys[i])
+			d.entry(strconv.Quote(keys[i]), elem, depth)
+		})
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d.annotate("dict (" + strconv.Itoa(len(keys)) + ")")
+		d.block("{", "}", len(keys), depth, func(i int) {
+			d.entry(strconv.Quote(keys[i]), v[keys[i]], depth)
+		})
+	case []interface{}:
+		d.annotate("array (" + strconv.Itoa(len(v)) + ")")
+		d.block("[", "]", len(v), depth, func(i int) {
+			d.entry(strconv.Itoa(i), v[i], depth)
+		})
+	case plist.ArchivedSet:
+		d.block("set [", "]", len(v), depth, func(i int) {
+			d.value(v[i], depth+1)
+		})
+	case *plist.ArchivedObject:
+		keys := make([]string, 0, len(v.Fields))
+		for k := range v.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d.block(v.Class+" {", "}", len(keys), depth, func(i int) {
+			d.entry(keys[i], v.Fields[keys[i]], depth)
+		})
+	default:
+		d.reflectValue(rv, depth)
+	}
+}
+
+// reflectValue prints the values that have no special form, such as numbers
+// and the structs of the bridged Foundation types.
+func (d *dumper) reflectValue(rv reflect.Value, depth int) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		d.annotate("bool")
+		d.paint(colorOther, strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := rv.Interface().(fmt.Stringer); ok {
+			d.paint(colorOther, s.String())
+		} else {
+			d.annotate("integer")
+			d.paint(colorNumber, strconv.FormatInt(rv.Int(), 10))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		d.annotate("integer")
+		d.paint(colorNumber, strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		d.annotate("real")
+		d.paint(colorNumber, strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits()))
+	case reflect.Slice, reflect.Array:
+		d.block("[", "]", rv.Len(), depth, func(i int) {
+			d.entry(strconv.Itoa(i), rv.Index(i).Interface(), depth)
+		})
+	case reflect.Map:
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(mapKeys{names, keys})
+		d.block("{", "}", len(keys), depth, func(i int) {
+			d.entry(strconv.Quote(names[i]), rv.MapIndex(keys[i]).Interface(), depth)
+		})
+	case reflect.Ptr:
+		d.value(rv.Elem().Interface(), depth)
+	case reflect.Struct:
+		t := rv.Type()
+		d.block(t.Name()+" {", "}", t.NumField(), depth, func(i int) {
+			d.entry(t.Field(i).Name, rv.Field(i).Interface(), depth)
+		})
+	default:
+		fmt.Fprint(&d.buf, rv.Interface())
+	}
+}
+
+// block prints a container of n entries, calling entry to print each one.
+// The entries of containers nested deeper than maxDepth are elided.
+func (d *dumper) block(open, close string, n, depth int, entry func(i int)) {
+	d.buf.WriteString(open)
+	if n > 0 && d.maxDepth > 0 && depth >= d.maxDepth {
+		d.buf.WriteString("...")
+	} else if n > 0 {
+		d.buf.WriteByte('\n')
+		for i := 0; i < n; i++ {
+			d.indent(depth + 1)
+			entry(i)
+			d.buf.WriteByte('\n')
+		}
+		d.indent(depth)
+	}
+	d.buf.WriteString(close)
+}
+
+func (d *dumper) entry(key string, v interface{}, depth int) {
+	d.paint(colorKey, key)
+	d.buf.WriteString(" => ")
+	d.value(v, depth+1)
+}
+
+// annotate prints the type of the next value if types are enabled.
+func (d *dumper) annotate(typ string) {
+	if d.types {
+		d.paint(colorType, typ)
+		d.buf.WriteByte(' ')
+	}
+}
+
+// paint prints s in the given color if colors are enabled.
+func (d *dumper) paint(color, s string) {
+	if d.color {
+		d.buf.WriteString(color + s + colorReset)
+	} else {
+		d.buf.WriteString(s)
+	}
+}
+
+func (d *dumper) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		d.buf.WriteString("  ")
+	}
+}
+
+// formatDataDump formats data in hexadecimal, truncated to limit bytes if
+// limit is positive.
+func formatDataDump(data []byte, limit int) string {
+	if limit <= 0 || len(data) <= limit {
+		return fmt.Sprintf("<%x>", data)
+	}
+	return fmt.Sprintf("<%x... %d bytes>", data[:limit], len(data))
+}
+
+// mapKeys sorts map keys by their printed form.
+type mapKeys struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (m mapKeys) Len() int           { return len(m.names) }
+func (m mapKeys) Less(i, j int) bool { return m.names[i] < m.names[j] }
+func (m mapKeys) Swap(i, j int) {
+	m.names[i], m.names[j] = m.names[j], m.names[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"print"}, `{
+  "Name" => "example"
+  "Count" => 3
+  "Items" => [
+    0 => "a"
+    1 => "b"
+  ]
+}
+`},
+		{[]string{"print", "-types"}, `dict (3) {
+  "Name" => string "example"
+  "Count" => integer 3
+  "Items" => array (2) [
+    0 => string "a"
+    1 => string "b"
+  ]
+}
+`},
+		{[]string{"print", "-depth", "1"}, `{
+  "Name" => "example"
+  "Count" => 3
+  "Items" => [...]
+}
+`},
+		{[]string{"print", "-color", "always", "-depth", "1"}, "{\n" +
+			"  \x1b[34m\"Name\"\x1b[0m => \x1b[32m\"example\"\x1b[0m\n" +
+			"  \x1b[34m\"Count\"\x1b[0m => \x1b[36m3\x1b[0m\n" +
+			"  \x1b[34m\"Items\"\x1b[0m => [...]\n" +
+			"}\n"},
+	}
+	for _, tc := range tests {
+		code, stdout, stderr := runTest(t, testXML, tc.args...)
+		if code != exitOK {
+			t.Errorf("%q: exit %d: %s", tc.args, code, stderr)
+		} else if stdout != tc.want {
+			t.Errorf("%q: got:\n%s\nwant:\n%s", tc.args, stdout, tc.want)
+		}
+	}
+
+	blob := `{"Blob": {"CF$UID": 1}}`
+	if code, stdout, _ := runTest(t, blob, "print"); code != exitOK || stdout != "{\n  \"Blob\" => UID(1)\n}\n" {
+		t.Errorf("UID: got %d, %q", code, stdout)
+	}
+	data := `<plist version="1.0"><data>AAECAwQFBgcICQ==</data></plist>`
+	if code, stdout, _ := runTest(t, data, "print", "-data", "4"); code != exitOK || stdout != "<00010203... 10 bytes>\n" {
+		t.Errorf("-data 4: got %d, %q", code, stdout)
+	}
+	if code, stdout, _ := runTest(t, data, "print", "-data", "0"); code != exitOK || stdout != "<00010203040506070809>\n" {
+		t.Errorf("-data 0: got %d, %q", code, stdout)
+	}
+	if code, _, stderr := runTest(t, testXML, "print", "-color", "sometimes"); code != exitError || !strings.Contains(stderr, "-color") {
+		t.Errorf("invalid -color: got %d, %q", code, stderr)
+	}
+}
+
+func TestFormatDataDump(t *testing.T) {
+	data := make([]byte, 40)
+	if got, want := formatDataDump(data[:2], 4), "<0000>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := formatDataDump(data, 4), "<00000000... 40 bytes>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
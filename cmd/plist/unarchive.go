@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var unarchiveCmd = &command{
+	name:    "unarchive",
+	args:    "[file]",
+	summary: "print the objects of NSKeyedArchiver archives",
+	run:     runUnarchive,
+}
+
+// runUnarchive implements "plist unarchive [-root name] [file]". If the
+// property list is itself a keyed archive, the object graph of its root is
+// printed. Otherwise the property list is printed with every data value that
+// holds a keyed archive replaced by its object graph, marked "archive". It is
+// an error if no archive is found.
+//
+// Archives with several top-level objects and no -root are printed as a
+// dictionary of their roots.
+func runUnarchive(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	root := fs.String("root", "", "print the top-level object `name` of each archive instead of the root object")
+	if !c.parse(fs, args, 0, 1) {
+		return exitUsage
+	}
+	path := "-"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+	v, _, err := readPlist(env, path)
+	if err != nil {
+		return fail(env, c, err)
+	}
+	var found bool
+	if isKeyedArchive(v) {
+		// re-encode the archive, since it may have been read from JSON
+		var data []byte
+		if data, err = plist.Marshal(v, plist.BinaryFormat); err == nil {
+			v, err = decodeArchive(data, *root)
+		}
+		found = true
+	} else {
+		v, found, err = unarchiveNested(v, nil, *root)
+	}
+	if err != nil {
+		return fail(env, c, err)
+	}
+	if !found {
+		return fail(env, c, errors.New("no keyed archive found"))
+	}
+	d := newDumper()
+	d.value(v, 0)
+	d.buf.WriteByte('\n')
+	if err := writeFile(env, "-", d.buf.Bytes()); err != nil {
+		return fail(env, c, err)
+	}
+	return exitOK
+}
+
+func isKeyedArchive(v interface{}) bool {
+	d, ok := v.(*plist.Dict)
+	if !ok {
+		return false
+	}
+	archiver, _ := d.Get("$archiver")
+	return archiver == "NSKeyedArchiver"
+}
+
+// decodeArchive decodes the named root of the archive data or, if root is
+// empty, its root object or all of its roots.
+func decodeArchive(data []byte, root string) (interface{}, error) {
+	u, err := plist.NewUnarchiver(data)
+	if err != nil {
+		return nil, err
+	}
+	if root != "" {
+		return u.DecodeRoot(root)
+	}
+	names := u.Roots()
+	if len(names) == 1 && names[0] == "root" {
+		return u.Decode()
+	}
+	roots := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		if roots[name], err = u.DecodeRoot(name); err != nil {
+			return nil, err
+		}
+	}
+	return roots, nil
+}
+
+// A nestedArchive is the object graph of a keyed archive found in a data
+// value.
+type nestedArchive struct {
+	value interface{}
+}
+
+// unarchiveNested replaces the data values in v that hold keyed archives by
+// nestedArchives, and reports whether it found any. Containers are modified
+// in place.
+func unarchiveNested(v interface{}, path plist.KeyPath, root string) (interface{}, bool, error) {
+	found := false
+	switch v := v.(type) {
+	case []byte:
+		if !bytes.HasPrefix(v, []byte("bplist")) && !bytes.HasPrefix(bytes.TrimSpace(v), []byte("<?xml")) {
+			return v, false, nil
+		}
+		if _, err := plist.NewUnarchiver(v); err != nil {
+			// not an archive, just data
+			return v, false, nil
+		}
+		obj, err := decodeArchive(v, root)
+		if err != nil {
+			return nil, false, errors.New(path.String() + ": " + err.Error())
+		}
+		return nestedArchive{obj}, true, nil
+	case *plist.Dict:
+		for _, key := range v.Keys() {
+			elem, _ := v.Get(key)
+			elem, ok, err := unarchiveNested(elem, append(path[:len(path):len(path)], key), root)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				v.Set(key, elem)
+				found = true
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			elem, ok, err := unarchiveNested(elem, append(path[:len(path):len(path)], strconv.Itoa(i)), root)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				v[i] = elem
+				found = true
+			}
+		}
+	}
+	return v, found, nil
+}
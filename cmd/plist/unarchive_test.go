@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestUnarchive(t *testing.T) {
+	node := &plist.ArchivedObject{Class: "Node", Fields: map[string]interface{}{
+		"name":  "a",
+		"count": 3,
+		"tags":  []interface{}{"x"},
+	}}
+	node.Fields["self"] = node
+	archive, err := plist.Archive(node, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNode := `Node {
+  count => 3
+  name => "a"
+  self => <cycle Node>
+  tags => [
+    0 => "x"
+  ]
+}
+`
+	code, stdout, stderr := runTest(t, string(archive), "unarchive")
+	if code != exitOK {
+		t.Fatalf("standalone: exit %d: %s", code, stderr)
+	}
+	if stdout != wantNode {
+		t.Errorf("standalone: got:\n%s\nwant:\n%s", stdout, wantNode)
+	}
+
+	binary, err := plist.Archive(node, plist.BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer := &plist.Dict{}
+	outer.Set("Name", "example")
+	outer.Set("State", binary)
+	outer.Set("Other", []byte{1, 2, 3})
+	data, err := plist.Marshal(outer, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "outer.plist")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "Name" => "example"
+  "State" => archive Node {
+    count => 3
+    name => "a"
+    self => <cycle Node>
+    tags => [
+      0 => "x"
+    ]
+  }
+  "Other" => <010203>
+}
+`
+	code, stdout, stderr = runTest(t, "", "unarchive", path)
+	if code != exitOK {
+		t.Fatalf("nested: exit %d: %s", code, stderr)
+	}
+	if stdout != want {
+		t.Errorf("nested: got:\n%s\nwant:\n%s", stdout, want)
+	}
+
+	if code, _, _ := runTest(t, testXML, "unarchive"); code != exitError {
+		t.Errorf("no archive: got exit %d", code)
+	}
+	if code, _, _ := runTest(t, string(archive), "unarchive", "-root", "missing"); code != exitError {
+		t.Errorf("missing root: got exit %d", code)
+	}
+}
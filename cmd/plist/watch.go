@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+	"github.com/kballard/go-osx-plist/prefs"
+)
+
+var watchCmd = &command{
+	name:    "watch",
+	args:    "file | -domain domain",
+	summary: "print changes to a property list as they happen",
+	run:     runWatch,
+}
+
+// watchTimeFormat is the layout of the timestamps of watch output.
+const watchTimeFormat = "2006-01-02T15:04:05.000"
+
+// runWatch implements "plist watch [-interval d] [-n count] file" and "plist
+// watch -domain [-currentHost] [-interval d] [-n count] domain". The file or
+// preferences domain is checked every interval, and each change to its
+// contents is printed as by diff, prefixed with the local time at which it
+// was seen. Watching stops after count changes if -n is given, and otherwise
+// runs until interrupted.
+//
+// A file that can't be read or decoded, as happens while it is being
+// rewritten, is reported once on standard error, and the next successful read
+// is compared against the last good contents.
+func runWatch(c *command, env *env, args []string) int {
+	fs := c.flags(env)
+	domain := fs.Bool("domain", false, "watch the preferences domain with the given ID instead of a file (NSGlobalDomain for the global domain)")
+	currentHost := fs.Bool("currentHost", false, "with -domain, watch the domain of the current host")
+	interval := fs.Duration("interval", time.Second, "how often to check for changes")
+	count := fs.Int("n", 0, "exit after `count` changes (default run until interrupted)")
+	if !c.parse(fs, args, 1, 1) {
+		return exitUsage
+	}
+	if *interval <= 0 {
+		return fail(env, c, errors.New("-interval must be positive"))
+	}
+	var load func() (interface{}, bool, error)
+	if *domain {
+		id := fs.Arg(0)
+		if id == "NSGlobalDomain" {
+			id = prefs.GlobalDomain
+		}
+		load = domainLoader(prefs.Domain{ID: id, ByHost: *currentHost})
+	} else {
+		if fs.Arg(0) == "-" {
+			return fail(env, c, errors.New("cannot watch standard input"))
+		}
+		if *currentHost {
+			return fail(env, c, errors.New("-currentHost requires -domain"))
+		}
+		load = fileLoader(env, fs.Arg(0))
+	}
+
+	prev, _, err := load()
+	if err != nil {
+		return fail(env, c, err)
+	}
+	var lastErr string
+	reported := 0
+	for {
+		time.Sleep(*interval)
+		v, changed, err := load()
+		now := time.Now().Format(watchTimeFormat)
+		if err != nil {
+			if err.Error() != lastErr {
+				fmt.Fprintf(env.stderr, "%s plist watch: %v\n", now, err)
+				lastErr = err.Error()
+			}
+			continue
+		}
+		lastErr = ""
+		if !changed {
+			continue
+		}
+		patch := plist.Diff(prev, v)
+		prev = v
+		text, err := patchText(patch)
+		if err != nil {
+			return fail(env, c, err)
+		}
+		var buf bytes.Buffer
+		for _, line := range bytes.SplitAfter(text, []byte("\n")) {
+			if len(line) > 0 {
+				buf.WriteString(now + " ")
+				buf.Write(line)
+			}
+		}
+		if err := writeFile(env, "-", buf.Bytes()); err != nil {
+			return fail(env, c, err)
+		}
+		reported += len(patch)
+		if *count > 0 && reported >= *count {
+			return exitOK
+		}
+	}
+}
+
+// fileLoader returns a function that reads the property list in path. It
+// reports the file as unchanged, without reading it, if its size and
+// modification time are the same as at the last read.
+func fileLoader(env *env, path string) func() (interface{}, bool, error) {
+	var last os.FileInfo
+	return func() (interface{}, bool, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, false, err
+		}
+		if last != nil && info.Size() == last.Size() && info.ModTime().Equal(last.ModTime()) {
+			return nil, false, nil
+		}
+		v, _, err := readPlist(env, path)
+		if err != nil {
+			return nil, false, err
+		}
+		last = info
+		return v, true, nil
+	}
+}
+
+// domainLoader returns a function that reads the contents of d, picking up
+// changes made by other processes.
+func domainLoader(d prefs.Domain) func() (interface{}, bool, error) {
+	return func() (interface{}, bool, error) {
+		if err := d.Synchronize(); err != nil {
+			return nil, false, err
+		}
+		var m map[string]interface{}
+		if err := d.Load(&m); err != nil {
+			return nil, false, err
+		}
+		return m, true, nil
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watched.plist")
+	if err := os.WriteFile(path, []byte(testXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		code           int
+		stdout, stderr string
+	}
+	done := make(chan result)
+	go func() {
+		code, stdout, stderr := runTest(t, "", "watch", "-interval", "10ms", "-n", "1", path)
+		done <- result{code, stdout, stderr}
+	}()
+
+	// keep changing the file until the change is seen, since the first
+	// changes may be made before watch has read the file
+	var res result
+	for i := 4; ; i++ {
+		contents := strings.Replace(testXML, "<integer>3</integer>", "<integer>"+strconv.Itoa(i)+"</integer>", 1)
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case res = <-done:
+		case <-time.After(50 * time.Millisecond):
+			continue
+		}
+		break
+	}
+	if res.code != exitOK {
+		t.Fatalf("exit %d: %s", res.code, res.stderr)
+	}
+	want := regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3} ~ Count: \d+ -> \d+\n$`)
+	if !want.MatchString(res.stdout) {
+		t.Errorf("got %q", res.stdout)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"watch", "-"},
+		{"watch", filepath.Join(t.TempDir(), "missing")},
+		{"watch", "-interval", "0s", "file"},
+		{"watch", "-currentHost", "file"},
+	} {
+		if code, _, _ := runTest(t, "", args...); code != exitError {
+			t.Errorf("%q: got exit %d, want %d", args, code, exitError)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const plistImport = "github.com/kballard/go-osx-plist"
+
+// A generator writes the methods for the types of a package.
+type generator struct {
+	buf     bytes.Buffer
+	types   map[string]*ast.TypeSpec   // type declarations of the package
+	methods map[string]map[string]bool // method names by receiver type
+	imports map[string]bool            // packages used by the generated code
+	n       int                        // suffix of the last temporary name
+	// usesFail is whether the method being written calls fail
+	usesFail bool
+}
+
+// generate returns the source of a file holding the MarshalPlist and
+// UnmarshalPlist methods of the named struct types of pkg.
+func generate(pkg *sourcePackage, names []string, command string) ([]byte, error) {
+	g := &generator{
+		types:   make(map[string]*ast.TypeSpec),
+		methods: make(map[string]map[string]bool),
+		imports: map[string]bool{plistImport: true},
+	}
+	for _, f := range pkg.files {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				if decl.Tok == token.TYPE {
+					for _, spec := range decl.Specs {
+						spec := spec.(*ast.TypeSpec)
+						g.types[spec.Name.Name] = spec
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv != nil && len(decl.Recv.List) == 1 {
+					g.addMethod(receiverName(decl.Recv.List[0].Type), decl.Name.Name)
+				}
+			}
+		}
+	}
+
+	var structs []*ast.StructType
+	for _, name := range names {
+		spec, ok := g.types[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found in package %s", name, pkg.name)
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return nil, fmt.Errorf("type %s is not a struct", name)
+		}
+		for _, m := range []string{"MarshalPlist", "UnmarshalPlist"} {
+			if g.methods[name][m] {
+				return nil, fmt.Errorf("type %s already has a %s method", name, m)
+			}
+		}
+		structs = append(structs, st)
+	}
+	// the types can refer to each other
+	for _, name := range names {
+		g.addMethod(name, "MarshalPlist")
+		g.addMethod(name, "UnmarshalPlist")
+	}
+	for i, name := range names {
+		if err := g.marshalMethod(name, structFields(structs[i])); err != nil {
+			return nil, err
+		}
+		if err := g.unmarshalMethod(name, structFields(structs[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by %s; DO NOT EDIT.\n\npackage %s\n\nimport (\n", command, pkg.name)
+	var paths []string
+	for path := range g.imports {
+		if path != plistImport {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(&out, "%q\n", path)
+	}
+	fmt.Fprintf(&out, "\nplist %q\n)\n", plistImport)
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %v", err)
+	}
+	return src, nil
+}
+
+func (g *generator) addMethod(typ, name string) {
+	if g.methods[typ] == nil {
+		g.methods[typ] = make(map[string]bool)
+	}
+	g.methods[typ][name] = true
+}
+
+// receiverName returns the name of the type of a method receiver.
+func receiverName(t ast.Expr) string {
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// A field is an exported field of a struct.
+type field struct {
+	name string
+	typ  ast.Expr
+	tag  string // the plist tag
+}
+
+func structFields(st *ast.StructType) []field {
+	var fields []field
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s).Get("plist")
+			}
+		}
+		// like Marshal, skip embedded fields
+		for _, name := range f.Names {
+			if name.IsExported() && tag != "-" {
+				fields = append(fields, field{name.Name, f.Type, tag})
+			}
+		}
+	}
+	return fields
+}
+
+// tagName returns the name given by the tag, which may be empty.
+func (f field) tagName() string {
+	if i := strings.Index(f.tag, ","); i >= 0 {
+		return f.tag[:i]
+	}
+	return f.tag
+}
+
+// key returns the dictionary key the field is encoded as, as Marshal does.
+func (f field) key() (key string, omitEmpty bool) {
+	if i := strings.Index(f.tag, ","); i >= 0 {
+		for _, opt := range strings.Split(f.tag[i+1:], ",") {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+	}
+	if name := f.tagName(); isValidName(name) {
+		return name, omitEmpty
+	}
+	return f.name, omitEmpty
+}
+
+// isValidName reports whether a tag name is used as the key, as Marshal does.
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c) && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// p writes a line of code.
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format+"\n", args...)
+}
+
+// convert returns the source of converting x to the type named typ, unless
+// t, the type of x, already is that type.
+func (g *generator) convert(typ string, t ast.Expr, x string) string {
+	if g.typeString(t) == typ {
+		return x
+	}
+	return typ + "(" + x + ")"
+}
+
+// tmp returns a new name for a temporary variable.
+func (g *generator) tmp(prefix string) string {
+	g.n++
+	return prefix + strconv.Itoa(g.n)
+}
+
+// typeString returns the source of the type t.
+func (g *generator) typeString(t ast.Expr) string {
+	return types.ExprString(t)
+}
+
+// useType returns the source of the type t, to be written to the generated
+// code, noting any imports it needs.
+func (g *generator) useType(t ast.Expr) string {
+	ast.Inspect(t, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok && id.Name == "time" {
+				g.imports["time"] = true
+			}
+		}
+		return true
+	})
+	return g.typeString(t)
+}
+
+// Kinds of field types, as returned by resolve.
+const (
+	kindMethod    = "method" // a type of the package with the method
+	kindBytes     = "[]byte"
+	kindTime      = "time.Time"
+	kindInterface = "interface{}"
+	kindSlice     = "slice"
+	kindMap       = "map"
+	kindPointer   = "pointer"
+)
+
+// basicKinds are the predeclared types that can be encoded.
+var basicKinds = map[string]bool{
+	"string": true, "bool": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "rune": true, "int64": true,
+	"uint8": true, "byte": true, "uint16": true, "uint32": true,
+	"float32": true, "float64": true,
+}
+
+// resolve returns the kind of t, and for slices, maps and pointers the
+// type literal it is based on. Types of the package with the given method,
+// if any, are of kindMethod; other types of the package are resolved to their
+// underlying type. The kind of a predeclared type is its name, with byte and
+// rune resolved to uint8 and int32.
+func (g *generator) resolve(t ast.Expr, method string) (string, ast.Expr, error) {
+	switch t := t.(type) {
+	case *ast.Ident:
+		if basicKinds[t.Name] && g.types[t.Name] == nil {
+			switch t.Name {
+			case "byte":
+				return "uint8", nil, nil
+			case "rune":
+				return "int32", nil, nil
+			}
+			return t.Name, nil, nil
+		}
+		if t.Name == "any" && g.types[t.Name] == nil {
+			return kindInterface, nil, nil
+		}
+		spec, ok := g.types[t.Name]
+		if !ok {
+			break
+		}
+		if method != "" && g.methods[t.Name][method] {
+			return kindMethod, nil, nil
+		}
+		if _, ok := spec.Type.(*ast.StructType); ok {
+			if method == "" {
+				return "struct", nil, nil
+			}
+			return "", nil, fmt.Errorf("struct type %s has no %s method; add it to -type", t.Name, method)
+		}
+		return g.resolve(spec.Type, method)
+	case *ast.SelectorExpr:
+		if types.ExprString(t) == kindTime {
+			return kindTime, nil, nil
+		}
+	case *ast.ArrayType:
+		if t.Len != nil {
+			break
+		}
+		if kind, _, err := g.resolve(t.Elt, ""); err == nil && kind == "uint8" {
+			return kindBytes, t, nil
+		}
+		return kindSlice, t, nil
+	case *ast.MapType:
+		if kind, _, err := g.resolve(t.Key, ""); err == nil && kind == "string" {
+			return kindMap, t, nil
+		}
+	case *ast.StarExpr:
+		return kindPointer, t, nil
+	case *ast.InterfaceType:
+		if len(t.Methods.List) == 0 {
+			return kindInterface, nil, nil
+		}
+	case *ast.ParenExpr:
+		return g.resolve(t.X, method)
+	}
+	return "", nil, fmt.Errorf("unsupported type %s", types.ExprString(t))
+}
+
+// emptyCheck returns a condition that is true if src, of type t, is not
+// empty as omitempty defines it, or "" if it is never empty.
+func (g *generator) emptyCheck(t ast.Expr, src string) string {
+	kind, _, err := g.resolve(t, "")
+	if err != nil {
+		return ""
+	}
+	switch kind {
+	case "string", kindBytes, kindSlice, kindMap:
+		return "len(" + src + ") != 0"
+	case "bool":
+		return src
+	case kindPointer, kindInterface:
+		return src + " != nil"
+	case "struct", kindTime:
+		return ""
+	}
+	return src + " != 0"
+}
+
+func (g *generator) marshalMethod(name string, fields []field) error {
+	g.p("\n// MarshalPlist implements plist.Marshaler.")
+	g.p("func (v %s) MarshalPlist() (interface{}, error) {", name)
+	g.p("m := make(map[string]interface{}, %d)", len(fields))
+	for _, f := range fields {
+		key, omitEmpty := f.key()
+		src := "v." + f.name
+		cond := ""
+		if omitEmpty {
+			cond = g.emptyCheck(f.typ, src)
+		}
+		if cond != "" {
+			g.p("if %s {", cond)
+		}
+		if err := g.encode(f.typ, src, "m["+strconv.Quote(key)+"]"); err != nil {
+			return fmt.Errorf("%s.%s: %v", name, f.name, err)
+		}
+		if cond != "" {
+			g.p("}")
+		}
+	}
+	g.p("return m, nil")
+	g.p("}")
+	return nil
+}
+
+// encode writes statements that set dst to the property list value of src,
+// an expression of type t.
+func (g *generator) encode(t ast.Expr, src, dst string) error {
+	kind, lit, err := g.resolve(t, "MarshalPlist")
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindMethod:
+		if strings.HasPrefix(src, "*") {
+			src = "(" + src + ")"
+		}
+		x := g.tmp("x")
+		g.p("%s, err := %s.MarshalPlist()", x, src)
+		g.p("if err != nil {\nreturn nil, err\n}")
+		g.p("%s = %s", dst, x)
+	case "string", "bool", kindBytes:
+		g.p("%s = %s", dst, g.convert(kind, t, src))
+	case "float32", "float64":
+		g.p("%s = %s", dst, g.convert("float64", t, src))
+	case kindTime, kindInterface:
+		g.p("%s = %s", dst, src)
+	case kindSlice:
+		a, i, e := g.tmp("a"), g.tmp("i"), g.tmp("e")
+		g.p("%s := make([]interface{}, len(%s))", a, src)
+		g.p("for %s, %s := range %s {", i, e, src)
+		if err := g.encode(lit.(*ast.ArrayType).Elt, e, a+"["+i+"]"); err != nil {
+			return err
+		}
+		g.p("}")
+		g.p("%s = %s", dst, a)
+	case kindMap:
+		m, k, e := g.tmp("m"), g.tmp("k"), g.tmp("e")
+		g.p("%s := make(map[string]interface{}, len(%s))", m, src)
+		g.p("for %s, %s := range %s {", k, e, src)
+		if err := g.encode(lit.(*ast.MapType).Value, e, m+"[string("+k+")]"); err != nil {
+			return err
+		}
+		g.p("}")
+		g.p("%s = %s", dst, m)
+	case kindPointer:
+		g.p("if %s == nil {", src)
+		g.p("return nil, &plist.UnsupportedValueError{Str: \"nil pointer\"}")
+		g.p("}")
+		return g.encode(lit.(*ast.StarExpr).X, "*"+src, dst)
+	default:
+		// the remaining basic kinds are integers
+		g.p("%s = %s", dst, g.convert("int64", t, src))
+	}
+	return nil
+}
+
+func (g *generator) unmarshalMethod(name string, fields []field) error {
+	// write the body first, to know whether it needs fail
+	saved := g.buf
+	g.buf = bytes.Buffer{}
+	g.usesFail = false
+	for i, f := range fields {
+		g.p("case %d:", i)
+		if err := g.decode(f.typ, "val", "v."+f.name); err != nil {
+			return fmt.Errorf("%s.%s: %v", name, f.name, err)
+		}
+	}
+	body := g.buf
+	g.buf = saved
+
+	g.p("\n// UnmarshalPlist implements plist.Unmarshaler.")
+	g.p("func (v *%s) UnmarshalPlist(pl interface{}) error {", name)
+	if len(fields) == 0 {
+		g.p("_, err := plist.DecodeDict(pl)")
+		g.p("return err")
+		g.p("}")
+		return nil
+	}
+	g.p("d, err := plist.DecodeDict(pl)")
+	g.p("if err != nil {\nreturn err\n}")
+	if g.usesFail {
+		g.p("var firstErr error")
+		g.p("fail := func(err error) {\nif firstErr == nil {\nfirstErr = err\n}\n}")
+	}
+	g.p("for key, val := range d {")
+	g.p("field := -1")
+	// a key matches the first field whose tag names it, the field with that
+	// name, or the first field whose name matches it case-insensitively
+	g.p("switch key {")
+	seen := make(map[string]bool)
+	var cases []string
+	for i, f := range fields {
+		if name := f.tagName(); name != "" && !seen[name] {
+			seen[name] = true
+			cases = append(cases, fmt.Sprintf("case %q:\nfield = %d", name, i))
+		}
+	}
+	for i, f := range fields {
+		if !seen[f.name] {
+			seen[f.name] = true
+			cases = append(cases, fmt.Sprintf("case %q:\nfield = %d", f.name, i))
+		}
+	}
+	for _, c := range cases {
+		g.p("%s", c)
+	}
+	g.p("default:")
+	g.p("switch {")
+	g.imports["strings"] = true
+	for i, f := range fields {
+		g.p("case strings.EqualFold(key, %q):\nfield = %d", f.name, i)
+	}
+	g.p("}")
+	g.p("}")
+	g.p("switch field {")
+	g.buf.Write(body.Bytes())
+	g.p("}")
+	g.p("}")
+	if g.usesFail {
+		g.p("return firstErr")
+	} else {
+		g.p("return nil")
+	}
+	g.p("}")
+	return nil
+}
+
+// decodeCalls are the plist functions that decode each basic kind, and the
+// types they return.
+var decodeCalls = map[string][2]string{
+	"string":  {"plist.DecodeString(%s)", "string"},
+	"bool":    {"plist.DecodeBool(%s)", "bool"},
+	"int":     {"plist.DecodeInt(%s, 0)", "int64"},
+	"int8":    {"plist.DecodeInt(%s, 8)", "int64"},
+	"int16":   {"plist.DecodeInt(%s, 16)", "int64"},
+	"int32":   {"plist.DecodeInt(%s, 32)", "int64"},
+	"int64":   {"plist.DecodeInt(%s, 64)", "int64"},
+	"uint8":   {"plist.DecodeUint(%s, 8)", "uint64"},
+	"uint16":  {"plist.DecodeUint(%s, 16)", "uint64"},
+	"uint32":  {"plist.DecodeUint(%s, 32)", "uint64"},
+	"float32": {"plist.DecodeFloat(%s, 32)", "float64"},
+	"float64": {"plist.DecodeFloat(%s, 64)", "float64"},
+	kindBytes: {"plist.DecodeData(%s)", "[]byte"},
+	kindTime:  {"plist.DecodeDate(%s)", "time.Time"},
+}
+
+// decode writes statements that store the property list value src in dst, an
+// addressable expression of type t, passing any errors to fail.
+func (g *generator) decode(t ast.Expr, src, dst string) error {
+	kind, lit, err := g.resolve(t, "UnmarshalPlist")
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindMethod:
+		if strings.HasPrefix(dst, "*") {
+			dst = "(" + dst + ")"
+		}
+		g.usesFail = true
+		g.p("if err := %s.UnmarshalPlist(%s); err != nil {\nfail(err)\n}", dst, src)
+	case kindInterface:
+		g.p("%s = %s", dst, src)
+	case kindSlice:
+		g.usesFail = true
+		a, s, i, e := g.tmp("a"), g.tmp("s"), g.tmp("i"), g.tmp("e")
+		g.p("if %s, err := plist.DecodeArray(%s); err != nil {\nfail(err)\n} else {", a, src)
+		g.p("%s := make(%s, len(%s))", s, g.useType(t), a)
+		g.p("for %s, %s := range %s {", i, e, a)
+		if err := g.decode(lit.(*ast.ArrayType).Elt, e, s+"["+i+"]"); err != nil {
+			return err
+		}
+		g.p("}")
+		g.p("%s = %s", dst, s)
+		g.p("}")
+	case kindMap:
+		g.usesFail = true
+		mt := lit.(*ast.MapType)
+		d, k, e, x := g.tmp("d"), g.tmp("k"), g.tmp("e"), g.tmp("x")
+		g.p("if %s, err := plist.DecodeDict(%s); err != nil {\nfail(err)\n} else {", d, src)
+		g.p("if %s == nil {\n%s = make(%s, len(%s))\n}", dst, dst, g.useType(t), d)
+		g.p("for %s, %s := range %s {", k, e, d)
+		g.p("var %s %s", x, g.useType(mt.Value))
+		if err := g.decode(mt.Value, e, x); err != nil {
+			return err
+		}
+		g.p("%s[%s(%s)] = %s", dst, g.useType(mt.Key), k, x)
+		g.p("}")
+		g.p("}")
+	case kindPointer:
+		elem := lit.(*ast.StarExpr).X
+		g.p("if %s == nil {\n%s = new(%s)\n}", dst, dst, g.useType(elem))
+		return g.decode(elem, src, "*"+dst)
+	default:
+		g.usesFail = true
+		x := g.tmp("x")
+		call := decodeCalls[kind]
+		val := x
+		if g.typeString(t) != call[1] {
+			val = g.useType(t) + "(" + x + ")"
+		}
+		g.p("if %s, err := %s; err != nil {\nfail(err)\n} else {\n%s = %s\n}", x, fmt.Sprintf(call[0], src), dst, val)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package ex
+
+import "time"
+
+type Mode int
+
+type Config struct {
+	Name   string ` + "`plist:\"name\"`" + `
+	Count  int    ` + "`plist:\",omitempty\"`" + `
+	Mode   Mode
+	When   time.Time
+	Items  []Item
+	ByName map[string]*Item ` + "`plist:\"by_name,omitempty\"`" + `
+	Extra  interface{}
+	hidden string
+	Skip   string ` + "`plist:\"-\"`" + `
+}
+
+type Item struct {
+	Data  []byte
+	Ratio *float32
+}
+`
+
+// plistStub declares the parts of the plist package the generated code uses,
+// since the package itself needs CoreFoundation to build.
+const plistStub = `package plist
+
+import (
+	"reflect"
+	"time"
+)
+
+type Marshaler interface {
+	MarshalPlist() (interface{}, error)
+}
+
+type Unmarshaler interface {
+	UnmarshalPlist(interface{}) error
+}
+
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string { return e.Str }
+
+func DecodeInt(v interface{}, bits int) (int64, error)                { return 0, nil }
+func DecodeUint(v interface{}, bits int) (uint64, error)              { return 0, nil }
+func DecodeFloat(v interface{}, bits int) (float64, error)            { return 0, nil }
+func DecodeString(v interface{}) (string, error)                      { return "", nil }
+func DecodeBool(v interface{}) (bool, error)                          { return false, nil }
+func DecodeData(v interface{}) ([]byte, error)                        { return nil, nil }
+func DecodeDate(v interface{}) (time.Time, error)                     { return time.Time{}, nil }
+func DecodeArray(v interface{}) ([]interface{}, error)                { return nil, nil }
+func DecodeDict(v interface{}) (map[string]interface{}, error)        { return nil, nil }
+`
+
+// stubImporter imports plistImport from plistStub, and other packages with
+// the default importer.
+type stubImporter struct {
+	fset *token.FileSet
+	std  types.Importer
+}
+
+func (imp stubImporter) Import(path string) (*types.Package, error) {
+	if path != plistImport {
+		return imp.std.Import(path)
+	}
+	f, err := parser.ParseFile(imp.fset, "plist.go", plistStub, 0)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: imp}
+	return conf.Check(plistImport, imp.fset, []*ast.File{f}, nil)
+}
+
+// typeCheck type-checks the generated source out together with the package
+// source src.
+func typeCheck(t *testing.T, src, out string) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for name, s := range map[string]string{"ex.go": src, "ex_plist.go": out} {
+		f, err := parser.ParseFile(fset, name, s, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: stubImporter{fset, importer.Default()}}
+	if _, err := conf.Check("ex", fset, files, nil); err != nil {
+		t.Errorf("generated code doesn't type-check: %v\n%s", err, out)
+	}
+}
+
+// generateTest generates methods for names from the package source src.
+func generateTest(t *testing.T, src string, names ...string) (string, error) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ex.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := parsePackage(dir, filepath.Join(dir, "ex_plist.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := generate(pkg, names, "plistgen")
+	return string(out), err
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := generateTest(t, testSource, "Config", "Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Code generated by plistgen; DO NOT EDIT.\n\npackage ex\n",
+		"\tplist \"github.com/kballard/go-osx-plist\"\n",
+		"func (v Config) MarshalPlist() (interface{}, error) {",
+		"func (v *Config) UnmarshalPlist(pl interface{}) error {",
+		"func (v Item) MarshalPlist() (interface{}, error) {",
+		"func (v *Item) UnmarshalPlist(pl interface{}) error {",
+		`m["name"] = v.Name`,
+		"if v.Count != 0 {",
+		`m["Mode"] = int64(v.Mode)`,
+		"plist.DecodeInt(val, 0)",
+		"v.Mode = Mode(",
+		"plist.DecodeDate(val)",
+		"plist.DecodeFloat(",
+		`m["Ratio"] = float64(*v.Ratio)`,
+		"strings.EqualFold(key, ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"hidden", `"Skip"`} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, out)
+		}
+	}
+	typeCheck(t, testSource, out)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		src   string
+		names []string
+		err   string
+	}{
+		{testSource, []string{"Missing"}, "type Missing not found"},
+		{testSource, []string{"Mode"}, "type Mode is not a struct"},
+		{testSource, []string{"Config"}, "Config.Items: struct type Item has no MarshalPlist method; add it to -type"},
+		{"package ex\ntype T struct{ C chan int }\n", []string{"T"}, "T.C: unsupported type chan int"},
+		{"package ex\ntype T struct{ U uint64 }\n", []string{"T"}, "T.U: unsupported type uint64"},
+		{"package ex\ntype T struct{}\nfunc (T) MarshalPlist() (interface{}, error) { return nil, nil }\n", []string{"T"}, "type T already has a MarshalPlist method"},
+	}
+	for _, test := range tests {
+		_, err := generateTest(t, test.src, test.names...)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: got error %v, want %q", test.names, err, test.err)
+		}
+	}
+}
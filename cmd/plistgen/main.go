@@ -0,0 +1,119 @@
+// Command plistgen generates MarshalPlist and UnmarshalPlist methods for
+// struct types, so that plist.Marshal and plist.Unmarshal encode and decode
+// them without reflection. It is meant to be run by go generate:
+//
+//	//go:generate plistgen -type=Config,Item
+//
+// Usage:
+//
+//	plistgen -type T[,T...] [-output file] [dir]
+//
+// The types are looked up in the package in dir, the current directory by
+// default, and the methods are written to the file named by -output, which
+// defaults to t_plist.go after the first type in lower case.
+//
+// The generated methods follow the rules Marshal and Unmarshal use for
+// structs, including plist tags and their omitempty option, and the same
+// case-insensitive matching of dictionary keys. Fields may be of these types,
+// or types defined in the package with one of them as the underlying type:
+//
+//	string, bool, signed integers, uint8, uint16, uint32, float32, float64
+//	[]byte, time.Time, interface{}
+//	slices and map[string] values of these types, and pointers to them
+//	types of the package with MarshalPlist and UnmarshalPlist methods,
+//	including the other types given to -type
+//
+// Unlike Unmarshal, an UnmarshalPlist method stops the whole Unmarshal call if
+// any value has the wrong type, though it still decodes the rest of the
+// struct first. Keys that match an unexported field are ignored.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of struct type names; required")
+	output := flag.String("output", "", "output file name; default <type>_plist.go")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: plistgen -type T[,T...] [-output file] [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *types == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	names := strings.Split(*types, ",")
+	if *output == "" {
+		*output = strings.ToLower(names[0]) + "_plist.go"
+	}
+	if !filepath.IsAbs(*output) && filepath.Dir(*output) == "." {
+		*output = filepath.Join(dir, *output)
+	}
+
+	pkg, err := parsePackage(dir, *output)
+	if err != nil {
+		fatal(err)
+	}
+	src, err := generate(pkg, names, "plistgen -type="+*types)
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "plistgen:", err)
+	os.Exit(1)
+}
+
+// A sourcePackage is the parsed source of the package the methods are
+// generated for.
+type sourcePackage struct {
+	name  string
+	files []*ast.File
+}
+
+// parsePackage parses the Go files of the package in dir, skipping tests and
+// the output file, which is about to be replaced.
+func parsePackage(dir, output string) (*sourcePackage, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var pkg *sourcePackage
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Clean(path) == filepath.Clean(output) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if pkg == nil {
+			pkg = &sourcePackage{name: f.Name.Name}
+		} else if f.Name.Name != pkg.name {
+			return nil, fmt.Errorf("%s: found packages %s and %s", dir, pkg.name, f.Name.Name)
+		}
+		pkg.files = append(pkg.files, f)
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("%s: no Go files", dir)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,128 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// A KeyCollisionError is returned by Marshal when two keys of a map or Dict
+// are different strings that would end up as the same dictionary key, or
+// could be taken for the same key by anything that normalizes them: strings
+// that are equal after Unicode normalization, such as a file name in NFC and
+// in NFD, or strings that aren't valid UTF-8 and are converted to the same
+// CFString.
+type KeyCollisionError struct {
+	Key1, Key2 string // in sorted order
+}
+
+func (e *KeyCollisionError) Error() string {
+	reason := "after Unicode normalization"
+	if !utf8.ValidString(e.Key1) || !utf8.ValidString(e.Key2) {
+		reason = "once invalid UTF-8 is replaced"
+	}
+	return "plist: dictionary keys " + strconv.Quote(e.Key1) + " and " + strconv.Quote(e.Key2) + " collide " + reason
+}
+
+// checkMapKeys returns a KeyCollisionError if two keys of m collide.
+func checkMapKeys[V any](m map[string]V) error {
+	for k := range m {
+		if !isASCII(k) {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return checkKeyCollisions(keys)
+		}
+	}
+	return nil
+}
+
+// checkValueKeys returns a KeyCollisionError if two of keys, the string keys
+// of a map, collide.
+func checkValueKeys(keys []reflect.Value) error {
+	for _, k := range keys {
+		if !isASCII(k.String()) {
+			strs := make([]string, len(keys))
+			for i, k := range keys {
+				strs[i] = k.String()
+			}
+			return checkKeyCollisions(strs)
+		}
+	}
+	return nil
+}
+
+// checkKeys returns a KeyCollisionError if two of keys, which are distinct,
+// collide.
+func checkKeys(keys []string) error {
+	for _, k := range keys {
+		if !isASCII(k) {
+			return checkKeyCollisions(keys)
+		}
+	}
+	return nil
+}
+
+// checkKeyCollisions returns a KeyCollisionError if two of keys, which are
+// distinct, have the same normalized form. Keys that are all ASCII are their
+// own normalized form, so only the others need converting.
+func checkKeyCollisions(keys []string) error {
+	seen := make(keySet, len(keys))
+	for _, k := range keys {
+		if prev, ok := seen.add(k); ok {
+			return newKeyCollisionError(prev, k)
+		}
+	}
+	return nil
+}
+
+func newKeyCollisionError(k1, k2 string) *KeyCollisionError {
+	if k1 > k2 {
+		k1, k2 = k2, k1
+	}
+	return &KeyCollisionError{k1, k2}
+}
+
+// keySet holds the keys of a dictionary, keyed by their normalized form, for
+// finding keys that collide as they're added one at a time.
+type keySet map[string]string
+
+// add adds k to s. If a key with the same normalized form was already added,
+// it returns that key instead.
+func (s keySet) add(k string) (string, bool) {
+	norm := k
+	if !isASCII(k) {
+		norm = normalizeKey(k)
+	}
+	if prev, ok := s[norm]; ok {
+		return prev, true
+	}
+	s[norm] = k
+	return "", false
+}
+
+// normalizeKey returns the NFC form of the CFString key is converted to.
+func normalizeKey(key string) string {
+	cfStr := convertStringToCFString(key)
+	if cfStr == nil {
+		return key
+	}
+	defer cfRelease(cfTypeRef(cfStr))
+	mutable := cfCreated(C.CFStringCreateMutableCopy(nil, 0, cfStr))
+	defer cfRelease(cfTypeRef(mutable))
+	C.CFStringNormalize(mutable, C.kCFStringNormalizationFormC)
+	return convertCFStringToString(C.CFStringRef(mutable))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
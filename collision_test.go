@@ -0,0 +1,70 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestKeyCollisions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		collide bool
+	}{
+		{"caf\u00e9", "cafe\u0301", true}, // NFC and NFD
+		{"\u212a", "K", true},             // Kelvin sign
+		{"a\x80", "a\xff", true},          // both convert to "a\ufffd"
+		{"caf\u00e9", "cafe", false},
+		{"\u00e9", "\u00e8", false},
+	}
+	for _, test := range tests {
+		d := new(Dict)
+		d.Set(test.a, 1)
+		d.Set(test.b, 2)
+		values := map[string]interface{}{
+			"map[string]string":      map[string]string{test.a: "1", test.b: "2"},
+			"map[string]interface{}": map[string]interface{}{test.a: 1, test.b: 2},
+			"map[string]int":         map[string]int{test.a: 1, test.b: 2},
+			"*Dict":                  d,
+		}
+		for name, v := range values {
+			_, err := Marshal(v, BinaryFormat)
+			encErr := NewEncoder(new(bytes.Buffer), XMLFormat).Encode(v)
+			for _, err := range []error{err, encErr} {
+				if !test.collide {
+					if err != nil {
+						t.Errorf("%s %q %q: %v", name, test.a, test.b, err)
+					}
+					continue
+				}
+				var ce *KeyCollisionError
+				if !errors.As(err, &ce) {
+					t.Errorf("%s %q %q: expected KeyCollisionError, got %v", name, test.a, test.b, err)
+					continue
+				}
+				want := KeyCollisionError{test.a, test.b}
+				if want.Key1 > want.Key2 {
+					want.Key1, want.Key2 = want.Key2, want.Key1
+				}
+				if *ce != want {
+					t.Errorf("%s: got keys %q and %q, expected %q and %q", name, ce.Key1, ce.Key2, want.Key1, want.Key2)
+				}
+			}
+		}
+	}
+}
+
+func TestKeyCollisionErrorMessage(t *testing.T) {
+	tests := []struct {
+		err  KeyCollisionError
+		want string
+	}{
+		{KeyCollisionError{"caf\u00e9", "cafe\u0301"}, "plist: dictionary keys \"caf\u00e9\" and \"cafe\u0301\" collide after Unicode normalization"},
+		{KeyCollisionError{"a\x80", "a\xff"}, `plist: dictionary keys "a\x80" and "a\xff" collide once invalid UTF-8 is replaced`},
+	}
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+	}
+}
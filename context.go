@@ -0,0 +1,64 @@
+package plist
+
+import (
+	"context"
+	"reflect"
+)
+
+// contextCheckInterval is the number of values converted between checks of
+// the context given to MarshalContext or UnmarshalContext.
+const contextCheckInterval = 1024
+
+// A contextChecker checks a context every contextCheckInterval values.
+type contextChecker struct {
+	ctx context.Context // nil if there is nothing to check
+	n   int             // values converted since the last check
+}
+
+// checkContext is called for each value converted, and returns the error of
+// the context if it is done, checking only every contextCheckInterval calls.
+func (c *contextChecker) checkContext() error {
+	if c.ctx == nil {
+		return nil
+	}
+	c.n++
+	if c.n < contextCheckInterval {
+		return nil
+	}
+	c.n = 0
+	return c.ctx.Err()
+}
+
+// MarshalContext is like Marshal, but gives up and returns the error of ctx
+// if ctx is done before the conversion of v is complete. The context is
+// checked periodically while v is converted, but not while the converted
+// value is written out in the given format.
+func MarshalContext(ctx context.Context, v interface{}, format Format) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return marshalAppend(nil, format, func(state *marshalState) (cfTypeRef, error) {
+		state.ctx = ctx
+		cfObj, err := state.marshalValue(reflect.ValueOf(v))
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			cfRelease(cfObj)
+			return nil, err
+		}
+		return cfObj, nil
+	})
+}
+
+// UnmarshalContext is like Unmarshal, but gives up and returns the error of
+// ctx if ctx is done before the property list is stored in v, which may then
+// be partly filled in. The context is checked periodically while the parsed
+// property list is stored in v, but parsing the data itself can't be
+// interrupted.
+func UnmarshalContext(ctx context.Context, data []byte, v interface{}) (Format, error) {
+	if err := ctx.Err(); err != nil {
+		return Format{}, err
+	}
+	return unmarshal(data, v, &unmarshalState{contextChecker: contextChecker{ctx: ctx}})
+}
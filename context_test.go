@@ -0,0 +1,88 @@
+package plist
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// canceler cancels a context when it is marshaled or unmarshaled.
+type canceler struct {
+	cancel context.CancelFunc
+}
+
+func (c canceler) MarshalPlist() (interface{}, error) {
+	c.cancel()
+	return "cancel", nil
+}
+
+func (c *canceler) UnmarshalPlist(interface{}) error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestMarshalContext(t *testing.T) {
+	v := map[string]interface{}{"a": []interface{}{"b", int64(1)}, "c": map[string]string{"d": "e"}}
+	data, err := MarshalContext(context.Background(), v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := Marshal(v, XMLFormat); !reflect.DeepEqual(data, want) {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MarshalContext(ctx, v, XMLFormat); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: got error %v", err)
+	}
+
+	// cancel partway through a long conversion
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	long := make([]interface{}, 10*contextCheckInterval)
+	for i := range long {
+		long[i] = "x"
+	}
+	long[0] = canceler{cancel}
+	if _, err := MarshalContext(ctx, long, BinaryFormat); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled conversion: got error %v", err)
+	}
+}
+
+func TestUnmarshalContext(t *testing.T) {
+	long := make([]string, 10*contextCheckInterval)
+	data, err := Marshal(map[string]interface{}{"long": long}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if _, err := UnmarshalContext(context.Background(), data, &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v["long"].([]interface{}); len(got) != len(long) {
+		t.Errorf("got %d elements, want %d", len(got), len(long))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := UnmarshalContext(ctx, data, &v); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled context: got error %v", err)
+	}
+
+	// cancel partway through a long conversion
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var cs [10 * contextCheckInterval]canceler
+	cs[0].cancel = cancel
+	data, err = Marshal(long, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalContext(ctx, data, &cs); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled conversion: got error %v", err)
+	}
+}
@@ -2,12 +2,30 @@ package plist
 
 // #import <CoreFoundation/CoreFoundation.h>
 // #import <ApplicationServices/ApplicationServices.h> // for CoreGraphics (for CGFloat)
+//
+// // CFKeyedArchiverUID is private API, but it's the only way to read and write
+// // the UID objects that NSKeyedArchiver stores in its property lists.
+// typedef const struct __CFKeyedArchiverUID * CFKeyedArchiverUIDRef;
+// extern CFTypeID _CFKeyedArchiverUIDGetTypeID(void);
+// extern CFKeyedArchiverUIDRef _CFKeyedArchiverUIDCreate(CFAllocatorRef allocator, uint32_t value);
+// extern uint32_t _CFKeyedArchiverUIDGetValue(CFKeyedArchiverUIDRef uid);
+//
+// static void releaseAll(const CFTypeRef *refs, CFIndex count) {
+// 	for (CFIndex i = 0; i < count; i++) {
+// 		if (refs[i] != NULL) {
+// 			CFRelease(refs[i]);
+// 		}
+// 	}
+// }
 import "C"
 
 import (
+	"encoding/hex"
 	"errors"
 	"math"
+	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
 	"time"
 	"unicode/utf8"
@@ -18,10 +36,59 @@ type cfTypeRef C.CFTypeRef
 
 func cfRelease(cfObj cfTypeRef) {
 	if cfObj != nil {
+		cfUntrack(cfObj)
 		C.CFRelease(C.CFTypeRef(cfObj))
 	}
 }
 
+// cfReleaseAll releases the non-nil objects in refs with a single cgo call.
+func cfReleaseAll(refs []cfTypeRef) {
+	if len(refs) > 0 {
+		cfUntrackAll(refs)
+		C.releaseAll((*C.CFTypeRef)(unsafe.Pointer(&refs[0])), C.CFIndex(len(refs)))
+	}
+}
+
+// cfRetain retains cfObj and returns it.
+func cfRetain(cfObj cfTypeRef) cfTypeRef {
+	cfObj = cfTypeRef(C.CFRetain(C.CFTypeRef(cfObj)))
+	cfTrack(cfObj)
+	return cfObj
+}
+
+// cfCreated records ref, which the package got from a Create or Copy function,
+// for leak checking and returns it.
+func cfCreated[T any](ref *T) *T {
+	cfTrack(cfTypeRef(unsafe.Pointer(ref)))
+	return ref
+}
+
+// releasePoolSize is the number of objects a releasePool collects before it
+// releases them.
+const releasePoolSize = 1024
+
+// A releasePool collects the temporary objects of the containers created
+// while marshaling, which keep them alive, and releases them in batches
+// rather than with a cgo call each.
+type releasePool struct {
+	refs []cfTypeRef
+}
+
+// add adds refs to the pool, releasing the pool's objects if it is full.
+func (p *releasePool) add(refs []cfTypeRef) {
+	p.refs = append(p.refs, refs...)
+	if len(p.refs) >= releasePoolSize {
+		p.drain()
+	}
+}
+
+// drain releases all the objects in the pool.
+func (p *releasePool) drain() {
+	cfReleaseAll(p.refs)
+	clear(p.refs)
+	p.refs = p.refs[:0]
+}
+
 func convertValueToCFType(v reflect.Value) (cfTypeRef, error) {
 	if !v.IsValid() {
 		return nil, &UnsupportedValueError{v, "invalid value"}
@@ -32,6 +99,9 @@ func convertValueToCFType(v reflect.Value) (cfTypeRef, error) {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return cfTypeRef(convertInt64ToCFNumber(v.Int())), nil
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if v.Type() == uidType {
+			return cfTypeRef(convertUIDToCFKeyedArchiverUID(UID(v.Uint()))), nil
+		}
 		return cfTypeRef(convertUInt32ToCFNumber(uint32(v.Uint()))), nil
 	case reflect.Uint, reflect.Uintptr:
 		// don't try and convert if uint/uintptr is 64-bits
@@ -39,6 +109,9 @@ func convertValueToCFType(v reflect.Value) (cfTypeRef, error) {
 			return cfTypeRef(convertUInt32ToCFNumber(uint32(v.Uint()))), nil
 		}
 	case reflect.Float32, reflect.Float64:
+		if v.Type() == dateType {
+			return cfTypeRef(convertDateToCFDate(Date(v.Float()))), nil
+		}
 		f := v.Float()
 		if math.IsInf(f, 0) || math.IsNaN(f) {
 			return nil, &UnsupportedValueError{v, strconv.FormatFloat(f, 'g', -1, v.Type().Bits())}
@@ -51,11 +124,24 @@ func convertValueToCFType(v reflect.Value) (cfTypeRef, error) {
 		}
 		return cfTypeRef(cfStr), nil
 	case reflect.Struct:
-		// only struct type we support is time.Time
+		// the only struct types we support are time.Time, url.URL and netip.Addr
 		if v.Type() == reflect.TypeOf(time.Time{}) {
 			return cfTypeRef(convertTimeToCFDate(v.Interface().(time.Time))), nil
 		}
+		if v.Type() == urlType {
+			u := v.Interface().(url.URL)
+			return marshalString(u.String())
+		}
+		if v.Type() == addrType {
+			return marshalAddress(v)
+		}
 	case reflect.Array, reflect.Slice:
+		if isUUIDType(v.Type()) {
+			return marshalString(formatUUID(uuidOf(v)))
+		}
+		if v.Type() == ipType {
+			return marshalAddress(v)
+		}
 		// check for []byte first (byte is uint8)
 		if v.Type().Elem().Kind() == reflect.Uint8 {
 			return cfTypeRef(convertBytesToCFData(v.Interface().([]byte))), nil
@@ -74,8 +160,83 @@ func convertValueToCFType(v reflect.Value) (cfTypeRef, error) {
 	return nil, &UnsupportedTypeError{v.Type()}
 }
 
+// convertInterfaceToCFType is like convertValueToCFType, but switches on the
+// types most often returned by MarshalPlist, including those of the methods
+// generated by plistgen, so that they are converted without reflection.
+func convertInterfaceToCFType(obj interface{}) (cfTypeRef, error) {
+	switch obj := obj.(type) {
+	case string:
+		return marshalString(obj)
+	case bool:
+		return cfTypeRef(convertBoolToCFBoolean(obj)), nil
+	case int64:
+		return cfTypeRef(convertInt64ToCFNumber(obj)), nil
+	case int:
+		return cfTypeRef(convertInt64ToCFNumber(int64(obj))), nil
+	case float64:
+		if !math.IsInf(obj, 0) && !math.IsNaN(obj) {
+			return cfTypeRef(convertFloat64ToCFNumber(obj)), nil
+		}
+	case []byte:
+		return cfTypeRef(convertBytesToCFData(obj)), nil
+	case time.Time:
+		return cfTypeRef(convertTimeToCFDate(obj)), nil
+	case []interface{}:
+		if len(obj) == 0 {
+			return cfTypeRef(cfCreated(C.CFArrayCreate(nil, nil, 0, nil))), nil
+		}
+		plists := make([]cfTypeRef, len(obj))
+		defer cfReleaseAll(plists)
+		for i, elem := range obj {
+			if elem == nil {
+				return nil, &UnsupportedValueError{reflect.ValueOf(obj).Index(i), "nil interface"}
+			}
+			cfObj, err := convertInterfaceToCFType(elem)
+			if err != nil {
+				return nil, err
+			}
+			plists[i] = cfObj
+		}
+		return cfTypeRef(createCFArray(plists)), nil
+	case map[string]interface{}:
+		if err := checkMapKeys(obj); err != nil {
+			return nil, err
+		}
+		keys := make([]cfTypeRef, 0, len(obj))
+		values := make([]cfTypeRef, 0, len(obj))
+		defer func() {
+			cfReleaseAll(keys)
+			cfReleaseAll(values)
+		}()
+		for key, val := range obj {
+			cfKey, err := marshalString(key)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, cfKey)
+			if val == nil {
+				return nil, &UnsupportedValueError{reflect.ValueOf(obj).MapIndex(reflect.ValueOf(key)), "nil interface"}
+			}
+			cfVal, err := convertInterfaceToCFType(val)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, cfVal)
+		}
+		return cfTypeRef(createCFDictionary(keys, values)), nil
+	}
+	return convertValueToCFType(reflect.ValueOf(obj))
+}
+
 // we shouldn't ever get an error from this, but I'd rather not panic
 func convertCFTypeToInterface(cfType cfTypeRef) (interface{}, error) {
+	return convertCFTypeToInterfaceKeys(cfType, false)
+}
+
+// convertCFTypeToInterfaceKeys is like convertCFTypeToInterface, but if
+// stringifyKeys is true, non-string dictionary keys are converted as described
+// by convertCFKeyToString instead of causing an error.
+func convertCFTypeToInterfaceKeys(cfType cfTypeRef, stringifyKeys bool) (interface{}, error) {
 	typeId := C.CFGetTypeID(C.CFTypeRef(cfType))
 	switch typeId {
 	case C.CFStringGetTypeID():
@@ -89,11 +250,13 @@ func convertCFTypeToInterface(cfType cfTypeRef) (interface{}, error) {
 	case C.CFDateGetTypeID():
 		return convertCFDateToTime(C.CFDateRef(cfType)), nil
 	case C.CFArrayGetTypeID():
-		ary, err := convertCFArrayToSlice(C.CFArrayRef(cfType))
+		ary, err := convertCFArrayToSlice(C.CFArrayRef(cfType), stringifyKeys)
 		return ary, err
 	case C.CFDictionaryGetTypeID():
-		dict, err := convertCFDictionaryToMap(C.CFDictionaryRef(cfType))
+		dict, err := convertCFDictionaryToMap(C.CFDictionaryRef(cfType), stringifyKeys)
 		return dict, err
+	case C._CFKeyedArchiverUIDGetTypeID():
+		return convertCFKeyedArchiverUIDToUID(cfType), nil
 	}
 	return nil, &UnknownCFTypeError{typeId}
 }
@@ -104,7 +267,7 @@ func convertBytesToCFData(data []byte) C.CFDataRef {
 	if len(data) > 0 {
 		ptr = (*C.UInt8)((&data[0]))
 	}
-	return C.CFDataCreate(nil, ptr, C.CFIndex(len(data)))
+	return cfCreated(C.CFDataCreate(nil, ptr, C.CFIndex(len(data))))
 }
 
 func convertCFDataToBytes(cfData C.CFDataRef) []byte {
@@ -112,6 +275,37 @@ func convertCFDataToBytes(cfData C.CFDataRef) []byte {
 	return C.GoBytes(unsafe.Pointer(bytes), C.int(C.CFDataGetLength(cfData)))
 }
 
+// convertCFDataToString returns the contents of cfData as a string.
+func convertCFDataToString(cfData C.CFDataRef) string {
+	bytes := C.CFDataGetBytePtr(cfData)
+	return C.GoStringN((*C.char)(unsafe.Pointer(bytes)), C.int(C.CFDataGetLength(cfData)))
+}
+
+// appendCFDataBytes appends the contents of cfData to dst, growing it only if
+// it lacks the capacity.
+func appendCFDataBytes(dst []byte, cfData C.CFDataRef) []byte {
+	n := int(C.CFDataGetLength(cfData))
+	if n == 0 {
+		return dst
+	}
+	if cap(dst)-len(dst) < n {
+		grown := make([]byte, len(dst), len(dst)+n)
+		copy(grown, dst)
+		dst = grown
+	}
+	C.CFDataGetBytes(cfData, C.CFRange{0, C.CFIndex(n)}, (*C.UInt8)(unsafe.Pointer(&dst[len(dst):cap(dst)][0])))
+	return dst[:len(dst)+n]
+}
+
+// convertCFDataPrefixToBytes copies at most the first n bytes of cfData.
+func convertCFDataPrefixToBytes(cfData C.CFDataRef, n int) []byte {
+	length := int(C.CFDataGetLength(cfData))
+	if length < n {
+		n = length
+	}
+	return C.GoBytes(unsafe.Pointer(C.CFDataGetBytePtr(cfData)), C.int(n))
+}
+
 // ===== CFString =====
 // convertStringToCFString may return nil if the input string is not a valid UTF-8 string
 func convertStringToCFString(str string) C.CFStringRef {
@@ -148,7 +342,7 @@ func convertStringToCFString(str string) C.CFStringRef {
 			byteCount = C.CFIndex(len(buf))
 		}
 	}
-	return C.CFStringCreateWithBytes(nil, bytes, byteCount, C.kCFStringEncodingUTF8, C.false)
+	return cfCreated(C.CFStringCreateWithBytes(nil, bytes, byteCount, C.kCFStringEncodingUTF8, C.false))
 }
 
 var runeErrorLen = utf8.RuneLen(utf8.RuneError)
@@ -160,27 +354,49 @@ func convertCFStringToString(cfStr C.CFStringRef) string {
 		// short-cut for empty strings
 		return ""
 	}
+	// Convert into a scratch buffer sized for the worst case, so the string
+	// is converted with a single call, and copy out only the bytes used;
+	// a string made from the buffer itself would keep all of it alive. If the
+	// conversion fails, for some reason, this returns "". Too bad there's no
+	// nil string.
+	buf := getBuffer()
+	scratch := slices.Grow((*buf)[:0], cfStringMaxUTF8Len(length))
+	used := getCFStringUTF8(cfStr, length, scratch[:cap(scratch)])
+	str := string(scratch[:used])
+	putBuffer(buf, scratch)
+	return str
+}
+
+// appendCFStringBytes appends the UTF-8 encoding of cfStr to dst, so that
+// callers that only look at the bytes can reuse a buffer.
+func appendCFStringBytes(dst []byte, cfStr C.CFStringRef) []byte {
+	length := C.CFStringGetLength(cfStr)
+	if length == 0 {
+		return dst
+	}
+	n := len(dst)
+	dst = slices.Grow(dst, cfStringMaxUTF8Len(length))
+	used := getCFStringUTF8(cfStr, length, dst[n:cap(dst)])
+	return dst[:n+used]
+}
+
+// cfStringMaxUTF8Len returns the most bytes the UTF-8 encoding of a CFString
+// of the given length can take.
+func cfStringMaxUTF8Len(length C.CFIndex) int {
+	return int(C.CFStringGetMaximumSizeForEncoding(length, C.CFStringEncoding(C.kCFStringEncodingUTF8)))
+}
+
+// getCFStringUTF8 converts cfStr, which has the given length, to UTF-8 in buf
+// and returns the number of bytes written, or 0 if the conversion fails.
+func getCFStringUTF8(cfStr C.CFStringRef, length C.CFIndex, buf []byte) int {
+	var usedBufLen C.CFIndex
 	cfRange := C.CFRange{0, length}
 	enc := C.CFStringEncoding(C.kCFStringEncodingUTF8)
-	// first find the buffer size necessary
-	var usedBufLen C.CFIndex
-	if C.CFStringGetBytes(cfStr, cfRange, enc, 0, C.false, nil, 0, &usedBufLen) > 0 {
-		bytes := make([]byte, usedBufLen)
-		buffer := (*C.UInt8)(unsafe.Pointer(&bytes[0]))
-		if C.CFStringGetBytes(cfStr, cfRange, enc, 0, C.false, buffer, usedBufLen, nil) > 0 {
-			// bytes is now filled up
-			// convert it to a string
-			header := (*reflect.SliceHeader)(unsafe.Pointer(&bytes))
-			strHeader := &reflect.StringHeader{
-				Data: header.Data,
-				Len:  header.Len,
-			}
-			return *(*string)(unsafe.Pointer(strHeader))
-		}
+	buffer := (*C.UInt8)(unsafe.Pointer(&buf[0]))
+	if C.CFStringGetBytes(cfStr, cfRange, enc, 0, C.false, buffer, C.CFIndex(len(buf)), &usedBufLen) == 0 {
+		return 0
 	}
-
-	// we failed to convert, for some reason. Too bad there's no nil string
-	return ""
+	return int(usedBufLen)
 }
 
 // ===== CFDate =====
@@ -189,7 +405,15 @@ func convertTimeToCFDate(t time.Time) C.CFDateRef {
 	ms := int64(time.Duration(t.UnixNano()) / time.Millisecond * time.Millisecond)
 	nano := C.double(ms) / C.double(time.Second)
 	nano -= C.double(C.kCFAbsoluteTimeIntervalSince1970)
-	return C.CFDateCreate(nil, C.CFAbsoluteTime(nano))
+	return cfCreated(C.CFDateCreate(nil, C.CFAbsoluteTime(nano)))
+}
+
+func convertDateToCFDate(d Date) C.CFDateRef {
+	return cfCreated(C.CFDateCreate(nil, C.CFAbsoluteTime(d)))
+}
+
+func convertCFDateToDate(cfDate C.CFDateRef) Date {
+	return Date(C.CFDateGetAbsoluteTime(cfDate))
 }
 
 func convertCFDateToTime(cfDate C.CFDateRef) time.Time {
@@ -207,9 +431,9 @@ func convertBoolToCFBoolean(b bool) C.CFBooleanRef {
 	// I don't think the CFBoolean constants have retain counts,
 	// but just in case lets call CFRetain on them
 	if b {
-		return C.CFBooleanRef(C.CFRetain(C.CFTypeRef(C.kCFBooleanTrue)))
+		return C.CFBooleanRef(cfRetain(cfTypeRef(C.kCFBooleanTrue)))
 	}
-	return C.CFBooleanRef(C.CFRetain(C.CFTypeRef(C.kCFBooleanFalse)))
+	return C.CFBooleanRef(cfRetain(cfTypeRef(C.kCFBooleanFalse)))
 }
 
 func convertCFBooleanToBool(cfBoolean C.CFBooleanRef) bool {
@@ -220,7 +444,7 @@ func convertCFBooleanToBool(cfBoolean C.CFBooleanRef) bool {
 // for simplicity's sake, only include the largest of any given numeric datatype
 func convertInt64ToCFNumber(i int64) C.CFNumberRef {
 	sint := C.SInt64(i)
-	return C.CFNumberCreate(nil, C.kCFNumberSInt64Type, unsafe.Pointer(&sint))
+	return cfCreated(C.CFNumberCreate(nil, C.kCFNumberSInt64Type, unsafe.Pointer(&sint)))
 }
 
 func convertCFNumberToInt64(cfNumber C.CFNumberRef) int64 {
@@ -232,7 +456,7 @@ func convertCFNumberToInt64(cfNumber C.CFNumberRef) int64 {
 // there is no uint64 CFNumber type, so we have to use the SInt64 one
 func convertUInt32ToCFNumber(u uint32) C.CFNumberRef {
 	sint := C.SInt64(u)
-	return C.CFNumberCreate(nil, C.kCFNumberSInt64Type, unsafe.Pointer(&sint))
+	return cfCreated(C.CFNumberCreate(nil, C.kCFNumberSInt64Type, unsafe.Pointer(&sint)))
 }
 
 func convertCFNumberToUInt32(cfNumber C.CFNumberRef) uint32 {
@@ -243,7 +467,7 @@ func convertCFNumberToUInt32(cfNumber C.CFNumberRef) uint32 {
 
 func convertFloat64ToCFNumber(f float64) C.CFNumberRef {
 	double := C.double(f)
-	return C.CFNumberCreate(nil, C.kCFNumberDoubleType, unsafe.Pointer(&double))
+	return cfCreated(C.CFNumberCreate(nil, C.kCFNumberDoubleType, unsafe.Pointer(&double)))
 }
 
 func convertCFNumberToFloat64(cfNumber C.CFNumberRef) float64 {
@@ -332,6 +556,21 @@ func convertCFNumberToInterface(cfNumber C.CFNumberRef) interface{} {
 	panic("plist: unknown CFNumber type")
 }
 
+// ===== CFKeyedArchiverUID =====
+func convertUIDToCFKeyedArchiverUID(uid UID) C.CFKeyedArchiverUIDRef {
+	return cfCreated(C._CFKeyedArchiverUIDCreate(nil, C.uint32_t(uid)))
+}
+
+// convertCFKeyedArchiverUIDToUID takes a cfTypeRef because the
+// CFKeyedArchiverUIDRef type is only declared in this file's preamble.
+func convertCFKeyedArchiverUIDToUID(cfUID cfTypeRef) UID {
+	return UID(C._CFKeyedArchiverUIDGetValue(C.CFKeyedArchiverUIDRef(cfUID)))
+}
+
+// cfKeyedArchiverUIDTypeID lives here instead of in marshal.go for the same
+// reason.
+var cfKeyedArchiverUIDTypeID = C._CFKeyedArchiverUIDGetTypeID()
+
 // ===== CFArray =====
 // use reflect.Value to support slices of any type
 func convertSliceToCFArray(slice reflect.Value) (C.CFArrayRef, error) {
@@ -341,16 +580,12 @@ func convertSliceToCFArray(slice reflect.Value) (C.CFArrayRef, error) {
 func convertSliceToCFArrayHelper(slice reflect.Value, helper func(reflect.Value) (cfTypeRef, error)) (C.CFArrayRef, error) {
 	if slice.Len() == 0 {
 		// short-circuit 0, so we can assume plists[0] is valid later
-		return C.CFArrayCreate(nil, nil, 0, nil), nil
+		return cfCreated(C.CFArrayCreate(nil, nil, 0, nil)), nil
 	}
 	// assume slice is a slice/array, because our caller already checked
 	plists := make([]cfTypeRef, slice.Len())
 	// defer the release
-	defer func() {
-		for _, cfObj := range plists {
-			cfRelease(cfObj)
-		}
-	}()
+	defer cfReleaseAll(plists)
 	// convert the slice
 	for i := 0; i < slice.Len(); i++ {
 		cfType, err := helper(slice.Index(i))
@@ -361,17 +596,22 @@ func convertSliceToCFArrayHelper(slice reflect.Value, helper func(reflect.Value)
 	}
 
 	// create the array
+	return createCFArray(plists), nil
+}
+
+// createCFArray creates a CFArray holding values, which must not be empty.
+func createCFArray(values []cfTypeRef) C.CFArrayRef {
 	callbacks := (*C.CFArrayCallBacks)(&C.kCFTypeArrayCallBacks)
-	return C.CFArrayCreate(nil, (*unsafe.Pointer)(&plists[0]), C.CFIndex(len(plists)), callbacks), nil
+	return cfCreated(C.CFArrayCreate(nil, (*unsafe.Pointer)(&values[0]), C.CFIndex(len(values)), callbacks))
 }
 
-func convertCFArrayToSlice(cfArray C.CFArrayRef) ([]interface{}, error) {
+func convertCFArrayToSlice(cfArray C.CFArrayRef, stringifyKeys bool) ([]interface{}, error) {
 	var result []interface{}
 	err := convertCFArrayToSliceHelper(cfArray, func(elem cfTypeRef, idx, count int) (bool, error) {
 		if result == nil {
 			result = make([]interface{}, count)
 		}
-		val, err := convertCFTypeToInterface(elem)
+		val, err := convertCFTypeToInterfaceKeys(elem, stringifyKeys)
 		if err != nil {
 			return false, err
 		}
@@ -417,16 +657,15 @@ func convertMapToCFDictionaryHelper(m reflect.Value, helper func(reflect.Value)
 		return nil, &UnsupportedTypeError{m.Type()}
 	}
 	mapKeys := m.MapKeys()
+	if err := checkValueKeys(mapKeys); err != nil {
+		return nil, err
+	}
 	keys := make([]cfTypeRef, len(mapKeys))
 	values := make([]cfTypeRef, len(mapKeys))
 	// defer the release
 	defer func() {
-		for _, cfKey := range keys {
-			cfRelease(cfTypeRef(cfKey))
-		}
-		for _, cfVal := range values {
-			cfRelease(cfTypeRef(cfVal))
-		}
+		cfReleaseAll(keys)
+		cfReleaseAll(values)
 	}()
 	// create the keys and values slices
 	for i, keyVal := range mapKeys {
@@ -458,22 +697,25 @@ func createCFDictionary(keys, values []cfTypeRef) C.CFDictionaryRef {
 	}
 	keyCallbacks := (*C.CFDictionaryKeyCallBacks)(&C.kCFTypeDictionaryKeyCallBacks)
 	valCallbacks := (*C.CFDictionaryValueCallBacks)(&C.kCFTypeDictionaryValueCallBacks)
-	return C.CFDictionaryCreate(nil, keyPtr, valPtr, C.CFIndex(len(keys)), keyCallbacks, valCallbacks)
+	return cfCreated(C.CFDictionaryCreate(nil, keyPtr, valPtr, C.CFIndex(len(keys)), keyCallbacks, valCallbacks))
 }
 
-func convertCFDictionaryToMap(cfDict C.CFDictionaryRef) (map[string]interface{}, error) {
+func convertCFDictionaryToMap(cfDict C.CFDictionaryRef, stringifyKeys bool) (map[string]interface{}, error) {
 	var m map[string]interface{}
-	convertCFDictionaryToMapHelper(cfDict, func(key string, value cfTypeRef, count int) error {
+	err := convertCFDictionaryToMapHelper(cfDict, stringifyKeys, func(key string, value cfTypeRef, count int) error {
 		if m == nil {
 			m = make(map[string]interface{}, count)
 		}
-		val, err := convertCFTypeToInterface(value)
+		val, err := convertCFTypeToInterfaceKeys(value, stringifyKeys)
 		if err != nil {
 			return err
 		}
 		m[key] = val
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 	if m == nil {
 		// must have been an empty dictionary
 		m = make(map[string]interface{}, 0)
@@ -481,24 +723,73 @@ func convertCFDictionaryToMap(cfDict C.CFDictionaryRef) (map[string]interface{},
 	return m, nil
 }
 
-func convertCFDictionaryToMapHelper(cfDict C.CFDictionaryRef, helper func(key string, value cfTypeRef, count int) error) error {
+func convertCFDictionaryToMapHelper(cfDict C.CFDictionaryRef, stringifyKeys bool, helper func(key string, value cfTypeRef, count int) error) error {
+	if !stringifyKeys {
+		return convertCFDictionaryEntries(cfDict, func(cfKey cfTypeRef) (string, error) {
+			return convertCFKeyToString(cfKey, false)
+		}, helper)
+	}
+	// stringified keys may collide with each other or with string keys
+	seen := make(map[string]bool)
+	return convertCFDictionaryEntries(cfDict, func(cfKey cfTypeRef) (string, error) {
+		key, err := convertCFKeyToString(cfKey, true)
+		if err != nil {
+			return "", err
+		}
+		if seen[key] {
+			return "", &DuplicateKeyError{key}
+		}
+		seen[key] = true
+		return key, nil
+	}, helper)
+}
+
+// convertCFDictionaryEntries calls helper with each entry of cfDict, using
+// convertKey to turn its key into a string.
+func convertCFDictionaryEntries(cfDict C.CFDictionaryRef, convertKey func(cfTypeRef) (string, error), helper func(key string, value cfTypeRef, count int) error) error {
 	count := int(C.CFDictionaryGetCount(cfDict))
 	if count == 0 {
 		return nil
 	}
-	cfKeys := make([]cfTypeRef, count)
-	cfVals := make([]cfTypeRef, count)
+	refs := make([]cfTypeRef, 2*count)
+	cfKeys, cfVals := refs[:count], refs[count:]
 	C.CFDictionaryGetKeysAndValues(cfDict, (*unsafe.Pointer)(&cfKeys[0]), (*unsafe.Pointer)(&cfVals[0]))
 	for i := 0; i < count; i++ {
-		cfKey := cfKeys[i]
-		typeId := C.CFGetTypeID(C.CFTypeRef(cfKey))
-		if typeId != C.CFStringGetTypeID() {
-			return &UnsupportedKeyTypeError{int(typeId)}
+		key, err := convertKey(cfKeys[i])
+		if err != nil {
+			return err
 		}
-		key := convertCFStringToString(C.CFStringRef(cfKey))
 		if err := helper(key, cfVals[i], count); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// convertCFKeyToString converts a CFDictionary key to a string. Property list
+// dictionaries only have CFString keys, but dictionaries from other APIs may
+// not. If stringify is true, CFNumber keys are converted to decimal, CFData
+// keys to lowercase hex, and CFBoolean keys to "true" or "false". Any other key
+// results in an UnsupportedKeyTypeError. Callers that stringify keys must check
+// for keys that convert to the same string.
+func convertCFKeyToString(cfKey cfTypeRef, stringify bool) (string, error) {
+	typeId := C.CFGetTypeID(C.CFTypeRef(cfKey))
+	if typeId == C.CFStringGetTypeID() {
+		return convertCFStringToString(C.CFStringRef(cfKey)), nil
+	}
+	if stringify {
+		switch typeId {
+		case C.CFNumberGetTypeID():
+			cfNumber := C.CFNumberRef(cfKey)
+			if C.CFNumberIsFloatType(cfNumber) != C.false {
+				return strconv.FormatFloat(convertCFNumberToFloat64(cfNumber), 'g', -1, 64), nil
+			}
+			return strconv.FormatInt(convertCFNumberToInt64(cfNumber), 10), nil
+		case C.CFDataGetTypeID():
+			return hex.EncodeToString(convertCFDataToBytes(C.CFDataRef(cfKey))), nil
+		case C.CFBooleanGetTypeID():
+			return strconv.FormatBool(convertCFBooleanToBool(C.CFBooleanRef(cfKey))), nil
+		}
+	}
+	return "", &UnsupportedKeyTypeError{int(typeId)}
+}
@@ -2,6 +2,8 @@ package plist
 
 import (
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 	"testing/quick"
 	"time"
@@ -39,6 +41,53 @@ func TestCFString(t *testing.T) {
 	}
 }
 
+func TestCFString_Widths(t *testing.T) {
+	// characters that take 1 to 4 bytes in UTF-8, and 1 or 2 units in UTF-16
+	for _, s := range []string{"a", "é", "€", "𝄞", "a\x00é€𝄞", strings.Repeat("é𝄞", 1000)} {
+		cfStr := convertStringToCFString(s)
+		if cfStr == nil {
+			t.Fatalf("CFStringRef is NULL (%q)", s)
+		}
+		if got := convertCFStringToString(cfStr); got != s {
+			t.Errorf("convertCFStringToString(%q) = %q", s, got)
+		}
+		if got := string(appendCFStringBytes([]byte("x"), cfStr)); got != "x"+s {
+			t.Errorf("appendCFStringBytes(%q) = %q", s, got)
+		}
+		cfRelease(cfTypeRef(cfStr))
+	}
+}
+
+func TestCFString_RetainedSize(t *testing.T) {
+	heapAlloc := func() uint64 {
+		// twice, to also empty the buffer pool
+		runtime.GC()
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return stats.HeapAlloc
+	}
+	const n = 16
+	for _, s := range []string{strings.Repeat("a", 1<<16), strings.Repeat("é", 1<<15), strings.Repeat("𝄞", 1<<14)} {
+		cfStr := convertStringToCFString(s)
+		if cfStr == nil {
+			t.Fatalf("CFStringRef is NULL (%.8q...)", s)
+		}
+		var strs [n]string
+		before := heapAlloc()
+		for i := range strs {
+			strs[i] = convertCFStringToString(cfStr)
+		}
+		retained := (heapAlloc() - before) / n
+		runtime.KeepAlive(strs)
+		cfRelease(cfTypeRef(cfStr))
+		// the worst case is 3 bytes for each UTF-16 unit
+		if limit := uint64(len(s)) * 5 / 4; retained > limit {
+			t.Errorf("%.8q...: each string retains %d bytes, want at most %d", s, retained, limit)
+		}
+	}
+}
+
 func TestCFString_Invalid(t *testing.T) {
 	// go ahead and generate random strings and see if we actually get objects back.
 	// This is testing the unicode replacement functionality.
@@ -179,3 +228,63 @@ func TestArbitrary(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCFKeyToString(t *testing.T) {
+	tests := []struct {
+		key  interface{}
+		want string
+	}{
+		{"name", "name"},
+		{int64(-42), "-42"},
+		{1.5, "1.5"},
+		{[]byte{0xde, 0xad, 0xbe, 0xef}, "deadbeef"},
+		{true, "true"},
+	}
+	for _, test := range tests {
+		ref, err := ToCFType(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := convertCFKeyToString(cfTypeRef(ref), true)
+		if err != nil {
+			t.Errorf("%#v: %v", test.key, err)
+		} else if got != test.want {
+			t.Errorf("%#v: got %q, want %q", test.key, got, test.want)
+		}
+		if _, isString := test.key.(string); !isString {
+			if _, err := convertCFKeyToString(cfTypeRef(ref), false); err == nil {
+				t.Errorf("%#v: expected error without stringify", test.key)
+			}
+		}
+		ReleaseCFType(ref)
+	}
+
+	ref, err := ToCFType(time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ReleaseCFType(ref)
+	if _, err := convertCFKeyToString(cfTypeRef(ref), true); err == nil {
+		t.Error("expected error for CFDate key")
+	}
+}
+
+func BenchmarkCFStringToString(b *testing.B) {
+	for _, bench := range []struct {
+		name, s string
+	}{
+		{"ASCII", strings.Repeat("a", 64)},
+		{"NonASCII", strings.Repeat("é€", 32)},
+		{"LongNonASCII", strings.Repeat("é€𝄞", 1<<12)},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			cfStr := convertStringToCFString(bench.s)
+			defer cfRelease(cfTypeRef(cfStr))
+			b.SetBytes(int64(len(bench.s)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				convertCFStringToString(cfStr)
+			}
+		})
+	}
+}
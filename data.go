@@ -0,0 +1,114 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// noCopyDataSize is the size from which Marshal uses a []byte in place
+// instead of copying it into a CFData.
+const noCopyDataSize = 64 << 10
+
+// A dataPinner pins the byte slices of CFData created without copying. It is
+// shared by the parallel workers of a marshalState, so it locks around Pin.
+type dataPinner struct {
+	mu     sync.Mutex
+	pinner runtime.Pinner
+}
+
+func (p *dataPinner) pin(ptr *byte) {
+	p.mu.Lock()
+	p.pinner.Pin(ptr)
+	p.mu.Unlock()
+}
+
+func (p *dataPinner) unpin() {
+	p.mu.Lock()
+	p.pinner.Unpin()
+	p.mu.Unlock()
+}
+
+// convertBytesToCFData converts data to a CFData. If state has a pinner, which
+// Marshal sets because none of the objects it creates outlive the call, data
+// of at least noCopyDataSize bytes is pinned and used without copying. The
+// pinner must be unpinned only after every object has been released.
+func (state *marshalState) convertBytesToCFData(data []byte) C.CFDataRef {
+	if state.pinner == nil || len(data) < noCopyDataSize {
+		return convertBytesToCFData(data)
+	}
+	state.pinner.pin(&data[0])
+	return cfCreated(C.CFDataCreateWithBytesNoCopy(nil, (*C.UInt8)(&data[0]), C.CFIndex(len(data)), C.kCFAllocatorNull))
+}
+
+var dataViewType = reflect.TypeOf(DataView{})
+
+// A DataView gives access to the bytes of a CFData decoded from a property
+// list without copying them. Using a DataView in place of a []byte field
+// avoids holding a second copy of large data values in Go memory.
+//
+// The DataView holds a reference to the CFData, which is released when
+// Release is called, or when the DataView becomes unreachable if Release is
+// never called. A slice returned by Bytes is only valid until then, so callers
+// should either call Release once they are done with it, or keep the DataView
+// alive with runtime.KeepAlive.
+//
+// A DataView marshals as the CFData it holds, so values can be re-encoded
+// without copying the data either.
+type DataView struct {
+	obj CFObject
+}
+
+// UnmarshalPlistCF implements CFUnmarshaler. The value must be a CFData. Any
+// CFData previously held by d is released.
+func (d *DataView) UnmarshalPlistCF(obj CFObject) error {
+	typeID := C.CFGetTypeID(C.CFTypeRef(obj.Ref()))
+	if typeID != cfDataTypeID {
+		obj.Release()
+		return &UnmarshalTypeError{cfTypeNames[typeID], dataViewType}
+	}
+	d.obj.Release()
+	d.obj = obj
+	return nil
+}
+
+// MarshalPlistCF implements CFMarshaler.
+func (d DataView) MarshalPlistCF() (CFObject, error) {
+	return d.obj, nil
+}
+
+// Bytes returns the data, which must not be modified. It returns nil if d
+// holds no data.
+func (d DataView) Bytes() []byte {
+	if d.obj.IsNil() {
+		return nil
+	}
+	cfData := C.CFDataRef(d.obj.Ref())
+	n := int(C.CFDataGetLength(cfData))
+	if n == 0 {
+		return []byte{}
+	}
+	b := unsafe.Slice((*byte)(unsafe.Pointer(C.CFDataGetBytePtr(cfData))), n)
+	runtime.KeepAlive(d.obj)
+	return b
+}
+
+// Len returns the length of the data.
+func (d DataView) Len() int {
+	if d.obj.IsNil() {
+		return 0
+	}
+	n := int(C.CFDataGetLength(C.CFDataRef(d.obj.Ref())))
+	runtime.KeepAlive(d.obj)
+	return n
+}
+
+// Release releases the CFData held by d. Slices returned by Bytes must not be
+// used afterwards. It is safe to call Release more than once.
+func (d DataView) Release() {
+	d.obj.Release()
+}
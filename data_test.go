@@ -0,0 +1,67 @@
+package plist
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalLargeData(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), noCopyDataSize/16+1)
+	v := map[string]interface{}{"big": big, "small": []byte{1, 2, 3}}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string][]byte
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got["big"], big) || !bytes.Equal(got["small"], []byte{1, 2, 3}) {
+			t.Errorf("%v: data changed in the round trip", format)
+		}
+	}
+}
+
+func TestDataView(t *testing.T) {
+	big := bytes.Repeat([]byte{0xa5}, noCopyDataSize)
+	data, err := Marshal(map[string]interface{}{"Blob": big, "Name": "x"}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Blob DataView
+		Name string
+	}
+	if _, err := Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Blob.Len() != len(big) || !bytes.Equal(s.Blob.Bytes(), big) {
+		t.Fatalf("got %d bytes, want %d", s.Blob.Len(), len(big))
+	}
+
+	// the view marshals as the CFData it holds
+	again, err := Marshal(&s, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var round map[string]interface{}
+	if _, err := Unmarshal(again, &round); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := round["Blob"].([]byte); !bytes.Equal(b, big) {
+		t.Error("re-marshaled view has different data")
+	}
+
+	s.Blob.Release()
+	s.Blob.Release()
+	if s.Blob.Bytes() != nil || s.Blob.Len() != 0 {
+		t.Error("released view still has data")
+	}
+
+	var wrong struct{ Name DataView }
+	_, err = Unmarshal(data, &wrong)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Errorf("CFString: got error %v, want UnmarshalTypeError", err)
+	}
+}
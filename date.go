@@ -0,0 +1,30 @@
+package plist
+
+import (
+	"math"
+	"reflect"
+	"time"
+)
+
+// A Date is a CFAbsoluteTime, the number of seconds since the NSDate reference
+// date of January 1, 2001 UTC. Dates encode as CFDates, as time.Time values
+// do, and CFDates unmarshal into them. Unlike a time.Time, which is rounded
+// to the millisecond on the way in and out of a CFDate, a Date holds exactly
+// the value stored in the property list, so one decoded and encoded again
+// writes back the same bytes, even far in the past or future where a
+// time.Time can't represent the value exactly.
+type Date float64
+
+var dateType = reflect.TypeOf(Date(0))
+
+// DateOf returns the Date of t.
+func DateOf(t time.Time) Date {
+	return Date(float64(t.Unix()-cocoaEpoch) + float64(t.Nanosecond())/float64(time.Second))
+}
+
+// Time returns d as a time.Time in the local time zone, to the nearest
+// nanosecond that a time.Time can represent.
+func (d Date) Time() time.Time {
+	sec, frac := math.Modf(float64(d))
+	return time.Unix(int64(sec)+cocoaEpoch, int64(math.Round(frac*float64(time.Second))))
+}
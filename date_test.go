@@ -0,0 +1,98 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDateTime(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 123456789, time.UTC)
+	d := DateOf(when)
+	if want := Date(636527167.123456789); math.Abs(float64(d-want)) > 1e-6 {
+		t.Errorf("DateOf: got %v, want %v", float64(d), float64(want))
+	}
+	if got := d.Time(); got.Sub(when).Abs() > time.Microsecond {
+		t.Errorf("Time: got %v, want %v", got, when)
+	}
+	if got := Date(0).Time(); !got.Equal(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date(0).Time(): got %v", got)
+	}
+	if got := Date(-1.5).Time(); !got.Equal(time.Date(2000, 12, 31, 23, 59, 58, 5e8, time.UTC)) {
+		t.Errorf("Date(-1.5).Time(): got %v", got)
+	}
+}
+
+type dated struct {
+	Created  Date
+	Modified *Date
+	History  []Date
+}
+
+func TestDateRoundTrip(t *testing.T) {
+	// values that a time.Time rounded to the millisecond would change
+	far := Date(1e13 + 0.1)
+	v := dated{
+		Created:  Date(636527167.123456789),
+		Modified: &far,
+		History:  []Date{Date(-63113904000.000244), Date(math.Nextafter(1, 2))},
+	}
+	encode := NewTypedEncoder[dated]()
+	data, err := Marshal(v, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, BinaryFormat).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	typed, err := encode(v, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{data, buf.Bytes(), typed} {
+		var got dated
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Created != v.Created || *got.Modified != far || len(got.History) != 2 || got.History[0] != v.History[0] || got.History[1] != v.History[1] {
+			t.Errorf("got %v, want %v", got, v)
+		}
+		got = dated{}
+		if _, err := NewTypedDecoder[dated]().Decode(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Created != v.Created || *got.Modified != far {
+			t.Errorf("TypedDecoder got %v, want %v", got, v)
+		}
+
+		// the dates are CFDates, and come back out as they went in
+		var plist map[string]interface{}
+		if _, err := Unmarshal(data, &plist); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := plist["Created"].(time.Time); !ok {
+			t.Errorf("Created is %T, not a date", plist["Created"])
+		}
+		again, err := Marshal(got, BinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotAgain dated
+		if _, err := Unmarshal(again, &gotAgain); err != nil {
+			t.Fatal(err)
+		}
+		if gotAgain.Created != v.Created {
+			t.Errorf("second round trip: got %v, want %v", gotAgain.Created, v.Created)
+		}
+	}
+
+	data, err = Marshal(dated{Created: DateOf(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)), Modified: &far}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("<date>2021-03-04T05:06:07Z</date>")) {
+		t.Errorf("unexpected XML:\n%s", data)
+	}
+}
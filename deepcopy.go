@@ -0,0 +1,110 @@
+package plist
+
+import "reflect"
+
+// DeepCopy returns a copy of v that shares no mutable state with it, so either
+// can be modified without affecting the other. Maps, slices (including []byte
+// data), arrays, pointers, Dicts and the exported fields of structs are copied
+// recursively; other values are returned as they are. Unexported struct
+// fields are copied shallowly.
+//
+// v must not contain cycles, which property lists can't represent anyway.
+// CFObject.DeepCopy copies a CoreFoundation object instead.
+func DeepCopy(v interface{}) interface{} {
+	return copyValue(v)
+}
+
+// copyValue implements DeepCopy, with fast paths for the types produced by
+// decoding into an interface{}.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, string, bool, int64, float64:
+		return v
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[k] = copyValue(val)
+		}
+		return m
+	case *Dict:
+		d := &Dict{keys: append([]string(nil), v.keys...), values: make(map[string]interface{}, len(v.values))}
+		for k, val := range v.values {
+			d.values[k] = copyValue(val)
+		}
+		return d
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, val := range v {
+			a[i] = copyValue(val)
+		}
+		return a
+	case []byte:
+		if v == nil {
+			return v
+		}
+		return append([]byte{}, v...)
+	}
+	return copyReflect(reflect.ValueOf(v)).Interface()
+}
+
+func copyReflect(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(copyReflect(iter.Key()), copyReflect(iter.Value()))
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(copyReflect(v.Index(i)))
+		}
+		return s
+	case reflect.Array:
+		a := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			a.Index(i).Set(copyReflect(v.Index(i)))
+		}
+		return a
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if v.Type() == dictPtrType {
+			return reflect.ValueOf(copyValue(v.Interface()))
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(copyReflect(v.Elem()))
+		return p
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		i := reflect.New(v.Type()).Elem()
+		i.Set(copyReflect(v.Elem()))
+		return i
+	case reflect.Struct:
+		s := reflect.New(v.Type()).Elem()
+		if v.Type() == dictType {
+			d := v.Interface().(Dict)
+			s.Set(reflect.ValueOf(copyValue(&d)).Elem())
+			return s
+		}
+		s.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := s.Field(i); f.CanSet() {
+				f.Set(copyReflect(v.Field(i)))
+			}
+		}
+		return s
+	}
+	return v
+}
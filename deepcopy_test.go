@@ -0,0 +1,55 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeepCopy(t *testing.T) {
+	d := &Dict{}
+	d.Set("k", []interface{}{"v"})
+	orig := map[string]interface{}{
+		"array": []interface{}{map[string]interface{}{"a": int64(1)}},
+		"data":  []byte("abc"),
+		"dict":  d,
+		"date":  time.Unix(0, 0),
+	}
+	cp := DeepCopy(orig).(map[string]interface{})
+	if !reflect.DeepEqual(cp, orig) {
+		t.Fatalf("copy differs: %#v", cp)
+	}
+	cp["array"].([]interface{})[0].(map[string]interface{})["a"] = int64(2)
+	cp["data"].([]byte)[0] = 'X'
+	cp["dict"].(*Dict).Set("new", true)
+	if orig["array"].([]interface{})[0].(map[string]interface{})["a"] != int64(1) {
+		t.Error("nested map is shared")
+	}
+	if string(orig["data"].([]byte)) != "abc" {
+		t.Error("data is shared")
+	}
+	if d.Len() != 1 {
+		t.Error("Dict is shared")
+	}
+}
+
+func TestDeepCopyTyped(t *testing.T) {
+	type inner struct {
+		Tags []string
+	}
+	type outer struct {
+		Name  string
+		Inner *inner
+		Map   map[string][]int
+	}
+	orig := outer{"x", &inner{[]string{"a"}}, map[string][]int{"k": {1}}}
+	cp := DeepCopy(orig).(outer)
+	if !reflect.DeepEqual(cp, orig) {
+		t.Fatalf("copy differs: %#v", cp)
+	}
+	cp.Inner.Tags[0] = "b"
+	cp.Map["k"][0] = 2
+	if orig.Inner.Tags[0] != "a" || orig.Map["k"][0] != 1 {
+		t.Errorf("copy shares state with original: %#v", orig)
+	}
+}
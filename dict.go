@@ -0,0 +1,99 @@
+package plist
+
+import "reflect"
+
+// A Dict is a property list dictionary that remembers the order of its keys.
+// CFDictionary doesn't preserve key order, so a Dict can be used to edit a
+// property list file without reordering its contents.
+//
+// When unmarshaling an XML property list into a Dict, or into an interface{}
+// with a Decoder set to UseOrderedDicts, keys are ordered as they appear in the
+// file. Dictionaries nested in a Dict are decoded as *Dict values as well. Key
+// order can't be recovered from binary property lists, so keys are sorted
+// instead.
+//
+// When marshaling a Dict to XMLFormat, its keys are written in order. Other
+// formats don't preserve key order.
+//
+// The zero value is an empty Dict ready to use.
+type Dict struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+var dictType = reflect.TypeOf(Dict{})
+var dictPtrType = reflect.TypeOf(&Dict{})
+
+// Len returns the number of entries in d.
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
+
+// Keys returns the keys of d in order.
+func (d *Dict) Keys() []string {
+	return append([]string(nil), d.keys...)
+}
+
+// Get returns the value for key, and whether it was present.
+func (d *Dict) Get(key string) (interface{}, bool) {
+	v, ok := d.values[key]
+	return v, ok
+}
+
+// Set sets the value for key. A new key is added at the end; an existing key
+// keeps its position.
+func (d *Dict) Set(key string, value interface{}) {
+	if d.values == nil {
+		d.values = make(map[string]interface{})
+	}
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = value
+}
+
+// Delete removes key from d, if present.
+func (d *Dict) Delete(key string) {
+	if _, ok := d.values[key]; !ok {
+		return
+	}
+	delete(d.values, key)
+	for i, k := range d.keys {
+		if k == key {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Map returns the entries of d as a map. Nested values are not converted.
+func (d *Dict) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(d.keys))
+	for k, v := range d.values {
+		m[k] = v
+	}
+	return m
+}
+
+// containsDict reports whether values of type t can hold a Dict, and so need
+// the key order of the input when unmarshaling.
+func containsDict(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == nil || seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsDict(t.Elem(), seen)
+	case reflect.Struct:
+		if t == dictType {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if containsDict(t.Field(i).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package plist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDict(t *testing.T) {
+	var d Dict
+	d.Set("b", 1)
+	d.Set("a", 2)
+	d.Set("c", 3)
+	d.Set("b", 4)
+	d.Delete("a")
+	d.Delete("missing")
+	if !reflect.DeepEqual(d.Keys(), []string{"b", "c"}) {
+		t.Errorf("got keys %v", d.Keys())
+	}
+	if v, ok := d.Get("b"); !ok || v != 4 {
+		t.Errorf("got %v, %v", v, ok)
+	}
+	if d.Len() != 2 {
+		t.Errorf("got length %d", d.Len())
+	}
+}
+
+func TestEncodeOrderedXML(t *testing.T) {
+	inner := &Dict{}
+	inner.Set("z", "last<&>")
+	inner.Set("a", []interface{}{})
+	d := &Dict{}
+	d.Set("zebra", inner)
+	d.Set("apple", map[string]interface{}{"y": true, "x": int64(-1)})
+	d.Set("date", time.Date(2009, 2, 13, 23, 31, 30, 0, time.UTC))
+	d.Set("data", []byte("hello"))
+	d.Set("real", 1.5)
+	got := string(appendOrderedXML(nil, d))
+	want := xmlPlistHeader + `<dict>
+	<key>zebra</key>
+	<dict>
+		<key>z</key>
+		<string>last&lt;&amp;&gt;</string>
+		<key>a</key>
+		<array/>
+	</dict>
+	<key>apple</key>
+	<dict>
+		<key>x</key>
+		<integer>-1</integer>
+		<key>y</key>
+		<true/>
+	</dict>
+	<key>date</key>
+	<date>2009-02-13T23:31:30Z</date>
+	<key>data</key>
+	<data>
+	aGVsbG8=
+	</data>
+	<key>real</key>
+	<real>1.5</real>
+</dict>
+</plist>
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestScanKeyOrder(t *testing.T) {
+	data := xmlPlistHeader + `<dict>
+	<key>b</key>
+	<array>
+		<string>x</string>
+		<dict>
+			<key>y</key>
+			<string/>
+			<key>x</key>
+			<string/>
+		</dict>
+	</array>
+	<key>a</key>
+	<dict/>
+</dict>
+</plist>
+`
+	n := scanKeyOrder([]byte(data))
+	if !reflect.DeepEqual(n.keys, []string{"b", "a"}) {
+		t.Fatalf("got keys %v", n.keys)
+	}
+	if got := n.key("b").index(1).orderKeys([]string{"z", "x", "y"}); !reflect.DeepEqual(got, []string{"y", "x", "z"}) {
+		t.Errorf("got %v", got)
+	}
+	if n.key("b").index(0) != nil {
+		t.Error("expected nil keyOrder for a string")
+	}
+}
+
+func TestDictRoundTrip(t *testing.T) {
+	data := xmlPlistHeader + `<dict>
+	<key>zebra</key>
+	<string>z</string>
+	<key>apple</key>
+	<dict>
+		<key>second</key>
+		<integer>2</integer>
+		<key>first</key>
+		<integer>1</integer>
+	</dict>
+	<key>mango</key>
+	<array>
+		<dict>
+			<key>b</key>
+			<true/>
+			<key>a</key>
+			<false/>
+		</dict>
+	</array>
+</dict>
+</plist>
+`
+	var d Dict
+	if _, err := Unmarshal([]byte(data), &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.Keys(), []string{"zebra", "apple", "mango"}) {
+		t.Errorf("got keys %v", d.Keys())
+	}
+	apple, _ := d.Get("apple")
+	if a, ok := apple.(*Dict); !ok || !reflect.DeepEqual(a.Keys(), []string{"second", "first"}) {
+		t.Errorf("got nested %#v", apple)
+	}
+	out, err := Marshal(&d, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("round trip changed the file:\n%s", out)
+	}
+
+	var v interface{}
+	dec := NewDecoder(strings.NewReader(data))
+	dec.UseOrderedDicts()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	mango, err := GetPath(v, "mango:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := mango.(*Dict); !ok || !reflect.DeepEqual(m.Keys(), []string{"b", "a"}) {
+		t.Errorf("got %#v", mango)
+	}
+}
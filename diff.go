@@ -0,0 +1,158 @@
+package plist
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// An Op is the kind of operation described by a Change.
+type Op int
+
+const (
+	// Add inserts a value that doesn't exist yet: a new dictionary key, or a
+	// new array element at the given index.
+	Add Op = iota + 1
+	// Remove deletes an existing value.
+	Remove
+	// Replace changes an existing value.
+	Replace
+)
+
+func (op Op) String() string {
+	switch op {
+	case Add:
+		return "add"
+	case Remove:
+		return "remove"
+	case Replace:
+		return "replace"
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// A Change describes a single difference between two property lists. Old is
+// the value being removed or replaced, and New is the value being added or
+// stored in its place.
+type Change struct {
+	Op   Op
+	Path KeyPath
+	Old  interface{}
+	New  interface{}
+}
+
+// A Patch is a list of changes, to be applied in order.
+type Patch []Change
+
+// Diff compares a and b using the same rules as Equal and returns the changes
+// that turn a into b. Dictionaries and arrays are compared entry by entry, so
+// a change deep inside a property list is reported at its own key path; other
+// values that differ are replaced outright.
+//
+// Array elements past the end of the shorter array are reported as removals
+// from the last element down, or as additions in increasing order, so the
+// patch can be applied in order. The result is empty if a and b are equal.
+func Diff(a, b interface{}) Patch {
+	return DiffWithOptions(a, b, nil)
+}
+
+// DiffWithOptions is like Diff, but compares values as EqualWithOptions does.
+// An array listed in opts.UnorderedArrays is replaced as a whole if its
+// elements differ, since there is no meaningful index for each change.
+func DiffWithOptions(a, b interface{}, opts *EqualOptions) Patch {
+	var patch Patch
+	newComparer(opts).diff(reflect.ValueOf(a), reflect.ValueOf(b), KeyPath{}, &patch)
+	return patch
+}
+
+func (c *comparer) diff(a, b reflect.Value, path KeyPath, patch *Patch) {
+	a, b = indirectValue(a), indirectValue(b)
+	if a.IsValid() && b.IsValid() {
+		ka, kb := equalKind(a), equalKind(b)
+		if ka == kindDict && kb == kindDict {
+			c.diffDicts(a, b, path, patch)
+			return
+		}
+		if ka == kindArray && kb == kindArray && !c.isUnordered(path) {
+			c.diffArrays(a, b, path, patch)
+			return
+		}
+	}
+	if !c.equal(a, b, path) {
+		*patch = append(*patch, Change{Replace, copyKeyPath(path), valueInterface(a), valueInterface(b)})
+	}
+}
+
+func (c *comparer) diffDicts(a, b reflect.Value, path KeyPath, patch *Patch) {
+	da, db := c.dictEntries(a), c.dictEntries(b)
+	for _, k := range dictIndexOrder(a, c) {
+		ea := da[k]
+		eb, ok := db[k]
+		p := append(path, ea.key)
+		if !ok {
+			*patch = append(*patch, Change{Remove, copyKeyPath(p), valueInterface(indirectValue(ea.value)), nil})
+			continue
+		}
+		c.diff(ea.value, eb.value, p, patch)
+	}
+	for _, k := range dictIndexOrder(b, c) {
+		if _, ok := da[k]; ok {
+			continue
+		}
+		eb := db[k]
+		p := append(path, eb.key)
+		*patch = append(*patch, Change{Add, copyKeyPath(p), nil, valueInterface(indirectValue(eb.value))})
+	}
+}
+
+func (c *comparer) diffArrays(a, b reflect.Value, path KeyPath, patch *Patch) {
+	n := a.Len()
+	if b.Len() < n {
+		n = b.Len()
+	}
+	for i := 0; i < n; i++ {
+		c.diff(a.Index(i), b.Index(i), append(path, strconv.Itoa(i)), patch)
+	}
+	for i := a.Len() - 1; i >= n; i-- {
+		p := append(path, strconv.Itoa(i))
+		*patch = append(*patch, Change{Remove, copyKeyPath(p), valueInterface(indirectValue(a.Index(i))), nil})
+	}
+	for i := n; i < b.Len(); i++ {
+		p := append(path, strconv.Itoa(i))
+		*patch = append(*patch, Change{Add, copyKeyPath(p), nil, valueInterface(indirectValue(b.Index(i)))})
+	}
+}
+
+// dictIndexOrder returns the dictEntries indexes of v in a stable order: key
+// order for a Dict, sorted for a map.
+func dictIndexOrder(v reflect.Value, c *comparer) []string {
+	var keys []string
+	if v.Type() == dictType {
+		seen := make(map[string]bool)
+		for _, k := range v.Interface().(Dict).keys {
+			index := c.keyIndex(k)
+			if !seen[index] {
+				seen[index] = true
+				keys = append(keys, index)
+			}
+		}
+		return keys
+	}
+	for k := range c.dictEntries(v) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyKeyPath(p KeyPath) KeyPath {
+	return append(KeyPath{}, p...)
+}
+
+// valueInterface returns the value held by v, or nil if v is the zero Value.
+func valueInterface(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
@@ -0,0 +1,82 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	a := map[string]interface{}{
+		"Name":    "old",
+		"Version": int64(1),
+		"Removed": true,
+		"Items":   []interface{}{"a", "b", "c"},
+		"Nested":  map[string]interface{}{"x": int32(1)},
+	}
+	b := map[string]interface{}{
+		"Name":    "new",
+		"Version": 1.0,
+		"Added":   []byte("hi"),
+		"Items":   []interface{}{"a", "B"},
+		"Nested":  map[string]interface{}{"x": int64(1), "y": int64(2)},
+	}
+	want := Patch{
+		{Replace, KeyPath{"Items", "1"}, "b", "B"},
+		{Remove, KeyPath{"Items", "2"}, "c", nil},
+		{Replace, KeyPath{"Name"}, "old", "new"},
+		{Add, KeyPath{"Nested", "y"}, nil, int64(2)},
+		{Remove, KeyPath{"Removed"}, true, nil},
+		{Add, KeyPath{"Added"}, nil, []byte("hi")},
+	}
+	got := Diff(a, b)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+	if patch := Diff(a, a); len(patch) != 0 {
+		t.Errorf("got %v for equal values", patch)
+	}
+}
+
+func TestDiffArrayGrowth(t *testing.T) {
+	got := Diff([]interface{}{"a"}, []interface{}{"a", "b", "c"})
+	want := Patch{
+		{Add, KeyPath{"1"}, nil, "b"},
+		{Add, KeyPath{"2"}, nil, "c"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v", got)
+	}
+	got = Diff([]interface{}{"a", "b", "c"}, []interface{}{"a"})
+	want = Patch{
+		{Remove, KeyPath{"2"}, "c", nil},
+		{Remove, KeyPath{"1"}, "b", nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestDiffWithOptions(t *testing.T) {
+	a := map[string]interface{}{"Tags": []interface{}{"x", "y"}}
+	b := map[string]interface{}{"tags": []interface{}{"y", "x"}}
+	opts := &EqualOptions{IgnoreKeyCase: true, UnorderedArrays: []string{"Tags"}}
+	if patch := DiffWithOptions(a, b, opts); len(patch) != 0 {
+		t.Errorf("got %v", patch)
+	}
+	b["tags"] = []interface{}{"y", "z"}
+	want := Patch{{Replace, KeyPath{"Tags"}, []interface{}{"x", "y"}, []interface{}{"y", "z"}}}
+	if patch := DiffWithOptions(a, b, opts); !reflect.DeepEqual(patch, want) {
+		t.Errorf("got %v", patch)
+	}
+}
+
+func TestDiffDates(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	a := map[string]interface{}{"When": DateOf(when), "Number": Date(5)}
+	b := map[string]interface{}{"When": when, "Number": 5.0}
+	want := Patch{{Replace, KeyPath{"Number"}, Date(5), 5.0}}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
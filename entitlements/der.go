@@ -0,0 +1,196 @@
+package entitlements
+
+import (
+	"encoding/asn1"
+	"errors"
+	"reflect"
+	"sort"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// DER entitlements are an [APPLICATION 16] holding a version number and the
+// dictionary. Dictionaries are a [CONTEXT 16] holding a SEQUENCE of key and
+// value for each entry, sorted by key; arrays are a SEQUENCE; and strings,
+// integers and booleans use the universal types. Other property list types
+// can't be represented.
+const (
+	derEntitlementsTag = 0x70 // constructed [APPLICATION 16]
+	derDictTag         = 0xb0 // constructed [CONTEXT 16]
+	derSequenceTag     = 0x30
+	derUTF8StringTag   = 0x0c
+	derBooleanTag      = 0x01
+)
+
+// MarshalDER returns the DER encoding of the entitlements v, which may be
+// anything that plist.Marshal encodes as a dictionary.
+func MarshalDER(v interface{}) ([]byte, error) {
+	dict, ok := v.(map[string]interface{})
+	if !ok {
+		data, err := plist.Marshal(v, plist.BinaryFormat)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := plist.Unmarshal(data, &dict); err != nil {
+			return nil, err
+		}
+	}
+	body, err := encodeDER(dict)
+	if err != nil {
+		return nil, err
+	}
+	version, err := asn1.Marshal(1)
+	if err != nil {
+		return nil, err
+	}
+	return derTLV(derEntitlementsTag, append(version, body...)), nil
+}
+
+// UnmarshalDER parses DER entitlements and stores the result in the value
+// pointed to by v, using the same rules as plist.Unmarshal.
+func UnmarshalDER(data []byte, v interface{}) error {
+	dict, err := unmarshalDER(data)
+	if err != nil {
+		return err
+	}
+	pl, err := plist.Marshal(dict, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(pl, v)
+	return err
+}
+
+// derTLV returns the DER encoding of a value with the given tag and contents.
+func derTLV(tag byte, contents []byte) []byte {
+	n := len(contents)
+	if n < 0x80 {
+		return append([]byte{tag, byte(n)}, contents...)
+	}
+	var length []byte
+	for ; n > 0; n >>= 8 {
+		length = append([]byte{byte(n)}, length...)
+	}
+	out := append([]byte{tag, 0x80 | byte(len(length))}, length...)
+	return append(out, contents...)
+}
+
+func encodeDER(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return derTLV(derUTF8StringTag, []byte(v)), nil
+	case bool:
+		if v {
+			return []byte{derBooleanTag, 1, 0xff}, nil
+		}
+		return []byte{derBooleanTag, 1, 0}, nil
+	case []interface{}:
+		var contents []byte
+		for _, elem := range v {
+			enc, err := encodeDER(elem)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, enc...)
+		}
+		return derTLV(derSequenceTag, contents), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var contents []byte
+		for _, k := range keys {
+			enc, err := encodeDER(v[k])
+			if err != nil {
+				return nil, err
+			}
+			pair := append(derTLV(derUTF8StringTag, []byte(k)), enc...)
+			contents = append(contents, derTLV(derSequenceTag, pair)...)
+		}
+		return derTLV(derDictTag, contents), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return asn1.Marshal(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return asn1.Marshal(int64(rv.Uint()))
+	}
+	return nil, errors.New("entitlements: DER encoding does not support values of type " + rv.Type().String())
+}
+
+func unmarshalDER(data []byte) (interface{}, error) {
+	var outer asn1.RawValue
+	rest, err := asn1.Unmarshal(data, &outer)
+	if err != nil {
+		return nil, errors.New("entitlements: invalid DER entitlements: " + err.Error())
+	}
+	if len(rest) > 0 || outer.FullBytes[0] != derEntitlementsTag {
+		return nil, errors.New("entitlements: invalid DER entitlements")
+	}
+	var version int
+	body, err := asn1.Unmarshal(outer.Bytes, &version)
+	if err != nil {
+		return nil, errors.New("entitlements: invalid DER entitlements: " + err.Error())
+	}
+	v, rest, err := decodeDER(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("entitlements: trailing data in DER entitlements")
+	}
+	return v, nil
+}
+
+// decodeDER decodes the first value of data, returning the remaining bytes.
+func decodeDER(data []byte) (interface{}, []byte, error) {
+	var raw asn1.RawValue
+	rest, err := asn1.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, nil, errors.New("entitlements: invalid DER entitlements: " + err.Error())
+	}
+	switch raw.FullBytes[0] {
+	case derUTF8StringTag:
+		return string(raw.Bytes), rest, nil
+	case derBooleanTag:
+		var b bool
+		_, err := asn1.Unmarshal(raw.FullBytes, &b)
+		return b, rest, err
+	case 0x02: // INTEGER
+		var n int64
+		_, err := asn1.Unmarshal(raw.FullBytes, &n)
+		return n, rest, err
+	case derSequenceTag:
+		ary := []interface{}{}
+		for contents := raw.Bytes; len(contents) > 0; {
+			var elem interface{}
+			if elem, contents, err = decodeDER(contents); err != nil {
+				return nil, nil, err
+			}
+			ary = append(ary, elem)
+		}
+		return ary, rest, nil
+	case derDictTag:
+		dict := make(map[string]interface{})
+		for contents := raw.Bytes; len(contents) > 0; {
+			var pair interface{}
+			if pair, contents, err = decodeDER(contents); err != nil {
+				return nil, nil, err
+			}
+			kv, ok := pair.([]interface{})
+			if !ok || len(kv) != 2 {
+				return nil, nil, errors.New("entitlements: invalid dictionary entry in DER entitlements")
+			}
+			key, ok := kv[0].(string)
+			if !ok {
+				return nil, nil, errors.New("entitlements: non-string key in DER entitlements")
+			}
+			dict[key] = kv[1]
+		}
+		return dict, rest, nil
+	}
+	return nil, nil, errors.New("entitlements: unsupported value in DER entitlements")
+}
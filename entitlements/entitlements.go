@@ -0,0 +1,134 @@
+// Package entitlements builds and parses code signing entitlements.
+//
+// Entitlements are a property list dictionary. Code signatures carry them in
+// two forms: the traditional XML property list, and since iOS 15 and macOS
+// 12 an ASN.1 DER encoding of the same dictionary. This package produces and
+// reads both.
+package entitlements
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// Entitlements holds the commonly used entitlement keys. Keys without a field
+// of their own go in Other.
+type Entitlements struct {
+	ApplicationIdentifier string   `plist:"application-identifier,omitempty"`
+	TeamIdentifier        string   `plist:"com.apple.developer.team-identifier,omitempty"`
+	GetTaskAllow          bool     `plist:"get-task-allow,omitempty"`
+	KeychainAccessGroups  []string `plist:"keychain-access-groups,omitempty"`
+	ApplicationGroups     []string `plist:"com.apple.security.application-groups,omitempty"`
+	AssociatedDomains     []string `plist:"com.apple.developer.associated-domains,omitempty"`
+	APSEnvironment        string   `plist:"aps-environment,omitempty"` // "development" or "production"
+
+	// App Sandbox
+	AppSandbox                 bool `plist:"com.apple.security.app-sandbox,omitempty"`
+	NetworkClient              bool `plist:"com.apple.security.network.client,omitempty"`
+	NetworkServer              bool `plist:"com.apple.security.network.server,omitempty"`
+	UserSelectedFilesReadOnly  bool `plist:"com.apple.security.files.user-selected.read-only,omitempty"`
+	UserSelectedFilesReadWrite bool `plist:"com.apple.security.files.user-selected.read-write,omitempty"`
+
+	// Hardened Runtime
+	AllowJIT                      bool `plist:"com.apple.security.cs.allow-jit,omitempty"`
+	AllowUnsignedExecutableMemory bool `plist:"com.apple.security.cs.allow-unsigned-executable-memory,omitempty"`
+	DisableLibraryValidation      bool `plist:"com.apple.security.cs.disable-library-validation,omitempty"`
+
+	// Other holds any other entitlements. Its keys must not overlap with the
+	// keys of the fields above.
+	Other map[string]interface{} `plist:"-"`
+}
+
+// knownKeys returns the entitlement keys of the fields of Entitlements.
+func knownKeys() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(Entitlements{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("plist")
+		if tag != "-" {
+			keys[strings.Split(tag, ",")[0]] = true
+		}
+	}
+	return keys
+}
+
+// Dict returns the entitlements as a property list dictionary.
+func (e *Entitlements) Dict() (map[string]interface{}, error) {
+	data, err := plist.Marshal(e, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	var dict map[string]interface{}
+	if _, err := plist.Unmarshal(data, &dict); err != nil {
+		return nil, err
+	}
+	known := knownKeys()
+	for k, v := range e.Other {
+		if known[k] {
+			return nil, errors.New("entitlements: Other has key " + k + ", which has a field of its own")
+		}
+		dict[k] = v
+	}
+	return dict, nil
+}
+
+// XML returns the entitlements as an XML property list.
+func (e *Entitlements) XML() ([]byte, error) {
+	dict, err := e.Dict()
+	if err != nil {
+		return nil, err
+	}
+	return plist.Marshal(dict, plist.XMLFormat)
+}
+
+// DER returns the entitlements in the DER encoding used by code signatures.
+func (e *Entitlements) DER() ([]byte, error) {
+	dict, err := e.Dict()
+	if err != nil {
+		return nil, err
+	}
+	return MarshalDER(dict)
+}
+
+// Parse parses entitlements in either the XML (or binary) property list form
+// or the DER form.
+func Parse(data []byte) (*Entitlements, error) {
+	var dict map[string]interface{}
+	if len(data) > 0 && data[0] == derEntitlementsTag {
+		// property lists start with "<", "bplist" or a byte order mark
+		v, err := unmarshalDER(data)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if dict, ok = v.(map[string]interface{}); !ok {
+			return nil, errors.New("entitlements: DER entitlements are not a dictionary")
+		}
+	} else if _, err := plist.Unmarshal(data, &dict); err != nil {
+		return nil, err
+	}
+
+	// decode the known keys through the plist encoding so the struct tags
+	// apply, and keep the rest
+	e := new(Entitlements)
+	data, err := plist.Marshal(dict, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plist.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	known := knownKeys()
+	for k, v := range dict {
+		if !known[k] {
+			if e.Other == nil {
+				e.Other = make(map[string]interface{})
+			}
+			e.Other[k] = v
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,91 @@
+package entitlements
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func testEntitlements() *Entitlements {
+	return &Entitlements{
+		ApplicationIdentifier: "ABCDE12345.com.example.app",
+		TeamIdentifier:        "ABCDE12345",
+		GetTaskAllow:          true,
+		KeychainAccessGroups:  []string{"ABCDE12345.com.example.shared"},
+		AppSandbox:            true,
+		Other: map[string]interface{}{
+			"com.apple.developer.icloud-services": []interface{}{"CloudKit"},
+		},
+	}
+}
+
+func TestDict(t *testing.T) {
+	dict, err := testEntitlements().Dict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"application-identifier":              "ABCDE12345.com.example.app",
+		"com.apple.developer.team-identifier": "ABCDE12345",
+		"get-task-allow":                      true,
+		"keychain-access-groups":              []interface{}{"ABCDE12345.com.example.shared"},
+		"com.apple.security.app-sandbox":      true,
+		"com.apple.developer.icloud-services": []interface{}{"CloudKit"},
+	}
+	if !reflect.DeepEqual(dict, want) {
+		t.Errorf("got %#v, want %#v", dict, want)
+	}
+}
+
+func TestMarshalDER(t *testing.T) {
+	in := map[string]interface{}{
+		"get-task-allow": true,
+		"b":              []interface{}{"x"},
+		"a":              int64(1),
+	}
+	got, err := MarshalDER(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x70, 0x2c, // [APPLICATION 16]
+		0x02, 0x01, 0x01, // version 1
+		0xb0, 0x27, // [CONTEXT 16], with the keys sorted
+		0x30, 0x06, 0x0c, 0x01, 'a', 0x02, 0x01, 0x01,
+		0x30, 0x08, 0x0c, 0x01, 'b', 0x30, 0x03, 0x0c, 0x01, 'x',
+		0x30, 0x13, 0x0c, 0x0e, 'g', 'e', 't', '-', 't', 'a', 's', 'k', '-', 'a', 'l', 'l', 'o', 'w', 0x01, 0x01, 0xff,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got  %x\nwant %x", got, want)
+	}
+
+	out, err := unmarshalDER(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("unmarshalDER: got %#v, want %#v", out, in)
+	}
+}
+
+func TestParse(t *testing.T) {
+	in := testEntitlements()
+	xml, err := in.XML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := in.DER()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"XML": xml, "DER": der} {
+		out, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("%s: got %#v, want %#v", name, out, in)
+		}
+	}
+}
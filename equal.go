@@ -0,0 +1,302 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Equal reports whether a and b represent the same property list. Unlike
+// reflect.DeepEqual, it compares values the way they would be serialized:
+//
+//   - numbers are equal if they have the same value, regardless of Go type, so
+//     int32(1), int64(1) and float64(1) are all equal
+//   - []byte values (and byte arrays) are compared by content
+//   - times are equal if they are the same instant at the millisecond
+//     precision used when encoding dates, regardless of location; a Date
+//     equals a time at the same precision, and another Date only if they
+//     hold the same value
+//   - dictionaries are equal if they have the same keys and equal values,
+//     whether they are maps with string keys, Dict or *Dict values; the key
+//     order of a Dict is ignored
+//   - arrays are compared element by element, whatever their slice type
+//   - pointers and interfaces are compared by the values they point to
+//
+// Other values, such as structs, are compared with reflect.DeepEqual.
+func Equal(a, b interface{}) bool {
+	return EqualWithOptions(a, b, nil)
+}
+
+// EqualOptions loosens the comparison made by EqualWithOptions.
+type EqualOptions struct {
+	// FloatEpsilon is the largest difference allowed between two equal
+	// numbers when at least one of them is a float.
+	FloatEpsilon float64
+	// IgnoreKeyCase makes dictionary keys match case-insensitively, as
+	// strings.EqualFold does. It also applies to the components of
+	// UnorderedArrays.
+	IgnoreKeyCase bool
+	// UnorderedArrays lists the key paths of arrays that are compared as
+	// multisets, ignoring the order of their elements. A "*" component
+	// matches any dictionary key or array index, so "Items:*:Tags" matches
+	// the Tags array of every element of Items.
+	UnorderedArrays []string
+}
+
+// EqualWithOptions is like Equal, but with the comparison loosened by opts.
+// A nil opts is the same as calling Equal.
+func EqualWithOptions(a, b interface{}, opts *EqualOptions) bool {
+	return newComparer(opts).equal(reflect.ValueOf(a), reflect.ValueOf(b), KeyPath{})
+}
+
+// A comparer compares property list values according to its options.
+type comparer struct {
+	opts      EqualOptions
+	unordered []KeyPath
+}
+
+func newComparer(opts *EqualOptions) *comparer {
+	c := &comparer{}
+	if opts != nil {
+		c.opts = *opts
+		for _, p := range opts.UnorderedArrays {
+			c.unordered = append(c.unordered, ParseKeyPath(p))
+		}
+	}
+	return c
+}
+
+func (c *comparer) equal(a, b reflect.Value, path KeyPath) bool {
+	a, b = indirectValue(a), indirectValue(b)
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	ka, kb := equalKind(a), equalKind(b)
+	if ka != kb {
+		return false
+	}
+	switch ka {
+	case kindBool:
+		return a.Bool() == b.Bool()
+	case kindNumber:
+		if c.opts.FloatEpsilon > 0 && (isFloatValue(a) || isFloatValue(b)) {
+			return math.Abs(numberAsFloat(a)-numberAsFloat(b)) <= c.opts.FloatEpsilon
+		}
+		return equalNumber(a, b)
+	case kindUID:
+		return a.Uint() == b.Uint()
+	case kindString:
+		return a.String() == b.String()
+	case kindData:
+		return bytes.Equal(bytesOf(a), bytesOf(b))
+	case kindDate:
+		if a.Type() == dateType && b.Type() == dateType {
+			return a.Float() == b.Float()
+		}
+		return dateMillis(a) == dateMillis(b)
+	case kindArray:
+		if a.Len() != b.Len() {
+			return false
+		}
+		if c.isUnordered(path) {
+			return c.equalUnordered(a, b, path)
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !c.equal(a.Index(i), b.Index(i), append(path, strconv.Itoa(i))) {
+				return false
+			}
+		}
+		return true
+	case kindDict:
+		da, db := c.dictEntries(a), c.dictEntries(b)
+		if len(da) != len(db) {
+			return false
+		}
+		for k, ea := range da {
+			eb, ok := db[k]
+			if !ok || !c.equal(ea.value, eb.value, append(path, ea.key)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// The kinds of property list values that equalValue distinguishes.
+const (
+	kindOther = iota
+	kindBool
+	kindNumber
+	kindUID
+	kindString
+	kindData
+	kindDate
+	kindArray
+	kindDict
+)
+
+func equalKind(v reflect.Value) int {
+	t := v.Type()
+	switch {
+	case t == uidType:
+		return kindUID
+	case t == timeType, t == dateType:
+		return kindDate
+	case t == dictType:
+		return kindDict
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return kindBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return kindNumber
+	case reflect.String:
+		return kindString
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return kindData
+		}
+		return kindArray
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return kindDict
+		}
+	}
+	return kindOther
+}
+
+// dateMillis returns the milliseconds since the Unix epoch of v, a time.Time
+// or Date. A Date is rounded to the millisecond as it is when a CFDate is
+// decoded into a time.Time.
+func dateMillis(v reflect.Value) int64 {
+	if v.Type() == dateType {
+		return int64(math.Round((v.Float() + cocoaEpoch) * 1000))
+	}
+	return v.Interface().(time.Time).UnixNano() / int64(time.Millisecond)
+}
+
+// indirectValue follows pointers and interfaces to the value they hold. It
+// returns the zero Value for nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// equalUnordered reports whether every element of a can be paired with an
+// equal element of b. a and b have the same length.
+func (c *comparer) equalUnordered(a, b reflect.Value, path KeyPath) bool {
+	used := make([]bool, b.Len())
+	for i := 0; i < a.Len(); i++ {
+		found := false
+		for j := 0; j < b.Len(); j++ {
+			if !used[j] && c.equal(a.Index(i), b.Index(j), append(path, strconv.Itoa(i))) {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnordered reports whether path matches one of c.unordered.
+func (c *comparer) isUnordered(path KeyPath) bool {
+	for _, p := range c.unordered {
+		if matchKeyPath(p, path, c.opts.IgnoreKeyCase) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyIndex returns the index of key k in the result of dictEntries.
+func (c *comparer) keyIndex(k string) string {
+	if c.opts.IgnoreKeyCase {
+		return strings.ToLower(k)
+	}
+	return k
+}
+
+func isFloatValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func equalNumber(a, b reflect.Value) bool {
+	isUint := func(v reflect.Value) bool {
+		switch v.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return true
+		}
+		return false
+	}
+	switch {
+	case isFloatValue(a) || isFloatValue(b):
+		return numberAsFloat(a) == numberAsFloat(b)
+	case isUint(a) && isUint(b):
+		return a.Uint() == b.Uint()
+	case isUint(a):
+		return b.Int() >= 0 && a.Uint() == uint64(b.Int())
+	case isUint(b):
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint()
+	}
+	return a.Int() == b.Int()
+}
+
+func numberAsFloat(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	}
+	return float64(v.Uint())
+}
+
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+// A dictEntry is a dictionary entry found by comparer.dictEntries.
+type dictEntry struct {
+	key   string
+	value reflect.Value
+}
+
+// dictEntries returns the entries of v, a map with string keys or a Dict,
+// indexed by key. If c ignores key case, the index is the lowercased key.
+func (c *comparer) dictEntries(v reflect.Value) map[string]dictEntry {
+	m := make(map[string]dictEntry)
+	add := func(k string, val reflect.Value) {
+		m[c.keyIndex(k)] = dictEntry{k, val}
+	}
+	if v.Type() == dictType {
+		d := v.Interface().(Dict)
+		for _, k := range d.keys {
+			add(k, reflect.ValueOf(d.values[k]))
+		}
+		return m
+	}
+	for _, k := range v.MapKeys() {
+		add(k.String(), v.MapIndex(k))
+	}
+	return m
+}
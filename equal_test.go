@@ -0,0 +1,94 @@
+package plist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEqual(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	d := &Dict{}
+	d.Set("b", int32(2))
+	d.Set("a", "x")
+	tests := []struct {
+		a, b interface{}
+		want bool
+	}{
+		{int32(1), int64(1), true},
+		{uint8(1), int64(1), true},
+		{int64(-1), uint64(1<<64 - 1), false},
+		{int64(1), 1.0, true},
+		{float32(0.5), 0.5, true},
+		{int64(1), "1", false},
+		{UID(1), int64(1), false},
+		{[]byte("abc"), []byte("abc"), true},
+		{[]byte{}, []byte(nil), true},
+		{[3]byte{1, 2, 3}, []byte{1, 2, 3}, true},
+		{when, when.In(time.FixedZone("X", 3600)), true},
+		{when, when.Add(500 * time.Microsecond), true},
+		{when, when.Add(time.Millisecond), false},
+		{DateOf(when), when, true},
+		{DateOf(when), when.Add(time.Millisecond), false},
+		{Date(5), Date(5), true},
+		{Date(5), Date(5.0001), false},
+		{Date(5), 5.0, false},
+		{[]interface{}{int8(1), "a"}, []int64{1, 2}, false},
+		{[]interface{}{int8(1), int16(2)}, []int64{1, 2}, true},
+		{map[string]interface{}{"a": "x", "b": int64(2)}, d, true},
+		{map[string]interface{}{"a": "x"}, d, false},
+		{map[string]int{"a": 1}, map[string]interface{}{"a": 1.0}, true},
+		{nil, nil, true},
+		{nil, "", false},
+		{(*int)(nil), nil, true},
+	}
+	for _, test := range tests {
+		if got := Equal(test.a, test.b); got != test.want {
+			t.Errorf("Equal(%#v, %#v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+		if got := Equal(test.b, test.a); got != test.want {
+			t.Errorf("Equal(%#v, %#v) = %v, want %v", test.b, test.a, got, test.want)
+		}
+	}
+}
+
+func TestEqualWithOptions(t *testing.T) {
+	a := map[string]interface{}{
+		"Version": 1.0,
+		"Items": []interface{}{
+			map[string]interface{}{"Tags": []interface{}{"x", "y"}},
+		},
+		"List": []interface{}{"a", "b"},
+	}
+	b := map[string]interface{}{
+		"version": 1.0000001,
+		"items": []interface{}{
+			map[string]interface{}{"tags": []interface{}{"y", "x"}},
+		},
+		"list": []interface{}{"a", "b"},
+	}
+	if Equal(a, b) {
+		t.Error("Equal should be strict")
+	}
+	opts := &EqualOptions{
+		FloatEpsilon:    1e-6,
+		IgnoreKeyCase:   true,
+		UnorderedArrays: []string{"Items:*:Tags"},
+	}
+	if !EqualWithOptions(a, b, opts) {
+		t.Error("EqualWithOptions should match")
+	}
+	b["list"] = []interface{}{"b", "a"}
+	if EqualWithOptions(a, b, opts) {
+		t.Error("List order should still matter")
+	}
+	opts.UnorderedArrays = append(opts.UnorderedArrays, "List")
+	if !EqualWithOptions(a, b, opts) {
+		t.Error("List order should be ignored")
+	}
+	if EqualWithOptions([]interface{}{"a", "a"}, []interface{}{"a", "b"}, &EqualOptions{UnorderedArrays: []string{""}}) {
+		t.Error("unordered comparison must pair elements one to one")
+	}
+	if EqualWithOptions(int64(1), 1.5, &EqualOptions{FloatEpsilon: 0.1}) {
+		t.Error("difference larger than epsilon")
+	}
+}
@@ -2,7 +2,9 @@ package plist
 
 // #include <CoreFoundation/CoreFoundation.h>
 import "C"
+import "fmt"
 import "reflect"
+import "runtime/debug"
 import "strconv"
 
 // An UnsupportedTypeError is returned by Marshal when attempting to encode an
@@ -29,7 +31,7 @@ type UnknownCFTypeError struct {
 }
 
 func (e *UnknownCFTypeError) Error() string {
-	cfStr := C.CFCopyTypeIDDescription(e.CFTypeID)
+	cfStr := cfCreated(C.CFCopyTypeIDDescription(e.CFTypeID))
 	str := convertCFStringToString(cfStr)
 	cfRelease(cfTypeRef(cfStr))
 	return "plist: unknown CFTypeID " + strconv.Itoa(int(e.CFTypeID)) + " (" + str + ")"
@@ -48,3 +50,60 @@ type UnsupportedKeyTypeError struct {
 func (e *UnsupportedKeyTypeError) Error() string {
 	return "plist: unexpected dictionary key CFTypeID " + strconv.Itoa(e.CFTypeID)
 }
+
+// A DuplicateKeyError is returned when dictionary keys that aren't strings are
+// converted to strings, and two keys of the same dictionary convert to Key.
+type DuplicateKeyError struct {
+	Key string
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return "plist: more than one dictionary key converts to " + strconv.Quote(e.Key)
+}
+
+// A PanicError is returned by Marshal, Unmarshal and the other encoding and
+// decoding functions when the conversion panics, whether in reflection on an
+// unusual type, in the package itself, or in a MarshalPlist or UnmarshalPlist
+// method. Value is the value passed to panic and Stack is the stack trace of
+// the goroutine that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return "plist: panic during conversion: " + fmt.Sprint(e.Value)
+}
+
+// Unwrap returns Value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic recovers from a panic in the function that defers it and
+// stores it in *err as a PanicError.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = asPanicError(r)
+	}
+}
+
+// asPanicError returns the PanicError for the recovered value r, which may
+// already be one if it was passed on from another goroutine.
+func asPanicError(r interface{}) *PanicError {
+	if e, ok := r.(*PanicError); ok {
+		return e
+	}
+	return &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// A DisallowedClassError is returned by an Unarchiver when the archive contains
+// an object of a class that was not allowed with AllowClasses.
+type DisallowedClassError struct {
+	Class string
+}
+
+func (e *DisallowedClassError) Error() string {
+	return "plist: keyed archive contains disallowed class " + strconv.Quote(e.Class)
+}
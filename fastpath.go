@@ -0,0 +1,186 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Maps of these types are converted to and from CFDictionaries without
+// iterating over them with reflect. Together with the slices in batch.go they
+// cover the most common shapes of decoded property lists.
+var (
+	stringMapType    = reflect.TypeOf(map[string]string(nil))
+	interfaceMapType = reflect.TypeOf(map[string]interface{}(nil))
+)
+
+// marshalMap converts a map[string]string or map[string]interface{} to a
+// CFDictionary, ranging over the map directly. Only the interface values that
+// marshalInterface doesn't handle itself go through reflect. It returns false
+// if v has any other type.
+func (state *marshalState) marshalMap(v reflect.Value) (C.CFDictionaryRef, bool, error) {
+	if v.Type() != stringMapType && v.Type() != interfaceMapType {
+		return nil, false, nil
+	}
+	keys := make([]cfTypeRef, 0, v.Len())
+	values := make([]cfTypeRef, 0, v.Len())
+	defer func() {
+		state.release(keys)
+		state.release(values)
+	}()
+	addKey := func(key string) error {
+		if err := state.checkContext(); err != nil {
+			return err
+		}
+		cfStr := convertStringToCFString(key)
+		if cfStr == nil {
+			return errors.New("plist: could not convert string to CFStringRef")
+		}
+		keys = append(keys, cfTypeRef(cfStr))
+		return nil
+	}
+	switch m := v.Interface().(type) {
+	case map[string]string:
+		if err := checkMapKeys(m); err != nil {
+			return nil, true, err
+		}
+		for key, val := range m {
+			if err := addKey(key); err != nil {
+				return nil, true, err
+			}
+			cfObj, err := marshalString(val)
+			if err != nil {
+				return nil, true, err
+			}
+			values = append(values, cfObj)
+		}
+	case map[string]interface{}:
+		if err := checkMapKeys(m); err != nil {
+			return nil, true, err
+		}
+		for key, val := range m {
+			if err := addKey(key); err != nil {
+				return nil, true, err
+			}
+			cfObj, err := state.marshalInterface(val)
+			if err != nil {
+				return nil, true, err
+			}
+			values = append(values, cfObj)
+		}
+	}
+	return createCFDictionary(keys, values), true, nil
+}
+
+// marshalInterface converts an element of a map[string]interface{},
+// switching on the common property list types before falling back to
+// marshalValue.
+func (state *marshalState) marshalInterface(val interface{}) (cfTypeRef, error) {
+	switch val := val.(type) {
+	case nil:
+		return nil, &UnsupportedValueError{reflect.ValueOf(&val).Elem(), "nil interface"}
+	case string:
+		return marshalString(val)
+	case bool:
+		return cfTypeRef(convertBoolToCFBoolean(val)), nil
+	case int:
+		return cfTypeRef(convertInt64ToCFNumber(int64(val))), nil
+	case int64:
+		return cfTypeRef(convertInt64ToCFNumber(val)), nil
+	case float64:
+		if !math.IsInf(val, 0) && !math.IsNaN(val) {
+			return cfTypeRef(convertFloat64ToCFNumber(val)), nil
+		}
+	case []byte:
+		return cfTypeRef(state.convertBytesToCFData(val)), nil
+	case time.Time:
+		return cfTypeRef(convertTimeToCFDate(val)), nil
+	}
+	return state.marshalValue(reflect.ValueOf(val))
+}
+
+func marshalString(s string) (cfTypeRef, error) {
+	cfStr := convertStringToCFString(s)
+	if cfStr == nil {
+		return nil, errors.New("plist: could not convert string to CFStringRef")
+	}
+	return cfTypeRef(cfStr), nil
+}
+
+// unmarshalMap stores the contents of cfDict in m, which is a non-nil
+// map[string]string or map[string]interface{}. Values of the simple property
+// list types are stored directly; anything else, including values that don't
+// fit, goes through unmarshalValue so errors are reported as usual.
+func (state *unmarshalState) unmarshalMap(cfDict C.CFDictionaryRef, m interface{}) error {
+	switch m := m.(type) {
+	case map[string]string:
+		return state.convertCFDictionary(cfDict, func(key string, value cfTypeRef, count int) error {
+			if C.CFGetTypeID(C.CFTypeRef(value)) == cfStringTypeID {
+				m[key] = convertCFStringToString(C.CFStringRef(value))
+				return nil
+			}
+			var s string
+			if err := state.unmarshalValue(value, reflect.ValueOf(&s).Elem()); err != nil {
+				return err
+			}
+			m[key] = s
+			return nil
+		})
+	case map[string]interface{}:
+		return state.convertCFDictionary(cfDict, func(key string, value cfTypeRef, count int) error {
+			if state.useArena() {
+				val, err := state.arenaValue(value)
+				if err != nil {
+					return err
+				}
+				m[key] = val
+				return nil
+			}
+			if val, ok := state.convertCFSimpleValue(value); ok {
+				m[key] = val
+				return nil
+			}
+			var val interface{}
+			saved := state.order
+			state.order = saved.key(key)
+			err := state.unmarshalValue(value, reflect.ValueOf(&val).Elem())
+			state.order = saved
+			if err != nil {
+				return err
+			}
+			m[key] = val
+			return nil
+		})
+	}
+	panic("plist: unexpected map type")
+}
+
+// convertCFSimpleValue converts a CFString, CFBoolean, CFDate, CFData or a
+// CFNumber that unmarshalValue would store as an int64 or float64 to the value
+// unmarshalValue would store in an empty interface. It returns false for
+// anything else.
+func (state *unmarshalState) convertCFSimpleValue(cfObj cfTypeRef) (interface{}, bool) {
+	switch C.CFGetTypeID(C.CFTypeRef(cfObj)) {
+	case cfStringTypeID:
+		return convertCFStringToString(C.CFStringRef(cfObj)), true
+	case cfBooleanTypeID:
+		return convertCFBooleanToBool(C.CFBooleanRef(cfObj)), true
+	case cfDateTypeID:
+		return state.convertCFDate(C.CFDateRef(cfObj)), true
+	case cfDataTypeID:
+		return convertCFDataToBytes(C.CFDataRef(cfObj)), true
+	case cfNumberTypeID:
+		cfNumber := C.CFNumberRef(cfObj)
+		switch cfNumberTypeToType(C.CFNumberGetType(cfNumber)).Kind() {
+		case reflect.Int64:
+			return convertCFNumberToInt64(cfNumber), true
+		case reflect.Float64:
+			return convertCFNumberToFloat64(cfNumber), true
+		}
+	}
+	return nil, false
+}
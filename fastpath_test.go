@@ -0,0 +1,86 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMapFastPathRoundTrip(t *testing.T) {
+	strs := map[string]string{"a": "one", "b": "", "é": "two"}
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	generic := map[string]interface{}{
+		"string": "x",
+		"bool":   true,
+		"int":    int64(-3),
+		"real":   1.5,
+		"data":   []byte{1, 2},
+		"date":   when,
+		"array":  []interface{}{"y", int64(2)},
+		"dict":   map[string]interface{}{"z": false},
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(strs, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gotStrs map[string]string
+		if _, err := Unmarshal(data, &gotStrs); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(gotStrs, strs) {
+			t.Errorf("%v: got %#v, want %#v", format, gotStrs, strs)
+		}
+
+		data, err = Marshal(generic, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(got, generic) {
+			t.Errorf("%v: got %#v, want %#v", format, got, generic)
+		}
+		if _, ok := got["int"].(int64); !ok {
+			t.Errorf("%v: got integer %T, want int64", format, got["int"])
+		}
+	}
+}
+
+func TestMapFastPathFallback(t *testing.T) {
+	if _, err := Marshal(map[string]interface{}{"a": nil}, XMLFormat); err == nil {
+		t.Error("nil value: expected an error")
+	} else if _, ok := err.(*UnsupportedValueError); !ok {
+		t.Errorf("nil value: got %T, want UnsupportedValueError", err)
+	}
+
+	data, err := Marshal(map[string]interface{}{"a": "x", "b": int64(2)}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs := map[string]string{"c": "kept"}
+	_, err = Unmarshal(data, &strs)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Errorf("got error %v, want UnmarshalTypeError", err)
+	}
+	if want := map[string]string{"a": "x", "b": "", "c": "kept"}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("got %#v, want %#v", strs, want)
+	}
+}
+
+func TestIntSliceRoundTrip(t *testing.T) {
+	ints := []int{0, -1, 1 << 40, 42}
+	data, err := Marshal(ints, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ints) {
+		t.Errorf("got %v, want %v", got, ints)
+	}
+}
@@ -0,0 +1,35 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// fieldData returns the bytes to encode as a CFData for v, the value of a
+// field with the "data" option, if the option applies to its type. The bytes
+// of a string are used without copying them.
+func fieldData(v reflect.Value) ([]byte, bool) {
+	if v.Kind() == reflect.String {
+		s := v.String()
+		return unsafe.Slice(unsafe.StringData(s), len(s)), true
+	}
+	if isUUIDType(v.Type()) {
+		uuid := uuidOf(v)
+		return uuid[:], true
+	}
+	return nil, false
+}
+
+// unmarshalFieldData stores cfObj in v, the value of a string field with the
+// "data" option, if cfObj is a CFData. UUID fields take either a CFData or a
+// CFString without the option, so they need nothing more.
+func unmarshalFieldData(cfObj cfTypeRef, v reflect.Value) bool {
+	if v.Kind() != reflect.String || cfTypeID(cfObj) != cfDataTypeID {
+		return false
+	}
+	v.SetString(convertCFDataToString(C.CFDataRef(cfObj)))
+	return true
+}
@@ -0,0 +1,88 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type certificate struct {
+	Name string
+	DER  string `plist:",data"`
+	PEM  string `plist:"pem,data,omitempty"`
+}
+
+func TestStringDataField(t *testing.T) {
+	v := certificate{
+		Name: "root",
+		DER:  "0\x82\x01\n\x02\x82\x01\x01\x00\xff",
+		// large enough to be marshaled without copying
+		PEM: strings.Repeat("-----BEGIN CERTIFICATE-----\n", 4096),
+	}
+	want := map[string]interface{}{
+		"Name": "root",
+		"DER":  []byte(v.DER),
+		"pem":  []byte(v.PEM),
+	}
+	encode := NewTypedEncoder[certificate]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var plist interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got a different property list", format)
+			}
+			var got certificate
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != v {
+				t.Errorf("%s: got %q, want %q", format, got.DER, v.DER)
+			}
+			got = certificate{}
+			if _, err := NewTypedDecoder[certificate]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != v {
+				t.Errorf("%s: TypedDecoder got %q, want %q", format, got.DER, v.DER)
+			}
+		}
+	}
+
+	// a string field with the option still takes a CFString
+	data, err := Marshal(map[string]interface{}{"DER": "text"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got certificate
+	if _, err := Unmarshal(data, &got); err != nil || got.DER != "text" {
+		t.Errorf("got %q, %v", got.DER, err)
+	}
+
+	data, err = Marshal(certificate{Name: "empty"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plist map[string]interface{}
+	if _, err := Unmarshal(data, &plist); err != nil {
+		t.Fatal(err)
+	}
+	if der, ok := plist["DER"].([]byte); !ok || len(der) != 0 || plist["pem"] != nil {
+		t.Errorf("unexpected property list for empty fields: %#v", plist)
+	}
+}
@@ -0,0 +1,140 @@
+package plist
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// UnmarshalFile reads the property list file at path and stores the result in
+// the value pointed to by v, as Unmarshal does.
+func UnmarshalFile(path string, v interface{}) (Format, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Format{}, err
+	}
+	return Unmarshal(data, v)
+}
+
+// UnmarshalFileMapped is like UnmarshalFile, but maps the file into memory
+// and parses the property list directly from the mapping instead of reading
+// it. For large files this avoids holding a copy of the whole file in the Go
+// heap. The file must not be modified until UnmarshalFileMapped returns.
+//
+// Only the copy of the file is saved: the property list is still parsed by
+// the current Backend, which creates every object in it before they are
+// stored in v. Files that can't be mapped, such as pipes, are read as
+// UnmarshalFile reads them.
+func UnmarshalFileMapped(path string, v interface{}) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Format{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Format{}, err
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return Format{}, err
+		}
+		return Unmarshal(data, v)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return Format{}, &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	defer syscall.Munmap(data)
+	return unmarshal(data, v, &unmarshalState{mapped: true})
+}
+
+// MarshalToFile writes the property list encoding of v to the file at path,
+// in the given format. The file is replaced atomically as described by
+// WriteFile, without syncing it to disk.
+func MarshalToFile(path string, v interface{}, format Format) error {
+	buf := getBuffer()
+	data, err := MarshalAppend((*buf)[:0], v, format)
+	if err == nil {
+		err = WriteFile(path, data, false)
+	}
+	putBuffer(buf, data)
+	return err
+}
+
+// WriteFile atomically replaces the contents of the file at path with data.
+// Readers of the file see either the old contents or the new contents, never a
+// partially written file.
+//
+// The data is written to a temporary file in the same directory, which is then
+// renamed over path. If path already exists, its permissions and (when
+// possible) its owner and group are copied to the new file, and if it is a
+// symbolic link, the file it points to is replaced instead. New files are
+// created with permissions 0644.
+//
+// If sync is true, the new file and its directory are flushed to stable
+// storage before WriteFile returns.
+func WriteFile(path string, data []byte, sync bool) (err error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	perm := os.FileMode(0644)
+	info, statErr := os.Stat(path)
+	if statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if statErr == nil {
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			// only root can give files away, so this is best-effort
+			if err = f.Chown(int(st.Uid), int(st.Gid)); err != nil && !os.IsPermission(err) {
+				return err
+			}
+			err = nil
+		}
+	}
+	if sync {
+		if err = f.Sync(); err != nil {
+			return err
+		}
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	if sync {
+		d, err := os.Open(dir)
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		return d.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package plist
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarshalToFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.plist")
+
+	if err := MarshalToFile(path, T{X: "one", Y: 1}, XMLFormat); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarshalToFile(path, T{X: "two", Y: 2}, BinaryFormat); err != nil {
+		t.Fatal(err)
+	}
+
+	var v T
+	format, err := UnmarshalFile(path, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != BinaryFormat {
+		t.Errorf("got format %v, want %v", format, BinaryFormat)
+	}
+	if want := (T{X: "two", Y: 2}); v != want {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("permissions were not preserved: got %v", perm)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary file was left behind: %v", entries)
+	}
+}
+
+func TestWriteFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.plist")
+	link := filepath.Join(dir, "link.plist")
+	if err := os.WriteFile(target, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(link, []byte("data"), true); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink was replaced")
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "data" {
+		t.Errorf("target has %q, %v", data, err)
+	}
+}
+
+func TestUnmarshalFileMapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.plist")
+	want := struct {
+		Name string
+		Blob []byte
+	}{"mapped", bytes.Repeat([]byte{0xab}, 1<<20)}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		if err := MarshalToFile(path, want, format); err != nil {
+			t.Fatal(err)
+		}
+		var v struct {
+			Name string
+			Blob []byte
+		}
+		got, err := UnmarshalFileMapped(path, &v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != format {
+			t.Errorf("got format %v, want %v", got, format)
+		}
+		if v.Name != want.Name || !bytes.Equal(v.Blob, want.Blob) {
+			t.Errorf("%v: got %q and %d bytes", format, v.Name, len(v.Blob))
+		}
+	}
+
+	// an empty file is an error, as it is for UnmarshalFile
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if _, err := UnmarshalFileMapped(path, &v); err == nil {
+		t.Error("expected an error for an empty file")
+	}
+	if _, err := UnmarshalFileMapped(filepath.Join(t.TempDir(), "missing.plist"), &v); !os.IsNotExist(err) {
+		t.Errorf("got %v for a missing file, want a not-exist error", err)
+	}
+}
@@ -0,0 +1,71 @@
+//go:build plist_foundation
+// +build plist_foundation
+
+package plist
+
+// #cgo CFLAGS: -x objective-c
+// #cgo LDFLAGS: -framework Foundation
+// #import <Foundation/Foundation.h>
+//
+// // These mirror CFPropertyListCreateWithData and CFPropertyListCreateData
+// // using NSPropertyListSerialization. NSData, NSError and the property list
+// // classes are toll-free bridged, so the results are returned as retained
+// // CoreFoundation references.
+// static CFPropertyListRef foundationCreateWithData(CFDataRef data, CFPropertyListFormat *format, CFErrorRef *error) {
+//     @autoreleasepool {
+//         NSPropertyListFormat fmt = 0;
+//         NSError *err = nil;
+//         id obj = [NSPropertyListSerialization propertyListWithData:(NSData *)data options:NSPropertyListImmutable format:&fmt error:&err];
+//         *format = (CFPropertyListFormat)fmt;
+//         if (obj == nil) {
+//             *error = err != nil ? (CFErrorRef)CFRetain((CFTypeRef)err) : NULL;
+//             return NULL;
+//         }
+//         return CFRetain((CFTypeRef)obj);
+//     }
+// }
+//
+// static CFDataRef foundationCreateData(CFPropertyListRef plist, CFPropertyListFormat format, CFErrorRef *error) {
+//     @autoreleasepool {
+//         NSError *err = nil;
+//         NSData *data = [NSPropertyListSerialization dataWithPropertyList:(id)plist format:(NSPropertyListFormat)format options:0 error:&err];
+//         if (data == nil) {
+//             *error = err != nil ? (CFErrorRef)CFRetain((CFTypeRef)err) : NULL;
+//             return NULL;
+//         }
+//         return (CFDataRef)CFRetain((CFTypeRef)data);
+//     }
+// }
+import "C"
+import "errors"
+
+func foundationPropertyListCreateWithCFData(cfData C.CFDataRef) (cfObj cfTypeRef, format Format, err error) {
+	var cfFormat C.CFPropertyListFormat
+	var cfError C.CFErrorRef
+	cfPlist := C.foundationCreateWithData(cfData, &cfFormat, &cfError)
+	if cfPlist == nil {
+		if cfError != nil {
+			cfCreated(cfError)
+			defer cfRelease(cfTypeRef(cfError))
+			return nil, Format{cfFormat}, NewCFError(cfError)
+		}
+		return nil, Format{}, errors.New("plist: unknown error in NSPropertyListSerialization")
+	}
+	cfTrack(cfTypeRef(cfPlist))
+	return cfTypeRef(cfPlist), Format{cfFormat}, nil
+}
+
+func appendFoundationPropertyListData(dst []byte, plist cfTypeRef, format Format) ([]byte, error) {
+	var cfError C.CFErrorRef
+	cfData := cfCreated(C.foundationCreateData(C.CFPropertyListRef(plist), format.cfFormat, &cfError))
+	if cfData == nil {
+		if cfError != nil {
+			cfCreated(cfError)
+			defer cfRelease(cfTypeRef(cfError))
+			return nil, NewCFError(cfError)
+		}
+		return nil, errors.New("plist: unknown error in NSPropertyListSerialization")
+	}
+	defer cfRelease(cfTypeRef(cfData))
+	return appendCFDataBytes(dst, cfData), nil
+}
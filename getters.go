@@ -0,0 +1,158 @@
+package plist
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The typed getters below look up a key path with GetPath and convert the
+// value found there to the requested type. They return def if the path
+// doesn't exist or the value can't be converted.
+
+// GetString returns the string at path in v. Numbers and booleans are
+// formatted as text.
+func GetString(v interface{}, path string, def string) string {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	switch val := val.(type) {
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	}
+	return def
+}
+
+// GetInt returns the integer at path in v. Floats with no fractional part and
+// strings holding a decimal integer are converted.
+func GetInt(v interface{}, path string, def int64) int64 {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	if s, ok := val.(string); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return i
+		}
+		return def
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= math.MaxInt64 {
+			return int64(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f)
+		}
+	}
+	return def
+}
+
+// GetFloat returns the number at path in v as a float64. Strings holding a
+// number are converted.
+func GetFloat(v interface{}, path string, def float64) float64 {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	if s, ok := val.(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f
+		}
+		return def
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return def
+}
+
+// GetBool returns the boolean at path in v. As with CFPreferences, numbers are
+// true if they are non-zero, and the strings "YES", "true" and "1" are true and
+// "NO", "false" and "0" are false, ignoring case.
+func GetBool(v interface{}, path string, def bool) bool {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	switch val := val.(type) {
+	case bool:
+		return val
+	case string:
+		switch strings.ToLower(strings.TrimSpace(val)) {
+		case "yes", "true", "1":
+			return true
+		case "no", "false", "0":
+			return false
+		}
+		return def
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() != 0
+	}
+	return def
+}
+
+// GetTime returns the date at path in v. Strings in RFC 3339 format are
+// converted.
+func GetTime(v interface{}, path string, def time.Time) time.Time {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	switch val := val.(type) {
+	case time.Time:
+		return val
+	case string:
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(val)); err == nil {
+			return t
+		}
+	}
+	return def
+}
+
+// GetData returns the data at path in v. Strings are converted to their UTF-8
+// bytes.
+func GetData(v interface{}, path string, def []byte) []byte {
+	val, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	switch val := val.(type) {
+	case []byte:
+		return val
+	case string:
+		return []byte(val)
+	}
+	return def
+}
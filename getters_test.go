@@ -0,0 +1,72 @@
+package plist
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(t *testing.T) {
+	when := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	v := map[string]interface{}{
+		"Name":    "widget",
+		"Count":   int32(3),
+		"Ratio":   2.0,
+		"Half":    0.5,
+		"Enabled": "YES",
+		"Flag":    int64(0),
+		"Numeric": " 42 ",
+		"When":    when,
+		"WhenStr": "2020-01-02T03:04:05Z",
+		"Data":    []byte{1, 2},
+		"List":    []interface{}{true},
+	}
+	if got := GetString(v, "Name", "x"); got != "widget" {
+		t.Errorf("GetString: got %q", got)
+	}
+	if got := GetString(v, "Count", "x"); got != "3" {
+		t.Errorf("GetString number: got %q", got)
+	}
+	if got := GetString(v, "Missing", "x"); got != "x" {
+		t.Errorf("GetString default: got %q", got)
+	}
+	if got := GetString(v, "Data", "x"); got != "x" {
+		t.Errorf("GetString data: got %q", got)
+	}
+	if got := GetInt(v, "Count", -1); got != 3 {
+		t.Errorf("GetInt: got %d", got)
+	}
+	if got := GetInt(v, "Ratio", -1); got != 2 {
+		t.Errorf("GetInt float: got %d", got)
+	}
+	if got := GetInt(v, "Half", -1); got != -1 {
+		t.Errorf("GetInt fraction: got %d", got)
+	}
+	if got := GetInt(v, "Numeric", -1); got != 42 {
+		t.Errorf("GetInt string: got %d", got)
+	}
+	if got := GetFloat(v, "Count", -1); got != 3 {
+		t.Errorf("GetFloat: got %v", got)
+	}
+	if got := GetBool(v, "Enabled", false); !got {
+		t.Error("GetBool YES: got false")
+	}
+	if got := GetBool(v, "Flag", true); got {
+		t.Error("GetBool 0: got true")
+	}
+	if got := GetBool(v, "List:0", false); !got {
+		t.Error("GetBool nested: got false")
+	}
+	if got := GetBool(v, "Name", true); !got {
+		t.Error("GetBool unconvertible: expected default")
+	}
+	if got := GetTime(v, "When", time.Time{}); !got.Equal(when) {
+		t.Errorf("GetTime: got %v", got)
+	}
+	if got := GetTime(v, "WhenStr", time.Time{}); !got.Equal(when) {
+		t.Errorf("GetTime string: got %v", got)
+	}
+	if got := GetData(v, "Data", nil); !bytes.Equal(got, []byte{1, 2}) {
+		t.Errorf("GetData: got %v", got)
+	}
+}
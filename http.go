@@ -0,0 +1,101 @@
+package plist
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types for property lists sent over HTTP.
+const (
+	XMLContentType    = "application/x-plist"
+	BinaryContentType = "application/x-bplist"
+)
+
+// DefaultHTTPMaxSize is the size limit NewHTTPDecoder places on request
+// bodies.
+const DefaultHTTPMaxSize = 10 << 20
+
+// httpContentTypes are the media types NewHTTPDecoder accepts. Apple's device
+// management protocols use several of their own.
+var httpContentTypes = map[string]bool{
+	XMLContentType:                          true,
+	BinaryContentType:                       true,
+	"application/xml":                       true,
+	"text/xml":                              true,
+	"application/octet-stream":              true,
+	"application/x-apple-aspen-config":      true,
+	"application/x-apple-aspen-mdm":         true,
+	"application/x-apple-aspen-mdm-checkin": true,
+}
+
+// NewHTTPDecoder returns a Decoder that reads the body of the request r. It
+// returns an error if the request has a Content-Type that isn't a property
+// list type; a missing Content-Type is allowed. The decoder's size limit is
+// DefaultHTTPMaxSize, which can be changed with LimitSize.
+func NewHTTPDecoder(r *http.Request) (*Decoder, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, errors.New("plist: invalid Content-Type: " + err.Error())
+		}
+		if !httpContentTypes[mediaType] {
+			return nil, errors.New("plist: unsupported Content-Type " + mediaType)
+		}
+	}
+	dec := NewDecoder(r.Body)
+	dec.LimitSize(DefaultHTTPMaxSize)
+	return dec, nil
+}
+
+// NegotiateFormat returns the format to respond to r with: BinaryFormat if
+// its Accept header lists BinaryContentType without rejecting it with a zero
+// quality value, and XMLFormat otherwise.
+func NegotiateFormat(r *http.Request) Format {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == BinaryContentType && acceptable(params) {
+				return BinaryFormat
+			}
+		}
+	}
+	return XMLFormat
+}
+
+// acceptable reports whether the quality value in the parameters of an
+// Accept header entry allows the media type. A q of 0, however it's spelled,
+// rejects it; a malformed q is ignored.
+func acceptable(params map[string]string) bool {
+	q, ok := params["q"]
+	if !ok {
+		return true
+	}
+	f, err := strconv.ParseFloat(q, 64)
+	return err != nil || f > 0
+}
+
+// WriteHTTP marshals v in the given format and writes it to w as the body of
+// a response with the given status code, setting the Content-Type and
+// Content-Length headers. Nothing is written if v can't be marshaled.
+func WriteHTTP(w http.ResponseWriter, status int, v interface{}, format Format) error {
+	buf := getBuffer()
+	data, err := MarshalAppend((*buf)[:0], v, format)
+	defer func() { putBuffer(buf, data) }()
+	if err != nil {
+		return err
+	}
+	contentType := XMLContentType
+	if format == BinaryFormat {
+		contentType = BinaryContentType
+	} else if format == OpenStepFormat {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,88 @@
+package plist
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dec, err := NewHTTPDecoder(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		dec.LimitSize(64)
+		var v T
+		if err := dec.Decode(&v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v.Y++
+		WriteHTTP(w, http.StatusOK, v, NegotiateFormat(r))
+	})
+
+	data, err := Marshal(T{X: "x", Y: 1}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/", bytes.NewReader(data))
+	req.Header.Set("Content-Type", BinaryContentType)
+	req.Header.Set("Accept", "application/x-bplist, application/x-plist;q=0.5")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != BinaryContentType {
+		t.Errorf("got Content-Type %q", ct)
+	}
+	var v T
+	format, err := Unmarshal(rec.Body.Bytes(), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != BinaryFormat || v.Y != 2 {
+		t.Errorf("got %v in %v", v, format)
+	}
+
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong Content-Type: got status %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/", bytes.NewReader(make([]byte, 65)))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || rec.Body.String() != ErrTooLarge.Error()+"\n" {
+		t.Errorf("oversized body: got status %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", XMLFormat},
+		{"application/x-bplist", BinaryFormat},
+		{"application/x-plist, application/x-bplist;q=0.1", BinaryFormat},
+		{"application/x-bplist;q=0", XMLFormat},
+		{"application/x-bplist;q=0.0", XMLFormat},
+		{"application/x-bplist; q=0.000", XMLFormat},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		if got := NegotiateFormat(req); got != test.want {
+			t.Errorf("Accept %q: got %v, want %v", test.accept, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package cms extracts the content of CMS (PKCS #7) signed-data messages, the
+// wrapper Apple uses for provisioning profiles and signed configuration
+// profiles.
+//
+// Signatures are not verified; the content is returned as-is.
+package cms
+
+import (
+	"encoding/asn1"
+	"errors"
+)
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue // [0] EXPLICIT
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+type encapContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"` // [0] EXPLICIT OCTET STRING
+}
+
+// Content returns the encapsulated content of the signed-data message data.
+// The message may be BER encoded, with indefinite lengths, as provisioning
+// profiles are.
+func Content(data []byte) ([]byte, error) {
+	data, _, err := toDER(data, 0)
+	if err != nil {
+		return nil, errors.New("cms: not a CMS message: " + err.Error())
+	}
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, errors.New("cms: not a CMS message: " + err.Error())
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("cms: not a signed-data message")
+	}
+	var sd signedData
+	if !isExplicit(ci.Content) {
+		return nil, errors.New("cms: invalid signed-data message")
+	}
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, errors.New("cms: invalid signed-data message: " + err.Error())
+	}
+	if !isExplicit(sd.EncapContentInfo.Content) {
+		return nil, errors.New("cms: signed-data message has detached content")
+	}
+	var content asn1.RawValue
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.Content.Bytes, &content); err != nil {
+		return nil, errors.New("cms: invalid signed-data content: " + err.Error())
+	}
+	if content.Class != asn1.ClassUniversal || content.Tag != asn1.TagOctetString {
+		return nil, errors.New("cms: signed-data content is not an OCTET STRING")
+	}
+	result, err := appendOctets(nil, content)
+	if err != nil {
+		return nil, errors.New("cms: invalid signed-data content: " + err.Error())
+	}
+	return result, nil
+}
+
+// appendOctets appends the contents of the OCTET STRING v to dst. A
+// constructed OCTET STRING is a sequence of segments, which BER allows to be
+// constructed themselves.
+func appendOctets(dst []byte, v asn1.RawValue) ([]byte, error) {
+	if !v.IsCompound {
+		return append(dst, v.Bytes...), nil
+	}
+	for rest := v.Bytes; len(rest) > 0; {
+		var segment asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &segment); err != nil {
+			return nil, err
+		}
+		if segment.Class != asn1.ClassUniversal || segment.Tag != asn1.TagOctetString {
+			return nil, errors.New("OCTET STRING segment has the wrong type")
+		}
+		if dst, err = appendOctets(dst, segment); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// maxDepth is how deeply toDER lets elements nest.
+const maxDepth = 64
+
+// toDER re-encodes the BER element at the start of data, which is depth
+// elements deep, with definite lengths in their shortest form, which is all
+// encoding/asn1 needs of DER to parse CMS messages. It returns the element
+// and the rest of data.
+func toDER(data []byte, depth int) (der, rest []byte, err error) {
+	if depth > maxDepth {
+		return nil, nil, errors.New("elements nested too deeply")
+	}
+	// the identifier octets are copied as they are
+	if len(data) < 2 {
+		return nil, nil, errTruncated
+	}
+	n := 1
+	if data[0]&0x1f == 0x1f {
+		// high tag number form
+		for n < len(data) && data[n]&0x80 != 0 {
+			n++
+		}
+		n++
+	}
+	if n >= len(data) {
+		return nil, nil, errTruncated
+	}
+	identifier := data[:n]
+	constructed := data[0]&0x20 != 0
+	lengthByte := data[n]
+	data = data[n+1:]
+
+	var contents []byte
+	switch {
+	case lengthByte == 0x80:
+		// indefinite length: the contents run to an end-of-contents
+		// element
+		if !constructed {
+			return nil, nil, errors.New("indefinite length on a primitive element")
+		}
+		for {
+			if len(data) >= 2 && data[0] == 0 && data[1] == 0 {
+				data = data[2:]
+				break
+			}
+			var child []byte
+			if child, data, err = toDER(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, child...)
+		}
+	default:
+		length := int(lengthByte)
+		if lengthByte&0x80 != 0 {
+			size := int(lengthByte & 0x7f)
+			if size > 4 || size > len(data) {
+				return nil, nil, errTruncated
+			}
+			length = 0
+			for _, b := range data[:size] {
+				length = length<<8 | int(b)
+			}
+			data = data[size:]
+		}
+		if length < 0 || length > len(data) {
+			return nil, nil, errTruncated
+		}
+		contents, data = data[:length], data[length:]
+		if constructed {
+			var children []byte
+			for inner := contents; len(inner) > 0; {
+				var child []byte
+				if child, inner, err = toDER(inner, depth+1); err != nil {
+					return nil, nil, err
+				}
+				children = append(children, child...)
+			}
+			contents = children
+		}
+	}
+
+	der = append(der, identifier...)
+	der = appendLength(der, len(contents))
+	der = append(der, contents...)
+	return der, data, nil
+}
+
+var errTruncated = errors.New("truncated element")
+
+// appendLength appends the DER encoding of length to dst.
+func appendLength(dst []byte, length int) []byte {
+	if length < 0x80 {
+		return append(dst, byte(length))
+	}
+	n := 0
+	for l := length; l > 0; l >>= 8 {
+		n++
+	}
+	dst = append(dst, 0x80|byte(n))
+	for i := n - 1; i >= 0; i-- {
+		dst = append(dst, byte(length>>(8*i)))
+	}
+	return dst
+}
+
+// isExplicit reports whether v is an explicit [0] tag.
+func isExplicit(v asn1.RawValue) bool {
+	return v.Class == asn1.ClassContextSpecific && v.Tag == 0 && v.IsCompound
+}
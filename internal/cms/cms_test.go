@@ -0,0 +1,139 @@
+package cms
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"testing"
+)
+
+// explicit returns der wrapped in an explicit context-specific tag 0.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// wrap returns an unsigned signed-data message with the given encapsulated
+// content, which must be an encoded OCTET STRING.
+func wrap(t *testing.T, content []byte) []byte {
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	sd, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo struct {
+			ContentType asn1.ObjectIdentifier
+			Content     asn1.RawValue
+		}
+		SignerInfos asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		EncapContentInfo: struct {
+			ContentType asn1.ObjectIdentifier
+			Content     asn1.RawValue
+		}{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, explicit(content)},
+		SignerInfos: emptySet,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, explicit(sd)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestContent(t *testing.T) {
+	want := []byte("<plist>hello</plist>")
+	primitive, _ := asn1.Marshal(want)
+	seg1, _ := asn1.Marshal(want[:7])
+	seg2, _ := asn1.Marshal(want[7:])
+	constructed, _ := asn1.Marshal(asn1.RawValue{
+		Class: asn1.ClassUniversal, Tag: asn1.TagOctetString, IsCompound: true,
+		Bytes: append(seg1, seg2...),
+	})
+
+	for name, content := range map[string][]byte{
+		"primitive":   primitive,
+		"constructed": constructed,
+	} {
+		got, err := Content(wrap(t, content))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := Content(want); err == nil {
+		t.Error("expected error for data that isn't a CMS message")
+	}
+}
+
+// ber returns a BER element with the given tag and contents, with an
+// indefinite length if tag is constructed and a definite one otherwise.
+func ber(tag byte, contents ...[]byte) []byte {
+	b := []byte{tag}
+	if tag&0x20 == 0 {
+		b = appendLength(b, len(bytes.Join(contents, nil)))
+		return append(b, bytes.Join(contents, nil)...)
+	}
+	b = append(b, 0x80)
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return append(b, 0, 0)
+}
+
+func TestContentBER(t *testing.T) {
+	// laid out as Apple signs provisioning profiles: every constructed
+	// element has an indefinite length, and the plist is a constructed
+	// OCTET STRING of definite-length segments
+	want := bytes.Repeat([]byte("<key>Name</key><string>profile</string>"), 100)
+	var segments [][]byte
+	for rest := want; len(rest) > 0; {
+		n := min(len(rest), 1000)
+		segments = append(segments, ber(0x04, rest[:n]))
+		rest = rest[n:]
+	}
+	oid := func(o asn1.ObjectIdentifier) []byte {
+		b, err := asn1.Marshal(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	sha256, _ := asn1.Marshal(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1})
+	data := ber(0x30,
+		oid(oidSignedData),
+		ber(0xa0,
+			ber(0x30,
+				[]byte{0x02, 0x01, 0x01},
+				ber(0x31, ber(0x30, sha256, []byte{0x05, 0x00})),
+				ber(0x30,
+					oid(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}),
+					ber(0xa0, ber(0x24, segments...))),
+				ber(0xa0, ber(0x30, ber(0x30, []byte{0x02, 0x01, 0x05}))),
+				ber(0x31, ber(0x30, []byte{0x02, 0x01, 0x01})))))
+
+	got, err := Content(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes, want %d", len(got), len(want))
+	}
+
+	for _, bad := range [][]byte{
+		data[:len(data)-2],                    // missing end-of-contents
+		{0x30, 0x80, 0x04, 0x80},              // indefinite primitive
+		{0x30, 0x84, 0xff, 0xff},              // length past the end
+		bytes.Repeat([]byte{0x30, 0x80}, 100), // too deep
+	} {
+		if _, err := Content(bad); err == nil {
+			t.Errorf("expected error for % x", bad)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package plist
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+)
+
+var (
+	ipType   = reflect.TypeOf(net.IP(nil))
+	addrType = reflect.TypeOf(netip.Addr{})
+)
+
+// isAddressType reports whether t is net.IP or netip.Addr, which are encoded
+// as strings of the address.
+func isAddressType(t reflect.Type) bool {
+	return t == ipType || t == addrType
+}
+
+// addressString returns the string form of v, a net.IP or netip.Addr. An
+// empty net.IP and the zero netip.Addr are the empty string.
+func addressString(v reflect.Value) (string, error) {
+	if ip, ok := v.Interface().(net.IP); ok {
+		if len(ip) == 0 {
+			return "", nil
+		}
+		if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+			return "", &UnsupportedValueError{v, "net.IP of length " + strconv.Itoa(len(ip))}
+		}
+		return ip.String(), nil
+	}
+	addr := v.Interface().(netip.Addr)
+	if !addr.IsValid() {
+		return "", nil
+	}
+	return addr.String(), nil
+}
+
+// parseAddress parses s as a value of t, which is net.IP or netip.Addr. The
+// empty string is an empty net.IP or the zero netip.Addr.
+func parseAddress(s string, t reflect.Type) (reflect.Value, bool) {
+	if t == ipType {
+		if s == "" {
+			return reflect.Zero(t), true
+		}
+		ip := net.ParseIP(s)
+		return reflect.ValueOf(ip), ip != nil
+	}
+	if s == "" {
+		return reflect.Zero(t), true
+	}
+	addr, err := netip.ParseAddr(s)
+	return reflect.ValueOf(addr), err == nil
+}
+
+// marshalAddress converts v, a net.IP or netip.Addr, to a CFString.
+func marshalAddress(v reflect.Value) (cfTypeRef, error) {
+	s, err := addressString(v)
+	if err != nil {
+		return nil, err
+	}
+	return marshalString(s)
+}
+
+// encodeAddress writes v, a net.IP or netip.Addr, as a string.
+func (state *streamState) encodeAddress(v reflect.Value) error {
+	s, err := addressString(v)
+	if err != nil {
+		return err
+	}
+	return state.w.value(s)
+}
@@ -0,0 +1,89 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+type network struct {
+	Router    net.IP
+	DNS       []net.IP
+	Gateway   netip.Addr
+	LinkLocal *netip.Addr
+	Unset     netip.Addr
+}
+
+func TestAddresses(t *testing.T) {
+	linkLocal := netip.MustParseAddr("fe80::1%en0")
+	v := network{
+		Router:    net.ParseIP("192.168.1.1"),
+		DNS:       []net.IP{net.ParseIP("1.1.1.1"), net.ParseIP("2606:4700:4700::1111")},
+		Gateway:   netip.MustParseAddr("10.0.0.1"),
+		LinkLocal: &linkLocal,
+	}
+	want := map[string]interface{}{
+		"Router":    "192.168.1.1",
+		"DNS":       []interface{}{"1.1.1.1", "2606:4700:4700::1111"},
+		"Gateway":   "10.0.0.1",
+		"LinkLocal": "fe80::1%en0",
+		"Unset":     "",
+	}
+	encode := NewTypedEncoder[network]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var plist interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got %#v, want %#v", format, plist, want)
+			}
+			var got network
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: got %#v, want %#v", format, got, v)
+			}
+			got = network{}
+			if _, err := NewTypedDecoder[network]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: TypedDecoder got %#v, want %#v", format, got, v)
+			}
+		}
+	}
+
+	if _, err := Marshal(net.IP{1, 2, 3}, XMLFormat); err == nil {
+		t.Error("expected an error marshaling a net.IP of length 3")
+	}
+
+	for _, field := range []string{"Router", "Gateway"} {
+		data, err := Marshal(map[string]interface{}{field: "300.1.1.1"}, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got network
+		var ute *UnmarshalTypeError
+		if _, err := Unmarshal(data, &got); !errors.As(err, &ute) {
+			t.Errorf("%s: expected UnmarshalTypeError, got %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONOptions controls the output of ToJSON.
+type JSONOptions struct {
+	// Indent, if not empty, causes ToJSON to write each element of an array
+	// or object on its own line, indented with one copy of Indent per level
+	// of nesting, as json.MarshalIndent does.
+	Indent string
+	// DecodeNestedPlists converts data holding a serialized property list as
+	// if the property list had appeared in its place, as
+	// Decoder.DecodeNestedPlists does.
+	DecodeNestedPlists bool
+}
+
+// ToJSON converts the property list in data, in any format, to JSON. Values
+// are mapped as follows:
+//
+//   - dictionaries become objects, with their keys in the order they appear
+//     in an XML property list (other formats don't record an order, so their
+//     keys are sorted). Keys that aren't strings are converted as
+//     Decoder.StringifyKeys does.
+//   - integers are written exactly, without a detour through float64, so
+//     values that don't fit in a double keep their precision
+//   - reals are written in the shortest form that round-trips, with a
+//     fraction or exponent even if they're integral, so FromJSON converts
+//     them back to reals. NaN and infinities have no JSON representation and
+//     are an error.
+//   - data becomes a standard base64 string
+//   - dates become RFC 3339 strings in UTC, with fractional seconds if needed
+//   - UIDs in keyed archives become {"CF$UID": n}, as in XML property lists
+//
+// A nil opts is the same as the zero JSONOptions.
+func ToJSON(data []byte, opts *JSONOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &JSONOptions{}
+	}
+	state := &unmarshalState{decodeOptions: decodeOptions{
+		nestedPlists:  opts.DecodeNestedPlists,
+		stringifyKeys: true,
+		orderedDicts:  true,
+	}}
+	var v interface{}
+	if _, err := unmarshal(data, &v, state); err != nil {
+		return nil, err
+	}
+	b, err := appendJSONValue(nil, reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Indent != "" {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, "", opts.Indent); err != nil {
+			return nil, err
+		}
+		b = buf.Bytes()
+	}
+	return b, nil
+}
+
+// appendJSONValue appends the JSON form of v, a value decoded into an
+// interface{}, to b.
+func appendJSONValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, "null"...), nil
+	}
+	switch v.Type() {
+	case dictPtrType:
+		d := v.Interface().(*Dict)
+		return appendJSONObject(b, d.keys, func(k string) interface{} { return d.values[k] })
+	case timeType:
+		t := v.Interface().(time.Time)
+		return appendJSONString(b, t.UTC().Format(time.RFC3339Nano)), nil
+	case uidType:
+		b = append(b, `{"CF$UID":`...)
+		b = strconv.AppendUint(b, v.Uint(), 10)
+		return append(b, '}'), nil
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return append(b, "null"...), nil
+		}
+		return appendJSONValue(b, v.Elem())
+	case reflect.String:
+		return appendJSONString(b, v.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(b, v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("plist: cannot represent " + formatXMLReal(f) + " in JSON")
+		}
+		n := len(b)
+		b = strconv.AppendFloat(b, f, 'g', -1, v.Type().Bits())
+		if !bytes.ContainsAny(b[n:], ".e") {
+			// keep integral reals from reading back as integers
+			b = append(b, ".0"...)
+		}
+		return b, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendJSONString(b, base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		b = append(b, '[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendJSONValue(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			keys := make([]string, 0, v.Len())
+			for _, k := range v.MapKeys() {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			return appendJSONObject(b, keys, func(k string) interface{} {
+				return v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())).Interface()
+			})
+		}
+	}
+	return nil, &UnsupportedTypeError{v.Type()}
+}
+
+func appendJSONObject(b []byte, keys []string, value func(string) interface{}) ([]byte, error) {
+	b = append(b, '{')
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, k)
+		b = append(b, ':')
+		var err error
+		if b, err = appendJSONValue(b, reflect.ValueOf(value(k))); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+// appendJSONString appends s as a JSON string. Unlike encoding/json, it
+// doesn't escape HTML characters, as plutil doesn't either. Invalid UTF-8 is
+// replaced with U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, `\n`...)
+			case c == '\r':
+				b = append(b, `\r`...)
+			case c == '\t':
+				b = append(b, `\t`...)
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, "\ufffd"...)
+		} else {
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
+
+// FromJSONOptions controls how FromJSONWithOptions recognizes values that JSON
+// has no type for. Key paths are matched as EqualOptions.UnorderedArrays are,
+// so a "*" component matches any key or index.
+type FromJSONOptions struct {
+	// DetectDates converts every string in RFC 3339 format to a date.
+	DetectDates bool
+	// DatePaths lists the key paths of strings to convert to dates. A string
+	// at one of these paths that isn't in RFC 3339 format is an error.
+	DatePaths []string
+	// DetectData converts every string that is valid standard base64, with
+	// padding, to data. Many short words are also valid base64, so DataPaths
+	// is usually a better choice.
+	DetectData bool
+	// DataPaths lists the key paths of strings to decode as standard base64
+	// data. A string at one of these paths that isn't valid base64 is an
+	// error.
+	DataPaths []string
+}
+
+// FromJSON converts JSON to a property list in the given format. It is the
+// reverse of ToJSON:
+//
+//   - objects become dictionaries, keeping the order of their keys in XML
+//     output. An object whose only key is "CF$UID", holding an integer,
+//     becomes a UID.
+//   - numbers without a fraction or exponent that fit in an int64 become
+//     integers, and other numbers become reals
+//   - strings, booleans and arrays map to their property list equivalents
+//
+// JSON null has no property list equivalent and is an error. Strings are
+// never converted to dates or data; use FromJSONWithOptions for that.
+func FromJSON(data []byte, format Format) ([]byte, error) {
+	return FromJSONWithOptions(data, format, nil)
+}
+
+// FromJSONWithOptions is like FromJSON, but converts strings to dates and data
+// as described by opts. A nil opts is the same as calling FromJSON.
+func FromJSONWithOptions(data []byte, format Format, opts *FromJSONOptions) ([]byte, error) {
+	v, err := decodeJSON(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(v, format)
+}
+
+// A jsonDecoder converts JSON to basic property list values.
+type jsonDecoder struct {
+	dec                  *json.Decoder
+	opts                 FromJSONOptions
+	datePaths, dataPaths []KeyPath
+}
+
+// decodeJSON parses data into basic property list values, with objects
+// decoded as *Dict values.
+func decodeJSON(data []byte, opts *FromJSONOptions) (interface{}, error) {
+	d := &jsonDecoder{dec: json.NewDecoder(bytes.NewReader(data))}
+	d.dec.UseNumber()
+	if opts != nil {
+		d.opts = *opts
+		for _, p := range opts.DatePaths {
+			d.datePaths = append(d.datePaths, ParseKeyPath(p))
+		}
+		for _, p := range opts.DataPaths {
+			d.dataPaths = append(d.dataPaths, ParseKeyPath(p))
+		}
+	}
+	v, err := d.value(KeyPath{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.dec.Token(); err != io.EOF {
+		return nil, errors.New("plist: invalid JSON: unexpected data after top-level value")
+	}
+	return v, nil
+}
+
+func (d *jsonDecoder) value(path KeyPath) (interface{}, error) {
+	tok, err := d.dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			arr := []interface{}{}
+			for d.dec.More() {
+				elem, err := d.value(append(path, strconv.Itoa(len(arr))))
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, elem)
+			}
+			_, err := d.dec.Token()
+			return arr, err
+		}
+		dict := &Dict{}
+		for d.dec.More() {
+			key, err := d.dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			k := key.(string)
+			elem, err := d.value(append(path, k))
+			if err != nil {
+				return nil, err
+			}
+			dict.Set(k, elem)
+		}
+		if _, err := d.dec.Token(); err != nil {
+			return nil, err
+		}
+		if uid, ok := dictUID(dict); ok {
+			return uid, nil
+		}
+		return dict, nil
+	case json.Number:
+		if n, err := tok.Int64(); err == nil {
+			return n, nil
+		}
+		f, err := tok.Float64()
+		if err != nil {
+			return nil, errors.New("plist: JSON number " + string(tok) + " is out of range")
+		}
+		return f, nil
+	case string:
+		return d.stringValue(tok, path)
+	case bool:
+		return tok, nil
+	}
+	return nil, errors.New("plist: JSON null at " + strconv.Quote(path.String()) + " has no property list equivalent")
+}
+
+// stringValue converts s to a date or data if the options call for it.
+func (d *jsonDecoder) stringValue(s string, path KeyPath) (interface{}, error) {
+	if matchAnyKeyPath(d.datePaths, path) {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, &KeyPathError{copyKeyPath(path), err}
+		}
+		return t, nil
+	}
+	if matchAnyKeyPath(d.dataPaths, path) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, &KeyPathError{copyKeyPath(path), err}
+		}
+		return b, nil
+	}
+	if d.opts.DetectDates {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+	}
+	if d.opts.DetectData && s != "" && len(s)%4 == 0 {
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return s, nil
+}
+
+func matchAnyKeyPath(patterns []KeyPath, path KeyPath) bool {
+	for _, p := range patterns {
+		if matchKeyPath(p, path, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// dictUID returns the UID represented by d, if it has the {"CF$UID": n} form
+// ToJSON writes for UIDs.
+func dictUID(d *Dict) (UID, bool) {
+	if d.Len() != 1 {
+		return 0, false
+	}
+	n, ok := d.values["CF$UID"].(int64)
+	if !ok || n < 0 || n > math.MaxUint32 {
+		return 0, false
+	}
+	return UID(n), true
+}
@@ -0,0 +1,195 @@
+package plist
+
+import (
+	"encoding/base64"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestToJSON(t *testing.T) {
+	data := []byte(xmlPlistHeader + `<dict>
+	<key>zebra</key>
+	<string>a "quoted" <tag></string>
+	<key>big</key>
+	<integer>9007199254740993</integer>
+	<key>real</key>
+	<real>1.5</real>
+	<key>data</key>
+	<data>AQID</data>
+	<key>date</key>
+	<date>2020-01-02T03:04:05Z</date>
+	<key>list</key>
+	<array>
+		<true/>
+		<dict/>
+	</array>
+</dict>
+</plist>
+`)
+	got, err := ToJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"zebra":"a \"quoted\" <tag>","big":9007199254740993,"real":1.5,"data":"AQID",` +
+		`"date":"2020-01-02T03:04:05Z","list":[true,{}]}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	got, err = ToJSON(data, &JSONOptions{Indent: "  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"zebra\": "; string(got[:len(want)]) != want {
+		t.Errorf("indented output: got %s", got)
+	}
+
+	if _, err := ToJSON([]byte("not a plist <"), nil); err == nil {
+		t.Error("invalid input: expected an error")
+	}
+}
+
+func TestToJSONNestedPlists(t *testing.T) {
+	inner, err := Marshal(map[string]interface{}{"k": "v", "n": 1}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(xmlPlistHeader + `<dict>
+	<key>inner</key>
+	<data>` + base64.StdEncoding.EncodeToString(inner) + `</data>
+	<key>raw</key>
+	<data>AQID</data>
+</dict>
+</plist>
+`)
+	got, err := ToJSON(data, &JSONOptions{DecodeNestedPlists: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"inner":{"k":"v","n":1},"raw":"AQID"}`; string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	// without the option the bplist is just data
+	got, err = ToJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"inner":"` + base64.StdEncoding.EncodeToString(inner) + `","raw":"AQID"}`; string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	d := &Dict{}
+	d.Set("b", int32(-2))
+	d.Set("a", map[string]interface{}{"y": uint64(math.MaxUint64), "x": nil})
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{d, `{"b":-2,"a":{"x":null,"y":18446744073709551615}}`},
+		{[]interface{}{float32(0.1), 1e21, false}, `[0.1,1e+21,false]`},
+		{[]interface{}{1.0, -2.0, float32(100)}, `[1.0,-2.0,100.0]`},
+		{time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.FixedZone("X", 3600)), `"2020-01-02T02:04:05.006Z"`},
+		{UID(7), `{"CF$UID":7}`},
+		{[]byte{}, `""`},
+	}
+	for _, tc := range tests {
+		got, err := appendJSONValue(nil, reflect.ValueOf(tc.v))
+		if err != nil {
+			t.Errorf("%#v: %v", tc.v, err)
+		} else if string(got) != tc.want {
+			t.Errorf("%#v: got %s, want %s", tc.v, got, tc.want)
+		}
+	}
+	if _, err := appendJSONValue(nil, reflect.ValueOf(math.NaN())); err == nil {
+		t.Error("NaN: expected an error")
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	got := string(appendJSONString(nil, "a\"\\\n\x01<&é\xff"))
+	want := `"a\"\\\n\u0001<&é` + "\ufffd" + `"`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	in := `{"b": 1, "a": [1.5, "x", true, {"CF$UID": 3}], "big": 1e300, "when": "2020-01-02T03:04:05Z"}`
+	data, err := FromJSON([]byte(in), XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"b":1,"a":[1.5,"x",true,{"CF$UID":3}],"big":1e+300,"when":"2020-01-02T03:04:05Z"}`
+	if string(out) != want {
+		t.Errorf("got  %s\nwant %s", out, want)
+	}
+}
+
+func TestJSONRoundTripReals(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{"real": 1.0, "int": int64(1)}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := ToJSON(data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := FromJSON(j, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if _, err := Unmarshal(back, &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"real": 1.0, "int": int64(1)}; !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		in   string
+		opts *FromJSONOptions
+		want interface{}
+	}{
+		{`[1, -1, 1.0, 18446744073709551615]`, nil, []interface{}{int64(1), int64(-1), 1.0, 18446744073709551615.0}},
+		{`["2020-01-02T03:04:05Z", "AQID"]`, nil, []interface{}{"2020-01-02T03:04:05Z", "AQID"}},
+		{`["2020-01-02T03:04:05Z", "AQID"]`, &FromJSONOptions{DetectDates: true, DetectData: true}, []interface{}{date, []byte{1, 2, 3}}},
+		{`["AQID", "AQID"]`, &FromJSONOptions{DataPaths: []string{"1"}}, []interface{}{"AQID", []byte{1, 2, 3}}},
+		{`{"a": [{"t": "2020-01-02T03:04:05Z"}]}`, &FromJSONOptions{DatePaths: []string{"a:*:t"}}, map[string]interface{}{
+			"a": []interface{}{map[string]interface{}{"t": date}},
+		}},
+		{`{"CF$UID": 2}`, nil, UID(2)},
+		{`{"CF$UID": 2, "x": 1}`, nil, map[string]interface{}{"CF$UID": int64(2), "x": int64(1)}},
+	}
+	for _, tc := range tests {
+		got, err := decodeJSON([]byte(tc.in), tc.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if !Equal(got, tc.want) {
+			t.Errorf("%s: got %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{`null`, `[1, null]`, `{"a": 1} {}`, `[1,`, ``} {
+		if _, err := decodeJSON([]byte(in), nil); err == nil {
+			t.Errorf("%s: expected an error", in)
+		}
+	}
+	if _, err := decodeJSON([]byte(`{"t": "soon"}`), &FromJSONOptions{DatePaths: []string{"t"}}); err == nil {
+		t.Error("invalid date at DatePaths: expected an error")
+	}
+}
@@ -0,0 +1,121 @@
+package plist
+
+import (
+	"sort"
+	"strconv"
+)
+
+// A KeyOrder reports whether key a of the dictionary at path goes before key
+// b. Keys that it doesn't put in either order keep the order they would have
+// otherwise. The path must not be retained or modified.
+type KeyOrder func(path KeyPath, a, b string) bool
+
+// KeyPriority returns a KeyOrder that puts the keys listed for a dictionary's
+// path first, in the order they're listed, followed by its other keys in
+// sorted order. The paths are colon-separated, as ParseKeyPath takes them, and
+// the empty path is the top-level dictionary. For instance, this puts Label
+// first in a launchd job:
+//
+//	enc.SetKeyOrder(plist.KeyPriority(map[string][]string{"": {"Label"}}))
+func KeyPriority(first map[string][]string) KeyOrder {
+	ranks := make(map[string]map[string]int, len(first))
+	for path, keys := range first {
+		rank := make(map[string]int, len(keys))
+		for i, key := range keys {
+			if _, ok := rank[key]; !ok {
+				rank[key] = i
+			}
+		}
+		ranks[ParseKeyPath(path).String()] = rank
+	}
+	return func(path KeyPath, a, b string) bool {
+		rank, ok := ranks[path.String()]
+		if !ok {
+			return false
+		}
+		ra, ok := rank[a]
+		if !ok {
+			return false
+		}
+		rb, ok := rank[b]
+		return !ok || ra < rb
+	}
+}
+
+// keyPathWriter is a streamWriter that keeps track of the key path of the
+// value being written, for an Encoder with a KeyOrder.
+type keyPathWriter struct {
+	streamWriter
+	// frames holds the open containers, with the index of the element
+	// being written in each array, and the key in each dictionary
+	frames []keyPathFrame
+}
+
+type keyPathFrame struct {
+	array bool
+	index int
+	key   string
+}
+
+// path returns the key path of the next value to be written.
+func (pw *keyPathWriter) path() KeyPath {
+	path := make(KeyPath, len(pw.frames))
+	for i, f := range pw.frames {
+		if f.array {
+			path[i] = strconv.Itoa(f.index)
+		} else {
+			path[i] = f.key
+		}
+	}
+	return path
+}
+
+// next moves on to the next element of the innermost container once one has
+// been written.
+func (pw *keyPathWriter) next() {
+	if n := len(pw.frames); n > 0 && pw.frames[n-1].array {
+		pw.frames[n-1].index++
+	}
+}
+
+func (pw *keyPathWriter) beginArray(n int) error {
+	pw.frames = append(pw.frames, keyPathFrame{array: true})
+	return pw.streamWriter.beginArray(n)
+}
+
+func (pw *keyPathWriter) endArray() error {
+	pw.frames = pw.frames[:len(pw.frames)-1]
+	pw.next()
+	return pw.streamWriter.endArray()
+}
+
+func (pw *keyPathWriter) beginDict(n int) error {
+	pw.frames = append(pw.frames, keyPathFrame{})
+	return pw.streamWriter.beginDict(n)
+}
+
+func (pw *keyPathWriter) endDict() error {
+	pw.frames = pw.frames[:len(pw.frames)-1]
+	pw.next()
+	return pw.streamWriter.endDict()
+}
+
+func (pw *keyPathWriter) key(k string) error {
+	pw.frames[len(pw.frames)-1].key = k
+	return pw.streamWriter.key(k)
+}
+
+func (pw *keyPathWriter) value(v interface{}) error {
+	pw.next()
+	return pw.streamWriter.value(v)
+}
+
+// orderKeys reorders x, a slice of the keys of the dictionary about to be
+// written, whose key i is returned by key, by the Encoder's KeyOrder.
+func (state *streamState) orderKeys(x interface{}, key func(i int) string) {
+	if state.keyOrder == nil {
+		return
+	}
+	path := state.w.(*keyPathWriter).path()
+	sort.SliceStable(x, func(i, j int) bool { return state.keyOrder(path, key(i), key(j)) })
+}
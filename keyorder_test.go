@@ -0,0 +1,120 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type launchdJob struct {
+	Program   string
+	Label     string
+	KeepAlive map[string]bool
+	Sockets   []map[string]string
+}
+
+// keyOrderOf returns the offsets of the <key> elements of keys in data, which
+// must all be there.
+func keyOrderOf(t *testing.T, data []byte, keys ...string) []int {
+	t.Helper()
+	offsets := make([]int, len(keys))
+	for i, key := range keys {
+		offsets[i] = bytes.Index(data, []byte("<key>"+key+"</key>"))
+		if offsets[i] < 0 {
+			t.Fatalf("key %q is missing:\n%s", key, data)
+		}
+	}
+	return offsets
+}
+
+func TestKeyPriority(t *testing.T) {
+	v := launchdJob{
+		Program:   "/usr/bin/true",
+		Label:     "com.example.true",
+		KeepAlive: map[string]bool{"Crashed": true, "SuccessfulExit": false},
+		Sockets:   []map[string]string{{"SockServiceName": "http", "SockFamily": "IPv4"}},
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf, format)
+		enc.SetKeyOrder(KeyPriority(map[string][]string{
+			"":          {"Label", "Program"},
+			"KeepAlive": {"SuccessfulExit"},
+			"Sockets:0": {"SockServiceName"},
+		}))
+		if err := enc.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		var got launchdJob
+		if _, err := Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("%v: got %+v, want %+v", format, got, v)
+		}
+		if format != XMLFormat {
+			continue
+		}
+		data := buf.Bytes()
+		o := keyOrderOf(t, data, "Label", "Program", "KeepAlive", "Sockets")
+		if !(o[0] < o[1] && o[1] < o[2] && o[2] < o[3]) {
+			t.Errorf("top-level keys out of order:\n%s", data)
+		}
+		o = keyOrderOf(t, data, "SuccessfulExit", "Crashed", "SockServiceName", "SockFamily")
+		if !(o[0] < o[1] && o[2] < o[3]) {
+			t.Errorf("nested keys out of order:\n%s", data)
+		}
+	}
+}
+
+func TestKeyOrderPaths(t *testing.T) {
+	// reverse order everywhere, recording the paths
+	var paths []string
+	reverse := func(path KeyPath, a, b string) bool {
+		if s := path.String(); len(paths) == 0 || paths[len(paths)-1] != s {
+			paths = append(paths, s)
+		}
+		return a > b
+	}
+	d := new(Dict)
+	d.Set("z", 1)
+	d.Set("d1", map[string]int{"x": 1, "y": 2})
+	v := map[string]interface{}{
+		"k1": []interface{}{"s", map[string]int{"m": 1, "n": 2}},
+		"k2": d,
+		"k3": struct{ P, Q int }{1, 2},
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, XMLFormat)
+	enc.SetKeyOrder(reverse)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	// the Dict keeps its order, so z comes before d1
+	data := buf.Bytes()
+	o := keyOrderOf(t, data, "k3", "Q", "P", "k2", "z", "d1", "y", "x", "k1", "n", "m")
+	for i := 1; i < len(o); i++ {
+		if o[i-1] > o[i] {
+			t.Errorf("keys out of order:\n%s", data)
+			break
+		}
+	}
+	want := []string{"", "k3", "k2:d1", "k1:1"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+
+	// without a KeyOrder, the output is that of Marshal
+	enc.SetKeyOrder(nil)
+	buf.Reset()
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	marshalled, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), marshalled) {
+		t.Errorf("got\n%s\nwant\n%s", buf.Bytes(), marshalled)
+	}
+}
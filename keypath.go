@@ -0,0 +1,297 @@
+package plist
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// A KeyPath identifies a value inside a decoded property list as a sequence of
+// components. Each component is a dictionary key, or a decimal index for an
+// array. The empty KeyPath identifies the root value.
+//
+// KeyPaths are written the same way as PlistBuddy entries, with components
+// separated by colons, e.g. "Items:3:Name". A leading colon is allowed.
+type KeyPath []string
+
+// ParseKeyPath splits a colon-separated key path into its components.
+func ParseKeyPath(path string) KeyPath {
+	path = strings.TrimPrefix(path, ":")
+	if path == "" {
+		return KeyPath{}
+	}
+	return KeyPath(strings.Split(path, ":"))
+}
+
+// String returns the colon-separated form of the key path.
+func (p KeyPath) String() string {
+	return strings.Join(p, ":")
+}
+
+// Errors wrapped by a KeyPathError.
+var (
+	ErrPathNotFound = errors.New("no such entry")
+	ErrPathExists   = errors.New("entry already exists")
+	ErrNotContainer = errors.New("entry is not a dictionary or array")
+	ErrBadIndex     = errors.New("invalid array index")
+)
+
+// A KeyPathError records the key path that caused an error in one of the key
+// path functions. Path is the path up to and including the component that
+// failed.
+type KeyPathError struct {
+	Path KeyPath
+	Err  error
+}
+
+func (e *KeyPathError) Error() string {
+	return "plist: " + strconv.Quote(e.Path.String()) + ": " + e.Err.Error()
+}
+
+func (e *KeyPathError) Unwrap() error {
+	return e.Err
+}
+
+// GetPath returns the value at path in v, which must be a value decoded into an
+// interface{}, i.e. built from map[string]interface{}, *Dict, []interface{},
+// and property list scalars.
+func GetPath(v interface{}, path string) (interface{}, error) {
+	return getPath(v, ParseKeyPath(path))
+}
+
+func getPath(v interface{}, p KeyPath) (interface{}, error) {
+	for i, key := range p {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			val, ok := c[key]
+			if !ok {
+				return nil, &KeyPathError{p[:i+1], ErrPathNotFound}
+			}
+			v = val
+		case *Dict:
+			val, ok := c.Get(key)
+			if !ok {
+				return nil, &KeyPathError{p[:i+1], ErrPathNotFound}
+			}
+			v = val
+		case []interface{}:
+			idx, err := arrayIndex(key, len(c)-1)
+			if err != nil {
+				return nil, &KeyPathError{p[:i+1], err}
+			}
+			v = c[idx]
+		default:
+			return nil, &KeyPathError{p[:i], ErrNotContainer}
+		}
+	}
+	return v, nil
+}
+
+// SetPath stores value at path in v, replacing any existing value, and returns
+// the updated root. Missing intermediate entries are created as dictionaries.
+// An array index may refer to an existing element, or be equal to the length of
+// the array to append.
+//
+// Containers in v are modified in place where possible, but appending to an
+// array may reallocate it, so callers must use the returned root. Setting the
+// empty path returns value itself.
+func SetPath(v interface{}, path string, value interface{}) (interface{}, error) {
+	return setPath(v, ParseKeyPath(path), value)
+}
+
+func setPath(v interface{}, p KeyPath, value interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	return updatePath(v, p, 0, true, func(c interface{}, key string) (interface{}, error) {
+		switch c := c.(type) {
+		case map[string]interface{}:
+			c[key] = value
+			return c, nil
+		case *Dict:
+			c.Set(key, value)
+			return c, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(c))
+			if err != nil {
+				return nil, err
+			}
+			if idx == len(c) {
+				return append(c, value), nil
+			}
+			c[idx] = value
+			return c, nil
+		}
+		return nil, ErrNotContainer
+	})
+}
+
+// AddPath is like SetPath, but fails with ErrPathExists if there is already a
+// dictionary entry at path. An array index inserts value before the element at
+// that index, or appends if it is equal to the length of the array. Adding the
+// empty path only succeeds if v is nil.
+func AddPath(v interface{}, path string, value interface{}) (interface{}, error) {
+	return addPath(v, ParseKeyPath(path), value)
+}
+
+func addPath(v interface{}, p KeyPath, value interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		if v != nil {
+			return nil, &KeyPathError{p, ErrPathExists}
+		}
+		return value, nil
+	}
+	return updatePath(v, p, 0, true, func(c interface{}, key string) (interface{}, error) {
+		switch c := c.(type) {
+		case map[string]interface{}:
+			if _, ok := c[key]; ok {
+				return nil, ErrPathExists
+			}
+			c[key] = value
+			return c, nil
+		case *Dict:
+			if _, ok := c.Get(key); ok {
+				return nil, ErrPathExists
+			}
+			c.Set(key, value)
+			return c, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(c))
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[idx+1:], c[idx:])
+			c[idx] = value
+			return c, nil
+		}
+		return nil, ErrNotContainer
+	})
+}
+
+// DeletePath removes the entry at path from v and returns the updated root.
+// Deleting an array element shifts the following elements down. It is an
+// error to delete the root or an entry that doesn't exist.
+func DeletePath(v interface{}, path string) (interface{}, error) {
+	return deletePath(v, ParseKeyPath(path))
+}
+
+func deletePath(v interface{}, p KeyPath) (interface{}, error) {
+	if len(p) == 0 {
+		return nil, &KeyPathError{p, errors.New("cannot delete the root")}
+	}
+	return updatePath(v, p, 0, false, func(c interface{}, key string) (interface{}, error) {
+		switch c := c.(type) {
+		case map[string]interface{}:
+			if _, ok := c[key]; !ok {
+				return nil, ErrPathNotFound
+			}
+			delete(c, key)
+			return c, nil
+		case *Dict:
+			if _, ok := c.Get(key); !ok {
+				return nil, ErrPathNotFound
+			}
+			c.Delete(key)
+			return c, nil
+		case []interface{}:
+			idx, err := arrayIndex(key, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:idx], c[idx+1:]...), nil
+		}
+		return nil, ErrNotContainer
+	})
+}
+
+// updatePath walks p from v and calls update with the container holding the
+// last component. The returned container replaces the original in its parent.
+// If create is true, missing intermediate entries, including a nil root, are
+// created as dictionaries. p must not be empty.
+func updatePath(v interface{}, p KeyPath, i int, create bool, update func(c interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if v == nil && create {
+		v = map[string]interface{}{}
+	}
+	key := p[i]
+	if i == len(p)-1 {
+		c, err := update(v, key)
+		if err != nil {
+			if err == ErrNotContainer {
+				return nil, &KeyPathError{p[:i], err}
+			}
+			return nil, &KeyPathError{p[:i+1], err}
+		}
+		return c, nil
+	}
+	switch c := v.(type) {
+	case map[string]interface{}:
+		child, ok := c[key]
+		if !ok {
+			if !create {
+				return nil, &KeyPathError{p[:i+1], ErrPathNotFound}
+			}
+			child = map[string]interface{}{}
+		}
+		child, err := updatePath(child, p, i+1, create, update)
+		if err != nil {
+			return nil, err
+		}
+		c[key] = child
+		return c, nil
+	case *Dict:
+		child, ok := c.Get(key)
+		if !ok {
+			if !create {
+				return nil, &KeyPathError{p[:i+1], ErrPathNotFound}
+			}
+			child = &Dict{}
+		}
+		child, err := updatePath(child, p, i+1, create, update)
+		if err != nil {
+			return nil, err
+		}
+		c.Set(key, child)
+		return c, nil
+	case []interface{}:
+		idx, err := arrayIndex(key, len(c)-1)
+		if err != nil {
+			return nil, &KeyPathError{p[:i+1], err}
+		}
+		child, err := updatePath(c[idx], p, i+1, create, update)
+		if err != nil {
+			return nil, err
+		}
+		c[idx] = child
+		return c, nil
+	}
+	return nil, &KeyPathError{p[:i], ErrNotContainer}
+}
+
+// matchKeyPath reports whether path matches pattern, in which a "*" component
+// matches any key or index. If ignoreCase is true, keys are compared as
+// strings.EqualFold does.
+func matchKeyPath(pattern, path KeyPath, ignoreCase bool) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, comp := range pattern {
+		if comp == "*" || comp == path[i] || (ignoreCase && strings.EqualFold(comp, path[i])) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// arrayIndex parses key as an array index no greater than max.
+func arrayIndex(key string, max int) (int, error) {
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 {
+		return 0, ErrBadIndex
+	}
+	if idx > max {
+		return 0, ErrPathNotFound
+	}
+	return idx, nil
+}
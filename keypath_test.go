@@ -0,0 +1,126 @@
+package plist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want KeyPath
+	}{
+		{"", KeyPath{}},
+		{":", KeyPath{}},
+		{"Items:3:Name", KeyPath{"Items", "3", "Name"}},
+		{":Items", KeyPath{"Items"}},
+	}
+	for _, test := range tests {
+		if got := ParseKeyPath(test.in); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseKeyPath(%q) = %#v, want %#v", test.in, got, test.want)
+		}
+	}
+}
+
+func keyPathFixture() interface{} {
+	return map[string]interface{}{
+		"Name": "root",
+		"Items": []interface{}{
+			map[string]interface{}{"Name": "first"},
+			map[string]interface{}{"Name": "second"},
+		},
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	v := keyPathFixture()
+	if got, err := GetPath(v, "Items:1:Name"); err != nil || got != "second" {
+		t.Errorf("got %#v, %v", got, err)
+	}
+	if got, err := GetPath(v, ""); err != nil || !reflect.DeepEqual(got, v) {
+		t.Errorf("root: got %#v, %v", got, err)
+	}
+	_, err := GetPath(v, "Items:2:Name")
+	if e, ok := err.(*KeyPathError); !ok || !errors.Is(err, ErrPathNotFound) || e.Path.String() != "Items:2" {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := GetPath(v, "Name:x"); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := GetPath(v, "Items:x"); !errors.Is(err, ErrBadIndex) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	v, err := SetPath(keyPathFixture(), "Items:2", "third")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = SetPath(v, "Items:0:Name", "FIRST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = SetPath(v, "New:Nested:Key", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Name": "root",
+		"Items": []interface{}{
+			map[string]interface{}{"Name": "FIRST"},
+			map[string]interface{}{"Name": "second"},
+			"third",
+		},
+		"New": map[string]interface{}{"Nested": map[string]interface{}{"Key": true}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v", v)
+	}
+	if _, err := SetPath(v, "Items:5", "x"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("got error %v", err)
+	}
+	if v, err := SetPath(nil, "A:B", 1); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"A": map[string]interface{}{"B": 1}}) {
+		t.Errorf("nil root: got %#v, %v", v, err)
+	}
+}
+
+func TestAddPath(t *testing.T) {
+	v, err := AddPath(keyPathFixture(), "Items:0", "zeroth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetPath(v, "Items:0"); got != "zeroth" {
+		t.Errorf("got %#v", got)
+	}
+	if got, _ := GetPath(v, "Items:1:Name"); got != "first" {
+		t.Errorf("got %#v", got)
+	}
+	if _, err := AddPath(v, "Name", "again"); !errors.Is(err, ErrPathExists) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	v, err := DeletePath(keyPathFixture(), "Items:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = DeletePath(v, "Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Items": []interface{}{map[string]interface{}{"Name": "second"}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v", v)
+	}
+	if _, err := DeletePath(v, "Missing:Key"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := DeletePath(v, ""); err == nil {
+		t.Error("expected error deleting root")
+	}
+}
@@ -0,0 +1,386 @@
+// Package l10n reads and writes the localization resources of Apple
+// platforms: .strings files, which map keys to localized strings, and
+// .stringsdict files, which add plural rules.
+package l10n
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// An Encoding is the text encoding of a .strings file.
+type Encoding int
+
+const (
+	UTF8    Encoding = iota // UTF-8 without a byte order mark
+	UTF16LE                 // little-endian UTF-16 with a byte order mark
+	UTF16BE                 // big-endian UTF-16 with a byte order mark
+)
+
+// A StringsEntry is a single key and value of a .strings file.
+type StringsEntry struct {
+	Key   string
+	Value string
+	// Comment is the comment preceding the entry, without the comment
+	// delimiters. It usually describes the string for translators.
+	Comment string
+}
+
+// A StringsFile is a parsed .strings file. The order of the entries and their
+// comments are kept, so a file can be edited and written back out without
+// losing information for translators.
+type StringsFile struct {
+	Entries []StringsEntry
+	// TrailingComment holds any comments after the last entry.
+	TrailingComment string
+	// Encoding is the encoding the file was read in, and the one Bytes uses.
+	Encoding Encoding
+}
+
+// Get returns the value of key.
+func (f *StringsFile) Get(key string) (string, bool) {
+	for _, e := range f.Entries {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of key, adding an entry at the end if there is none.
+func (f *StringsFile) Set(key, value string) {
+	for i := range f.Entries {
+		if f.Entries[i].Key == key {
+			f.Entries[i].Value = value
+			return
+		}
+	}
+	f.Entries = append(f.Entries, StringsEntry{Key: key, Value: value})
+}
+
+// Map returns the entries as a map from key to value. When a key appears more
+// than once, the last value wins, as it does for CFBundle.
+func (f *StringsFile) Map() map[string]string {
+	m := make(map[string]string, len(f.Entries))
+	for _, e := range f.Entries {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+// ParseStrings parses a .strings file. The encoding is detected from the
+// byte order mark, falling back to UTF-8, or UTF-16 if the data looks like
+// it.
+func ParseStrings(data []byte) (*StringsFile, error) {
+	text, enc, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	f := &StringsFile{Encoding: enc}
+	p := &stringsParser{s: text}
+	for {
+		comment, err := p.skipSpace()
+		if err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			f.TrailingComment = comment
+			return f, nil
+		}
+		key, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		value := key
+		if p.peek() == '=' {
+			p.pos++
+			if _, err := p.skipSpace(); err != nil {
+				return nil, err
+			}
+			if value, err = p.token(); err != nil {
+				return nil, err
+			}
+			if _, err := p.skipSpace(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() != ';' {
+			return nil, p.errorf("expected ';'")
+		}
+		p.pos++
+		f.Entries = append(f.Entries, StringsEntry{key, value, comment})
+	}
+}
+
+func decodeText(data []byte) (string, Encoding, error) {
+	var order binary.ByteOrder
+	enc := UTF8
+	switch {
+	case len(data) >= 2 && data[0] == 0xff && data[1] == 0xfe:
+		order, enc, data = binary.LittleEndian, UTF16LE, data[2:]
+	case len(data) >= 2 && data[0] == 0xfe && data[1] == 0xff:
+		order, enc, data = binary.BigEndian, UTF16BE, data[2:]
+	case len(data) >= 3 && data[0] == 0xef && data[1] == 0xbb && data[2] == 0xbf:
+		data = data[3:]
+	case len(data) >= 2 && len(data)%2 == 0 && data[0] != 0 && data[1] == 0:
+		// UTF-16 without a byte order mark, starting with an ASCII character
+		order, enc = binary.LittleEndian, UTF16LE
+	case len(data) >= 2 && len(data)%2 == 0 && data[0] == 0 && data[1] != 0:
+		order, enc = binary.BigEndian, UTF16BE
+	}
+	if order == nil {
+		if !utf8.Valid(data) {
+			return "", enc, errors.New("l10n: .strings file is not valid UTF-8 or UTF-16")
+		}
+		return string(data), enc, nil
+	}
+	if len(data)%2 != 0 {
+		return "", enc, errors.New("l10n: .strings file has an odd number of bytes for UTF-16")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units)), enc, nil
+}
+
+type stringsParser struct {
+	s   string
+	pos int
+}
+
+func (p *stringsParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *stringsParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *stringsParser) errorf(msg string) error {
+	line := 1 + strings.Count(p.s[:p.pos], "\n")
+	return errors.New("l10n: .strings line " + strconv.Itoa(line) + ": " + msg)
+}
+
+// skipSpace skips whitespace and comments, returning the text of the
+// comments.
+func (p *stringsParser) skipSpace() (string, error) {
+	var comments []string
+	for !p.eof() {
+		switch c := p.s[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			p.pos++
+		case strings.HasPrefix(p.s[p.pos:], "/*"):
+			end := strings.Index(p.s[p.pos+2:], "*/")
+			if end < 0 {
+				return "", p.errorf("unterminated comment")
+			}
+			comments = append(comments, strings.TrimSpace(p.s[p.pos+2:p.pos+2+end]))
+			p.pos += end + 4
+		case strings.HasPrefix(p.s[p.pos:], "//"):
+			end := strings.IndexByte(p.s[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.s) - p.pos
+			}
+			comments = append(comments, strings.TrimSpace(p.s[p.pos+2:p.pos+end]))
+			p.pos += end
+		default:
+			return strings.Join(comments, "\n"), nil
+		}
+	}
+	return strings.Join(comments, "\n"), nil
+}
+
+// isUnquoted reports whether c may appear in an unquoted string.
+func isUnquoted(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_$+/:.-", c) >= 0
+}
+
+// token parses a quoted or unquoted string.
+func (p *stringsParser) token() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for !p.eof() && isUnquoted(p.s[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", p.errorf("expected a string")
+		}
+		return p.s[start:p.pos], nil
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *stringsParser) escape(b *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.s[p.pos]
+	p.pos++
+	switch c {
+	case 'a':
+		b.WriteByte('\a')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case 'v':
+		b.WriteByte('\v')
+	case 'U', 'u':
+		// up to four hex digits of a UTF-16 code unit, which may be half of
+		// a surrogate pair
+		r, ok := p.hex()
+		if !ok {
+			return p.errorf("invalid \\U escape")
+		}
+		rest := p.s[p.pos:]
+		if utf16.IsSurrogate(r) && (strings.HasPrefix(rest, "\\U") || strings.HasPrefix(rest, "\\u")) {
+			save := p.pos
+			p.pos += 2
+			if r2, ok := p.hex(); ok && utf16.DecodeRune(r, r2) != utf8.RuneError {
+				r = utf16.DecodeRune(r, r2)
+			} else {
+				p.pos = save
+			}
+		}
+		b.WriteRune(r)
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		n := int(c - '0')
+		for i := 0; i < 2 && !p.eof() && p.s[p.pos] >= '0' && p.s[p.pos] <= '7'; i++ {
+			n = n*8 + int(p.s[p.pos]-'0')
+			p.pos++
+		}
+		b.WriteRune(rune(n))
+	default:
+		// \", \\, \' and anything else stand for themselves
+		b.WriteByte(c)
+	}
+	return nil
+}
+
+// hex parses up to four hex digits.
+func (p *stringsParser) hex() (rune, bool) {
+	start := p.pos
+	var r rune
+	for p.pos-start < 4 && !p.eof() {
+		c := p.s[p.pos]
+		var d byte
+		switch {
+		case c >= '0' && c <= '9':
+			d = c - '0'
+		case c >= 'a' && c <= 'f':
+			d = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			d = c - 'A' + 10
+		default:
+			return r, p.pos > start
+		}
+		r = r*16 + rune(d)
+		p.pos++
+	}
+	return r, p.pos > start
+}
+
+// Bytes returns the file serialized in its Encoding. Each entry is written
+// on its own line, preceded by its comment and followed by a blank line, the
+// layout genstrings uses.
+func (f *StringsFile) Bytes() []byte {
+	var b strings.Builder
+	for i, e := range f.Entries {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if e.Comment != "" {
+			b.WriteString("/* " + strings.ReplaceAll(e.Comment, "*/", "* /") + " */\n")
+		}
+		b.WriteString(quoteString(e.Key) + " = " + quoteString(e.Value) + ";\n")
+	}
+	if f.TrailingComment != "" {
+		if len(f.Entries) > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString("/* " + strings.ReplaceAll(f.TrailingComment, "*/", "* /") + " */\n")
+	}
+	return encodeText(b.String(), f.Encoding)
+}
+
+func encodeText(s string, enc Encoding) []byte {
+	var order binary.ByteOrder
+	switch enc {
+	case UTF16LE:
+		order = binary.LittleEndian
+	case UTF16BE:
+		order = binary.BigEndian
+	default:
+		return []byte(s)
+	}
+	units := utf16.Encode([]rune("\ufeff" + s))
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(data[2*i:], u)
+	}
+	return data
+}
+
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				h := strconv.FormatInt(int64(r), 16)
+				b.WriteString(`\U` + strings.Repeat("0", 4-len(h)) + h)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
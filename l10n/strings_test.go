@@ -0,0 +1,80 @@
+package l10n
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const testStrings = `/* Title of the main window */
+"window.title" = "Hello, \"world\"";
+
+// Shown when the \ escapes work
+"escapes" = "tab\there\nnewline \U00e9 \UD83D\UDE00 \101";
+unquoted = value.with-punctuation;
+
+/* Same key and value */
+"Cancel";
+/* end of file */
+`
+
+func TestParseStrings(t *testing.T) {
+	f, err := ParseStrings([]byte(testStrings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StringsEntry{
+		{"window.title", `Hello, "world"`, "Title of the main window"},
+		{"escapes", "tab\there\nnewline é \U0001F600 A", `Shown when the \ escapes work`},
+		{"unquoted", "value.with-punctuation", ""},
+		{"Cancel", "Cancel", "Same key and value"},
+	}
+	if !reflect.DeepEqual(f.Entries, want) {
+		t.Errorf("got %#v, want %#v", f.Entries, want)
+	}
+	if f.TrailingComment != "end of file" {
+		t.Errorf("got trailing comment %q", f.TrailingComment)
+	}
+	if f.Encoding != UTF8 {
+		t.Errorf("got encoding %v", f.Encoding)
+	}
+}
+
+func TestStringsRoundTrip(t *testing.T) {
+	f, err := ParseStrings([]byte(testStrings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Set("Cancel", "Annuler")
+	f.Set("new", "entry")
+	for _, enc := range []Encoding{UTF8, UTF16LE, UTF16BE} {
+		f.Encoding = enc
+		data := f.Bytes()
+		if enc == UTF16LE && !bytes.HasPrefix(data, []byte{0xff, 0xfe}) {
+			t.Errorf("UTF-16LE output has no byte order mark: % x", data[:4])
+		}
+		g, err := ParseStrings(data)
+		if err != nil {
+			t.Fatalf("encoding %v: %v", enc, err)
+		}
+		if !reflect.DeepEqual(g, f) {
+			t.Errorf("encoding %v: got %#v, want %#v", enc, g, f)
+		}
+	}
+	if v, ok := f.Get("Cancel"); !ok || v != "Annuler" {
+		t.Errorf("Get: got %q, %v", v, ok)
+	}
+}
+
+func TestParseStringsErrors(t *testing.T) {
+	for _, s := range []string{
+		`"a" = "b"`,
+		`"a" = "b`,
+		`/* unterminated`,
+		`= "b";`,
+	} {
+		if _, err := ParseStrings([]byte(s)); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package l10n
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A StringsDict is a parsed .stringsdict file, mapping localized string keys
+// to their formats.
+type StringsDict map[string]LocalizedFormat
+
+// A LocalizedFormat is an entry of a .stringsdict file.
+type LocalizedFormat struct {
+	// Format is the NSStringLocalizedFormatKey, a format string that refers
+	// to variables as %#@name@.
+	Format string
+	// Variables holds the plural rule of each variable referenced by Format.
+	Variables map[string]PluralRule
+}
+
+// A PluralRule selects a string according to the CLDR plural category of a
+// number.
+type PluralRule struct {
+	// ValueType is the NSStringFormatValueTypeKey, the format specifier of
+	// the number without the %, such as "d" or "lu".
+	ValueType string
+	// Forms maps plural categories ("zero", "one", "two", "few", "many" and
+	// "other") to strings.
+	Forms map[string]string
+}
+
+const (
+	formatKey    = "NSStringLocalizedFormatKey"
+	specTypeKey  = "NSStringFormatSpecTypeKey"
+	valueTypeKey = "NSStringFormatValueTypeKey"
+	pluralType   = "NSStringPluralRuleType"
+)
+
+// pluralCategoryNames are the CLDR plural categories, in order.
+var pluralCategoryNames = []string{"zero", "one", "two", "few", "many", "other"}
+
+func isPluralCategory(s string) bool {
+	for _, c := range pluralCategoryNames {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStringsDict parses the contents of a .stringsdict file. Variables with
+// a rule type other than NSStringPluralRuleType are rejected.
+func ParseStringsDict(data []byte) (StringsDict, error) {
+	var raw map[string]map[string]interface{}
+	if _, err := plist.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	d := make(StringsDict, len(raw))
+	for key, entry := range raw {
+		var lf LocalizedFormat
+		for name, v := range entry {
+			if name == formatKey {
+				s, ok := v.(string)
+				if !ok {
+					return nil, errors.New("l10n: " + key + ": " + formatKey + " is not a string")
+				}
+				lf.Format = s
+				continue
+			}
+			dict, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, errors.New("l10n: " + key + ": variable " + name + " is not a dictionary")
+			}
+			if spec, _ := dict[specTypeKey].(string); spec != pluralType {
+				return nil, errors.New("l10n: " + key + ": variable " + name + " has unsupported rule type " + strconv.Quote(spec))
+			}
+			rule := PluralRule{Forms: make(map[string]string)}
+			for k, v := range dict {
+				s, ok := v.(string)
+				if !ok {
+					return nil, errors.New("l10n: " + key + ": " + name + "." + k + " is not a string")
+				}
+				switch k {
+				case specTypeKey:
+				case valueTypeKey:
+					rule.ValueType = s
+				default:
+					rule.Forms[k] = s
+				}
+			}
+			if lf.Variables == nil {
+				lf.Variables = make(map[string]PluralRule)
+			}
+			lf.Variables[name] = rule
+		}
+		d[key] = lf
+	}
+	return d, nil
+}
+
+// ReadStringsDict parses the .stringsdict file at path.
+func ReadStringsDict(path string) (StringsDict, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseStringsDict(data)
+}
+
+// Marshal returns the .stringsdict file serialized in the given format.
+// .stringsdict files are conventionally XML.
+func (d StringsDict) Marshal(format plist.Format) ([]byte, error) {
+	raw := make(map[string]interface{}, len(d))
+	for key, lf := range d {
+		entry := map[string]interface{}{formatKey: lf.Format}
+		for name, rule := range lf.Variables {
+			if name == formatKey {
+				return nil, errors.New("l10n: " + key + ": variable may not be named " + formatKey)
+			}
+			dict := map[string]interface{}{specTypeKey: pluralType}
+			if rule.ValueType != "" {
+				dict[valueTypeKey] = rule.ValueType
+			}
+			for category, s := range rule.Forms {
+				dict[category] = s
+			}
+			entry[name] = dict
+		}
+		raw[key] = entry
+	}
+	return plist.Marshal(raw, format)
+}
+
+// variableNames returns the names of the variables that format refers to
+// with %#@name@.
+func variableNames(format string) []string {
+	var names []string
+	for {
+		i := strings.Index(format, "%#@")
+		if i < 0 {
+			return names
+		}
+		format = format[i+3:]
+		j := strings.IndexByte(format, '@')
+		if j < 0 {
+			return names
+		}
+		names = append(names, format[:j])
+		format = format[j+1:]
+	}
+}
+
+// pluralCategories lists the cardinal plural categories that CLDR defines
+// for each language, other than "other".
+var pluralCategories = map[string][]string{
+	"ar": {"zero", "one", "two", "few", "many"},
+	"be": {"one", "few", "many"},
+	"bs": {"one", "few"},
+	"ca": {"one", "many"},
+	"cs": {"one", "few", "many"},
+	"cy": {"zero", "one", "two", "few", "many"},
+	"es": {"one", "many"},
+	"fr": {"one", "many"},
+	"ga": {"one", "two", "few", "many"},
+	"he": {"one", "two"},
+	"hr": {"one", "few"},
+	"id": {},
+	"it": {"one", "many"},
+	"ja": {},
+	"km": {},
+	"ko": {},
+	"lo": {},
+	"lt": {"one", "few", "many"},
+	"lv": {"zero", "one"},
+	"ms": {},
+	"my": {},
+	"pl": {"one", "few", "many"},
+	"pt": {"one", "many"},
+	"ro": {"one", "few"},
+	"ru": {"one", "few", "many"},
+	"sk": {"one", "few", "many"},
+	"sl": {"one", "two", "few"},
+	"sr": {"one", "few"},
+	"th": {},
+	"uk": {"one", "few", "many"},
+	"vi": {},
+	"zh": {},
+}
+
+// PluralCategories returns the CLDR plural categories that the language uses
+// for cardinal numbers, always including "other". The language is a BCP 47
+// tag or Apple localization name such as "en", "pt-BR" or "zh-Hans"; only the
+// language subtag is considered. Languages not in the package's table are
+// assumed to use "one" and "other", as English does.
+func PluralCategories(language string) []string {
+	lang := strings.ToLower(language)
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	categories, ok := pluralCategories[lang]
+	if !ok {
+		categories = []string{"one"}
+	}
+	return append(append([]string(nil), categories...), "other")
+}
+
+// Validate checks the .stringsdict for problems: missing format keys,
+// variables that are referenced but not defined or defined but not used,
+// plural rules without an "other" form or with unknown categories, and, if
+// language is not empty, plural rules that lack a category the language uses
+// or have one it never selects. "zero" is always allowed, since Apple
+// platforms use it for the number 0 in every language.
+//
+// The problems are returned sorted by key; nil means the file is valid.
+func (d StringsDict) Validate(language string) []error {
+	var used map[string]bool
+	if language != "" {
+		used = make(map[string]bool)
+		for _, c := range PluralCategories(language) {
+			used[c] = true
+		}
+	}
+	keys := make([]string, 0, len(d))
+	for key := range d {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	report := func(key, msg string) {
+		errs = append(errs, errors.New("l10n: "+key+": "+msg))
+	}
+	for _, key := range keys {
+		lf := d[key]
+		if lf.Format == "" {
+			report(key, "missing "+formatKey)
+		}
+		referenced := make(map[string]bool)
+		for _, name := range variableNames(lf.Format) {
+			referenced[name] = true
+		}
+		// variable values may refer to further variables
+		for _, rule := range lf.Variables {
+			for _, s := range rule.Forms {
+				for _, name := range variableNames(s) {
+					referenced[name] = true
+				}
+			}
+		}
+		names := make([]string, 0, len(referenced)+len(lf.Variables))
+		for name := range referenced {
+			names = append(names, name)
+		}
+		for name := range lf.Variables {
+			if !referenced[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rule, ok := lf.Variables[name]
+			if !ok {
+				report(key, "variable "+name+" is not defined")
+				continue
+			}
+			if !referenced[name] {
+				report(key, "variable "+name+" is not used")
+			}
+			if rule.ValueType == "" {
+				report(key, name+" has no "+valueTypeKey)
+			}
+			for _, category := range sortedCategories(rule.Forms) {
+				if !isPluralCategory(category) {
+					report(key, name+" has unknown plural category "+category)
+				} else if used != nil && !used[category] && category != "zero" {
+					report(key, name+" has plural category "+category+", which "+language+" does not use")
+				}
+			}
+			if _, ok := rule.Forms["other"]; !ok {
+				report(key, name+" has no \"other\" form")
+			}
+			if used != nil {
+				for _, category := range PluralCategories(language) {
+					if _, ok := rule.Forms[category]; !ok && category != "other" {
+						report(key, name+" is missing plural category "+category+", which "+language+" uses")
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func sortedCategories(forms map[string]string) []string {
+	categories := make([]string, 0, len(forms))
+	for c := range forms {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	return categories
+}
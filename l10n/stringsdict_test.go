@@ -0,0 +1,78 @@
+package l10n
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestStringsDictRoundTrip(t *testing.T) {
+	in := StringsDict{
+		"%d files in %d folders": {
+			Format: "%#@files@ in %#@folders@",
+			Variables: map[string]PluralRule{
+				"files":   {ValueType: "d", Forms: map[string]string{"one": "%d file", "other": "%d files"}},
+				"folders": {ValueType: "d", Forms: map[string]string{"one": "%d folder", "other": "%d folders"}},
+			},
+		},
+	}
+	data, err := in.Marshal(plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseStringsDict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %#v, want %#v", out, in)
+	}
+}
+
+func TestStringsDictValidate(t *testing.T) {
+	d := StringsDict{
+		"ok": {
+			Format:    "%#@n@",
+			Variables: map[string]PluralRule{"n": {ValueType: "d", Forms: map[string]string{"zero": "none", "one": "%d", "few": "%d", "many": "%d", "other": "%d"}}},
+		},
+		"bad": {
+			Format: "%#@n@ %#@missing@",
+			Variables: map[string]PluralRule{
+				"n":      {Forms: map[string]string{"one": "%d", "two": "%d", "several": "%d"}},
+				"unused": {ValueType: "d", Forms: map[string]string{"one": "%d", "few": "%d", "many": "%d", "other": "%d"}},
+			},
+		},
+	}
+	var got []string
+	for _, err := range d.Validate("ru") {
+		got = append(got, strings.TrimPrefix(err.Error(), "l10n: "))
+	}
+	want := []string{
+		"bad: variable missing is not defined",
+		"bad: n has no NSStringFormatValueTypeKey",
+		"bad: n has unknown plural category several",
+		"bad: n has plural category two, which ru does not use",
+		`bad: n has no "other" form`,
+		"bad: n is missing plural category few, which ru uses",
+		"bad: n is missing plural category many, which ru uses",
+		"bad: variable unused is not used",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPluralCategories(t *testing.T) {
+	for lang, want := range map[string][]string{
+		"en":      {"one", "other"},
+		"pt-BR":   {"one", "many", "other"},
+		"zh-Hans": {"other"},
+		"ar":      {"zero", "one", "two", "few", "many", "other"},
+	} {
+		if got := PluralCategories(lang); !reflect.DeepEqual(got, want) {
+			t.Errorf("PluralCategories(%q) = %v, want %v", lang, got, want)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package launchd
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A ValidationError is returned when building a job definition that has
+// findings with severity Error.
+type ValidationError struct {
+	Findings []Finding // all findings, including warnings
+}
+
+func (e *ValidationError) Error() string {
+	var errs []Finding
+	for _, f := range e.Findings {
+		if f.Severity == Error {
+			errs = append(errs, f)
+		}
+	}
+	if len(errs) == 0 {
+		return "launchd: invalid job definition"
+	}
+	msg := "launchd: invalid job definition: " + errs[0].String()
+	if len(errs) > 1 {
+		msg += " (and " + strconv.Itoa(len(errs)-1) + " more errors)"
+	}
+	return msg
+}
+
+// A Builder builds a launchd job definition. Its methods return the Builder so
+// calls can be chained:
+//
+//	job := launchd.NewJob("com.example.backup").
+//		Arguments("/usr/local/bin/backup", "--quiet").
+//		StartCalendarInterval(launchd.Daily(3, 30)).
+//		StandardErrorPath("/var/log/backup.log")
+//	err := job.WriteFile("/Library/LaunchDaemons/com.example.backup.plist")
+//
+// The job is validated after every change, and Findings reports the problems
+// with the job as it stands. Marshal and WriteFile refuse to emit a job that
+// has errors.
+type Builder struct {
+	job      map[string]interface{}
+	errs     []Finding // values that couldn't be converted, one per key
+	findings []Finding
+}
+
+// NewJob returns a Builder for a job with the given label.
+func NewJob(label string) *Builder {
+	b := &Builder{job: make(map[string]interface{})}
+	return b.set("Label", label)
+}
+
+func (b *Builder) set(key string, v interface{}) *Builder {
+	b.dropErr(key)
+	b.job[key] = v
+	b.findings = validateJob(b.job)
+	return b
+}
+
+// Findings returns the problems with the job as it currently stands.
+func (b *Builder) Findings() []Finding {
+	return append(append([]Finding(nil), b.errs...), b.findings...)
+}
+
+// Set sets an arbitrary key of the job, for keys that have no method of their
+// own. The value may be anything that plist.Marshal accepts.
+func (b *Builder) Set(key string, v interface{}) *Builder {
+	nv, err := normalize(v)
+	if err != nil {
+		b.dropErr(key)
+		b.errs = append(b.errs, Finding{key, Error, err.Error()})
+		return b
+	}
+	return b.set(key, nv)
+}
+
+// dropErr forgets the conversion error of an earlier Set of key, which a new
+// value replaces.
+func (b *Builder) dropErr(key string) {
+	errs := b.errs[:0]
+	for _, f := range b.errs {
+		if f.Key != key {
+			errs = append(errs, f)
+		}
+	}
+	b.errs = errs
+}
+
+// normalize converts v into the generic representation that
+// plist.Unmarshal produces, which is what the validator understands.
+func normalize(v interface{}) (interface{}, error) {
+	if _, ok := v.(time.Time); ok || v == nil {
+		return v, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		ary := make([]interface{}, rv.Len())
+		for i := range ary {
+			elem, err := normalize(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			ary[i] = elem
+		}
+		return ary, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			dict := make(map[string]interface{}, rv.Len())
+			for _, k := range rv.MapKeys() {
+				elem, err := normalize(rv.MapIndex(k).Interface())
+				if err != nil {
+					return nil, err
+				}
+				dict[k.String()] = elem
+			}
+			return dict, nil
+		}
+	}
+	// anything else, such as a struct, goes through the plist encoding
+	data, err := plist.Marshal(v, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	_, err = plist.Unmarshal(data, &result)
+	return result, err
+}
+
+// Program sets the path of the executable to run.
+func (b *Builder) Program(path string) *Builder {
+	return b.set("Program", path)
+}
+
+// Arguments sets the arguments of the job, including argv[0]. If Program
+// isn't set, the first argument is the executable to run.
+func (b *Builder) Arguments(args ...string) *Builder {
+	ary := make([]interface{}, len(args))
+	for i, arg := range args {
+		ary[i] = arg
+	}
+	return b.set("ProgramArguments", ary)
+}
+
+// Environment sets an environment variable for the job.
+func (b *Builder) Environment(name, value string) *Builder {
+	env, _ := b.job["EnvironmentVariables"].(map[string]interface{})
+	if env == nil {
+		env = make(map[string]interface{})
+	}
+	env[name] = value
+	return b.set("EnvironmentVariables", env)
+}
+
+// WorkingDirectory sets the directory the job runs in.
+func (b *Builder) WorkingDirectory(path string) *Builder {
+	return b.set("WorkingDirectory", path)
+}
+
+// UserName sets the user a daemon runs as.
+func (b *Builder) UserName(name string) *Builder {
+	return b.set("UserName", name)
+}
+
+// GroupName sets the group a daemon runs as.
+func (b *Builder) GroupName(name string) *Builder {
+	return b.set("GroupName", name)
+}
+
+// StandardOutPath sets the file the job's standard output is appended to.
+func (b *Builder) StandardOutPath(path string) *Builder {
+	return b.set("StandardOutPath", path)
+}
+
+// StandardErrorPath sets the file the job's standard error is appended to.
+func (b *Builder) StandardErrorPath(path string) *Builder {
+	return b.set("StandardErrorPath", path)
+}
+
+// RunAtLoad makes the job start as soon as it is loaded.
+func (b *Builder) RunAtLoad() *Builder {
+	return b.set("RunAtLoad", true)
+}
+
+// ProcessType sets the resource policy of the job: "Background", "Standard",
+// "Adaptive" or "Interactive".
+func (b *Builder) ProcessType(typ string) *Builder {
+	return b.set("ProcessType", typ)
+}
+
+// StartInterval makes the job run every d, which is rounded down to whole
+// seconds.
+func (b *Builder) StartInterval(d time.Duration) *Builder {
+	return b.set("StartInterval", int64(d/time.Second))
+}
+
+// A CalendarInterval is an entry of StartCalendarInterval. The keys are
+// "Minute", "Hour", "Day", "Weekday" and "Month"; missing keys match any
+// value, like a "*" in a crontab.
+type CalendarInterval map[string]int
+
+// Hourly returns a CalendarInterval that matches the given minute of every
+// hour.
+func Hourly(minute int) CalendarInterval {
+	return CalendarInterval{"Minute": minute}
+}
+
+// Daily returns a CalendarInterval that matches the given time of every day.
+func Daily(hour, minute int) CalendarInterval {
+	return CalendarInterval{"Hour": hour, "Minute": minute}
+}
+
+// Weekly returns a CalendarInterval that matches the given time on the given
+// day of every week.
+func Weekly(day time.Weekday, hour, minute int) CalendarInterval {
+	return CalendarInterval{"Weekday": int(day), "Hour": hour, "Minute": minute}
+}
+
+// Monthly returns a CalendarInterval that matches the given time on the given
+// day of every month.
+func Monthly(day, hour, minute int) CalendarInterval {
+	return CalendarInterval{"Day": day, "Hour": hour, "Minute": minute}
+}
+
+// StartCalendarInterval adds times at which the job is run.
+func (b *Builder) StartCalendarInterval(intervals ...CalendarInterval) *Builder {
+	ary, _ := b.job["StartCalendarInterval"].([]interface{})
+	for _, interval := range intervals {
+		dict := make(map[string]interface{}, len(interval))
+		for k, v := range interval {
+			dict[k] = int64(v)
+		}
+		ary = append(ary, dict)
+	}
+	return b.set("StartCalendarInterval", ary)
+}
+
+// WatchPaths adds paths that start the job when they are modified.
+func (b *Builder) WatchPaths(paths ...string) *Builder {
+	ary, _ := b.job["WatchPaths"].([]interface{})
+	for _, path := range paths {
+		ary = append(ary, path)
+	}
+	return b.set("WatchPaths", ary)
+}
+
+// KeepAlive makes launchd restart the job whenever it exits.
+func (b *Builder) KeepAlive() *Builder {
+	return b.set("KeepAlive", true)
+}
+
+// keepAlive returns the KeepAlive conditions dictionary, replacing an
+// unconditional KeepAlive.
+func (b *Builder) keepAlive() map[string]interface{} {
+	dict, _ := b.job["KeepAlive"].(map[string]interface{})
+	if dict == nil {
+		dict = make(map[string]interface{})
+	}
+	return dict
+}
+
+// KeepAliveOnFailure makes launchd restart the job when it exits with a
+// non-zero status. This replaces an unconditional KeepAlive.
+func (b *Builder) KeepAliveOnFailure() *Builder {
+	dict := b.keepAlive()
+	dict["SuccessfulExit"] = false
+	return b.set("KeepAlive", dict)
+}
+
+// KeepAliveOnCrash makes launchd restart the job when it exits due to a
+// signal. This replaces an unconditional KeepAlive.
+func (b *Builder) KeepAliveOnCrash() *Builder {
+	dict := b.keepAlive()
+	dict["Crashed"] = true
+	return b.set("KeepAlive", dict)
+}
+
+// KeepAliveWhilePath keeps the job running while path exists, or while it
+// doesn't exist if exists is false. This replaces an unconditional KeepAlive.
+func (b *Builder) KeepAliveWhilePath(path string, exists bool) *Builder {
+	return b.keepAliveCondition("PathState", path, exists)
+}
+
+// KeepAliveWhileJob keeps the job running while the job with the given label
+// is loaded, or while it isn't loaded if enabled is false. This replaces an
+// unconditional KeepAlive.
+func (b *Builder) KeepAliveWhileJob(label string, enabled bool) *Builder {
+	return b.keepAliveCondition("OtherJobEnabled", label, enabled)
+}
+
+func (b *Builder) keepAliveCondition(key, name string, value bool) *Builder {
+	dict := b.keepAlive()
+	cond, _ := dict[key].(map[string]interface{})
+	if cond == nil {
+		cond = make(map[string]interface{})
+	}
+	cond[name] = value
+	dict[key] = cond
+	return b.set("KeepAlive", dict)
+}
+
+// A Socket describes a socket that launchd listens on for the job, starting
+// it on demand when a connection arrives. Empty fields use launchd's
+// defaults.
+type Socket struct {
+	Type        string // "stream" (the default), "dgram" or "seqpacket"
+	NodeName    string // the address to listen on
+	ServiceName string // the port number or service name
+	Family      string // "IPv4", "IPv6", "IPv4v6" or "Unix"
+	Protocol    string // "TCP" or "UDP"
+	PathName    string // the path of a Unix domain socket
+	PathMode    int    // the permissions of a Unix domain socket
+	Bonjour     bool   // advertise the socket with Bonjour
+}
+
+func (s Socket) dict() map[string]interface{} {
+	dict := make(map[string]interface{})
+	for key, value := range map[string]string{
+		"SockType":        s.Type,
+		"SockNodeName":    s.NodeName,
+		"SockServiceName": s.ServiceName,
+		"SockFamily":      s.Family,
+		"SockProtocol":    s.Protocol,
+		"SockPathName":    s.PathName,
+	} {
+		if value != "" {
+			dict[key] = value
+		}
+	}
+	if s.PathMode != 0 {
+		dict["SockPathMode"] = int64(s.PathMode)
+	}
+	if s.Bonjour {
+		dict["Bonjour"] = true
+	}
+	return dict
+}
+
+// Socket adds a socket to the socket group with the given name. The job
+// checks the sockets in by that name. Adding several sockets to the same
+// group listens on all of them.
+func (b *Builder) Socket(name string, s Socket) *Builder {
+	sockets, _ := b.job["Sockets"].(map[string]interface{})
+	if sockets == nil {
+		sockets = make(map[string]interface{})
+	}
+	switch group := sockets[name].(type) {
+	case nil:
+		sockets[name] = s.dict()
+	case []interface{}:
+		sockets[name] = append(group, s.dict())
+	default:
+		sockets[name] = []interface{}{group, s.dict()}
+	}
+	return b.set("Sockets", sockets)
+}
+
+// MachService registers a Mach service name for the job.
+func (b *Builder) MachService(name string) *Builder {
+	services, _ := b.job["MachServices"].(map[string]interface{})
+	if services == nil {
+		services = make(map[string]interface{})
+	}
+	services[name] = true
+	return b.set("MachServices", services)
+}
+
+// Job returns the job definition as it currently stands. The result is shared
+// with the Builder.
+func (b *Builder) Job() map[string]interface{} {
+	return b.job
+}
+
+// Marshal returns the job definition serialized in the given format. It
+// returns a *ValidationError if the job has errors.
+func (b *Builder) Marshal(format plist.Format) ([]byte, error) {
+	findings := b.Findings()
+	if HasErrors(findings) {
+		return nil, &ValidationError{findings}
+	}
+	return plist.Marshal(b.job, format)
+}
+
+// WriteFile writes the job definition to path as an XML property list,
+// replacing the file atomically. It returns a *ValidationError if the job has
+// errors.
+func (b *Builder) WriteFile(path string) error {
+	data, err := b.Marshal(plist.XMLFormat)
+	if err != nil {
+		return err
+	}
+	return plist.WriteFile(path, data, true)
+}
@@ -0,0 +1,84 @@
+package launchd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestBuilder(t *testing.T) {
+	b := NewJob("com.example.job")
+	if !HasErrors(b.Findings()) {
+		t.Error("job without a program has no errors")
+	}
+
+	b.Arguments("/usr/local/bin/job", "--flag").
+		Environment("PATH", "/usr/bin:/bin").
+		StartInterval(90*time.Second+500*time.Millisecond).
+		StartCalendarInterval(Daily(3, 30), Weekly(time.Sunday, 12, 0)).
+		KeepAliveOnFailure().
+		KeepAliveWhilePath("/tmp/enabled", true).
+		Socket("Listeners", Socket{ServiceName: "8080"}).
+		Socket("Listeners", Socket{ServiceName: "8443"})
+	if findings := b.Findings(); len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+
+	want := map[string]interface{}{
+		"Label":                "com.example.job",
+		"ProgramArguments":     []interface{}{"/usr/local/bin/job", "--flag"},
+		"EnvironmentVariables": map[string]interface{}{"PATH": "/usr/bin:/bin"},
+		"StartInterval":        int64(90),
+		"StartCalendarInterval": []interface{}{
+			map[string]interface{}{"Hour": int64(3), "Minute": int64(30)},
+			map[string]interface{}{"Weekday": int64(0), "Hour": int64(12), "Minute": int64(0)},
+		},
+		"KeepAlive": map[string]interface{}{
+			"SuccessfulExit": false,
+			"PathState":      map[string]interface{}{"/tmp/enabled": true},
+		},
+		"Sockets": map[string]interface{}{
+			"Listeners": []interface{}{
+				map[string]interface{}{"SockServiceName": "8080"},
+				map[string]interface{}{"SockServiceName": "8443"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(b.Job(), want) {
+		t.Errorf("got %#v, want %#v", b.Job(), want)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	b := NewJob("com.example.job").
+		Program("/usr/bin/true").
+		StartCalendarInterval(Daily(24, 0)).
+		Set("Nice", 40)
+	want := []Finding{
+		{"Nice", Error, "must be between -20 and 20"},
+		{"StartCalendarInterval:0:Hour", Error, "must be between 0 and 23"},
+	}
+	if got := b.Findings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	_, err := b.Marshal(plist.XMLFormat)
+	if e, ok := err.(*ValidationError); !ok || !reflect.DeepEqual(e.Findings, want) {
+		t.Errorf("Marshal: got error %v", err)
+	}
+}
+
+func TestBuilderSetError(t *testing.T) {
+	b := NewJob("com.example.job").
+		Program("/usr/bin/true").
+		Set("Custom", make(chan int)).
+		Set("Custom", func() {})
+	if got := b.Findings(); len(got) != 1 || got[0].Key != "Custom" || got[0].Severity != Error {
+		t.Errorf("after failed Sets: got %v, want one error for Custom", got)
+	}
+	b.Set("Custom", "value")
+	if got := b.Findings(); len(got) != 0 {
+		t.Errorf("after replacing the value: got %v", got)
+	}
+}
@@ -0,0 +1,515 @@
+// Package launchd validates and builds launchd job definitions, the property
+// lists installed in LaunchAgents and LaunchDaemons directories.
+package launchd
+
+import (
+	"errors"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A Severity classifies a Finding.
+type Severity int
+
+const (
+	// Warning findings describe keys that launchd accepts but that are
+	// probably mistakes, such as unknown or deprecated keys.
+	Warning Severity = iota
+	// Error findings describe problems that make launchd reject the job or
+	// ignore the key.
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "Severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// A Finding is a problem found in a job definition.
+type Finding struct {
+	// Key is the path of the offending key, with the components separated by
+	// colons as in PlistBuddy, e.g. "StartCalendarInterval:0:Hour". It is
+	// empty for problems with the job as a whole.
+	Key      string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	if f.Key == "" {
+		return f.Severity.String() + ": " + f.Message
+	}
+	return f.Key + ": " + f.Severity.String() + ": " + f.Message
+}
+
+// HasErrors reports whether any of the findings has severity Error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the job definition v, which is marshaled with plist.Marshal
+// first, against the keys documented in launchd.plist(5). The findings are
+// sorted by key. An error is only returned if v can't be marshaled or doesn't
+// marshal as a dictionary.
+func Validate(v interface{}) ([]Finding, error) {
+	data, err := plist.Marshal(v, plist.BinaryFormat)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateData(data)
+}
+
+// ValidateData is like Validate for a serialized property list.
+func ValidateData(data []byte) ([]Finding, error) {
+	var job interface{}
+	if _, err := plist.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	dict, ok := job.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("launchd: job definition is not a dictionary")
+	}
+	return validateJob(dict), nil
+}
+
+// ValidateFile is like Validate for the property list file at path.
+func ValidateFile(path string) ([]Finding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateData(data)
+}
+
+type validator struct {
+	findings []Finding
+}
+
+func (c *validator) report(key string, sev Severity, msg string) {
+	c.findings = append(c.findings, Finding{key, sev, msg})
+}
+
+// A checker validates the value of a single key.
+type checker func(c *validator, key string, v interface{})
+
+func join(key, sub string) string {
+	if key == "" {
+		return sub
+	}
+	return key + ":" + sub
+}
+
+// typeName returns the property list type name of v, as used by PlistBuddy.
+func typeName(v interface{}) string {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "real"
+	case reflect.Map:
+		return "dict"
+	case reflect.Slice:
+		if _, ok := v.([]byte); ok {
+			return "data"
+		}
+		return "array"
+	}
+	if _, ok := v.(time.Time); ok {
+		return "date"
+	}
+	return "unknown"
+}
+
+// article returns the type name with an indefinite article.
+func article(name string) string {
+	switch name[0] {
+	case 'a', 'i', 'u':
+		return "an " + name
+	}
+	return "a " + name
+}
+
+func isType(name string) checker {
+	return func(c *validator, key string, v interface{}) {
+		if t := typeName(v); t != name {
+			c.report(key, Error, "must be "+article(name)+", not "+article(t))
+		}
+	}
+}
+
+var (
+	isString  = isType("string")
+	isBool    = isType("bool")
+	isInteger = isType("integer")
+)
+
+func asInt(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func intRange(min, max int64) checker {
+	return func(c *validator, key string, v interface{}) {
+		n, ok := asInt(v)
+		if !ok {
+			isInteger(c, key, v)
+		} else if n < min || n > max {
+			c.report(key, Error, "must be between "+strconv.FormatInt(min, 10)+" and "+strconv.FormatInt(max, 10))
+		}
+	}
+}
+
+func positive(c *validator, key string, v interface{}) {
+	if n, ok := asInt(v); !ok {
+		isInteger(c, key, v)
+	} else if n <= 0 {
+		c.report(key, Error, "must be positive")
+	}
+}
+
+func oneOf(values ...string) checker {
+	return func(c *validator, key string, v interface{}) {
+		s, ok := v.(string)
+		if !ok {
+			isString(c, key, v)
+			return
+		}
+		for _, value := range values {
+			if s == value {
+				return
+			}
+		}
+		msg := "must be one of"
+		for i, value := range values {
+			if i > 0 {
+				msg += ","
+			}
+			msg += " " + strconv.Quote(value)
+		}
+		c.report(key, Error, msg)
+	}
+}
+
+func absolutePath(c *validator, key string, v interface{}) {
+	if s, ok := v.(string); !ok {
+		isString(c, key, v)
+	} else if !path.IsAbs(s) {
+		c.report(key, Warning, "should be an absolute path")
+	}
+}
+
+func arrayOf(elem checker) checker {
+	return func(c *validator, key string, v interface{}) {
+		ary, ok := v.([]interface{})
+		if !ok {
+			c.report(key, Error, "must be an array, not "+article(typeName(v)))
+			return
+		}
+		for i, e := range ary {
+			elem(c, join(key, strconv.Itoa(i)), e)
+		}
+	}
+}
+
+// orArrayOf accepts either a single value or an array of values.
+func orArrayOf(elem checker) checker {
+	return func(c *validator, key string, v interface{}) {
+		if _, ok := v.([]interface{}); ok {
+			arrayOf(elem)(c, key, v)
+		} else {
+			elem(c, key, v)
+		}
+	}
+}
+
+// dictOf checks every value of a dictionary with arbitrary keys.
+func dictOf(elem checker) checker {
+	return func(c *validator, key string, v interface{}) {
+		dict, ok := v.(map[string]interface{})
+		if !ok {
+			c.report(key, Error, "must be a dict, not "+article(typeName(v)))
+			return
+		}
+		for _, k := range sortedKeys(dict) {
+			elem(c, join(key, k), dict[k])
+		}
+	}
+}
+
+// dictWith checks a dictionary with a known set of keys. Unknown keys are
+// reported as warnings.
+func dictWith(keys map[string]checker) checker {
+	return func(c *validator, key string, v interface{}) {
+		dict, ok := v.(map[string]interface{})
+		if !ok {
+			c.report(key, Error, "must be a dict, not "+article(typeName(v)))
+			return
+		}
+		c.checkKeys(key, dict, keys)
+	}
+}
+
+func (c *validator) checkKeys(key string, dict map[string]interface{}, keys map[string]checker) {
+	for _, k := range sortedKeys(dict) {
+		if check, ok := keys[k]; ok {
+			check(c, join(key, k), dict[k])
+		} else {
+			c.report(join(key, k), Warning, "unknown key")
+		}
+	}
+}
+
+func deprecated(replacement string, check checker) checker {
+	return func(c *validator, key string, v interface{}) {
+		msg := "is deprecated"
+		if replacement != "" {
+			msg += "; use " + replacement + " instead"
+		}
+		c.report(key, Warning, msg)
+		check(c, key, v)
+	}
+}
+
+func sortedKeys(dict map[string]interface{}) []string {
+	keys := make([]string, 0, len(dict))
+	for k := range dict {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var calendarIntervalKeys = map[string]checker{
+	"Minute":  intRange(0, 59),
+	"Hour":    intRange(0, 23),
+	"Day":     intRange(1, 31),
+	"Weekday": intRange(0, 7),
+	"Month":   intRange(1, 12),
+}
+
+func calendarInterval(c *validator, key string, v interface{}) {
+	dictWith(calendarIntervalKeys)(c, key, v)
+	if dict, ok := v.(map[string]interface{}); ok && len(dict) == 0 {
+		c.report(key, Warning, "empty calendar interval runs the job every minute")
+	}
+}
+
+var keepAliveKeys = map[string]checker{
+	"SuccessfulExit":     isBool,
+	"Crashed":            isBool,
+	"NetworkState":       deprecated("", isBool),
+	"PathState":          dictOf(isBool),
+	"OtherJobEnabled":    dictOf(isBool),
+	"AfterInitialDemand": dictOf(isBool),
+}
+
+func keepAlive(c *validator, key string, v interface{}) {
+	if _, ok := v.(bool); ok {
+		return
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		c.report(key, Error, "must be a bool or a dict, not "+article(typeName(v)))
+		return
+	}
+	dictWith(keepAliveKeys)(c, key, v)
+}
+
+var machServiceKeys = map[string]checker{
+	"ResetAtClose":     isBool,
+	"HideUntilCheckIn": isBool,
+}
+
+func machService(c *validator, key string, v interface{}) {
+	if _, ok := v.(bool); ok {
+		return
+	}
+	if _, ok := v.(map[string]interface{}); !ok {
+		c.report(key, Error, "must be a bool or a dict, not "+article(typeName(v)))
+		return
+	}
+	dictWith(machServiceKeys)(c, key, v)
+}
+
+func stringOrInteger(c *validator, key string, v interface{}) {
+	if t := typeName(v); t != "string" && t != "integer" {
+		c.report(key, Error, "must be a string or an integer, not "+article(t))
+	}
+}
+
+var socketKeys = map[string]checker{
+	"SockType":            oneOf("stream", "dgram", "seqpacket"),
+	"SockPassive":         isBool,
+	"SockNodeName":        isString,
+	"SockServiceName":     stringOrInteger,
+	"SockFamily":          oneOf("IPv4", "IPv6", "IPv4v6", "Unix"),
+	"SockProtocol":        oneOf("TCP", "UDP"),
+	"SockPathName":        absolutePath,
+	"SecureSocketWithKey": isString,
+	"SockPathOwner":       isInteger,
+	"SockPathGroup":       isInteger,
+	"SockPathMode":        intRange(0, 07777),
+	"Bonjour":             bonjour,
+	"MulticastGroup":      isString,
+}
+
+// bonjour accepts a bool, a service name, or an array of service names.
+func bonjour(c *validator, key string, v interface{}) {
+	if _, ok := v.(bool); !ok {
+		orArrayOf(isString)(c, key, v)
+	}
+}
+
+func socket(c *validator, key string, v interface{}) {
+	dictWith(socketKeys)(c, key, v)
+	dict, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if _, ok := dict["SockPathName"]; ok {
+		for _, k := range []string{"SockNodeName", "SockServiceName"} {
+			if _, ok := dict[k]; ok {
+				c.report(join(key, k), Error, "cannot be combined with SockPathName")
+			}
+		}
+	}
+	if _, ok := dict["SecureSocketWithKey"]; ok {
+		if _, ok := dict["SockPathName"]; ok {
+			c.report(join(key, "SecureSocketWithKey"), Error, "cannot be combined with SockPathName")
+		}
+	}
+}
+
+var resourceLimitKeys = map[string]checker{
+	"Core":              isInteger,
+	"CPU":               isInteger,
+	"Data":              isInteger,
+	"FileSize":          isInteger,
+	"MemoryLock":        isInteger,
+	"NumberOfFiles":     isInteger,
+	"NumberOfProcesses": isInteger,
+	"ResidentSetSize":   isInteger,
+	"Stack":             isInteger,
+}
+
+var jobKeys = map[string]checker{
+	"Label":                       isString,
+	"Disabled":                    isBool,
+	"UserName":                    isString,
+	"GroupName":                   isString,
+	"inetdCompatibility":          dictWith(map[string]checker{"Wait": isBool}),
+	"LimitLoadToHosts":            arrayOf(isString),
+	"LimitLoadFromHosts":          arrayOf(isString),
+	"LimitLoadToSessionType":      orArrayOf(oneOf("Aqua", "Background", "LoginWindow", "StandardIO", "System")),
+	"LimitLoadToHardware":         dictOf(arrayOf(isString)),
+	"LimitLoadFromHardware":       dictOf(arrayOf(isString)),
+	"Program":                     absolutePath,
+	"ProgramArguments":            arrayOf(isString),
+	"EnableGlobbing":              isBool,
+	"EnableTransactions":          isBool,
+	"EnablePressuredExit":         isBool,
+	"OnDemand":                    deprecated("KeepAlive", isBool),
+	"ServiceIPC":                  deprecated("", isBool),
+	"KeepAlive":                   keepAlive,
+	"RunAtLoad":                   isBool,
+	"RootDirectory":               absolutePath,
+	"WorkingDirectory":            absolutePath,
+	"EnvironmentVariables":        dictOf(isString),
+	"Umask":                       intRange(0, 0777),
+	"TimeOut":                     positive,
+	"ExitTimeOut":                 isInteger,
+	"ThrottleInterval":            isInteger,
+	"InitGroups":                  isBool,
+	"WatchPaths":                  arrayOf(absolutePath),
+	"QueueDirectories":            arrayOf(absolutePath),
+	"StartOnMount":                isBool,
+	"StartInterval":               positive,
+	"StartCalendarInterval":       orArrayOf(calendarInterval),
+	"StandardInPath":              absolutePath,
+	"StandardOutPath":             absolutePath,
+	"StandardErrorPath":           absolutePath,
+	"Debug":                       isBool,
+	"WaitForDebugger":             isBool,
+	"SoftResourceLimits":          dictWith(resourceLimitKeys),
+	"HardResourceLimits":          dictWith(resourceLimitKeys),
+	"Nice":                        intRange(-20, 20),
+	"ProcessType":                 oneOf("Background", "Standard", "Adaptive", "Interactive"),
+	"AbandonProcessGroup":         isBool,
+	"LowPriorityIO":               isBool,
+	"LowPriorityBackgroundIO":     isBool,
+	"MaterializeDatalessFiles":    isBool,
+	"LaunchOnlyOnce":              isBool,
+	"MachServices":                dictOf(machService),
+	"Sockets":                     dictOf(orArrayOf(socket)),
+	"LaunchEvents":                dictOf(dictOf(isType("dict"))),
+	"SessionCreate":               isBool,
+	"LegacyTimers":                isBool,
+	"AssociatedBundleIdentifiers": orArrayOf(isString),
+	"HopefullyExitsFirst":         deprecated("", isBool),
+	"HopefullyExitsLast":          deprecated("", isBool),
+}
+
+func validateJob(job map[string]interface{}) []Finding {
+	c := &validator{}
+	c.checkKeys("", job, jobKeys)
+
+	if label, ok := job["Label"]; !ok {
+		c.report("Label", Error, "is required")
+	} else if s, ok := label.(string); ok && s == "" {
+		c.report("Label", Error, "must not be empty")
+	}
+	_, hasProgram := job["Program"]
+	args, hasArgs := job["ProgramArguments"]
+	if !hasProgram && !hasArgs {
+		c.report("", Error, "one of Program or ProgramArguments is required")
+	}
+	if ary, ok := args.([]interface{}); ok && len(ary) == 0 && !hasProgram {
+		c.report("ProgramArguments", Error, "must not be empty when Program is not set")
+	}
+	if _, ok := job["OnDemand"]; ok {
+		if _, ok := job["KeepAlive"]; ok {
+			c.report("OnDemand", Error, "cannot be combined with KeepAlive")
+		}
+	}
+	if _, ok := job["inetdCompatibility"]; ok {
+		if _, ok := job["Sockets"]; !ok {
+			c.report("inetdCompatibility", Warning, "has no effect without Sockets")
+		}
+	}
+	if once, _ := job["LaunchOnlyOnce"].(bool); once {
+		if _, ok := job["KeepAlive"]; ok {
+			c.report("LaunchOnlyOnce", Warning, "conflicts with KeepAlive")
+		}
+	}
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return c.findings[i].Key < c.findings[j].Key
+	})
+	return c.findings
+}
@@ -0,0 +1,76 @@
+package launchd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateJob(t *testing.T) {
+	job := map[string]interface{}{
+		"Label":            "com.example.job",
+		"ProgramArguments": []interface{}{"/usr/bin/true"},
+		"StartCalendarInterval": []interface{}{
+			map[string]interface{}{"Hour": int64(3), "Minute": int64(30)},
+			map[string]interface{}{"Hour": int64(25)},
+			map[string]interface{}{"Hour": "3"},
+			map[string]interface{}{},
+		},
+		"KeepAlive":       map[string]interface{}{"SuccessfulExit": false, "Bogus": true},
+		"OnDemand":        true,
+		"StandardOutPath": "log.txt",
+		"Sockets": map[string]interface{}{
+			"Listeners": map[string]interface{}{
+				"SockPathName":    "/var/run/example.sock",
+				"SockServiceName": int64(8080),
+			},
+		},
+		"RunAtLoad": "yes",
+	}
+	want := []Finding{
+		{"KeepAlive:Bogus", Warning, "unknown key"},
+		{"OnDemand", Warning, "is deprecated; use KeepAlive instead"},
+		{"OnDemand", Error, "cannot be combined with KeepAlive"},
+		{"RunAtLoad", Error, "must be a bool, not a string"},
+		{"Sockets:Listeners:SockServiceName", Error, "cannot be combined with SockPathName"},
+		{"StandardOutPath", Warning, "should be an absolute path"},
+		{"StartCalendarInterval:1:Hour", Error, "must be between 0 and 23"},
+		{"StartCalendarInterval:2:Hour", Error, "must be an integer, not a string"},
+		{"StartCalendarInterval:3", Warning, "empty calendar interval runs the job every minute"},
+	}
+	got := validateJob(job)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings:")
+		for _, f := range got {
+			t.Errorf("\t%v", f)
+		}
+	}
+	if !HasErrors(got) {
+		t.Error("HasErrors returned false")
+	}
+}
+
+func TestValidateJobRequired(t *testing.T) {
+	got := validateJob(map[string]interface{}{})
+	want := []Finding{
+		{"", Error, "one of Program or ProgramArguments is required"},
+		{"Label", Error, "is required"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type job struct {
+		Label     string
+		Program   string
+		RunAtLoad bool
+	}
+	findings, err := Validate(job{Label: "com.example.job", Program: "/usr/bin/true", RunAtLoad: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+}
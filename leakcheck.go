@@ -0,0 +1,49 @@
+package plist
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A LeakReport describes the CoreFoundation references the package took
+// during a call to LeakCheck.
+type LeakReport struct {
+	Created  int // objects created, copied or retained
+	Released int // references released
+	// ExtraReleases counts releases of objects the package holds no
+	// reference to. These are over-releases, unless the reference was made
+	// outside the package and handed to it, as with NewCFObject.
+	ExtraReleases int
+	Leaks         []Leak // references not released, oldest first
+}
+
+// A Leak is a reference to a CoreFoundation object that was created or
+// retained during LeakCheck and not released by the time it returned.
+type Leak struct {
+	Type  string // the CoreFoundation type of the object, such as "CFString"
+	Stack string // where the reference was taken
+}
+
+func (r *LeakReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d created, %d released, %d extra releases, %d leaked", r.Created, r.Released, r.ExtraReleases, len(r.Leaks))
+	for _, leak := range r.Leaks {
+		fmt.Fprintf(&b, "\n\n%s created at:\n%s", leak.Type, leak.Stack)
+	}
+	return b.String()
+}
+
+// LeakCheck calls f and reports the CoreFoundation objects that the package
+// created, copied or retained during the call without releasing them, along
+// with where each reference was taken. It is meant for tracking down leaks in
+// error paths, in tests or while debugging, by checking an operation such as
+// a call to Marshal or Unmarshal at a time.
+//
+// The references are only counted in programs built with the plist_leakcheck
+// build tag; otherwise LeakCheck calls f and returns an error. References are
+// counted for the whole process, so anything else using the package while f
+// runs shows up in the report as well. Objects that are released later, for
+// instance by the finalizer of a CFObject, are reported as leaks.
+func LeakCheck(f func()) (*LeakReport, error) {
+	return leakCheck(f)
+}
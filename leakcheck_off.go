@@ -0,0 +1,15 @@
+//go:build !plist_leakcheck
+// +build !plist_leakcheck
+
+package plist
+
+import "errors"
+
+func cfTrack(cfObj cfTypeRef)       {}
+func cfUntrack(cfObj cfTypeRef)     {}
+func cfUntrackAll(refs []cfTypeRef) {}
+
+func leakCheck(f func()) (*LeakReport, error) {
+	f()
+	return nil, errors.New("plist: LeakCheck needs the plist_leakcheck build tag")
+}
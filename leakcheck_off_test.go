@@ -0,0 +1,14 @@
+//go:build !plist_leakcheck
+// +build !plist_leakcheck
+
+package plist
+
+import "testing"
+
+func TestLeakCheckDisabled(t *testing.T) {
+	called := false
+	r, err := LeakCheck(func() { called = true })
+	if !called || r != nil || err == nil {
+		t.Errorf("LeakCheck called f: %v, returned %v, %v", called, r, err)
+	}
+}
@@ -0,0 +1,128 @@
+//go:build plist_leakcheck
+// +build plist_leakcheck
+
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// tracked holds the references the package has taken and not yet released.
+var tracked = struct {
+	sync.Mutex
+	refs          map[cfTypeRef][]trackedRef
+	seq           uint64 // number of the last reference taken
+	released      int
+	extraReleases int
+}{refs: make(map[cfTypeRef][]trackedRef)}
+
+// A trackedRef is a reference to an object and the stack it was taken on.
+type trackedRef struct {
+	seq uint64
+	pcs []uintptr
+}
+
+func cfTrack(cfObj cfTypeRef) {
+	if cfObj == nil {
+		return
+	}
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	tracked.Lock()
+	defer tracked.Unlock()
+	tracked.seq++
+	tracked.refs[cfObj] = append(tracked.refs[cfObj], trackedRef{tracked.seq, pcs})
+}
+
+func cfUntrack(cfObj cfTypeRef) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	untrackLocked(cfObj)
+}
+
+func cfUntrackAll(refs []cfTypeRef) {
+	tracked.Lock()
+	defer tracked.Unlock()
+	for _, cfObj := range refs {
+		if cfObj != nil {
+			untrackLocked(cfObj)
+		}
+	}
+}
+
+// untrackLocked drops the latest reference to cfObj. The references to an
+// object are interchangeable, so which one is released can't be known.
+func untrackLocked(cfObj cfTypeRef) {
+	tracked.released++
+	refs := tracked.refs[cfObj]
+	switch len(refs) {
+	case 0:
+		tracked.extraReleases++
+	case 1:
+		delete(tracked.refs, cfObj)
+	default:
+		tracked.refs[cfObj] = refs[:len(refs)-1]
+	}
+}
+
+func leakCheck(f func()) (*LeakReport, error) {
+	tracked.Lock()
+	start, released, extraReleases := tracked.seq, tracked.released, tracked.extraReleases
+	tracked.Unlock()
+
+	f()
+
+	tracked.Lock()
+	defer tracked.Unlock()
+	r := &LeakReport{
+		Created:       int(tracked.seq - start),
+		Released:      tracked.released - released,
+		ExtraReleases: tracked.extraReleases - extraReleases,
+	}
+	type leak struct {
+		trackedRef
+		typ string
+	}
+	var leaks []leak
+	for cfObj, refs := range tracked.refs {
+		for _, ref := range refs {
+			if ref.seq > start {
+				leaks = append(leaks, leak{ref, cfTypeName(cfObj)})
+			}
+		}
+	}
+	sort.Slice(leaks, func(i, j int) bool { return leaks[i].seq < leaks[j].seq })
+	for _, l := range leaks {
+		r.Leaks = append(r.Leaks, Leak{Type: l.typ, Stack: formatStack(l.pcs)})
+	}
+	return r, nil
+}
+
+// cfTypeName returns the name of the type of cfObj. It doesn't go through
+// cfCreated and cfRelease, so it can be used with tracked locked.
+func cfTypeName(cfObj cfTypeRef) string {
+	cfStr := C.CFCopyTypeIDDescription(C.CFGetTypeID(C.CFTypeRef(cfObj)))
+	defer C.CFRelease(C.CFTypeRef(cfStr))
+	return convertCFStringToString(cfStr)
+}
+
+// formatStack formats pcs in the style of a goroutine's stack trace.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
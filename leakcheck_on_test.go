@@ -0,0 +1,75 @@
+//go:build plist_leakcheck
+// +build plist_leakcheck
+
+package plist
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeakCheck(t *testing.T) {
+	type item struct {
+		Name  string
+		When  time.Time
+		Data  []byte
+		Flags []bool
+		Extra map[string]interface{}
+	}
+	items := []item{{Name: "a", When: time.Unix(1e9, 0), Data: []byte{1}, Flags: []bool{true}, Extra: map[string]interface{}{"n": 1.5}}}
+	ops := map[string]func(){
+		"Marshal": func() {
+			for _, format := range []Format{XMLFormat, BinaryFormat} {
+				data, err := Marshal(items, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []item
+				if _, err := Unmarshal(data, &got); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+		"Marshal error": func() {
+			bad := map[string]interface{}{"a": "b", "c": []interface{}{1, math.NaN()}}
+			if _, err := Marshal(bad, XMLFormat); err == nil {
+				t.Error("no error for NaN")
+			}
+		},
+		"Unmarshal error": func() {
+			if _, err := Unmarshal([]byte("<plist><dict><key>a</key></plist>"), new(interface{})); err == nil {
+				t.Error("no error for invalid property list")
+			}
+		},
+		"ToCFType": func() {
+			ref, err := ToCFType(items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ReleaseCFType(ref)
+		},
+	}
+	for name, op := range ops {
+		r, err := LeakCheck(op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Leaks) != 0 || r.ExtraReleases != 0 || r.Created == 0 {
+			t.Errorf("%s: %v", name, r)
+		}
+	}
+
+	r, err := LeakCheck(func() {
+		if _, err := ToCFType([]string{"leaked"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Leaks) != 1 || r.Leaks[0].Type != "CFArray" || !strings.Contains(r.Leaks[0].Stack, "TestLeakCheck") {
+		t.Errorf("leaked ToCFType result: %v", r)
+	}
+}
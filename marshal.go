@@ -5,12 +5,15 @@ import "C"
 
 import (
 	"errors"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // Format represents the format of the property list
@@ -47,7 +50,9 @@ func (f Format) String() string {
 // the Marshaler interface and is not a nil pointer, Marshal calls its
 // MarshalPlist method to produce a property list object (as defined by
 // CFPropertyListCreateData()). If the method returns any other object, that is
-// considered an error.
+// considered an error. A value implementing the CFMarshaler interface instead
+// has its MarshalPlistCF method called, which returns a CoreFoundation object
+// that is used as-is.
 //
 // Otherwise, Marshal uses the following type-dependent default encodings:
 //
@@ -60,23 +65,45 @@ func (f Format) String() string {
 // by the encoding of the Unicode replacement character U+FFFD.
 //
 // Time values encode as CFDate, with millisecond precision. Far-future or
-// far-past dates may have less than millisecond precision.
+// far-past dates may have less than millisecond precision. Date values encode
+// as CFDates of exactly their value.
+//
+// url.URL values encode as CFStrings of the URL, and net.IP and netip.Addr
+// values as CFStrings of the address, with an empty net.IP or zero netip.Addr
+// encoding as the empty string.
 //
 // Array and slice values encode as CFArrays, except that []byte encodes as a
-// CFData.
+// CFData, and [16]byte, along with any type defined as one such as the UUID
+// types of the common UUID packages, encodes as a CFString of the UUID in its
+// canonical hyphenated form.
 //
 // Struct values encode as CFDictionaries. Each exported struct field becomes a
 // member of the object unless
 //
 //     - the field's tag is "-"
-//     - the field is empty and its tag specifies the "omitempty" option.
+//     - the field is empty and its tag specifies the "omitempty" option
+//     - the field is zero and its tag specifies the "omitzero" option.
 //
 // The empty values are false, 0, any nil pointer or interface value, and any
-// array, slice, map, or string of length zero. The object's default key string
+// array, slice, map, or string of length zero. The zero values are the empty
+// values and any struct whose fields are all zero, such as the zero
+// time.Time, so that a struct field with the "omitzero" option is left out
+// rather than appearing as a dictionary of empty values. A struct type with
+// its own MarshalPlist, MarshalPlistCF or MarshalText method is zero only if
+// all its fields, exported or not, are. The object's default key string
 // is the struct field name but can be specified in the struct field's tag
 // value. The "plist" key in the struct field's tag value is the key name,
-// followed by an optional comma and options. Examples:
+// followed by an optional comma and options. The key name may contain
+// letters, digits, spaces and punctuation other than backslash and quote, so
+// that keys such as "com.apple.security.app-sandbox" and "Device Name" can be
+// named; a tag with any other name is ignored and the field name is used.
+// Examples:
 //
+//     // Field appears in plist unless it is nil. Unlike with omitempty,
+//     // an empty but non-nil slice or map appears as an empty array or
+//     // dictionary, and an empty array or data decodes into the field as
+//     // an empty slice rather than nil, so nil means the key was absent.
+//     Field []string `plist:",omitnil"`
 //     // Field is ignored by this package.
 //     Field int `plist:"-"`
 //     // Field appears in plist as key "myName".
@@ -89,12 +116,45 @@ func (f Format) String() string {
 //     // the field is skipped if empty.
 //     // Note the leading comma.
 //     Field int `plist:",omitempty"`
+//     // Field is skipped if all of its fields are zero.
+//     Field struct{ A, B string } `plist:",omitzero"`
+//     // Field is a UUID that appears in plist as a CFData of its 16 bytes
+//     // rather than as a string.
+//     Field [16]byte `plist:",data"`
+//     // Field appears in plist as a CFData of the bytes of the string, and
+//     // a CFData is decoded back into it.
+//     Field string `plist:",data"`
+//     // Field appears in plist as the CFString returned by its String
+//     // method, and is decoded by the parser registered for its type with
+//     // RegisterStringParser or by its UnmarshalText method.
+//     Field Level `plist:",stringer"`
+//     // Field appears in plist as a CFNumber of whole seconds since the
+//     // Unix epoch. The "unixmilli" format is whole milliseconds since then,
+//     // and "cocoa" fractional seconds since the NSDate reference date.
+//     Field time.Time `plist:",format=unix"`
+//     // Field is a struct that appears in plist as a CFArray of its
+//     // fields in the order they're declared, and is decoded from one.
+//     Field Point `plist:",tuple"`
+//     // Field is a map[T]struct{}, or a map[T]bool of the keys that map to
+//     // true, that appears in plist as a CFArray of its keys in sorted
+//     // order, and is decoded from one.
+//     Field map[string]struct{} `plist:",set"`
 //
 // The key name will be used if it's a non-empty string consisting of only
 // Unicode letters, digits, dollar signs, percent signs, hyphens, underscores
 // and slashes.
 //
 // Map values encode as CFDictionaries. The map's key type must be string.
+// sync.Map values also encode as CFDictionaries, of the entries that Range
+// visits, whose keys must all be strings.
+//
+// iter.Seq values encode as CFArrays of the values they yield, and
+// iter.Seq2 values with string keys as CFDictionaries of the pairs they yield,
+// with their keys in the order they were yielded, as a Dict keeps them. This
+// also holds for any other function type of the same shape. An Encoder writes
+// the values as they're yielded, so they never need to be held in memory all
+// at once. An iterator that yields the same key twice causes Marshal to return
+// an UnsupportedValueError.
 //
 // Pointer values encode as the value pointed to. A nil pointer causes Marshal
 // to return an UnsupportedValueError.
@@ -110,19 +170,80 @@ func (f Format) String() string {
 // handle them. Passing cyclic structures to Marshal will result in an infinite
 // recursion.
 func Marshal(v interface{}, format Format) ([]byte, error) {
-	cfObj, err := marshalValue(reflect.ValueOf(v))
+	return MarshalAppend(nil, v, format)
+}
+
+// MarshalAppend appends the property list encoding of v to dst and returns the
+// extended buffer, as Marshal does otherwise. If dst has enough spare capacity
+// no new buffer is allocated, so callers that encode many values can reuse
+// buffers, for instance by keeping them in a sync.Pool and passing buf[:0].
+// If an error occurs, dst is returned unchanged.
+func MarshalAppend(dst []byte, v interface{}, format Format) ([]byte, error) {
+	return marshalAppend(dst, format, func(state *marshalState) (cfTypeRef, error) {
+		return state.marshalValue(reflect.ValueOf(v))
+	})
+}
+
+// marshalAppend implements MarshalAppend for a value converted by marshal.
+func marshalAppend(dst []byte, format Format, marshal func(*marshalState) (cfTypeRef, error)) (out []byte, err error) {
+	// if marshal panics, return dst along with the error, like other errors
+	out = dst
+	defer recoverPanic(&err)
+	state := &marshalState{pinner: new(dataPinner), pool: new(releasePool)}
+	// unpin only after everything has been released
+	defer state.pinner.unpin()
+	defer state.pool.drain()
+	cfObj, err := marshal(state)
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
 	defer cfRelease(cfObj)
-	return cfPropertyListCreateData(cfObj, format)
+	if format == XMLFormat && len(state.dictKeys) > 0 {
+		// CFPropertyListCreateData sorts dictionary keys
+		plist, err := state.convertOrdered(cfObj)
+		if err != nil {
+			return dst, err
+		}
+		return appendOrderedXML(dst, plist), nil
+	}
+	out, err = appendCFPropertyListData(dst, cfObj, format)
+	if err != nil {
+		return dst, err
+	}
+	return out, nil
+}
+
+// marshalState holds the state of a single call to Marshal.
+type marshalState struct {
+	contextChecker
+	// dictKeys records the key order of each CFDictionary created from a Dict
+	dictKeys map[cfTypeRef][]string
+	// pinner pins the large byte slices used by CFData without copying. It is
+	// nil when the objects may outlive the call, as in ToCFType.
+	pinner *dataPinner
+	// pool collects the temporary objects to release. If it is nil they are
+	// released as soon as their container has been created.
+	pool *releasePool
+}
+
+// release releases refs, the temporary objects of a container that has been
+// created, or adds them to the pool.
+func (state *marshalState) release(refs []cfTypeRef) {
+	if state.pool != nil {
+		state.pool.add(refs)
+	} else {
+		cfReleaseAll(refs)
+	}
 }
 
 var timeType = reflect.TypeOf(time.Time{})
 var byteSliceType = reflect.TypeOf([]byte(nil))
 var stringType = reflect.TypeOf("")
 
-func marshalValue(v reflect.Value) (cfTypeRef, error) {
+func (state *marshalState) marshalValue(v reflect.Value) (cfTypeRef, error) {
+	if err := state.checkContext(); err != nil {
+		return nil, err
+	}
 	if !v.IsValid() {
 		return nil, &UnsupportedValueError{v, "invalid value"}
 	}
@@ -133,6 +254,23 @@ func marshalValue(v reflect.Value) (cfTypeRef, error) {
 		return nil, &UnsupportedValueError{v, "nil interface"}
 	}
 
+	cm, ok := v.Interface().(CFMarshaler)
+	if !ok {
+		if v.Kind() != reflect.Ptr && v.CanAddr() {
+			cm, ok = v.Addr().Interface().(CFMarshaler)
+		}
+	}
+	if ok {
+		obj, err := cm.MarshalPlistCF()
+		if err != nil {
+			return nil, err
+		}
+		if obj.IsNil() {
+			return nil, &UnsupportedValueError{v, "nil CFObject from MarshalPlistCF"}
+		}
+		return cfTypeRef(obj.Retain()), nil
+	}
+
 	m, ok := v.Interface().(Marshaler)
 	if !ok {
 		if v.Kind() != reflect.Ptr && v.CanAddr() {
@@ -147,70 +285,178 @@ func marshalValue(v reflect.Value) (cfTypeRef, error) {
 		if err != nil {
 			return nil, err
 		}
-		return convertValueToCFType(reflect.ValueOf(obj))
+		return convertInterfaceToCFType(obj)
 	}
 
 	switch v.Kind() {
 	case reflect.Slice, reflect.Array:
 		if v.Type() == byteSliceType {
 			// this is a []byte
-			return cfTypeRef(convertBytesToCFData(v.Interface().([]byte))), nil
+			return cfTypeRef(state.convertBytesToCFData(v.Interface().([]byte))), nil
+		}
+		if isUUIDType(v.Type()) {
+			return marshalString(formatUUID(uuidOf(v)))
+		}
+		if v.Type() == ipType {
+			return marshalAddress(v)
 		}
-		cfAry, err := convertSliceToCFArrayHelper(v, marshalValue)
+		if cfAry := convertPrimitiveSliceToCFArray(v); cfAry != nil {
+			return cfTypeRef(cfAry), nil
+		}
+		cfAry, err := state.marshalArray(v)
 		return cfTypeRef(cfAry), err
 	case reflect.Map:
-		cfDict, err := convertMapToCFDictionaryHelper(v, marshalValue)
+		if cfDict, ok, err := state.marshalMap(v); ok {
+			return cfTypeRef(cfDict), err
+		}
+		cfDict, err := convertMapToCFDictionaryHelper(v, state.marshalValue)
 		return cfTypeRef(cfDict), err
 	case reflect.Struct:
 		if v.Type() == timeType {
 			// this is a time.Time
 			return cfTypeRef(convertTimeToCFDate(v.Interface().(time.Time))), nil
 		}
-		cfDict, err := marshalStruct(v)
+		if v.Type() == urlType {
+			u := v.Interface().(url.URL)
+			return marshalString(u.String())
+		}
+		if v.Type() == addrType {
+			return marshalAddress(v)
+		}
+		if v.Type() == dictType {
+			d := v.Interface().(Dict)
+			return state.marshalDict(&d)
+		}
+		if v.Type() == syncMapType {
+			return state.marshalSyncMap(syncMapOf(v))
+		}
+		cfDict, err := state.marshalStruct(v)
 		return cfTypeRef(cfDict), err
 	case reflect.Ptr, reflect.Interface:
-		return marshalValue(v.Elem())
+		return state.marshalValue(v.Elem())
+	case reflect.Func:
+		if arity := seqArity(v.Type()); arity != 0 {
+			return state.marshalSeq(v, arity)
+		}
 	}
 	// everything else can be covered by the dumb conversion routine
 	return convertValueToCFType(v)
 }
 
-func marshalStruct(v reflect.Value) (C.CFDictionaryRef, error) {
+func (state *marshalState) marshalStruct(v reflect.Value) (C.CFDictionaryRef, error) {
 	// assume v is a struct
 	// we could translate the struct to a map[string]interface{}, but that would
 	// be wasteful. Just replicate the relevant logic here
 	fields := encodeFields(v.Type())
+	// the keys are the cached field names, which are never released
 	keys := make([]cfTypeRef, 0, len(fields))
 	values := make([]cfTypeRef, 0, len(fields))
-	defer func() {
-		for _, cfKey := range keys {
-			if cfKey != nil {
-				cfRelease(cfTypeRef(cfKey))
-			}
-		}
-		for _, cfVal := range values {
-			if cfVal != nil {
-				cfRelease(cfTypeRef(cfVal))
-			}
-		}
-	}()
+	defer func() { state.release(values) }()
 	for _, ef := range fields {
 		fieldValue := v.Field(ef.i)
-		if ef.omitEmpty && isEmptyValue(fieldValue) {
+		if ef.omitted(fieldValue) {
 			continue
 		}
-		cfStr := convertStringToCFString(ef.name)
+		if ef.cfName == nil {
+			return nil, errors.New("plist: could not convert string to CFStringRef")
+		}
+		keys = append(keys, cfTypeRef(ef.cfName))
+		cfObj, err := state.marshalField(&ef, fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cfObj)
+	}
+	return createCFDictionary(keys, values), nil
+}
+
+// marshalField converts v, the value of the field ef, as the options of the
+// field say.
+func (state *marshalState) marshalField(ef *encodeField, v reflect.Value) (cfTypeRef, error) {
+	if obj, ok := ef.encodeAs(v); ok {
+		return state.marshalInterface(obj)
+	}
+	if s, ok := ef.tupleStruct(v); ok {
+		return state.marshalTuple(s)
+	}
+	if s, ok := ef.setSlice(v); ok {
+		return state.marshalValue(s)
+	}
+	return state.marshalValue(v)
+}
+
+// marshalDict converts d to a CFDictionary, recording its key order.
+func (state *marshalState) marshalDict(d *Dict) (cfTypeRef, error) {
+	if err := checkKeys(d.keys); err != nil {
+		return nil, err
+	}
+	keys := make([]cfTypeRef, 0, len(d.keys))
+	values := make([]cfTypeRef, 0, len(d.keys))
+	defer func() {
+		state.release(keys)
+		state.release(values)
+	}()
+	for _, key := range d.keys {
+		cfStr := convertStringToCFString(key)
 		if cfStr == nil {
 			return nil, errors.New("plist: could not convert string to CFStringRef")
 		}
 		keys = append(keys, cfTypeRef(cfStr))
-		cfObj, err := marshalValue(fieldValue)
+		cfObj, err := state.marshalValue(reflect.ValueOf(d.values[key]))
 		if err != nil {
 			return nil, err
 		}
 		values = append(values, cfObj)
 	}
-	return createCFDictionary(keys, values), nil
+	cfDict := cfTypeRef(createCFDictionary(keys, values))
+	if state.dictKeys == nil {
+		state.dictKeys = make(map[cfTypeRef][]string)
+	}
+	state.dictKeys[cfDict] = d.keys
+	return cfDict, nil
+}
+
+// convertOrdered converts cfObj to basic property list values, converting
+// dictionaries that were created from a Dict back to a *Dict so their key
+// order is kept.
+func (state *marshalState) convertOrdered(cfObj cfTypeRef) (interface{}, error) {
+	switch C.CFGetTypeID(C.CFTypeRef(cfObj)) {
+	case cfArrayTypeID:
+		var result []interface{}
+		err := convertCFArrayToSliceHelper(C.CFArrayRef(cfObj), func(elem cfTypeRef, idx, count int) (bool, error) {
+			if result == nil {
+				result = make([]interface{}, count)
+			}
+			val, err := state.convertOrdered(elem)
+			if err != nil {
+				return false, err
+			}
+			result[idx] = val
+			return true, nil
+		})
+		if result == nil {
+			result = []interface{}{}
+		}
+		return result, err
+	case cfDictionaryTypeID:
+		m := make(map[string]interface{}, int(C.CFDictionaryGetCount(C.CFDictionaryRef(cfObj))))
+		err := convertCFDictionaryToMapHelper(C.CFDictionaryRef(cfObj), false, func(key string, value cfTypeRef, count int) error {
+			val, err := state.convertOrdered(value)
+			if err != nil {
+				return err
+			}
+			m[key] = val
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if keys, ok := state.dictKeys[cfObj]; ok {
+			return &Dict{keys: keys, values: m}, nil
+		}
+		return m, nil
+	}
+	return convertCFTypeToInterface(cfObj)
 }
 
 // isEmptyValue determines if the value should be skipped for omitempty fields.
@@ -233,14 +479,59 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
+// isZeroValue determines if the value should be skipped for omitzero fields:
+// an empty value, a zero time.Time, url.URL or netip.Addr, a Dict or sync.Map
+// with no entries, or any other struct whose encoded fields are all zero.
+// Structs that encode themselves are zero only if all their fields are, since
+// their unexported fields may hold what they encode.
+func isZeroValue(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return isEmptyValue(v)
+	}
+	switch v.Type() {
+	case timeType, urlType, addrType:
+		return v.IsZero()
+	case dictType:
+		d := v.Interface().(Dict)
+		return d.Len() == 0
+	case syncMapType:
+		empty := true
+		syncMapOf(v).Range(func(k, v interface{}) bool {
+			empty = false
+			return false
+		})
+		return empty
+	}
+	if hasMarshaler(v.Type(), true) || v.Type().Implements(textMarshalerType) || reflect.PointerTo(v.Type()).Implements(textMarshalerType) {
+		return v.IsZero()
+	}
+	for _, ef := range encodeFields(v.Type()) {
+		if !isZeroValue(v.Field(ef.i)) {
+			return false
+		}
+	}
+	return true
+}
+
 // Take a cue from encoding/json and pre-parse the rules for encoding struct
 // fields.
 
 // encodeField contains information about how to encode a field of a struct.
 type encodeField struct {
-	i         int // field index in struct
-	name      string
-	omitEmpty bool
+	i          int // field index in struct
+	name       string
+	omitEmpty  bool
+	omitZero   bool
+	omitNil    bool
+	asData     bool   // from the "data" option
+	stringer   bool   // from the "stringer" option
+	timeFormat string // from the "format" option
+	tuple      bool   // from the "tuple" option
+	set        bool   // from the "set" option
+	// cfName is name as a CFString. Like the rest of the cache it lives for
+	// the life of the process, so it is shared by every dictionary created
+	// for the type and never released.
+	cfName C.CFStringRef
 }
 
 var (
@@ -248,6 +539,26 @@ var (
 	encodeFieldsCache = make(map[reflect.Type][]encodeField)
 )
 
+// encodeAs returns what v, the value of the field, is encoded as in place of
+// itself because of the options of the field, if one applies to it: a []byte
+// for "data", a string for "stringer", or an int64 or float64 for "format".
+func (ef *encodeField) encodeAs(v reflect.Value) (interface{}, bool) {
+	if ef.asData {
+		if data, ok := fieldData(v); ok {
+			return data, true
+		}
+	}
+	if ef.stringer {
+		if s, ok := fieldString(v); ok {
+			return s, true
+		}
+	}
+	if ef.timeFormat != "" && v.Type() == timeType {
+		return timeNumber(v.Interface().(time.Time), ef.timeFormat)
+	}
+	return nil, false
+}
+
 // encodeFields returns a slice of encodeField for a given struct type.
 func encodeFields(t reflect.Type) []encodeField {
 	typeCacheLock.RLock()
@@ -291,13 +602,180 @@ func encodeFields(t reflect.Type) []encodeField {
 				ef.name = name
 			}
 			ef.omitEmpty = opts.Contains("omitempty")
+			ef.omitZero = opts.Contains("omitzero")
+			ef.omitNil = opts.Contains("omitnil")
+			ef.asData = opts.Contains("data")
+			ef.stringer = opts.Contains("stringer")
+			ef.timeFormat = opts.Get("format")
+			ef.tuple = opts.Contains("tuple")
+			ef.set = opts.Contains("set")
 		}
+		ef.cfName = convertStringToCFString(ef.name)
 		fs = append(fs, ef)
 	}
 	encodeFieldsCache[t] = fs
 	return fs
 }
 
+// decodeFields indexes the fields of a struct type by the dictionary keys
+// that decode into them. A key matches, in order of preference, the first
+// field whose tag names it, the field with that name, or the first field
+// whose name matches it case-insensitively.
+type decodeFields struct {
+	byTag  map[string]int // indexes into fields
+	byName map[string]int
+	byFold map[string]int // keyed by foldName
+	fields []decodeField
+}
+
+// decodeField is a field of a struct along with the tag options that affect
+// how it is decoded.
+type decodeField struct {
+	reflect.StructField
+	asData     bool   // from the "data" option
+	stringer   bool   // from the "stringer" option
+	timeFormat string // from the "format" option
+	tuple      bool   // from the "tuple" option
+	set        bool   // from the "set" option
+	omitNil    bool   // from the "omitnil" option
+}
+
+var decodeFieldsCache = make(map[reflect.Type]*decodeFields)
+
+// cachedDecodeFields returns the decodeFields for a given struct type.
+func cachedDecodeFields(t reflect.Type) *decodeFields {
+	typeCacheLock.RLock()
+	df, ok := decodeFieldsCache[t]
+	typeCacheLock.RUnlock()
+	if ok {
+		return df
+	}
+
+	typeCacheLock.Lock()
+	defer typeCacheLock.Unlock()
+	df, ok = decodeFieldsCache[t]
+	if ok {
+		return df
+	}
+
+	df = &decodeFields{
+		byTag:  make(map[string]int),
+		byName: make(map[string]int),
+		byFold: make(map[string]int),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("plist")
+		if tag == "-" {
+			// Pretend this field doesn't exist
+			continue
+		}
+		if sf.Anonymous {
+			// Match encoding/json's behavior here and pretend it doesn't exist
+			continue
+		}
+		// unexported fields are indexed too, so decoding into one is reported
+		// as an UnmarshalFieldError
+		idx := len(df.fields)
+		name, opts := parseTag(tag)
+		df.fields = append(df.fields, decodeField{
+			StructField: sf,
+			asData:      opts.Contains("data"),
+			stringer:    opts.Contains("stringer"),
+			timeFormat:  opts.Get("format"),
+			tuple:       opts.Contains("tuple"),
+			set:         opts.Contains("set"),
+			omitNil:     opts.Contains("omitnil"),
+		})
+		if _, ok := df.byTag[name]; !ok {
+			df.byTag[name] = idx
+		}
+		df.byName[sf.Name] = idx
+		// encoding/json does a case-insensitive match. Lets do that too
+		fold := foldName(sf.Name)
+		if _, ok := df.byFold[fold]; !ok {
+			df.byFold[fold] = idx
+		}
+	}
+	decodeFieldsCache[t] = df
+	return df
+}
+
+// unmarshalField stores cfObj in v, the value of the field f, as the options
+// of the field say.
+func (state *unmarshalState) unmarshalField(f *decodeField, cfObj cfTypeRef, v reflect.Value) error {
+	if f.asData && unmarshalFieldData(cfObj, v) {
+		return nil
+	}
+	if f.stringer && state.unmarshalParsed(cfObj, v) {
+		return nil
+	}
+	if f.timeFormat != "" && state.unmarshalTimeFormat(cfObj, v, f.timeFormat) {
+		return nil
+	}
+	if f.tuple {
+		if ok, err := state.unmarshalTuple(cfObj, v); ok {
+			return err
+		}
+	}
+	if f.set {
+		if ok, err := state.unmarshalSet(cfObj, v); ok {
+			return err
+		}
+	}
+	if f.omitNil {
+		defer keepEmptySlice(cfObj, v)
+	}
+	return state.unmarshalValue(cfObj, v)
+}
+
+// field returns the field that key decodes into.
+func (df *decodeFields) field(key string) (decodeField, bool) {
+	idx, ok := df.byTag[key]
+	if !ok {
+		idx, ok = df.byName[key]
+	}
+	if !ok {
+		idx, ok = df.byFold[foldName(key)]
+	}
+	if !ok {
+		return decodeField{}, false
+	}
+	return df.fields[idx], true
+}
+
+// foldName maps each rune of s to the smallest rune it is equivalent to under
+// simple case folding, so that two strings have the same foldName exactly
+// when strings.EqualFold reports them equal.
+func foldName(s string) string {
+	ascii := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			ascii = nil
+			break
+		}
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		ascii[i] = c
+	}
+	if ascii != nil {
+		return string(ascii)
+	}
+	b := make([]rune, 0, len(s))
+	for _, r := range s {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		b = append(b, min)
+	}
+	return string(b)
+}
+
 // isValidName determines if the name matches the naming rules for valid names.
 // This is lifted from encoding/json
 func isValidName(name string) bool {
@@ -306,11 +784,11 @@ func isValidName(name string) bool {
 	}
 	for _, c := range name {
 		switch {
-		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~", c):
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
 			// Backslash and quote chars are reserved, but
 			// otherwise any punctuation chars are allowed
 			// in a tag name.
-			// default:
+		default:
 			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
 				return false
 			}
@@ -342,29 +820,96 @@ func isValidName(name string) bool {
 //     []interface{}, for CFArrays
 //     map[string]interface{}, for CFDictionaries
 //
+// UUID values, of [16]byte or a type defined as one, unmarshal from either a
+// CFString of the UUID or a CFData of its 16 bytes.
+//
+// CFDates unmarshal into Date values exactly. They also unmarshal into
+// integer values as seconds since the Unix epoch, into floating point values
+// as fractional seconds since it, and into strings in RFC 3339 format. A
+// struct field's tag can give the "format=unixmilli" option to unmarshal a
+// CFDate into the field as milliseconds instead of seconds, or "format=cocoa"
+// for seconds since the NSDate reference date of January 1, 2001 UTC. The
+// same option on a time.Time field unmarshals a CFNumber into it as a time in
+// that format, or as seconds since the Unix epoch with "format=unix".
+//
+// CFDictionaries unmarshal into sync.Map values by storing each entry, with
+// its value unmarshalled as it would be into an interface value.
+//
+// CFStrings unmarshal into url.URL values by parsing them with url.Parse. A
+// string that doesn't parse is treated like a value of the wrong type, and
+// the error from url.Parse is returned.
+//
+// CFStrings unmarshal into net.IP and netip.Addr values by parsing them as IP
+// addresses. A string that isn't one is treated like a value of the wrong
+// type.
+//
 // If a plist value is not appropriate for a given target type, or if a plist
 // number overflows the target type, Unmarshal skips that field and completes
 // the unmarshalling as best it can. If no more serious errors are encountered,
 // Unmarshal returns an UnmarshalTypeError describing the earliest such error.
 func Unmarshal(data []byte, v interface{}) (format Format, err error) {
-	cfObj, format, err := cfPropertyListCreateWithData(data)
+	return unmarshal(data, v, &unmarshalState{})
+}
+
+func unmarshal(data []byte, v interface{}, state *unmarshalState) (format Format, err error) {
+	defer recoverPanic(&err)
+	parse := cfPropertyListCreateWithData
+	if state.mapped {
+		parse = cfPropertyListCreateWithBytesNoCopy
+	}
+	cfObj, format, err := parse(data)
 	if err != nil {
 		return format, err
 	}
 	defer cfRelease(cfObj)
+	if state.ctx != nil {
+		// parsing may have taken a while
+		if err := state.ctx.Err(); err != nil {
+			return format, err
+		}
+	}
+	if format == XMLFormat && (state.orderedDicts || containsDict(reflect.TypeOf(v), map[reflect.Type]bool{})) {
+		state.order = scanKeyOrder(data)
+	}
+	return format, state.unmarshalRoot(cfObj, v)
+}
+
+// unmarshalRoot stores the Go value of cfObj in the value pointed to by v.
+func (state *unmarshalState) unmarshalRoot(cfObj cfTypeRef, v interface{}) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
-		return format, &InvalidUnmarshalError{reflect.TypeOf(v)}
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
-	state := &unmarshalState{}
 	if err := state.unmarshalValue(cfObj, rv); err != nil {
-		return format, err
+		return err
 	}
-	return format, state.err
+	return state.err
+}
+
+// decodeOptions holds the options that can be set on a Decoder.
+type decodeOptions struct {
+	nestedPlists  bool
+	stringifyKeys bool
+	orderedDicts  bool
+	arena         *Arena
+	location      *time.Location // of decoded times, if not the local time zone
 }
 
 type unmarshalState struct {
-	err error
+	decodeOptions
+	contextChecker
+	err   error
+	order *keyOrder // key order of the value being unmarshaled, if known
+	// scratch and keyBuf are reused by a TypedDecoder for the elements of
+	// arrays and dictionaries and for dictionary keys
+	scratch []cfTypeRef
+	keyBuf  []byte
+	// keys holds the dictionary keys seen so far, so that each distinct key
+	// is only allocated once
+	keys map[string]string
+	// mapped is set when the data is a file mapping, which is parsed in place
+	// rather than copied into a CFData
+	mapped bool
 }
 
 var (
@@ -378,22 +923,24 @@ var (
 )
 
 var cfTypeMap = map[C.CFTypeID]reflect.Type{
-	cfArrayTypeID:      reflect.TypeOf([]interface{}(nil)),
-	cfBooleanTypeID:    reflect.TypeOf(false),
-	cfDataTypeID:       reflect.TypeOf([]byte(nil)),
-	cfDateTypeID:       reflect.TypeOf(time.Time{}),
-	cfDictionaryTypeID: reflect.TypeOf(map[string]interface{}(nil)),
-	cfStringTypeID:     reflect.TypeOf(""),
+	cfArrayTypeID:            reflect.TypeOf([]interface{}(nil)),
+	cfBooleanTypeID:          reflect.TypeOf(false),
+	cfDataTypeID:             reflect.TypeOf([]byte(nil)),
+	cfDateTypeID:             reflect.TypeOf(time.Time{}),
+	cfDictionaryTypeID:       reflect.TypeOf(map[string]interface{}(nil)),
+	cfStringTypeID:           reflect.TypeOf(""),
+	cfKeyedArchiverUIDTypeID: uidType,
 }
 
 var cfTypeNames = map[C.CFTypeID]string{
-	cfArrayTypeID:      "CFArray",
-	cfBooleanTypeID:    "CFBoolean",
-	cfDataTypeID:       "CFData",
-	cfDateTypeID:       "CFDate",
-	cfDictionaryTypeID: "CFDictionary",
-	cfNumberTypeID:     "CFNumber",
-	cfStringTypeID:     "CFString",
+	cfArrayTypeID:            "CFArray",
+	cfBooleanTypeID:          "CFBoolean",
+	cfDataTypeID:             "CFData",
+	cfDateTypeID:             "CFDate",
+	cfDictionaryTypeID:       "CFDictionary",
+	cfNumberTypeID:           "CFNumber",
+	cfStringTypeID:           "CFString",
+	cfKeyedArchiverUIDTypeID: "CFKeyedArchiverUID",
 }
 
 func cfNumberTypeToType(t C.CFNumberType) reflect.Type {
@@ -416,7 +963,25 @@ func cfNumberTypeToType(t C.CFNumberType) reflect.Type {
 }
 
 func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) error {
+	if err := state.checkContext(); err != nil {
+		return err
+	}
 	vType := v.Type()
+	var cfUnmarshaler CFUnmarshaler
+	if u, ok := v.Interface().(CFUnmarshaler); ok {
+		cfUnmarshaler = u
+	} else if vType.Kind() != reflect.Ptr && vType.Name() != "" && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(CFUnmarshaler); ok {
+			cfUnmarshaler = u
+		}
+	}
+	if cfUnmarshaler != nil {
+		if vType.Kind() == reflect.Ptr && v.IsNil() {
+			v.Set(reflect.New(vType.Elem()))
+			cfUnmarshaler = v.Interface().(CFUnmarshaler)
+		}
+		return cfUnmarshaler.UnmarshalPlistCF(RetainCFObject(unsafe.Pointer(cfObj)))
+	}
 	var unmarshaler Unmarshaler
 	if u, ok := v.Interface().(Unmarshaler); ok {
 		unmarshaler = u
@@ -430,7 +995,7 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 	}
 	if unmarshaler != nil {
 		// flip over to the dumb conversion routine so we have something to give UnmarshalPlist()
-		plist, err := convertCFTypeToInterface(cfObj)
+		plist, err := convertCFTypeToInterfaceKeys(cfObj, state.stringifyKeys)
 		if err != nil {
 			return err
 		}
@@ -447,14 +1012,34 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 		return state.unmarshalValue(cfObj, v.Elem())
 	}
 	typeID := C.CFGetTypeID(C.CFTypeRef(cfObj))
+	if typeID == cfDataTypeID && state.nestedPlists && (!byteSliceType.AssignableTo(vType) || (vType.Kind() == reflect.Interface && vType.NumMethod() == 0)) {
+		// decode the data as a property list if it looks like one
+		if nested := cfNestedPropertyList(C.CFDataRef(cfObj)); nested != nil {
+			defer cfRelease(nested)
+			saved := state.order
+			state.order = nil
+			defer func() { state.order = saved }()
+			return state.unmarshalValue(nested, v)
+		}
+	}
 	vSetter := v      // receiver of any Set* calls
 	vAddr := v.Addr() // used for re-setting v for maps/slices
 	if vType.Kind() == reflect.Interface {
+		if v.IsNil() && vType.NumMethod() == 0 && state.useArena() {
+			val, err := state.arenaValue(cfObj)
+			if err != nil {
+				return err
+			}
+			vSetter.Set(reflect.ValueOf(val))
+			return nil
+		}
 		if v.IsNil() {
 			// pick an appropriate type based on the cfobj
 			var typ reflect.Type
 			if typeID == cfNumberTypeID {
 				typ = cfNumberTypeToType(C.CFNumberGetType(C.CFNumberRef(cfObj)))
+			} else if typeID == cfDictionaryTypeID && state.orderedDicts {
+				typ = dictPtrType
 			} else {
 				var ok bool
 				typ, ok = cfTypeMap[typeID]
@@ -479,6 +1064,15 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 			state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
 			return nil
 		}
+		if s, ok := convertCFArrayToPrimitiveSlice(C.CFArrayRef(cfObj), vType); ok {
+			vSetter.Set(reflect.ValueOf(s))
+			return nil
+		}
+		if vType.Kind() == reflect.Slice {
+			if ok, err := state.unmarshalArray(C.CFArrayRef(cfObj), vSetter, vType); ok {
+				return err
+			}
+		}
 		return convertCFArrayToSliceHelper(C.CFArrayRef(cfObj), func(elem cfTypeRef, idx, count int) (bool, error) {
 			if idx == 0 && vType.Kind() == reflect.Slice {
 				vSetter.Set(reflect.MakeSlice(vType, count, count))
@@ -486,7 +1080,11 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 			} else if vType.Kind() == reflect.Array && idx >= v.Len() {
 				return false, nil
 			}
-			if err := state.unmarshalValue(elem, v.Index(idx)); err != nil {
+			saved := state.order
+			state.order = saved.index(idx)
+			err := state.unmarshalValue(elem, v.Index(idx))
+			state.order = saved
+			if err != nil {
 				return false, err
 			}
 			return true, nil
@@ -499,6 +1097,17 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 		vSetter.Set(reflect.ValueOf(C.CFBooleanGetValue(C.CFBooleanRef(cfObj)) != C.false))
 		return nil
 	case cfDataTypeID:
+		if isUUIDType(vType) {
+			var uuid [16]byte
+			data := convertCFDataToBytes(C.CFDataRef(cfObj))
+			if len(data) != len(uuid) {
+				state.recordError(&UnmarshalTypeError{cfTypeNames[typeID] + " of length " + strconv.Itoa(len(data)), vType})
+				return nil
+			}
+			copy(uuid[:], data)
+			vSetter.Set(reflect.ValueOf(uuid).Convert(vType))
+			return nil
+		}
 		if !byteSliceType.AssignableTo(vType) {
 			state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
 			return nil
@@ -506,72 +1115,75 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 		vSetter.Set(reflect.ValueOf(convertCFDataToBytes(C.CFDataRef(cfObj))))
 		return nil
 	case cfDateTypeID:
+		if vType == dateType {
+			vSetter.Set(reflect.ValueOf(convertCFDateToDate(C.CFDateRef(cfObj))))
+			return nil
+		}
+		if state.unmarshalDate(C.CFDateRef(cfObj), v, vSetter, "") {
+			return nil
+		}
 		if !timeType.AssignableTo(vType) {
 			state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
 			return nil
 		}
-		vSetter.Set(reflect.ValueOf(convertCFDateToTime(C.CFDateRef(cfObj))))
+		vSetter.Set(reflect.ValueOf(state.convertCFDate(C.CFDateRef(cfObj))))
 		return nil
 	case cfDictionaryTypeID:
-		if vType.Kind() == reflect.Map {
+		if vType == dictType || vType == dictPtrType {
+			d, err := state.unmarshalDict(C.CFDictionaryRef(cfObj))
+			if err != nil {
+				return err
+			}
+			if vType == dictPtrType {
+				vSetter.Set(reflect.ValueOf(d))
+			} else {
+				vSetter.Set(reflect.ValueOf(d).Elem())
+			}
+			return nil
+		} else if vType == syncMapType {
+			return state.unmarshalSyncMap(C.CFDictionaryRef(cfObj), v.Addr().Interface().(*sync.Map))
+		} else if vType.Kind() == reflect.Map {
 			// it's a map. Check its key type first
 			if !stringType.AssignableTo(vType.Key()) {
 				state.recordError(&UnmarshalTypeError{cfTypeNames[cfStringTypeID], vType.Key()})
 				return nil
 			}
 			if v.IsNil() {
-				vSetter.Set(reflect.MakeMap(vType))
+				vSetter.Set(reflect.MakeMapWithSize(vType, int(C.CFDictionaryGetCount(C.CFDictionaryRef(cfObj)))))
 				v = vAddr.Elem()
 			}
-			return convertCFDictionaryToMapHelper(C.CFDictionaryRef(cfObj), func(key string, value cfTypeRef, count int) error {
+			if vType == stringMapType || vType == interfaceMapType {
+				return state.unmarshalMap(C.CFDictionaryRef(cfObj), v.Interface())
+			}
+			return state.convertCFDictionary(C.CFDictionaryRef(cfObj), func(key string, value cfTypeRef, count int) error {
 				keyVal := reflect.ValueOf(key)
 				val := reflect.New(vType.Elem())
-				if err := state.unmarshalValue(value, val); err != nil {
+				saved := state.order
+				state.order = saved.key(key)
+				err := state.unmarshalValue(value, val)
+				state.order = saved
+				if err != nil {
 					return err
 				}
 				v.SetMapIndex(keyVal, val.Elem())
 				return nil
 			})
 		} else if vType.Kind() == reflect.Struct {
-			return convertCFDictionaryToMapHelper(C.CFDictionaryRef(cfObj), func(key string, value cfTypeRef, count int) error {
-				// we need to iterate the fields because the tag might rename the key
-				var f reflect.StructField
-				var ok bool
-				for i := 0; i < vType.NumField(); i++ {
-					sf := vType.Field(i)
-					tag := sf.Tag.Get("plist")
-					if tag == "-" {
-						// Pretend this field doesn't exist
-						continue
-					}
-					if sf.Anonymous {
-						// Match encoding/json's behavior here and pretend it doesn't exist
-						continue
-					}
-					name, _ := parseTag(tag)
-					if name == key {
-						f = sf
-						ok = true
-						// This is unambiguously the right match
-						break
-					}
-					if sf.Name == key {
-						f = sf
-						ok = true
-					}
-					// encoding/json does a case-insensitive match. Lets do that too
-					if !ok && strings.EqualFold(sf.Name, key) {
-						f = sf
-						ok = true
-					}
-				}
+			fields := cachedDecodeFields(vType)
+			return state.convertCFDictionary(C.CFDictionaryRef(cfObj), func(key string, value cfTypeRef, count int) error {
+				// the tag might rename the key, so look up the field by key
+				f, ok := fields.field(key)
 				if ok {
 					if f.PkgPath != "" {
 						// this is an unexported field
-						return &UnmarshalFieldError{key, vType, f}
+						return &UnmarshalFieldError{key, vType, f.StructField}
 					}
 					vElem := v.FieldByIndex(f.Index)
-					if err := state.unmarshalValue(value, vElem); err != nil {
+					saved := state.order
+					state.order = saved.key(key)
+					err := state.unmarshalField(&f, value, vElem)
+					state.order = saved
+					if err != nil {
 						return err
 					}
 				}
@@ -622,16 +1234,131 @@ func (state *unmarshalState) unmarshalValue(cfObj cfTypeRef, v reflect.Value) er
 		state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
 		return nil
 	case cfStringTypeID:
+		if isUUIDType(vType) {
+			s := convertCFStringToString(C.CFStringRef(cfObj))
+			uuid, ok := parseUUID(s)
+			if !ok {
+				state.recordError(&UnmarshalTypeError{cfTypeNames[typeID] + " " + strconv.Quote(s), vType})
+				return nil
+			}
+			vSetter.Set(reflect.ValueOf(uuid).Convert(vType))
+			return nil
+		}
+		if isAddressType(vType) {
+			s := convertCFStringToString(C.CFStringRef(cfObj))
+			addr, ok := parseAddress(s, vType)
+			if !ok {
+				state.recordError(&UnmarshalTypeError{cfTypeNames[typeID] + " " + strconv.Quote(s), vType})
+				return nil
+			}
+			vSetter.Set(addr)
+			return nil
+		}
+		if vType == urlType {
+			u, err := url.Parse(convertCFStringToString(C.CFStringRef(cfObj)))
+			if err != nil {
+				state.recordError(err)
+				return nil
+			}
+			vSetter.Set(reflect.ValueOf(*u))
+			return nil
+		}
 		if vType.Kind() != reflect.String {
 			state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
 			return nil
 		}
 		vSetter.Set(reflect.ValueOf(convertCFStringToString(C.CFStringRef(cfObj))))
 		return nil
+	case cfKeyedArchiverUIDTypeID:
+		if !uidType.AssignableTo(vType) {
+			state.recordError(&UnmarshalTypeError{cfTypeNames[typeID], vType})
+			return nil
+		}
+		vSetter.Set(reflect.ValueOf(convertCFKeyedArchiverUIDToUID(cfObj)))
+		return nil
 	}
 	return &UnknownCFTypeError{typeID}
 }
 
+// unmarshalDict converts cfDict to a Dict, ordering its keys as they appeared
+// in the input if known. Nested dictionaries are converted to a *Dict too.
+func (state *unmarshalState) unmarshalDict(cfDict C.CFDictionaryRef) (*Dict, error) {
+	count := int(C.CFDictionaryGetCount(cfDict))
+	keys := make([]string, 0, count)
+	cfValues := make(map[string]cfTypeRef, count)
+	err := state.convertCFDictionary(cfDict, func(key string, value cfTypeRef, count int) error {
+		keys = append(keys, key)
+		cfValues[key] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved, savedOrdered := state.order, state.orderedDicts
+	defer func() { state.order, state.orderedDicts = saved, savedOrdered }()
+	state.orderedDicts = true
+	d := &Dict{keys: saved.orderKeys(keys), values: make(map[string]interface{}, len(keys))}
+	for _, key := range d.keys {
+		state.order = saved.key(key)
+		var val interface{}
+		if err := state.unmarshalValue(cfValues[key], reflect.ValueOf(&val).Elem()); err != nil {
+			return nil, err
+		}
+		d.values[key] = val
+	}
+	return d, nil
+}
+
+// convertCFDictionary calls helper with each entry of cfDict, like
+// convertCFDictionaryToMapHelper, but with keys from state.dictKey.
+func (state *unmarshalState) convertCFDictionary(cfDict C.CFDictionaryRef, helper func(key string, value cfTypeRef, count int) error) error {
+	if state.ctx != nil {
+		// unmarshalMap stores simple values without unmarshalValue
+		inner := helper
+		helper = func(key string, value cfTypeRef, count int) error {
+			if err := state.checkContext(); err != nil {
+				return err
+			}
+			return inner(key, value, count)
+		}
+	}
+	if !state.stringifyKeys {
+		return convertCFDictionaryEntries(cfDict, state.dictKey, helper)
+	}
+	// stringified keys may collide with each other or with string keys
+	seen := make(map[string]bool)
+	return convertCFDictionaryEntries(cfDict, func(cfKey cfTypeRef) (string, error) {
+		key, err := state.dictKey(cfKey)
+		if err != nil {
+			return "", err
+		}
+		if seen[key] {
+			return "", &DuplicateKeyError{key}
+		}
+		seen[key] = true
+		return key, nil
+	}, helper)
+}
+
+// dictKey converts cfKey to a string like convertCFKeyToString, returning the
+// same string for every key with the same contents. Property lists often
+// repeat the same keys in thousands of dictionaries, which then share them.
+func (state *unmarshalState) dictKey(cfKey cfTypeRef) (string, error) {
+	if C.CFGetTypeID(C.CFTypeRef(cfKey)) != cfStringTypeID {
+		return convertCFKeyToString(cfKey, state.stringifyKeys)
+	}
+	state.keyBuf = appendCFStringBytes(state.keyBuf[:0], C.CFStringRef(cfKey))
+	if key, ok := state.keys[string(state.keyBuf)]; ok {
+		return key, nil
+	}
+	key := string(state.keyBuf)
+	if state.keys == nil {
+		state.keys = make(map[string]string)
+	}
+	state.keys[key] = key
+	return key, nil
+}
+
 func (state *unmarshalState) recordError(err error) {
 	if state.err == nil {
 		state.err = err
@@ -651,6 +1378,22 @@ type Unmarshaler interface {
 	UnmarshalPlist(interface{}) error
 }
 
+// CFMarshaler is the interface implemented by objects that can marshal
+// themselves directly into a CoreFoundation property list object. It takes
+// precedence over Marshaler. The returned object is retained as needed, so
+// it may be shared with the receiver.
+type CFMarshaler interface {
+	MarshalPlistCF() (CFObject, error)
+}
+
+// CFUnmarshaler is the interface implemented by objects that can unmarshal
+// themselves directly from a CoreFoundation property list object. It takes
+// precedence over Unmarshaler. The CFObject holds its own reference, so the
+// receiver may keep it.
+type CFUnmarshaler interface {
+	UnmarshalPlistCF(CFObject) error
+}
+
 // An UnmarshalTypeError describes a plist value that was not appropriate for a
 // value of a specific Go type.
 type UnmarshalTypeError struct {
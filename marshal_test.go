@@ -1,10 +1,18 @@
 package plist
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Crib some of the test data from encoding/json
@@ -58,6 +66,186 @@ func TestOmitEmpty(t *testing.T) {
 	}
 }
 
+func TestTagNames(t *testing.T) {
+	var v struct {
+		Sandbox   bool   `plist:"com.apple.security.app-sandbox"`
+		Groups    string `plist:"keychain-access-groups,omitempty"`
+		Backslash int    `plist:"a\\b"`
+		Quote     int    `plist:"a\"b"`
+		Device    string `plist:"Device Name"`
+		Semicolon int    `plist:"a;b"`
+		Tab       int    `plist:"a\tb"`
+	}
+	v.Sandbox = true
+	v.Groups = "group"
+	v.Device = "iPhone"
+	data, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(got))
+	for key := range got {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	// names with a backslash, a quote or whitespace other than a space
+	// fall back to the field name
+	want := []string{"Backslash", "Device Name", "Quote", "Tab", "a;b", "com.apple.security.app-sandbox", "keychain-access-groups"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+	var back struct {
+		Device string `plist:"Device Name"`
+	}
+	if _, err := Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Device != v.Device {
+		t.Errorf("Device Name: got %q, want %q", back.Device, v.Device)
+	}
+	for _, name := range []string{"a\\b", "a\"b", "a\tb", ""} {
+		if isValidName(name) {
+			t.Errorf("isValidName(%q) = true", name)
+		}
+	}
+}
+
+func TestOmitZero(t *testing.T) {
+	type options struct {
+		Verbose bool
+		Paths   []string
+		Since   time.Time
+	}
+	type config struct {
+		Name    string
+		Options options             `plist:",omitzero"`
+		When    time.Time           `plist:",omitzero"`
+		Meta    Dict                `plist:",omitzero"`
+		Nested  struct{ O options } `plist:",omitzero"`
+		Always  options             `plist:",omitempty"`
+	}
+	encode := NewTypedEncoder[config]()
+	for _, v := range []config{{Name: "empty"}, {Name: "set", Options: options{Paths: []string{"/"}}}} {
+		data, err := Marshal(v, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, XMLFormat).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var got map[string]interface{}
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			// only the fields without omitzero, and Options once it has a
+			// path, are there; omitempty never leaves out a struct
+			want := []string{"Always", "Name"}
+			if v.Options.Paths != nil {
+				want = []string{"Always", "Name", "Options"}
+			}
+			keys := make([]string, 0, len(got))
+			for key := range got {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("%s: got keys %v, want %v", v.Name, keys, want)
+			}
+		}
+	}
+}
+
+func TestOmitDataView(t *testing.T) {
+	// a DataView has no exported fields, but isn't empty or zero when it
+	// holds data
+	type holder struct {
+		Empty DataView `plist:",omitempty"`
+		Zero  DataView `plist:",omitzero"`
+	}
+	src, err := Marshal(map[string][]byte{"Empty": []byte("abc"), "Zero": []byte("def")}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v holder
+	if _, err := Unmarshal(src, &v); err != nil {
+		t.Fatal(err)
+	}
+	defer v.Empty.Release()
+	defer v.Zero.Release()
+	data, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, XMLFormat).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	typed, err := NewTypedEncoder[holder]()(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]byte{"Empty": []byte("abc"), "Zero": []byte("def")}
+	for _, data := range [][]byte{data, buf.Bytes(), typed} {
+		var got map[string][]byte
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+	// a DataView that holds nothing is zero
+	data, err = Marshal(struct {
+		Zero DataView `plist:",omitzero"`
+	}{}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("a zero DataView was encoded: %v", got)
+	}
+}
+
+func TestMarshalStructRepeatedly(t *testing.T) {
+	// the field names are cached as CFStrings shared by every marshal
+	for i := 0; i < 3; i++ {
+		o := Optionals{Sr: "a", So: "b", Io: i + 1}
+		data, err := Marshal(&o, BinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]interface{}{
+			"sr":        "a",
+			"so":        "b",
+			"omitempty": int64(0),
+			"io":        int64(i + 1),
+			"slr":       []interface{}{},
+			"mr":        map[string]interface{}{},
+		}
+		if !Equal(got, want) {
+			t.Errorf("marshal %d: got %#v, want %#v", i, got, want)
+		}
+	}
+}
+
 var unsupportedValues = []interface{}{
 	math.NaN(),
 	math.Inf(-1),
@@ -126,3 +314,182 @@ func TestRefValMarshal(t *testing.T) {
 		t.Errorf("got %#v, want %#v", got, expected)
 	}
 }
+
+func TestMarshalManyTemporaries(t *testing.T) {
+	// enough dictionaries that the temporary objects are released in several
+	// batches while the rest of the tree is still being converted
+	type entry struct {
+		Name   string
+		Values map[string]interface{}
+		Order  *Dict
+	}
+	entries := make([]entry, 3*releasePoolSize)
+	for i := range entries {
+		order := new(Dict)
+		order.Set("z", int64(i))
+		order.Set("a", "first")
+		entries[i] = entry{
+			Name:   strconv.Itoa(i),
+			Values: map[string]interface{}{"i": int64(i), "list": []interface{}{"a", int64(i)}},
+			Order:  order,
+		}
+	}
+	for _, threshold := range []int{0, 100} {
+		withParallelism(threshold, func() {
+			for _, format := range []Format{XMLFormat, BinaryFormat} {
+				data, err := Marshal(entries, format)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []entry
+				if _, err := Unmarshal(data, &got); err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(entries) {
+					t.Fatalf("%s: got %d entries, want %d", format, len(got), len(entries))
+				}
+				for i := range got {
+					if got[i].Name != entries[i].Name || !reflect.DeepEqual(got[i].Values, entries[i].Values) {
+						t.Fatalf("%s: entry %d is %#v", format, i, got[i])
+					}
+					if format == XMLFormat && !reflect.DeepEqual(got[i].Order.Keys(), []string{"z", "a"}) {
+						t.Fatalf("%s: entry %d has keys %v", format, i, got[i].Order.Keys())
+					}
+				}
+			}
+		})
+	}
+}
+
+type panicky struct{}
+
+func (panicky) MarshalPlist() (interface{}, error) {
+	panic("marshal")
+}
+
+func (*panicky) UnmarshalPlist(interface{}) error {
+	panic(errors.New("unmarshal"))
+}
+
+func TestPanicError(t *testing.T) {
+	check := func(name string, err error, want interface{}) {
+		t.Helper()
+		var pe *PanicError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: got error %v, want a PanicError", name, err)
+			return
+		}
+		if fmt.Sprint(pe.Value) != fmt.Sprint(want) || len(pe.Stack) == 0 {
+			t.Errorf("%s: got %#v", name, pe)
+		}
+	}
+	_, err := Marshal(panicky{}, XMLFormat)
+	check("Marshal", err, "marshal")
+	dst := []byte("prefix")
+	out, err := MarshalAppend(dst, []panicky{{}}, BinaryFormat)
+	check("MarshalAppend", err, "marshal")
+	if string(out) != "prefix" {
+		t.Errorf("MarshalAppend returned %q", out)
+	}
+
+	data, err := Marshal([]string{"a", "b"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v []panicky
+	_, err = Unmarshal(data, &v)
+	check("Unmarshal", err, "unmarshal")
+	if !strings.Contains(err.Error(), "unmarshal") {
+		t.Errorf("Unmarshal: error %q", err)
+	}
+
+	// panics on the goroutines that convert a parallel array
+	withParallelism(2, func() {
+		_, err := Marshal(make([]panicky, 10), XMLFormat)
+		check("parallel Marshal", err, "marshal")
+		data, err := Marshal(make([]string, 10), XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Unmarshal(data, &v)
+		check("parallel Unmarshal", err, "unmarshal")
+	})
+}
+
+type urls struct {
+	Home  url.URL
+	Feed  *url.URL
+	Links []*url.URL
+}
+
+func TestURL(t *testing.T) {
+	parse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	v := urls{
+		Home:  *parse("https://example.com/a%20b?q=1#top"),
+		Feed:  parse("feed.xml"),
+		Links: []*url.URL{parse("mailto:someone@example.com"), parse("file:///tmp/x")},
+	}
+	want := map[string]interface{}{
+		"Home":  "https://example.com/a%20b?q=1#top",
+		"Feed":  "feed.xml",
+		"Links": []interface{}{"mailto:someone@example.com", "file:///tmp/x"},
+	}
+	encode := NewTypedEncoder[urls]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		var buf strings.Builder
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, []byte(buf.String()), typed} {
+			var plist interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got %#v, want %#v", format, plist, want)
+			}
+			var got urls
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: got %#v, want %#v", format, got, v)
+			}
+			got = urls{}
+			if _, err := NewTypedDecoder[urls]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: TypedDecoder got %#v, want %#v", format, got, v)
+			}
+		}
+	}
+
+	data, err := Marshal(map[string]interface{}{"Home": "https://x.com", "Feed": "%zz"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got urls
+	_, err = Unmarshal(data, &got)
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) || urlErr.URL != "%zz" {
+		t.Errorf("expected a url.Error for %%zz, got %v", err)
+	}
+	if got.Home.Host != "x.com" {
+		t.Errorf("Home was not unmarshaled: %#v", got.Home)
+	}
+}
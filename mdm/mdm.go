@@ -0,0 +1,223 @@
+// Package mdm defines the messages of Apple's Mobile Device Management
+// protocol.
+//
+// Devices talk to an MDM server over two endpoints. The check-in endpoint
+// receives Authenticate, TokenUpdate and CheckOut messages as the device
+// enrolls and unenrolls. The server endpoint receives a CommandResult each
+// time the device polls for work, and answers it with the next Command.
+// All messages are property lists; use plist.NewHTTPDecoder and
+// plist.WriteHTTP to read and write them.
+package mdm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// Check-in message types.
+const (
+	AuthenticateMessageType = "Authenticate"
+	TokenUpdateMessageType  = "TokenUpdate"
+	CheckOutMessageType     = "CheckOut"
+)
+
+// Authenticate is the first check-in message of an enrolling device.
+type Authenticate struct {
+	MessageType  string
+	Topic        string
+	UDID         string `plist:",omitempty"`
+	EnrollmentID string `plist:",omitempty"` // user enrollments have this instead of a UDID
+	BuildVersion string `plist:",omitempty"`
+	DeviceName   string `plist:",omitempty"`
+	IMEI         string `plist:",omitempty"`
+	MEID         string `plist:",omitempty"`
+	Model        string `plist:",omitempty"`
+	ModelName    string `plist:",omitempty"`
+	OSVersion    string `plist:",omitempty"`
+	ProductName  string `plist:",omitempty"`
+	SerialNumber string `plist:",omitempty"`
+}
+
+// TokenUpdate delivers the push notification token of a device or user
+// channel. It is sent after Authenticate and whenever the token changes.
+type TokenUpdate struct {
+	MessageType           string
+	Topic                 string
+	UDID                  string `plist:",omitempty"`
+	EnrollmentID          string `plist:",omitempty"`
+	UserID                string `plist:",omitempty"` // set for the user channel of macOS devices
+	UserShortName         string `plist:",omitempty"`
+	UserLongName          string `plist:",omitempty"`
+	Token                 []byte
+	PushMagic             string
+	UnlockToken           []byte `plist:",omitempty"`
+	AwaitingConfiguration bool   `plist:",omitempty"`
+	NotOnConsole          bool   `plist:",omitempty"`
+}
+
+// CheckOut is sent when the MDM profile is removed from the device.
+type CheckOut struct {
+	MessageType  string
+	Topic        string
+	UDID         string `plist:",omitempty"`
+	EnrollmentID string `plist:",omitempty"`
+	UserID       string `plist:",omitempty"`
+}
+
+// ParseCheckIn parses a check-in message, returning an *Authenticate,
+// *TokenUpdate or *CheckOut according to its MessageType.
+func ParseCheckIn(data []byte) (interface{}, error) {
+	var header struct{ MessageType string }
+	if _, err := plist.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	var msg interface{}
+	switch header.MessageType {
+	case AuthenticateMessageType:
+		msg = new(Authenticate)
+	case TokenUpdateMessageType:
+		msg = new(TokenUpdate)
+	case CheckOutMessageType:
+		msg = new(CheckOut)
+	case "":
+		return nil, errors.New("mdm: check-in message has no MessageType")
+	default:
+		return nil, errors.New("mdm: unsupported check-in MessageType " + header.MessageType)
+	}
+	if _, err := plist.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// A Command is sent by the server in response to a CommandResult.
+type Command struct {
+	CommandUUID string
+	// Command is the request, a map or struct with a RequestType key, such
+	// as one of the request types of this package.
+	Command interface{}
+}
+
+// NewCommand returns a Command with a new random CommandUUID.
+func NewCommand(request interface{}) *Command {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	s := hex.EncodeToString(u[:])
+	uuid := strings.ToUpper(s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:])
+	return &Command{CommandUUID: uuid, Command: request}
+}
+
+// Command statuses reported in CommandResult.Status.
+const (
+	StatusIdle               = "Idle" // the device has nothing to report and is asking for a command
+	StatusAcknowledged       = "Acknowledged"
+	StatusError              = "Error"
+	StatusCommandFormatError = "CommandFormatError"
+	StatusNotNow             = "NotNow"
+)
+
+// A CommandResult is sent by the device each time it polls the server,
+// reporting the result of the previous command if there was one.
+//
+// The request specific keys of a result, such as the QueryResponses of a
+// DeviceInformation result, aren't part of CommandResult; unmarshal the
+// message again into a struct that has them.
+type CommandResult struct {
+	UDID         string `plist:",omitempty"`
+	EnrollmentID string `plist:",omitempty"`
+	UserID       string `plist:",omitempty"`
+	Status       string
+	CommandUUID  string           `plist:",omitempty"`
+	ErrorChain   []ErrorChainItem `plist:",omitempty"`
+}
+
+// An ErrorChainItem describes one error of a failed command, from the most
+// specific to the most general.
+type ErrorChainItem struct {
+	ErrorCode            int
+	ErrorDomain          string
+	LocalizedDescription string `plist:",omitempty"`
+	USEnglishDescription string `plist:",omitempty"`
+}
+
+// ErrorDescription returns the description of the first error of the chain,
+// or "" if there is none.
+func (r *CommandResult) ErrorDescription() string {
+	if len(r.ErrorChain) == 0 {
+		return ""
+	}
+	e := r.ErrorChain[0]
+	desc := e.USEnglishDescription
+	if desc == "" {
+		desc = e.LocalizedDescription
+	}
+	return e.ErrorDomain + ": " + desc
+}
+
+// DeviceInformation requests the values of the given queries, such as
+// "DeviceName", "OSVersion" or "SerialNumber".
+type DeviceInformation struct {
+	RequestType string // "DeviceInformation"
+	Queries     []string
+}
+
+// NewDeviceInformation returns a DeviceInformation request.
+func NewDeviceInformation(queries ...string) DeviceInformation {
+	return DeviceInformation{"DeviceInformation", queries}
+}
+
+// DeviceInformationResult holds the request specific keys of the result of a
+// DeviceInformation command.
+type DeviceInformationResult struct {
+	QueryResponses map[string]interface{}
+}
+
+// InstallProfile requests the installation of a configuration profile.
+type InstallProfile struct {
+	RequestType string // "InstallProfile"
+	Payload     []byte // the profile, which may be signed
+}
+
+// NewInstallProfile returns an InstallProfile request.
+func NewInstallProfile(profile []byte) InstallProfile {
+	return InstallProfile{"InstallProfile", profile}
+}
+
+// RemoveProfile requests the removal of the profile with the given
+// identifier.
+type RemoveProfile struct {
+	RequestType string // "RemoveProfile"
+	Identifier  string
+}
+
+// NewRemoveProfile returns a RemoveProfile request.
+func NewRemoveProfile(identifier string) RemoveProfile {
+	return RemoveProfile{"RemoveProfile", identifier}
+}
+
+// DeviceLock requests that the device be locked.
+type DeviceLock struct {
+	RequestType string // "DeviceLock"
+	PIN         string `plist:",omitempty"` // required for macOS
+	Message     string `plist:",omitempty"`
+	PhoneNumber string `plist:",omitempty"`
+}
+
+// NewDeviceLock returns a DeviceLock request.
+func NewDeviceLock(pin, message string) DeviceLock {
+	return DeviceLock{RequestType: "DeviceLock", PIN: pin, Message: message}
+}
+
+// A Request is a request that takes no parameters, such as "ProfileList",
+// "InstalledApplicationList", "SecurityInfo" or "RestartDevice".
+type Request struct {
+	RequestType string
+}
@@ -0,0 +1,88 @@
+package mdm
+
+import (
+	"reflect"
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestParseCheckIn(t *testing.T) {
+	in := &TokenUpdate{
+		MessageType: TokenUpdateMessageType,
+		Topic:       "com.apple.mgmt.External.example",
+		UDID:        "00008030-001A2B3C4D5E6F70",
+		Token:       []byte{1, 2, 3},
+		PushMagic:   "magic",
+	}
+	data, err := plist.Marshal(in, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ParseCheckIn(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(msg, in) {
+		t.Errorf("got %#v, want %#v", msg, in)
+	}
+
+	data, err = plist.Marshal(map[string]string{"MessageType": "Bogus"}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseCheckIn(data); err == nil {
+		t.Error("expected error for unknown MessageType")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cmd := NewCommand(NewDeviceInformation("DeviceName", "OSVersion"))
+	if len(cmd.CommandUUID) != 36 {
+		t.Errorf("got CommandUUID %q", cmd.CommandUUID)
+	}
+	data, err := plist.Marshal(cmd, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if _, err := plist.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"CommandUUID": cmd.CommandUUID,
+		"Command": map[string]interface{}{
+			"RequestType": "DeviceInformation",
+			"Queries":     []interface{}{"DeviceName", "OSVersion"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestCommandResult(t *testing.T) {
+	data, err := plist.Marshal(map[string]interface{}{
+		"UDID":        "00008030-001A2B3C4D5E6F70",
+		"Status":      StatusError,
+		"CommandUUID": "A",
+		"ErrorChain": []interface{}{map[string]interface{}{
+			"ErrorCode":            4001,
+			"ErrorDomain":          "MCProfileErrorDomain",
+			"USEnglishDescription": "The profile is invalid.",
+		}},
+	}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result CommandResult
+	if _, err := plist.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != StatusError || result.ErrorChain[0].ErrorCode != 4001 {
+		t.Errorf("got %#v", result)
+	}
+	if desc := result.ErrorDescription(); desc != "MCProfileErrorDomain: The profile is invalid." {
+		t.Errorf("got description %q", desc)
+	}
+}
@@ -0,0 +1,160 @@
+package plist
+
+import (
+	"sort"
+	"strconv"
+)
+
+// An ArrayStrategy determines how Merge combines two arrays.
+type ArrayStrategy int
+
+const (
+	// ReplaceArrays uses the overlay array in place of the base array.
+	ReplaceArrays ArrayStrategy = iota
+	// AppendArrays appends the elements of the overlay array to the base
+	// array.
+	AppendArrays
+	// UnionArrays appends the elements of the overlay array that aren't
+	// Equal to an element already in the result.
+	UnionArrays
+)
+
+// MergeOptions controls how Merge combines values.
+type MergeOptions struct {
+	// Arrays is the strategy for arrays not listed in ArrayPaths.
+	Arrays ArrayStrategy
+	// ArrayPaths overrides the strategy for arrays at particular key paths.
+	// A "*" component matches any dictionary key or array index. If several
+	// paths match, the first in sorted order is used.
+	ArrayPaths map[string]ArrayStrategy
+}
+
+// Merge layers overlay over base and returns the result. Neither argument is
+// modified. Both must be values decoded into an interface{}, as for GetPath.
+//
+// Dictionaries are merged recursively: keys only in base are kept, keys only
+// in overlay are added, and keys in both have their values merged. Arrays are
+// combined according to opts, which may be nil to replace them. Any other
+// value in overlay replaces the value in base.
+//
+// If base is a *Dict, the result keeps its key order, with new keys appended
+// in the order they appear in overlay.
+func Merge(base, overlay interface{}, opts *MergeOptions) interface{} {
+	m := &merger{}
+	if opts != nil {
+		m.strategy = opts.Arrays
+		paths := make([]string, 0, len(opts.ArrayPaths))
+		for p := range opts.ArrayPaths {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			m.paths = append(m.paths, ParseKeyPath(p))
+			m.strategies = append(m.strategies, opts.ArrayPaths[p])
+		}
+	}
+	return m.merge(base, overlay, KeyPath{})
+}
+
+type merger struct {
+	strategy   ArrayStrategy
+	paths      []KeyPath
+	strategies []ArrayStrategy
+}
+
+func (m *merger) arrayStrategy(path KeyPath) ArrayStrategy {
+	for i, p := range m.paths {
+		if matchKeyPath(p, path, false) {
+			return m.strategies[i]
+		}
+	}
+	return m.strategy
+}
+
+func (m *merger) merge(base, overlay interface{}, path KeyPath) interface{} {
+	switch b := base.(type) {
+	case map[string]interface{}:
+		keys, values, ok := mergeEntries(overlay)
+		if !ok {
+			break
+		}
+		result := copyValue(b).(map[string]interface{})
+		for _, k := range keys {
+			if old, ok := b[k]; ok {
+				result[k] = m.merge(old, values[k], append(path, k))
+			} else {
+				result[k] = copyValue(values[k])
+			}
+		}
+		return result
+	case *Dict:
+		keys, values, ok := mergeEntries(overlay)
+		if !ok {
+			break
+		}
+		result := copyValue(b).(*Dict)
+		for _, k := range keys {
+			if old, ok := b.values[k]; ok {
+				result.Set(k, m.merge(old, values[k], append(path, k)))
+			} else {
+				result.Set(k, copyValue(values[k]))
+			}
+		}
+		return result
+	case []interface{}:
+		o, ok := overlay.([]interface{})
+		if !ok {
+			break
+		}
+		switch m.arrayStrategy(path) {
+		case AppendArrays:
+			return copyValue(append(append([]interface{}(nil), b...), o...))
+		case UnionArrays:
+			result := copyValue(b).([]interface{})
+			for _, elem := range o {
+				found := false
+				for _, existing := range result {
+					if Equal(existing, elem) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					result = append(result, copyValue(elem))
+				}
+			}
+			return result
+		}
+	}
+	return copyValue(overlay)
+}
+
+// mergeEntries returns the keys of overlay, in order if it's a *Dict, and its
+// values. ok is false if overlay isn't a dictionary.
+func mergeEntries(overlay interface{}) (keys []string, values map[string]interface{}, ok bool) {
+	switch o := overlay.(type) {
+	case map[string]interface{}:
+		keys = make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys, o, true
+	case *Dict:
+		return o.keys, o.values, true
+	}
+	return nil, nil, false
+}
+
+// String returns the name of the strategy.
+func (s ArrayStrategy) String() string {
+	switch s {
+	case ReplaceArrays:
+		return "replace"
+	case AppendArrays:
+		return "append"
+	case UnionArrays:
+		return "union"
+	}
+	return "ArrayStrategy(" + strconv.Itoa(int(s)) + ")"
+}
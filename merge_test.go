@@ -0,0 +1,72 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	base := map[string]interface{}{
+		"Name":  "vendor",
+		"Hosts": []interface{}{"a", "b"},
+		"Tags":  []interface{}{"x"},
+		"Env": map[string]interface{}{
+			"PATH": "/usr/bin",
+			"HOME": "/var/empty",
+		},
+	}
+	overlay := map[string]interface{}{
+		"Name":  "site",
+		"Hosts": []interface{}{"b", "c"},
+		"Tags":  []interface{}{"y"},
+		"Env":   map[string]interface{}{"HOME": "/Users/site"},
+		"Extra": true,
+	}
+	got := Merge(base, overlay, &MergeOptions{
+		Arrays:     AppendArrays,
+		ArrayPaths: map[string]ArrayStrategy{"Hosts": UnionArrays},
+	})
+	want := map[string]interface{}{
+		"Name":  "site",
+		"Hosts": []interface{}{"a", "b", "c"},
+		"Tags":  []interface{}{"x", "y"},
+		"Env": map[string]interface{}{
+			"PATH": "/usr/bin",
+			"HOME": "/Users/site",
+		},
+		"Extra": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v", got)
+	}
+	if base["Name"] != "vendor" || base["Env"].(map[string]interface{})["HOME"] != "/var/empty" {
+		t.Errorf("Merge modified base: %#v", base)
+	}
+
+	got = Merge(base, overlay, nil)
+	if hosts, _ := GetPath(got, "Hosts"); !reflect.DeepEqual(hosts, []interface{}{"b", "c"}) {
+		t.Errorf("default strategy: got %#v", hosts)
+	}
+}
+
+func TestMergeDict(t *testing.T) {
+	base := &Dict{}
+	base.Set("b", int64(1))
+	base.Set("a", int64(2))
+	overlay := &Dict{}
+	overlay.Set("z", int64(3))
+	overlay.Set("a", int64(4))
+	got, ok := Merge(base, overlay, nil).(*Dict)
+	if !ok {
+		t.Fatalf("got %T, want *Dict", got)
+	}
+	if !reflect.DeepEqual(got.Keys(), []string{"b", "a", "z"}) {
+		t.Errorf("got keys %v", got.Keys())
+	}
+	if v, _ := got.Get("a"); v != int64(4) {
+		t.Errorf("got a = %v", v)
+	}
+	if v, _ := base.Get("a"); v != int64(2) {
+		t.Error("Merge modified base")
+	}
+}
@@ -0,0 +1,295 @@
+// Package mobileconfig builds and parses configuration profiles
+// (.mobileconfig files), the payload envelopes installed on Apple devices by
+// hand or through MDM.
+//
+// A Profile holds the envelope keys and a list of payloads. The payload
+// specific keys are supplied as an ordinary map, so any payload type can be
+// described, including ones this package knows nothing about.
+package mobileconfig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	plist "github.com/kballard/go-osx-plist"
+	"github.com/kballard/go-osx-plist/internal/cms"
+)
+
+// A Profile is a configuration profile.
+type Profile struct {
+	// Identifier is the PayloadIdentifier of the profile, a reverse-DNS
+	// string such as "com.example.wifi". It is required. Installing a profile
+	// with the same identifier as an installed one replaces it.
+	Identifier string
+	// UUID is the PayloadUUID of the profile. If it is empty, a UUID derived
+	// from Identifier is used, so rebuilding the same profile yields the same
+	// UUID.
+	UUID              string
+	DisplayName       string
+	Description       string
+	Organization      string
+	Scope             string // "User" or "System"; empty means the platform default
+	RemovalDisallowed bool
+	Version           int // PayloadVersion; 0 means 1
+	Payloads          []Payload
+}
+
+// A Payload is one entry of a profile's PayloadContent.
+type Payload struct {
+	// Type is the PayloadType, e.g. "com.apple.wifi.managed". It is
+	// required.
+	Type string
+	// Identifier is the PayloadIdentifier. If it is empty, it is derived
+	// from the profile's identifier and the payload type.
+	Identifier string
+	// UUID is the PayloadUUID. If it is empty, a UUID derived from the
+	// payload identifier is used.
+	UUID         string
+	DisplayName  string
+	Description  string
+	Organization string
+	Version      int // PayloadVersion; 0 means 1
+	// Content holds the payload specific keys. Values may be anything that
+	// plist.Marshal accepts, including nested maps and structs.
+	Content map[string]interface{}
+}
+
+// envelopeKeys are the payload keys that Payload manages itself. Some payload
+// types have a PayloadContent key of their own, so it is not among them.
+var envelopeKeys = map[string]bool{
+	"PayloadType":         true,
+	"PayloadIdentifier":   true,
+	"PayloadUUID":         true,
+	"PayloadDisplayName":  true,
+	"PayloadDescription":  true,
+	"PayloadOrganization": true,
+	"PayloadVersion":      true,
+}
+
+// NewUUID returns a new random UUID in the uppercase form that Apple tools
+// use.
+func NewUUID() string {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		panic(err)
+	}
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return formatUUID(u)
+}
+
+// namespace is the name space of the UUIDs derived from payload identifiers.
+var namespace = [16]byte{0x3c, 0x4f, 0x8b, 0x5e, 0x0d, 0x2a, 0x4c, 0x61, 0x9b, 0x7e, 0x52, 0x1f, 0x6a, 0x80, 0xc3, 0xd7}
+
+// derivedUUID returns the name-based (version 5) UUID of identifier.
+func derivedUUID(identifier string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(identifier))
+	var u [16]byte
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50 // version 5
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return formatUUID(u)
+}
+
+func formatUUID(u [16]byte) string {
+	s := hex.EncodeToString(u[:])
+	return strings.ToUpper(s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:])
+}
+
+func setNonEmpty(dict map[string]interface{}, key, value string) {
+	if value != "" {
+		dict[key] = value
+	}
+}
+
+func version(v int) int {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+// Dict returns the profile as a property list dictionary, filling in
+// identifiers and UUIDs that were left empty. It returns an error if a
+// required key is missing, if two payloads have the same identifier or UUID,
+// or if a payload's Content has keys that belong to the envelope.
+func (p *Profile) Dict() (map[string]interface{}, error) {
+	if p.Identifier == "" {
+		return nil, errors.New("mobileconfig: profile has no identifier")
+	}
+	dict := map[string]interface{}{
+		"PayloadType":       "Configuration",
+		"PayloadIdentifier": p.Identifier,
+		"PayloadVersion":    version(p.Version),
+	}
+	if p.UUID != "" {
+		dict["PayloadUUID"] = p.UUID
+	} else {
+		dict["PayloadUUID"] = derivedUUID(p.Identifier)
+	}
+	setNonEmpty(dict, "PayloadDisplayName", p.DisplayName)
+	setNonEmpty(dict, "PayloadDescription", p.Description)
+	setNonEmpty(dict, "PayloadOrganization", p.Organization)
+	setNonEmpty(dict, "PayloadScope", p.Scope)
+	if p.RemovalDisallowed {
+		dict["PayloadRemovalDisallowed"] = true
+	}
+
+	identifiers := map[string]bool{p.Identifier: true}
+	uuids := map[string]bool{dict["PayloadUUID"].(string): true}
+	content := make([]interface{}, len(p.Payloads))
+	for i, payload := range p.Payloads {
+		if payload.Type == "" {
+			return nil, errors.New("mobileconfig: payload " + strconv.Itoa(i) + " has no type")
+		}
+		pd := make(map[string]interface{}, len(payload.Content)+8)
+		for k, v := range payload.Content {
+			if envelopeKeys[k] {
+				return nil, errors.New("mobileconfig: payload " + strconv.Itoa(i) + " sets envelope key " + k + " in its content")
+			}
+			pd[k] = v
+		}
+		id := payload.Identifier
+		if id == "" {
+			id = p.Identifier + "." + payload.Type
+			for n := 2; identifiers[id]; n++ {
+				id = p.Identifier + "." + payload.Type + "-" + strconv.Itoa(n)
+			}
+		} else if identifiers[id] {
+			return nil, errors.New("mobileconfig: duplicate payload identifier " + id)
+		}
+		identifiers[id] = true
+		uuid := payload.UUID
+		if uuid == "" {
+			uuid = derivedUUID(id)
+		}
+		if uuids[uuid] {
+			return nil, errors.New("mobileconfig: duplicate payload UUID " + uuid)
+		}
+		uuids[uuid] = true
+
+		pd["PayloadType"] = payload.Type
+		pd["PayloadIdentifier"] = id
+		pd["PayloadUUID"] = uuid
+		pd["PayloadVersion"] = version(payload.Version)
+		setNonEmpty(pd, "PayloadDisplayName", payload.DisplayName)
+		setNonEmpty(pd, "PayloadDescription", payload.Description)
+		setNonEmpty(pd, "PayloadOrganization", payload.Organization)
+		content[i] = pd
+	}
+	dict["PayloadContent"] = content
+	return dict, nil
+}
+
+// Marshal returns the unsigned profile serialized in the given format.
+// Profiles are conventionally XML.
+func (p *Profile) Marshal(format plist.Format) ([]byte, error) {
+	dict, err := p.Dict()
+	if err != nil {
+		return nil, err
+	}
+	return plist.Marshal(dict, format)
+}
+
+// A Signer wraps a serialized profile in a CMS signed-data message. This
+// package doesn't implement signing itself; a Signer typically calls out to a
+// PKCS #7 library, an HSM, or the security command.
+type Signer interface {
+	Sign(content []byte) ([]byte, error)
+}
+
+// The SignerFunc type is an adapter to allow the use of ordinary functions as
+// Signers.
+type SignerFunc func(content []byte) ([]byte, error)
+
+// Sign calls f(content).
+func (f SignerFunc) Sign(content []byte) ([]byte, error) {
+	return f(content)
+}
+
+// MarshalSigned returns the profile as XML, signed by s.
+func (p *Profile) MarshalSigned(s Signer) ([]byte, error) {
+	data, err := p.Marshal(plist.XMLFormat)
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(data)
+}
+
+// Parse parses a configuration profile, which may be signed. The signature is
+// not verified. The payload Content maps hold every key of the payload except
+// the envelope keys.
+func Parse(data []byte) (*Profile, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if !bytes.HasPrefix(trimmed, []byte("<")) && !bytes.HasPrefix(trimmed, []byte("bplist")) {
+		content, err := cms.Content(data)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	}
+	var dict map[string]interface{}
+	if _, err := plist.Unmarshal(data, &dict); err != nil {
+		return nil, err
+	}
+	if typ, _ := dict["PayloadType"].(string); typ != "Configuration" {
+		return nil, errors.New("mobileconfig: not a configuration profile")
+	}
+	p := &Profile{}
+	p.Identifier, _ = dict["PayloadIdentifier"].(string)
+	p.UUID, _ = dict["PayloadUUID"].(string)
+	p.DisplayName, _ = dict["PayloadDisplayName"].(string)
+	p.Description, _ = dict["PayloadDescription"].(string)
+	p.Organization, _ = dict["PayloadOrganization"].(string)
+	p.Scope, _ = dict["PayloadScope"].(string)
+	p.RemovalDisallowed, _ = dict["PayloadRemovalDisallowed"].(bool)
+	p.Version = intValue(dict["PayloadVersion"])
+	content, _ := dict["PayloadContent"].([]interface{})
+	for _, item := range content {
+		pd, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("mobileconfig: PayloadContent has an entry that isn't a dictionary")
+		}
+		var payload Payload
+		payload.Type, _ = pd["PayloadType"].(string)
+		payload.Identifier, _ = pd["PayloadIdentifier"].(string)
+		payload.UUID, _ = pd["PayloadUUID"].(string)
+		payload.DisplayName, _ = pd["PayloadDisplayName"].(string)
+		payload.Description, _ = pd["PayloadDescription"].(string)
+		payload.Organization, _ = pd["PayloadOrganization"].(string)
+		payload.Version = intValue(pd["PayloadVersion"])
+		payload.Content = make(map[string]interface{})
+		for k, v := range pd {
+			if !envelopeKeys[k] {
+				payload.Content[k] = v
+			}
+		}
+		p.Payloads = append(p.Payloads, payload)
+	}
+	return p, nil
+}
+
+// intValue returns the integer held by v, which may be any of the integer
+// types that plist.Unmarshal produces.
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package mobileconfig
+
+import (
+	"encoding/asn1"
+	"reflect"
+	"testing"
+)
+
+func testProfile() *Profile {
+	return &Profile{
+		Identifier:  "com.example.profile",
+		DisplayName: "Example",
+		Payloads: []Payload{
+			{Type: "com.apple.wifi.managed", Content: map[string]interface{}{"SSID_STR": "Office"}},
+			{Type: "com.apple.wifi.managed", Content: map[string]interface{}{"SSID_STR": "Guest"}},
+			{Type: "com.apple.dock", Identifier: "com.example.dock", Content: map[string]interface{}{
+				"persistent-apps": []interface{}{map[string]interface{}{"tile-type": "file-tile"}},
+			}},
+		},
+	}
+}
+
+func TestDict(t *testing.T) {
+	dict, err := testProfile().Dict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range dict["PayloadContent"].([]interface{}) {
+		ids = append(ids, p.(map[string]interface{})["PayloadIdentifier"].(string))
+	}
+	want := []string{"com.example.profile.com.apple.wifi.managed", "com.example.profile.com.apple.wifi.managed-2", "com.example.dock"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("got identifiers %v, want %v", ids, want)
+	}
+
+	again, err := testProfile().Dict()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dict, again) {
+		t.Error("derived UUIDs are not stable")
+	}
+	if uuid := dict["PayloadUUID"].(string); len(uuid) != 36 || uuid[14] != '5' {
+		t.Errorf("got profile UUID %q", uuid)
+	}
+
+	bad := testProfile()
+	bad.Payloads[0].Content["PayloadType"] = "oops"
+	if _, err := bad.Dict(); err == nil {
+		t.Error("expected error for envelope key in payload content")
+	}
+	bad = testProfile()
+	bad.Payloads[1].Identifier = "com.example.dock"
+	if _, err := bad.Dict(); err == nil {
+		t.Error("expected error for duplicate payload identifier")
+	}
+}
+
+// unsigned wraps content in a CMS signed-data message without any signers.
+func unsigned(content []byte) ([]byte, error) {
+	explicit := func(der []byte) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+	}
+	octets, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	type encapContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	sd, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo encapContentInfo
+		SignerInfos      asn1.RawValue
+	}{1, emptySet, encapContentInfo{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, explicit(octets)}, emptySet})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}, explicit(sd)})
+}
+
+func TestParseSigned(t *testing.T) {
+	in := testProfile()
+	data, err := in.MarshalSigned(SignerFunc(unsigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Identifier != in.Identifier || out.DisplayName != in.DisplayName || out.Version != 1 {
+		t.Errorf("got %#v", out)
+	}
+	if len(out.Payloads) != 3 {
+		t.Fatalf("got %d payloads, want 3", len(out.Payloads))
+	}
+	if !reflect.DeepEqual(out.Payloads[2].Content, in.Payloads[2].Content) {
+		t.Errorf("got content %#v, want %#v", out.Payloads[2].Content, in.Payloads[2].Content)
+	}
+}
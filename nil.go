@@ -0,0 +1,93 @@
+package plist
+
+import "reflect"
+
+// A NilPolicy says what an Encoder does with nil pointers and interface
+// values, which property lists have no way to represent.
+type NilPolicy int
+
+const (
+	// NilError makes Encode return an UnsupportedValueError for a nil
+	// pointer or interface value, as Marshal does. It is the default.
+	NilError NilPolicy = iota
+	// NilEmpty encodes a nil pointer as the empty value of the type it
+	// points to: an empty dictionary for structs and maps, an empty array
+	// for slices, and the zero value of anything else. A nil interface
+	// value, which has no type, encodes as the empty string.
+	NilEmpty
+	// NilOmit leaves nil values out of the structs, maps, Dicts and arrays
+	// that contain them, as if they weren't there. A nil value at the top
+	// level, or in a tuple, can't be left out, and is still an error.
+	NilOmit
+)
+
+// isNilValue reports whether v is a nil pointer or interface value, or a
+// pointer or interface leading to one.
+func isNilValue(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	return false
+}
+
+// omit reports whether v, an element of a container, is left out of it.
+func (state *streamState) omit(v reflect.Value) bool {
+	return state.nilPolicy == NilOmit && isNilValue(v)
+}
+
+// encodeNil writes v, a nil pointer or interface value, as the NilPolicy says.
+func (state *streamState) encodeNil(v reflect.Value) error {
+	if state.nilPolicy != NilEmpty {
+		if v.Kind() == reflect.Interface {
+			return &UnsupportedValueError{v, "nil interface"}
+		}
+		return &UnsupportedValueError{v, "nil pointer"}
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Interface:
+		return state.w.value("")
+	case isTupleType(t):
+		if err := state.w.beginDict(0); err != nil {
+			return err
+		}
+		return state.w.endDict()
+	}
+	return state.encodeValue(reflect.Zero(t))
+}
+
+// isNil reports whether v is a nil slice, map, pointer, interface, function
+// or channel.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// omitted reports whether v, the value of the field, is left out because of
+// the "omitempty", "omitzero" or "omitnil" options.
+func (ef *encodeField) omitted(v reflect.Value) bool {
+	return (ef.omitEmpty && isEmptyValue(v)) || (ef.omitZero && isZeroValue(v)) || (ef.omitNil && isNil(v))
+}
+
+// keepEmptySlice makes v, the value of a field with the "omitnil" option that
+// cfObj has been decoded into, an empty slice rather than nil if cfObj is an
+// empty array or data, which leave a slice alone, so that a nil field means
+// the key was absent.
+func keepEmptySlice(cfObj cfTypeRef, v reflect.Value) {
+	if v.Kind() != reflect.Slice || !v.IsNil() {
+		return
+	}
+	id := cfTypeID(cfObj)
+	if id == cfArrayTypeID || (id == cfDataTypeID && v.Type().Elem().Kind() == reflect.Uint8) {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+}
@@ -0,0 +1,198 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+type nilHolder struct {
+	Name     *string
+	Count    *int
+	Tags     *[]string
+	Child    *nilHolder
+	Extra    interface{}
+	Elements []*int
+	Values   map[string]interface{}
+}
+
+func encodeWithNilPolicy(t *testing.T, v interface{}, policy NilPolicy) ([]byte, error) {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, XMLFormat)
+	enc.SetNilPolicy(policy)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func TestNilPolicy(t *testing.T) {
+	one := 1
+	v := nilHolder{
+		Elements: []*int{&one, nil},
+		Values:   map[string]interface{}{"a": nil, "b": "x"},
+	}
+
+	var uve *UnsupportedValueError
+	if _, err := encodeWithNilPolicy(t, v, NilError); !errors.As(err, &uve) {
+		t.Errorf("NilError: got error %v, want an UnsupportedValueError", err)
+	}
+
+	data, err := encodeWithNilPolicy(t, v, NilEmpty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if tags, ok := got["Tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("NilEmpty: Tags: got %#v, want an empty array", got["Tags"])
+	}
+	if child, ok := got["Child"].(map[string]interface{}); !ok || len(child) != 0 {
+		t.Errorf("NilEmpty: Child: got %#v, want an empty dictionary", got["Child"])
+	}
+	delete(got, "Tags")
+	delete(got, "Child")
+	want := map[string]interface{}{
+		"Name":     "",
+		"Count":    int64(0),
+		"Extra":    "",
+		"Elements": []interface{}{int64(1), int64(0)},
+		"Values":   map[string]interface{}{"a": "", "b": "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NilEmpty: got %#v, want %#v", got, want)
+	}
+
+	data, err = encodeWithNilPolicy(t, v, NilOmit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]interface{}{
+		"Elements": []interface{}{int64(1)},
+		"Values":   map[string]interface{}{"b": "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NilOmit: got %#v, want %#v", got, want)
+	}
+
+	// a nil at the top level can't be omitted
+	if _, err := encodeWithNilPolicy(t, (*nilHolder)(nil), NilOmit); !errors.As(err, &uve) {
+		t.Errorf("NilOmit at the top level: got error %v, want an UnsupportedValueError", err)
+	}
+	if data, err := encodeWithNilPolicy(t, (*nilHolder)(nil), NilEmpty); err != nil || !bytes.Contains(data, []byte("<dict/>")) {
+		t.Errorf("NilEmpty at the top level: got %s, %v", data, err)
+	}
+}
+
+func TestNilPlaceholder(t *testing.T) {
+	one, two := 1, 2
+	entries := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+	entries["500"] = nil
+	v := map[string]interface{}{
+		"List":    []*int{&one, nil, &two},
+		"Entries": entries,
+	}
+	for _, policy := range []NilPolicy{NilError, NilEmpty, NilOmit} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf, XMLFormat)
+		enc.SetNilPolicy(policy)
+		enc.SetNilPlaceholder("missing")
+		err := enc.Encode(v)
+		if policy == NilError {
+			// the nil map value is still an error
+			var uve *UnsupportedValueError
+			if !errors.As(err, &uve) {
+				t.Errorf("NilError: got error %v, want an UnsupportedValueError", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			List    []interface{}
+			Entries map[string]interface{}
+		}
+		if _, err := Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if want := []interface{}{int64(1), "missing", int64(2)}; !reflect.DeepEqual(got.List, want) {
+			t.Errorf("policy %d: List: got %#v, want %#v", policy, got.List, want)
+		}
+		e, ok := got.Entries["500"]
+		if policy == NilOmit && (ok || len(got.Entries) != 999) {
+			t.Errorf("NilOmit: got %d entries, with 500 %v", len(got.Entries), e)
+		}
+		if policy == NilEmpty && e != "" {
+			t.Errorf("NilEmpty: got %#v for 500", e)
+		}
+	}
+}
+
+type patch struct {
+	Name  *string           `plist:",omitnil"`
+	Tags  []string          `plist:",omitnil"`
+	Attrs map[string]string `plist:",omitnil"`
+	Blob  []byte            `plist:",omitnil"`
+}
+
+func TestOmitNil(t *testing.T) {
+	empty := patch{Tags: []string{}, Attrs: map[string]string{}, Blob: []byte{}}
+	encode := NewTypedEncoder[patch]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		for _, v := range []patch{{}, empty} {
+			data, err := Marshal(v, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf, format).Encode(v); err != nil {
+				t.Fatal(err)
+			}
+			typed, err := encode(v, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, data := range [][]byte{data, buf.Bytes(), typed} {
+				// nil fields are left out, empty ones are there
+				var keys map[string]interface{}
+				if _, err := Unmarshal(data, &keys); err != nil {
+					t.Fatal(err)
+				}
+				want := 0
+				if v.Tags != nil {
+					want = 3
+				}
+				if len(keys) != want {
+					t.Errorf("%v: got keys %v for %#v", format, keys, v)
+				}
+
+				// and decode back the same way, nil or empty
+				var got patch
+				if _, err := Unmarshal(data, &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, v) {
+					t.Errorf("%v: got %#v, want %#v", format, got, v)
+				}
+				got = patch{}
+				if _, err := NewTypedDecoder[patch]().Decode(data, &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, v) {
+					t.Errorf("%v: TypedDecoder got %#v, want %#v", format, got, v)
+				}
+			}
+		}
+	}
+}
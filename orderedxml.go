@@ -0,0 +1,284 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A keyOrder records the order of dictionary keys in an XML property list.
+// Dictionaries and arrays have a keyOrder for each of their children that is
+// itself a dictionary or array; other values have a nil keyOrder. A nil
+// *keyOrder is valid and records nothing.
+type keyOrder struct {
+	keys     []string             // dictionary keys in document order
+	children map[string]*keyOrder // dictionary values
+	elems    []*keyOrder          // array elements
+}
+
+// key returns the keyOrder of the dictionary value for key.
+func (n *keyOrder) key(key string) *keyOrder {
+	if n == nil {
+		return nil
+	}
+	return n.children[key]
+}
+
+// index returns the keyOrder of the array element at i.
+func (n *keyOrder) index(i int) *keyOrder {
+	if n == nil || i >= len(n.elems) {
+		return nil
+	}
+	return n.elems[i]
+}
+
+// orderKeys sorts keys to match the order recorded in n. Keys that n doesn't
+// know about are sorted and placed at the end.
+func (n *keyOrder) orderKeys(keys []string) []string {
+	pos := make(map[string]int, len(keys))
+	if n != nil {
+		for i, k := range n.keys {
+			if _, ok := pos[k]; !ok {
+				pos[k] = i
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, iok := pos[keys[i]]
+		pj, jok := pos[keys[j]]
+		switch {
+		case iok && jok:
+			return pi < pj
+		case iok != jok:
+			return iok
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// scanKeyOrder records the key order of the XML property list in data. It
+// returns nil if data isn't an XML property list.
+func scanKeyOrder(data []byte) *keyOrder {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return nil
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			if se.Name.Local == "plist" {
+				continue
+			}
+			n, err := scanKeyOrderValue(d, se)
+			if err != nil {
+				return nil
+			}
+			return n
+		}
+	}
+}
+
+func scanKeyOrderValue(d *xml.Decoder, start xml.StartElement) (*keyOrder, error) {
+	var n *keyOrder
+	switch start.Name.Local {
+	case "dict":
+		n = &keyOrder{children: make(map[string]*keyOrder)}
+	case "array":
+		n = &keyOrder{}
+	default:
+		return nil, d.Skip()
+	}
+	var key string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if n.children != nil && t.Name.Local == "key" {
+				if err := d.DecodeElement(&key, &t); err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key)
+				continue
+			}
+			child, err := scanKeyOrderValue(d, t)
+			if err != nil {
+				return nil, err
+			}
+			if n.children != nil {
+				n.children[key] = child
+			} else {
+				n.elems = append(n.elems, child)
+			}
+		case xml.EndElement:
+			return n, nil
+		}
+	}
+}
+
+const xmlPlistHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+`
+
+// appendOrderedXML appends v, a tree of basic property list values that may
+// contain *Dict values, to b as an XML property list. The output matches the
+// layout CFPropertyListCreateData uses, except that *Dict keys keep their
+// order.
+func appendOrderedXML(b []byte, v interface{}) []byte {
+	b = append(b, xmlPlistHeader...)
+	b = appendXMLValue(b, v, 0)
+	return append(b, "</plist>\n"...)
+}
+
+func appendXMLIndent(b []byte, indent int) []byte {
+	for i := 0; i < indent; i++ {
+		b = append(b, '\t')
+	}
+	return b
+}
+
+func appendXMLText(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			b = append(b, "&amp;"...)
+		case '<':
+			b = append(b, "&lt;"...)
+		case '>':
+			b = append(b, "&gt;"...)
+		default:
+			b = append(b, s[i])
+		}
+	}
+	return b
+}
+
+func appendXMLElement(b []byte, name, text string) []byte {
+	b = append(b, '<')
+	b = append(b, name...)
+	b = append(b, '>')
+	b = appendXMLText(b, text)
+	b = append(b, "</"...)
+	b = append(b, name...)
+	return append(b, ">\n"...)
+}
+
+func appendXMLEntries(b []byte, keys []string, value func(string) interface{}, indent int) []byte {
+	if len(keys) == 0 {
+		return append(b, "<dict/>\n"...)
+	}
+	b = append(b, "<dict>\n"...)
+	for _, k := range keys {
+		b = appendXMLIndent(b, indent+1)
+		b = appendXMLElement(b, "key", k)
+		b = appendXMLIndent(b, indent+1)
+		b = appendXMLValue(b, value(k), indent+1)
+	}
+	b = appendXMLIndent(b, indent)
+	return append(b, "</dict>\n"...)
+}
+
+// appendXMLValue appends v at the given indentation level. The caller has
+// already written the indentation for the first line.
+func appendXMLValue(b []byte, v interface{}, indent int) []byte {
+	switch v := v.(type) {
+	case *Dict:
+		return appendXMLEntries(b, v.keys, func(k string) interface{} { return v.values[k] }, indent)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return appendXMLEntries(b, keys, func(k string) interface{} { return v[k] }, indent)
+	case []interface{}:
+		if len(v) == 0 {
+			return append(b, "<array/>\n"...)
+		}
+		b = append(b, "<array>\n"...)
+		for _, elem := range v {
+			b = appendXMLIndent(b, indent+1)
+			b = appendXMLValue(b, elem, indent+1)
+		}
+		b = appendXMLIndent(b, indent)
+		return append(b, "</array>\n"...)
+	case string:
+		return appendXMLElement(b, "string", v)
+	case bool:
+		if v {
+			return append(b, "<true/>\n"...)
+		}
+		return append(b, "<false/>\n"...)
+	case int8:
+		return appendXMLElement(b, "integer", strconv.FormatInt(int64(v), 10))
+	case int16:
+		return appendXMLElement(b, "integer", strconv.FormatInt(int64(v), 10))
+	case int32:
+		return appendXMLElement(b, "integer", strconv.FormatInt(int64(v), 10))
+	case int64:
+		return appendXMLElement(b, "integer", strconv.FormatInt(v, 10))
+	case float32:
+		return appendXMLElement(b, "real", formatXMLReal(float64(v)))
+	case float64:
+		return appendXMLElement(b, "real", formatXMLReal(v))
+	case time.Time:
+		return appendXMLElement(b, "date", v.UTC().Format("2006-01-02T15:04:05Z"))
+	case Date:
+		return appendXMLElement(b, "date", v.Time().UTC().Format("2006-01-02T15:04:05Z"))
+	case UID:
+		b = append(b, "<dict>\n"...)
+		b = appendXMLIndent(b, indent+1)
+		b = appendXMLElement(b, "key", "CF$UID")
+		b = appendXMLIndent(b, indent+1)
+		b = appendXMLElement(b, "integer", strconv.FormatUint(uint64(v), 10))
+		b = appendXMLIndent(b, indent)
+		return append(b, "</dict>\n"...)
+	case []byte:
+		b = append(b, "<data>\n"...)
+		// CoreFoundation wraps base64 data at 76 characters, less 8 for
+		// each level of indentation, down to a minimum of 12 characters.
+		lineLen := 76 - 8*indent
+		if indent >= 8 {
+			lineLen = 12
+		}
+		lineLen -= lineLen % 4
+		enc := base64.StdEncoding.EncodeToString(v)
+		for len(enc) > 0 {
+			n := lineLen
+			if n > len(enc) {
+				n = len(enc)
+			}
+			b = appendXMLIndent(b, indent)
+			b = append(b, enc[:n]...)
+			b = append(b, '\n')
+			enc = enc[n:]
+		}
+		b = appendXMLIndent(b, indent)
+		return append(b, "</data>\n"...)
+	}
+	// appendOrderedXML is only given values converted from CoreFoundation
+	panic("plist: unexpected type in appendOrderedXML")
+}
+
+func formatXMLReal(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "+infinity"
+	case math.IsInf(f, -1):
+		return "-infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
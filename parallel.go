@@ -0,0 +1,165 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+var parallelThreshold int64
+
+// SetParallelThreshold sets the length from which arrays are converted on
+// multiple goroutines: Marshal converts the elements to CoreFoundation objects,
+// and Unmarshal converts them to Go values, in one chunk per processor (as set
+// by GOMAXPROCS), and the result is assembled once all chunks are done. A
+// threshold of 0, the default, disables parallel conversion. It is safe to
+// call concurrently with Marshal and Unmarshal.
+//
+// The elements of a parallel array are converted concurrently, so the
+// MarshalPlist and UnmarshalPlist methods (and their CF counterparts) of their
+// types must be safe to call concurrently. Errors are reported as they are
+// without parallel conversion. Slices of the types that are converted in a
+// single cgo call, such as []int64 and []string, are never split up.
+func SetParallelThreshold(n int) {
+	atomic.StoreInt64(&parallelThreshold, int64(n))
+}
+
+// ParallelThreshold returns the threshold set by SetParallelThreshold.
+func ParallelThreshold() int {
+	return int(atomic.LoadInt64(&parallelThreshold))
+}
+
+// parallelChunks returns the number of chunks an array of n elements is split
+// into, or 0 if it should be converted serially.
+func parallelChunks(n int) int {
+	threshold := atomic.LoadInt64(&parallelThreshold)
+	if threshold <= 0 || int64(n) < threshold {
+		return 0
+	}
+	chunks := runtime.GOMAXPROCS(0)
+	if chunks > n {
+		chunks = n
+	}
+	if chunks < 2 {
+		return 0
+	}
+	return chunks
+}
+
+// runChunks splits n elements into chunks ranges of nearly equal size and
+// calls f for each on its own goroutine, returning once they have all
+// returned. If f panics, runChunks panics with a PanicError on the calling
+// goroutine, where the panic can be recovered.
+func runChunks(n, chunks int, f func(chunk, start, end int)) {
+	var wg sync.WaitGroup
+	panics := make([]*PanicError, chunks)
+	wg.Add(chunks)
+	for c := 0; c < chunks; c++ {
+		go func(c int) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panics[c] = asPanicError(r)
+				}
+			}()
+			f(c, c*n/chunks, (c+1)*n/chunks)
+		}(c)
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+}
+
+// marshalArray converts v, a slice or array, to a CFArray, in parallel if it
+// is long enough. Each chunk has its own marshalState, whose dictionary key
+// orders are merged into state afterwards.
+func (state *marshalState) marshalArray(v reflect.Value) (C.CFArrayRef, error) {
+	n := v.Len()
+	chunks := parallelChunks(n)
+	if chunks == 0 {
+		return convertSliceToCFArrayHelper(v, state.marshalValue)
+	}
+	plists := make([]cfTypeRef, n)
+	defer state.release(plists)
+	workers := make([]marshalState, chunks)
+	errs := make([]error, chunks)
+	runChunks(n, chunks, func(c, start, end int) {
+		w := &workers[c]
+		w.pinner = state.pinner
+		w.ctx = state.ctx
+		if state.pool != nil {
+			w.pool = new(releasePool)
+			defer w.pool.drain()
+		}
+		for i := start; i < end; i++ {
+			cfObj, err := w.marshalValue(v.Index(i))
+			if err != nil {
+				errs[c] = err
+				return
+			}
+			plists[i] = cfObj
+		}
+	})
+	for c := range workers {
+		for cfDict, keys := range workers[c].dictKeys {
+			if state.dictKeys == nil {
+				state.dictKeys = make(map[cfTypeRef][]string)
+			}
+			state.dictKeys[cfDict] = keys
+		}
+	}
+	// the first error is the one a serial conversion would have stopped at
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return createCFArray(plists), nil
+}
+
+// unmarshalArray stores the elements of cfArray in a new slice of type vType,
+// set with vSetter, converting them in parallel. It returns false without
+// doing anything if the array is too short to be worth it.
+func (state *unmarshalState) unmarshalArray(cfArray C.CFArrayRef, vSetter reflect.Value, vType reflect.Type) (bool, error) {
+	count := int(C.CFArrayGetCount(cfArray))
+	chunks := parallelChunks(count)
+	if chunks == 0 {
+		return false, nil
+	}
+	elems := make([]cfTypeRef, count)
+	C.CFArrayGetValues(cfArray, C.CFRange{0, C.CFIndex(count)}, (*unsafe.Pointer)(&elems[0]))
+	slice := reflect.MakeSlice(vType, count, count)
+	vSetter.Set(slice)
+	workers := make([]unmarshalState, chunks)
+	errs := make([]error, chunks)
+	runChunks(count, chunks, func(c, start, end int) {
+		w := &workers[c]
+		w.decodeOptions = state.decodeOptions
+		w.ctx = state.ctx
+		for i := start; i < end; i++ {
+			w.order = state.order.index(i)
+			if err := w.unmarshalValue(elems[i], slice.Index(i)); err != nil {
+				errs[c] = err
+				return
+			}
+		}
+	})
+	// merge the errors in the order a serial conversion would have found them
+	for c := range workers {
+		if workers[c].err != nil {
+			state.recordError(workers[c].err)
+		}
+		if errs[c] != nil {
+			return true, errs[c]
+		}
+	}
+	return true, nil
+}
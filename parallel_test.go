@@ -0,0 +1,109 @@
+package plist
+
+import (
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// withParallelism runs f with parallel conversion of arrays of at least
+// threshold elements on 4 processors.
+func withParallelism(threshold int, f func()) {
+	defer SetParallelThreshold(ParallelThreshold())
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	SetParallelThreshold(threshold)
+	f()
+}
+
+func TestParallelRoundTrip(t *testing.T) {
+	type item struct {
+		Name  string
+		Index int
+	}
+	items := make([]item, 1000)
+	mixed := make([]interface{}, 1000)
+	for i := range items {
+		items[i] = item{strconv.Itoa(i), i}
+		d := &Dict{}
+		d.Set("z", i)
+		d.Set("a", "x")
+		mixed[i] = d
+	}
+	withParallelism(10, func() {
+		for _, format := range []Format{XMLFormat, BinaryFormat} {
+			data, err := Marshal(items, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []item
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(items) {
+				t.Fatalf("%v: got %d items, want %d", format, len(got), len(items))
+			}
+			for i := range got {
+				if got[i] != items[i] {
+					t.Fatalf("%v: item %d: got %v, want %v", format, i, got[i], items[i])
+				}
+			}
+		}
+
+		// key order is kept across chunks
+		data, err := Marshal(mixed, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "<key>z</key>\n\t\t<integer>"); n != len(mixed) {
+			t.Errorf("got %d dictionaries in order, want %d", n, len(mixed))
+		}
+		dec := NewDecoder(strings.NewReader(string(data)))
+		dec.UseOrderedDicts()
+		var got []interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(got, mixed) {
+			t.Error("ordered round trip changed the value")
+		}
+		if d, ok := got[999].(*Dict); !ok || d.Keys()[0] != "z" {
+			t.Errorf("got %#v, want a *Dict starting with key z", got[999])
+		}
+	})
+}
+
+func TestParallelErrors(t *testing.T) {
+	withParallelism(10, func() {
+		values := make([]interface{}, 100)
+		for i := range values {
+			values[i] = float64(i)
+		}
+		values[90] = math.NaN()
+		values[30] = math.Inf(1)
+		_, err := Marshal(values, BinaryFormat)
+		if e, ok := err.(*UnsupportedValueError); !ok || e.Str != "+Inf" {
+			t.Errorf("got error %v, want the one for element 30", err)
+		}
+
+		ints := make([]int64, 100)
+		for i := range ints {
+			ints[i] = int64(i)
+		}
+		ints[80] = 1000
+		ints[20] = 300
+		data, err := Marshal(ints, BinaryFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var small []int8
+		_, err = Unmarshal(data, &small)
+		if e, ok := err.(*UnmarshalTypeError); !ok || e.Value != "CFNumber 300" {
+			t.Errorf("got error %v, want the one for element 20", err)
+		}
+		if len(small) != 100 || small[99] != 99 {
+			t.Errorf("got %v, want the other elements converted", small)
+		}
+	})
+}
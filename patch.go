@@ -0,0 +1,93 @@
+package plist
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrPatchConflict is wrapped by a PatchError when the value at a change's
+// path doesn't match the change's Old value.
+var ErrPatchConflict = errors.New("current value does not match")
+
+// A PatchError describes the change that could not be applied by ApplyPatch.
+type PatchError struct {
+	Index  int // index of the change in the patch
+	Change Change
+	Err    error
+}
+
+func (e *PatchError) Error() string {
+	return "plist: patch change " + strconv.Itoa(e.Index) + " (" + e.Change.Op.String() + " " +
+		strconv.Quote(e.Change.Path.String()) + "): " + e.Err.Error()
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch applies the changes in patch, in order, to a copy of v and
+// returns the result. v must be a value decoded into an interface{}, as for
+// GetPath. v itself is never modified, so either every change is applied or
+// none is.
+//
+// Each change behaves like the corresponding key path function, with stricter
+// checks suited to applying a Diff:
+//
+//   - Add inserts New as AddPath does, but the parent of Path must already
+//     exist
+//   - Remove deletes the entry at Path
+//   - Replace stores New at Path, which must already exist
+//
+// If a Remove or Replace change has a non-nil Old, the current value must be
+// Equal to it, or ApplyPatch fails with ErrPatchConflict. This catches patches
+// computed against a different version of v.
+func ApplyPatch(v interface{}, patch Patch) (interface{}, error) {
+	v = copyValue(v)
+	for i, change := range patch {
+		var err error
+		v, err = applyChange(v, change)
+		if err != nil {
+			return nil, &PatchError{i, change, err}
+		}
+	}
+	return v, nil
+}
+
+// CheckPatch reports whether ApplyPatch would succeed, without returning the
+// result. It is useful as a dry run before committing to a change.
+func CheckPatch(v interface{}, patch Patch) error {
+	_, err := ApplyPatch(v, patch)
+	return err
+}
+
+func applyChange(v interface{}, change Change) (interface{}, error) {
+	p := change.Path
+	switch change.Op {
+	case Add:
+		if len(p) > 0 {
+			parent, err := getPath(v, p[:len(p)-1])
+			if err != nil {
+				return nil, err
+			}
+			switch parent.(type) {
+			case map[string]interface{}, *Dict, []interface{}:
+			default:
+				return nil, &KeyPathError{p[:len(p)-1], ErrNotContainer}
+			}
+		}
+		return addPath(v, p, change.New)
+	case Remove, Replace:
+		current, err := getPath(v, p)
+		if err != nil {
+			return nil, err
+		}
+		if change.Old != nil && !Equal(current, change.Old) {
+			return nil, ErrPatchConflict
+		}
+		if change.Op == Remove {
+			return deletePath(v, p)
+		}
+		return setPath(v, p, change.New)
+	}
+	return nil, errors.New("unknown operation " + change.Op.String())
+}
@@ -0,0 +1,61 @@
+package plist
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	a := map[string]interface{}{
+		"Name":    "old",
+		"Removed": true,
+		"Items":   []interface{}{"a", "b", "c"},
+		"Nested":  map[string]interface{}{"x": int64(1)},
+	}
+	b := map[string]interface{}{
+		"Name":   "new",
+		"Added":  []byte("hi"),
+		"Items":  []interface{}{"a", "B"},
+		"Nested": map[string]interface{}{"x": int64(1), "y": int64(2)},
+	}
+	patch := Diff(a, b)
+	got, err := ApplyPatch(a, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(got, b) {
+		t.Errorf("got %#v, want %#v", got, b)
+	}
+	if a["Name"] != "old" || len(a["Items"].([]interface{})) != 3 {
+		t.Errorf("ApplyPatch modified its input: %#v", a)
+	}
+	// the patch no longer applies to its own result
+	if err := CheckPatch(got, patch); !errors.Is(err, ErrPatchConflict) && !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	v := map[string]interface{}{"Name": "x", "List": []interface{}{}}
+	tests := []struct {
+		change Change
+		want   error
+	}{
+		{Change{Op: Replace, Path: KeyPath{"Name"}, Old: "y", New: "z"}, ErrPatchConflict},
+		{Change{Op: Remove, Path: KeyPath{"Missing"}}, ErrPathNotFound},
+		{Change{Op: Add, Path: KeyPath{"Name"}, New: "z"}, ErrPathExists},
+		{Change{Op: Add, Path: KeyPath{"Missing", "Key"}, New: "z"}, ErrPathNotFound},
+		{Change{Op: Add, Path: KeyPath{"Name", "Key"}, New: "z"}, ErrNotContainer},
+		{Change{Op: Add, Path: KeyPath{"List", "1"}, New: "z"}, ErrPathNotFound},
+	}
+	for _, test := range tests {
+		err := CheckPatch(v, Patch{test.change})
+		var pe *PatchError
+		if !errors.As(err, &pe) || !errors.Is(err, test.want) {
+			t.Errorf("%v %v: got error %v, want %v", test.change.Op, test.change.Path, err, test.want)
+		}
+	}
+	if err := CheckPatch(v, Patch{{Op: Replace, Path: KeyPath{"Name"}, Old: "x", New: "y"}}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -17,41 +17,99 @@ package plist
 // #cgo LDFLAGS: -framework CoreFoundation
 // #include <CoreFoundation/CoreFoundation.h>
 import "C"
-import "errors"
+import (
+	"bytes"
+	"errors"
+)
 
 // TODO: CFPropertyListWrite() for stream-based writing
 // TODO: CFPropertyListCreateWithStream() for stream-based reading
 
 func cfPropertyListCreateWithData(data []byte) (cfObj cfTypeRef, format Format, err error) {
 	cfData := convertBytesToCFData(data)
-	defer C.CFRelease(C.CFTypeRef(cfData))
+	defer cfRelease(cfTypeRef(cfData))
+	return cfPropertyListCreateWithCFData(cfData)
+}
+
+// cfPropertyListCreateWithBytesNoCopy is like cfPropertyListCreateWithData,
+// but parses data in place. data must not be Go memory, and must not change
+// until it returns. The objects it creates don't refer to data.
+func cfPropertyListCreateWithBytesNoCopy(data []byte) (cfObj cfTypeRef, format Format, err error) {
+	if len(data) == 0 {
+		return cfPropertyListCreateWithData(data)
+	}
+	cfData := cfCreated(C.CFDataCreateWithBytesNoCopy(nil, (*C.UInt8)(&data[0]), C.CFIndex(len(data)), C.kCFAllocatorNull))
+	defer cfRelease(cfTypeRef(cfData))
+	return cfPropertyListCreateWithCFData(cfData)
+}
+
+func cfPropertyListCreateWithCFData(cfData C.CFDataRef) (cfObj cfTypeRef, format Format, err error) {
+	if CurrentBackend() == FoundationBackend {
+		return foundationPropertyListCreateWithCFData(cfData)
+	}
 	var cfFormat C.CFPropertyListFormat
 	var cfError C.CFErrorRef
 	cfPlist := C.CFPropertyListCreateWithData(nil, cfData, 0, &cfFormat, &cfError)
 	if cfPlist == nil {
 		// an error occurred
 		if cfError != nil {
+			cfCreated(cfError)
 			defer cfRelease(cfTypeRef(cfError))
 			return nil, Format{cfFormat}, NewCFError(cfError)
 		}
 		return nil, Format{}, errors.New("plist: unknown error in CFPropertyListCreateWithData")
 	}
+	cfTrack(cfTypeRef(cfPlist))
 	return cfTypeRef(cfPlist), Format{cfFormat}, nil
 }
 
-func cfPropertyListCreateData(plist cfTypeRef, format Format) ([]byte, error) {
+// cfNestedPropertyList parses the contents of cfData as a property list if it
+// starts with a binary or XML property list header. It returns nil if the data
+// doesn't look like a property list or fails to parse.
+func cfNestedPropertyList(cfData C.CFDataRef) cfTypeRef {
+	if !hasPropertyListHeader(convertCFDataPrefixToBytes(cfData, 64)) {
+		return nil
+	}
+	cfObj, _, err := cfPropertyListCreateWithCFData(cfData)
+	if err != nil {
+		return nil
+	}
+	return cfObj
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// hasPropertyListHeader reports whether data begins like a binary or XML
+// property list.
+func hasPropertyListHeader(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return true
+	}
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	return bytes.HasPrefix(data, []byte("<?xml")) ||
+		bytes.HasPrefix(data, []byte("<!DOCTYPE plist")) ||
+		bytes.HasPrefix(data, []byte("<plist"))
+}
+
+// appendCFPropertyListData appends the encoding of plist in the given format
+// to dst.
+func appendCFPropertyListData(dst []byte, plist cfTypeRef, format Format) ([]byte, error) {
+	if CurrentBackend() == FoundationBackend {
+		return appendFoundationPropertyListData(dst, plist, format)
+	}
 	var cfError C.CFErrorRef
-	cfData := C.CFPropertyListCreateData(nil, C.CFPropertyListRef(plist), format.cfFormat, 0, &cfError)
+	cfData := cfCreated(C.CFPropertyListCreateData(nil, C.CFPropertyListRef(plist), format.cfFormat, 0, &cfError))
 	if cfData == nil {
 		// an error occurred
 		if cfError != nil {
+			cfCreated(cfError)
 			defer cfRelease(cfTypeRef(cfError))
 			return nil, NewCFError(cfError)
 		}
 		return nil, errors.New("plist: unknown error in CFPropertyListCreateData")
 	}
 	defer cfRelease(cfTypeRef(cfData))
-	return convertCFDataToBytes(cfData), nil
+	return appendCFDataBytes(dst, cfData), nil
 }
 
 type CFError struct {
@@ -66,14 +124,14 @@ func NewCFError(c C.CFErrorRef) *CFError {
 		Domain: convertCFStringToString(C.CFStringRef(C.CFErrorGetDomain(c))),
 		Code:   int(C.CFErrorGetCode(c)),
 	}
-	cfDict := C.CFErrorCopyUserInfo(c)
-	defer C.CFRelease(C.CFTypeRef(cfDict))
-	if userInfo, err := convertCFDictionaryToMap(cfDict); err == nil {
+	cfDict := cfCreated(C.CFErrorCopyUserInfo(c))
+	defer cfRelease(cfTypeRef(cfDict))
+	if userInfo, err := convertCFDictionaryToMap(cfDict, true); err == nil {
 		// on error, skip user info
 		e.UserInfo = userInfo
 	}
-	cfStr := C.CFErrorCopyDescription(c)
-	defer C.CFRelease(C.CFTypeRef(cfStr))
+	cfStr := cfCreated(C.CFErrorCopyDescription(c))
+	defer cfRelease(cfTypeRef(cfStr))
 	e.Description = convertCFStringToString(cfStr)
 	return e
 }
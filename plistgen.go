@@ -0,0 +1,207 @@
+package plist
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The Decode functions convert a property list value, as given to
+// UnmarshalPlist, to a Go type following the rules Unmarshal uses. They are
+// used by the UnmarshalPlist methods that cmd/plistgen generates, and return
+// an *UnmarshalTypeError if the value doesn't fit.
+
+var (
+	intTypes = map[int]reflect.Type{
+		0:  reflect.TypeOf(int(0)),
+		8:  reflect.TypeOf(int8(0)),
+		16: reflect.TypeOf(int16(0)),
+		32: reflect.TypeOf(int32(0)),
+		64: reflect.TypeOf(int64(0)),
+	}
+	uintTypes = map[int]reflect.Type{
+		0:  reflect.TypeOf(uint(0)),
+		8:  reflect.TypeOf(uint8(0)),
+		16: reflect.TypeOf(uint16(0)),
+		32: reflect.TypeOf(uint32(0)),
+		64: reflect.TypeOf(uint64(0)),
+	}
+	floatTypes = map[int]reflect.Type{
+		32: reflect.TypeOf(float32(0)),
+		64: reflect.TypeOf(float64(0)),
+	}
+)
+
+// plistValueName describes v as UnmarshalTypeError does.
+func plistValueName(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "CFString"
+	case bool:
+		return "CFBoolean"
+	case []byte:
+		return "CFData"
+	case time.Time:
+		return "CFDate"
+	case []interface{}:
+		return "CFArray"
+	case map[string]interface{}, *Dict:
+		return "CFDictionary"
+	case UID:
+		return "CFKeyedArchiverUID"
+	}
+	if _, ok := numberValue(v); ok {
+		return "CFNumber"
+	}
+	return "unknown value"
+}
+
+// numberValue returns the number v as a float64, and false if v isn't one of
+// the numeric types FromCFType returns.
+func numberValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// intValue returns the number v as an int64, truncating floats as
+// CFNumberGetValue does.
+func intValue(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case float32:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// DecodeInt converts a number to a signed integer of the given size in bits,
+// or of the size of int if bits is 0. Floats are truncated.
+func DecodeInt(v interface{}, bits int) (int64, error) {
+	i, ok := intValue(v)
+	if !ok {
+		return 0, &UnmarshalTypeError{plistValueName(v), intTypes[bits]}
+	}
+	size := bits
+	if size == 0 {
+		size = strconv.IntSize
+	}
+	if size < 64 && (i < -1<<uint(size-1) || i >= 1<<uint(size-1)) {
+		return 0, &UnmarshalTypeError{"CFNumber " + strconv.FormatInt(i, 10), intTypes[bits]}
+	}
+	return i, nil
+}
+
+// DecodeUint converts a number to an unsigned integer of the given size in
+// bits, or of the size of uint if bits is 0. Like Unmarshal, it only keeps
+// the low 32 bits of the number.
+func DecodeUint(v interface{}, bits int) (uint64, error) {
+	i, ok := intValue(v)
+	if !ok {
+		return 0, &UnmarshalTypeError{plistValueName(v), uintTypes[bits]}
+	}
+	u := uint64(uint32(i))
+	if bits != 0 && bits < 32 && u >= 1<<uint(bits) {
+		return 0, &UnmarshalTypeError{"CFNumber " + strconv.FormatUint(u, 10), uintTypes[bits]}
+	}
+	return u, nil
+}
+
+// DecodeFloat converts a number to a float of the given size in bits.
+func DecodeFloat(v interface{}, bits int) (float64, error) {
+	f, ok := numberValue(v)
+	if !ok {
+		return 0, &UnmarshalTypeError{plistValueName(v), floatTypes[bits]}
+	}
+	if bits == 32 && !math.IsInf(f, 0) && math.Abs(f) > math.MaxFloat32 {
+		return 0, &UnmarshalTypeError{"CFNumber " + strconv.FormatFloat(f, 'f', -1, 64), floatTypes[bits]}
+	}
+	return f, nil
+}
+
+// DecodeString converts a string value.
+func DecodeString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", &UnmarshalTypeError{plistValueName(v), stringType}
+	}
+	return s, nil
+}
+
+// DecodeBool converts a boolean value.
+func DecodeBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, &UnmarshalTypeError{plistValueName(v), reflect.TypeOf(false)}
+	}
+	return b, nil
+}
+
+// DecodeData converts a data value.
+func DecodeData(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, &UnmarshalTypeError{plistValueName(v), byteSliceType}
+	}
+	return b, nil
+}
+
+// DecodeDate converts a date value.
+func DecodeDate(v interface{}) (time.Time, error) {
+	t, ok := v.(time.Time)
+	if !ok {
+		return time.Time{}, &UnmarshalTypeError{plistValueName(v), timeType}
+	}
+	return t, nil
+}
+
+// DecodeArray converts an array value.
+func DecodeArray(v interface{}) ([]interface{}, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil, &UnmarshalTypeError{plistValueName(v), reflect.TypeOf(a)}
+	}
+	return a, nil
+}
+
+// DecodeDict converts a dictionary value. A *Dict is converted with its Map
+// method.
+func DecodeDict(v interface{}) (map[string]interface{}, error) {
+	switch d := v.(type) {
+	case map[string]interface{}:
+		return d, nil
+	case *Dict:
+		return d.Map(), nil
+	}
+	return nil, &UnmarshalTypeError{plistValueName(v), interfaceMapType}
+}
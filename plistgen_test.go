@@ -0,0 +1,51 @@
+package plist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeFunctions(t *testing.T) {
+	if i, err := DecodeInt(int16(-300), 16); err != nil || i != -300 {
+		t.Errorf("DecodeInt: got %d, %v", i, err)
+	}
+	if i, err := DecodeInt(float64(2.7), 0); err != nil || i != 2 {
+		t.Errorf("DecodeInt(2.7): got %d, %v", i, err)
+	}
+	if _, err := DecodeInt(int64(300), 8); err == nil || err.Error() != "plist: cannot unmarshal CFNumber 300 into Go value of type int8" {
+		t.Errorf("DecodeInt overflow: got error %v", err)
+	}
+	if _, err := DecodeInt("1", 64); err == nil || err.Error() != "plist: cannot unmarshal CFString into Go value of type int64" {
+		t.Errorf("DecodeInt(string): got error %v", err)
+	}
+	if u, err := DecodeUint(int64(1<<32+5), 32); err != nil || u != 5 {
+		t.Errorf("DecodeUint: got %d, %v", u, err)
+	}
+	if _, err := DecodeUint(int64(256), 8); err == nil {
+		t.Error("DecodeUint overflow: got no error")
+	}
+	if _, err := DecodeFloat(float64(1e300), 32); err == nil {
+		t.Error("DecodeFloat overflow: got no error")
+	}
+	if s, err := DecodeString("x"); err != nil || s != "x" {
+		t.Errorf("DecodeString: got %q, %v", s, err)
+	}
+	if _, err := DecodeBool(int64(1)); err == nil || err.Error() != "plist: cannot unmarshal CFNumber into Go value of type bool" {
+		t.Errorf("DecodeBool(int64): got error %v", err)
+	}
+	if _, err := DecodeData([]interface{}{}); err == nil || err.Error() != "plist: cannot unmarshal CFArray into Go value of type []uint8" {
+		t.Errorf("DecodeData(array): got error %v", err)
+	}
+	now := time.Now()
+	if d, err := DecodeDate(now); err != nil || !d.Equal(now) {
+		t.Errorf("DecodeDate: got %v, %v", d, err)
+	}
+	if a, err := DecodeArray([]interface{}{"a"}); err != nil || len(a) != 1 {
+		t.Errorf("DecodeArray: got %v, %v", a, err)
+	}
+	d := &Dict{}
+	d.Set("a", int64(1))
+	if m, err := DecodeDict(d); err != nil || m["a"] != int64(1) {
+		t.Errorf("DecodeDict(*Dict): got %v, %v", m, err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package prefs reads and writes macOS preferences domains (the "defaults"
+// system) using CFPreferences.
+//
+// Values are converted with the same rules as plist.Marshal and
+// plist.Unmarshal, so the structs used to read and write property list files
+// can be used with preferences domains as well. Going through CFPreferences
+// instead of editing the property list files directly means changes are
+// coordinated with cfprefsd and visible to running applications.
+package prefs
+
+// #cgo LDFLAGS: -framework CoreFoundation
+// #include <CoreFoundation/CoreFoundation.h>
+//
+// static CFStringRef prefsCreateString(const char *bytes, CFIndex len) {
+//     return CFStringCreateWithBytes(NULL, (const UInt8 *)bytes, len, kCFStringEncodingUTF8, false);
+// }
+import "C"
+
+import (
+	"errors"
+	"unicode/utf8"
+	"unsafe"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// GlobalDomain is the application ID of the global domain, which holds
+// preferences shared by all applications (NSGlobalDomain).
+const GlobalDomain = ".GlobalPreferences"
+
+// ErrNotFound is returned by Get when the key has no value.
+var ErrNotFound = errors.New("prefs: key not found")
+
+// A Domain identifies a preferences domain.
+//
+// The zero values of AnyUser and ByHost select the domain of the current user
+// on any host, which is the domain written by `defaults write`. Reads from
+// that domain go through the full search list of CFPreferencesCopyAppValue,
+// so they include managed (profile-installed) preferences and the global
+// domain. Reads from any other domain only see that domain.
+type Domain struct {
+	ID      string // the application ID, e.g. "com.apple.finder", or GlobalDomain
+	AnyUser bool   // the domain applies to all users (writing requires root)
+	ByHost  bool   // the domain only applies to the current host
+}
+
+// App returns the standard domain for the given application ID.
+func App(id string) Domain {
+	return Domain{ID: id}
+}
+
+func (d Domain) searchList() bool {
+	return !d.AnyUser && !d.ByHost
+}
+
+// cfString returns a new CFString. Invalid UTF-8 sequences are replaced by
+// U+FFFD, as plist.Marshal does, since CFStringCreateWithBytes fails on them.
+// The caller must release it.
+func cfString(s string) C.CFStringRef {
+	if !utf8.ValidString(s) {
+		s = string([]rune(s))
+	}
+	b := []byte(s)
+	var ptr *C.char
+	if len(b) > 0 {
+		ptr = (*C.char)(unsafe.Pointer(&b[0]))
+	}
+	return C.prefsCreateString(ptr, C.CFIndex(len(b)))
+}
+
+// appID returns the application ID as a CFString. The caller must release it.
+func (d Domain) appID() C.CFStringRef {
+	if d.ID == GlobalDomain {
+		return C.CFStringRef(C.CFRetain(C.CFTypeRef(C.kCFPreferencesAnyApplication)))
+	}
+	return cfString(d.ID)
+}
+
+func (d Domain) user() C.CFStringRef {
+	if d.AnyUser {
+		return C.kCFPreferencesAnyUser
+	}
+	return C.kCFPreferencesCurrentUser
+}
+
+func (d Domain) host() C.CFStringRef {
+	if d.ByHost {
+		return C.kCFPreferencesCurrentHost
+	}
+	return C.kCFPreferencesAnyHost
+}
+
+// unmarshalCF stores the property list cfObj into v using
+// plist.UnmarshalCFType.
+func unmarshalCF(cfObj C.CFPropertyListRef, v interface{}) error {
+	return plist.UnmarshalCFType(unsafe.Pointer(cfObj), v)
+}
+
+// marshalCF converts v into a property list using plist.ToCFType. The caller
+// must release the result with releaseCF.
+func marshalCF(v interface{}) (C.CFPropertyListRef, error) {
+	ref, err := plist.ToCFType(v)
+	if err != nil {
+		return nil, err
+	}
+	return C.CFPropertyListRef(ref), nil
+}
+
+// releaseCF releases a property list returned by marshalCF.
+func releaseCF(cfObj C.CFPropertyListRef) {
+	plist.ReleaseCFType(unsafe.Pointer(cfObj))
+}
+
+// Get stores the value of key in the value pointed to by v. It returns
+// ErrNotFound if the key has no value.
+func (d Domain) Get(key string, v interface{}) error {
+	cfKey := cfString(key)
+	defer C.CFRelease(C.CFTypeRef(cfKey))
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	var cfObj C.CFPropertyListRef
+	if d.searchList() {
+		cfObj = C.CFPreferencesCopyAppValue(cfKey, appID)
+	} else {
+		cfObj = C.CFPreferencesCopyValue(cfKey, appID, d.user(), d.host())
+	}
+	if cfObj == nil {
+		return ErrNotFound
+	}
+	defer C.CFRelease(C.CFTypeRef(cfObj))
+	return unmarshalCF(cfObj, v)
+}
+
+// Set sets the value of key to v. The change is not guaranteed to be written
+// to disk until Synchronize is called.
+func (d Domain) Set(key string, v interface{}) error {
+	cfObj, err := marshalCF(v)
+	if err != nil {
+		return err
+	}
+	defer releaseCF(cfObj)
+	d.set(key, cfObj)
+	return nil
+}
+
+// Delete removes the value of key.
+func (d Domain) Delete(key string) {
+	d.set(key, nil)
+}
+
+func (d Domain) set(key string, cfObj C.CFPropertyListRef) {
+	cfKey := cfString(key)
+	defer C.CFRelease(C.CFTypeRef(cfKey))
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	C.CFPreferencesSetValue(cfKey, cfObj, appID, d.user(), d.host())
+}
+
+// Load stores every key of the domain in the value pointed to by v, as if the
+// domain were a dictionary. Unlike Get, Load only sees the domain itself and
+// not the rest of the search list.
+func (d Domain) Load(v interface{}) error {
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	cfDict := C.CFPreferencesCopyMultiple(nil, appID, d.user(), d.host())
+	if cfDict == nil {
+		return errors.New("prefs: could not read domain " + d.ID)
+	}
+	defer C.CFRelease(C.CFTypeRef(cfDict))
+	return unmarshalCF(C.CFPropertyListRef(cfDict), v)
+}
+
+// Store sets a key in the domain for each key of v, which must marshal as a
+// dictionary. Keys of the domain that aren't present in v are left alone.
+func (d Domain) Store(v interface{}) error {
+	cfObj, err := marshalCF(v)
+	if err != nil {
+		return err
+	}
+	defer releaseCF(cfObj)
+	if C.CFGetTypeID(C.CFTypeRef(cfObj)) != C.CFDictionaryGetTypeID() {
+		return errors.New("prefs: Store requires a value that marshals as a dictionary")
+	}
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	C.CFPreferencesSetMultiple(C.CFDictionaryRef(cfObj), nil, appID, d.user(), d.host())
+	return nil
+}
+
+// Keys returns the keys that have values in the domain.
+func (d Domain) Keys() ([]string, error) {
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	cfKeys := C.CFPreferencesCopyKeyList(appID, d.user(), d.host())
+	if cfKeys == nil {
+		// CFPreferencesCopyKeyList returns NULL for an empty domain
+		return nil, nil
+	}
+	defer C.CFRelease(C.CFTypeRef(cfKeys))
+	var keys []string
+	err := unmarshalCF(C.CFPropertyListRef(cfKeys), &keys)
+	return keys, err
+}
+
+// IsForced reports whether the value of key is managed by a configuration
+// profile or other administrative mechanism, in which case Set has no effect
+// on the value that applications see.
+func (d Domain) IsForced(key string) bool {
+	cfKey := cfString(key)
+	defer C.CFRelease(C.CFTypeRef(cfKey))
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	return C.CFPreferencesAppValueIsForced(cfKey, appID) != 0
+}
+
+// Synchronize writes any pending changes to the domain to permanent storage
+// and reads in changes made by other processes.
+func (d Domain) Synchronize() error {
+	appID := d.appID()
+	defer C.CFRelease(C.CFTypeRef(appID))
+	if C.CFPreferencesSynchronize(appID, d.user(), d.host()) == 0 {
+		return errors.New("prefs: could not synchronize domain " + d.ID)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package prefs
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testDomainID = "com.github.kballard.go-osx-plist.prefs-test"
+
+type testPrefs struct {
+	Name    string
+	Count   int
+	Enabled bool
+	Tags    []string
+}
+
+func TestRoundTrip(t *testing.T) {
+	d := App(testDomainID)
+	defer func() {
+		d.Delete("Name")
+		d.Delete("Count")
+		d.Delete("Enabled")
+		d.Delete("Tags")
+		d.Synchronize()
+	}()
+
+	want := testPrefs{Name: "test", Count: 3, Enabled: true, Tags: []string{"a", "b"}}
+	if err := d.Store(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Synchronize(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got testPrefs
+	if err := d.Load(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load: got %#v, want %#v", got, want)
+	}
+
+	var name string
+	if err := d.Get("Name", &name); err != nil || name != "test" {
+		t.Errorf("Get: got %q, %v", name, err)
+	}
+
+	if err := d.Set("Count", 4); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := d.Get("Count", &count); err != nil || count != 4 {
+		t.Errorf("Get after Set: got %d, %v", count, err)
+	}
+
+	d.Delete("Name")
+	if err := d.Get("Name", &name); err != ErrNotFound {
+		t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestInvalidUTF8Key(t *testing.T) {
+	d := App(testDomainID)
+	defer func() {
+		d.Delete("\xff")
+		d.Synchronize()
+	}()
+	if err := d.Set("\xff", "value"); err != nil {
+		t.Fatal(err)
+	}
+	// the key is stored with U+FFFD in place of the invalid byte
+	var got string
+	if err := d.Get("�", &got); err != nil || got != "value" {
+		t.Errorf("Get: got %q, %v", got, err)
+	}
+	if d.IsForced("\xff") {
+		t.Error("IsForced: got true")
+	}
+	// the result doesn't matter, as long as it doesn't crash
+	(Domain{ID: "\xff"}).Synchronize()
+}
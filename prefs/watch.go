@@ -0,0 +1,159 @@
+package prefs
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A ChangeKind describes how the value of a key changed.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// A Change describes a key of a domain whose value changed. Old is nil for
+// added keys and New is nil for removed keys. Values are decoded as if
+// unmarshaled into an interface{}.
+type Change struct {
+	Key  string
+	Kind ChangeKind
+	Old  interface{}
+	New  interface{}
+}
+
+// A Watcher reports changes to a preferences domain.
+type Watcher struct {
+	// Changes receives a Change for each key whose value changes. Changes
+	// found by the same poll are sent in key order. The channel is closed
+	// after Stop is called.
+	Changes <-chan Change
+	// Errors receives errors encountered while polling. Errors are dropped
+	// if the previous one hasn't been received yet.
+	Errors <-chan error
+
+	changes chan Change
+	errors  chan error
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Watch polls the domain every interval and reports changes to the values of
+// its keys. Like Load, it only sees the domain itself and not the rest of the
+// search list.
+//
+// The initial contents of the domain are read before Watch returns, so only
+// changes made after Watch returns are reported. The interval must be
+// positive.
+func (d Domain) Watch(interval time.Duration) (*Watcher, error) {
+	if interval <= 0 {
+		return nil, errors.New("prefs: non-positive interval for Watch")
+	}
+	prev, err := d.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		changes: make(chan Change),
+		errors:  make(chan error, 1),
+		done:    make(chan struct{}),
+	}
+	w.Changes = w.changes
+	w.Errors = w.errors
+	go w.run(d, interval, prev)
+	return w, nil
+}
+
+// Stop stops polling and closes the Changes channel.
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.done) })
+}
+
+func (w *Watcher) run(d Domain, interval time.Duration, prev map[string]interface{}) {
+	defer close(w.changes)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		current, err := d.snapshot()
+		if err != nil {
+			select {
+			case w.errors <- err:
+			default:
+			}
+			continue
+		}
+		for _, c := range diffSnapshots(prev, current) {
+			select {
+			case w.changes <- c:
+			case <-w.done:
+				return
+			}
+		}
+		prev = current
+	}
+}
+
+// snapshot reads the current contents of the domain, picking up any changes
+// made by other processes.
+func (d Domain) snapshot() (map[string]interface{}, error) {
+	if err := d.Synchronize(); err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := d.Load(&m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// diffSnapshots returns the changes between two snapshots of a domain, in key
+// order.
+func diffSnapshots(prev, current map[string]interface{}) []Change {
+	keys := make([]string, 0, len(prev)+len(current))
+	for key := range prev {
+		keys = append(keys, key)
+	}
+	for key := range current {
+		if _, ok := prev[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var changes []Change
+	for _, key := range keys {
+		old, hadOld := prev[key]
+		val, hasNew := current[key]
+		switch {
+		case !hadOld:
+			changes = append(changes, Change{key, Added, nil, val})
+		case !hasNew:
+			changes = append(changes, Change{key, Removed, old, nil})
+		case !reflect.DeepEqual(old, val):
+			changes = append(changes, Change{key, Modified, old, val})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,74 @@
+package prefs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	prev := map[string]interface{}{
+		"same":    "value",
+		"changed": int64(1),
+		"removed": true,
+		"data":    []byte{1, 2},
+	}
+	current := map[string]interface{}{
+		"same":    "value",
+		"changed": int64(2),
+		"added":   []interface{}{"x"},
+		"data":    []byte{1, 2},
+	}
+	want := []Change{
+		{"added", Added, nil, []interface{}{"x"}},
+		{"changed", Modified, int64(1), int64(2)},
+		{"removed", Removed, true, nil},
+	}
+	if got := diffSnapshots(prev, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	d := App(testDomainID)
+	defer func() {
+		d.Delete("Watched")
+		d.Synchronize()
+	}()
+	w, err := d.Watch(10 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if err := d.Set("Watched", "yes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Synchronize(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-w.Changes:
+		if c.Key != "Watched" || c.Kind != Added || c.New != "yes" {
+			t.Errorf("got %#v", c)
+		}
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+
+	w.Stop()
+	for range w.Changes {
+	}
+}
+
+func TestWatchInterval(t *testing.T) {
+	d := App(testDomainID)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if w, err := d.Watch(interval); err == nil {
+			w.Stop()
+			t.Errorf("Watch(%v): expected error", interval)
+		}
+	}
+}
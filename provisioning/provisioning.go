@@ -0,0 +1,102 @@
+// Package provisioning parses provisioning profiles (.mobileprovision and
+// .provisionprofile files).
+//
+// A provisioning profile is a property list wrapped in a CMS signed-data
+// message. The signature is not verified.
+package provisioning
+
+import (
+	"crypto/x509"
+	"os"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+	"github.com/kballard/go-osx-plist/internal/cms"
+)
+
+// A Profile is the decoded property list of a provisioning profile.
+type Profile struct {
+	Name                        string
+	UUID                        string
+	AppIDName                   string
+	ApplicationIdentifierPrefix []string
+	TeamIdentifier              []string
+	TeamName                    string
+	Platform                    []string
+	CreationDate                time.Time
+	ExpirationDate              time.Time
+	TimeToLive                  int
+	Version                     int
+	IsXcodeManaged              bool
+	ProvisionsAllDevices        bool     // enterprise distribution profiles
+	ProvisionedDevices          []string // device UDIDs
+	DeveloperCertificates       [][]byte // DER-encoded certificates
+	Entitlements                map[string]interface{}
+}
+
+// Content returns the property list embedded in the provisioning profile
+// data.
+func Content(data []byte) ([]byte, error) {
+	return cms.Content(data)
+}
+
+// Unmarshal stores the property list embedded in the provisioning profile
+// data in the value pointed to by v.
+func Unmarshal(data []byte, v interface{}) error {
+	content, err := cms.Content(data)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(content, v)
+	return err
+}
+
+// Parse parses the provisioning profile data.
+func Parse(data []byte) (*Profile, error) {
+	p := new(Profile)
+	if err := Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ParseFile parses the provisioning profile at path.
+func ParseFile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Certificates parses the profile's developer certificates.
+func (p *Profile) Certificates() ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, len(p.DeveloperCertificates))
+	for i, der := range p.DeveloperCertificates {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs[i] = cert
+	}
+	return certs, nil
+}
+
+// Expired reports whether the profile has expired as of t.
+func (p *Profile) Expired(t time.Time) bool {
+	return !t.Before(p.ExpirationDate)
+}
+
+// HasDevice reports whether the profile can be installed on the device with
+// the given UDID.
+func (p *Profile) HasDevice(udid string) bool {
+	if p.ProvisionsAllDevices {
+		return true
+	}
+	for _, d := range p.ProvisionedDevices {
+		if d == udid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package provisioning
+
+import (
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// sign wraps content in an unsigned CMS signed-data message.
+func sign(t *testing.T, content []byte) []byte {
+	explicit := func(der []byte) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+	}
+	octets, err := asn1.Marshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type encapContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	sd, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		EncapContentInfo encapContentInfo
+		SignerInfos      asn1.RawValue
+	}{1, emptySet, encapContentInfo{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, explicit(octets)}, emptySet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}, explicit(sd)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParse(t *testing.T) {
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	content, err := plist.Marshal(map[string]interface{}{
+		"Name":               "Test Profile",
+		"UUID":               "8C5D6F6A-0000-4000-8000-000000000000",
+		"TeamIdentifier":     []string{"ABCDE12345"},
+		"ExpirationDate":     expiry,
+		"ProvisionedDevices": []string{"00008030-001A2B3C4D5E6F70"},
+		"Entitlements": map[string]interface{}{
+			"application-identifier": "ABCDE12345.com.example.app",
+			"get-task-allow":         true,
+		},
+	}, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(sign(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "Test Profile" || len(p.TeamIdentifier) != 1 || p.TeamIdentifier[0] != "ABCDE12345" {
+		t.Errorf("got %#v", p)
+	}
+	if !p.ExpirationDate.Equal(expiry) {
+		t.Errorf("ExpirationDate: got %v, want %v", p.ExpirationDate, expiry)
+	}
+	if p.Expired(expiry.Add(-time.Hour)) || !p.Expired(expiry) {
+		t.Error("Expired returned the wrong result")
+	}
+	if !p.HasDevice("00008030-001A2B3C4D5E6F70") || p.HasDevice("other") {
+		t.Error("HasDevice returned the wrong result")
+	}
+	if p.Entitlements["get-task-allow"] != true {
+		t.Errorf("Entitlements: got %#v", p.Entitlements)
+	}
+}
@@ -0,0 +1,353 @@
+package schema
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	dictType = reflect.TypeOf(plist.Dict{})
+	uidType  = reflect.TypeOf(plist.UID(0))
+)
+
+// Generate returns a Schema describing the property list that Marshal
+// produces for v, and that Unmarshal accepts into it. v is usually the zero
+// value of a struct type, or a nil pointer to one.
+//
+// Struct fields are named and skipped the same way Marshal does, and
+// additional metadata is taken from the field's tags:
+//
+//	// Label must be present.
+//	Label string `plist:",required" doc:"Unique name of the job."`
+//	// Port is optional and defaults to 80.
+//	Port int `plist:",omitempty" default:"80"`
+//
+// The "required" plist tag option adds the key to Required, and the doc tag
+// becomes the Description. The default tag is parsed according to the type of
+// the field and stored in Default. Fields tagged "omitempty" are marked with
+// OmitEmpty. Struct schemas are not Strict, since Unmarshal ignores unknown
+// keys.
+//
+// Values whose layout can't be known from their type, such as interface{}
+// fields or types implementing Marshaler, are described by a Schema of type
+// Any.
+func Generate(v interface{}) (*Schema, error) {
+	g := generator{inProgress: make(map[reflect.Type]bool)}
+	return g.schema(reflect.TypeOf(v))
+}
+
+type generator struct {
+	inProgress map[reflect.Type]bool
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*plist.Marshaler)(nil)).Elem()
+	cfMarshalerType = reflect.TypeOf((*plist.CFMarshaler)(nil)).Elem()
+)
+
+// isMarshaler reports whether values of type t, or pointers to them, encode
+// themselves.
+func isMarshaler(t reflect.Type) bool {
+	for _, t := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if t.Implements(marshalerType) || t.Implements(cfMarshalerType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) schema(t reflect.Type) (*Schema, error) {
+	if t == nil {
+		return &Schema{}, nil
+	}
+	for t.Kind() == reflect.Ptr && !isMarshaler(t) {
+		t = t.Elem()
+	}
+	if isMarshaler(t) {
+		return &Schema{}, nil
+	}
+	switch t {
+	case timeType:
+		return &Schema{Type: Date}, nil
+	case dictType:
+		return &Schema{Type: Dict}, nil
+	case uidType:
+		return &Schema{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: Bool}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: Integer}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: Real}, nil
+	case reflect.String:
+		return &Schema{Type: String}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: Data}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Array, Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, errors.New("schema: unsupported map key type " + t.Key().String())
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: Dict, Values: values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return nil, errors.New("schema: unsupported type " + t.String())
+}
+
+func (g *generator) structSchema(t reflect.Type) (*Schema, error) {
+	s := &Schema{Type: Dict}
+	if g.inProgress[t] {
+		// A recursive type. Schemas are trees, so the nested value is only
+		// described as a dictionary.
+		return s, nil
+	}
+	g.inProgress[t] = true
+	defer delete(g.inProgress, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Anonymous {
+			// Marshal skips non-exported and anonymous fields
+			continue
+		}
+		name, omitEmpty, required := f.Name, false, false
+		if tv := f.Tag.Get("plist"); tv != "" {
+			if tv == "-" {
+				continue
+			}
+			tagName, opts := parseTag(tv)
+			if isValidName(tagName) {
+				name = tagName
+			}
+			omitEmpty = hasOption(opts, "omitempty")
+			required = hasOption(opts, "required")
+		}
+		fs, err := g.schema(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		fs.Description = f.Tag.Get("doc")
+		fs.OmitEmpty = omitEmpty
+		if def, ok := f.Tag.Lookup("default"); ok {
+			fs.Default, err = parseDefault(def, f.Type)
+			if err != nil {
+				return nil, errors.New("schema: invalid default for field " + t.String() + "." + f.Name + ": " + err.Error())
+			}
+		}
+		if s.Properties == nil {
+			s.Properties = make(map[string]*Schema)
+		}
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s, nil
+}
+
+// parseDefault converts the default tag def to a value of the kind stored in
+// a field of type t.
+func parseDefault(def string, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return time.Parse(time.RFC3339, def)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(def)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(def, 10, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(def, 10, t.Bits())
+		return int64(n), err
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(def, t.Bits())
+	}
+	return def, nil
+}
+
+// parseTag splits a plist tag into its name and comma-separated options, the
+// same way Marshal does.
+func parseTag(tag string) (string, string) {
+	if i := strings.Index(tag, ","); i != -1 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+func hasOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidName reports whether a tag name is used by Marshal. It matches the
+// rules in encoding/json.
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c) && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteDoc writes a plain text description of s to w, listing every key path
+// it describes with its type, constraints and description:
+//
+//	Label (string, required)
+//	    Unique name of the job.
+//	Port (integer, optional, default 80)
+//
+// Array elements are listed with a "*" component, as in "Tags:*".
+func WriteDoc(w io.Writer, s *Schema) error {
+	var b []byte
+	b = appendDoc(b, s, nil, false, true)
+	_, err := w.Write(b)
+	return err
+}
+
+func appendDoc(b []byte, s *Schema, path plist.KeyPath, required, top bool) []byte {
+	if s == nil {
+		return b
+	}
+	if !top {
+		b = append(b, path.String()...)
+		b = append(b, " ("...)
+		b = append(b, describe(s, required, len(path) > 0 && path[len(path)-1] != "*")...)
+		b = append(b, ")\n"...)
+		if s.Description != "" {
+			b = append(b, "    "...)
+			b = append(b, s.Description...)
+			b = append(b, '\n')
+		}
+	}
+	child := func(b []byte, s *Schema, key string, required bool) []byte {
+		return appendDoc(b, s, append(append(plist.KeyPath{}, path...), key), required, false)
+	}
+	switch s.Type {
+	case Array:
+		b = child(b, s.Items, "*", false)
+	case Dict, Any:
+		keys := make([]string, 0, len(s.Properties))
+		for k := range s.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b = child(b, s.Properties[k], k, contains(s.Required, k))
+		}
+		b = child(b, s.Values, "*", false)
+	}
+	return b
+}
+
+// describe summarizes the type and constraints of s.
+func describe(s *Schema, required, isKey bool) string {
+	var parts []string
+	if s.Type == Any {
+		parts = append(parts, "any")
+	} else {
+		parts = append(parts, string(s.Type))
+	}
+	if isKey {
+		if required {
+			parts = append(parts, "required")
+		} else {
+			parts = append(parts, "optional")
+		}
+	}
+	if s.Default != nil {
+		parts = append(parts, "default "+formatValue(s.Default))
+	}
+	if s.OmitEmpty {
+		parts = append(parts, "omitted when empty")
+	}
+	if s.Min != nil {
+		parts = append(parts, "min "+formatNumber(*s.Min))
+	}
+	if s.Max != nil {
+		parts = append(parts, "max "+formatNumber(*s.Max))
+	}
+	if s.MinLen != nil {
+		parts = append(parts, "min length "+strconv.Itoa(*s.MinLen))
+	}
+	if s.MaxLen != nil {
+		parts = append(parts, "max length "+strconv.Itoa(*s.MaxLen))
+	}
+	if s.Pattern != "" {
+		parts = append(parts, "matching "+strconv.Quote(s.Pattern))
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = formatValue(e)
+		}
+		parts = append(parts, "one of "+strings.Join(values, ", "))
+	}
+	if s.Strict {
+		parts = append(parts, "no other keys")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	}
+	switch TypeOf(v) {
+	case Integer, Real:
+		return formatNumber(numberValue(v))
+	}
+	if t := TypeOf(v); t != Any {
+		return "<" + string(t) + ">"
+	}
+	return "<value>"
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
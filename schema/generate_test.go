@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Label    string            `plist:",required" doc:"Unique name of the job."`
+	Port     int               `plist:"port,omitempty" default:"80"`
+	Enabled  *bool             `default:"true"`
+	Tags     []string          `plist:",omitempty"`
+	Env      map[string]string `doc:"Environment variables."`
+	Since    time.Time
+	Extra    interface{}
+	Children []testConfig
+	Ignored  string `plist:"-"`
+	hidden   string
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := Generate((*testConfig)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Type != Dict || s.Strict || !reflect.DeepEqual(s.Required, []string{"Label"}) {
+		t.Errorf("got %+v", s)
+	}
+	var keys []string
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	if len(keys) != 8 {
+		t.Errorf("got properties %v", keys)
+	}
+	port := s.Properties["port"]
+	if port == nil || port.Type != Integer || port.Default != int64(80) || !port.OmitEmpty {
+		t.Errorf("port: got %+v", port)
+	}
+	if e := s.Properties["Enabled"]; e.Type != Bool || e.Default != true {
+		t.Errorf("Enabled: got %+v", e)
+	}
+	if tags := s.Properties["Tags"]; tags.Type != Array || tags.Items.Type != String {
+		t.Errorf("Tags: got %+v", tags)
+	}
+	if env := s.Properties["Env"]; env.Type != Dict || env.Values.Type != String || env.Description != "Environment variables." {
+		t.Errorf("Env: got %+v", env)
+	}
+	if c := s.Properties["Children"]; c.Type != Array || c.Items.Type != Dict || c.Items.Properties != nil {
+		t.Errorf("Children: got %+v", c)
+	}
+	if x := s.Properties["Extra"]; x.Type != Any {
+		t.Errorf("Extra: got %+v", x)
+	}
+
+	valid := map[string]interface{}{"Label": "x", "port": int64(8080), "Since": time.Now()}
+	if v := Validate(valid, s); len(v) != 0 {
+		t.Errorf("got violations %v", v)
+	}
+	if v := Validate(map[string]interface{}{"port": "80"}, s); len(v) != 2 {
+		t.Errorf("got violations %v, want 2", v)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := Generate(map[int]string{}); err == nil {
+		t.Error("non-string map keys: expected an error")
+	}
+	type bad struct {
+		N int `default:"many"`
+	}
+	if _, err := Generate(bad{}); err == nil {
+		t.Error("invalid default: expected an error")
+	}
+}
+
+func TestWriteDoc(t *testing.T) {
+	type sub struct {
+		Name string `plist:",required"`
+	}
+	type config struct {
+		Label string `plist:",required" doc:"Unique name of the job."`
+		Port  int    `plist:",omitempty" default:"80"`
+		Subs  []sub
+	}
+	s, err := Generate(config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Properties["Port"].Max = float(65535)
+	var buf bytes.Buffer
+	if err := WriteDoc(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	want := `Label (string, required)
+    Unique name of the job.
+Port (integer, optional, default 80, omitted when empty, max 65535)
+Subs (array, optional)
+Subs:* (dictionary)
+Subs:*:Name (string, required)
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,330 @@
+// Package schema validates decoded property lists against a declarative
+// description of their expected layout.
+//
+// A Schema describes a single value: its type, and constraints such as ranges
+// for numbers, patterns for strings, or the keys of a dictionary, each with a
+// nested Schema. Validate checks a value decoded into an interface{} and
+// reports every Violation it finds, with the key path of the offending value,
+// so configuration files can be checked before they are unmarshaled.
+package schema
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// A Type names a kind of property list value.
+type Type string
+
+const (
+	Any     Type = ""
+	String  Type = "string"
+	Integer Type = "integer"
+	Real    Type = "real"
+	Number  Type = "number" // an integer or a real
+	Bool    Type = "boolean"
+	Data    Type = "data"
+	Date    Type = "date"
+	Array   Type = "array"
+	Dict    Type = "dictionary"
+)
+
+// A Schema describes the expected form of a property list value. The zero
+// Schema accepts any value. Constraints that don't apply to the type of the
+// value being checked are ignored.
+type Schema struct {
+	Type        Type   `plist:"type,omitempty"`
+	Description string `plist:"description,omitempty"`
+
+	// Enum, if not empty, lists the allowed values, compared with
+	// plist.Equal.
+	Enum []interface{} `plist:"enum,omitempty"`
+
+	// Min and Max bound numbers, inclusively.
+	Min *float64 `plist:"min,omitempty"`
+	Max *float64 `plist:"max,omitempty"`
+
+	// MinLen and MaxLen bound the length of strings (in characters), data (in
+	// bytes), arrays and dictionaries, inclusively.
+	MinLen *int `plist:"minLength,omitempty"`
+	MaxLen *int `plist:"maxLength,omitempty"`
+
+	// Pattern is a regular expression that strings must match. It is not
+	// anchored, so use ^ and $ to match the whole string.
+	Pattern string `plist:"pattern,omitempty"`
+
+	// Items is the schema for the elements of an array.
+	Items *Schema `plist:"items,omitempty"`
+
+	// Properties holds the schemas for known dictionary keys, and Required
+	// lists the keys that must be present.
+	Properties map[string]*Schema `plist:"properties,omitempty"`
+	Required   []string           `plist:"required,omitempty"`
+	// Values is the schema for the values of keys not in Properties.
+	Values *Schema `plist:"values,omitempty"`
+	// Strict disallows keys not in Properties.
+	Strict bool `plist:"strict,omitempty"`
+
+	// Default documents the value used when an optional key is missing, and
+	// OmitEmpty documents that the key is left out when its value is empty.
+	// Neither is used for validation.
+	Default   interface{} `plist:"default,omitempty"`
+	OmitEmpty bool        `plist:"omitEmpty,omitempty"`
+}
+
+// A Violation is a way in which a value doesn't match its schema.
+type Violation struct {
+	// Path is the key path of the offending value. It is empty for the root
+	// value.
+	Path    plist.KeyPath
+	Message string
+}
+
+func (v Violation) String() string {
+	if len(v.Path) == 0 {
+		return v.Message
+	}
+	return v.Path.String() + ": " + v.Message
+}
+
+// Validate checks v, a value decoded into an interface{}, against s, and
+// returns the violations found, ordered by key path. The result is empty if v
+// matches s.
+func Validate(v interface{}, s *Schema) []Violation {
+	var violations []Violation
+	validate(v, s, plist.KeyPath{}, &violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		return lessKeyPath(violations[i].Path, violations[j].Path)
+	})
+	return violations
+}
+
+// ValidateData decodes the property list in data and validates it against s.
+func ValidateData(data []byte, s *Schema) ([]Violation, error) {
+	var v interface{}
+	if _, err := plist.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return Validate(v, s), nil
+}
+
+func validate(v interface{}, s *Schema, path plist.KeyPath, violations *[]Violation) {
+	if s == nil {
+		return
+	}
+	report := func(msg string) {
+		*violations = append(*violations, Violation{append(plist.KeyPath{}, path...), msg})
+	}
+	t := TypeOf(v)
+	if !s.Type.accepts(t) {
+		report("expected " + article(string(s.Type)) + ", found " + typeName(t))
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if plist.Equal(v, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("value is not one of the allowed values")
+		}
+	}
+	switch t {
+	case Integer, Real:
+		f := numberValue(v)
+		if s.Min != nil && f < *s.Min {
+			report("value " + formatNumber(f) + " is less than the minimum " + formatNumber(*s.Min))
+		}
+		if s.Max != nil && f > *s.Max {
+			report("value " + formatNumber(f) + " is greater than the maximum " + formatNumber(*s.Max))
+		}
+	case String:
+		str := v.(string)
+		checkLen(utf8.RuneCountInString(str), s, report)
+		if s.Pattern != "" {
+			re, err := compilePattern(s.Pattern)
+			if err != nil {
+				report("schema has invalid pattern: " + err.Error())
+			} else if !re.MatchString(str) {
+				report(strconv.Quote(str) + " does not match pattern " + strconv.Quote(s.Pattern))
+			}
+		}
+	case Data:
+		checkLen(len(v.([]byte)), s, report)
+	case Array:
+		rv := reflect.ValueOf(v)
+		checkLen(rv.Len(), s, report)
+		for i := 0; i < rv.Len(); i++ {
+			validate(rv.Index(i).Interface(), s.Items, append(path, strconv.Itoa(i)), violations)
+		}
+	case Dict:
+		keys, values := dictEntries(v)
+		checkLen(len(keys), s, report)
+		for _, k := range s.Required {
+			if _, ok := values[k]; !ok {
+				report("missing required key " + strconv.Quote(k))
+			}
+		}
+		for _, k := range keys {
+			p := append(path, k)
+			if ps, ok := s.Properties[k]; ok {
+				validate(values[k], ps, p, violations)
+			} else if s.Strict {
+				*violations = append(*violations, Violation{append(plist.KeyPath{}, p...), "unexpected key"})
+			} else {
+				validate(values[k], s.Values, p, violations)
+			}
+		}
+	}
+}
+
+func checkLen(n int, s *Schema, report func(string)) {
+	if s.MinLen != nil && n < *s.MinLen {
+		report("length " + strconv.Itoa(n) + " is less than the minimum " + strconv.Itoa(*s.MinLen))
+	}
+	if s.MaxLen != nil && n > *s.MaxLen {
+		report("length " + strconv.Itoa(n) + " is greater than the maximum " + strconv.Itoa(*s.MaxLen))
+	}
+}
+
+// TypeOf returns the Type of v, a value decoded into an interface{}. It
+// returns Any if v isn't a property list value.
+func TypeOf(v interface{}) Type {
+	switch v.(type) {
+	case string:
+		return String
+	case bool:
+		return Bool
+	case []byte:
+		return Data
+	case time.Time:
+		return Date
+	case *plist.Dict, map[string]interface{}:
+		return Dict
+	case plist.UID, nil:
+		return Any
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer
+	case reflect.Float32, reflect.Float64:
+		return Real
+	case reflect.Slice, reflect.Array:
+		return Array
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return Dict
+		}
+	}
+	return Any
+}
+
+func (t Type) accepts(found Type) bool {
+	switch t {
+	case Any:
+		return true
+	case Number:
+		return found == Integer || found == Real
+	}
+	return t == found
+}
+
+func typeName(t Type) string {
+	if t == Any {
+		return "an unsupported value"
+	}
+	return article(string(t))
+}
+
+func article(noun string) string {
+	switch noun[0] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return "an " + noun
+	}
+	return "a " + noun
+}
+
+func numberValue(v interface{}) float64 {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	}
+	return rv.Float()
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// dictEntries returns the keys of v in order (sorted for a map) and its
+// entries.
+func dictEntries(v interface{}) ([]string, map[string]interface{}) {
+	if d, ok := v.(*plist.Dict); ok {
+		return d.Keys(), d.Map()
+	}
+	m := make(map[string]interface{})
+	rv := reflect.ValueOf(v)
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+		m[k.String()] = rv.MapIndex(k).Interface()
+	}
+	sort.Strings(keys)
+	return keys, m
+}
+
+var patternCache struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}
+
+// compilePattern compiles pattern, reusing the result for later calls since
+// a schema is usually checked against many values.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	if re, ok := patternCache.m[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if patternCache.m == nil {
+		patternCache.m = make(map[string]*regexp.Regexp)
+	}
+	patternCache.m[pattern] = re
+	return re, nil
+}
+
+// lessKeyPath orders key paths component by component, comparing array
+// indexes numerically.
+func lessKeyPath(a, b plist.KeyPath) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aerr := strconv.Atoi(a[i])
+		bi, berr := strconv.Atoi(b[i])
+		if aerr == nil && berr == nil {
+			return ai < bi
+		}
+		return a[i] < b[i]
+	}
+	return len(a) < len(b)
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func float(f float64) *float64 { return &f }
+func length(n int) *int        { return &n }
+
+var testSchema = &Schema{
+	Type:     Dict,
+	Required: []string{"Label", "Port"},
+	Strict:   true,
+	Properties: map[string]*Schema{
+		"Label": {Type: String, Pattern: `^[a-z]+(\.[a-z]+)*$`},
+		"Port":  {Type: Integer, Min: float(1), Max: float(65535)},
+		"Ratio": {Type: Number},
+		"Mode":  {Type: String, Enum: []interface{}{"fast", "slow"}},
+		"Tags":  {Type: Array, MaxLen: length(2), Items: &Schema{Type: String, MinLen: length(1)}},
+		"Since": {Type: Date},
+		"Env":   {Type: Dict, Values: &Schema{Type: String}},
+	},
+}
+
+func TestValidate(t *testing.T) {
+	valid := map[string]interface{}{
+		"Label": "com.example",
+		"Port":  int64(80),
+		"Ratio": 0.5,
+		"Mode":  "fast",
+		"Tags":  []interface{}{"a", "b"},
+		"Since": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"Env":   map[string]interface{}{"HOME": "/"},
+	}
+	if got := Validate(valid, testSchema); len(got) != 0 {
+		t.Errorf("valid value: got violations %v", got)
+	}
+
+	invalid := map[string]interface{}{
+		"Label": "Com Example",
+		"Ratio": true,
+		"Mode":  "medium",
+		"Tags":  []interface{}{"a", "", int64(3)},
+		"Env":   map[string]interface{}{"PATH": []interface{}{}},
+		"Extra": "x",
+	}
+	want := []string{
+		`missing required key "Port"`,
+		`Env:PATH: expected a string, found an array`,
+		`Extra: unexpected key`,
+		`Label: "Com Example" does not match pattern "^[a-z]+(\\.[a-z]+)*$"`,
+		`Mode: value is not one of the allowed values`,
+		`Ratio: expected a number, found a boolean`,
+		`Tags: length 3 is greater than the maximum 2`,
+		`Tags:1: length 0 is less than the minimum 1`,
+		`Tags:2: expected a string, found an integer`,
+	}
+	got := Validate(invalid, testSchema)
+	if len(got) != len(want) {
+		t.Fatalf("got %d violations, want %d: %v", len(got), len(want), got)
+	}
+	for i, v := range got {
+		if v.String() != want[i] {
+			t.Errorf("violation %d: got %q, want %q", i, v.String(), want[i])
+		}
+	}
+}
+
+func TestValidateRange(t *testing.T) {
+	s := &Schema{Type: Integer, Min: float(1), Max: float(10)}
+	for _, tc := range []struct {
+		v    interface{}
+		want string
+	}{
+		{int64(5), ""},
+		{uint8(1), ""},
+		{int64(0), "value 0 is less than the minimum 1"},
+		{int64(11), "value 11 is greater than the maximum 10"},
+		{2.5, "expected an integer, found a real"},
+	} {
+		got := Validate(tc.v, s)
+		switch {
+		case tc.want == "" && len(got) != 0:
+			t.Errorf("%v: got %v, want no violations", tc.v, got)
+		case tc.want != "" && (len(got) != 1 || got[0].String() != tc.want):
+			t.Errorf("%v: got %v, want %q", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestValidateOrderedDict(t *testing.T) {
+	d := &plist.Dict{}
+	d.Set("Port", "80")
+	d.Set("Label", "x")
+	got := Validate(d, testSchema)
+	if len(got) != 1 || got[0].String() != "Port: expected an integer, found a string" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestValidateInvalidPattern(t *testing.T) {
+	got := Validate("x", &Schema{Pattern: "("})
+	if len(got) != 1 || len(got[0].Path) != 0 {
+		t.Errorf("got %v, want one violation at the root", got)
+	}
+}
@@ -0,0 +1,154 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+)
+
+// seqArity returns 1 if t is an iter.Seq, 2 if it is an iter.Seq2 with string
+// keys, or any other function type of the same shape, and 0 otherwise.
+func seqArity(t reflect.Type) int {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return 0
+	}
+	yield := t.In(0)
+	if yield.Kind() != reflect.Func || yield.IsVariadic() || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return 0
+	}
+	switch yield.NumIn() {
+	case 1:
+		return 1
+	case 2:
+		if yield.In(0).Kind() == reflect.String {
+			return 2
+		}
+	}
+	return 0
+}
+
+// rangeSeq calls f with each value v, a non-nil iterator, yields, and the
+// key if it yields pairs. Iteration stops at the first error f returns.
+func rangeSeq(v reflect.Value, f func(key, elem reflect.Value) error) error {
+	yieldType := v.Type().In(0)
+	var err error
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if err == nil {
+			if len(args) == 1 {
+				err = f(reflect.Value{}, args[0])
+			} else {
+				err = f(args[0], args[1])
+			}
+		}
+		return []reflect.Value{reflect.ValueOf(err == nil).Convert(yieldType.Out(0))}
+	})
+	v.Call([]reflect.Value{yield})
+	return err
+}
+
+// addSeqKey adds key, yielded by the iterator v, to seen, and returns an error
+// if it was already yielded or collides with a key that was.
+func addSeqKey(v reflect.Value, seen keySet, key string) error {
+	prev, ok := seen.add(key)
+	if !ok {
+		return nil
+	}
+	if prev == key {
+		return &UnsupportedValueError{v, "iterator yielded key " + strconv.Quote(key) + " twice"}
+	}
+	return newKeyCollisionError(prev, key)
+}
+
+// marshalSeq converts v, an iterator of the given arity, to a CFArray of the
+// values it yields, or a CFDictionary of the pairs it yields with its keys in
+// the order they were yielded.
+func (state *marshalState) marshalSeq(v reflect.Value, arity int) (cfTypeRef, error) {
+	if v.IsNil() {
+		return nil, &UnsupportedValueError{v, "nil iterator"}
+	}
+	var keys []string
+	var cfKeys, values []cfTypeRef
+	defer func() {
+		state.release(cfKeys)
+		state.release(values)
+	}()
+	seen := make(keySet)
+	err := rangeSeq(v, func(key, elem reflect.Value) error {
+		if key.IsValid() {
+			if err := addSeqKey(v, seen, key.String()); err != nil {
+				return err
+			}
+			cfStr := convertStringToCFString(key.String())
+			if cfStr == nil {
+				return errors.New("plist: could not convert string to CFStringRef")
+			}
+			keys = append(keys, key.String())
+			cfKeys = append(cfKeys, cfTypeRef(cfStr))
+		}
+		cfObj, err := state.marshalValue(elem)
+		if err != nil {
+			return err
+		}
+		values = append(values, cfObj)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arity == 1 {
+		if len(values) == 0 {
+			return cfTypeRef(cfCreated(C.CFArrayCreate(nil, nil, 0, nil))), nil
+		}
+		return cfTypeRef(createCFArray(values)), nil
+	}
+	cfDict := cfTypeRef(createCFDictionary(cfKeys, values))
+	if state.dictKeys == nil {
+		state.dictKeys = make(map[cfTypeRef][]string)
+	}
+	state.dictKeys[cfDict] = keys
+	return cfDict, nil
+}
+
+// encodeSeq writes v, an iterator of the given arity, as an array of the
+// values it yields, or a dictionary of the pairs it yields in the order they
+// were yielded, without collecting them first.
+func (state *streamState) encodeSeq(v reflect.Value, arity int) error {
+	if v.IsNil() {
+		return &UnsupportedValueError{v, "nil iterator"}
+	}
+	if arity == 1 {
+		if err := state.w.beginArray(-1); err != nil {
+			return err
+		}
+		err := rangeSeq(v, func(_, elem reflect.Value) error {
+			return state.encodeElement(elem, state.encodeValue)
+		})
+		if err != nil {
+			return err
+		}
+		return state.w.endArray()
+	}
+	if err := state.w.beginDict(-1); err != nil {
+		return err
+	}
+	seen := make(keySet)
+	err := rangeSeq(v, func(key, elem reflect.Value) error {
+		if err := addSeqKey(v, seen, key.String()); err != nil {
+			return err
+		}
+		if state.omit(elem) {
+			return nil
+		}
+		if err := state.w.key(key.String()); err != nil {
+			return err
+		}
+		return state.encodeValue(elem)
+	})
+	if err != nil {
+		return err
+	}
+	return state.w.endDict()
+}
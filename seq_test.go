@@ -0,0 +1,123 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pairs returns an iterator of the keys and values in kv, in order.
+func pairs(kv ...interface{}) iter.Seq2[string, interface{}] {
+	return func(yield func(string, interface{}) bool) {
+		for i := 0; i < len(kv); i += 2 {
+			if !yield(kv[i].(string), kv[i+1]) {
+				return
+			}
+		}
+	}
+}
+
+type seqHolder struct {
+	Names  iter.Seq[string]
+	Counts iter.Seq2[string, interface{}]
+	Empty  iter.Seq[int]
+}
+
+func TestSeq(t *testing.T) {
+	v := seqHolder{
+		Names:  slices.Values([]string{"b", "a", "c"}),
+		Counts: pairs("zebra", 1, "apple", []int{2, 3}),
+		Empty:  slices.Values([]int(nil)),
+	}
+	encode := NewTypedEncoder[seqHolder]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var got struct {
+				Names  []string
+				Counts struct {
+					Zebra int
+					Apple []int
+				}
+				Empty []int
+			}
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Names, []string{"b", "a", "c"}) {
+				t.Errorf("%v: Names: got %v", format, got.Names)
+			}
+			if got.Counts.Zebra != 1 || !reflect.DeepEqual(got.Counts.Apple, []int{2, 3}) {
+				t.Errorf("%v: Counts: got %+v", format, got.Counts)
+			}
+			if len(got.Empty) != 0 {
+				t.Errorf("%v: Empty: got %v", format, got.Empty)
+			}
+			if format != XMLFormat {
+				continue
+			}
+			// the pairs are in the order they were yielded
+			if s := string(data); strings.Index(s, "<key>zebra</key>") > strings.Index(s, "<key>apple</key>") {
+				t.Errorf("keys out of order:\n%s", s)
+			}
+			if !strings.Contains(string(data), "<array/>") {
+				t.Errorf("empty iterator isn't an empty array:\n%s", data)
+			}
+		}
+	}
+}
+
+func TestSeqErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"duplicate key", pairs("a", 1, "b", 2, "a", 3)},
+		{"nil iterator", iter.Seq[int](nil)},
+		{"bad value", slices.Values([]interface{}{1, nil})},
+	}
+	for _, test := range tests {
+		_, err := Marshal(test.v, XMLFormat)
+		var uve *UnsupportedValueError
+		if !errors.As(err, &uve) {
+			t.Errorf("%s: Marshal returned %v, want an UnsupportedValueError", test.name, err)
+		}
+		err = NewEncoder(new(bytes.Buffer), XMLFormat).Encode(test.v)
+		if !errors.As(err, &uve) {
+			t.Errorf("%s: Encode returned %v, want an UnsupportedValueError", test.name, err)
+		}
+	}
+
+	// iteration stops at the first error
+	calls := 0
+	stop := func(yield func(interface{}) bool) {
+		for i := 0; i < 10; i++ {
+			calls++
+			var v interface{} = i
+			if i == 2 {
+				v = nil
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	if _, err := Marshal(stop, XMLFormat); err == nil || calls != 3 {
+		t.Errorf("got error %v after %d values, want an error after 3", err, calls)
+	}
+}
@@ -0,0 +1,104 @@
+package plist
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var emptyStructType = reflect.TypeOf(struct{}{})
+
+// isSetType reports whether t is a map type that the "set" option applies to:
+// map[T]struct{}, or map[T]bool holding the keys that map to true.
+func isSetType(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && (t.Elem() == emptyStructType || t.Elem().Kind() == reflect.Bool)
+}
+
+// setSlice returns the members of v, a set, as a slice sorted in the order
+// lessSetKey defines.
+func setSlice(v reflect.Value) reflect.Value {
+	keys := make([]reflect.Value, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		if iter.Value().Kind() == reflect.Bool && !iter.Value().Bool() {
+			continue
+		}
+		keys = append(keys, iter.Key())
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessSetKey(keys[i], keys[j]) })
+	s := reflect.MakeSlice(reflect.SliceOf(v.Type().Key()), len(keys), len(keys))
+	for i, k := range keys {
+		s.Index(i).Set(k)
+	}
+	return s
+}
+
+// lessSetKey reports whether the set member a sorts before b. Strings and
+// numbers sort in their natural order, false before true, arrays element by
+// element, and anything else by its default formatting.
+func lessSetKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uintptr, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if lessSetKey(a.Index(i), b.Index(i)) {
+				return true
+			}
+			if lessSetKey(b.Index(i), a.Index(i)) {
+				return false
+			}
+		}
+		return false
+	case reflect.Interface, reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && !b.IsNil()
+		}
+		if a.Elem().Type() == b.Elem().Type() {
+			return lessSetKey(a.Elem(), b.Elem())
+		}
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// setSlice returns the sorted members of v, the value of the field, if the
+// field has the "set" option.
+func (ef *encodeField) setSlice(v reflect.Value) (reflect.Value, bool) {
+	if !ef.set || !isSetType(v.Type()) {
+		return v, false
+	}
+	return setSlice(v), true
+}
+
+// unmarshalSet adds the elements of cfObj to v, the value of a field with the
+// "set" option, if cfObj is a CFArray and v a set. A nil map is allocated, and
+// existing members are kept, as a CFDictionary decoded into a map does.
+func (state *unmarshalState) unmarshalSet(cfObj cfTypeRef, v reflect.Value) (bool, error) {
+	if cfTypeID(cfObj) != cfArrayTypeID || !isSetType(v.Type()) {
+		return false, nil
+	}
+	t := v.Type()
+	s := reflect.New(reflect.SliceOf(t.Key())).Elem()
+	if err := state.unmarshalValue(cfObj, s); err != nil {
+		return true, err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, s.Len()))
+	}
+	member := reflect.New(t.Elem()).Elem()
+	if member.Kind() == reflect.Bool {
+		member.SetBool(true)
+	}
+	for i := 0; i < s.Len(); i++ {
+		v.SetMapIndex(s.Index(i), member)
+	}
+	return true, nil
+}
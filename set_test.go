@@ -0,0 +1,106 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type setHolder struct {
+	Tags  map[string]struct{} `plist:",set"`
+	Ports map[int]bool        `plist:",set"`
+	Flags map[string]bool
+}
+
+func TestSet(t *testing.T) {
+	v := setHolder{
+		Tags:  map[string]struct{}{"work": {}, "home": {}, "archive": {}},
+		Ports: map[int]bool{443: true, 80: true, 8080: false},
+		Flags: map[string]bool{"on": true, "off": false},
+	}
+	want := v
+	want.Ports = map[int]bool{443: true, 80: true}
+	encode := NewTypedEncoder[setHolder]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			// sets are sorted arrays of their members, other maps are
+			// dictionaries
+			var plist struct {
+				Tags  []string
+				Ports []int
+				Flags map[string]bool
+			}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist.Tags, []string{"archive", "home", "work"}) {
+				t.Errorf("Tags: got %v", plist.Tags)
+			}
+			if !reflect.DeepEqual(plist.Ports, []int{80, 443}) {
+				t.Errorf("Ports: got %v", plist.Ports)
+			}
+			if !reflect.DeepEqual(plist.Flags, v.Flags) {
+				t.Errorf("Flags: got %v", plist.Flags)
+			}
+
+			var got setHolder
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+			got = setHolder{}
+			if _, err := NewTypedDecoder[setHolder]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("TypedDecoder got %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestSetDecode(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{
+		"Tags":  []string{"home", "home", "work"},
+		"Ports": map[string]bool{"22": true},
+	}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// members are added to an existing set, and a dictionary still decodes
+	// as it would without the option, which here is a type error
+	decode := func(got *setHolder) error {
+		_, err := Unmarshal(data, got)
+		return err
+	}
+	typedDecode := func(got *setHolder) error {
+		_, err := NewTypedDecoder[setHolder]().Decode(data, got)
+		return err
+	}
+	for _, f := range []func(*setHolder) error{decode, typedDecode} {
+		got := setHolder{Tags: map[string]struct{}{"old": {}}}
+		var typeErr *UnmarshalTypeError
+		if err := f(&got); !errors.As(err, &typeErr) {
+			t.Errorf("got error %v, want an UnmarshalTypeError", err)
+		}
+		want := map[string]struct{}{"old": {}, "home": {}, "work": {}}
+		if !reflect.DeepEqual(got.Tags, want) {
+			t.Errorf("Tags: got %v, want %v", got.Tags, want)
+		}
+	}
+}
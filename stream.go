@@ -0,0 +1,282 @@
+package plist
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"time"
+)
+
+// ErrTooLarge is returned by Decoder.Decode when the input is larger than the
+// limit set with LimitSize.
+var ErrTooLarge = errors.New("plist: input exceeds size limit")
+
+// A Decoder reads and decodes a property list from an input stream.
+//
+// Property lists can't be parsed incrementally, so Decode reads the input
+// stream to EOF before decoding it.
+//
+// A Decoder keeps the buffer it reads the input into, and other memory used
+// while decoding, from one call to Decode to the next. Servers decoding many
+// requests can keep Decoders in a sync.Pool and Reset them for each request
+// to reuse that memory; the Decoders in a pool should all have the same
+// options, which Reset keeps. A Decoder must not be used concurrently.
+type Decoder struct {
+	r     io.Reader
+	opts  decodeOptions
+	limit int64
+	// noTrailing is set by DisallowTrailingData
+	noTrailing bool
+	// format is the format of the last property list decoded
+	format Format
+	buf    bytes.Buffer
+	state  unmarshalState
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Reset makes dec read from r, keeping its options and buffers.
+func (dec *Decoder) Reset(r io.Reader) {
+	dec.r = r
+}
+
+// DecodeNestedPlists causes the Decoder to decode CFData values that hold a
+// serialized binary or XML property list as if the property list had appeared
+// in place of the data. This is common in preference files, which often embed
+// whole property lists in <data> elements.
+//
+// Data is only decoded this way when the target value is an empty interface
+// or can't hold a []byte, so []byte fields continue to receive the raw data.
+// Data that looks like a property list but fails to parse is treated as
+// ordinary data.
+func (dec *Decoder) DecodeNestedPlists() {
+	dec.opts.nestedPlists = true
+}
+
+// StringifyKeys causes the Decoder to convert dictionary keys that aren't
+// strings, which binary property lists can contain, to strings instead of
+// failing. Numbers are converted to decimal, data to lowercase hex, and
+// booleans to "true" or "false", as CFTypeOptions.StringifyKeys does. If two
+// keys of a dictionary convert to the same string, Decode fails with a
+// DuplicateKeyError.
+func (dec *Decoder) StringifyKeys() {
+	dec.opts.stringifyKeys = true
+}
+
+// UseOrderedDicts causes the Decoder to decode dictionaries into an
+// interface{} as *Dict values rather than maps, preserving the order of their
+// keys in XML input.
+func (dec *Decoder) UseOrderedDicts() {
+	dec.opts.orderedDicts = true
+}
+
+// UseArena causes the Decoder to allocate the strings, data, arrays and
+// dictionaries it decodes into empty interfaces from a, which the caller can
+// release all at once with a.Reset. It has no effect together with
+// UseOrderedDicts or DecodeNestedPlists.
+func (dec *Decoder) UseArena(a *Arena) {
+	dec.opts.arena = a
+}
+
+// UseLocation causes the Decoder to return decoded times in loc, such as
+// time.UTC, rather than the local time zone, so that they compare and format
+// the same way on every machine. Dates decoded into strings are formatted in
+// loc rather than UTC.
+func (dec *Decoder) UseLocation(loc *time.Location) {
+	dec.opts.location = loc
+}
+
+// LimitSize makes Decode fail with ErrTooLarge if the input is larger than n
+// bytes, instead of reading all of it. A limit of 0 means no limit.
+func (dec *Decoder) LimitSize(n int64) {
+	dec.limit = n
+}
+
+// DisallowTrailingData makes Decode fail with ErrTrailingData if the input
+// continues after the end of the property list, as happens when a truncated
+// file has had another appended to it. CoreFoundation ignores anything after
+// the top element of an XML property list; only white space, comments and
+// processing instructions are allowed there. In a binary property list, the
+// offset table must end where the trailer begins. Other formats aren't
+// checked.
+//
+// Unmarshal doesn't make this check. To make it on a []byte, use a Decoder
+// reading from a bytes.Reader.
+func (dec *Decoder) DisallowTrailingData() {
+	dec.noTrailing = true
+}
+
+// Format returns the format of the property list read by the last call to
+// Decode, so that it can be written back out in the same format. It is the
+// zero Format if nothing has been decoded yet, or the last input wasn't
+// recognized as a property list.
+func (dec *Decoder) Format() Format {
+	return dec.format
+}
+
+// Decode reads the property list from its input and stores it in the value
+// pointed to by v.
+//
+// See the documentation for Unmarshal for details about the conversion of a
+// property list into a Go value.
+func (dec *Decoder) Decode(v interface{}) error {
+	dec.format = Format{}
+	r := dec.r
+	if dec.limit > 0 {
+		r = io.LimitReader(r, dec.limit+1)
+	}
+	dec.buf.Reset()
+	if _, err := dec.buf.ReadFrom(r); err != nil {
+		return err
+	}
+	data := dec.buf.Bytes()
+	if dec.limit > 0 && int64(len(data)) > dec.limit {
+		return ErrTooLarge
+	}
+	if dec.noTrailing {
+		if err := checkTrailingData(data); err != nil {
+			return err
+		}
+	}
+	clear(dec.state.keys)
+	dec.state = unmarshalState{
+		decodeOptions: dec.opts,
+		scratch:       dec.state.scratch[:0],
+		keyBuf:        dec.state.keyBuf[:0],
+		keys:          dec.state.keys,
+	}
+	format, err := unmarshal(data, v, &dec.state)
+	dec.format = format
+	return err
+}
+
+// An Encoder writes property lists to an output stream.
+//
+// Like a Decoder, an Encoder keeps its buffers from one call to Encode to the
+// next, and can be kept in a sync.Pool and Reset for each use. An Encoder
+// must not be used concurrently.
+type Encoder struct {
+	w      io.Writer
+	format Format
+	bw     *bufio.Writer
+	xw     *xmlStreamWriter
+	binw   *binaryStreamWriter
+
+	keyOrder       KeyOrder
+	fieldOrder     bool
+	nilPolicy      NilPolicy
+	nilPlaceholder interface{}
+}
+
+// NewEncoder returns a new encoder that writes to w in the given format, which
+// must be XMLFormat or BinaryFormat.
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{w: w, format: format}
+}
+
+// Reset makes enc write to w, keeping its format and buffers.
+func (enc *Encoder) Reset(w io.Writer) {
+	enc.w = w
+}
+
+// SetKeyOrder makes enc write the keys of the dictionaries converted from maps,
+// structs and sync.Maps in the order given by order, rather than sorted as
+// CoreFoundation sorts them, or for structs, in the order KeepFieldOrder
+// gives. Dicts and iterators keep the order of their
+// keys. A nil order restores the default.
+func (enc *Encoder) SetKeyOrder(order KeyOrder) {
+	enc.keyOrder = order
+}
+
+// KeepFieldOrder makes enc write the fields of structs in the order they're
+// declared, rather than sorted by name as CoreFoundation sorts dictionary
+// keys, so that the output reads like the struct definition. A KeyOrder set with SetKeyOrder still applies, with
+// the keys it doesn't order left in declaration order.
+func (enc *Encoder) KeepFieldOrder() {
+	enc.fieldOrder = true
+}
+
+// SetNilPolicy sets what enc does with nil pointers and interface values. By
+// default they are an error, as they are for Marshal, which has no other
+// policy; to marshal into memory with another one, Encode into a
+// bytes.Buffer.
+func (enc *Encoder) SetNilPolicy(policy NilPolicy) {
+	enc.nilPolicy = policy
+}
+
+// SetNilPlaceholder makes enc write placeholder, such as an empty string, in
+// place of each nil pointer or interface element of an array or slice,
+// whatever the NilPolicy, so that the other elements keep their positions.
+// Together with NilOmit, which leaves nil map values out, a single nil deep
+// inside a large value doesn't make Encode fail. A nil placeholder restores
+// the default.
+func (enc *Encoder) SetNilPlaceholder(placeholder interface{}) {
+	enc.nilPlaceholder = placeholder
+}
+
+// SetFormat makes enc write property lists in the given format, which must be
+// XMLFormat or BinaryFormat, instead of the one passed to NewEncoder.
+func (enc *Encoder) SetFormat(format Format) {
+	enc.format = format
+}
+
+// Encode writes the property list encoding of v to the stream.
+//
+// See the documentation for Marshal for details about the conversion of a Go
+// value into a property list. Unlike Marshal, Encode doesn't build the whole
+// property list in memory before writing it out: it writes each value as it
+// is converted, with its own XML and binary writers, so memory use depends
+// on how deeply v is nested rather than on its size. Only values returned by
+// MarshalPlistCF are converted from CoreFoundation objects.
+//
+// The XML output is the same as that of Marshal. Binary output decodes to the
+// same property list, but isn't laid out the same way: equal values aren't
+// shared, and since the number of objects isn't known until the end, object
+// references are always 4 bytes. Besides the output itself, Encode keeps the
+// offset of each object, 8 bytes per value, until the end of the property list.
+//
+// If Encode returns an error, part of the property list may already have been
+// written.
+func (enc *Encoder) Encode(v interface{}) (err error) {
+	defer recoverPanic(&err)
+	if enc.bw == nil {
+		enc.bw = bufio.NewWriter(enc.w)
+	} else {
+		// drop anything left over from a failed Encode
+		enc.bw.Reset(enc.w)
+	}
+	state := &streamState{
+		keyOrder:       enc.keyOrder,
+		fieldOrder:     enc.fieldOrder,
+		nilPolicy:      enc.nilPolicy,
+		nilPlaceholder: reflect.ValueOf(enc.nilPlaceholder),
+	}
+	switch enc.format {
+	case XMLFormat:
+		if enc.xw == nil {
+			enc.xw = new(xmlStreamWriter)
+		}
+		enc.xw.reset(enc.bw)
+		state.w = enc.xw
+	case BinaryFormat:
+		if enc.binw == nil {
+			enc.binw = new(binaryStreamWriter)
+		}
+		enc.binw.reset(enc.bw)
+		state.w = enc.binw
+	default:
+		return errors.New("plist: Encoder only writes XML and binary property lists")
+	}
+	if state.keyOrder != nil {
+		state.w = &keyPathWriter{streamWriter: state.w}
+	}
+	if err := state.encodeValue(reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	return state.w.finish()
+}
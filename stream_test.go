@@ -0,0 +1,371 @@
+package plist
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecoder(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{"X": "hello", "Y": 3}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v T
+	if err := NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if want := (T{X: "hello", Y: 3}); v != want {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
+
+func TestDecodeNestedPlists(t *testing.T) {
+	inner, err := Marshal(map[string]interface{}{"X": "nested", "Y": 7}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	innerXML, err := Marshal([]interface{}{"a", "b"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outer, err := Marshal(map[string]interface{}{
+		"Struct": inner,
+		"Raw":    inner,
+		"Any":    innerXML,
+		"Other":  []byte("not a plist"),
+	}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		Struct T
+		Raw    []byte
+		Any    interface{}
+		Other  interface{}
+	}
+	dec := NewDecoder(bytes.NewReader(outer))
+	dec.DecodeNestedPlists()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if want := (T{X: "nested", Y: 7}); v.Struct != want {
+		t.Errorf("Struct: got %#v, want %#v", v.Struct, want)
+	}
+	if !bytes.Equal(v.Raw, inner) {
+		t.Errorf("Raw: data was not left alone")
+	}
+	if !reflect.DeepEqual(v.Any, []interface{}{"a", "b"}) {
+		t.Errorf("Any: got %#v", v.Any)
+	}
+	if !reflect.DeepEqual(v.Other, []byte("not a plist")) {
+		t.Errorf("Other: got %#v", v.Other)
+	}
+
+	// without the option, the nested plist is a type error for the struct
+	v.Struct = T{}
+	if err := NewDecoder(bytes.NewReader(outer)).Decode(&v); err == nil {
+		t.Error("expected type error without DecodeNestedPlists")
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	type methods struct {
+		R *Ref
+		V Val
+	}
+	d := &Dict{}
+	d.Set("z", "last")
+	d.Set("a", []interface{}{int64(1), 2.5, true})
+	v := map[string]interface{}{
+		"struct":  Optionals{Sr: "x", Io: 3, Slo: []string{"a", "b"}, Mr: map[string]interface{}{}},
+		"ordered": d,
+		"data":    bytes.Repeat([]byte("0123456789"), 20),
+		"date":    time.Date(2020, 1, 2, 3, 4, 5, 6e6, time.UTC),
+		"strings": []string{"", "ascii", "h\u00e9llo \U0001D11E", "invalid \xff", strings.Repeat("long", 10)},
+		"numbers": []interface{}{0, 255, 256, 1 << 20, 1 << 40, -1, uint32(math.MaxUint32), float32(0.1)},
+		"uid":     UID(300),
+		"methods": methods{new(Ref), 3},
+		"empty":   map[string]interface{}{"array": []int{}, "dict": map[string]int{}},
+	}
+	want, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, XMLFormat).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("XML: got\n%s\nwant\n%s", buf.Bytes(), want)
+	}
+
+	buf.Reset()
+	if err := NewEncoder(&buf, BinaryFormat).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	var got, wantValue interface{}
+	if _, err := Unmarshal(want, &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if format, err := Unmarshal(buf.Bytes(), &got); err != nil || format != BinaryFormat {
+		t.Fatalf("binary: got %v, %v", format, err)
+	}
+	if !Equal(got, wantValue) {
+		t.Errorf("binary: got %#v, want %#v", got, wantValue)
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	for _, v := range unsupportedValues {
+		for _, format := range []Format{XMLFormat, BinaryFormat} {
+			_, want := Marshal(v, format)
+			err := NewEncoder(&bytes.Buffer{}, format).Encode(v)
+			if err == nil || err.Error() != want.Error() {
+				t.Errorf("%v: got error %v, want %v", v, err, want)
+			}
+		}
+	}
+	if err := NewEncoder(&bytes.Buffer{}, OpenStepFormat).Encode("x"); err == nil {
+		t.Error("OpenStep: got no error")
+	}
+}
+
+func TestEncoderReset(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{"a": []interface{}{"b", int64(1)}, "c": true},
+		[]interface{}{math.NaN()}, // fails after part of the output is buffered
+		"short",
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		enc := NewEncoder(nil, format)
+		for _, v := range values {
+			var buf bytes.Buffer
+			enc.Reset(&buf)
+			err := enc.Encode(v)
+			want, wantErr := Marshal(v, format)
+			if wantErr != nil {
+				if err == nil {
+					t.Errorf("%s %v: got no error", format, v)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s %v: %v", format, v, err)
+			}
+			if format == XMLFormat && !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s %v: got %q, want %q", format, v, buf.Bytes(), want)
+			}
+			var got interface{}
+			if _, err := Unmarshal(buf.Bytes(), &got); err != nil || !Equal(got, v) {
+				t.Errorf("%s %v: decoded %v, %v", format, v, got, err)
+			}
+		}
+	}
+}
+
+func TestDecoderReset(t *testing.T) {
+	dec := NewDecoder(nil)
+	dec.LimitSize(1 << 20)
+	for _, v := range []interface{}{
+		map[string]interface{}{"long": strings.Repeat("x", 1000), "n": int64(1)},
+		map[string]interface{}{"n": int64(2)},
+		[]interface{}{"a", map[string]interface{}{"n": int64(3)}},
+	} {
+		data, err := Marshal(v, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec.Reset(bytes.NewReader(data))
+		var got interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("got %#v, want %#v", got, v)
+		}
+	}
+	dec.Reset(strings.NewReader(strings.Repeat(" ", 2<<20)))
+	if err := dec.Decode(new(interface{})); err != ErrTooLarge {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestDecoderUseLocation(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	data, err := Marshal(map[string]interface{}{
+		"Time":   when,
+		"String": when,
+		"Unix":   when.Unix(),
+		"List":   []interface{}{when},
+	}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokyo := time.FixedZone("JST", 9*60*60)
+	for _, loc := range []*time.Location{time.UTC, tokyo} {
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.UseLocation(loc)
+		var got struct {
+			Time   time.Time
+			String string
+			Unix   time.Time `plist:",format=unix"`
+			List   []interface{}
+		}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		for _, tm := range []time.Time{got.Time, got.Unix, got.List[0].(time.Time)} {
+			if tm.Location() != loc || !tm.Equal(when) {
+				t.Errorf("%s: got %v", loc, tm)
+			}
+		}
+		if want := when.In(loc).Format(time.RFC3339Nano); got.String != want {
+			t.Errorf("%s: got %q, want %q", loc, got.String, want)
+		}
+
+		dec = NewDecoder(bytes.NewReader(data))
+		dec.UseLocation(loc)
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if tm := m["Time"].(time.Time); tm.Location() != loc {
+			t.Errorf("%s: got %v in a map", loc, tm)
+		}
+	}
+}
+
+func TestEncoderKeepFieldOrder(t *testing.T) {
+	type job struct {
+		Label     string
+		Program   string
+		Arguments []string
+		Nested    struct{ Zeta, Alpha int }
+	}
+	v := job{Label: "com.example.job", Program: "/bin/echo", Arguments: []string{"hi"}}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, XMLFormat)
+	enc.KeepFieldOrder()
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	o := keyOrderOf(t, data, "Label", "Program", "Arguments", "Nested", "Zeta", "Alpha")
+	for i := 1; i < len(o); i++ {
+		if o[i-1] > o[i] {
+			t.Errorf("fields out of order:\n%s", data)
+			break
+		}
+	}
+	var got job
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("got %+v, want %+v", got, v)
+	}
+
+	// a KeyOrder still applies, keeping declaration order for the rest
+	buf.Reset()
+	enc.SetKeyOrder(KeyPriority(map[string][]string{"": {"Nested"}}))
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	data = buf.Bytes()
+	o = keyOrderOf(t, data, "Nested", "Zeta", "Alpha", "Label", "Program", "Arguments")
+	for i := 1; i < len(o); i++ {
+		if o[i-1] > o[i] {
+			t.Errorf("fields out of order:\n%s", data)
+			break
+		}
+	}
+}
+
+func TestFormatAccessors(t *testing.T) {
+	v := map[string]interface{}{"a": "b", "n": int64(1)}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, XMLFormat)
+	enc.SetFormat(BinaryFormat)
+	if err := enc.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("bplist00")) {
+		t.Fatalf("SetFormat(BinaryFormat) wrote %q", buf.Bytes())
+	}
+
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()))
+	if f := dec.Format(); f != (Format{}) {
+		t.Errorf("Format before Decode: got %v", f)
+	}
+	var got map[string]interface{}
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if f := dec.Format(); f != BinaryFormat {
+		t.Errorf("Format: got %v, want %v", f, BinaryFormat)
+	}
+
+	// read whatever, write it back in the same format
+	var out bytes.Buffer
+	enc = NewEncoder(&out, XMLFormat)
+	enc.SetFormat(dec.Format())
+	if err := enc.Encode(got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), buf.Bytes()) {
+		t.Errorf("got %q, want %q", out.Bytes(), buf.Bytes())
+	}
+
+	// a type error still records the format
+	data, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec.Reset(bytes.NewReader(data))
+	var wrong []string
+	if err := dec.Decode(&wrong); err == nil {
+		t.Error("expected an error decoding a dictionary into a slice")
+	}
+	if f := dec.Format(); f != XMLFormat {
+		t.Errorf("Format: got %v, want %v", f, XMLFormat)
+	}
+}
+
+// intKeyPlist returns a binary plist holding a dictionary with the keys 1 and
+// key, which must be a single ASCII character.
+func intKeyPlist(key string) []byte {
+	return []byte("bplist00" + "\xd2\x01\x02\x03\x04" + "\x10\x01" + "\x51" + key + "\x51a" + "\x51b" +
+		"\x08\x0d\x0f\x11\x13" + "\x00\x00\x00\x00\x00\x00\x01\x01" +
+		"\x00\x00\x00\x00\x00\x00\x00\x05" + "\x00\x00\x00\x00\x00\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x15")
+}
+
+func TestDecoderStringifyKeys(t *testing.T) {
+	var got map[string]interface{}
+	err := NewDecoder(bytes.NewReader(intKeyPlist("x"))).Decode(&got)
+	if _, ok := err.(*UnsupportedKeyTypeError); !ok {
+		t.Errorf("without StringifyKeys: got %v, want an UnsupportedKeyTypeError", err)
+	}
+	dec := NewDecoder(bytes.NewReader(intKeyPlist("x")))
+	dec.StringifyKeys()
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"1": "a", "x": "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	// the key 1 and the key "1" both convert to "1"
+	for _, v := range []interface{}{new(interface{}), new(map[string]string)} {
+		dec := NewDecoder(bytes.NewReader(intKeyPlist("1")))
+		dec.StringifyKeys()
+		err := dec.Decode(v)
+		if e, ok := err.(*DuplicateKeyError); !ok || e.Key != "1" {
+			t.Errorf("%T: got %v, want a DuplicateKeyError", v, err)
+		}
+	}
+}
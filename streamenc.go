@@ -0,0 +1,687 @@
+package plist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// A streamWriter writes the values of a property list in document order:
+// each container is begun, has its elements (and for dictionaries, their
+// keys) written, and is ended.
+type streamWriter interface {
+	// beginArray and beginDict begin a container of n elements, or of an
+	// unknown number if n is negative
+	beginArray(n int) error
+	endArray() error
+	beginDict(n int) error
+	key(k string) error
+	endDict() error
+	// value writes a string, bool, int64, float64, time.Time, Date, []byte or
+	// UID
+	value(v interface{}) error
+	// finish completes the property list once the top value is written
+	finish() error
+}
+
+// streamState holds the state of a single call to Encoder.Encode. It walks
+// values as marshalValue does, but hands them to a streamWriter instead of
+// creating CoreFoundation objects.
+type streamState struct {
+	w streamWriter
+	// keyOrder, if set, orders the keys of the dictionaries that have no
+	// order of their own, and w is a keyPathWriter
+	keyOrder KeyOrder
+	// fieldOrder is whether struct fields are written in the order they're
+	// declared rather than sorted by name
+	fieldOrder bool
+	nilPolicy  NilPolicy
+	// nilPlaceholder, if valid, is written in place of nil array elements
+	nilPlaceholder reflect.Value
+}
+
+func (state *streamState) encodeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		return &UnsupportedValueError{v, "invalid value"}
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return state.encodeNil(v)
+	}
+
+	cm, ok := v.Interface().(CFMarshaler)
+	if !ok && v.Kind() != reflect.Ptr && v.CanAddr() {
+		cm, ok = v.Addr().Interface().(CFMarshaler)
+	}
+	if ok {
+		obj, err := cm.MarshalPlistCF()
+		if err != nil {
+			return err
+		}
+		if obj.IsNil() {
+			return &UnsupportedValueError{v, "nil CFObject from MarshalPlistCF"}
+		}
+		plist, err := convertCFTypeToInterface(cfTypeRef(obj.Ref()))
+		if err != nil {
+			return err
+		}
+		return state.encodePlain(reflect.ValueOf(plist))
+	}
+
+	m, ok := v.Interface().(Marshaler)
+	if !ok && v.Kind() != reflect.Ptr && v.CanAddr() {
+		m, ok = v.Addr().Interface().(Marshaler)
+	}
+	if ok {
+		obj, err := m.MarshalPlist()
+		if err != nil {
+			return err
+		}
+		return state.encodePlain(reflect.ValueOf(obj))
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type() == byteSliceType {
+			return state.w.value(v.Bytes())
+		}
+		if isUUIDType(v.Type()) {
+			return state.w.value(formatUUID(uuidOf(v)))
+		}
+		if v.Type() == ipType {
+			return state.encodeAddress(v)
+		}
+		return state.encodeArray(v, state.encodeValue)
+	case reflect.Map:
+		return state.encodeMap(v, state.encodeValue)
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return state.w.value(v.Interface().(time.Time))
+		}
+		if v.Type() == urlType {
+			u := v.Interface().(url.URL)
+			return state.w.value(u.String())
+		}
+		if v.Type() == addrType {
+			return state.encodeAddress(v)
+		}
+		if v.Type() == dictType {
+			d := v.Interface().(Dict)
+			return state.encodeDict(&d)
+		}
+		if v.Type() == syncMapType {
+			return state.encodeSyncMap(syncMapOf(v))
+		}
+		return state.encodeStruct(v)
+	case reflect.Ptr, reflect.Interface:
+		return state.encodeValue(v.Elem())
+	case reflect.Func:
+		if arity := seqArity(v.Type()); arity != 0 {
+			return state.encodeSeq(v, arity)
+		}
+	}
+	return state.encodePlain(v)
+}
+
+// encodePlain writes v as convertValueToCFType converts it, which is how
+// values returned by MarshalPlist are converted.
+func (state *streamState) encodePlain(v reflect.Value) error {
+	if !v.IsValid() {
+		return &UnsupportedValueError{v, "invalid value"}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return state.w.value(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return state.w.value(v.Int())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if v.Type() == uidType {
+			return state.w.value(UID(v.Uint()))
+		}
+		return state.w.value(int64(v.Uint()))
+	case reflect.Uint, reflect.Uintptr:
+		if v.Type().Bits() < 64 {
+			return state.w.value(int64(v.Uint()))
+		}
+	case reflect.Float32, reflect.Float64:
+		if v.Type() == dateType {
+			return state.w.value(Date(v.Float()))
+		}
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return &UnsupportedValueError{v, strconv.FormatFloat(f, 'g', -1, v.Type().Bits())}
+		}
+		return state.w.value(f)
+	case reflect.String:
+		return state.w.value(v.String())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return state.w.value(v.Interface().(time.Time))
+		}
+		if v.Type() == urlType {
+			u := v.Interface().(url.URL)
+			return state.w.value(u.String())
+		}
+		if v.Type() == addrType {
+			return state.encodeAddress(v)
+		}
+	case reflect.Array, reflect.Slice:
+		if isUUIDType(v.Type()) {
+			return state.w.value(formatUUID(uuidOf(v)))
+		}
+		if v.Type() == ipType {
+			return state.encodeAddress(v)
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return state.w.value(data)
+		}
+		return state.encodeArray(v, state.encodePlain)
+	case reflect.Map:
+		return state.encodeMap(v, state.encodePlain)
+	case reflect.Interface:
+		if v.IsNil() {
+			return state.encodeNil(v)
+		}
+		return state.encodePlain(v.Elem())
+	}
+	return &UnsupportedTypeError{v.Type()}
+}
+
+// encodeArray writes the array or slice v, writing the elements with encode.
+func (state *streamState) encodeArray(v reflect.Value, encode func(reflect.Value) error) error {
+	n := v.Len()
+	if state.nilPolicy == NilOmit && !state.nilPlaceholder.IsValid() {
+		for i := 0; i < v.Len(); i++ {
+			if isNilValue(v.Index(i)) {
+				n--
+			}
+		}
+	}
+	if err := state.w.beginArray(n); err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := state.encodeElement(v.Index(i), encode); err != nil {
+			return err
+		}
+	}
+	return state.w.endArray()
+}
+
+// encodeElement writes v, an element of an array, with encode, unless it is
+// nil and replaced with the placeholder or left out.
+func (state *streamState) encodeElement(v reflect.Value, encode func(reflect.Value) error) error {
+	if isNilValue(v) {
+		if state.nilPlaceholder.IsValid() {
+			return state.encodeValue(state.nilPlaceholder)
+		}
+		if state.nilPolicy == NilOmit {
+			return nil
+		}
+	}
+	return encode(v)
+}
+
+// encodeMap writes the map v with its keys sorted, as CoreFoundation sorts
+// them in XML, writing the values with encode.
+func (state *streamState) encodeMap(v reflect.Value, encode func(reflect.Value) error) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return &UnsupportedTypeError{v.Type()}
+	}
+	keys := v.MapKeys()
+	if err := checkValueKeys(keys); err != nil {
+		return err
+	}
+	if state.nilPolicy == NilOmit {
+		kept := keys[:0]
+		for _, key := range keys {
+			if !isNilValue(v.MapIndex(key)) {
+				kept = append(kept, key)
+			}
+		}
+		keys = kept
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	state.orderKeys(keys, func(i int) string { return keys[i].String() })
+	if err := state.w.beginDict(len(keys)); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := state.w.key(key.String()); err != nil {
+			return err
+		}
+		if err := encode(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+	return state.w.endDict()
+}
+
+// encodeDict writes d with its keys in order.
+func (state *streamState) encodeDict(d *Dict) error {
+	if err := checkKeys(d.keys); err != nil {
+		return err
+	}
+	keys := d.keys
+	if state.nilPolicy == NilOmit {
+		keys = nil
+		for _, key := range d.keys {
+			if !isNilValue(reflect.ValueOf(d.values[key])) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if err := state.w.beginDict(len(keys)); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := state.w.key(key); err != nil {
+			return err
+		}
+		if err := state.encodeValue(reflect.ValueOf(d.values[key])); err != nil {
+			return err
+		}
+	}
+	return state.w.endDict()
+}
+
+var sortedFieldsCache = make(map[reflect.Type][]encodeField)
+
+// sortedEncodeFields returns the encodeFields of t sorted by name.
+func sortedEncodeFields(t reflect.Type) []encodeField {
+	typeCacheLock.RLock()
+	fs, ok := sortedFieldsCache[t]
+	typeCacheLock.RUnlock()
+	if ok {
+		return fs
+	}
+	fs = append([]encodeField(nil), encodeFields(t)...)
+	sort.SliceStable(fs, func(i, j int) bool { return fs[i].name < fs[j].name })
+	typeCacheLock.Lock()
+	sortedFieldsCache[t] = fs
+	typeCacheLock.Unlock()
+	return fs
+}
+
+func (state *streamState) encodeStruct(v reflect.Value) error {
+	var fields []encodeField
+	if state.fieldOrder {
+		fields = encodeFields(v.Type())
+	} else {
+		fields = sortedEncodeFields(v.Type())
+	}
+	if state.keyOrder != nil {
+		fields = append([]encodeField(nil), fields...)
+		state.orderKeys(fields, func(i int) string { return fields[i].name })
+	}
+	n := 0
+	for _, ef := range fields {
+		fieldValue := v.Field(ef.i)
+		if !ef.omitted(fieldValue) && !state.omit(fieldValue) {
+			n++
+		}
+	}
+	if err := state.w.beginDict(n); err != nil {
+		return err
+	}
+	for _, ef := range fields {
+		fieldValue := v.Field(ef.i)
+		if ef.omitted(fieldValue) || state.omit(fieldValue) {
+			continue
+		}
+		if err := state.w.key(ef.name); err != nil {
+			return err
+		}
+		if err := state.encodeFieldValue(&ef, fieldValue); err != nil {
+			return err
+		}
+	}
+	return state.w.endDict()
+}
+
+// encodeFieldValue writes v, the value of the field ef, as the options of the
+// field say.
+func (state *streamState) encodeFieldValue(ef *encodeField, v reflect.Value) error {
+	if obj, ok := ef.encodeAs(v); ok {
+		return state.w.value(obj)
+	}
+	if s, ok := ef.tupleStruct(v); ok {
+		return state.encodeTuple(s)
+	}
+	if s, ok := ef.setSlice(v); ok {
+		return state.encodeValue(s)
+	}
+	return state.encodeValue(v)
+}
+
+// validString replaces each invalid byte in s with U+FFFD, as
+// convertStringToCFString does.
+func validString(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	b := make([]byte, 0, len(s)+8)
+	for _, r := range s {
+		b = utf8.AppendRune(b, r)
+	}
+	return string(b)
+}
+
+// absoluteTime returns t as a CFAbsoluteTime, truncated to milliseconds as
+// convertTimeToCFDate does.
+func absoluteTime(t time.Time) float64 {
+	ms := time.Duration(t.UnixNano()) / time.Millisecond * time.Millisecond
+	return float64(ms)/float64(time.Second) - 978307200
+}
+
+// xmlStreamWriter writes an XML property list laid out as
+// CFPropertyListCreateData lays it out.
+type xmlStreamWriter struct {
+	w      *bufio.Writer
+	buf    []byte
+	indent int
+	// empty records, for each open container, whether it has no elements
+	empty []bool
+	// pending is the name of the innermost open container if its number of
+	// elements wasn't known, and its start tag hasn't been written because
+	// it has no elements yet
+	pending string
+}
+
+// reset begins a new property list written to w, keeping the buffers of xw.
+func (xw *xmlStreamWriter) reset(w *bufio.Writer) {
+	*xw = xmlStreamWriter{w: w, buf: xw.buf[:0], empty: xw.empty[:0]}
+	xw.w.WriteString(xmlPlistHeader)
+}
+
+func (xw *xmlStreamWriter) write(b []byte) error {
+	xw.buf = b[:0]
+	_, err := xw.w.Write(b)
+	return err
+}
+
+// flush writes the start tag of the pending container, which now has an
+// element.
+func (xw *xmlStreamWriter) flush() error {
+	if xw.pending == "" {
+		return nil
+	}
+	b := appendXMLIndent(xw.buf, xw.indent-1)
+	b = append(append(append(b, '<'), xw.pending...), ">\n"...)
+	xw.pending = ""
+	xw.empty[len(xw.empty)-1] = false
+	return xw.write(b)
+}
+
+func (xw *xmlStreamWriter) begin(name string, n int) error {
+	if err := xw.flush(); err != nil {
+		return err
+	}
+	if n < 0 {
+		xw.empty = append(xw.empty, true)
+		xw.indent++
+		xw.pending = name
+		return nil
+	}
+	b := appendXMLIndent(xw.buf, xw.indent)
+	xw.empty = append(xw.empty, n == 0)
+	xw.indent++
+	if n == 0 {
+		return xw.write(append(append(append(b, '<'), name...), "/>\n"...))
+	}
+	return xw.write(append(append(append(b, '<'), name...), ">\n"...))
+}
+
+func (xw *xmlStreamWriter) end(name string) error {
+	xw.indent--
+	empty := xw.empty[len(xw.empty)-1]
+	xw.empty = xw.empty[:len(xw.empty)-1]
+	if xw.pending != "" {
+		xw.pending = ""
+		b := appendXMLIndent(xw.buf, xw.indent)
+		return xw.write(append(append(append(b, '<'), name...), "/>\n"...))
+	}
+	if empty {
+		return nil
+	}
+	b := appendXMLIndent(xw.buf, xw.indent)
+	return xw.write(append(append(append(b, "</"...), name...), ">\n"...))
+}
+
+func (xw *xmlStreamWriter) beginArray(n int) error { return xw.begin("array", n) }
+func (xw *xmlStreamWriter) endArray() error        { return xw.end("array") }
+func (xw *xmlStreamWriter) beginDict(n int) error  { return xw.begin("dict", n) }
+func (xw *xmlStreamWriter) endDict() error         { return xw.end("dict") }
+
+func (xw *xmlStreamWriter) key(k string) error {
+	if err := xw.flush(); err != nil {
+		return err
+	}
+	b := appendXMLIndent(xw.buf, xw.indent)
+	return xw.write(appendXMLElement(b, "key", validString(k)))
+}
+
+func (xw *xmlStreamWriter) value(v interface{}) error {
+	if err := xw.flush(); err != nil {
+		return err
+	}
+	if s, ok := v.(string); ok {
+		v = validString(s)
+	}
+	b := appendXMLIndent(xw.buf, xw.indent)
+	return xw.write(appendXMLValue(b, v, xw.indent))
+}
+
+func (xw *xmlStreamWriter) finish() error {
+	if _, err := xw.w.WriteString("</plist>\n"); err != nil {
+		return err
+	}
+	return xw.w.Flush()
+}
+
+// binaryObjectRefSize is the size of the object references written by
+// binaryStreamWriter. References are written before the number of objects is
+// known, so they are always wide enough for any property list that could be
+// written in practice.
+const binaryObjectRefSize = 4
+
+var errTooManyObjects = errors.New("plist: too many objects for a binary property list")
+
+// binaryStreamWriter writes a binary property list. Objects are written as
+// soon as they are complete, so containers follow their elements, and only
+// the offset of each object, and the references of the open containers, are
+// kept in memory.
+type binaryStreamWriter struct {
+	w       *bufio.Writer
+	buf     []byte
+	offset  uint64   // bytes written so far
+	offsets []uint64 // offset of each object
+	// refs holds the references of the elements of the open containers, and
+	// the keys and values of open dictionaries in turn; starts holds the
+	// index in refs where each open container begins
+	refs   []uint64
+	starts []int
+}
+
+// reset begins a new property list written to w, keeping the buffers of bw.
+func (bw *binaryStreamWriter) reset(w *bufio.Writer) {
+	*bw = binaryStreamWriter{
+		w:       w,
+		buf:     bw.buf[:0],
+		offsets: bw.offsets[:0],
+		refs:    bw.refs[:0],
+		starts:  bw.starts[:0],
+	}
+	bw.w.WriteString("bplist00")
+	bw.offset = 8
+}
+
+// object writes b as the next object and adds a reference to it to the open
+// container.
+func (bw *binaryStreamWriter) object(b []byte) error {
+	if uint64(len(bw.offsets)) == 1<<(8*binaryObjectRefSize)-1 {
+		return errTooManyObjects
+	}
+	bw.refs = append(bw.refs, uint64(len(bw.offsets)))
+	bw.offsets = append(bw.offsets, bw.offset)
+	bw.offset += uint64(len(b))
+	bw.buf = b[:0]
+	_, err := bw.w.Write(b)
+	return err
+}
+
+// appendBinaryUint appends u as a big-endian integer of size bytes.
+func appendBinaryUint(b []byte, u uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(u>>(8*uint(i))))
+	}
+	return b
+}
+
+// appendBinaryInt appends an integer object in the smallest size that
+// CoreFoundation reads back as i.
+func appendBinaryInt(b []byte, i int64) []byte {
+	switch {
+	case i < 0 || i > math.MaxUint32:
+		return appendBinaryUint(append(b, 0x13), uint64(i), 8)
+	case i > math.MaxUint16:
+		return appendBinaryUint(append(b, 0x12), uint64(i), 4)
+	case i > math.MaxUint8:
+		return appendBinaryUint(append(b, 0x11), uint64(i), 2)
+	}
+	return append(b, 0x10, byte(i))
+}
+
+// appendBinaryMarker appends the marker of an object of the given kind with n
+// elements.
+func appendBinaryMarker(b []byte, kind byte, n int) []byte {
+	if n < 15 {
+		return append(b, kind|byte(n))
+	}
+	return appendBinaryInt(append(b, kind|0xf), int64(n))
+}
+
+func (bw *binaryStreamWriter) begin(n int) error {
+	bw.starts = append(bw.starts, len(bw.refs))
+	return nil
+}
+
+// end writes the container whose elements have been written, with the given
+// kind and count.
+func (bw *binaryStreamWriter) end(kind byte, pairs bool) error {
+	start := bw.starts[len(bw.starts)-1]
+	bw.starts = bw.starts[:len(bw.starts)-1]
+	refs := bw.refs[start:]
+	n := len(refs)
+	if pairs {
+		n /= 2
+	}
+	b := appendBinaryMarker(bw.buf, kind, n)
+	if pairs {
+		// the keys, then the values
+		for i := 0; i < len(refs); i += 2 {
+			b = appendBinaryUint(b, refs[i], binaryObjectRefSize)
+		}
+		for i := 1; i < len(refs); i += 2 {
+			b = appendBinaryUint(b, refs[i], binaryObjectRefSize)
+		}
+	} else {
+		for _, ref := range refs {
+			b = appendBinaryUint(b, ref, binaryObjectRefSize)
+		}
+	}
+	bw.refs = bw.refs[:start]
+	return bw.object(b)
+}
+
+func (bw *binaryStreamWriter) beginArray(n int) error { return bw.begin(n) }
+func (bw *binaryStreamWriter) endArray() error        { return bw.end(0xa0, false) }
+func (bw *binaryStreamWriter) beginDict(n int) error  { return bw.begin(n) }
+func (bw *binaryStreamWriter) endDict() error         { return bw.end(0xd0, true) }
+func (bw *binaryStreamWriter) key(k string) error     { return bw.value(k) }
+
+func (bw *binaryStreamWriter) value(v interface{}) error {
+	b := bw.buf
+	switch v := v.(type) {
+	case string:
+		v = validString(v)
+		ascii := true
+		for i := 0; i < len(v); i++ {
+			if v[i] >= utf8.RuneSelf {
+				ascii = false
+				break
+			}
+		}
+		if ascii {
+			b = append(appendBinaryMarker(b, 0x50, len(v)), v...)
+		} else {
+			units := utf16.Encode([]rune(v))
+			b = appendBinaryMarker(b, 0x60, len(units))
+			for _, u := range units {
+				b = append(b, byte(u>>8), byte(u))
+			}
+		}
+	case bool:
+		if v {
+			b = append(b, 0x09)
+		} else {
+			b = append(b, 0x08)
+		}
+	case int64:
+		b = appendBinaryInt(b, v)
+	case float64:
+		b = appendBinaryUint(append(b, 0x23), math.Float64bits(v), 8)
+	case time.Time:
+		b = appendBinaryUint(append(b, 0x33), math.Float64bits(absoluteTime(v)), 8)
+	case Date:
+		b = appendBinaryUint(append(b, 0x33), math.Float64bits(float64(v)), 8)
+	case []byte:
+		b = append(appendBinaryMarker(b, 0x40, len(v)), v...)
+	case UID:
+		size := 1
+		for size < 8 && uint64(v) >= 1<<(8*uint(size)) {
+			size *= 2
+		}
+		b = appendBinaryUint(append(b, 0x80|byte(size-1)), uint64(v), size)
+	}
+	return bw.object(b)
+}
+
+func (bw *binaryStreamWriter) finish() error {
+	tableOffset := bw.offset
+	offsetSize := 1
+	for offsetSize < 8 && tableOffset >= 1<<(8*uint(offsetSize)) {
+		offsetSize *= 2
+	}
+	b := bw.buf
+	for _, offset := range bw.offsets {
+		b = appendBinaryUint(b, offset, offsetSize)
+		if len(b) >= 4096 {
+			if _, err := bw.w.Write(b); err != nil {
+				return err
+			}
+			b = b[:0]
+		}
+	}
+	var trailer [32]byte
+	trailer[6] = byte(offsetSize)
+	trailer[7] = binaryObjectRefSize
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(bw.offsets)))
+	// the top object is the last one written
+	binary.BigEndian.PutUint64(trailer[16:], uint64(len(bw.offsets)-1))
+	binary.BigEndian.PutUint64(trailer[24:], tableOffset)
+	b = append(b, trailer[:]...)
+	if _, err := bw.w.Write(b); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
@@ -0,0 +1,104 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// stringParsers holds the parsers registered with RegisterStringParser.
+var stringParsers = struct {
+	sync.RWMutex
+	m map[reflect.Type]func(string) (reflect.Value, error)
+}{m: make(map[reflect.Type]func(string) (reflect.Value, error))}
+
+// RegisterStringParser registers parse as the way to decode a CFString into
+// a struct field of type T that has the "stringer" option. Without one, such
+// a field is decoded with its UnmarshalText method if *T implements
+// encoding.TextUnmarshaler. Parsers should be registered before anything is
+// decoded, such as in an init function.
+func RegisterStringParser[T any](parse func(string) (T, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	stringParsers.Lock()
+	defer stringParsers.Unlock()
+	stringParsers.m[t] = func(s string) (reflect.Value, error) {
+		v, err := parse(s)
+		return reflect.ValueOf(&v).Elem(), err
+	}
+}
+
+// stringParser returns the function that parses strings into values of t for
+// fields with the "stringer" option, or nil if there isn't one. A pointer type
+// uses the parser of the type it points to.
+func stringParser(t reflect.Type) func(string) (reflect.Value, error) {
+	stringParsers.RLock()
+	parse := stringParsers.m[t]
+	stringParsers.RUnlock()
+	if parse != nil {
+		return parse
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return func(s string) (reflect.Value, error) {
+			p := reflect.New(t)
+			err := p.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+			return p.Elem(), err
+		}
+	}
+	if t.Kind() == reflect.Ptr {
+		if parse := stringParser(t.Elem()); parse != nil {
+			return func(s string) (reflect.Value, error) {
+				v, err := parse(s)
+				p := reflect.New(t.Elem())
+				p.Elem().Set(v)
+				return p, err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldString returns the result of the String method of v, the value of a
+// field with the "stringer" option, if it has one.
+func fieldString(v reflect.Value) (string, bool) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	}
+	return "", false
+}
+
+// unmarshalParsed stores cfObj in v, the value of a field with the "stringer"
+// option, if cfObj is a CFString and there is a parser for the type of v.
+func (state *unmarshalState) unmarshalParsed(cfObj cfTypeRef, v reflect.Value) bool {
+	if cfTypeID(cfObj) != cfStringTypeID {
+		return false
+	}
+	parse := stringParser(v.Type())
+	if parse == nil {
+		return false
+	}
+	parsed, err := parse(convertCFStringToString(C.CFStringRef(cfObj)))
+	if err != nil {
+		state.recordError(err)
+		return true
+	}
+	v.Set(parsed)
+	return true
+}
@@ -0,0 +1,124 @@
+package plist
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// level is an enum that marshals as a string with the "stringer" option and
+// unmarshals with UnmarshalText.
+type level int
+
+var levelNames = []string{"debug", "info", "error"}
+
+func (l level) String() string {
+	return levelNames[l]
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	for i, name := range levelNames {
+		if name == string(text) {
+			*l = level(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level %q", text)
+}
+
+// color has a parser registered with RegisterStringParser.
+type color struct{ r, g, b uint8 }
+
+func (c *color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+func parseColor(s string) (color, error) {
+	var c color
+	_, err := fmt.Sscanf(s, "#%02x%02x%02x", &c.r, &c.g, &c.b)
+	return c, err
+}
+
+func init() {
+	RegisterStringParser(parseColor)
+}
+
+type logConfig struct {
+	Level    level  `plist:",stringer"`
+	Fallback *level `plist:",stringer"`
+	Color    color  `plist:",stringer"`
+	Raw      level
+}
+
+func TestStringer(t *testing.T) {
+	fallback := level(2)
+	v := &logConfig{Level: 1, Fallback: &fallback, Color: color{0x12, 0xab, 0xff}, Raw: 1}
+	want := map[string]interface{}{
+		"Level":    "info",
+		"Fallback": "error",
+		"Color":    "#12abff",
+		"Raw":      int64(1),
+	}
+	encode := NewTypedEncoder[*logConfig]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var plist interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got %#v, want %#v", format, plist, want)
+			}
+			var got logConfig
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, v) {
+				t.Errorf("%s: got %#v, want %#v", format, got, *v)
+			}
+			got = logConfig{}
+			if _, err := NewTypedDecoder[logConfig]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(&got, v) {
+				t.Errorf("%s: TypedDecoder got %#v, want %#v", format, got, *v)
+			}
+		}
+	}
+
+	// numbers still decode into a stringer field
+	data, err := Marshal(map[string]interface{}{"Level": 2, "Color": "red"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got logConfig
+	_, err = Unmarshal(data, &got)
+	if err == nil || !strings.Contains(err.Error(), "input does not match format") {
+		t.Errorf("expected the error from parseColor, got %v", err)
+	}
+	if got.Level != 2 {
+		t.Errorf("Level: got %v", got.Level)
+	}
+
+	data, err = Marshal(map[string]interface{}{"Level": "fatal"}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTypedDecoder[logConfig]().Decode(data, &got); err == nil || !strings.Contains(err.Error(), `unknown level "fatal"`) {
+		t.Errorf("expected the error from UnmarshalText, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var syncMapType = reflect.TypeOf((*sync.Map)(nil)).Elem()
+
+// syncMapOf returns the sync.Map that v, a value of type sync.Map, holds. If v
+// isn't addressable the value is a copy anyway, so it is copied again to get
+// something to call Range on.
+func syncMapOf(v reflect.Value) *sync.Map {
+	if !v.CanAddr() {
+		p := reflect.New(syncMapType)
+		p.Elem().Set(v)
+		v = p.Elem()
+	}
+	return v.Addr().Interface().(*sync.Map)
+}
+
+// syncMapEntries returns the entries of m sorted by key. Every key must be a
+// string.
+func syncMapEntries(m *sync.Map) ([]string, map[string]interface{}, error) {
+	var keys []string
+	values := make(map[string]interface{})
+	var err error
+	m.Range(func(k, v interface{}) bool {
+		key, ok := k.(string)
+		if !ok {
+			err = &UnsupportedValueError{reflect.ValueOf(m), fmt.Sprintf("sync.Map key of type %T", k)}
+			return false
+		}
+		keys = append(keys, key)
+		values[key] = v
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkKeys(keys); err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(keys)
+	return keys, values, nil
+}
+
+// marshalSyncMap converts m to a CFDictionary of its entries.
+func (state *marshalState) marshalSyncMap(m *sync.Map) (cfTypeRef, error) {
+	keys, entries, err := syncMapEntries(m)
+	if err != nil {
+		return nil, err
+	}
+	cfKeys := make([]cfTypeRef, 0, len(keys))
+	values := make([]cfTypeRef, 0, len(keys))
+	defer func() {
+		state.release(cfKeys)
+		state.release(values)
+	}()
+	for _, key := range keys {
+		cfStr := convertStringToCFString(key)
+		if cfStr == nil {
+			return nil, errors.New("plist: could not convert string to CFStringRef")
+		}
+		cfKeys = append(cfKeys, cfTypeRef(cfStr))
+		cfObj, err := state.marshalValue(reflect.ValueOf(entries[key]))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cfObj)
+	}
+	return cfTypeRef(createCFDictionary(cfKeys, values)), nil
+}
+
+// encodeSyncMap writes m as a dictionary of its entries with their keys
+// sorted.
+func (state *streamState) encodeSyncMap(m *sync.Map) error {
+	keys, entries, err := syncMapEntries(m)
+	if err != nil {
+		return err
+	}
+	if state.nilPolicy == NilOmit {
+		kept := keys[:0]
+		for _, key := range keys {
+			if !isNilValue(reflect.ValueOf(entries[key])) {
+				kept = append(kept, key)
+			}
+		}
+		keys = kept
+	}
+	state.orderKeys(keys, func(i int) string { return keys[i] })
+	if err := state.w.beginDict(len(keys)); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := state.w.key(key); err != nil {
+			return err
+		}
+		if err := state.encodeValue(reflect.ValueOf(entries[key])); err != nil {
+			return err
+		}
+	}
+	return state.w.endDict()
+}
+
+// unmarshalSyncMap stores the entries of cfDict in m, with their values
+// unmarshalled as they would be into an interface{}. Existing entries for
+// other keys are kept.
+func (state *unmarshalState) unmarshalSyncMap(cfDict C.CFDictionaryRef, m *sync.Map) error {
+	return state.convertCFDictionary(cfDict, func(key string, value cfTypeRef, count int) error {
+		var val interface{}
+		saved := state.order
+		state.order = saved.key(key)
+		err := state.unmarshalValue(value, reflect.ValueOf(&val).Elem())
+		state.order = saved
+		if err != nil {
+			return err
+		}
+		m.Store(key, val)
+		return nil
+	})
+}
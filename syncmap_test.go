@@ -0,0 +1,93 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type syncMapHolder struct {
+	Name  string
+	Cache sync.Map
+}
+
+func TestSyncMap(t *testing.T) {
+	var m sync.Map
+	m.Store("b", "two")
+	m.Store("a", int64(1))
+	m.Store("c", []interface{}{true, "x"})
+	want := map[string]interface{}{"a": int64(1), "b": "two", "c": []interface{}{true, "x"}}
+
+	h := &syncMapHolder{Name: "cache"}
+	h.Cache.Store("key", "value")
+	encode := NewTypedEncoder[*syncMapHolder]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(&m, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(&m); err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes()} {
+			var got map[string]interface{}
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+
+			// entries are stored in an existing sync.Map, and keep the
+			// ones it has
+			var decoded sync.Map
+			decoded.Store("old", "entry")
+			if _, err := Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			got = make(map[string]interface{})
+			decoded.Range(func(k, v interface{}) bool {
+				got[k.(string)] = v
+				return true
+			})
+			want := map[string]interface{}{"old": "entry", "a": int64(1), "b": "two", "c": []interface{}{true, "x"}}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded sync.Map: got %v, want %v", got, want)
+			}
+		}
+
+		// a sync.Map in a struct
+		data, err = Marshal(h, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(h, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, typed} {
+			var got syncMapHolder
+			if _, err := NewTypedDecoder[syncMapHolder]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok := got.Cache.Load("key"); got.Name != "cache" || !ok || v != "value" {
+				t.Errorf("got Name %q and Cache[key] %v", got.Name, v)
+			}
+		}
+	}
+}
+
+func TestSyncMapNonStringKey(t *testing.T) {
+	var m sync.Map
+	m.Store(1, "one")
+	var uve *UnsupportedValueError
+	if _, err := Marshal(&m, XMLFormat); !errors.As(err, &uve) {
+		t.Errorf("Marshal returned %v, want an UnsupportedValueError", err)
+	}
+	if err := NewEncoder(new(bytes.Buffer), XMLFormat).Encode(&m); !errors.As(err, &uve) {
+		t.Errorf("Encode returned %v, want an UnsupportedValueError", err)
+	}
+}
@@ -40,3 +40,21 @@ func (o tagOptions) Contains(optionName string) bool {
 	}
 	return false
 }
+
+// Get returns the value of an option of the form name=value, or the empty
+// string if there isn't one.
+func (o tagOptions) Get(name string) string {
+	s := string(o)
+	for s != "" {
+		var next string
+		i := strings.Index(s, ",")
+		if i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if value, ok := strings.CutPrefix(s, name+"="); ok {
+			return value
+		}
+		s = next
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The values of the "format" tag option, which says how a time is stored as
+// a number.
+const (
+	timeFormatUnix      = "unix"      // seconds since the Unix epoch; the default
+	timeFormatUnixMilli = "unixmilli" // milliseconds since the Unix epoch
+	timeFormatCocoa     = "cocoa"     // seconds since the NSDate reference date
+)
+
+// convertCFDate converts cfDate to a time.Time in the location set with
+// Decoder.UseLocation, or the local time zone.
+func (state *unmarshalState) convertCFDate(cfDate C.CFDateRef) time.Time {
+	t := convertCFDateToTime(cfDate)
+	if state.location != nil {
+		t = t.In(state.location)
+	}
+	return t
+}
+
+// unixTime converts seconds since the Unix epoch into a time.Time, rounded to
+// the millisecond as CFDates are.
+func unixTime(secs float64) time.Time {
+	ms := int64(math.Floor(secs*1000 + 0.5))
+	return time.UnixMilli(ms)
+}
+
+// timeNumber returns t as a number in the given format: an int64 of whole
+// seconds or milliseconds since the Unix epoch, or a float64 of seconds since
+// the NSDate reference date. It returns false for an unknown format.
+func timeNumber(t time.Time, format string) (interface{}, bool) {
+	switch format {
+	case timeFormatUnix:
+		return t.Unix(), true
+	case timeFormatUnixMilli:
+		return t.UnixMilli(), true
+	case timeFormatCocoa:
+		return timeToCocoa(t), true
+	}
+	return nil, false
+}
+
+// unmarshalTimeFormat stores cfObj in v, the value of a field with the
+// "format" option, if cfObj is a CFDate and v a number or string, or cfObj is
+// a CFNumber and v a time.Time.
+func (state *unmarshalState) unmarshalTimeFormat(cfObj cfTypeRef, v reflect.Value, format string) bool {
+	switch cfTypeID(cfObj) {
+	case cfDateTypeID:
+		return state.unmarshalDate(C.CFDateRef(cfObj), v, v, format)
+	case cfNumberTypeID:
+		return state.unmarshalNumberTime(C.CFNumberRef(cfObj), v, format)
+	}
+	return false
+}
+
+// unmarshalNumberTime stores cfNumber in v, a time.Time, as a time in the
+// given format. It returns false if v isn't a time.Time or the format is
+// unknown.
+func (state *unmarshalState) unmarshalNumberTime(cfNumber C.CFNumberRef, v reflect.Value, format string) bool {
+	if v.Type() != timeType {
+		return false
+	}
+	var t time.Time
+	switch format {
+	case timeFormatUnix:
+		if C.CFNumberIsFloatType(cfNumber) == C.false {
+			t = time.Unix(convertCFNumberToInt64(cfNumber), 0)
+		} else {
+			t = unixTime(convertCFNumberToFloat64(cfNumber))
+		}
+	case timeFormatUnixMilli:
+		if C.CFNumberIsFloatType(cfNumber) == C.false {
+			t = time.UnixMilli(convertCFNumberToInt64(cfNumber))
+		} else {
+			t = unixTime(convertCFNumberToFloat64(cfNumber) / 1000)
+		}
+	case timeFormatCocoa:
+		t = cocoaTime(convertCFNumberToFloat64(cfNumber))
+	default:
+		return false
+	}
+	if state.location != nil {
+		t = t.In(state.location)
+	}
+	v.Set(reflect.ValueOf(t))
+	return true
+}
+
+// unmarshalDate stores the time of cfDate in v, an integer, floating point or
+// string value, with vSetter as the receiver of the Set call as in
+// unmarshalValue. Numbers are the time in the given format, and strings the
+// time in RFC 3339 format. It returns false if v is none of those.
+func (state *unmarshalState) unmarshalDate(cfDate C.CFDateRef, v, vSetter reflect.Value, format string) bool {
+	t := state.convertCFDate(cfDate)
+	vType := v.Type()
+	var val reflect.Value
+	switch vType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := t.Unix()
+		switch format {
+		case timeFormatUnixMilli:
+			n = t.UnixMilli()
+		case timeFormatCocoa:
+			n = int64(math.Floor(timeToCocoa(t)))
+		}
+		if v.OverflowInt(n) {
+			state.recordError(&UnmarshalTypeError{cfTypeNames[cfDateTypeID] + " " + strconv.FormatInt(n, 10), vType})
+			return true
+		}
+		val = reflect.ValueOf(n)
+	case reflect.Float32, reflect.Float64:
+		f := float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second)
+		switch format {
+		case timeFormatUnixMilli:
+			f = float64(t.UnixMilli())
+		case timeFormatCocoa:
+			f = timeToCocoa(t)
+		}
+		val = reflect.ValueOf(f)
+	case reflect.String:
+		if state.location == nil {
+			t = t.UTC()
+		}
+		val = reflect.ValueOf(t.Format(time.RFC3339Nano))
+	default:
+		return false
+	}
+	vSetter.Set(val.Convert(vType))
+	return true
+}
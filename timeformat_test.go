@@ -0,0 +1,163 @@
+package plist
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type timestamps struct {
+	Seconds int64
+	Millis  int64 `plist:",format=unixmilli"`
+	Float   float64
+	Small   int32
+	String  string
+	Any     interface{}
+	List    []int64
+}
+
+func TestUnmarshalDateIntoNumbers(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 890*int(time.Millisecond), time.UTC)
+	far := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	plist := map[string]interface{}{
+		"Seconds": when,
+		"Millis":  when,
+		"Float":   when,
+		"Small":   when,
+		"String":  when,
+		"Any":     when,
+		"List":    []interface{}{when, int64(5)},
+	}
+	want := timestamps{
+		Seconds: when.Unix(),
+		Millis:  when.UnixMilli(),
+		Float:   float64(when.Unix()) + 0.89,
+		Small:   int32(when.Unix()),
+		String:  "2021-03-04T05:06:07.89Z",
+		List:    []int64{when.Unix(), 5},
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(plist, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got timestamps
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if a, ok := got.Any.(time.Time); !ok || !a.Equal(when) {
+			t.Errorf("%s: Any: got %#v", format, got.Any)
+		}
+		got.Any = nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", format, got, want)
+		}
+		got = timestamps{}
+		if _, err := NewTypedDecoder[timestamps]().Decode(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		got.Any = nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: TypedDecoder got %#v, want %#v", format, got, want)
+		}
+	}
+
+	data, err := Marshal(map[string]interface{}{"Small": far}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got timestamps
+	if _, err := Unmarshal(data, &got); err == nil {
+		t.Error("expected an overflow error for a date past 2038 in an int32")
+	} else if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Errorf("expected UnmarshalTypeError, got %v", err)
+	}
+}
+
+type numericTimes struct {
+	Unix   time.Time `plist:",format=unix"`
+	Millis time.Time `plist:",format=unixmilli"`
+	Cocoa  time.Time `plist:",format=cocoa"`
+	Date   time.Time
+}
+
+func TestTimeFormat(t *testing.T) {
+	when := time.Date(2021, 3, 4, 5, 6, 7, 250*int(time.Millisecond), time.UTC)
+	v := numericTimes{
+		Unix:   when.Truncate(time.Second),
+		Millis: when,
+		Cocoa:  when,
+		Date:   when,
+	}
+	want := map[string]interface{}{
+		"Unix":   when.Unix(),
+		"Millis": when.UnixMilli(),
+		"Cocoa":  636527167.25,
+	}
+	encode := NewTypedEncoder[numericTimes]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var plist map[string]interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if d, ok := plist["Date"].(time.Time); !ok || !d.Equal(when) {
+				t.Errorf("%s: Date: got %#v", format, plist["Date"])
+			}
+			delete(plist, "Date")
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got %#v, want %#v", format, plist, want)
+			}
+			for _, decode := range []func(*numericTimes) error{
+				func(got *numericTimes) error { _, err := Unmarshal(data, got); return err },
+				func(got *numericTimes) error { _, err := NewTypedDecoder[numericTimes]().Decode(data, got); return err },
+			} {
+				var got numericTimes
+				if err := decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if !got.Unix.Equal(v.Unix) || !got.Millis.Equal(v.Millis) || !got.Cocoa.Equal(v.Cocoa) || !got.Date.Equal(v.Date) {
+					t.Errorf("%s: got %#v, want %#v", format, got, v)
+				}
+			}
+		}
+	}
+
+	// numbers of either kind decode into a field with the option
+	data, err := Marshal(map[string]interface{}{"Unix": 1.5, "Millis": 1500.0, "Cocoa": int64(0)}, BinaryFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got numericTimes
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.UnixMilli(1500); !got.Unix.Equal(want) || !got.Millis.Equal(want) {
+		t.Errorf("got %v and %v, want %v", got.Unix, got.Millis, want)
+	}
+	if want := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC); !got.Cocoa.Equal(want) {
+		t.Errorf("Cocoa: got %v, want %v", got.Cocoa, want)
+	}
+
+	// a number in a time.Time without the option is still a type error
+	data, err = Marshal(map[string]interface{}{"Date": int64(5)}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Unmarshal(data, &got); err == nil {
+		t.Error("expected an error unmarshaling a number into a time.Time")
+	}
+}
@@ -0,0 +1,92 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// ErrTrailingData is returned by Decoder.Decode, if DisallowTrailingData was
+// called, when the input has more than the property list.
+var ErrTrailingData = errors.New("plist: trailing data after property list")
+
+// checkTrailingData returns ErrTrailingData if anything besides white space,
+// comments and processing instructions follows the top element of an XML
+// property list, or if a binary property list has bytes that aren't part of
+// its offset table before its trailer. Other formats, and XML the scan can't
+// read, aren't checked.
+func checkTrailingData(data []byte) error {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		return checkBinaryTrailingData(data)
+	}
+	if !hasPropertyListHeader(data) {
+		return nil
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	if !skipTopElement(d) {
+		// leave anything the scan can't read to the parser
+		return nil
+	}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ErrTrailingData
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(tok)) != 0 {
+				return ErrTrailingData
+			}
+		case xml.Comment, xml.ProcInst:
+		default:
+			return ErrTrailingData
+		}
+	}
+}
+
+// skipTopElement reads d up to the end of the top element of the document,
+// returning false if it can't.
+func skipTopElement(d *xml.Decoder) bool {
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+}
+
+// checkBinaryTrailingData checks that the offset table of the binary
+// property list in data ends where its trailer begins.
+func checkBinaryTrailingData(data []byte) error {
+	if len(data) < 40 {
+		return nil
+	}
+	trailer := data[len(data)-32:]
+	offsetSize := uint64(trailer[6])
+	count := binary.BigEndian.Uint64(trailer[8:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+	end := uint64(len(data) - 32)
+	if offsetSize == 0 || count > end/offsetSize || tableOffset > end-count*offsetSize {
+		// not a valid trailer; leave it to the parser
+		return nil
+	}
+	if tableOffset+count*offsetSize != end {
+		return ErrTrailingData
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package plist
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCheckTrailingData(t *testing.T) {
+	const doc = xmlPlistHeader + "<string>hello</string>\n</plist>\n"
+	tests := []struct {
+		data     string
+		trailing bool
+	}{
+		{doc, false},
+		{doc + " \n\t", false},
+		{doc + "<!-- comment -->\n<?pi x?>\n", false},
+		{doc + "garbage", true},
+		{doc + "<string>more</string>", true},
+		{doc + "<", true},
+		{doc + doc, true},
+		{"<plist><array/></plist>x", true},
+		// the parser reports these
+		{doc[:len(doc)-20] + "garbage", false},
+		{"{a = b;} garbage", false},
+	}
+	for _, test := range tests {
+		err := checkTrailingData([]byte(test.data))
+		if (err == ErrTrailingData) != test.trailing || (err != nil && err != ErrTrailingData) {
+			t.Errorf("%q: got %v", test.data, err)
+		}
+	}
+
+	// "hello" with a 1-byte offset table
+	bin := []byte("bplist00" + "\x55hello" + "\x08" + "\x00\x00\x00\x00\x00\x00\x01\x01" +
+		"\x00\x00\x00\x00\x00\x00\x00\x01" + "\x00\x00\x00\x00\x00\x00\x00\x00" + "\x00\x00\x00\x00\x00\x00\x00\x0e")
+	if err := checkTrailingData(bin); err != nil {
+		t.Errorf("binary: got %v", err)
+	}
+	i := len(bin) - 32
+	junk := append(append(append([]byte(nil), bin[:i]...), "junk"...), bin[i:]...)
+	if err := checkTrailingData(junk); err != ErrTrailingData {
+		t.Errorf("binary with junk: got %v", err)
+	}
+}
+
+func TestDecoderTrailingData(t *testing.T) {
+	data, err := Marshal("hello", XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.DisallowTrailingData()
+	var s string
+	if err := dec.Decode(&s); err != nil || s != "hello" {
+		t.Errorf("got %q, %v", s, err)
+	}
+	dec.Reset(bytes.NewReader(append(data, "garbage"...)))
+	if err := dec.Decode(&s); err != ErrTrailingData {
+		t.Errorf("got error %v, want ErrTrailingData", err)
+	}
+}
@@ -0,0 +1,110 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// isTupleType reports whether t is a struct type that the "tuple" option
+// applies to: any struct besides those that are encoded as something other
+// than a dictionary of their fields.
+func isTupleType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t != urlType && t != addrType && t != dictType && t != syncMapType
+}
+
+// tupleStruct returns the struct that v, the value of a field with the
+// "tuple" option, holds: v itself, or what it points to. It returns false if
+// v is neither a tuple struct nor a non-nil pointer to one.
+func tupleStruct(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v, isTupleType(v.Type())
+}
+
+// tupleStruct returns the struct to encode as an array for v, the value of
+// the field, if the field has the "tuple" option.
+func (ef *encodeField) tupleStruct(v reflect.Value) (reflect.Value, bool) {
+	if !ef.tuple {
+		return v, false
+	}
+	return tupleStruct(v)
+}
+
+// marshalTuple converts v, a struct, to a CFArray of its encoded fields in
+// the order they're declared.
+func (state *marshalState) marshalTuple(v reflect.Value) (cfTypeRef, error) {
+	fields := encodeFields(v.Type())
+	if len(fields) == 0 {
+		return cfTypeRef(cfCreated(C.CFArrayCreate(nil, nil, 0, nil))), nil
+	}
+	values := make([]cfTypeRef, 0, len(fields))
+	defer func() { state.release(values) }()
+	for i := range fields {
+		cfObj, err := state.marshalField(&fields[i], v.Field(fields[i].i))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cfObj)
+	}
+	return cfTypeRef(createCFArray(values)), nil
+}
+
+// encodeTuple writes v, a struct, as an array of its fields in the order
+// they're declared.
+func (state *streamState) encodeTuple(v reflect.Value) error {
+	fields := encodeFields(v.Type())
+	if err := state.w.beginArray(len(fields)); err != nil {
+		return err
+	}
+	for i := range fields {
+		if err := state.encodeFieldValue(&fields[i], v.Field(fields[i].i)); err != nil {
+			return err
+		}
+	}
+	return state.w.endArray()
+}
+
+// unmarshalTuple stores the elements of cfObj in the fields of v, the value
+// of a field with the "tuple" option, in the order they're declared, if
+// cfObj is a CFArray and v a struct or pointer to one. An array with the
+// wrong number of elements is a type error.
+func (state *unmarshalState) unmarshalTuple(cfObj cfTypeRef, v reflect.Value) (bool, error) {
+	if cfTypeID(cfObj) != cfArrayTypeID {
+		return false, nil
+	}
+	if v.Kind() == reflect.Ptr && isTupleType(v.Type().Elem()) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if !isTupleType(v.Type()) {
+		return false, nil
+	}
+	var fields []*decodeField
+	df := cachedDecodeFields(v.Type())
+	for i := range df.fields {
+		if df.fields[i].PkgPath == "" {
+			fields = append(fields, &df.fields[i])
+		}
+	}
+	cfArray := C.CFArrayRef(cfObj)
+	if count := int(C.CFArrayGetCount(cfArray)); count != len(fields) {
+		state.recordError(&UnmarshalTypeError{cfTypeNames[cfArrayTypeID] + " of length " + strconv.Itoa(count), v.Type()})
+		return true, nil
+	}
+	saved := state.order
+	defer func() { state.order = saved }()
+	for i, f := range fields {
+		state.order = saved.index(i)
+		elem := cfTypeRef(C.CFArrayGetValueAtIndex(cfArray, C.CFIndex(i)))
+		if err := state.unmarshalField(f, elem, v.FieldByIndex(f.Index)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
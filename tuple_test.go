@@ -0,0 +1,129 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type tuplePoint struct {
+	X, Y float64
+}
+
+type tupleSize struct {
+	Width, Height float64
+}
+
+type tupleRect struct {
+	Origin tuplePoint `plist:",tuple"`
+	Size   *tupleSize `plist:",tuple"`
+}
+
+type tupleWindow struct {
+	Title string
+	Frame tupleRect `plist:",tuple"`
+}
+
+func TestTuple(t *testing.T) {
+	v := tupleWindow{"Main", tupleRect{tuplePoint{1, 2}, &tupleSize{640, 480}}}
+	encode := NewTypedEncoder[tupleWindow]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			// Frame is [[1, 2], [640, 480]]
+			var plist map[string]interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			frame, ok := plist["Frame"].([]interface{})
+			if !ok || len(frame) != 2 {
+				t.Fatalf("Frame is %#v, not an array of 2 elements", plist["Frame"])
+			}
+			if size, ok := frame[1].([]interface{}); !ok || len(size) != 2 {
+				t.Errorf("Frame[1] is %#v, not an array of 2 elements", frame[1])
+			}
+
+			var got tupleWindow
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("got %+v, want %+v", got, v)
+			}
+			got = tupleWindow{}
+			if _, err := NewTypedDecoder[tupleWindow]().Decode(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("TypedDecoder got %+v, want %+v", got, v)
+			}
+		}
+	}
+}
+
+func TestTupleDecode(t *testing.T) {
+	// a dictionary still decodes into a field with the "tuple" option
+	data, err := Marshal(map[string]interface{}{
+		"Frame": map[string]interface{}{
+			"Origin": []float64{3, 4},
+			"Size":   map[string]float64{"Width": 5, "Height": 6},
+		},
+	}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := tupleWindow{Frame: tupleRect{tuplePoint{3, 4}, &tupleSize{5, 6}}}
+	var got tupleWindow
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	got = tupleWindow{}
+	if _, err := NewTypedDecoder[tupleWindow]().Decode(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TypedDecoder got %+v, want %+v", got, want)
+	}
+
+	// an array of the wrong length is a type error
+	data, err = Marshal(map[string]interface{}{
+		"Title": "Main",
+		"Frame": []interface{}{[]float64{1, 2, 3}, []float64{5, 6}},
+	}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = tupleWindow{Title: "Main", Frame: tupleRect{Size: &tupleSize{5, 6}}}
+	got = tupleWindow{}
+	_, err = Unmarshal(data, &got)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Value != "CFArray of length 3" {
+		t.Errorf("Unmarshal: got error %v, want an UnmarshalTypeError for a CFArray of length 3", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	got = tupleWindow{}
+	_, err = NewTypedDecoder[tupleWindow]().Decode(data, &got)
+	if !errors.As(err, &typeErr) || typeErr.Value != "CFArray of length 3" {
+		t.Errorf("TypedDecoder: got error %v, want an UnmarshalTypeError for a CFArray of length 3", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TypedDecoder got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,925 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// NewTypedEncoder returns a function that encodes values of type T as Marshal
+// does. The type is analyzed once, when NewTypedEncoder is called: the
+// returned function reads struct fields at their offsets and uses the cached
+// CFString keys, without going through reflection for fields of the basic
+// types, structs, pointers, slices and arrays. Maps, interfaces and types with
+// their own MarshalPlist or MarshalPlistCF methods are encoded as Marshal
+// encodes them.
+//
+// The returned function is safe for concurrent use, and is meant to be
+// created once and kept, for instance in a package-level variable.
+func NewTypedEncoder[T any]() func(v T, format Format) ([]byte, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Interface {
+		// Marshal only sees the dynamic value
+		return func(v T, format Format) ([]byte, error) {
+			return Marshal(v, format)
+		}
+	}
+	// like Marshal(v), the value given to the encoder isn't addressable
+	enc := compileEncoder(t, false, make(map[encoderKey]*encoderFunc))
+	return func(v T, format Format) ([]byte, error) {
+		return marshalAppend(nil, format, func(state *marshalState) (cfTypeRef, error) {
+			return enc(state, unsafe.Pointer(&v))
+		})
+	}
+}
+
+// An encoderFunc converts the value at p to a CoreFoundation object.
+type encoderFunc func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error)
+
+// encoderKey identifies an encoderFunc being compiled. Whether the value is
+// addressable decides, as in marshalValue, whether methods on its pointer type
+// are used.
+type encoderKey struct {
+	t           reflect.Type
+	addressable bool
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	cfMarshalerType = reflect.TypeOf((*CFMarshaler)(nil)).Elem()
+)
+
+// hasMarshaler returns whether marshalValue calls a method to convert values
+// of type t.
+func hasMarshaler(t reflect.Type, addressable bool) bool {
+	if t.Implements(marshalerType) || t.Implements(cfMarshalerType) {
+		return true
+	}
+	if addressable && t.Kind() != reflect.Ptr {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(marshalerType) || pt.Implements(cfMarshalerType)
+	}
+	return false
+}
+
+// compileEncoder returns the encoderFunc for type t. The functions of the
+// types being compiled are kept in seen, so that recursive types refer to
+// themselves.
+func compileEncoder(t reflect.Type, addressable bool, seen map[encoderKey]*encoderFunc) encoderFunc {
+	key := encoderKey{t, addressable}
+	if fp, ok := seen[key]; ok {
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return (*fp)(state, p)
+		}
+	}
+	fp := new(encoderFunc)
+	seen[key] = fp
+	*fp = newEncoder(t, addressable, seen)
+	return *fp
+}
+
+func newEncoder(t reflect.Type, addressable bool, seen map[encoderKey]*encoderFunc) encoderFunc {
+	if hasMarshaler(t, addressable) {
+		return reflectEncoder(t, addressable)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertBoolToCFBoolean(*(*bool)(p))), nil
+		}
+	case reflect.Int:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertInt64ToCFNumber(int64(*(*int)(p)))), nil
+		}
+	case reflect.Int8:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertInt64ToCFNumber(int64(*(*int8)(p)))), nil
+		}
+	case reflect.Int16:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertInt64ToCFNumber(int64(*(*int16)(p)))), nil
+		}
+	case reflect.Int32:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertInt64ToCFNumber(int64(*(*int32)(p)))), nil
+		}
+	case reflect.Int64:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertInt64ToCFNumber(*(*int64)(p))), nil
+		}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if t == uidType {
+			break
+		}
+		switch t.Kind() {
+		case reflect.Uint8:
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return cfTypeRef(convertUInt32ToCFNumber(uint32(*(*uint8)(p)))), nil
+			}
+		case reflect.Uint16:
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return cfTypeRef(convertUInt32ToCFNumber(uint32(*(*uint16)(p)))), nil
+			}
+		}
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(convertUInt32ToCFNumber(*(*uint32)(p))), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if t == dateType {
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return cfTypeRef(convertDateToCFDate(*(*Date)(p))), nil
+			}
+		}
+		bits := t.Bits()
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			var f float64
+			if bits == 32 {
+				f = float64(*(*float32)(p))
+			} else {
+				f = *(*float64)(p)
+			}
+			if math.IsInf(f, 0) || math.IsNaN(f) {
+				return nil, &UnsupportedValueError{reflect.NewAt(t, p).Elem(), strconv.FormatFloat(f, 'g', -1, bits)}
+			}
+			return cfTypeRef(convertFloat64ToCFNumber(f)), nil
+		}
+	case reflect.String:
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return marshalString(*(*string)(p))
+		}
+	case reflect.Struct:
+		switch t {
+		case timeType:
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return cfTypeRef(convertTimeToCFDate(*(*time.Time)(p))), nil
+			}
+		case syncMapType:
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return state.marshalSyncMap((*sync.Map)(p))
+			}
+		case dictType, urlType, addrType:
+			break
+		default:
+			return structEncoder(t, addressable, seen)
+		}
+	case reflect.Ptr:
+		elem := compileEncoder(t.Elem(), true, seen)
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			ptr := *(*unsafe.Pointer)(p)
+			if ptr == nil {
+				return nil, &UnsupportedValueError{reflect.NewAt(t, p).Elem(), "nil pointer"}
+			}
+			return elem(state, ptr)
+		}
+	case reflect.Slice:
+		if t == ipType {
+			break
+		}
+		if t == byteSliceType {
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return cfTypeRef(state.convertBytesToCFData(*(*[]byte)(p))), nil
+			}
+		}
+		if t == int64SliceType || t == float64SliceType || t == stringSliceType || t == intSliceType {
+			// these are converted in a single cgo call
+			break
+		}
+		return arrayEncoder(t, -1, true, seen)
+	case reflect.Array:
+		if isUUIDType(t) {
+			return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+				return marshalString(formatUUID(*(*[16]byte)(p)))
+			}
+		}
+		return arrayEncoder(t, t.Len(), addressable, seen)
+	}
+	return reflectEncoder(t, addressable)
+}
+
+// reflectEncoder returns an encoderFunc that converts values of type t with
+// marshalValue.
+func reflectEncoder(t reflect.Type, addressable bool) encoderFunc {
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		v := reflect.NewAt(t, p).Elem()
+		if !addressable {
+			v = reflect.ValueOf(v.Interface())
+		}
+		return state.marshalValue(v)
+	}
+}
+
+// arrayEncoder returns the encoderFunc for t, a slice type if n is negative,
+// and an array type of length n otherwise. Arrays long enough to be converted
+// in parallel are handed to marshalArray.
+func arrayEncoder(t reflect.Type, n int, addressable bool, seen map[encoderKey]*encoderFunc) encoderFunc {
+	elem := compileEncoder(t.Elem(), addressable, seen)
+	size := t.Elem().Size()
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		data, count := p, n
+		if n < 0 {
+			// every slice header has the layout of a []byte
+			s := *(*[]byte)(p)
+			data, count = unsafe.Pointer(unsafe.SliceData(s)), len(s)
+		}
+		if count == 0 {
+			return cfTypeRef(cfCreated(C.CFArrayCreate(nil, nil, 0, nil))), nil
+		}
+		if parallelChunks(count) != 0 {
+			v := reflect.NewAt(t, p).Elem()
+			if !addressable {
+				v = reflect.ValueOf(v.Interface())
+			}
+			cfAry, err := state.marshalArray(v)
+			return cfTypeRef(cfAry), err
+		}
+		plists := make([]cfTypeRef, count)
+		defer state.release(plists)
+		for i := range plists {
+			cfObj, err := elem(state, unsafe.Add(data, uintptr(i)*size))
+			if err != nil {
+				return nil, err
+			}
+			plists[i] = cfObj
+		}
+		return cfTypeRef(createCFArray(plists)), nil
+	}
+}
+
+// typedField is an encodeField with the offset and encoderFunc of its field.
+type typedField struct {
+	encodeField
+	offset  uintptr
+	isEmpty func(p unsafe.Pointer) bool
+	enc     encoderFunc
+}
+
+// structEncoder returns the encoderFunc for the struct type t, which does what
+// marshalStruct does.
+func structEncoder(t reflect.Type, addressable bool, seen map[encoderKey]*encoderFunc) encoderFunc {
+	efs := encodeFields(t)
+	fields := make([]typedField, len(efs))
+	for i, ef := range efs {
+		f := t.Field(ef.i)
+		fields[i] = typedField{
+			encodeField: ef,
+			offset:      f.Offset,
+			enc:         compileEncoder(f.Type, addressable, seen),
+		}
+		if ef.asData {
+			if enc := dataEncoder(f.Type); enc != nil {
+				fields[i].enc = enc
+			}
+		}
+		if ef.stringer {
+			fields[i].enc = stringerEncoder(f.Type, addressable, fields[i].enc)
+		}
+		if ef.timeFormat != "" && f.Type == timeType {
+			if enc := timeFormatEncoder(ef.timeFormat); enc != nil {
+				fields[i].enc = enc
+			}
+		}
+		if ef.tuple {
+			fields[i].enc = tupleEncoder(f.Type, addressable, fields[i].enc)
+		}
+		if ef.set && isSetType(f.Type) {
+			fields[i].enc = setEncoder(f.Type)
+		}
+		if ef.omitZero {
+			fields[i].isEmpty = zeroFunc(f.Type)
+		} else if ef.omitEmpty {
+			fields[i].isEmpty = emptyFunc(f.Type)
+		} else if ef.omitNil {
+			fields[i].isEmpty = nilFunc(f.Type)
+		}
+	}
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		keys := make([]cfTypeRef, 0, len(fields))
+		values := make([]cfTypeRef, 0, len(fields))
+		defer func() { state.release(values) }()
+		for i := range fields {
+			f := &fields[i]
+			fp := unsafe.Add(p, f.offset)
+			if f.isEmpty != nil && f.isEmpty(fp) {
+				continue
+			}
+			if f.cfName == nil {
+				return nil, errors.New("plist: could not convert string to CFStringRef")
+			}
+			keys = append(keys, cfTypeRef(f.cfName))
+			cfObj, err := f.enc(state, fp)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, cfObj)
+		}
+		return cfTypeRef(createCFDictionary(keys, values)), nil
+	}
+}
+
+// dataEncoder returns the encoderFunc for a field of type t with the "data"
+// option, or nil if the option doesn't apply to t.
+func dataEncoder(t reflect.Type) encoderFunc {
+	if t.Kind() == reflect.String {
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			s := *(*string)(p)
+			return cfTypeRef(state.convertBytesToCFData(unsafe.Slice(unsafe.StringData(s), len(s)))), nil
+		}
+	}
+	if isUUIDType(t) {
+		return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+			return cfTypeRef(state.convertBytesToCFData((*[16]byte)(p)[:])), nil
+		}
+	}
+	return nil
+}
+
+// stringerEncoder returns the encoderFunc for a field of type t with the
+// "stringer" option, which falls back to enc for values that aren't a
+// fmt.Stringer.
+func stringerEncoder(t reflect.Type, addressable bool, enc encoderFunc) encoderFunc {
+	if !t.Implements(stringerType) && !(addressable && reflect.PointerTo(t).Implements(stringerType)) {
+		return enc
+	}
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		v := reflect.NewAt(t, p).Elem()
+		if !addressable {
+			v = reflect.ValueOf(v.Interface())
+		}
+		if s, ok := fieldString(v); ok {
+			return marshalString(s)
+		}
+		return enc(state, p)
+	}
+}
+
+// timeFormatEncoder returns the encoderFunc for a time.Time field with the
+// "format" option, or nil if the format is unknown.
+func timeFormatEncoder(format string) encoderFunc {
+	if _, ok := timeNumber(time.Time{}, format); !ok {
+		return nil
+	}
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		n, _ := timeNumber(*(*time.Time)(p), format)
+		return state.marshalInterface(n)
+	}
+}
+
+// tupleEncoder returns the encoderFunc for a field of type t with the "tuple"
+// option, which encodes a struct as marshalTuple does and falls back to enc
+// for anything else.
+func tupleEncoder(t reflect.Type, addressable bool, enc encoderFunc) encoderFunc {
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		v := reflect.NewAt(t, p).Elem()
+		if !addressable {
+			v = reflect.ValueOf(v.Interface())
+		}
+		if s, ok := tupleStruct(v); ok {
+			return state.marshalTuple(s)
+		}
+		return enc(state, p)
+	}
+}
+
+// setEncoder returns the encoderFunc for a field of type t, a set, with the
+// "set" option, which encodes it as a CFArray of its sorted members.
+func setEncoder(t reflect.Type) encoderFunc {
+	return func(state *marshalState, p unsafe.Pointer) (cfTypeRef, error) {
+		return state.marshalValue(setSlice(reflect.NewAt(t, p).Elem()))
+	}
+}
+
+// emptyFunc returns a function that does what isEmptyValue does for values of
+// type t.
+func emptyFunc(t reflect.Type) func(p unsafe.Pointer) bool {
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(p unsafe.Pointer) bool { return !*(*bool)(p) }
+	case reflect.String:
+		return func(p unsafe.Pointer) bool { return len(*(*string)(p)) == 0 }
+	case reflect.Slice:
+		return func(p unsafe.Pointer) bool { return len(*(*[]byte)(p)) == 0 }
+	case reflect.Ptr:
+		return func(p unsafe.Pointer) bool { return *(*unsafe.Pointer)(p) == nil }
+	case reflect.Int, reflect.Int64:
+		if t.Size() == 8 {
+			return func(p unsafe.Pointer) bool { return *(*int64)(p) == 0 }
+		}
+	case reflect.Float64:
+		return func(p unsafe.Pointer) bool { return *(*float64)(p) == 0 }
+	}
+	return func(p unsafe.Pointer) bool {
+		return isEmptyValue(reflect.NewAt(t, p).Elem())
+	}
+}
+
+// zeroFunc returns a function that does what isZeroValue does for values of
+// type t.
+func zeroFunc(t reflect.Type) func(p unsafe.Pointer) bool {
+	if t.Kind() != reflect.Struct {
+		return emptyFunc(t)
+	}
+	return func(p unsafe.Pointer) bool {
+		return isZeroValue(reflect.NewAt(t, p).Elem())
+	}
+}
+
+// nilFunc returns a function that reports whether a value of type t is nil,
+// or nil if values of type t can't be.
+func nilFunc(t reflect.Type) func(p unsafe.Pointer) bool {
+	switch t.Kind() {
+	case reflect.Slice:
+		return func(p unsafe.Pointer) bool { return unsafe.SliceData(*(*[]byte)(p)) == nil }
+	case reflect.Map, reflect.Ptr, reflect.Func, reflect.Chan:
+		return func(p unsafe.Pointer) bool { return *(*unsafe.Pointer)(p) == nil }
+	case reflect.Interface:
+		// the type word of every interface value comes first
+		return func(p unsafe.Pointer) bool { return (*[2]unsafe.Pointer)(p)[0] == nil }
+	}
+	return nil
+}
+
+// A TypedDecoder decodes property lists into values of type T as Unmarshal
+// does, but with the decoding of T worked out in advance: struct fields are
+// found through a table of their keys and set at their offsets, and the
+// elements of arrays and dictionaries are read into scratch space that is kept
+// between calls. Values that Unmarshal would decode with an UnmarshalPlist or
+// UnmarshalPlistCF method, maps and interfaces, as well as values of the wrong
+// type, are decoded by Unmarshal's own code, so errors are reported the same
+// way.
+//
+// A TypedDecoder is not safe for concurrent use. Servers can keep one per
+// goroutine, or in a sync.Pool; creating more decoders for the same type is
+// cheap once the first has been created.
+type TypedDecoder[T any] struct {
+	dec      decoderFunc
+	keyOrder bool
+	state    unmarshalState
+}
+
+// NewTypedDecoder returns a TypedDecoder for values of type T.
+func NewTypedDecoder[T any]() *TypedDecoder[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return &TypedDecoder[T]{
+		dec:      cachedDecoder(t),
+		keyOrder: containsDict(t, map[reflect.Type]bool{}),
+	}
+}
+
+// Decode parses the property list data and stores the result in the value
+// pointed to by v, returning the format of the data as Unmarshal does.
+func (d *TypedDecoder[T]) Decode(data []byte, v *T) (format Format, err error) {
+	if v == nil {
+		return Format{}, &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	defer recoverPanic(&err)
+	cfObj, format, err := cfPropertyListCreateWithData(data)
+	if err != nil {
+		return format, err
+	}
+	defer cfRelease(cfObj)
+	clear(d.state.keys)
+	d.state = unmarshalState{scratch: d.state.scratch[:0], keyBuf: d.state.keyBuf[:0], keys: d.state.keys}
+	if format == XMLFormat && d.keyOrder {
+		d.state.order = scanKeyOrder(data)
+	}
+	if err := d.dec(&d.state, cfObj, unsafe.Pointer(v)); err != nil {
+		return format, err
+	}
+	return format, d.state.err
+}
+
+// A decoderFunc stores the Go value of cfObj in the value at p.
+type decoderFunc func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error
+
+var (
+	unmarshalerType   = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	cfUnmarshalerType = reflect.TypeOf((*CFUnmarshaler)(nil)).Elem()
+	decoderCache      = make(map[reflect.Type]decoderFunc)
+)
+
+// hasUnmarshaler returns whether unmarshalValue calls a method to decode into
+// values of type t, which are always addressable.
+func hasUnmarshaler(t reflect.Type) bool {
+	if t.Implements(unmarshalerType) || t.Implements(cfUnmarshalerType) {
+		return true
+	}
+	if t.Kind() != reflect.Ptr && t.Name() != "" {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(unmarshalerType) || pt.Implements(cfUnmarshalerType)
+	}
+	return false
+}
+
+// cachedDecoder returns the decoderFunc for type t.
+func cachedDecoder(t reflect.Type) decoderFunc {
+	typeCacheLock.RLock()
+	dec, ok := decoderCache[t]
+	typeCacheLock.RUnlock()
+	if ok {
+		return dec
+	}
+	// compile without the lock, since cachedDecodeFields takes it
+	dec = compileDecoder(t, make(map[reflect.Type]*decoderFunc))
+	typeCacheLock.Lock()
+	defer typeCacheLock.Unlock()
+	if cached, ok := decoderCache[t]; ok {
+		return cached
+	}
+	decoderCache[t] = dec
+	return dec
+}
+
+// compileDecoder returns the decoderFunc for type t, keeping the functions of
+// the types being compiled in seen as compileEncoder does.
+func compileDecoder(t reflect.Type, seen map[reflect.Type]*decoderFunc) decoderFunc {
+	if fp, ok := seen[t]; ok {
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			return (*fp)(state, cfObj, p)
+		}
+	}
+	fp := new(decoderFunc)
+	seen[t] = fp
+	*fp = newDecoder(t, seen)
+	return *fp
+}
+
+func newDecoder(t reflect.Type, seen map[reflect.Type]*decoderFunc) decoderFunc {
+	if hasUnmarshaler(t) {
+		return reflectDecoder(t)
+	}
+	slow := reflectDecoder(t)
+	switch t.Kind() {
+	case reflect.Bool:
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			if cfTypeID(cfObj) != cfBooleanTypeID {
+				return slow(state, cfObj, p)
+			}
+			*(*bool)(p) = convertCFBooleanToBool(C.CFBooleanRef(cfObj))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		bits := t.Bits()
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			if cfTypeID(cfObj) != cfNumberTypeID {
+				return slow(state, cfObj, p)
+			}
+			i := convertCFNumberToInt64(C.CFNumberRef(cfObj))
+			if bits < 64 && (i < -1<<uint(bits-1) || i >= 1<<uint(bits-1)) {
+				state.recordError(&UnmarshalTypeError{cfTypeNames[cfNumberTypeID] + " " + strconv.FormatInt(i, 10), t})
+				return nil
+			}
+			switch bits {
+			case 8:
+				*(*int8)(p) = int8(i)
+			case 16:
+				*(*int16)(p) = int16(i)
+			case 32:
+				*(*int32)(p) = int32(i)
+			default:
+				*(*int64)(p) = i
+			}
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t == uidType {
+			break
+		}
+		bits := t.Bits()
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			if cfTypeID(cfObj) != cfNumberTypeID {
+				return slow(state, cfObj, p)
+			}
+			u := uint64(convertCFNumberToUInt32(C.CFNumberRef(cfObj)))
+			if bits < 32 && u >= 1<<uint(bits) {
+				state.recordError(&UnmarshalTypeError{cfTypeNames[cfNumberTypeID] + " " + strconv.FormatUint(u, 10), t})
+				return nil
+			}
+			switch bits {
+			case 8:
+				*(*uint8)(p) = uint8(u)
+			case 16:
+				*(*uint16)(p) = uint16(u)
+			case 32:
+				*(*uint32)(p) = uint32(u)
+			default:
+				*(*uint64)(p) = u
+			}
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if t == dateType {
+			return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+				if cfTypeID(cfObj) != cfDateTypeID {
+					return slow(state, cfObj, p)
+				}
+				*(*Date)(p) = convertCFDateToDate(C.CFDateRef(cfObj))
+				return nil
+			}
+		}
+		bits := t.Bits()
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			if cfTypeID(cfObj) != cfNumberTypeID {
+				return slow(state, cfObj, p)
+			}
+			f := convertCFNumberToFloat64(C.CFNumberRef(cfObj))
+			if bits == 32 {
+				if a := math.Abs(f); a > math.MaxFloat32 && a <= math.MaxFloat64 {
+					state.recordError(&UnmarshalTypeError{cfTypeNames[cfNumberTypeID] + " " + strconv.FormatFloat(f, 'f', -1, 64), t})
+					return nil
+				}
+				*(*float32)(p) = float32(f)
+			} else {
+				*(*float64)(p) = f
+			}
+			return nil
+		}
+	case reflect.String:
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			if cfTypeID(cfObj) != cfStringTypeID {
+				return slow(state, cfObj, p)
+			}
+			*(*string)(p) = convertCFStringToString(C.CFStringRef(cfObj))
+			return nil
+		}
+	case reflect.Struct:
+		switch t {
+		case timeType:
+			return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+				if cfTypeID(cfObj) != cfDateTypeID {
+					return slow(state, cfObj, p)
+				}
+				*(*time.Time)(p) = state.convertCFDate(C.CFDateRef(cfObj))
+				return nil
+			}
+		case dictType, urlType, addrType, syncMapType:
+			break
+		default:
+			return structDecoder(t, seen)
+		}
+	case reflect.Ptr:
+		elemType := t.Elem()
+		elem := compileDecoder(elemType, seen)
+		return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+			ptr := (*unsafe.Pointer)(p)
+			if *ptr == nil {
+				*ptr = reflect.New(elemType).UnsafePointer()
+			}
+			return elem(state, cfObj, *ptr)
+		}
+	case reflect.Slice:
+		if byteSliceType.AssignableTo(t) {
+			return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+				if cfTypeID(cfObj) != cfDataTypeID {
+					return slow(state, cfObj, p)
+				}
+				*(*[]byte)(p) = convertCFDataToBytes(C.CFDataRef(cfObj))
+				return nil
+			}
+		}
+		if t == int64SliceType || t == float64SliceType || t == stringSliceType || t == intSliceType {
+			break
+		}
+		return sliceDecoder(t, seen)
+	}
+	return slow
+}
+
+// dataDecoder returns the decoderFunc for a string field with the "data"
+// option, which decodes a CFData into the string and passes anything else to
+// dec.
+func dataDecoder(dec decoderFunc) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if cfTypeID(cfObj) != cfDataTypeID {
+			return dec(state, cfObj, p)
+		}
+		*(*string)(p) = convertCFDataToString(C.CFDataRef(cfObj))
+		return nil
+	}
+}
+
+// timeFormatDecoder returns the decoderFunc for a field of type t with the
+// "format" option, which decodes CFDates into numbers and strings and numbers
+// into a time.Time in that format, and passes anything else to dec.
+func timeFormatDecoder(t reflect.Type, format string, dec decoderFunc) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if state.unmarshalTimeFormat(cfObj, reflect.NewAt(t, p).Elem(), format) {
+			return nil
+		}
+		return dec(state, cfObj, p)
+	}
+}
+
+// tupleDecoder returns the decoderFunc for a field of type t with the "tuple"
+// option, which decodes a CFArray as unmarshalTuple does and passes anything
+// else to dec.
+func tupleDecoder(t reflect.Type, dec decoderFunc) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if ok, err := state.unmarshalTuple(cfObj, reflect.NewAt(t, p).Elem()); ok {
+			return err
+		}
+		return dec(state, cfObj, p)
+	}
+}
+
+// setDecoder returns the decoderFunc for a field of type t with the "set"
+// option, which decodes a CFArray as unmarshalSet does and passes anything
+// else to dec.
+func setDecoder(t reflect.Type, dec decoderFunc) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if ok, err := state.unmarshalSet(cfObj, reflect.NewAt(t, p).Elem()); ok {
+			return err
+		}
+		return dec(state, cfObj, p)
+	}
+}
+
+// omitNilDecoder returns the decoderFunc for a field of type t with the
+// "omitnil" option, which decodes with dec and then does what keepEmptySlice
+// does.
+func omitNilDecoder(t reflect.Type, dec decoderFunc) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		defer keepEmptySlice(cfObj, reflect.NewAt(t, p).Elem())
+		return dec(state, cfObj, p)
+	}
+}
+
+// stringerDecoder returns the decoderFunc for a field of type t with the
+// "stringer" option, which parses CFStrings with the parser for t and passes
+// anything else to dec.
+func stringerDecoder(t reflect.Type, dec decoderFunc) decoderFunc {
+	parse := stringParser(t)
+	if parse == nil {
+		return dec
+	}
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if cfTypeID(cfObj) != cfStringTypeID {
+			return dec(state, cfObj, p)
+		}
+		v, err := parse(convertCFStringToString(C.CFStringRef(cfObj)))
+		if err != nil {
+			state.recordError(err)
+			return nil
+		}
+		reflect.NewAt(t, p).Elem().Set(v)
+		return nil
+	}
+}
+
+// cfTypeID returns the type ID of cfObj.
+func cfTypeID(cfObj cfTypeRef) C.CFTypeID {
+	return C.CFGetTypeID(C.CFTypeRef(cfObj))
+}
+
+// reflectDecoder returns a decoderFunc that decodes values of type t with
+// unmarshalValue.
+func reflectDecoder(t reflect.Type) decoderFunc {
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		return state.unmarshalValue(cfObj, reflect.NewAt(t, p).Elem())
+	}
+}
+
+// getScratch returns n values from the end of the scratch space of state,
+// along with the length to truncate it back to once they are no longer
+// needed.
+func (state *unmarshalState) getScratch(n int) ([]cfTypeRef, int) {
+	base := len(state.scratch)
+	state.scratch = append(state.scratch, make([]cfTypeRef, n)...)
+	return state.scratch[base:], base
+}
+
+// sliceDecoder returns the decoderFunc for the slice type t. Like
+// unmarshalValue, it leaves the slice alone if the array is empty, and hands
+// arrays long enough to be converted in parallel to unmarshalArray.
+func sliceDecoder(t reflect.Type, seen map[reflect.Type]*decoderFunc) decoderFunc {
+	slow := reflectDecoder(t)
+	elem := compileDecoder(t.Elem(), seen)
+	size := t.Elem().Size()
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if cfTypeID(cfObj) != cfArrayTypeID {
+			return slow(state, cfObj, p)
+		}
+		cfArray := C.CFArrayRef(cfObj)
+		count := int(C.CFArrayGetCount(cfArray))
+		if count == 0 || parallelChunks(count) != 0 {
+			return slow(state, cfObj, p)
+		}
+		elems, base := state.getScratch(count)
+		defer func() { state.scratch = state.scratch[:base] }()
+		C.CFArrayGetValues(cfArray, C.CFRange{0, C.CFIndex(count)}, (*unsafe.Pointer)(&elems[0]))
+		slice := reflect.MakeSlice(t, count, count)
+		reflect.NewAt(t, p).Elem().Set(slice)
+		data := slice.UnsafePointer()
+		saved := state.order
+		defer func() { state.order = saved }()
+		for i, cfElem := range elems {
+			state.order = saved.index(i)
+			if err := elem(state, cfElem, unsafe.Add(data, uintptr(i)*size)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// typedDecodeField is a struct field with its offset and decoderFunc.
+type typedDecodeField struct {
+	sf     reflect.StructField
+	offset uintptr
+	dec    decoderFunc
+}
+
+// structDecoder returns the decoderFunc for the struct type t, which finds the
+// field for a key as decodeFields.field does.
+func structDecoder(t reflect.Type, seen map[reflect.Type]*decoderFunc) decoderFunc {
+	slow := reflectDecoder(t)
+	df := cachedDecodeFields(t)
+	fields := make([]typedDecodeField, len(df.fields))
+	for i, sf := range df.fields {
+		fields[i] = typedDecodeField{sf: sf.StructField, offset: sf.Offset}
+		if sf.PkgPath == "" {
+			fields[i].dec = compileDecoder(sf.Type, seen)
+			if sf.asData && sf.Type.Kind() == reflect.String {
+				fields[i].dec = dataDecoder(fields[i].dec)
+			}
+			if sf.stringer {
+				fields[i].dec = stringerDecoder(sf.Type, fields[i].dec)
+			}
+			if sf.timeFormat != "" {
+				fields[i].dec = timeFormatDecoder(sf.Type, sf.timeFormat, fields[i].dec)
+			}
+			if sf.tuple {
+				fields[i].dec = tupleDecoder(sf.Type, fields[i].dec)
+			}
+			if sf.set {
+				fields[i].dec = setDecoder(sf.Type, fields[i].dec)
+			}
+			if sf.omitNil {
+				fields[i].dec = omitNilDecoder(sf.Type, fields[i].dec)
+			}
+		}
+	}
+	// a tag takes precedence over a field name
+	byKey := make(map[string]*typedDecodeField, len(df.byName)+len(df.byTag))
+	for name, idx := range df.byName {
+		byKey[name] = &fields[idx]
+	}
+	for tag, idx := range df.byTag {
+		byKey[tag] = &fields[idx]
+	}
+	byFold := make(map[string]*typedDecodeField, len(df.byFold))
+	for fold, idx := range df.byFold {
+		byFold[fold] = &fields[idx]
+	}
+	return func(state *unmarshalState, cfObj cfTypeRef, p unsafe.Pointer) error {
+		if cfTypeID(cfObj) != cfDictionaryTypeID {
+			return slow(state, cfObj, p)
+		}
+		cfDict := C.CFDictionaryRef(cfObj)
+		count := int(C.CFDictionaryGetCount(cfDict))
+		if count == 0 {
+			return nil
+		}
+		refs, base := state.getScratch(2 * count)
+		defer func() { state.scratch = state.scratch[:base] }()
+		cfKeys, cfVals := refs[:count], refs[count:]
+		C.CFDictionaryGetKeysAndValues(cfDict, (*unsafe.Pointer)(&cfKeys[0]), (*unsafe.Pointer)(&cfVals[0]))
+		saved := state.order
+		defer func() { state.order = saved }()
+		for i, cfKey := range cfKeys {
+			if cfTypeID(cfKey) != cfStringTypeID {
+				if _, err := convertCFKeyToString(cfKey, state.stringifyKeys); err != nil {
+					return err
+				}
+				// the slow path checks stringified keys for collisions
+				return slow(state, cfObj, p)
+			}
+			// looking up string(keyBuf) doesn't allocate
+			state.keyBuf = appendCFStringBytes(state.keyBuf[:0], C.CFStringRef(cfKey))
+			f, ok := byKey[string(state.keyBuf)]
+			if !ok {
+				f, ok = byFold[foldName(string(state.keyBuf))]
+			}
+			if !ok {
+				continue
+			}
+			if f.dec == nil {
+				// this is an unexported field
+				return &UnmarshalFieldError{string(state.keyBuf), t, f.sf}
+			}
+			if saved != nil {
+				state.order = saved.key(string(state.keyBuf))
+			}
+			if err := f.dec(state, cfVals[i], unsafe.Add(p, f.offset)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedNode struct {
+	Name     string
+	Weight   float32 `plist:",omitempty"`
+	Children []*typedNode
+	Pair     [2]int8
+	Tags     []string `plist:"tags,omitempty"`
+	When     time.Time
+	Data     []byte
+	Extra    interface{} `plist:",omitempty"`
+	ID       UID         `plist:",omitempty"`
+	R        Ref
+	V        *Val
+}
+
+// checkTypedEncoder checks that enc encodes v as Marshal does.
+func checkTypedEncoder[T any](t *testing.T, enc func(T, Format) ([]byte, error), v T) {
+	t.Helper()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		want, wantErr := Marshal(v, format)
+		got, err := enc(v, format)
+		if (err == nil) != (wantErr == nil) {
+			t.Errorf("%v: got error %v, want %v", format, err, wantErr)
+		} else if err != nil && err.Error() != wantErr.Error() {
+			t.Errorf("%v: got error %q, want %q", format, err, wantErr)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%v: got\n%s\nwant\n%s", format, got, want)
+		}
+	}
+}
+
+func TestTypedEncoder(t *testing.T) {
+	tree := typedNode{
+		Name:   "root",
+		Weight: 1.5,
+		Pair:   [2]int8{-1, 1},
+		When:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:   []byte("data"),
+		Extra:  map[string]interface{}{"a": int64(1)},
+		V:      new(Val),
+		Children: []*typedNode{
+			{Name: "a", Tags: []string{"x", "y"}, ID: 3, V: new(Val)},
+			{Name: "b", Children: []*typedNode{}, V: new(Val)},
+		},
+	}
+	enc := NewTypedEncoder[typedNode]()
+	checkTypedEncoder(t, enc, tree)
+	// the children are addressable, so the pointer method of Ref is used
+	if data, err := enc(tree, XMLFormat); err != nil || bytes.Count(data, []byte("<string>ref</string>")) != 2 {
+		t.Errorf("got %s, %v, want ref for both children", data, err)
+	}
+	checkTypedEncoder(t, NewTypedEncoder[*typedNode](), &tree)
+	checkTypedEncoder(t, NewTypedEncoder[[]typedNode](), []typedNode{tree, *tree.Children[0]})
+	checkTypedEncoder(t, NewTypedEncoder[Optionals](), Optionals{Sr: "x", Io: 3, Mr: map[string]interface{}{}})
+	checkTypedEncoder(t, NewTypedEncoder[interface{}](), interface{}(tree))
+
+	// errors
+	tree.Children[1].V = nil
+	checkTypedEncoder(t, enc, tree)
+	tree.Weight = float32(math.Inf(-1))
+	checkTypedEncoder(t, enc, tree)
+	checkTypedEncoder(t, NewTypedEncoder[struct{ U uint64 }](), struct{ U uint64 }{1})
+	checkTypedEncoder(t, NewTypedEncoder[*int](), nil)
+
+	// long slices go through the parallel conversion
+	nodes := make([]typedNode, 100)
+	for i := range nodes {
+		nodes[i] = typedNode{Name: "n", V: new(Val)}
+	}
+	withParallelism(10, func() {
+		checkTypedEncoder(t, NewTypedEncoder[[]typedNode](), nodes)
+	})
+}
+
+// jsonPlist returns the XML property list of the JSON value in s.
+func jsonPlist(t *testing.T, s string) []byte {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(v, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// checkTypedDecoder checks that dec decodes data into v as Unmarshal does.
+func checkTypedDecoder[T any](t *testing.T, dec *TypedDecoder[T], data []byte, v T) {
+	t.Helper()
+	want := v
+	wantFormat, wantErr := Unmarshal(data, &want)
+	got := v
+	format, err := dec.Decode(data, &got)
+	if format != wantFormat {
+		t.Errorf("got format %v, want %v", format, wantFormat)
+	}
+	if (err == nil) != (wantErr == nil) || err != nil && err.Error() != wantErr.Error() {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestTypedDecoder(t *testing.T) {
+	tree := typedNode{
+		Name:   "root",
+		Weight: 1.5,
+		Pair:   [2]int8{-1, 1},
+		When:   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:   []byte("data"),
+		Extra:  map[string]interface{}{"a": int64(1)},
+		ID:     7,
+		Children: []*typedNode{
+			{Name: "a", Tags: []string{"x", "y"}},
+			{Name: "b"},
+		},
+	}
+	dec := NewTypedDecoder[typedNode]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(tree, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// decode twice, to reuse the scratch space
+		checkTypedDecoder(t, dec, data, typedNode{})
+		checkTypedDecoder(t, dec, data, typedNode{Name: "old", Children: []*typedNode{{Name: "kept"}}})
+	}
+
+	// keys match by tag, name and case-insensitively
+	checkTypedDecoder(t, NewTypedDecoder[renamed](), jsonPlist(t, `{"B": "b", "b": "x", "KELVIN": "k"}`), renamed{})
+
+	// values of the wrong type are skipped and reported
+	checkTypedDecoder(t, dec, jsonPlist(t, `{"Name": 1, "Weight": "x", "Pair": [300, 2], "Children": [{"Name": "c"}, 3]}`), typedNode{})
+	checkTypedDecoder(t, NewTypedDecoder[[]uint8](), jsonPlist(t, `[1, 256, 2]`), nil)
+	checkTypedDecoder(t, NewTypedDecoder[struct{ F float32 }](), jsonPlist(t, `{"F": 1e300}`), struct{ F float32 }{})
+	checkTypedDecoder(t, NewTypedDecoder[struct{ x int }](), jsonPlist(t, `{"x": 1}`), struct{ x int }{})
+	checkTypedDecoder(t, NewTypedDecoder[ustruct](), jsonPlist(t, `{"M": {}}`), ustruct{})
+	checkTypedDecoder(t, NewTypedDecoder[*int](), jsonPlist(t, `3`), nil)
+
+	// the key order is kept for Dict values
+	d := &Dict{}
+	d.Set("z", true)
+	d.Set("a", true)
+	data, err := Marshal(map[string]interface{}{"D": []*Dict{d}}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type ordered struct{ D []Dict }
+	checkTypedDecoder(t, NewTypedDecoder[ordered](), data, ordered{})
+	var o ordered
+	if _, err := NewTypedDecoder[ordered]().Decode(data, &o); err != nil || len(o.D) != 1 || o.D[0].Keys()[0] != "z" {
+		t.Errorf("got %v, %v, want a Dict starting with key z", o, err)
+	}
+
+	if _, err := dec.Decode(jsonPlist(t, `{}`), nil); err == nil {
+		t.Error("decoding into a nil pointer: got no error")
+	}
+}
@@ -0,0 +1,641 @@
+package plist
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"unicode/utf16"
+)
+
+// A TypedStreamObject is an object decoded from an NSArchiver typedstream
+// whose class has no native Go representation.
+//
+// Unlike keyed archives, typedstreams don't name the values an object
+// archives. Values holds them in the order they were written, with objects
+// resolved and C arrays and structs decoded as []interface{} (or []byte for
+// arrays of chars).
+type TypedStreamObject struct {
+	Class   string   // the class of the object
+	Classes []string // the class hierarchy, most derived class first
+	Values  []interface{}
+}
+
+// UnarchiveTypedStream decodes the root object of a typedstream, the format
+// written by the legacy non-keyed NSArchiver.
+//
+// Decoding is best-effort. The following classes are bridged to Go values in
+// the same way as Unarchive:
+//
+//	NSString and NSMutableString, as string
+//	NSArray and NSMutableArray, as []interface{}
+//	NSDictionary and NSMutableDictionary, as map[string]interface{}
+//	NSData and NSMutableData, as []byte
+//	NSNumber, as the numeric type it holds
+//	NSDate, as time.Time
+//	NSAttributedString and NSMutableAttributedString, as AttributedString
+//
+// Objects of any other class, and objects of these classes whose contents
+// don't have the expected layout, decode as a *TypedStreamObject.
+func UnarchiveTypedStream(data []byte) (interface{}, error) {
+	r := &typedStreamReader{data: data}
+	if err := r.readHeader(); err != nil {
+		return nil, err
+	}
+	encodings, err := r.readTypeEncodings()
+	if err != nil {
+		return nil, err
+	}
+	if len(encodings) == 0 {
+		return nil, errors.New("plist: typedstream has no root object")
+	}
+	return r.readValue(encodings[0])
+}
+
+// typedstream tags. Any other byte is a literal integer in the range
+// [-110, 127].
+const (
+	tsTagInteger2 = 0x81
+	tsTagInteger4 = 0x82
+	tsTagFloat    = 0x83
+	tsTagNew      = 0x84
+	tsTagNil      = 0x85
+	tsTagEnd      = 0x86
+	tsLastTag     = 0x91
+
+	// reference numbers are stored offset by the first non-tag value
+	tsFirstReference = -110
+)
+
+var errTypedStreamEOF = errors.New("plist: unexpected end of typedstream")
+
+type typedStreamReader struct {
+	data    []byte
+	pos     int
+	order   binary.ByteOrder
+	strings []string      // the shared string table
+	objects []interface{} // objects, classes and C strings that may be referenced
+}
+
+// typedStreamClass is a class entry in the object table.
+type typedStreamClass struct {
+	name    string
+	version int64
+	super   *typedStreamClass
+}
+
+func (r *typedStreamReader) readByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errTypedStreamEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *typedStreamReader) readBytes(n int) ([]byte, error) {
+	if n < 0 || len(r.data)-r.pos < n {
+		return nil, errTypedStreamEOF
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *typedStreamReader) readHeader() error {
+	version, err := r.readInteger()
+	if err != nil {
+		return err
+	}
+	signature, err := r.readUnsharedString()
+	if err != nil {
+		return err
+	}
+	switch string(signature) {
+	case "streamtyped":
+		r.order = binary.LittleEndian
+	case "typedstream":
+		r.order = binary.BigEndian
+	default:
+		return errors.New("plist: not a typedstream")
+	}
+	if version != 4 {
+		return errors.New("plist: unsupported typedstream version " + strconv.FormatInt(version, 10))
+	}
+	// the system version isn't needed for decoding
+	_, err = r.readInteger()
+	return err
+}
+
+func (r *typedStreamReader) readInteger() (int64, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return 0, err
+	}
+	return r.readIntegerWithHead(head)
+}
+
+func (r *typedStreamReader) readIntegerWithHead(head byte) (int64, error) {
+	switch {
+	case (head == tsTagInteger2 || head == tsTagInteger4) && r.order == nil:
+		// the byte order isn't known until the signature has been read
+		return 0, errors.New("plist: not a typedstream")
+	case head == tsTagInteger2:
+		b, err := r.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int16(r.order.Uint16(b))), nil
+	case head == tsTagInteger4:
+		b, err := r.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return int64(int32(r.order.Uint32(b))), nil
+	case head >= 0x80 && head <= tsLastTag:
+		return 0, errors.New("plist: unexpected tag " + strconv.Itoa(int(head)) + " in typedstream integer")
+	}
+	return int64(int8(head)), nil
+}
+
+// readReference reads a reference number whose first byte is head.
+func (r *typedStreamReader) readReference(head byte, tableLen int) (int, error) {
+	n, err := r.readIntegerWithHead(head)
+	if err != nil {
+		return 0, err
+	}
+	idx := n - tsFirstReference
+	if idx < 0 || idx >= int64(tableLen) {
+		return 0, errors.New("plist: invalid typedstream reference " + strconv.FormatInt(n, 10))
+	}
+	return int(idx), nil
+}
+
+func (r *typedStreamReader) readUnsharedString() ([]byte, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if head == tsTagNil {
+		return nil, nil
+	}
+	n, err := r.readIntegerWithHead(head)
+	if err != nil {
+		return nil, err
+	}
+	return r.readBytes(int(n))
+}
+
+// readSharedString returns the string and whether it was non-nil.
+func (r *typedStreamReader) readSharedString() (string, bool, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return "", false, err
+	}
+	switch head {
+	case tsTagNil:
+		return "", false, nil
+	case tsTagNew:
+		b, err := r.readUnsharedString()
+		if err != nil {
+			return "", false, err
+		}
+		r.strings = append(r.strings, string(b))
+		return string(b), true, nil
+	}
+	idx, err := r.readReference(head, len(r.strings))
+	if err != nil {
+		return "", false, err
+	}
+	return r.strings[idx], true, nil
+}
+
+// readCString reads a char * value. These are shared strings that are also
+// entered into the object table.
+func (r *typedStreamReader) readCString() (interface{}, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch head {
+	case tsTagNil:
+		return nil, nil
+	case tsTagNew:
+		str, ok, err := r.readSharedString()
+		if err != nil || !ok {
+			return nil, err
+		}
+		r.objects = append(r.objects, str)
+		return str, nil
+	}
+	idx, err := r.readReference(head, len(r.objects))
+	if err != nil {
+		return nil, err
+	}
+	return r.objects[idx], nil
+}
+
+func (r *typedStreamReader) readClass() (*typedStreamClass, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch head {
+	case tsTagNil:
+		return nil, nil
+	case tsTagNew:
+		name, ok, err := r.readSharedString()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, errors.New("plist: typedstream class has no name")
+		}
+		version, err := r.readInteger()
+		if err != nil {
+			return nil, err
+		}
+		class := &typedStreamClass{name: name, version: version}
+		r.objects = append(r.objects, class)
+		if class.super, err = r.readClass(); err != nil {
+			return nil, err
+		}
+		// the superclass can refer back to the class, which is already in
+		// the table
+		for c := class.super; c != nil; c = c.super {
+			if c == class {
+				return nil, errors.New("plist: typedstream class " + strconv.Quote(name) + " is its own superclass")
+			}
+		}
+		return class, nil
+	}
+	idx, err := r.readReference(head, len(r.objects))
+	if err != nil {
+		return nil, err
+	}
+	class, ok := r.objects[idx].(*typedStreamClass)
+	if !ok {
+		return nil, errors.New("plist: typedstream class reference is not a class")
+	}
+	return class, nil
+}
+
+func (r *typedStreamReader) readObject() (interface{}, error) {
+	head, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch head {
+	case tsTagNil:
+		return nil, nil
+	case tsTagNew:
+		// decoded below
+	default:
+		idx, err := r.readReference(head, len(r.objects))
+		if err != nil {
+			return nil, err
+		}
+		return r.objects[idx], nil
+	}
+	// the object is entered into the table before its class
+	idx := len(r.objects)
+	r.objects = append(r.objects, nil)
+	class, err := r.readClass()
+	if err != nil {
+		return nil, err
+	}
+	if class == nil {
+		return nil, errors.New("plist: typedstream object has no class")
+	}
+	obj := &TypedStreamObject{Class: class.name}
+	for c := class; c != nil; c = c.super {
+		obj.Classes = append(obj.Classes, c.name)
+	}
+	r.objects[idx] = obj
+	for {
+		if r.pos < len(r.data) && r.data[r.pos] == tsTagEnd {
+			r.pos++
+			break
+		}
+		encodings, err := r.readTypeEncodings()
+		if err != nil {
+			return nil, err
+		}
+		for _, enc := range encodings {
+			val, err := r.readValue(enc)
+			if err != nil {
+				return nil, err
+			}
+			obj.Values = append(obj.Values, val)
+		}
+	}
+	result := bridgeTypedStreamObject(obj)
+	r.objects[idx] = result
+	return result, nil
+}
+
+// readTypeEncodings reads the type string that precedes each group of values.
+func (r *typedStreamReader) readTypeEncodings() ([]string, error) {
+	str, ok, err := r.readSharedString()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("plist: typedstream value has no type")
+	}
+	return splitTypeEncodings(str)
+}
+
+func (r *typedStreamReader) readValue(enc string) (interface{}, error) {
+	if enc == "" {
+		return nil, errors.New("plist: empty typedstream type")
+	}
+	switch enc[0] {
+	case 'c', 's', 'i', 'l', 'q':
+		return r.readInteger()
+	case 'C', 'S', 'I', 'L', 'Q':
+		n, err := r.readInteger()
+		if err != nil {
+			return nil, err
+		}
+		switch enc[0] {
+		case 'C':
+			return uint64(uint8(n)), nil
+		case 'S':
+			return uint64(uint16(n)), nil
+		case 'I', 'L':
+			return uint64(uint32(n)), nil
+		}
+		return uint64(n), nil
+	case 'B':
+		n, err := r.readInteger()
+		return n != 0, err
+	case 'f', 'd':
+		head, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if head != tsTagFloat {
+			// integral values are stored as integers
+			n, err := r.readIntegerWithHead(head)
+			return float64(n), err
+		}
+		if enc[0] == 'f' {
+			b, err := r.readBytes(4)
+			if err != nil {
+				return nil, err
+			}
+			return float64(math.Float32frombits(r.order.Uint32(b))), nil
+		}
+		b, err := r.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(r.order.Uint64(b)), nil
+	case '*':
+		return r.readCString()
+	case '%', ':':
+		str, ok, err := r.readSharedString()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return str, nil
+	case '+':
+		b, err := r.readUnsharedString()
+		if err != nil || b == nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case '@':
+		return r.readObject()
+	case '#':
+		class, err := r.readClass()
+		if err != nil || class == nil {
+			return nil, err
+		}
+		return class.name, nil
+	case '^':
+		return r.readValue(enc[1:])
+	case '[':
+		i := 1
+		for i < len(enc) && enc[i] >= '0' && enc[i] <= '9' {
+			i++
+		}
+		count, err := strconv.Atoi(enc[1:i])
+		if err != nil || i == len(enc)-1 || enc[len(enc)-1] != ']' {
+			return nil, errors.New("plist: invalid typedstream array type " + strconv.Quote(enc))
+		}
+		// every element takes at least a byte, so a count larger than what's
+		// left of the stream can't be satisfied
+		if count > len(r.data)-r.pos {
+			return nil, errTypedStreamEOF
+		}
+		elem := enc[i : len(enc)-1]
+		if elem == "c" || elem == "C" {
+			b, err := r.readBytes(count)
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte(nil), b...), nil
+		}
+		values := make([]interface{}, count)
+		for j := range values {
+			if values[j], err = r.readValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case '{':
+		if len(enc) < 2 || enc[len(enc)-1] != '}' {
+			return nil, errors.New("plist: invalid typedstream struct type " + strconv.Quote(enc))
+		}
+		fields := ""
+		for i := 1; i < len(enc)-1; i++ {
+			if enc[i] == '=' {
+				fields = enc[i+1 : len(enc)-1]
+				break
+			}
+		}
+		encodings, err := splitTypeEncodings(fields)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(encodings))
+		for j, e := range encodings {
+			if values[j], err = r.readValue(e); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case 'v':
+		return nil, nil
+	}
+	return nil, errors.New("plist: unsupported typedstream type " + strconv.Quote(enc))
+}
+
+// splitTypeEncodings splits an Objective-C type string into the encodings of
+// the individual values, dropping any type qualifiers.
+func splitTypeEncodings(s string) ([]string, error) {
+	var result []string
+	for i := 0; i < len(s); {
+		for i < len(s) && isTypeQualifier(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+		end, err := typeEncodingEnd(s, i)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s[i:end])
+		i = end
+	}
+	return result, nil
+}
+
+func isTypeQualifier(c byte) bool {
+	switch c {
+	case 'r', 'n', 'N', 'o', 'O', 'R', 'V':
+		return true
+	}
+	return false
+}
+
+// typeEncodingEnd returns the index just past the type encoding at s[i].
+func typeEncodingEnd(s string, i int) (int, error) {
+	switch s[i] {
+	case '^':
+		if i+1 >= len(s) {
+			break
+		}
+		return typeEncodingEnd(s, i+1)
+	case '[', '{', '(':
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '[', '{', '(':
+				depth++
+			case ']', '}', ')':
+				depth--
+				if depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+	default:
+		return i + 1, nil
+	}
+	return 0, errors.New("plist: invalid typedstream type " + strconv.Quote(s))
+}
+
+// bridgeTypedStreamObject converts objects of well-known classes into native
+// Go values. Objects whose values don't match the expected layout are
+// returned unchanged.
+func bridgeTypedStreamObject(obj *TypedStreamObject) interface{} {
+	values := obj.Values
+	switch obj.Class {
+	case "NSString", "NSMutableString":
+		if len(values) == 1 {
+			if b, ok := values[0].([]byte); ok {
+				return string(b)
+			}
+		}
+	case "NSArray", "NSMutableArray":
+		if len(values) > 0 {
+			if count, ok := values[0].(int64); ok && int(count) == len(values)-1 {
+				return append([]interface{}{}, values[1:]...)
+			}
+		}
+	case "NSDictionary", "NSMutableDictionary":
+		if len(values) == 0 {
+			break
+		}
+		count, ok := values[0].(int64)
+		if !ok || int(count)*2 != len(values)-1 {
+			break
+		}
+		m := make(map[string]interface{}, count)
+		for i := 1; i < len(values); i += 2 {
+			key, ok := values[i].(string)
+			if !ok {
+				return obj
+			}
+			m[key] = values[i+1]
+		}
+		return m
+	case "NSData", "NSMutableData":
+		if len(values) == 2 {
+			if b, ok := values[1].([]byte); ok {
+				return b
+			}
+		}
+	case "NSNumber":
+		// NSNumber writes its Objective-C type followed by the value
+		if len(values) == 2 {
+			if _, ok := values[0].(string); ok {
+				return values[1]
+			}
+		}
+	case "NSDate":
+		if len(values) == 1 {
+			if secs, ok := values[0].(float64); ok {
+				return cocoaTime(secs)
+			}
+		}
+	case "NSAttributedString", "NSMutableAttributedString":
+		if s, ok := bridgeTypedStreamAttributedString(values); ok {
+			return s
+		}
+	}
+	return obj
+}
+
+// bridgeTypedStreamAttributedString decodes the values of an
+// NSAttributedString. After the string, each run is written as its attribute
+// dictionary number and length, followed by the dictionary itself the first
+// time that number is used.
+func bridgeTypedStreamAttributedString(values []interface{}) (AttributedString, bool) {
+	var result AttributedString
+	if len(values) == 0 {
+		return result, false
+	}
+	str, ok := values[0].(string)
+	if !ok {
+		return result, false
+	}
+	result.String = str
+	var dicts []map[string]interface{}
+	var location int64
+	for i := 1; i < len(values); {
+		if i+1 >= len(values) {
+			return result, false
+		}
+		num, ok1 := values[i].(int64)
+		length, ok2 := values[i+1].(uint64)
+		if !ok1 || !ok2 || num < 1 {
+			return result, false
+		}
+		i += 2
+		if int(num) > len(dicts) {
+			if i >= len(values) {
+				return result, false
+			}
+			dict, ok := values[i].(map[string]interface{})
+			if !ok {
+				return result, false
+			}
+			dicts = append(dicts, dict)
+			i++
+		}
+		if int(num) > len(dicts) {
+			return result, false
+		}
+		result.Runs = append(result.Runs, AttributeRun{Range{location, int64(length)}, dicts[num-1]})
+		location += int64(length)
+	}
+	if location != int64(len(utf16.Encode([]rune(str)))) && len(result.Runs) > 0 {
+		return result, false
+	}
+	return result, true
+}
@@ -0,0 +1,92 @@
+package plist
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// the attributedBody of a message from the macOS Messages database
+var typedStreamAttributedString = []byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x12NSAttributedString\x00" +
+	"\x84\x84\x08NSObject\x00\x85\x92\x84\x84\x84\x08NSString\x01\x94\x84\x01+\x0cHello World!\x86" +
+	"\x84\x02iI\x01\x0c\x92\x84\x84\x84\x0cNSDictionary\x00\x94\x84\x01i\x01\x92\x84\x96\x96" +
+	"\x1d__kIMMessagePartAttributeName\x86\x92\x84\x84\x84\x08NSNumber\x00\x84\x84\x07NSValue\x00" +
+	"\x94\x84\x01*\x84\x99\x99\x00\x86\x86\x86")
+
+func TestUnarchiveTypedStream(t *testing.T) {
+	v, err := UnarchiveTypedStream(typedStreamAttributedString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AttributedString{
+		String: "Hello World!",
+		Runs: []AttributeRun{{
+			Range:      Range{0, 12},
+			Attributes: map[string]interface{}{"__kIMMessagePartAttributeName": int64(0)},
+		}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v\nwant %#v", v, want)
+	}
+}
+
+func TestUnarchiveTypedStreamInvalid(t *testing.T) {
+	inputs := [][]byte{
+		nil,
+		[]byte("bplist00"),
+		typedStreamAttributedString[:40],
+		// an array with no element type
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x03[5]"),
+		// an array longer than the stream
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x14[99999999999999999i]"),
+		// an unterminated array
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x03[5i\x01"),
+		// a pointer to nothing
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01^\x01"),
+		// an unbalanced struct inside an array
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x05[1{]=\x01"),
+		// a multi-byte integer before the byte order is known
+		[]byte("\x81000"),
+		[]byte("\x04\x82000"),
+		// a class that is its own superclass
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x03Foo\x00\x93\x86"),
+		// a class whose superclass's superclass is the class
+		[]byte("\x04\x0bstreamtyped\x81\xe8\x03\x84\x01@\x84\x84\x84\x03Foo\x00\x84\x84\x03Bar\x00\x93\x86"),
+	}
+	for i, data := range inputs {
+		if _, err := UnarchiveTypedStream(data); err == nil {
+			t.Errorf("#%d: expected error", i)
+		}
+	}
+
+	// malformed types that splitTypeEncodings doesn't produce but readValue
+	// must still reject
+	for _, enc := range []string{"", "{=", "{", "[1{]=", "[5]", "^"} {
+		r := &typedStreamReader{data: []byte{1, 1, 1, 1}, order: binary.LittleEndian}
+		if _, err := r.readValue(enc); err == nil {
+			t.Errorf("readValue(%q): expected error", enc)
+		}
+	}
+}
+
+func TestSplitTypeEncodings(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"iI", []string{"i", "I"}},
+		{"@", []string{"@"}},
+		{"r*{_NSRange=QQ}[12c]^i", []string{"*", "{_NSRange=QQ}", "[12c]", "^i"}},
+		{"{a={b=ii}[2{c=d}]}", []string{"{a={b=ii}[2{c=d}]}"}},
+	}
+	for _, tt := range tests {
+		out, err := splitTypeEncodings(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("%q: got %q, want %q", tt.in, out, tt.out)
+		}
+	}
+}
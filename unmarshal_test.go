@@ -3,8 +3,10 @@ package plist
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 	"testing/quick"
+	"unsafe"
 )
 
 // The tests here are based off of the ones in encoding/json
@@ -21,6 +23,13 @@ type tx struct {
 
 var txType = reflect.TypeOf((*tx)(nil)).Elem()
 
+// renamed has fields that keys match by tag, by name and case-insensitively.
+type renamed struct {
+	A      string `plist:"B"`
+	B      string
+	Kelvin string
+}
+
 // A type that can unmarshal itself.
 
 type unmarshaler struct {
@@ -69,6 +78,11 @@ var unmarshalTests = []unmarshalTest{
 	// Z has a "-" tag.
 	{`{"Y": 1, "Z": 2}`, new(T), T{Y: 1}, nil},
 
+	// tags take precedence over names, which take precedence over
+	// case-insensitive matches
+	{`{"B": "x", "kelvin": "y"}`, new(renamed), renamed{A: "x", Kelvin: "y"}, nil},
+	{`{"b": "x", "KELVIN": "y", "a": "z"}`, new(renamed), renamed{A: "z", B: "x", Kelvin: "y"}, nil},
+
 	// array tests
 	{`[1, 2, 3]`, new([3]int), [3]int{1, 2, 3}, nil},
 	{`[1, 2, 3]`, new([1]int), [1]int{1}, nil},
@@ -120,6 +134,17 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+func TestFoldName(t *testing.T) {
+	names := []string{"", "a", "A", "kelvin", "\u212aelvin", "KELVIN", "s", "\u017f", "Stra\u00dfe", "STRASSE", "\u00e9", "\u00c9", "x1", "X1", "a_b", "A_B"}
+	for _, a := range names {
+		for _, b := range names {
+			if got, want := foldName(a) == foldName(b), strings.EqualFold(a, b); got != want {
+				t.Errorf("%q, %q: foldName equal is %v, EqualFold is %v", a, b, got, want)
+			}
+		}
+	}
+}
+
 func TestMarshalUnmarshalArbitrary(t *testing.T) {
 	f := func(arb Arbitrary) interface{} { a, _ := standardize(arb.Value); return a }
 	g := func(arb Arbitrary) interface{} {
@@ -148,3 +173,42 @@ func TestMarshalUnmarshalArbitrary(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestUnmarshalInternsKeys(t *testing.T) {
+	items := []map[string]interface{}{
+		{"Name": "a", "UUID": "1"},
+		{"Name": "b", "UUID": "2"},
+		{"Name": "c", "UUID": "3", "Extra": map[string]int{"Name": 4}},
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(items, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var maps []map[string]interface{}
+		var ints []struct{ Extra map[string]int }
+		for _, v := range []interface{}{&maps, &ints} {
+			if _, err := Unmarshal(data, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		name := func(m interface{}) *byte {
+			for _, k := range reflect.ValueOf(m).MapKeys() {
+				if k.String() == "Name" {
+					return unsafe.StringData(k.String())
+				}
+			}
+			t.Fatalf("%s: no Name key in %v", format, m)
+			return nil
+		}
+		first := name(maps[0])
+		for i, m := range []interface{}{maps[1], maps[2], maps[2]["Extra"]} {
+			if name(m) != first {
+				t.Errorf("%s: key %d was not interned", format, i)
+			}
+		}
+		if !reflect.DeepEqual(ints[2].Extra, map[string]int{"Name": 4}) {
+			t.Errorf("%s: got %v", format, ints[2].Extra)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package plist
+
+import (
+	"encoding/hex"
+	"reflect"
+)
+
+// isUUIDType reports whether t is [16]byte or a type defined as one, such as
+// the UUID types of the common UUID packages. Values of these types are
+// encoded as UUID strings, unless the field they're in has the "data" option.
+func isUUIDType(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.ConvertibleTo(uuidType)
+}
+
+// uuidOf returns the bytes of v, whose type is a UUID type.
+func uuidOf(v reflect.Value) [16]byte {
+	return v.Convert(uuidType).Interface().([16]byte)
+}
+
+// formatUUID returns the canonical string form of uuid, in the upper case
+// that CFUUID and NSUUID use.
+func formatUUID(uuid [16]byte) string {
+	var b [36]byte
+	const digits = "0123456789ABCDEF"
+	j := 0
+	for i, c := range uuid {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			b[j] = '-'
+			j++
+		}
+		b[j] = digits[c>>4]
+		b[j+1] = digits[c&0xf]
+		j += 2
+	}
+	return string(b[:])
+}
+
+// parseUUID parses a UUID in the canonical string form, in either case.
+func parseUUID(s string) ([16]byte, bool) {
+	var uuid [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return uuid, false
+	}
+	src := make([]byte, 0, 32)
+	src = append(src, s[:8]...)
+	src = append(src, s[9:13]...)
+	src = append(src, s[14:18]...)
+	src = append(src, s[19:23]...)
+	src = append(src, s[24:]...)
+	if _, err := hex.Decode(uuid[:], src); err != nil {
+		return uuid, false
+	}
+	return uuid, true
+}
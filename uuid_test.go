@@ -0,0 +1,96 @@
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testUUID [16]byte
+
+type profile struct {
+	PayloadUUID testUUID
+	Raw         [16]byte `plist:",data"`
+	Parent      *[16]byte
+}
+
+func TestUUID(t *testing.T) {
+	id := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 1, 2, 3, 4, 5, 6, 7, 0xff}
+	const idString = "12345678-9ABC-DEF0-0102-0304050607FF"
+	if s := formatUUID(id); s != idString {
+		t.Errorf("formatUUID: got %q", s)
+	}
+	for _, s := range []string{idString, "12345678-9abc-def0-0102-0304050607ff"} {
+		if u, ok := parseUUID(s); !ok || u != id {
+			t.Errorf("parseUUID(%q): got %x, %v", s, u, ok)
+		}
+	}
+	for _, s := range []string{"", "123456789ABCDEF00102030405060FF", "12345678-9ABC-DEF0-0102-0304050607FG", "12345678+9ABC-DEF0-0102-0304050607FF"} {
+		if _, ok := parseUUID(s); ok {
+			t.Errorf("parseUUID(%q) succeeded", s)
+		}
+	}
+
+	v := profile{PayloadUUID: testUUID(id), Raw: id, Parent: &id}
+	want := map[string]interface{}{
+		"PayloadUUID": idString,
+		"Raw":         id[:],
+		"Parent":      idString,
+	}
+	encode := NewTypedEncoder[profile]()
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		data, err := Marshal(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf, format).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		typed, err := encode(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{data, buf.Bytes(), typed} {
+			var plist interface{}
+			if _, err := Unmarshal(data, &plist); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(plist, want) {
+				t.Errorf("%s: got %#v, want %#v", format, plist, want)
+			}
+			var got profile
+			if _, err := Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, v) {
+				t.Errorf("%s: got %#v, want %#v", format, got, v)
+			}
+		}
+	}
+
+	// either form decodes into any field
+	data, err := Marshal(map[string]interface{}{"PayloadUUID": id[:], "Raw": idString}, XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got profile
+	if _, err := Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.PayloadUUID != testUUID(id) || got.Raw != id {
+		t.Errorf("got %#v", got)
+	}
+
+	for _, bad := range []interface{}{"not a uuid", id[:15]} {
+		data, err := Marshal(map[string]interface{}{"PayloadUUID": bad}, XMLFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ute *UnmarshalTypeError
+		if _, err := Unmarshal(data, &got); !errors.As(err, &ute) {
+			t.Errorf("%#v: expected UnmarshalTypeError, got %v", bad, err)
+		}
+	}
+}
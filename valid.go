@@ -0,0 +1,176 @@
+package plist
+
+// #include <CoreFoundation/CoreFoundation.h>
+import "C"
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// An InvalidValueError is returned by ValidValue for a value that can't be
+// represented in a property list of the given format. Path is the key path of
+// the offending element, and Err the error that converting it returned, or nil
+// if it converted but isn't valid in Format.
+type InvalidValueError struct {
+	Path   KeyPath
+	Format Format
+	Err    error
+}
+
+func (e *InvalidValueError) Error() string {
+	s := "plist: " + strconv.Quote(e.Path.String()) + " can't be represented in " + e.Format.String()
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// ValidValue reports whether v can be encoded as a property list of the given
+// format by Marshal. It converts v to CoreFoundation objects and checks them
+// with CFPropertyListIsValid, without serializing them, so it's cheaper than
+// calling Marshal and discarding the result. This is mostly useful for
+// OpenStepFormat, which only holds strings, data, arrays and dictionaries.
+//
+// If v can't be encoded, the error is an InvalidValueError holding the key
+// path of the offending element.
+func ValidValue(v interface{}, format Format) (err error) {
+	defer recoverPanic(&err)
+	state := &marshalState{}
+	rv := reflect.ValueOf(v)
+	cfObj, err := state.marshalValue(rv)
+	if err != nil {
+		return &InvalidValueError{state.errorPath(rv, KeyPath{}), format, err}
+	}
+	defer cfRelease(cfObj)
+	if path, ok := invalidPath(cfObj, format, KeyPath{}); !ok {
+		return &InvalidValueError{path, format, nil}
+	}
+	return nil
+}
+
+// invalidPath returns the path of the innermost element of cfObj, which is
+// at path, that isn't valid in format, and false, or true if cfObj is valid.
+func invalidPath(cfObj cfTypeRef, format Format, path KeyPath) (KeyPath, bool) {
+	if C.CFPropertyListIsValid(C.CFPropertyListRef(cfObj), format.cfFormat) != 0 {
+		return nil, true
+	}
+	switch cfTypeID(cfObj) {
+	case cfArrayTypeID:
+		cfArray := C.CFArrayRef(cfObj)
+		count := int(C.CFArrayGetCount(cfArray))
+		for i := 0; i < count; i++ {
+			elem := cfTypeRef(C.CFArrayGetValueAtIndex(cfArray, C.CFIndex(i)))
+			if p, ok := invalidPath(elem, format, appendPath(path, strconv.Itoa(i))); !ok {
+				return p, false
+			}
+		}
+	case cfDictionaryTypeID:
+		var found KeyPath
+		convertCFDictionaryToMapHelper(C.CFDictionaryRef(cfObj), false, func(key string, value cfTypeRef, count int) error {
+			if found == nil {
+				if p, ok := invalidPath(value, format, appendPath(path, key)); !ok {
+					found = p
+				}
+			}
+			return nil
+		})
+		if found != nil {
+			return found, false
+		}
+	}
+	return path, false
+}
+
+// errorPath returns the path of the innermost element of v, which is at path
+// and failed to convert, whose conversion fails on its own. The elements are
+// those that marshalValue converts separately: array elements, map and Dict
+// values, and struct fields. Values with Marshal methods, and fields with
+// options that change how they're encoded, aren't looked into.
+func (state *marshalState) errorPath(v reflect.Value, path KeyPath) KeyPath {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return path
+		}
+		v = v.Elem()
+	}
+	if hasMarshaler(v.Type(), v.CanAddr()) {
+		return path
+	}
+	// fails reports whether converting the element at key fails, and if so
+	// sets path to the element, or to where inside it the error is if descend
+	// is true.
+	fails := func(elem reflect.Value, key string, descend bool, marshal func() (cfTypeRef, error)) bool {
+		cfObj, err := marshal()
+		if err == nil {
+			cfRelease(cfObj)
+			return false
+		}
+		path = appendPath(path, key)
+		if descend {
+			path = state.errorPath(elem, path)
+		}
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type() == byteSliceType || isUUIDType(v.Type()) || v.Type() == ipType {
+			break
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if fails(elem, strconv.Itoa(i), true, func() (cfTypeRef, error) { return state.marshalValue(elem) }) {
+				return path
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			elem := v.MapIndex(k)
+			if fails(elem, k.String(), true, func() (cfTypeRef, error) { return state.marshalValue(elem) }) {
+				return path
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == dictType {
+			d := v.Interface().(Dict)
+			for _, k := range d.keys {
+				elem := reflect.ValueOf(d.values[k])
+				if fails(elem, k, true, func() (cfTypeRef, error) { return state.marshalValue(elem) }) {
+					return path
+				}
+			}
+			break
+		}
+		if !isTupleType(v.Type()) {
+			break
+		}
+		for _, ef := range encodeFields(v.Type()) {
+			ef := ef
+			elem := v.Field(ef.i)
+			if ef.omitted(elem) {
+				continue
+			}
+			plain := !ef.asData && !ef.stringer && ef.timeFormat == "" && !ef.tuple && !ef.set
+			if fails(elem, ef.name, plain, func() (cfTypeRef, error) { return state.marshalField(&ef, elem) }) {
+				return path
+			}
+		}
+	}
+	return path
+}
+
+// appendPath returns path with components added, without sharing its backing
+// array with path.
+func appendPath(path KeyPath, components ...string) KeyPath {
+	return append(path[:len(path):len(path)], components...)
+}
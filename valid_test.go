@@ -0,0 +1,56 @@
+package plist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type validHolder struct {
+	Name  string
+	Items []interface{} `plist:"items"`
+	Ch    chan int      `plist:",omitempty"`
+}
+
+func TestValidValue(t *testing.T) {
+	v := validHolder{
+		Name:  "x",
+		Items: []interface{}{"a", map[string]interface{}{"n": 1, "s": "b"}},
+	}
+	for _, format := range []Format{XMLFormat, BinaryFormat} {
+		if err := ValidValue(v, format); err != nil {
+			t.Errorf("ValidValue(%v): %v", format, err)
+		}
+	}
+
+	// OpenStep property lists only hold strings, data, arrays and
+	// dictionaries
+	err := ValidValue(v, OpenStepFormat)
+	var invalid *InvalidValueError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("ValidValue(OpenStepFormat) returned %v, want an InvalidValueError", err)
+	}
+	if want := (KeyPath{"items", "1", "n"}); !reflect.DeepEqual(invalid.Path, want) {
+		t.Errorf("Path = %q, want %q", invalid.Path, want)
+	}
+	if invalid.Format != OpenStepFormat || invalid.Err != nil {
+		t.Errorf("Format = %v, Err = %v", invalid.Format, invalid.Err)
+	}
+	if err := ValidValue([]string{"a", "b"}, OpenStepFormat); err != nil {
+		t.Errorf("ValidValue of strings in OpenStepFormat: %v", err)
+	}
+
+	// values that don't convert report the element that doesn't
+	v.Ch = make(chan int)
+	err = ValidValue(map[string]interface{}{"v": &v}, XMLFormat)
+	if !errors.As(err, &invalid) {
+		t.Fatalf("ValidValue returned %v, want an InvalidValueError", err)
+	}
+	if want := (KeyPath{"v", "Ch"}); !reflect.DeepEqual(invalid.Path, want) {
+		t.Errorf("Path = %q, want %q", invalid.Path, want)
+	}
+	var unsupported *UnsupportedTypeError
+	if !errors.As(err, &unsupported) {
+		t.Errorf("error %v doesn't wrap an UnsupportedTypeError", err)
+	}
+}
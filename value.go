@@ -0,0 +1,232 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// valueTypes maps the type names accepted by ParseValue, including the
+// defaults(1) spellings, to their canonical names.
+var valueTypes = map[string]string{
+	"string":     "string",
+	"int":        "integer",
+	"integer":    "integer",
+	"float":      "real",
+	"real":       "real",
+	"bool":       "bool",
+	"boolean":    "bool",
+	"date":       "date",
+	"data":       "data",
+	"dict":       "dict",
+	"dictionary": "dict",
+	"array":      "array",
+}
+
+const valueTypeList = "string, integer, real, bool, date, data, dict or array"
+
+// valueDateLayouts are the layouts ParseValue accepts for dates, tried in
+// order. The second is the one defaults(1) prints.
+var valueDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseValue parses text as a property list value of the named type. The
+// types are named as in PlistBuddy, and the spellings defaults(1) uses are
+// also accepted:
+//
+//	string
+//	integer, int   decimal, or hexadecimal or octal with a 0x or 0 prefix
+//	real, float
+//	bool, boolean  YES, NO, true, false, 1 or 0, in any case
+//	date           RFC 3339, or "2006-01-02 15:04:05 -0700" as defaults
+//	               prints it; dates without a zone are in UTC
+//	data           hexadecimal, as defaults accepts it, optionally in angle
+//	               brackets and with whitespace, or base64 if the text is not
+//	               valid hexadecimal
+//	dict, dictionary, array
+//	               text must be empty, and an empty container is returned
+//
+// Dictionaries are returned as *Dict values.
+func ParseValue(typ, text string) (interface{}, error) {
+	canonical, ok := valueTypes[strings.ToLower(typ)]
+	if !ok {
+		return nil, errors.New("plist: unknown value type " + strconv.Quote(typ) + " (want " + valueTypeList + ")")
+	}
+	switch canonical {
+	case "string":
+		return text, nil
+	case "integer":
+		i, err := strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+		if err != nil {
+			return nil, errors.New("plist: invalid integer " + strconv.Quote(text))
+		}
+		return i, nil
+	case "real":
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return nil, errors.New("plist: invalid real " + strconv.Quote(text))
+		}
+		return f, nil
+	case "bool":
+		switch strings.ToLower(strings.TrimSpace(text)) {
+		case "yes", "true", "1":
+			return true, nil
+		case "no", "false", "0":
+			return false, nil
+		}
+		return nil, errors.New("plist: invalid bool " + strconv.Quote(text))
+	case "date":
+		text := strings.TrimSpace(text)
+		for _, layout := range valueDateLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, errors.New("plist: invalid date " + strconv.Quote(text))
+	case "data":
+		return parseDataValue(text)
+	case "dict", "array":
+		if text != "" {
+			return nil, errors.New("plist: a " + canonical + " value takes no text")
+		}
+		if canonical == "dict" {
+			return &Dict{}, nil
+		}
+		return []interface{}{}, nil
+	}
+	panic("unreachable")
+}
+
+func parseDataValue(text string) ([]byte, error) {
+	compact := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, text)
+	if strings.HasPrefix(compact, "<") && strings.HasSuffix(compact, ">") {
+		compact = compact[1 : len(compact)-1]
+		if b, err := hex.DecodeString(compact); err == nil {
+			return b, nil
+		}
+		return nil, errors.New("plist: invalid hexadecimal data " + strconv.Quote(text))
+	}
+	if b, err := hex.DecodeString(compact); err == nil {
+		return b, nil
+	}
+	if b, err := base64.StdEncoding.DecodeString(compact); err == nil {
+		return b, nil
+	}
+	return nil, errors.New("plist: invalid data " + strconv.Quote(text) + " (want hexadecimal or base64)")
+}
+
+// ParseValueArgs parses a value given as command line arguments in the style
+// of "defaults write", such as
+//
+//	-bool YES
+//	-int 42
+//	-array one -int 2
+//	-dict Name value Count -int 3
+//
+// A type flag is followed by a single argument, in the formats ParseValue
+// accepts. The elements of -array, and the values of -dict, are strings
+// unless they are preceded by a type flag of their own; containers can't be
+// nested. A single argument with no type flag is parsed as an OpenStep
+// property list if it is one, as defaults does, and is a string otherwise.
+//
+// Dictionaries are returned as *Dict values, in the order of the arguments.
+func ParseValueArgs(args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, errors.New("plist: missing value")
+	}
+	typ, ok := valueFlag(args[0])
+	if !ok {
+		if len(args) > 1 {
+			return nil, errors.New("plist: unexpected arguments after value " + strconv.Quote(args[0]))
+		}
+		return parseBareValue(args[0]), nil
+	}
+	switch typ {
+	case "array":
+		values := []interface{}{}
+		for rest := args[1:]; len(rest) > 0; {
+			v, n, err := parseElementArgs(rest)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			rest = rest[n:]
+		}
+		return values, nil
+	case "dict":
+		d := &Dict{}
+		for rest := args[1:]; len(rest) > 0; {
+			key := rest[0]
+			if len(rest) == 1 {
+				return nil, errors.New("plist: missing value for dictionary key " + strconv.Quote(key))
+			}
+			v, n, err := parseElementArgs(rest[1:])
+			if err != nil {
+				return nil, err
+			}
+			d.Set(key, v)
+			rest = rest[1+n:]
+		}
+		return d, nil
+	}
+	if len(args) != 2 {
+		return nil, errors.New("plist: " + args[0] + " takes exactly one argument")
+	}
+	return ParseValue(typ, args[1])
+}
+
+// parseElementArgs parses an element of an array or dictionary given to
+// ParseValueArgs and returns the number of arguments it used.
+func parseElementArgs(args []string) (interface{}, int, error) {
+	typ, ok := valueFlag(args[0])
+	if !ok {
+		return args[0], 1, nil
+	}
+	if typ == "array" || typ == "dict" {
+		return nil, 0, errors.New("plist: " + args[0] + " cannot be nested")
+	}
+	if len(args) < 2 {
+		return nil, 0, errors.New("plist: missing argument for " + args[0])
+	}
+	v, err := ParseValue(typ, args[1])
+	return v, 2, err
+}
+
+// valueFlag returns the canonical type named by a type flag such as "-int".
+func valueFlag(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", false
+	}
+	typ, ok := valueTypes[strings.ToLower(arg[1:])]
+	return typ, ok
+}
+
+// parseBareValue parses an untyped value as defaults does: as an OpenStep
+// array, dictionary or data value if it is one, and as a string otherwise.
+func parseBareValue(text string) interface{} {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || !strings.ContainsAny(trimmed[:1], "({<") {
+		return text
+	}
+	dec := NewDecoder(bytes.NewReader([]byte(trimmed)))
+	dec.UseOrderedDicts()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return text
+	}
+	return v
+}
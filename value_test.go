@@ -0,0 +1,115 @@
+package plist
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		typ, text string
+		want      interface{}
+	}{
+		{"string", " x ", " x "},
+		{"int", "42", int64(42)},
+		{"integer", "0x1f", int64(31)},
+		{"float", "1.5", 1.5},
+		{"real", "-2", -2.0},
+		{"bool", "YES", true},
+		{"boolean", "false", false},
+		{"BOOL", "0", false},
+		{"date", "2020-01-02T03:04:05Z", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"date", "2020-01-02 03:04:05 +0000", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"date", "2020-01-02", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"data", "deadBEEF", []byte{0xde, 0xad, 0xbe, 0xef}},
+		{"data", "<0102 0304>", []byte{1, 2, 3, 4}},
+		{"data", "aGk=", []byte("hi")},
+		{"array", "", []interface{}{}},
+		{"dictionary", "", &Dict{}},
+	}
+	for _, tc := range tests {
+		got, err := ParseValue(tc.typ, tc.text)
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.typ, tc.text, err)
+			continue
+		}
+		if tm, ok := tc.want.(time.Time); ok {
+			if g, ok := got.(time.Time); !ok || !g.Equal(tm) {
+				t.Errorf("%s %q: got %#v, want %v", tc.typ, tc.text, got, tm)
+			}
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %q: got %#v, want %#v", tc.typ, tc.text, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct{ typ, text string }{
+		{"int", "4.5"},
+		{"real", "x"},
+		{"bool", "maybe"},
+		{"date", "yesterday"},
+		{"data", "<xyz>"},
+		{"data", "not data!"},
+		{"array", "a"},
+		{"uid", "1"},
+	} {
+		if v, err := ParseValue(tc.typ, tc.text); err == nil {
+			t.Errorf("%s %q: got %#v, want an error", tc.typ, tc.text, v)
+		}
+	}
+}
+
+func TestParseValueArgs(t *testing.T) {
+	d := &Dict{}
+	d.Set("Name", "value")
+	d.Set("Count", int64(3))
+	tests := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"-bool", "YES"}, true},
+		{[]string{"-int", "42"}, int64(42)},
+		{[]string{"-data", "0102"}, []byte{1, 2}},
+		{[]string{"-array"}, []interface{}{}},
+		{[]string{"-array", "one", "-int", "2"}, []interface{}{"one", int64(2)}},
+		{[]string{"-dict", "Name", "value", "Count", "-int", "3"}, d},
+		{[]string{"-5"}, "-5"},
+	}
+	for _, tc := range tests {
+		got, err := ParseValueArgs(tc.args)
+		if err != nil {
+			t.Errorf("%q: %v", tc.args, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %#v, want %#v", tc.args, got, tc.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"-int"},
+		{"-int", "1", "2"},
+		{"-int", "x"},
+		{"a", "b"},
+		{"-array", "-dict"},
+		{"-array", "-int"},
+		{"-dict", "key"},
+	} {
+		if v, err := ParseValueArgs(args); err == nil {
+			t.Errorf("%q: got %#v, want an error", args, v)
+		}
+	}
+}
+
+func TestParseValueArgsPlist(t *testing.T) {
+	v, err := ParseValueArgs([]string{"{ a = (1, 2); }"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"a": []interface{}{"1", "2"}}
+	if !Equal(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+	if v, _ := ParseValueArgs([]string{"(unterminated"}); v != "(unterminated" {
+		t.Errorf("got %#v, want the text as a string", v)
+	}
+}
@@ -0,0 +1,70 @@
+package plist
+
+import (
+	"sort"
+	"strconv"
+)
+
+// A WalkAction tells Walk how to continue after visiting a value.
+type WalkAction int
+
+const (
+	// WalkContinue visits the children of the value, if any, and continues.
+	WalkContinue WalkAction = iota
+	// WalkSkip continues without visiting the children of the value.
+	WalkSkip
+	// WalkStop ends the walk. Walk returns nil.
+	WalkStop
+)
+
+// A WalkFunc is called by Walk for each value in a property list, with the key
+// path leading to it. If it returns an error, Walk stops and returns that
+// error; otherwise the action determines how the walk continues.
+type WalkFunc func(path KeyPath, value interface{}) (WalkAction, error)
+
+// Walk calls fn for v and every value inside it, parents before their
+// children. v must be a value decoded into an interface{}, as for GetPath.
+// Dictionary entries are visited in sorted key order, or in key order for a
+// *Dict, and array elements in index order. Each call to fn gets its own copy
+// of the path, which it may keep.
+func Walk(v interface{}, fn WalkFunc) error {
+	_, err := walk(v, KeyPath{}, fn)
+	return err
+}
+
+// walk visits v and its children, returning false if the walk should stop.
+func walk(v interface{}, path KeyPath, fn WalkFunc) (bool, error) {
+	action, err := fn(copyKeyPath(path), v)
+	if err != nil || action == WalkStop {
+		return false, err
+	}
+	if action == WalkSkip {
+		return true, nil
+	}
+	switch c := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(c))
+		for k := range c {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ok, err := walk(c[k], append(path, k), fn); !ok {
+				return false, err
+			}
+		}
+	case *Dict:
+		for _, k := range c.Keys() {
+			if ok, err := walk(c.values[k], append(path, k), fn); !ok {
+				return false, err
+			}
+		}
+	case []interface{}:
+		for i, elem := range c {
+			if ok, err := walk(elem, append(path, strconv.Itoa(i)), fn); !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
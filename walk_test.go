@@ -0,0 +1,56 @@
+package plist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	v := map[string]interface{}{
+		"b": []interface{}{"x", map[string]interface{}{"c": true}},
+		"a": "first",
+		"s": map[string]interface{}{"skipped": true},
+	}
+	var paths []string
+	err := Walk(v, func(path KeyPath, value interface{}) (WalkAction, error) {
+		paths = append(paths, path.String())
+		if path.String() == "s" {
+			return WalkSkip, nil
+		}
+		return WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"", "a", "b", "b:0", "b:1", "b:1:c", "s"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %q, want %q", paths, want)
+	}
+}
+
+func TestWalkStop(t *testing.T) {
+	v := []interface{}{"a", "b", "c"}
+	var visited []interface{}
+	err := Walk(v, func(path KeyPath, value interface{}) (WalkAction, error) {
+		visited = append(visited, value)
+		if value == "b" {
+			return WalkStop, nil
+		}
+		return WalkContinue, nil
+	})
+	if err != nil || len(visited) != 3 {
+		t.Errorf("got %v, %v", visited, err)
+	}
+
+	errFound := errors.New("found")
+	err = Walk(v, func(path KeyPath, value interface{}) (WalkAction, error) {
+		if value == "a" {
+			return WalkContinue, errFound
+		}
+		return WalkContinue, nil
+	})
+	if err != errFound {
+		t.Errorf("got error %v", err)
+	}
+}
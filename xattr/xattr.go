@@ -0,0 +1,112 @@
+// Package xattr reads and writes property list values stored in extended
+// attributes.
+//
+// macOS stores a number of attributes as binary property lists, such as the
+// Spotlight metadata attributes under "com.apple.metadata:". Values are
+// converted with the same rules as plist.Marshal and plist.Unmarshal.
+package xattr
+
+// #include <stdlib.h>
+// #include <sys/xattr.h>
+import "C"
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// Well-known attributes that hold property lists.
+const (
+	WhereFroms     = "com.apple.metadata:kMDItemWhereFroms"     // []string of download URLs
+	DownloadedDate = "com.apple.metadata:kMDItemDownloadedDate" // []time.Time
+	UserTags       = "com.apple.metadata:_kMDItemUserTags"      // []string of Finder tags
+)
+
+// ErrNotFound is returned by Get when the file has no such attribute.
+var ErrNotFound = errors.New("xattr: attribute not found")
+
+// GetData returns the raw value of the attribute name of the file at path.
+func GetData(path, name string) ([]byte, error) {
+	cPath, cName := C.CString(path), C.CString(name)
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cName))
+	for {
+		n, err := C.getxattr(cPath, cName, nil, 0, 0, 0)
+		if n < 0 {
+			return nil, pathError("getxattr", path, err)
+		}
+		if n == 0 {
+			return []byte{}, nil
+		}
+		data := make([]byte, n)
+		m, err := C.getxattr(cPath, cName, unsafe.Pointer(&data[0]), C.size_t(n), 0, 0)
+		if m < 0 {
+			if err == syscall.ERANGE {
+				// the attribute grew between the two calls
+				continue
+			}
+			return nil, pathError("getxattr", path, err)
+		}
+		return data[:m], nil
+	}
+}
+
+// SetData sets the raw value of the attribute name of the file at path.
+func SetData(path, name string, data []byte) error {
+	cPath, cName := C.CString(path), C.CString(name)
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cName))
+	var ptr unsafe.Pointer
+	if len(data) > 0 {
+		ptr = unsafe.Pointer(&data[0])
+	}
+	if r, err := C.setxattr(cPath, cName, ptr, C.size_t(len(data)), 0, 0); r < 0 {
+		return pathError("setxattr", path, err)
+	}
+	return nil
+}
+
+// Get stores the property list held in the attribute name of the file at
+// path in the value pointed to by v. It returns ErrNotFound if the file has
+// no such attribute.
+func Get(path, name string, v interface{}) error {
+	data, err := GetData(path, name)
+	if err != nil {
+		return err
+	}
+	_, err = plist.Unmarshal(data, v)
+	return err
+}
+
+// Set stores v in the attribute name of the file at path as a binary
+// property list, the format the system uses for its own attributes.
+func Set(path, name string, v interface{}) error {
+	data, err := plist.Marshal(v, plist.BinaryFormat)
+	if err != nil {
+		return err
+	}
+	return SetData(path, name, data)
+}
+
+// Remove removes the attribute name from the file at path. It returns
+// ErrNotFound if the file has no such attribute.
+func Remove(path, name string) error {
+	cPath, cName := C.CString(path), C.CString(name)
+	defer C.free(unsafe.Pointer(cPath))
+	defer C.free(unsafe.Pointer(cName))
+	if r, err := C.removexattr(cPath, cName, 0); r < 0 {
+		return pathError("removexattr", path, err)
+	}
+	return nil
+}
+
+func pathError(op, path string, err error) error {
+	if err == syscall.ENOATTR {
+		return ErrNotFound
+	}
+	return &os.PathError{Op: op, Path: path, Err: err}
+}
@@ -0,0 +1,39 @@
+package xattr
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	const name = "com.github.kballard.go-osx-plist.test"
+
+	var got []string
+	if err := Get(path, name, &got); err != ErrNotFound {
+		t.Errorf("Get before Set: got error %v, want ErrNotFound", err)
+	}
+
+	want := []string{"https://example.com/file.zip", "https://example.com/"}
+	if err := Set(path, name, want); err != nil {
+		t.Fatal(err)
+	}
+	if err := Get(path, name, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	if err := Remove(path, name); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path, name); err != ErrNotFound {
+		t.Errorf("second Remove: got error %v, want ErrNotFound", err)
+	}
+}
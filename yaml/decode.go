@@ -0,0 +1,711 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// Decode parses a YAML document into property list values: mappings become
+// *plist.Dict values with their keys in document order, sequences become
+// []interface{}, integers become int64, floats become float64, timestamps
+// become time.Time and !!binary strings become []byte. A mapping whose only
+// key is CF$UID, holding an integer, becomes a plist.UID.
+func Decode(data []byte) (interface{}, error) {
+	p := newParser(data)
+	if !p.next() {
+		return nil, &SyntaxError{1, "empty document"}
+	}
+	v, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.next() {
+		return nil, p.errorf("unexpected content at indentation " + strconv.Itoa(p.cur().indent))
+	}
+	return v, nil
+}
+
+type line struct {
+	num    int    // 1-based line number
+	indent int    // number of leading spaces
+	text   string // the line without its indentation
+}
+
+type parser struct {
+	lines []line
+	pos   int
+	done  bool // true once a document end marker is seen
+}
+
+func newParser(data []byte) *parser {
+	p := &parser{}
+	for i, s := range strings.Split(string(data), "\n") {
+		s = strings.TrimSuffix(s, "\r")
+		text := strings.TrimLeft(s, " ")
+		p.lines = append(p.lines, line{i + 1, len(s) - len(text), text})
+	}
+	return p
+}
+
+func (p *parser) cur() *line {
+	return &p.lines[p.pos]
+}
+
+func (p *parser) errorf(msg string) error {
+	n := len(p.lines)
+	if p.pos < len(p.lines) {
+		n = p.cur().num
+	}
+	return &SyntaxError{n, msg}
+}
+
+// next skips blank lines, comments and directives, and reports whether there
+// is another line of content.
+func (p *parser) next() bool {
+	for !p.done && p.pos < len(p.lines) {
+		l := p.cur()
+		switch {
+		case l.text == "" || l.text[0] == '#':
+		case l.indent == 0 && (l.text == "---" || strings.HasPrefix(l.text, "--- ")):
+			rest := strings.TrimSpace(stripComment(l.text[3:]))
+			if rest != "" {
+				// content on the document start line
+				l.indent = len(l.text) - len(strings.TrimLeft(l.text[3:], " "))
+				l.text = strings.TrimLeft(l.text[3:], " ")
+				return true
+			}
+		case l.indent == 0 && l.text[0] == '%':
+		case l.indent == 0 && (l.text == "..." || strings.HasPrefix(l.text, "... ")):
+			p.done = true
+			return false
+		default:
+			return true
+		}
+		p.pos++
+	}
+	return false
+}
+
+// node parses the value starting at the current line, whose indentation must
+// be at least minIndent.
+func (p *parser) node(minIndent int) (interface{}, error) {
+	l := p.cur()
+	if l.indent < minIndent {
+		return nil, p.errorf("missing value")
+	}
+	if strings.HasPrefix(l.text, "\t") {
+		return nil, p.errorf("tabs are not allowed for indentation")
+	}
+	if isSeqItem(l.text) {
+		return p.sequence(l.indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.mapping(l.indent)
+	}
+	text := strings.TrimSpace(stripComment(l.text))
+	if isBlockScalar(text) {
+		return p.blockScalar(text, l.indent-1)
+	}
+	p.pos++
+	v, err := parseFlowValue(text)
+	if err != nil {
+		return nil, &SyntaxError{l.num, err.Error()}
+	}
+	return v, nil
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+func (p *parser) sequence(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for p.next() {
+		l := p.cur()
+		if l.indent < indent || (l.indent == indent && !isSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		rest := strings.TrimLeft(l.text[1:], " \t")
+		if strings.TrimSpace(stripComment(rest)) == "" {
+			p.pos++
+			if !p.next() {
+				return nil, p.errorf("missing sequence entry")
+			}
+			v, err := p.node(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+		// Parse the rest of the line as if it started a line of its own, so
+		// "- key: value" begins a mapping indented past the "-".
+		l.indent += len(l.text) - len(rest)
+		l.text = rest
+		v, err := p.node(indent + 1)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+	}
+	return seq, nil
+}
+
+func (p *parser) mapping(indent int) (interface{}, error) {
+	d := &plist.Dict{}
+	for p.next() {
+		l := p.cur()
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, p.errorf("expected a mapping key")
+		}
+		if _, dup := d.Get(key); dup {
+			return nil, p.errorf("duplicate key " + strconv.Quote(key))
+		}
+		rest = strings.TrimSpace(stripComment(rest))
+		var v interface{}
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			if !p.next() {
+				return nil, p.errorf("missing value for key " + strconv.Quote(key))
+			}
+			next := p.cur()
+			if next.indent == indent && isSeqItem(next.text) {
+				// a sequence may be indented at the same level as its key
+				v, err = p.sequence(indent)
+			} else {
+				v, err = p.node(indent + 1)
+			}
+		case isBlockScalar(rest):
+			v, err = p.blockScalar(rest, indent)
+		default:
+			p.pos++
+			v, err = parseFlowValue(rest)
+			if err != nil {
+				err = &SyntaxError{l.num, err.Error()}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.Set(key, v)
+	}
+	if uid, ok := dictUID(d); ok {
+		return uid, nil
+	}
+	return d, nil
+}
+
+// splitKey splits a mapping entry line into its key and the text after the
+// colon.
+func splitKey(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || isSeqItem(text) {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		s := &scanner{s: text}
+		k, err := s.quoted()
+		if err != nil {
+			return "", "", false
+		}
+		after := text[s.i:]
+		if after == ":" || strings.HasPrefix(after, ": ") || strings.HasPrefix(after, ":\t") {
+			return k, after[1:], true
+		}
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+			break
+		}
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			return strings.TrimSpace(text[:i]), text[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func isBlockScalar(text string) bool {
+	if text == "" || (text[0] != '|' && text[0] != '>') {
+		return false
+	}
+	switch text[1:] {
+	case "", "-", "+":
+		return true
+	}
+	return false
+}
+
+// blockScalar parses a literal or folded block scalar whose header is on the
+// current line. Its content is indented past parentIndent.
+func (p *parser) blockScalar(header string, parentIndent int) (interface{}, error) {
+	p.pos++
+	var lines []string
+	contentIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.cur()
+		if l.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if contentIndent < 0 {
+			if l.indent <= parentIndent {
+				break
+			}
+			contentIndent = l.indent
+		}
+		if l.indent < contentIndent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", l.indent-contentIndent)+l.text)
+	}
+	// trailing blank lines are subject to chomping
+	content := len(lines)
+	for content > 0 && lines[content-1] == "" {
+		content--
+	}
+	var s string
+	if header[0] == '|' {
+		s = strings.Join(lines[:content], "\n")
+	} else {
+		var b strings.Builder
+		for i, l := range lines[:content] {
+			if i > 0 {
+				// A line break folds into a space, unless it ends an empty
+				// line or is next to a more indented line. An empty line's
+				// preceding break is dropped.
+				switch {
+				case l == "":
+					b.WriteByte('\n')
+				case lines[i-1] == "":
+				case strings.HasPrefix(l, " ") || strings.HasPrefix(lines[i-1], " "):
+					b.WriteByte('\n')
+				default:
+					b.WriteByte(' ')
+				}
+			}
+			b.WriteString(l)
+		}
+		s = b.String()
+	}
+	switch {
+	case header[1:] == "-" || content == 0:
+	case header[1:] == "+":
+		s += strings.Repeat("\n", len(lines)-content+1)
+	default:
+		s += "\n"
+	}
+	return s, nil
+}
+
+// stripComment removes a trailing comment from text, ignoring # characters
+// inside quoted scalars.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" \t[{,:", text[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#':
+			if i == 0 || text[i-1] == ' ' || text[i-1] == '\t' {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+// parseFlowValue parses a complete scalar or flow collection.
+func parseFlowValue(text string) (interface{}, error) {
+	s := &scanner{s: text}
+	v, err := s.value(false)
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.i < len(s.s) {
+		return nil, errorString("unexpected " + strconv.Quote(s.s[s.i:]))
+	}
+	return v, nil
+}
+
+type errorString string
+
+func (e errorString) Error() string {
+	return string(e)
+}
+
+// A scanner parses scalars and flow collections within a single line.
+type scanner struct {
+	s string
+	i int
+}
+
+func (s *scanner) skipSpace() {
+	for s.i < len(s.s) && (s.s[s.i] == ' ' || s.s[s.i] == '\t') {
+		s.i++
+	}
+}
+
+// value parses a value. If inFlow is true, the value is inside a flow
+// collection, so plain scalars end at flow indicators.
+func (s *scanner) value(inFlow bool) (interface{}, error) {
+	s.skipSpace()
+	if s.i == len(s.s) {
+		return nil, errorString("null values are not supported")
+	}
+	switch c := s.s[s.i]; c {
+	case '[':
+		return s.flowSequence()
+	case '{':
+		return s.flowMapping()
+	case '"', '\'':
+		return s.quoted()
+	case '&', '*':
+		return nil, errorString("anchors and aliases are not supported")
+	case '!':
+		return s.tagged(inFlow)
+	}
+	str := s.plain(inFlow, false)
+	return resolvePlain(str)
+}
+
+func (s *scanner) tagged(inFlow bool) (interface{}, error) {
+	start := s.i
+	for s.i < len(s.s) && s.s[s.i] != ' ' {
+		s.i++
+	}
+	tag := s.s[start:s.i]
+	s.skipSpace()
+	var str string
+	if s.i < len(s.s) && (s.s[s.i] == '"' || s.s[s.i] == '\'') {
+		var err error
+		if str, err = s.quoted(); err != nil {
+			return nil, err
+		}
+	} else {
+		str = s.plain(inFlow, false)
+	}
+	switch tag {
+	case "!!str":
+		return str, nil
+	case "!!binary":
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(str), ""))
+		if err != nil {
+			return nil, errorString("invalid !!binary value: " + err.Error())
+		}
+		return b, nil
+	}
+	return nil, errorString("unsupported tag " + tag)
+}
+
+// plain scans a plain scalar. In a flow collection, it ends at a flow
+// indicator, and if isKey is true, also at a colon followed by a space.
+func (s *scanner) plain(inFlow, isKey bool) string {
+	start := s.i
+	for ; s.i < len(s.s); s.i++ {
+		c := s.s[s.i]
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if c == ':' && (inFlow || isKey) && (s.i+1 == len(s.s) || strings.IndexByte(" \t,]}", s.s[s.i+1]) >= 0) {
+			break
+		}
+		if c == '#' && s.i > start && (s.s[s.i-1] == ' ' || s.s[s.i-1] == '\t') {
+			break
+		}
+	}
+	return strings.TrimSpace(s.s[start:s.i])
+}
+
+func (s *scanner) flowSequence() (interface{}, error) {
+	s.i++ // [
+	seq := []interface{}{}
+	for {
+		s.skipSpace()
+		if s.i == len(s.s) {
+			return nil, errorString("unterminated flow sequence")
+		}
+		if s.s[s.i] == ']' {
+			s.i++
+			return seq, nil
+		}
+		v, err := s.value(true)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		if err := s.flowSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *scanner) flowMapping() (interface{}, error) {
+	s.i++ // {
+	d := &plist.Dict{}
+	for {
+		s.skipSpace()
+		if s.i == len(s.s) {
+			return nil, errorString("unterminated flow mapping")
+		}
+		if s.s[s.i] == '}' {
+			s.i++
+			if uid, ok := dictUID(d); ok {
+				return uid, nil
+			}
+			return d, nil
+		}
+		var key string
+		if c := s.s[s.i]; c == '"' || c == '\'' {
+			var err error
+			if key, err = s.quoted(); err != nil {
+				return nil, err
+			}
+		} else {
+			key = s.plain(true, true)
+		}
+		s.skipSpace()
+		if s.i == len(s.s) || s.s[s.i] != ':' {
+			return nil, errorString("expected ':' after key " + strconv.Quote(key))
+		}
+		s.i++
+		if _, dup := d.Get(key); dup {
+			return nil, errorString("duplicate key " + strconv.Quote(key))
+		}
+		v, err := s.value(true)
+		if err != nil {
+			return nil, err
+		}
+		d.Set(key, v)
+		if err := s.flowSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// flowSeparator consumes the comma after a flow collection entry, or checks
+// that the collection ends with end.
+func (s *scanner) flowSeparator(end byte) error {
+	s.skipSpace()
+	if s.i < len(s.s) {
+		switch s.s[s.i] {
+		case ',':
+			s.i++
+			return nil
+		case end:
+			return nil
+		}
+	}
+	return errorString("expected ',' or '" + string(end) + "' in flow collection")
+}
+
+// quoted scans a single- or double-quoted scalar.
+func (s *scanner) quoted() (string, error) {
+	q := s.s[s.i]
+	s.i++
+	var b strings.Builder
+	for s.i < len(s.s) {
+		c := s.s[s.i]
+		s.i++
+		switch {
+		case c == q && q == '\'':
+			if s.i < len(s.s) && s.s[s.i] == '\'' {
+				b.WriteByte('\'')
+				s.i++
+				continue
+			}
+			return b.String(), nil
+		case c == q:
+			return b.String(), nil
+		case c == '\\' && q == '"':
+			if err := s.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errorString("unterminated quoted string")
+}
+
+var simpleEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+func (s *scanner) escape(b *strings.Builder) error {
+	if s.i == len(s.s) {
+		return errorString("unterminated escape sequence")
+	}
+	c := s.s[s.i]
+	s.i++
+	if r, ok := simpleEscapes[c]; ok {
+		b.WriteString(r)
+		return nil
+	}
+	width := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+	if width == 0 || s.i+width > len(s.s) {
+		return errorString("invalid escape sequence \\" + string(c))
+	}
+	n, err := strconv.ParseUint(s.s[s.i:s.i+width], 16, 32)
+	if err != nil {
+		return errorString("invalid escape sequence \\" + s.s[s.i-1:s.i+width])
+	}
+	s.i += width
+	b.WriteRune(rune(n))
+	return nil
+}
+
+// resolvePlain converts a plain scalar to the value it represents under the
+// YAML 1.2 core schema, plus timestamps.
+func resolvePlain(s string) (interface{}, error) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, errorString("null values are not supported")
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1), nil
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1), nil
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), nil
+	}
+	if isNumberStart(s) {
+		if n, ok := parseInt(s); ok {
+			return n, nil
+		}
+		if isDecimalFloat(s) {
+			if f, err := strconv.ParseFloat(s, 64); err == nil || isRangeError(err) {
+				return f, nil
+			}
+		}
+	}
+	if t, ok := parseTimestamp(s); ok {
+		return t, nil
+	}
+	return s, nil
+}
+
+func isNumberStart(s string) bool {
+	c := s[0]
+	return c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9')
+}
+
+func parseInt(s string) (int64, bool) {
+	base, digits := 10, s
+	switch {
+	case strings.HasPrefix(s, "0x"):
+		base, digits = 16, s[2:]
+	case strings.HasPrefix(s, "0o"):
+		base, digits = 8, s[2:]
+	}
+	n, err := strconv.ParseInt(digits, base, 64)
+	return n, err == nil
+}
+
+// isDecimalFloat reports whether s matches the core schema's float syntax,
+// [-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?.
+func isDecimalFloat(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	intDigits := digits()
+	fracDigits := 0
+	if i < len(s) && s[i] == '.' {
+		i++
+		fracDigits = digits()
+	}
+	if intDigits == 0 && fracDigits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+func isRangeError(err error) bool {
+	ne, ok := err.(*strconv.NumError)
+	return ok && ne.Err == strconv.ErrRange
+}
+
+var timestampLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02t15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseTimestamp parses the YAML timestamp formats. Timestamps without a time
+// zone are in UTC.
+func parseTimestamp(s string) (time.Time, bool) {
+	if len(s) < 10 || s[4] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dictUID returns the UID represented by d, if it has the {CF$UID: n} form
+// Encode writes for UIDs.
+func dictUID(d *plist.Dict) (plist.UID, bool) {
+	if d.Len() != 1 {
+		return 0, false
+	}
+	n, ok := d.Map()["CF$UID"].(int64)
+	if !ok || n < 0 || n > math.MaxUint32 {
+		return 0, false
+	}
+	return plist.UID(n), true
+}
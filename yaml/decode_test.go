@@ -0,0 +1,154 @@
+package yaml
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestDecode(t *testing.T) {
+	in := `%YAML 1.2
+---
+# a comment
+zeta: plain text   # trailing comment
+alpha:
+- name: first
+  tags: [a, 1, {k: v}]
+- - true
+  - false
+- []
+env:
+    A: "x: y"
+    'B': 'it''s'
+nums: [0x1F, 0o17, -3, 1.5, 1e3, .inf, 99999999999999999999]
+when: 2020-01-02T03:04:05Z
+day: 2020-01-02
+data: !!binary AQID
+str: !!str 12
+uid: {CF$UID: 4}
+literal: |
+  line one
+    indented
+  line three
+folded: >-
+  one
+  two
+
+  three
+"quoted # not a comment": x#y
+...
+ignored: after end
+`
+	v, err := Decode([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok := v.(*plist.Dict)
+	if !ok {
+		t.Fatalf("got %T, want *plist.Dict", v)
+	}
+	wantKeys := []string{"zeta", "alpha", "env", "nums", "when", "day", "data", "str", "uid", "literal", "folded", "quoted # not a comment"}
+	if !reflect.DeepEqual(d.Keys(), wantKeys) {
+		t.Errorf("keys: got %q, want %q", d.Keys(), wantKeys)
+	}
+	want := map[string]interface{}{
+		"zeta": "plain text",
+		"alpha": []interface{}{
+			map[string]interface{}{"name": "first", "tags": []interface{}{"a", int64(1), map[string]interface{}{"k": "v"}}},
+			[]interface{}{true, false},
+			[]interface{}{},
+		},
+		"env":                    map[string]interface{}{"A": "x: y", "B": "it's"},
+		"nums":                   []interface{}{int64(31), int64(15), int64(-3), 1.5, 1000.0, math.Inf(1), 1e20},
+		"when":                   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"day":                    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"data":                   []byte{1, 2, 3},
+		"str":                    "12",
+		"uid":                    plist.UID(4),
+		"literal":                "line one\n  indented\nline three\n",
+		"folded":                 "one two\nthree",
+		"quoted # not a comment": "x#y",
+	}
+	if !plist.Equal(d, want) {
+		t.Errorf("got %#v", d.Map())
+		for k, w := range want {
+			if g, _ := d.Get(k); !plist.Equal(g, w) {
+				t.Errorf("%s: got %#v, want %#v", k, g, w)
+			}
+		}
+	}
+}
+
+func TestDecodeScalars(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want interface{}
+	}{
+		{"hello", "hello"},
+		{"- a\n- b", []interface{}{"a", "b"}},
+		{`"esc\t\u00e9\x41"`, "esc\téA"},
+		{"--- 5", int64(5)},
+		{"yes", "yes"},
+		{"[]", []interface{}{}},
+		{"{}", map[string]interface{}{}},
+		{"a:\n  - x\n  - y\nb: 1", map[string]interface{}{"a": []interface{}{"x", "y"}, "b": int64(1)}},
+	} {
+		got, err := Decode([]byte(tc.in))
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+		} else if !plist.Equal(got, tc.want) {
+			t.Errorf("%q: got %#v, want %#v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		line int
+	}{
+		{"", 1},
+		{"a: ~", 1},
+		{"a: 1\nb:\nc: 2", 3},
+		{"a: 1\na: 2", 2},
+		{"a: &x 1", 1},
+		{"a: [1, 2", 1},
+		{"a: 1\n  b: 2", 2},
+		{"- a\nb: 1", 2},
+		{"a: \"open", 1},
+		{"a: !!binary ***", 1},
+		{"a: !custom x", 1},
+	} {
+		_, err := Decode([]byte(tc.in))
+		se, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: got error %v, want a SyntaxError", tc.in, err)
+		} else if se.Line != tc.line {
+			t.Errorf("%q: got error on line %d, want %d: %v", tc.in, se.Line, tc.line, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	d := &plist.Dict{}
+	d.Set("b", []interface{}{"1", "two words", "", " padded", "a\nb", 2.5, int64(-7), false})
+	d.Set("a", map[string]interface{}{"x: y": []byte("hi"), "nested": []interface{}{[]interface{}{"z"}}})
+	d.Set("c", time.Date(2021, 6, 7, 8, 9, 10, 123e6, time.UTC))
+	data, err := Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	if !plist.Equal(got, d) {
+		t.Errorf("got %#v\nfrom:\n%s", got.(*plist.Dict).Map(), data)
+	}
+	if keys := got.(*plist.Dict).Keys(); !reflect.DeepEqual(keys, []string{"b", "a", "c"}) {
+		t.Errorf("got keys %q", keys)
+	}
+}
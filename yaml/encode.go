@@ -0,0 +1,260 @@
+package yaml
+
+import (
+	"encoding/base64"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// Encode writes v, a property list value such as one decoded into an
+// interface{}, as a YAML document. The keys of a *plist.Dict keep their
+// order; map keys are sorted.
+func Encode(v interface{}) ([]byte, error) {
+	var e encoder
+	if err := e.value(reflect.ValueOf(v), 0, false); err != nil {
+		return nil, err
+	}
+	return e.b, nil
+}
+
+type encoder struct {
+	b []byte
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uidType  = reflect.TypeOf(plist.UID(0))
+	dictType = reflect.TypeOf(&plist.Dict{})
+)
+
+// value writes v followed by a newline. If inline is true, v follows a
+// sequence item indicator, so the first line of a collection is already
+// indented.
+func (e *encoder) value(v reflect.Value, indent int, inline bool) error {
+	v = indirect(v)
+	if keys, values, ok := entries(v); ok && len(keys) > 0 {
+		for i, k := range keys {
+			if i > 0 || !inline {
+				e.indent(indent)
+			}
+			e.b = appendString(e.b, k)
+			e.b = append(e.b, ':')
+			if err := e.child(values(k), indent, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if isSequence(v) && v.Len() > 0 {
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 || !inline {
+				e.indent(indent)
+			}
+			e.b = append(e.b, '-')
+			if err := e.child(v.Index(i), indent, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var err error
+	e.b, err = appendScalar(e.b, v)
+	if err != nil {
+		return err
+	}
+	e.b = append(e.b, '\n')
+	return nil
+}
+
+// child writes v after a mapping key or sequence item indicator at the given
+// indentation.
+func (e *encoder) child(v reflect.Value, indent int, isKey bool) error {
+	v = indirect(v)
+	keys, _, isMap := entries(v)
+	if (isMap && len(keys) > 0) || (isSequence(v) && v.Len() > 0) {
+		if isKey {
+			e.b = append(e.b, '\n')
+			return e.value(v, indent+2, false)
+		}
+		e.b = append(e.b, ' ')
+		return e.value(v, indent+2, true)
+	}
+	e.b = append(e.b, ' ')
+	return e.value(v, indent, false)
+}
+
+func (e *encoder) indent(n int) {
+	for i := 0; i < n; i++ {
+		e.b = append(e.b, ' ')
+	}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && v.Type() != dictType && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// entries returns the keys of v, if it is a dictionary, and a function to look
+// up their values.
+func entries(v reflect.Value) ([]string, func(string) reflect.Value, bool) {
+	if !v.IsValid() {
+		return nil, nil, false
+	}
+	if v.Type() == dictType {
+		d := v.Interface().(*plist.Dict)
+		return d.Keys(), func(k string) reflect.Value {
+			val, _ := d.Get(k)
+			return reflect.ValueOf(val)
+		}, true
+	}
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, nil, false
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys, func(k string) reflect.Value {
+		return v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key()))
+	}, true
+}
+
+func isSequence(v reflect.Value) bool {
+	return v.IsValid() && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// appendScalar appends v, which is not a non-empty collection, in flow style.
+func appendScalar(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return nil, &plist.UnsupportedTypeError{Type: reflect.TypeOf(nil)}
+	}
+	switch v.Type() {
+	case timeType:
+		return append(b, v.Interface().(time.Time).UTC().Format(time.RFC3339Nano)...), nil
+	case uidType:
+		b = append(b, "{CF$UID: "...)
+		b = strconv.AppendUint(b, v.Uint(), 10)
+		return append(b, '}'), nil
+	}
+	if _, _, ok := entries(v); ok {
+		return append(b, "{}"...), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return appendString(b, v.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(b, v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return append(b, formatFloat(v.Float(), v.Type().Bits())...), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return append(b, "[]"...), nil
+		}
+		data := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(data), v)
+		b = append(b, "!!binary "...)
+		if len(data) == 0 {
+			return append(b, `""`...), nil
+		}
+		return append(b, base64.StdEncoding.EncodeToString(data)...), nil
+	}
+	return nil, &plist.UnsupportedTypeError{Type: v.Type()}
+}
+
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".e") {
+		// keep it from reading as an integer
+		s += ".0"
+	}
+	return s
+}
+
+// appendString appends s as a plain scalar if it reads back as the same
+// string, and as a double-quoted scalar otherwise.
+func appendString(b []byte, s string) []byte {
+	if isPlainSafe(s) {
+		return append(b, s...)
+	}
+	b = append(b, '"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b = append(b, '\\', byte(r))
+		case '\n':
+			b = append(b, `\n`...)
+		case '\t':
+			b = append(b, `\t`...)
+		case '\r':
+			b = append(b, `\r`...)
+		default:
+			if unicode.IsPrint(r) {
+				b = append(b, string(r)...)
+			} else if r <= 0xFF {
+				b = append(b, `\x`...)
+				b = appendHex(b, uint64(r), 2)
+			} else if r <= 0xFFFF {
+				b = append(b, `\u`...)
+				b = appendHex(b, uint64(r), 4)
+			} else {
+				b = append(b, `\U`...)
+				b = appendHex(b, uint64(r), 8)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+func appendHex(b []byte, n uint64, width int) []byte {
+	s := strconv.FormatUint(n, 16)
+	for i := len(s); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, s...)
+}
+
+// isPlainSafe reports whether s can be written as a plain scalar, in block or
+// flow context, and read back as the same string.
+func isPlainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return false
+	}
+	if strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return false
+	}
+	if strings.ContainsAny(s, ",[]{}") || strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if r == '\t' || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	v, err := resolvePlain(s)
+	return err == nil && v == s
+}
@@ -0,0 +1,104 @@
+package yaml
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestEncode(t *testing.T) {
+	inner := &plist.Dict{}
+	inner.Set("name", "first")
+	inner.Set("tags", []interface{}{"a", int64(1)})
+	d := &plist.Dict{}
+	d.Set("zeta", "plain text")
+	d.Set("alpha", []interface{}{inner, []interface{}{true, false}, []interface{}{}})
+	d.Set("env", map[string]interface{}{"B": "2", "A": "x: y"})
+	d.Set("empty", map[string]interface{}{})
+	d.Set("real", 1.0)
+	d.Set("inf", math.Inf(-1))
+	d.Set("when", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	d.Set("data", []byte{1, 2, 3})
+	d.Set("uid", plist.UID(4))
+	d.Set("multi\nline", "tab\there")
+
+	got, err := Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `zeta: plain text
+alpha:
+  - name: first
+    tags:
+      - a
+      - 1
+  - - true
+    - false
+  - []
+env:
+  A: "x: y"
+  B: "2"
+empty: {}
+real: 1.0
+inf: -.inf
+when: 2020-01-02T03:04:05Z
+data: !!binary AQID
+uid: {CF$UID: 4}
+"multi\nline": "tab\there"
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncodeScalar(t *testing.T) {
+	for _, tc := range []struct {
+		v    interface{}
+		want string
+	}{
+		{"hello", "hello\n"},
+		{int32(-5), "-5\n"},
+		{[]interface{}{}, "[]\n"},
+		{[]byte{}, "!!binary \"\"\n"},
+	} {
+		got, err := Encode(tc.v)
+		if err != nil {
+			t.Errorf("%#v: %v", tc.v, err)
+		} else if string(got) != tc.want {
+			t.Errorf("%#v: got %q, want %q", tc.v, got, tc.want)
+		}
+	}
+	if _, err := Encode(map[string]interface{}{"a": nil}); err == nil {
+		t.Error("nil value: expected an error")
+	}
+}
+
+func TestAppendString(t *testing.T) {
+	for _, tc := range []struct {
+		s, want string
+	}{
+		{"simple", "simple"},
+		{"with space", "with space"},
+		{"", `""`},
+		{"true", `"true"`},
+		{"12", `"12"`},
+		{"1e3", `"1e3"`},
+		{"2020-01-02", `"2020-01-02"`},
+		{"null", `"null"`},
+		{"- item", `"- item"`},
+		{"#comment", `"#comment"`},
+		{"a #b", `"a #b"`},
+		{"trailing ", `"trailing "`},
+		{"key:", `"key:"`},
+		{"a:b", "a:b"},
+		{"[x]", `"[x]"`},
+		{"quote\"d\x01", `"quote\"d\x01"`},
+		{"héllo", "héllo"},
+	} {
+		if got := string(appendString(nil, tc.s)); got != tc.want {
+			t.Errorf("%q: got %s, want %s", tc.s, got, tc.want)
+		}
+	}
+}
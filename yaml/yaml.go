@@ -0,0 +1,61 @@
+// Package yaml converts between property lists and YAML.
+//
+// It implements the subset of YAML 1.2 needed to represent property lists:
+// block and flow mappings and sequences, plain, quoted and block scalars, and
+// comments. Anchors, aliases, multiple documents and tags other than !!binary
+// and !!str are not supported. YAML has no null property list equivalent, so
+// null values are an error.
+//
+// Property list values map to YAML as follows:
+//
+//	dictionary  mapping, keeping the order of plist.Dict keys
+//	array       sequence
+//	string      string, quoted if it would otherwise read as another type
+//	integer     integer
+//	real        float, including .inf, -.inf and .nan
+//	boolean     true or false
+//	date        timestamp, in RFC 3339 format
+//	data        !!binary string in standard base64
+//	UID         {CF$UID: n}, as in XML property lists
+package yaml
+
+import (
+	"bytes"
+	"strconv"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+// ToYAML converts the property list in data, in any format, to YAML.
+// Dictionary keys are written in the order they appear in an XML property
+// list, and sorted for other formats.
+func ToYAML(data []byte) ([]byte, error) {
+	dec := plist.NewDecoder(bytes.NewReader(data))
+	dec.UseOrderedDicts()
+	dec.StringifyKeys()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return Encode(v)
+}
+
+// FromYAML converts a YAML document to a property list in the given format.
+// Mapping keys keep their order in XML output.
+func FromYAML(data []byte, format plist.Format) ([]byte, error) {
+	v, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return plist.Marshal(v, format)
+}
+
+// A SyntaxError describes YAML that Decode could not parse.
+type SyntaxError struct {
+	Line int // 1-based line number
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return "yaml: line " + strconv.Itoa(e.Line) + ": " + e.Msg
+}
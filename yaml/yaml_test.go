@@ -0,0 +1,44 @@
+package yaml
+
+import (
+	"testing"
+
+	plist "github.com/kballard/go-osx-plist"
+)
+
+func TestPlistRoundTrip(t *testing.T) {
+	in := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>com.example.job</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>/usr/bin/true</string>
+	</array>
+	<key>KeepAlive</key>
+	<true/>
+</dict>
+</plist>
+`)
+	y, err := ToYAML(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Label: com.example.job
+ProgramArguments:
+  - /usr/bin/true
+KeepAlive: true
+`
+	if string(y) != want {
+		t.Errorf("ToYAML: got:\n%s\nwant:\n%s", y, want)
+	}
+	out, err := FromYAML(y, plist.XMLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(in) {
+		t.Errorf("FromYAML: got:\n%s\nwant:\n%s", out, in)
+	}
+}